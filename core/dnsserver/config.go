@@ -61,6 +61,12 @@ type Config struct {
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
 
+	// MaxMsgSize bounds the size of the messages read and written per transport.
+	MaxMsgSize MsgSizes
+
+	// QUIC tunes the quic and squic servers.
+	QUIC QUICConfig
+
 	// TSIG secrets, [name]key.
 	TsigSecret map[string]string
 
@@ -81,6 +87,85 @@ type Config struct {
 
 	// metaCollector references the first MetadataCollector plugin, if one exists
 	metaCollector MetadataCollector
+
+	// defaultPort is true when no port was given in the server block's key and the
+	// transport's default port is used.
+	defaultPort bool
+}
+
+// QUICConfig holds the settings of the quic plugin for the quic and squic servers of a server block.
+// Zero values leave the defaults in place.
+type QUICConfig struct {
+	// IdleReap is the duration after which DoQ connections without any open streams are closed. Zero
+	// disables reaping.
+	IdleReap time.Duration
+	// SelfCheck makes the servers query themselves once they are listening, if SelfCheckName is set the
+	// certificate must be valid for it. With SelfCheckFail a failed check stops CoreDNS at startup, and
+	// marks the endpoint as failed on a reload.
+	SelfCheck     bool
+	SelfCheckName string
+	SelfCheckFail bool
+
+	// MaxStreams is the maximum number of concurrent streams a client may open on a DoQ connection,
+	// MaxWorkerPoolSize the number of streams a server handles concurrently.
+	MaxStreams        int
+	MaxWorkerPoolSize int
+	// AcceptLoops is the number of loops accepting new DoQ connections per server.
+	AcceptLoops int
+	// MaxSessions is the number of DoQ connections a server keeps open, 0 means no limit.
+	MaxSessions int
+
+	// IdleTimeout, KeepAlive and the flow control receive windows tune the QUIC connections.
+	IdleTimeout      time.Duration
+	KeepAlive        time.Duration
+	StreamWindow     QUICWindow
+	ConnectionWindow QUICWindow
+	// DrainTimeout is how long stopping a server waits for open streams to finish.
+	DrainTimeout time.Duration
+	// CertExpiryWarning is how long before their certificate expires the servers warn about it.
+	CertExpiryWarning time.Duration
+	// Allow0RTT makes the servers accept 0-RTT data from resuming clients.
+	Allow0RTT bool
+	// ClientAddrKey authenticates the client addresses forwarders add to queries, see package clientaddr.
+	ClientAddrKey []byte
+	// NoCompression makes the servers send all responses without name compression.
+	NoCompression bool
+	// Telemetry makes the servers export handshake RTTs, congestion and path validation events of their
+	// connections as metrics.
+	Telemetry bool
+	// QlogDir makes the servers write a qlog file for every connection into it, keeping the newest
+	// QlogMaxFiles files, or all if it is 0.
+	QlogDir      string
+	QlogMaxFiles int
+	// KeyLogFile makes the servers append the TLS session keys to it, in the SSLKEYLOGFILE format.
+	// LogHandshakes makes them log the parameters of every handshake, and why failed ones failed.
+	KeyLogFile    string
+	LogHandshakes bool
+	// LegacyALPN makes the servers accept the ALPN tokens of DoQ drafts besides "doq".
+	LegacyALPN bool
+	// ReplySelector and ReplySelectorArgs override the reply path selector of the scion plugin for squic
+	// servers, see pkgscion.Defaults.
+	ReplySelector     string
+	ReplySelectorArgs string
+	// ConnectionRate and SourceRate limit the queries the servers accept per connection and per source,
+	// i.e. per ISD-AS for SCION clients and per IP address for others. A QPS of 0 disables the limit.
+	ConnectionRate QUICRate
+	SourceRate     QUICRate
+	// AddressValidation makes the servers validate client addresses with a Retry, once more than
+	// AddressValidationThreshold new connections per second arrive, or always if it is 0.
+	AddressValidation          bool
+	AddressValidationThreshold int
+	// NonRecursive and CheckingDisabled set how the servers treat queries without the RD or with the CD
+	// bit, CacheBypass lets clients bypass caches with the NoCacheCode option.
+	NonRecursive     string
+	CheckingDisabled string
+	CacheBypass      bool
+}
+
+// QUICWindow is the initial and maximum size, in bytes, of a QUIC flow control receive window.
+type QUICWindow struct {
+	Initial uint64
+	Max     uint64
 }
 
 // FilterFunc is a function that filters requests from the Config
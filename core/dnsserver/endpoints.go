@@ -0,0 +1,95 @@
+package dnsserver
+
+import (
+	"net"
+	"sort"
+	"sync"
+)
+
+// Endpoint is an encrypted DNS endpoint this process is listening on.
+type Endpoint struct {
+	// Transport is either transport.QUIC or transport.SQUIC.
+	Transport string
+	// Addr is the bound address, for squic this is a SCION address.
+	Addr net.Addr
+	// ALPN lists the application protocols the endpoint accepts.
+	ALPN []string
+	// Err is the error the listener stopped accepting connections with, or that of its failed self check
+	// if the quic plugin's self_check has fail set. It is nil while the endpoint works.
+	Err error
+}
+
+// endpoints holds the endpoints of all running DoQ servers.
+var endpoints = struct {
+	sync.RWMutex
+	m map[*ServerQUIC]Endpoint
+}{m: make(map[*ServerQUIC]Endpoint)}
+
+// Endpoints returns the DoQ endpoints, over IP and SCION, this process is currently listening on.
+// The endpoints are sorted by transport and address.
+func Endpoints() []Endpoint {
+	endpoints.RLock()
+	eps := make([]Endpoint, 0, len(endpoints.m))
+	for _, ep := range endpoints.m {
+		eps = append(eps, ep)
+	}
+	endpoints.RUnlock()
+
+	sort.Slice(eps, func(i, j int) bool {
+		if eps[i].Transport != eps[j].Transport {
+			return eps[i].Transport < eps[j].Transport
+		}
+		return eps[i].Addr.String() < eps[j].Addr.String()
+	})
+	return eps
+}
+
+// EndpointOf returns the endpoint of the running DoQ server that serves the server block c. Ok is false
+// if that server isn't listening (yet).
+func EndpointOf(c *Config) (ep Endpoint, ok bool) {
+	endpoints.RLock()
+	defer endpoints.RUnlock()
+	for s, ep := range endpoints.m {
+		for _, site := range s.zones[c.Zone] {
+			if site == c {
+				return ep, true
+			}
+		}
+	}
+	return Endpoint{}, false
+}
+
+// FailedEndpoints returns the endpoints that stopped accepting connections or failed their self check,
+// see Endpoint.Err.
+func FailedEndpoints() []Endpoint {
+	var failed []Endpoint
+	for _, ep := range Endpoints() {
+		if ep.Err != nil {
+			failed = append(failed, ep)
+		}
+	}
+	return failed
+}
+
+func registerEndpoint(s *ServerQUIC, ep Endpoint) {
+	endpoints.Lock()
+	endpoints.m[s] = ep
+	endpoints.Unlock()
+}
+
+func unregisterEndpoint(s *ServerQUIC) {
+	endpoints.Lock()
+	delete(endpoints.m, s)
+	endpoints.Unlock()
+}
+
+// failEndpoint records err as the reason the endpoint of s stopped accepting connections. Endpoints
+// already unregistered, because s was stopped, are left alone.
+func failEndpoint(s *ServerQUIC, err error) {
+	endpoints.Lock()
+	if ep, ok := endpoints.m[s]; ok {
+		ep.Err = err
+		endpoints.m[s] = ep
+	}
+	endpoints.Unlock()
+}
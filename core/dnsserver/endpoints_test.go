@@ -0,0 +1,50 @@
+package dnsserver
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/transport"
+)
+
+func TestEndpointOf(t *testing.T) {
+	c := testConfig(transport.SQUIC, nil)
+	other := testConfig(transport.SQUIC, nil)
+	s := &ServerQUIC{Server: &Server{zones: map[string][]*Config{c.Zone: {c}}}}
+
+	if _, ok := EndpointOf(c); ok {
+		t.Fatal("Expected no endpoint before the server listens")
+	}
+
+	registerEndpoint(s, Endpoint{Transport: transport.SQUIC, Addr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8853}})
+	defer unregisterEndpoint(s)
+
+	ep, ok := EndpointOf(c)
+	if !ok {
+		t.Fatal("Expected an endpoint for the server block")
+	}
+	if ep.Err != nil {
+		t.Errorf("Expected no error, got %s", ep.Err)
+	}
+	if _, ok := EndpointOf(other); ok {
+		t.Error("Expected no endpoint for a server block of another server")
+	}
+	if failed := FailedEndpoints(); len(failed) != 0 {
+		t.Errorf("Expected no failed endpoints, got %d", len(failed))
+	}
+
+	failEndpoint(s, errors.New("accept failed"))
+	if ep, _ := EndpointOf(c); ep.Err == nil {
+		t.Error("Expected the accept error to be recorded")
+	}
+	if failed := FailedEndpoints(); len(failed) != 1 {
+		t.Errorf("Expected 1 failed endpoint, got %d", len(failed))
+	}
+
+	unregisterEndpoint(s)
+	failEndpoint(s, errors.New("accept failed"))
+	if _, ok := EndpointOf(c); ok {
+		t.Error("Expected a stopped server to stay unregistered")
+	}
+}
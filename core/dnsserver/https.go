@@ -5,6 +5,8 @@ import (
 	"net/http"
 
 	"github.com/coredns/coredns/plugin/pkg/nonwriter"
+
+	"github.com/miekg/dns"
 )
 
 // DoHWriter is a nonwriter.Writer that adds more specific LocalAddr and RemoteAddr methods.
@@ -20,6 +22,19 @@ type DoHWriter struct {
 	request        *http.Request
 	tsigTimersOnly bool
 	tsigStatus     error
+	// tsigMAC is the MAC of the query, if it is signed.
+	tsigMAC string
+
+	// noCompression packs all responses without name compression.
+	noCompression bool
+}
+
+// WriteMsg records the message, which is packed later on.
+func (d *DoHWriter) WriteMsg(m *dns.Msg) error {
+	if d.noCompression {
+		m.Compress = false
+	}
+	return d.Writer.WriteMsg(m)
 }
 
 func (d *DoHWriter) TsigTimersOnly(b bool) { d.tsigTimersOnly = b }
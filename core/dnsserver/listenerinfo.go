@@ -0,0 +1,91 @@
+package dnsserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// listenerInfo creates the text that we show for a listener when starting up, so that misconfigured
+// listeners are obvious before the first query fails:
+//
+//	transport: squic, address: 19-ffaa:1:1067,[127.0.0.1]:8853, alpn: doq-i02,doq, certificate expires: 2024-05-01 (in 91 days)
+//
+// addr is the bound address, which for squic includes the ISD-AS.
+func listenerInfo(trans, addr string, tlsConfig *tls.Config) string {
+	s := "   transport: " + trans + ", address: " + addr
+	if tlsConfig == nil {
+		return s + ", no TLS configured\n"
+	}
+
+	if len(tlsConfig.NextProtos) > 0 {
+		s += ", alpn: " + strings.Join(tlsConfig.NextProtos, ",")
+	}
+
+	notAfter, ok := certificateExpiry(tlsConfig)
+	if !ok {
+		return s + "\n"
+	}
+	left := time.Until(notAfter)
+	if left <= 0 {
+		return s + fmt.Sprintf(", certificate EXPIRED: %s\n", notAfter.UTC().Format("2006-01-02"))
+	}
+	return s + fmt.Sprintf(", certificate expires: %s (in %d days)\n", notAfter.UTC().Format("2006-01-02"), int(left.Hours()/24))
+}
+
+// certificateExpiry returns the NotAfter of the first certificate in tlsConfig. Ok is false if
+// there is no certificate that can be parsed.
+func certificateExpiry(tlsConfig *tls.Config) (notAfter time.Time, ok bool) {
+	certs := serverCertificates(tlsConfig)
+	if len(certs) == 0 {
+		return time.Time{}, false
+	}
+	leaf, ok := leafCertificate(certs[0])
+	if !ok {
+		return time.Time{}, false
+	}
+	return leaf.NotAfter, true
+}
+
+// serverCertificates returns the certificates of tlsConfig. If they aren't static, but are reloaded or
+// obtained by ACME, this is the certificate GetCertificate currently hands out.
+func serverCertificates(tlsConfig *tls.Config) []tls.Certificate {
+	if len(tlsConfig.Certificates) > 0 || tlsConfig.GetCertificate == nil {
+		return tlsConfig.Certificates
+	}
+	cert, err := tlsConfig.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil || cert == nil {
+		return nil
+	}
+	return []tls.Certificate{*cert}
+}
+
+// leafCertificate returns the parsed leaf of cert.
+func leafCertificate(cert tls.Certificate) (*x509.Certificate, bool) {
+	if cert.Leaf != nil {
+		return cert.Leaf, true
+	}
+	if len(cert.Certificate) == 0 {
+		return nil, false
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, false
+	}
+	return leaf, true
+}
+
+// boundAddr returns the string form of the bound address a, or the configured address if the
+// server isn't listening yet.
+func (s *Server) boundAddr(a net.Addr) string {
+	if a != nil {
+		return a.String()
+	}
+	if i := strings.Index(s.Addr, "://"); i >= 0 {
+		return s.Addr[i+3:]
+	}
+	return s.Addr
+}
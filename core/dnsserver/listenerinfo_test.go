@@ -0,0 +1,50 @@
+package dnsserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListenerInfo(t *testing.T) {
+	valid := selfSigned(t, time.Now().Add(48*time.Hour+time.Minute))
+	expired := selfSigned(t, time.Now().Add(-time.Hour))
+
+	tests := []struct {
+		tlsConfig *tls.Config
+		expected  []string
+	}{
+		{nil, []string{"transport: quic, address: 127.0.0.1:853", "no TLS configured"}},
+		{&tls.Config{NextProtos: []string{"doq-i02", "doq"}}, []string{"alpn: doq-i02,doq"}},
+		{&tls.Config{Certificates: []tls.Certificate{valid}}, []string{"certificate expires:", "(in 2 days)"}},
+		{&tls.Config{Certificates: []tls.Certificate{expired}}, []string{"certificate EXPIRED"}},
+	}
+
+	for i, tc := range tests {
+		out := listenerInfo("quic", "127.0.0.1:853", tc.tlsConfig)
+		for _, e := range tc.expected {
+			if !strings.Contains(out, e) {
+				t.Errorf("Test %d: expected %q to contain %q", i, out, e)
+			}
+		}
+	}
+}
+
+func selfSigned(t *testing.T, notAfter time.Time) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: notAfter.Add(-72 * time.Hour), NotAfter: notAfter}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
@@ -0,0 +1,62 @@
+package dnsserver
+
+import (
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
+)
+
+// MsgSizes holds the maximum DNS message size per transport, zero means dns.MaxMsgSize.
+type MsgSizes struct {
+	UDP      int
+	QUIC     int
+	SQUIC    int
+	Transfer int
+}
+
+// ForTransport returns the maximum message size for messages received and sent over trans.
+func (m MsgSizes) ForTransport(trans string) int {
+	size := 0
+	switch trans {
+	case transport.DNS, "udp":
+		size = m.UDP
+	case transport.QUIC:
+		size = m.QUIC
+	case transport.SQUIC:
+		size = m.SQUIC
+	}
+	if size == 0 {
+		return dns.MaxMsgSize
+	}
+	return size
+}
+
+// merge sets the sizes in o that are not zero.
+func (m *MsgSizes) merge(o MsgSizes) {
+	if o.UDP != 0 {
+		m.UDP = o.UDP
+	}
+	if o.QUIC != 0 {
+		m.QUIC = o.QUIC
+	}
+	if o.SQUIC != 0 {
+		m.SQUIC = o.SQUIC
+	}
+	if o.Transfer != 0 {
+		m.Transfer = o.Transfer
+	}
+}
+
+// truncatingWriter truncates responses that are larger than max before writing them.
+type truncatingWriter struct {
+	dns.ResponseWriter
+	max int
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *truncatingWriter) WriteMsg(m *dns.Msg) error {
+	if m.Len() > w.max {
+		m.Truncate(w.max)
+	}
+	return w.ResponseWriter.WriteMsg(m)
+}
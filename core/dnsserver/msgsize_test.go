@@ -0,0 +1,49 @@
+package dnsserver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestMsgSizesForTransport(t *testing.T) {
+	m := MsgSizes{UDP: 1232, SQUIC: 4096}
+	tests := []struct {
+		transport string
+		expected  int
+	}{
+		{"dns", 1232},
+		{"udp", 1232},
+		{"squic", 4096},
+		{"quic", dns.MaxMsgSize},
+		{"tls", dns.MaxMsgSize},
+	}
+	for i, tc := range tests {
+		if got := m.ForTransport(tc.transport); got != tc.expected {
+			t.Errorf("Test %d: expected %d for %s, got %d", i, tc.expected, tc.transport, got)
+		}
+	}
+}
+
+func TestTruncatingWriter(t *testing.T) {
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	w := &truncatingWriter{ResponseWriter: rec, max: 512}
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeTXT)
+	for i := 0; i < 50; i++ {
+		m.Answer = append(m.Answer, test.TXT(fmt.Sprintf("example.org. 3600 IN TXT \"record %d\"", i)))
+	}
+	w.WriteMsg(m)
+
+	if rec.Msg.Len() > 512 {
+		t.Errorf("Expected response to be at most 512 bytes, got %d", rec.Msg.Len())
+	}
+	if !rec.Msg.Truncated {
+		t.Errorf("Expected TC bit to be set")
+	}
+}
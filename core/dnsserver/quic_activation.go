@@ -0,0 +1,99 @@
+package dnsserver
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+)
+
+// listenFDsStart is the first file descriptor systemd passes on, see sd_listen_fds(3).
+const listenFDsStart = 3
+
+// activatedSockets holds the UDP sockets systemd passed on with socket activation that no server took
+// yet. Systemd opens them before it starts CoreDNS, so DoQ servers can listen on privileged ports
+// without root, and keeps them open while CoreDNS restarts, so clients see no refused packets.
+var activatedSockets struct {
+	once  sync.Once
+	mu    sync.Mutex
+	conns []net.PacketConn
+}
+
+// takeActivatedSocket returns the socket systemd passed on for the UDP address addr, as in
+// "127.0.0.1:853" or ":853", and removes it from the activated sockets. It returns nil if there is none.
+func takeActivatedSocket(addr string) net.PacketConn {
+	activatedSockets.once.Do(func() {
+		conns, err := listenFDs(os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS"), listenFDsStart)
+		if err != nil {
+			clog.Warningf("Ignoring the sockets passed on by systemd: %s", err)
+		}
+		activatedSockets.conns = conns
+		// Sockets are passed on only once, not to the processes CoreDNS starts.
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	})
+
+	want, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil
+	}
+	activatedSockets.mu.Lock()
+	defer activatedSockets.mu.Unlock()
+	for i, p := range activatedSockets.conns {
+		if sameUDPAddr(p.LocalAddr(), want) {
+			activatedSockets.conns = append(activatedSockets.conns[:i], activatedSockets.conns[i+1:]...)
+			return p
+		}
+	}
+	return nil
+}
+
+// listenFDs returns the UDP sockets among the fds file descriptors passed on to the process pid,
+// starting at first. Other sockets, e.g. TCP sockets for DNS over TLS, are closed.
+func listenFDs(pid, fds string, first int) ([]net.PacketConn, error) {
+	if pid == "" || fds == "" {
+		return nil, nil
+	}
+	if p, err := strconv.Atoi(pid); err != nil || p != os.Getpid() {
+		return nil, fmt.Errorf("they are for process %s", pid)
+	}
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q", fds)
+	}
+
+	var conns []net.PacketConn
+	for fd := first; fd < first+n; fd++ {
+		f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		p, err := net.FilePacketConn(f)
+		// The packet conn has a copy of the descriptor. Close f now in either case, rather than leaving it
+		// to the finalizer of f at some random time.
+		f.Close()
+		if err != nil {
+			continue
+		}
+		if _, ok := p.LocalAddr().(*net.UDPAddr); !ok {
+			p.Close()
+			continue
+		}
+		conns = append(conns, p)
+	}
+	return conns, nil
+}
+
+// sameUDPAddr returns true if the socket bound to a serves the address want. An unspecified IP matches
+// any unspecified IP, as systemd binds dual-stack sockets to [::].
+func sameUDPAddr(a net.Addr, want *net.UDPAddr) bool {
+	ua, ok := a.(*net.UDPAddr)
+	if !ok || ua.Port != want.Port {
+		return false
+	}
+	if want.IP == nil || want.IP.IsUnspecified() {
+		return ua.IP.IsUnspecified()
+	}
+	return ua.IP.Equal(want.IP)
+}
@@ -0,0 +1,78 @@
+package dnsserver
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestListenFDs(t *testing.T) {
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcp.Close()
+
+	// Pass the sockets on as systemd would, in consecutive descriptors.
+	uf, err := udp.(*net.UDPConn).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer uf.Close()
+	tf, err := tcp.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tf.Close()
+	if int(tf.Fd()) != int(uf.Fd())+1 {
+		t.Skip("Sockets were not passed in consecutive descriptors")
+	}
+	pid := strconv.Itoa(os.Getpid())
+
+	if _, err := listenFDs("1", "2", int(uf.Fd())); err == nil {
+		t.Error("Expected an error for the sockets of another process")
+	}
+	conns, err := listenFDs(pid, "2", int(uf.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conns) != 1 {
+		t.Fatalf("Expected only the UDP socket, got %d sockets", len(conns))
+	}
+	defer conns[0].Close()
+	if l, err := net.FileListener(tf); err == nil {
+		l.Close()
+		t.Error("Expected the TCP socket to be closed")
+	}
+	if conns[0].LocalAddr().String() != udp.LocalAddr().String() {
+		t.Errorf("Expected the socket on %s, got %s", udp.LocalAddr(), conns[0].LocalAddr())
+	}
+}
+
+func TestSameUDPAddr(t *testing.T) {
+	for i, test := range []struct {
+		local, want string
+		same        bool
+	}{
+		{"127.0.0.1:853", "127.0.0.1:853", true},
+		{"127.0.0.1:853", "127.0.0.1:8853", false},
+		{"127.0.0.1:853", "127.0.0.2:853", false},
+		{"[::]:853", ":853", true},
+		{"0.0.0.0:853", ":853", true},
+		{"[::]:853", "0.0.0.0:853", true},
+		{"127.0.0.1:853", ":853", false},
+		{"[::]:853", "127.0.0.1:853", false},
+	} {
+		local, _ := net.ResolveUDPAddr("udp", test.local)
+		want, _ := net.ResolveUDPAddr("udp", test.want)
+		if got := sameUDPAddr(local, want); got != test.same {
+			t.Errorf("Test %d: expected %t for %s and %s, got %t", i, test.same, test.local, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package dnsserver
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+
+	"github.com/quic-go/quic-go"
+)
+
+// QUICPeer describes the client of a DoQ connection once the TLS handshake completed.
+type QUICPeer struct {
+	// Transport is either quic or squic.
+	Transport string
+	// RemoteAddr is the address of the client, for squic this is a *pan.UDPAddr that includes its ISD-AS.
+	RemoteAddr net.Addr
+	// TLS holds the negotiated ALPN and, if the tls plugin asks for them, the client's certificates.
+	TLS tls.ConnectionState
+}
+
+// QUICAuthenticator - If QUICAuthenticator is implemented by a plugin in a server block, the quic and squic
+// servers call Authenticate for every new connection, before any of its streams are accepted. If an error
+// is returned the connection is closed. Otherwise a non nil identity is attached to the context of every
+// query on the connection, see QUICIdentity.
+type QUICAuthenticator interface {
+	Authenticate(ctx context.Context, peer QUICPeer) (identity interface{}, err error)
+}
+
+// QUICIdentityKey is the context key for the identity a QUICAuthenticator attached to a DoQ connection.
+type QUICIdentityKey struct{}
+
+// QUICIdentity returns the identity attached to the DoQ connection the query in ctx arrived on.
+func QUICIdentity(ctx context.Context) (interface{}, bool) {
+	id := ctx.Value(QUICIdentityKey{})
+	return id, id != nil
+}
+
+// QUICPeerKey is the context key for the QUICPeer of the DoQ connection a query arrived on. It is only
+// set once the handshake completed, so the client's certificates are known: queries answered from 0-RTT
+// data don't have it, zone transfers always do.
+type QUICPeerKey struct{}
+
+// QUICPeerOf returns the QUICPeer of the DoQ connection the query in ctx arrived on.
+func QUICPeerOf(ctx context.Context) (QUICPeer, bool) {
+	peer, ok := ctx.Value(QUICPeerKey{}).(QUICPeer)
+	return peer, ok
+}
+
+// withPeer returns ctx with the QUICPeer of session, if its handshake completed.
+func (s *ServerQUIC) withPeer(ctx context.Context, session quic.Connection) context.Context {
+	select {
+	case <-s.handshakeComplete(session):
+		return context.WithValue(ctx, QUICPeerKey{}, s.peer(session))
+	default:
+		return ctx
+	}
+}
+
+// quicAuthenticators returns the QUICAuthenticators of the server blocks in group, in plugin.cfg order.
+func quicAuthenticators(group []*Config) []QUICAuthenticator {
+	var auths []QUICAuthenticator
+	for _, c := range group {
+		// All zones of a server block share the same plugins, ask them only once.
+		if c.firstConfigInBlock != nil && c.firstConfigInBlock != c {
+			continue
+		}
+		for _, d := range Directives {
+			if a, ok := c.registry[d].(QUICAuthenticator); ok {
+				auths = append(auths, a)
+			}
+		}
+	}
+	return auths
+}
+
+// authenticate runs all authenticators for peer. Every one of them must accept the connection, the first
+// identity returned is kept.
+func authenticate(ctx context.Context, auths []QUICAuthenticator, peer QUICPeer) (interface{}, error) {
+	var identity interface{}
+	for _, a := range auths {
+		id, err := a.Authenticate(ctx, peer)
+		if err != nil {
+			return nil, err
+		}
+		if identity == nil {
+			identity = id
+		}
+	}
+	return identity, nil
+}
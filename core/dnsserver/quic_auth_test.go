@@ -0,0 +1,84 @@
+package dnsserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+type testAuthenticator struct {
+	name     string
+	identity interface{}
+	err      error
+}
+
+func (a testAuthenticator) ServeDNS(context.Context, dns.ResponseWriter, *dns.Msg) (int, error) {
+	return dns.RcodeSuccess, nil
+}
+
+func (a testAuthenticator) Name() string { return a.name }
+
+func (a testAuthenticator) Authenticate(context.Context, QUICPeer) (interface{}, error) {
+	return a.identity, a.err
+}
+
+func TestAuthenticate(t *testing.T) {
+	errDenied := errors.New("denied")
+	tests := []struct {
+		auths            []QUICAuthenticator
+		expectedIdentity interface{}
+		expectedErr      error
+	}{
+		{nil, nil, nil},
+		{[]QUICAuthenticator{testAuthenticator{}}, nil, nil},
+		{[]QUICAuthenticator{testAuthenticator{identity: "a"}, testAuthenticator{identity: "b"}}, "a", nil},
+		{[]QUICAuthenticator{testAuthenticator{}, testAuthenticator{identity: "b"}}, "b", nil},
+		{[]QUICAuthenticator{testAuthenticator{identity: "a"}, testAuthenticator{err: errDenied}}, nil, errDenied},
+	}
+	for i, tc := range tests {
+		id, err := authenticate(context.TODO(), tc.auths, QUICPeer{Transport: "quic"})
+		if err != tc.expectedErr {
+			t.Errorf("Test %d: expected error %v, got %v", i, tc.expectedErr, err)
+		}
+		if id != tc.expectedIdentity {
+			t.Errorf("Test %d: expected identity %v, got %v", i, tc.expectedIdentity, id)
+		}
+	}
+}
+
+func TestQUICAuthenticators(t *testing.T) {
+	first := &Config{}
+	first.firstConfigInBlock = first
+	first.registerHandler(testAuthenticator{name: "acl", identity: "acl"})
+	first.registerHandler(test.ErrorHandler())
+
+	// A second zone in the same block has the same plugins and must not be asked again.
+	second := &Config{firstConfigInBlock: first}
+	second.registerHandler(testAuthenticator{name: "acl", identity: "acl"})
+
+	other := &Config{}
+	other.firstConfigInBlock = other
+	other.registerHandler(testAuthenticator{name: "tsig", identity: "tsig"})
+
+	auths := quicAuthenticators([]*Config{first, second, other})
+	if len(auths) != 2 {
+		t.Fatalf("Expected 2 authenticators, got %d", len(auths))
+	}
+	if id, _ := auths[1].Authenticate(context.TODO(), QUICPeer{}); id != "tsig" {
+		t.Errorf("Expected the authenticator of the second block, got %v", id)
+	}
+}
+
+func TestQUICIdentity(t *testing.T) {
+	if _, ok := QUICIdentity(context.TODO()); ok {
+		t.Errorf("Expected no identity")
+	}
+	ctx := context.WithValue(context.TODO(), QUICIdentityKey{}, "client.example.org")
+	if id, ok := QUICIdentity(ctx); !ok || id != "client.example.org" {
+		t.Errorf("Expected identity %q, got %v", "client.example.org", id)
+	}
+}
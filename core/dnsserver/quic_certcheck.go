@@ -0,0 +1,81 @@
+package dnsserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"time"
+
+	"github.com/coredns/coredns/plugin/metrics/vars"
+)
+
+// DefaultCertExpiryWarning is how long before a certificate of a DoQ listener expires warnings are logged.
+const DefaultCertExpiryWarning = 14 * 24 * time.Hour
+
+// certCheckInterval is how often the certificates are checked.
+const certCheckInterval = time.Hour
+
+// checkCertificates periodically warns about certificates that are about to expire. Unlike plain DNS, DoQ
+// stops working altogether once the certificate expired, which is easily overlooked when the server keeps
+// answering over Do53.
+func (s *ServerQUIC) checkCertificates() {
+	ticker := time.NewTicker(certCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		// With several server blocks on the listener, each has certificates of its own.
+		for _, cfg := range s.tlsConfigs {
+			for _, c := range leafCertificates(cfg) {
+				vars.QUICCertificateExpiry.WithLabelValues(s.Addr, certificateName(c)).Set(float64(c.NotAfter.Unix()))
+			}
+			for _, c := range expiringCertificates(cfg, now, s.certExpiryWarning) {
+				left := c.NotAfter.Sub(now)
+				if left <= 0 {
+					s.log.Errorf("Certificate for %s EXPIRED on %s", certificateName(c), c.NotAfter.UTC().Format("2006-01-02"))
+					continue
+				}
+				s.log.Warningf("Certificate for %s expires on %s (in %d days)", certificateName(c), c.NotAfter.UTC().Format("2006-01-02"), int(left.Hours()/24))
+			}
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// leafCertificates returns the certificates of tlsConfig that can be parsed.
+func leafCertificates(tlsConfig *tls.Config) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for _, cert := range serverCertificates(tlsConfig) {
+		if leaf, ok := leafCertificate(cert); ok {
+			certs = append(certs, leaf)
+		}
+	}
+	return certs
+}
+
+// expiringCertificates returns the certificates of tlsConfig that expire within window from now, or
+// have expired already.
+func expiringCertificates(tlsConfig *tls.Config, now time.Time, window time.Duration) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for _, c := range leafCertificates(tlsConfig) {
+		if c.NotAfter.Sub(now) < window {
+			certs = append(certs, c)
+		}
+	}
+	return certs
+}
+
+// certificateName returns the name identifying c in logs and metrics.
+func certificateName(c *x509.Certificate) string {
+	if len(c.DNSNames) > 0 {
+		return c.DNSNames[0]
+	}
+	if c.Subject.CommonName != "" {
+		return c.Subject.CommonName
+	}
+	return c.SerialNumber.String()
+}
@@ -0,0 +1,45 @@
+package dnsserver
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+)
+
+func TestExpiringCertificates(t *testing.T) {
+	now := time.Now()
+	valid := selfSigned(t, now.Add(60*24*time.Hour))
+	soon := selfSigned(t, now.Add(3*24*time.Hour))
+	expired := selfSigned(t, now.Add(-time.Hour))
+
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{valid, soon, expired, {}}}
+
+	if n := len(leafCertificates(tlsConfig)); n != 3 {
+		t.Errorf("Expected 3 certificates, got %d", n)
+	}
+
+	tests := []struct {
+		window   time.Duration
+		expected int
+	}{
+		{0, 1},
+		{DefaultCertExpiryWarning, 2},
+		{90 * 24 * time.Hour, 3},
+	}
+	for i, tc := range tests {
+		if n := len(expiringCertificates(tlsConfig, now, tc.window)); n != tc.expected {
+			t.Errorf("Test %d: expected %d expiring certificates, got %d", i, tc.expected, n)
+		}
+	}
+}
+
+func TestExpiringCertificatesGetCertificate(t *testing.T) {
+	now := time.Now()
+	soon := selfSigned(t, now.Add(3*24*time.Hour))
+
+	// Reloaded certificates are only known through GetCertificate.
+	tlsConfig := &tls.Config{GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return &soon, nil }}
+	if n := len(expiringCertificates(tlsConfig, now, DefaultCertExpiryWarning)); n != 1 {
+		t.Errorf("Expected 1 expiring certificate, got %d", n)
+	}
+}
@@ -0,0 +1,83 @@
+package dnsserver
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"os"
+
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/logging"
+)
+
+// openKeyLog opens the TLS key log file at path for appending, in the NSS key log format that the
+// SSLKEYLOGFILE environment variable of browsers, curl and ngtcp2 produces. Wireshark decrypts the DoQ
+// traffic with it. The file is only readable by us, it holds the secrets of every connection.
+func openKeyLog(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+}
+
+// withKeyLog returns a copy of cfg, the TLS config of a listener, that writes the session keys to w. If cfg
+// hands out the configs of several server blocks, see quicTLSConfig, those are copied as well.
+func withKeyLog(cfg *tls.Config, blocks []*tls.Config, w io.Writer) *tls.Config {
+	cfg, _ = cloneTLSConfigs(cfg, blocks, func(c *tls.Config) { c.KeyLogWriter = w })
+	return cfg
+}
+
+// logHandshake logs the parameters the handshake of session negotiated, once it completed. These are the
+// usual suspects when a client fails to interoperate.
+func (s *ServerQUIC) logHandshake(session quic.Connection) {
+	select {
+	case <-s.handshakeComplete(session):
+	case <-session.Context().Done():
+		return
+	}
+	cs := session.ConnectionState()
+	s.log.Infof("DoQ handshake with %s: QUIC %s, ALPN %q, cipher %s, server name %q, resumed %t, 0-RTT %t",
+		session.RemoteAddr(), cs.Version, cs.TLS.NegotiatedProtocol, tls.CipherSuiteName(cs.TLS.CipherSuite),
+		cs.TLS.ServerName, cs.TLS.DidResume, cs.TLS.Used0RTT)
+}
+
+// quicHandshakeLogger is a logging.Tracer that logs connections that were closed before their handshake
+// completed, with the reason, e.g. a client offering none of our ALPNs. Those never reach the server
+// otherwise.
+type quicHandshakeLogger struct {
+	logging.NullTracer
+	log clog.P
+}
+
+// TracerForConnection implements logging.Tracer.
+func (t *quicHandshakeLogger) TracerForConnection(context.Context, logging.Perspective, logging.ConnectionID) logging.ConnectionTracer {
+	return &connHandshakeLogger{log: t.log}
+}
+
+// connHandshakeLogger traces the handshake of a single connection.
+type connHandshakeLogger struct {
+	logging.NullConnectionTracer
+	log clog.P
+
+	remote net.Addr
+	done   bool
+}
+
+// StartedConnection implements logging.ConnectionTracer.
+func (c *connHandshakeLogger) StartedConnection(_, remote net.Addr, _, _ logging.ConnectionID) {
+	c.remote = remote
+}
+
+// DroppedEncryptionLevel implements logging.ConnectionTracer.
+func (c *connHandshakeLogger) DroppedEncryptionLevel(level logging.EncryptionLevel) {
+	if level == logging.EncryptionHandshake {
+		c.done = true
+	}
+}
+
+// ClosedConnection implements logging.ConnectionTracer.
+func (c *connHandshakeLogger) ClosedConnection(err error) {
+	if !c.done {
+		c.log.Infof("DoQ handshake with %s failed: %s", c.remote, err)
+	}
+}
@@ -0,0 +1,57 @@
+package dnsserver
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithKeyLog(t *testing.T) {
+	org := &tls.Config{ServerName: "org", Certificates: []tls.Certificate{selfSigned(t, time.Now().Add(time.Hour))}}
+	netTLS := &tls.Config{ServerName: "net"}
+	listener, configs, err := quicTLSConfig("quic://:853", map[string][]*Config{
+		"example.org.": {{Zone: "example.org.", TLSConfigQUIC: org}},
+		"example.net.": {{Zone: "example.net.", TLSConfigQUIC: netTLS}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys bytes.Buffer
+	logged := withKeyLog(listener, configs, &keys)
+	if listener.KeyLogWriter != nil || org.KeyLogWriter != nil {
+		t.Fatal("Expected the configs of the server blocks to be left alone")
+	}
+	cfg, err := logged.GetConfigForClient(&tls.ClientHelloInfo{ServerName: "example.org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ServerName != "org" || cfg.KeyLogWriter == nil {
+		t.Fatalf("Expected the config of example.org. with the key log, got %q", cfg.ServerName)
+	}
+
+	// A handshake writes the secrets in the SSLKEYLOGFILE format.
+	c, s := net.Pipe()
+	defer c.Close()
+	defer s.Close()
+	go tls.Server(s, logged).Handshake()
+	client := tls.Client(c, &tls.Config{ServerName: "example.org", InsecureSkipVerify: true})
+	if err := client.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(keys.String(), "CLIENT_HANDSHAKE_TRAFFIC_SECRET ") {
+		t.Errorf("Expected TLS 1.3 secrets in the key log, got %q", keys.String())
+	}
+}
+
+func TestWithKeyLogSingleConfig(t *testing.T) {
+	cfg := &tls.Config{ServerName: "org"}
+	var keys bytes.Buffer
+	logged := withKeyLog(cfg, []*tls.Config{cfg}, &keys)
+	if logged == cfg || logged.KeyLogWriter == nil || cfg.KeyLogWriter != nil {
+		t.Error("Expected a copy of the config with the key log")
+	}
+}
@@ -0,0 +1,186 @@
+package dnsserver
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// quicHandoverTimeout is how long a stopping server waits for the server that claimed its socket to take
+// it over, before it closes the socket.
+const quicHandoverTimeout = 5 * time.Second
+
+// quicSockets holds the sockets of the running DoQ servers by address. Caddy passes UDP sockets on to
+// the servers of a reloaded Corefile as files, but not the SCION sockets of pan, and no server could
+// take over the connections of its predecessor anyway: they live in its QUIC listener. So the squic
+// server of the new Corefile claims the socket, with the listener on it, when caddy asks it to listen,
+// and takes it over once it serves. The old server then hands its connections over, instead of closing
+// them, see ServerQUIC.Stop.
+var quicSockets = struct {
+	sync.Mutex
+	m map[string]*quicSocket
+}{m: make(map[string]*quicSocket)}
+
+// quicSocket is a packet conn with the QUIC listener on it, served by the servers of consecutive Corefiles.
+type quicSocket struct {
+	conn   net.PacketConn
+	listen quicListener
+	// params are the settings of the listener, the server of a reloaded Corefile can only take it over if
+	// its settings are the same.
+	params string
+
+	// owner is the server accepting the connections, next the one that claimed the socket, adopted is
+	// closed once it took over. They are guarded by quicSockets.
+	owner   *ServerQUIC
+	next    *ServerQUIC
+	adopted chan struct{}
+
+	// tlsConfig is the *tls.Config of the owner, it is handed out per handshake.
+	tlsConfig atomic.Value
+}
+
+// newQUICSocket returns a socket for p owned by s, whose handshakes use tlsConfig. It isn't registered
+// until the listener on it is set and register is called.
+func newQUICSocket(s *ServerQUIC, p net.PacketConn, tlsConfig *tls.Config) *quicSocket {
+	sock := &quicSocket{conn: p, owner: s, params: s.listenerParams()}
+	sock.tlsConfig.Store(tlsConfig)
+	return sock
+}
+
+// listenerTLSConfig returns the TLS config of the QUIC listener, it hands out the config of the current
+// owner, so a server taking over the listener serves new handshakes with its certificates.
+func (sock *quicSocket) listenerTLSConfig() *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			cfg := sock.tlsConfig.Load().(*tls.Config)
+			if cfg.GetConfigForClient != nil {
+				if c, err := cfg.GetConfigForClient(hello); c != nil || err != nil {
+					return c, err
+				}
+			}
+			return cfg, nil
+		},
+	}
+}
+
+// register makes sock the socket of the owner's address, replacing that of a predecessor that didn't
+// hand its socket over, e.g. a quic server whose socket caddy passed on as a file.
+func (sock *quicSocket) register() {
+	quicSockets.Lock()
+	quicSockets.m[sock.owner.Addr] = sock
+	quicSockets.Unlock()
+}
+
+// listenerParams returns the settings of s that end up in its QUIC listener, or its socket.
+func (s *ServerQUIC) listenerParams() string {
+	threshold := -1
+	if s.validator != nil {
+		threshold = s.validator.threshold
+	}
+	qlog := ""
+	if s.qlog != nil {
+		qlog = fmt.Sprintf("%s:%d", s.qlog.dir, s.qlog.maxFiles)
+	}
+	return fmt.Sprintf("%s %s %s %d %v %v %t %d %t %t %q %q %q",
+		s.transport, s.idleTimeout, s.keepAlive, s.maxStreams, s.streamWindow, s.connWindow,
+		s.allow0RTT, threshold, s.telemetry, s.logHandshakes, qlog, s.replySelector, s.replySelectorArgs)
+}
+
+// claimQUICSocket returns the socket of the running server on the address of s, for s to take it over
+// once it serves. It returns nil if there is none, or if its listener has other settings.
+func claimQUICSocket(s *ServerQUIC) *quicSocket {
+	quicSockets.Lock()
+	defer quicSockets.Unlock()
+	sock := quicSockets.m[s.Addr]
+	if sock == nil {
+		return nil
+	}
+	if sock.params != s.listenerParams() {
+		s.log.Infof("QUIC settings changed, DoQ connections are not carried over to the reloaded server")
+		return nil
+	}
+	sock.next = s
+	sock.adopted = make(chan struct{})
+	return sock
+}
+
+// releaseQUICSockets withdraws the claims on the sockets of the running servers, when the servers of a
+// reloaded Corefile failed to start and will never take them over. The running servers keep their
+// sockets, and close them when they stop.
+func releaseQUICSockets() {
+	quicSockets.Lock()
+	defer quicSockets.Unlock()
+	for _, sock := range quicSockets.m {
+		sock.next, sock.adopted = nil, nil
+	}
+}
+
+// takeOverSocket takes over the socket s claimed, if p is its packet conn. From then on, s accepts the
+// connections and serves new handshakes with tlsConfig. It returns nil if s claimed no socket, or if the
+// previous owner gave up waiting for s and closed it.
+func (s *ServerQUIC) takeOverSocket(p net.PacketConn, tlsConfig *tls.Config) *quicSocket {
+	quicSockets.Lock()
+	defer quicSockets.Unlock()
+	sock := quicSockets.m[s.Addr]
+	if sock == nil || sock.next != s || sock.conn != p {
+		return nil
+	}
+	sock.tlsConfig.Store(tlsConfig)
+	sock.owner, sock.next = s, nil
+	close(sock.adopted)
+	return sock
+}
+
+// handOver returns the server that took over the socket of s, waiting for it if it claimed the socket
+// but doesn't serve yet. If no server took over, the socket is unregistered and nil is returned.
+func (s *ServerQUIC) handOver() *ServerQUIC {
+	s.m.Lock()
+	sock := s.socket
+	s.m.Unlock()
+	if sock == nil {
+		return nil
+	}
+
+	quicSockets.Lock()
+	if sock.owner == s && sock.next != nil {
+		adopted := sock.adopted
+		quicSockets.Unlock()
+		select {
+		case <-adopted:
+		case <-time.After(quicHandoverTimeout):
+		}
+		quicSockets.Lock()
+	}
+	defer quicSockets.Unlock()
+	if sock.owner != s {
+		return sock.owner
+	}
+	sock.next = nil
+	if quicSockets.m[s.Addr] == sock {
+		delete(quicSockets.m, s.Addr)
+	}
+	return nil
+}
+
+// successor returns the server that took over the socket of s, once s stopped.
+func (s *ServerQUIC) successor() *ServerQUIC {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.next
+}
+
+// handOverSessions makes the sessions of s stop accepting streams, so the loops accepting them pass
+// them on to the successor. It waits for the streams in flight to finish, or for the drain timeout.
+func (s *ServerQUIC) handOverSessions() {
+	s.sessions.stopAccepting()
+	deadline := time.Now().Add(s.drainTimeout)
+	for len(s.streamWorkers) > 0 && time.Now().Before(deadline) {
+		time.Sleep(drainInterval)
+	}
+	if n := len(s.streamWorkers); n > 0 {
+		s.log.Infof("Handing over the DoQ connections with %d queries in flight after the drain timeout of %s", n, s.drainTimeout)
+	}
+}
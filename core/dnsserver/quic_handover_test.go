@@ -0,0 +1,139 @@
+package dnsserver
+
+import (
+	"crypto/tls"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+)
+
+func handoverServer(idleTimeout time.Duration) *ServerQUIC {
+	return &ServerQUIC{
+		Server:      &Server{Addr: "squic://:8853"},
+		transport:   "squic",
+		log:         clog.NewWithServer("squic://:8853"),
+		idleTimeout: idleTimeout,
+		sessions:    newQUICSessions(),
+		stop:        make(chan struct{}),
+	}
+}
+
+func TestQUICSocketHandover(t *testing.T) {
+	p, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	old := handoverServer(DefaultQUICIdleTimeout)
+	oldTLS := &tls.Config{ServerName: "old"}
+	sock := newQUICSocket(old, p, oldTLS)
+	sock.register()
+	old.socket = sock
+	defer func() {
+		quicSockets.Lock()
+		delete(quicSockets.m, old.Addr)
+		quicSockets.Unlock()
+	}()
+
+	listenerTLS := sock.listenerTLSConfig()
+	if cfg, _ := listenerTLS.GetConfigForClient(&tls.ClientHelloInfo{}); cfg != oldTLS {
+		t.Fatalf("Expected the TLS config of the owner, got %q", cfg.ServerName)
+	}
+
+	// Other QUIC settings need another listener.
+	if claimQUICSocket(handoverServer(time.Minute)) != nil {
+		t.Fatal("Expected a server with another idle timeout not to claim the socket")
+	}
+
+	next := handoverServer(DefaultQUICIdleTimeout)
+	if claimQUICSocket(next) != sock {
+		t.Fatal("Expected the server of the reloaded Corefile to claim the socket")
+	}
+	other, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if next.takeOverSocket(other, nil) != nil {
+		t.Fatal("Expected a server serving another packet conn not to take over the socket")
+	}
+	nextTLS := &tls.Config{ServerName: "next"}
+	if next.takeOverSocket(p, nextTLS) != sock {
+		t.Fatal("Expected the server of the reloaded Corefile to take over the socket")
+	}
+	next.socket = sock
+	if cfg, _ := listenerTLS.GetConfigForClient(&tls.ClientHelloInfo{}); cfg != nextTLS {
+		t.Errorf("Expected the TLS config of the new owner, got %q", cfg.ServerName)
+	}
+	if s := old.handOver(); s != next {
+		t.Errorf("Expected the old server to hand over to the new one, got %v", s)
+	}
+
+	// Without a reload the socket is released.
+	if s := next.handOver(); s != nil {
+		t.Errorf("Expected no successor, got %v", s)
+	}
+	quicSockets.Lock()
+	defer quicSockets.Unlock()
+	if _, ok := quicSockets.m[old.Addr]; ok {
+		t.Error("Expected the socket to be unregistered")
+	}
+}
+
+func TestQUICSocketFailedReload(t *testing.T) {
+	p, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	i := &caddy.Instance{}
+	newContext(i)
+
+	old := handoverServer(DefaultQUICIdleTimeout)
+	sock := newQUICSocket(old, p, nil)
+	sock.register()
+	old.socket = sock
+	defer func() {
+		quicSockets.Lock()
+		delete(quicSockets.m, old.Addr)
+		quicSockets.Unlock()
+	}()
+
+	// The server of the reloaded Corefile claims the socket, but another server of it fails to listen.
+	next := handoverServer(DefaultQUICIdleTimeout)
+	if claimQUICSocket(next) != sock {
+		t.Fatal("Expected the server of the reloaded Corefile to claim the socket")
+	}
+	runHooks(t, i.OnRestartFailed)
+	if next.takeOverSocket(p, nil) != nil {
+		t.Error("Expected the server of the failed reload not to take over the socket")
+	}
+
+	start := time.Now()
+	if err := old.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d >= quicHandoverTimeout {
+		t.Errorf("Expected Stop to return at once, took %s", d)
+	}
+	if s := old.successor(); s != nil {
+		t.Errorf("Expected no successor, got %v", s)
+	}
+}
+
+func TestQUICSessionsStopAccepting(t *testing.T) {
+	sessions := newQUICSessions()
+	qs := sessions.add(nil, time.Now())
+	if qs.accept.Err() != nil {
+		t.Fatal("Expected a new session to accept streams")
+	}
+	sessions.stopAccepting()
+	if qs.accept.Err() == nil {
+		t.Error("Expected the session to stop accepting streams")
+	}
+}
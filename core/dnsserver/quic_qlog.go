@@ -0,0 +1,101 @@
+package dnsserver
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+
+	"github.com/quic-go/quic-go/logging"
+	"github.com/quic-go/quic-go/qlog"
+)
+
+// qlogExt is the extension of the qlog files.
+const qlogExt = ".qlog"
+
+// qlogDir writes a qlog file for every connection of a DoQ server into dir, which can be analyzed with
+// the usual QUIC tooling, e.g. qvis. If maxFiles is not 0, only the newest maxFiles files are kept.
+type qlogDir struct {
+	dir       string
+	maxFiles  int
+	transport string
+	log       clog.P
+
+	mu sync.Mutex // serializes the rotation
+}
+
+// tracer returns the logging.Tracer writing the qlog files.
+func (q *qlogDir) tracer() logging.Tracer { return qlog.NewTracer(q.create) }
+
+// create returns the file for the qlog of the connection with the original destination connection ID
+// odcid. It returns nil, which disables the qlog for the connection, if the file can't be created.
+func (q *qlogDir) create(p logging.Perspective, odcid []byte) io.WriteCloser {
+	// The timestamp first, so the names sort by age.
+	name := fmt.Sprintf("%s_%s_%x_%s%s", time.Now().UTC().Format("20060102T150405.000000000"), q.transport, odcid, perspective(p), qlogExt)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	f, err := os.Create(filepath.Join(q.dir, name))
+	if err != nil {
+		q.log.Warningf("Failed to create qlog file: %s", err)
+		return nil
+	}
+	q.rotate()
+	return &qlogFile{Writer: bufio.NewWriter(f), f: f}
+}
+
+// rotate removes the oldest qlog files, so at most q.maxFiles are left. q.mu must be held.
+func (q *qlogDir) rotate() {
+	if q.maxFiles == 0 {
+		return
+	}
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		q.log.Warningf("Failed to read qlog directory: %s", err)
+		return
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && strings.HasSuffix(e.Name(), qlogExt) {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) <= q.maxFiles {
+		return
+	}
+	sort.Strings(names)
+	for _, name := range names[:len(names)-q.maxFiles] {
+		if err := os.Remove(filepath.Join(q.dir, name)); err != nil {
+			q.log.Warningf("Failed to remove qlog file: %s", err)
+		}
+	}
+}
+
+func perspective(p logging.Perspective) string {
+	if p == logging.PerspectiveClient {
+		return "client"
+	}
+	return "server"
+}
+
+// qlogFile buffers the writes to a qlog file, qlog writes every event on its own.
+type qlogFile struct {
+	*bufio.Writer
+	f *os.File
+}
+
+// Close implements io.Closer.
+func (q *qlogFile) Close() error {
+	if err := q.Flush(); err != nil {
+		q.f.Close()
+		return err
+	}
+	return q.f.Close()
+}
@@ -0,0 +1,51 @@
+package dnsserver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+
+	"github.com/quic-go/quic-go/logging"
+)
+
+func TestQlogDir(t *testing.T) {
+	dir := t.TempDir()
+	q := &qlogDir{dir: dir, maxFiles: 2, transport: "squic", log: clog.NewWithServer("squic://:8853")}
+
+	var names []string
+	for _, odcid := range [][]byte{{1}, {2}, {3}} {
+		w := q.create(logging.PerspectiveServer, odcid)
+		if w == nil {
+			t.Fatal("Expected a qlog file, got none")
+		}
+		if _, err := w.Write([]byte("{}\n")); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		files, _ := filepath.Glob(filepath.Join(dir, "*"+qlogExt))
+		names = files
+	}
+
+	if len(names) != 2 {
+		t.Fatalf("Expected 2 qlog files, got %d", len(names))
+	}
+	for i, suffix := range []string{"_squic_02_server.qlog", "_squic_03_server.qlog"} {
+		if !strings.HasSuffix(names[i], suffix) {
+			t.Errorf("Expected file %d to end in %s, got %s", i, suffix, names[i])
+		}
+	}
+	if b, _ := os.ReadFile(names[1]); string(b) != "{}\n" {
+		t.Errorf("Expected the file to be flushed, got %q", b)
+	}
+
+	// A missing directory disables the qlog of the connection.
+	q.dir = filepath.Join(dir, "missing")
+	if w := q.create(logging.PerspectiveServer, []byte{4}); w != nil {
+		t.Error("Expected no qlog file in a missing directory")
+	}
+}
@@ -0,0 +1,103 @@
+package dnsserver
+
+import (
+	"context"
+
+	"github.com/miekg/dns"
+)
+
+// How quic and squic servers treat queries without the RD bit, see Config.QUIC.NonRecursive.
+const (
+	// NonRecursiveRecurse ignores the RD bit, queries are answered as usual. This is the default.
+	NonRecursiveRecurse = "recurse"
+	// NonRecursiveLocal answers from caches and authoritative data only, queries are not forwarded.
+	NonRecursiveLocal = "local"
+	// NonRecursiveRefuse refuses queries without the RD bit.
+	NonRecursiveRefuse = "refuse"
+)
+
+// How quic and squic servers treat queries with the CD bit, see Config.QUIC.CheckingDisabled.
+const (
+	// CheckingDisabledPass passes the CD bit on. This is the default.
+	CheckingDisabledPass = "pass"
+	// CheckingDisabledClear clears the CD bit, so clients can't turn off validation upstream.
+	CheckingDisabledClear = "clear"
+	// CheckingDisabledNoCache passes the CD bit on and answers the query without caches.
+	CheckingDisabledNoCache = "no_cache"
+)
+
+// NoCacheCode is the EDNS0 option code, from the range for local use, with which clients ask
+// quic and squic servers to bypass caches. The option has no data.
+const NoCacheCode = dns.EDNS0LOCALSTART + 2
+
+// NoRecursionKey is the context key that is set for queries that must not be forwarded.
+type NoRecursionKey struct{}
+
+// NoCacheKey is the context key that is set for queries that must not be answered from, or
+// stored in, caches.
+type NoCacheKey struct{}
+
+// NoRecursion returns true if the query in ctx must not be forwarded.
+func NoRecursion(ctx context.Context) bool {
+	no, _ := ctx.Value(NoRecursionKey{}).(bool)
+	return no
+}
+
+// NoCache returns true if the query in ctx must bypass caches.
+func NoCache(ctx context.Context) bool {
+	no, _ := ctx.Value(NoCacheKey{}).(bool)
+	return no
+}
+
+// queryPolicy is how a DoQ server treats the RD and CD bits and the no-cache option.
+type queryPolicy struct {
+	nonRecursive     string
+	checkingDisabled string
+	cacheBypass      bool
+}
+
+// apply prepares m and ctx for the plugins. It returns false if m must be refused.
+func (p queryPolicy) apply(ctx context.Context, m *dns.Msg) (context.Context, bool) {
+	noCache := removeNoCache(m) && p.cacheBypass
+
+	if !m.RecursionDesired {
+		switch p.nonRecursive {
+		case NonRecursiveRefuse:
+			return ctx, false
+		case NonRecursiveLocal:
+			ctx = context.WithValue(ctx, NoRecursionKey{}, true)
+		}
+	}
+	if m.CheckingDisabled {
+		switch p.checkingDisabled {
+		case CheckingDisabledClear:
+			m.CheckingDisabled = false
+		case CheckingDisabledNoCache:
+			noCache = true
+		}
+	}
+	if noCache {
+		ctx = context.WithValue(ctx, NoCacheKey{}, true)
+	}
+	return ctx, true
+}
+
+// removeNoCache removes the no-cache option from m and returns true if it was present. It is
+// always removed, so it isn't passed on upstream.
+func removeNoCache(m *dns.Msg) bool {
+	o := m.IsEdns0()
+	if o == nil {
+		return false
+	}
+	found := false
+	opts := o.Option[:0]
+	for _, opt := range o.Option {
+		if l, ok := opt.(*dns.EDNS0_LOCAL); ok && l.Code == NoCacheCode {
+			found = true
+			continue
+		}
+		opts = append(opts, opt)
+	}
+	o.Option = opts
+	return found
+}
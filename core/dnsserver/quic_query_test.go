@@ -0,0 +1,65 @@
+package dnsserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestQueryPolicy(t *testing.T) {
+	query := func(rd, cd, noCache bool) *dns.Msg {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		m.RecursionDesired = rd
+		m.CheckingDisabled = cd
+		if noCache {
+			m.SetEdns0(4096, false)
+			o := m.IsEdns0()
+			o.Option = append(o.Option, &dns.EDNS0_LOCAL{Code: NoCacheCode})
+		}
+		return m
+	}
+
+	tests := []struct {
+		policy      queryPolicy
+		m           *dns.Msg
+		ok          bool
+		noRecursion bool
+		noCache     bool
+		cd          bool
+	}{
+		{queryPolicy{}, query(false, true, true), true, false, false, true},
+		{queryPolicy{nonRecursive: NonRecursiveRecurse}, query(false, false, false), true, false, false, false},
+		{queryPolicy{nonRecursive: NonRecursiveLocal}, query(false, false, false), true, true, false, false},
+		{queryPolicy{nonRecursive: NonRecursiveLocal}, query(true, false, false), true, false, false, false},
+		{queryPolicy{nonRecursive: NonRecursiveRefuse}, query(false, false, false), false, false, false, false},
+		{queryPolicy{nonRecursive: NonRecursiveRefuse}, query(true, false, false), true, false, false, false},
+		{queryPolicy{checkingDisabled: CheckingDisabledClear}, query(true, true, false), true, false, false, false},
+		{queryPolicy{checkingDisabled: CheckingDisabledNoCache}, query(true, true, false), true, false, true, true},
+		{queryPolicy{cacheBypass: true}, query(true, false, true), true, false, true, false},
+		{queryPolicy{cacheBypass: true}, query(true, false, false), true, false, false, false},
+	}
+	for i, tc := range tests {
+		ctx, ok := tc.policy.apply(context.Background(), tc.m)
+		if ok != tc.ok {
+			t.Errorf("Test %d: expected ok %t, got %t", i, tc.ok, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if NoRecursion(ctx) != tc.noRecursion {
+			t.Errorf("Test %d: expected no recursion %t, got %t", i, tc.noRecursion, NoRecursion(ctx))
+		}
+		if NoCache(ctx) != tc.noCache {
+			t.Errorf("Test %d: expected no cache %t, got %t", i, tc.noCache, NoCache(ctx))
+		}
+		if tc.m.CheckingDisabled != tc.cd {
+			t.Errorf("Test %d: expected CD %t, got %t", i, tc.cd, tc.m.CheckingDisabled)
+		}
+		if o := tc.m.IsEdns0(); o != nil && len(o.Option) != 0 {
+			t.Errorf("Test %d: expected the no-cache option to be removed", i)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package dnsserver
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+// QUICRate is a rate limit of QPS queries per second, with bursts of up to Burst queries.
+type QUICRate struct {
+	QPS   float64
+	Burst int
+}
+
+// tokenBucket holds the queries a client may still send, it refills at the rate of its limit.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket for the time passed since the last call and takes a token, if there is one.
+func (b *tokenBucket) take(rate QUICRate, now time.Time) bool {
+	if b.last.IsZero() {
+		b.tokens = float64(rate.Burst)
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * rate.QPS
+		if b.tokens > float64(rate.Burst) {
+			b.tokens = float64(rate.Burst)
+		}
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// full returns true if the bucket refilled completely by now, it then is the same as a new one.
+func (b *tokenBucket) full(rate QUICRate, now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*rate.QPS >= float64(rate.Burst)
+}
+
+// sourceLimiter rate limits the queries per source, the ISD-AS for SCION clients and the IP address
+// for all others.
+type sourceLimiter struct {
+	rate QUICRate
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func newSourceLimiter(rate QUICRate) *sourceLimiter {
+	return &sourceLimiter{rate: rate, buckets: make(map[string]*tokenBucket)}
+}
+
+// sweepInterval is how often the buckets that refilled completely are dropped.
+const sweepInterval = time.Minute
+
+// allow returns true if a query from addr is within the limit.
+func (l *sourceLimiter) allow(addr net.Addr, now time.Time) bool {
+	key := sourceKey(addr)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastSweep) > sweepInterval {
+		for k, b := range l.buckets {
+			if b.full(l.rate, now) {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{}
+		l.buckets[key] = b
+	}
+	return b.take(l.rate, now)
+}
+
+// sourceKey returns the ISD-AS of SCION addresses and the IP of all others.
+func sourceKey(addr net.Addr) string {
+	if a, ok := addr.(pan.UDPAddr); ok {
+		return a.IA.String()
+	}
+	if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+		return host
+	}
+	return addr.String()
+}
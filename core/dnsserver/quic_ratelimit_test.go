@@ -0,0 +1,68 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+func TestTokenBucket(t *testing.T) {
+	rate := QUICRate{QPS: 2, Burst: 3}
+	var b tokenBucket
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		at       time.Time
+		expected bool
+	}{
+		// The burst is available right away.
+		{now, true},
+		{now, true},
+		{now, true},
+		{now, false},
+		// Two queries per second refill a token every 500ms.
+		{now.Add(400 * time.Millisecond), false},
+		{now.Add(500 * time.Millisecond), true},
+		{now.Add(600 * time.Millisecond), false},
+		// The bucket never holds more than the burst.
+		{now.Add(time.Minute), true},
+		{now.Add(time.Minute), true},
+		{now.Add(time.Minute), true},
+		{now.Add(time.Minute), false},
+	}
+	for i, tc := range tests {
+		if got := b.take(rate, tc.at); got != tc.expected {
+			t.Errorf("Test %d: expected %t, got %t", i, tc.expected, got)
+		}
+	}
+}
+
+func TestSourceLimiter(t *testing.T) {
+	l := newSourceLimiter(QUICRate{QPS: 1, Burst: 1})
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	a1, _ := pan.ParseUDPAddr("19-ffaa:1:1067,[10.0.0.1]:40000")
+	a2, _ := pan.ParseUDPAddr("19-ffaa:1:1067,[10.0.0.2]:40000")
+	b, _ := pan.ParseUDPAddr("19-ffaa:1:1068,[10.0.0.1]:40000")
+	ip := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 40000}
+
+	if !l.allow(a1, now) {
+		t.Errorf("Expected first query from %s to be allowed", a1)
+	}
+	if l.allow(a2, now) {
+		t.Errorf("Expected query from %s to be limited, it is in the same AS as %s", a2, a1)
+	}
+	if !l.allow(b, now) {
+		t.Errorf("Expected first query from %s to be allowed", b)
+	}
+	if !l.allow(ip, now) {
+		t.Errorf("Expected first query from %s to be allowed", ip)
+	}
+
+	// Buckets that refilled are dropped.
+	l.allow(ip, now.Add(2*sweepInterval))
+	if len(l.buckets) != 1 {
+		t.Errorf("Expected 1 bucket after the sweep, got %d", len(l.buckets))
+	}
+}
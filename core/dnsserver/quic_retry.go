@@ -0,0 +1,48 @@
+package dnsserver
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/metrics/vars"
+)
+
+// addressValidator decides whether a client must prove it owns its address, with a QUIC Retry,
+// before the server answers its Initial with the much larger handshake. Without this, spoofed
+// sources can use the server to amplify traffic towards their victims.
+type addressValidator struct {
+	server string
+	// threshold is the number of new connections per second above which addresses are validated,
+	// 0 validates all of them.
+	threshold int
+
+	mu     sync.Mutex
+	second time.Time
+	n      int
+}
+
+// RequireAddressValidation is used as the quic.Config callback of the same name.
+func (v *addressValidator) RequireAddressValidation(net.Addr) bool {
+	if v.require(time.Now()) {
+		vars.QUICRetryCount.WithLabelValues(v.server).Inc()
+		return true
+	}
+	return false
+}
+
+// require counts a new connection at now and returns true if it must be validated.
+func (v *addressValidator) require(now time.Time) bool {
+	if v.threshold == 0 {
+		return true
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	now = now.Truncate(time.Second)
+	if !now.Equal(v.second) {
+		v.second = now
+		v.n = 0
+	}
+	v.n++
+	return v.n > v.threshold
+}
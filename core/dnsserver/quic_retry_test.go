@@ -0,0 +1,34 @@
+package dnsserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAddressValidator(t *testing.T) {
+	always := &addressValidator{}
+	if !always.require(time.Now()) {
+		t.Errorf("Expected validation without a threshold")
+	}
+
+	v := &addressValidator{threshold: 2}
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		at       time.Time
+		expected bool
+	}{
+		{now, false},
+		{now.Add(100 * time.Millisecond), false},
+		{now.Add(200 * time.Millisecond), true},
+		{now.Add(900 * time.Millisecond), true},
+		// A new second starts counting from zero.
+		{now.Add(time.Second), false},
+		{now.Add(1500 * time.Millisecond), false},
+		{now.Add(1600 * time.Millisecond), true},
+	}
+	for i, tc := range tests {
+		if got := v.require(tc.at); got != tc.expected {
+			t.Errorf("Test %d: expected %t, got %t", i, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package dnsserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/doqclient"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+	"inet.af/netaddr"
+)
+
+// selfCheckTimeout bounds the time the self check may take, including the handshake.
+const selfCheckTimeout = 10 * time.Second
+
+// selfCheckGrace is how much longer than selfCheckTimeout the startup waits for the self check, for
+// the server to create its listener.
+const selfCheckGrace = 5 * time.Second
+
+// runSelfCheck dials the server's own listener, over SCION for squic, and sends a SOA query for the
+// first zone. The result and latency, and for squic the path, are logged, so a broken SCION stack or a certificate mismatch
+// is detected at startup and not by the first external client. With selfCheckFail, a failed check
+// also marks the endpoint as failed, see OnStartupComplete for what happens at startup.
+func (s *ServerQUIC) runSelfCheck() {
+	defer close(s.selfChecked)
+
+	s.m.Lock()
+	addr := selfCheckAddr(s.listenAddr)
+	s.m.Unlock()
+
+	rtt, path, err := s.selfCheckQuery(addr)
+	if err != nil {
+		s.log.Errorf("Self check of %s://%s failed%s: %s", s.transport, addr, selfCheckPath(path), err)
+		s.selfCheckErr = fmt.Errorf("self check failed: %w", err)
+		if s.selfCheckFail {
+			failEndpoint(s, s.selfCheckErr)
+		}
+		return
+	}
+	s.log.Infof("Self check of %s://%s succeeded in %s%s", s.transport, addr, rtt, selfCheckPath(path))
+}
+
+// selfCheckPath describes the SCION path p the self check used, for the log: its ASes and interfaces,
+// if known, and its fingerprint. It's empty if there is no path, e.g. for quic.
+func selfCheckPath(p *pan.Path) string {
+	switch {
+	case p == nil:
+		return ""
+	case p.Metadata != nil && len(p.Metadata.Interfaces) > 0:
+		return fmt.Sprintf(" on path %s (%x)", p, string(p.Fingerprint))
+	case p.Fingerprint != "":
+		return fmt.Sprintf(" on path %x", string(p.Fingerprint))
+	}
+	return " on the empty path"
+}
+
+// selfCheckResult waits for the self check and returns its error. If the server doesn't run the check
+// in time, e.g. because it failed to create its listener, that is an error as well.
+func (s *ServerQUIC) selfCheckResult() error {
+	select {
+	case <-s.selfChecked:
+		return s.selfCheckErr
+	case <-time.After(selfCheckTimeout + selfCheckGrace):
+		return errors.New("self check didn't complete, the server may not be serving")
+	}
+}
+
+// selfCheckQuery queries the listener on addr, and returns how long the exchange took and, for squic, the
+// path it was sent on. Errors tell whether the handshake or the query failed; over SCION, the handshake
+// errors are classified, so an unreachable SCION daemon can be told apart from a certificate mismatch.
+func (s *ServerQUIC) selfCheckQuery(addr string) (time.Duration, *pan.Path, error) {
+	policy, err := pkgscion.Get().PathPolicy()
+	if err != nil {
+		return 0, nil, fmt.Errorf("path policy: %w", err)
+	}
+	c := doqclient.New(s.transport, s.selfCheckTLSConfig())
+	c.Policy = policy
+	// Our own selector, to learn the path the connection uses.
+	var selector *pan.DefaultSelector
+	if s.transport == transport.SQUIC {
+		selector = pan.NewDefaultSelector()
+		c.Selector = func() pan.Selector { return selector }
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(s.firstZone(), dns.TypeSOA)
+
+	ctx, cancel := context.WithTimeout(context.Background(), selfCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	conn, err := c.Dial(ctx, addr)
+	if err != nil {
+		return 0, nil, fmt.Errorf("handshake: %w", err)
+	}
+	defer conn.Close()
+	var path *pan.Path
+	if selector != nil {
+		path = selector.Path()
+	}
+	// Any response will do, we're interested in the transport, not the answer.
+	if _, err := conn.Exchange(ctx, m); err != nil {
+		return 0, path, fmt.Errorf("query: %w", err)
+	}
+	return time.Since(start), path, nil
+}
+
+// selfCheckTLSConfig returns the TLS config for the self check. The presented certificate must be the
+// configured one, and be valid for selfCheckName if that is set. Our certificate is presented as client
+// certificate as well, for listeners that require one.
+func (s *ServerQUIC) selfCheckTLSConfig() *tls.Config {
+	name := s.selfCheckName
+	own := serverCertificates(s.tlsConfigFor(name))
+	return &tls.Config{
+		ServerName:         name,
+		NextProtos:         s.tlsConfig.NextProtos,
+		Certificates:       own,
+		InsecureSkipVerify: true, // We verify ourselves in VerifyConnection.
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("no certificate presented")
+			}
+			leaf := cs.PeerCertificates[0]
+			if len(own) > 0 && len(own[0].Certificate) > 0 && !bytes.Equal(own[0].Certificate[0], leaf.Raw) {
+				return errors.New("presented certificate is not the configured one")
+			}
+			if name != "" {
+				return leaf.VerifyHostname(name)
+			}
+			return nil
+		},
+	}
+}
+
+// firstZone returns the alphabetically first zone served by s.
+func (s *ServerQUIC) firstZone() string {
+	zones := make([]string, 0, len(s.zones))
+	for z := range s.zones {
+		zones = append(zones, z)
+	}
+	if len(zones) == 0 {
+		return "."
+	}
+	sort.Strings(zones)
+	return zones[0]
+}
+
+// selfCheckAddr returns the address to dial to reach the listener bound to a. An unspecified IP, of a UDP
+// or a SCION listener, is replaced by the loopback address.
+func selfCheckAddr(a net.Addr) string {
+	switch u := a.(type) {
+	case *net.UDPAddr:
+		if u.IP == nil || u.IP.IsUnspecified() {
+			return net.JoinHostPort("127.0.0.1", strconv.Itoa(u.Port))
+		}
+	case pan.UDPAddr:
+		if u.IP.IsZero() || u.IP.IsUnspecified() {
+			u.IP = netaddr.IPv4(127, 0, 0, 1)
+			return u.String()
+		}
+	}
+	return a.String()
+}
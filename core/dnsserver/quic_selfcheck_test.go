@@ -0,0 +1,81 @@
+package dnsserver
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+func TestSelfCheckResult(t *testing.T) {
+	s := &ServerQUIC{selfChecked: make(chan struct{})}
+	close(s.selfChecked)
+	if err := s.selfCheckResult(); err != nil {
+		t.Errorf("Expected no error for a passed self check, got %s", err)
+	}
+
+	failed := errors.New("self check failed: handshake: timeout")
+	s = &ServerQUIC{selfChecked: make(chan struct{}), selfCheckErr: failed}
+	close(s.selfChecked)
+	if err := s.selfCheckResult(); err != failed {
+		t.Errorf("Expected the error of the self check, got %v", err)
+	}
+}
+
+func TestSelfCheckAddr(t *testing.T) {
+	mustParse := func(s string) pan.UDPAddr {
+		a, err := pan.ParseUDPAddr(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+
+	tests := []struct {
+		addr     net.Addr
+		expected string
+	}{
+		{&net.UDPAddr{Port: 853}, "127.0.0.1:853"},
+		{&net.UDPAddr{IP: net.IPv6unspecified, Port: 853}, "127.0.0.1:853"},
+		{&net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 853}, "10.0.0.1:853"},
+		{mustParse("19-ffaa:1:1067,[0.0.0.0]:8853"), "19-ffaa:1:1067,127.0.0.1:8853"},
+		{mustParse("19-ffaa:1:1067,[::]:8853"), "19-ffaa:1:1067,127.0.0.1:8853"},
+		{pan.UDPAddr{IA: mustParse("19-ffaa:1:1067,[10.0.0.1]:8853").IA, Port: 8853}, "19-ffaa:1:1067,127.0.0.1:8853"},
+		{mustParse("19-ffaa:1:1067,[10.0.0.1]:8853"), "19-ffaa:1:1067,10.0.0.1:8853"},
+	}
+	for i, tc := range tests {
+		if addr := selfCheckAddr(tc.addr); addr != tc.expected {
+			t.Errorf("Test %d: expected %s, got %s", i, tc.expected, addr)
+		}
+	}
+}
+
+func TestSelfCheckPath(t *testing.T) {
+	ia, err := pan.ParseIA("19-ffaa:1:1067")
+	if err != nil {
+		t.Fatal(err)
+	}
+	core, err := pan.ParseIA("19-ffaa:0:1301")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     *pan.Path
+		expected string
+	}{
+		{nil, ""},
+		{&pan.Path{}, " on the empty path"},
+		{&pan.Path{Fingerprint: "1 2"}, " on path 312032"},
+		{&pan.Path{
+			Fingerprint: "1 2",
+			Metadata:    &pan.PathMetadata{Interfaces: []pan.PathInterface{{IA: ia, IfID: 1}, {IA: core, IfID: 2}}},
+		}, " on path 19-ffaa:1:1067 1>2 19-ffaa:0:1301 (312032)"},
+	}
+	for i, tc := range tests {
+		if s := selfCheckPath(tc.path); s != tc.expected {
+			t.Errorf("Test %d: expected %q, got %q", i, tc.expected, s)
+		}
+	}
+}
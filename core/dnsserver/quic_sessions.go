@@ -0,0 +1,132 @@
+package dnsserver
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ot "github.com/opentracing/opentracing-go"
+	"github.com/quic-go/quic-go"
+)
+
+// quicSession tracks the activity of a single DoQ connection.
+type quicSession struct {
+	created    time.Time
+	lastActive int64 // unix nano time of the last stream activity, atomic
+	streams    int32 // number of open streams, atomic
+
+	// limit rate limits the streams of the connection, it is only used by the loop accepting them.
+	limit tokenBucket
+
+	// accept is the context the streams are accepted with, it is canceled to hand the connection over to
+	// the server of a reloaded Corefile.
+	accept        context.Context
+	stopAccepting context.CancelFunc
+
+	// span is the trace span of the connection, started with its first traced stream, see sessionSpan.
+	spanMu sync.Mutex
+	span   ot.Span
+}
+
+func (qs *quicSession) streamStarted() {
+	atomic.AddInt32(&qs.streams, 1)
+	atomic.StoreInt64(&qs.lastActive, time.Now().UnixNano())
+}
+
+func (qs *quicSession) streamDone() {
+	atomic.StoreInt64(&qs.lastActive, time.Now().UnixNano())
+	atomic.AddInt32(&qs.streams, -1)
+}
+
+// idleFor returns for how long the session hasn't had any open streams, it is 0 when streams are open.
+func (qs *quicSession) idleFor(now time.Time) time.Duration {
+	if atomic.LoadInt32(&qs.streams) > 0 {
+		return 0
+	}
+	return now.Sub(time.Unix(0, atomic.LoadInt64(&qs.lastActive)))
+}
+
+// quicSessions holds the open sessions of a DoQ server.
+type quicSessions struct {
+	sync.RWMutex
+	m map[quic.Connection]*quicSession
+}
+
+func newQUICSessions() *quicSessions {
+	return &quicSessions{m: make(map[quic.Connection]*quicSession)}
+}
+
+// add starts tracking session, which was accepted at created.
+func (q *quicSessions) add(session quic.Connection, created time.Time) *quicSession {
+	qs := &quicSession{created: created, lastActive: time.Now().UnixNano()}
+	qs.accept, qs.stopAccepting = context.WithCancel(context.Background())
+	q.Lock()
+	q.m[session] = qs
+	q.Unlock()
+	return qs
+}
+
+// remove stops tracking session.
+func (q *quicSessions) remove(session quic.Connection) {
+	q.Lock()
+	if qs, ok := q.m[session]; ok {
+		qs.stopAccepting()
+		delete(q.m, session)
+	}
+	q.Unlock()
+}
+
+// stopAccepting makes all sessions stop accepting streams.
+func (q *quicSessions) stopAccepting() {
+	q.RLock()
+	for _, qs := range q.m {
+		qs.stopAccepting()
+	}
+	q.RUnlock()
+}
+
+// len returns the number of tracked sessions.
+func (q *quicSessions) len() int {
+	q.RLock()
+	defer q.RUnlock()
+	return len(q.m)
+}
+
+// idle returns the sessions that haven't had any open streams for at least d.
+func (q *quicSessions) idle(d time.Duration) []quic.Connection {
+	now := time.Now()
+	var sessions []quic.Connection
+	q.RLock()
+	for session, qs := range q.m {
+		if qs.idleFor(now) >= d {
+			sessions = append(sessions, session)
+		}
+	}
+	q.RUnlock()
+	return sessions
+}
+
+// withoutStreams returns the sessions that have no open streams.
+func (q *quicSessions) withoutStreams() []quic.Connection {
+	var sessions []quic.Connection
+	q.RLock()
+	for session, qs := range q.m {
+		if atomic.LoadInt32(&qs.streams) == 0 {
+			sessions = append(sessions, session)
+		}
+	}
+	q.RUnlock()
+	return sessions
+}
+
+// all returns all sessions.
+func (q *quicSessions) all() []quic.Connection {
+	q.RLock()
+	sessions := make([]quic.Connection, 0, len(q.m))
+	for session := range q.m {
+		sessions = append(sessions, session)
+	}
+	q.RUnlock()
+	return sessions
+}
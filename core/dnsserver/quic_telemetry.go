@@ -0,0 +1,112 @@
+package dnsserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/coredns/coredns/plugin/metrics/vars"
+
+	"github.com/quic-go/quic-go/logging"
+)
+
+// quicTelemetry is a logging.Tracer that aggregates selected events of the connections of a DoQ server into
+// metrics, so they can be watched without collecting qlog traces: the RTT once the handshake is confirmed, lost
+// packets and congestion recoveries, and path validation frames, which show client migrations.
+type quicTelemetry struct {
+	logging.NullTracer
+	server string
+}
+
+// TracerForConnection implements logging.Tracer.
+func (t *quicTelemetry) TracerForConnection(context.Context, logging.Perspective, logging.ConnectionID) logging.ConnectionTracer {
+	return &connTelemetry{server: t.server}
+}
+
+// connTelemetry traces a single connection. quic-go calls it from the connection's run loop only, so
+// it doesn't need locking.
+type connTelemetry struct {
+	logging.NullConnectionTracer
+	server string
+
+	rtt       float64 // smoothed RTT in seconds, 0 until there is a sample
+	recovered bool    // whether the connection currently is in congestion recovery
+}
+
+// UpdatedMetrics implements logging.ConnectionTracer.
+func (c *connTelemetry) UpdatedMetrics(rttStats *logging.RTTStats, _, _ logging.ByteCount, _ int) {
+	c.rtt = rttStats.SmoothedRTT().Seconds()
+}
+
+// DroppedEncryptionLevel implements logging.ConnectionTracer. The handshake keys are dropped once the
+// handshake is confirmed.
+func (c *connTelemetry) DroppedEncryptionLevel(level logging.EncryptionLevel) {
+	if level == logging.EncryptionHandshake && c.rtt > 0 {
+		vars.QUICHandshakeRTT.WithLabelValues(c.server).Observe(c.rtt)
+	}
+}
+
+// LostPacket implements logging.ConnectionTracer.
+func (c *connTelemetry) LostPacket(logging.EncryptionLevel, logging.PacketNumber, logging.PacketLossReason) {
+	vars.QUICPacketsLostCount.WithLabelValues(c.server).Inc()
+}
+
+// UpdatedCongestionState implements logging.ConnectionTracer.
+func (c *connTelemetry) UpdatedCongestionState(state logging.CongestionState) {
+	recovery := state == logging.CongestionStateRecovery
+	if recovery && !c.recovered {
+		vars.QUICCongestionRecoveryCount.WithLabelValues(c.server).Inc()
+	}
+	c.recovered = recovery
+}
+
+// SentShortHeaderPacket implements logging.ConnectionTracer.
+func (c *connTelemetry) SentShortHeaderPacket(_ *logging.ShortHeader, _ logging.ByteCount, _ *logging.AckFrame, frames []logging.Frame) {
+	c.pathValidation(frames, "sent")
+}
+
+// ReceivedShortHeaderPacket implements logging.ConnectionTracer.
+func (c *connTelemetry) ReceivedShortHeaderPacket(_ *logging.ShortHeader, _ logging.ByteCount, frames []logging.Frame) {
+	c.pathValidation(frames, "received")
+}
+
+// pathValidation counts the path validation frames in frames.
+func (c *connTelemetry) pathValidation(frames []logging.Frame, direction string) {
+	for _, f := range frames {
+		switch f.(type) {
+		case *logging.PathChallengeFrame:
+			vars.QUICPathValidationCount.WithLabelValues(c.server, "path_challenge", direction).Inc()
+		case *logging.PathResponseFrame:
+			vars.QUICPathValidationCount.WithLabelValues(c.server, "path_response", direction).Inc()
+		}
+	}
+}
+
+// quicHandshakeTimer is a logging.Tracer that measures how long the handshakes of a DoQ server take. Unlike
+// quicTelemetry it is always installed, it only looks at the start and the end of the handshake.
+type quicHandshakeTimer struct {
+	logging.NullTracer
+	server    string
+	transport string
+}
+
+// TracerForConnection implements logging.Tracer.
+func (t *quicHandshakeTimer) TracerForConnection(context.Context, logging.Perspective, logging.ConnectionID) logging.ConnectionTracer {
+	return &connHandshakeTimer{server: t.server, transport: t.transport, start: time.Now()}
+}
+
+// connHandshakeTimer times the handshake of a single connection, from the first packet, when quic-go creates
+// the tracer, until the handshake is confirmed.
+type connHandshakeTimer struct {
+	logging.NullConnectionTracer
+	server    string
+	transport string
+
+	start time.Time
+}
+
+// DroppedEncryptionLevel implements logging.ConnectionTracer.
+func (c *connHandshakeTimer) DroppedEncryptionLevel(level logging.EncryptionLevel) {
+	if level == logging.EncryptionHandshake {
+		vars.QUICHandshakeDuration.WithLabelValues(c.server, c.transport).Observe(time.Since(c.start).Seconds())
+	}
+}
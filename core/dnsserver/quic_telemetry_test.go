@@ -0,0 +1,56 @@
+package dnsserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/metrics/vars"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/quic-go/quic-go/logging"
+)
+
+func TestQUICTelemetry(t *testing.T) {
+	const server = "squic://:8853"
+	tracer := &quicTelemetry{server: server}
+	c := tracer.TracerForConnection(context.Background(), logging.PerspectiveServer, logging.ConnectionID{}).(*connTelemetry)
+
+	var rttStats logging.RTTStats
+	rttStats.UpdateRTT(50*time.Millisecond, 0, time.Now())
+	c.UpdatedMetrics(&rttStats, 0, 0, 0)
+	if c.rtt != 0.05 {
+		t.Errorf("Expected an RTT of 0.05s, got %v", c.rtt)
+	}
+
+	lost := testutil.ToFloat64(vars.QUICPacketsLostCount.WithLabelValues(server))
+	c.LostPacket(logging.Encryption1RTT, 1, logging.PacketLossTimeThreshold)
+	if got := testutil.ToFloat64(vars.QUICPacketsLostCount.WithLabelValues(server)); got != lost+1 {
+		t.Errorf("Expected %v lost packets, got %v", lost+1, got)
+	}
+
+	recoveries := testutil.ToFloat64(vars.QUICCongestionRecoveryCount.WithLabelValues(server))
+	for _, state := range []logging.CongestionState{
+		logging.CongestionStateSlowStart,
+		logging.CongestionStateRecovery,
+		logging.CongestionStateRecovery,
+		logging.CongestionStateCongestionAvoidance,
+		logging.CongestionStateRecovery,
+	} {
+		c.UpdatedCongestionState(state)
+	}
+	if got := testutil.ToFloat64(vars.QUICCongestionRecoveryCount.WithLabelValues(server)); got != recoveries+2 {
+		t.Errorf("Expected %v congestion recoveries, got %v", recoveries+2, got)
+	}
+
+	challenges := testutil.ToFloat64(vars.QUICPathValidationCount.WithLabelValues(server, "path_challenge", "received"))
+	responses := testutil.ToFloat64(vars.QUICPathValidationCount.WithLabelValues(server, "path_response", "sent"))
+	c.ReceivedShortHeaderPacket(&logging.ShortHeader{}, 100, []logging.Frame{&logging.PathChallengeFrame{}, &logging.PingFrame{}})
+	c.SentShortHeaderPacket(&logging.ShortHeader{}, 100, nil, []logging.Frame{&logging.PathResponseFrame{}})
+	if got := testutil.ToFloat64(vars.QUICPathValidationCount.WithLabelValues(server, "path_challenge", "received")); got != challenges+1 {
+		t.Errorf("Expected %v received path challenges, got %v", challenges+1, got)
+	}
+	if got := testutil.ToFloat64(vars.QUICPathValidationCount.WithLabelValues(server, "path_response", "sent")); got != responses+1 {
+		t.Errorf("Expected %v sent path responses, got %v", responses+1, got)
+	}
+}
@@ -0,0 +1,169 @@
+package dnsserver
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
+)
+
+// quicTLSConfig returns the TLS config of a DoQ listener on addr shared by the server blocks of zones,
+// and the distinct TLS configs of those blocks. If they share one TLS config, that is returned as is.
+// Otherwise each handshake gets the config of the block whose zone is the longest match of the server
+// name (SNI) the client sent. Clients without a server name, or with one outside of all zones, get the
+// config of the root zone, or else the one of the alphabetically first zone.
+//
+// Blocks without a TLS config can't share a listener with blocks that have one, as their clients would
+// be presented a certificate for other zones. Neither can several blocks for the same zone have
+// different TLS configs.
+func quicTLSConfig(addr string, zones map[string][]*Config) (*tls.Config, []*tls.Config, error) {
+	names := make(plugin.Zones, 0, len(zones))
+	for zone := range zones {
+		names = append(names, zone)
+	}
+	sort.Strings(names)
+
+	byZone := make(map[string]*tls.Config, len(names))
+	blocks := map[*Config]*tls.Config{}
+	var configs []*tls.Config
+	var without []string
+	for _, zone := range names {
+		for _, conf := range zones[zone] {
+			if conf.TLSConfigQUIC == nil {
+				without = append(without, zone)
+				continue
+			}
+			// Each key of a server block has a TLS config of its own, but they are all the same.
+			block := conf.firstConfigInBlock
+			if block == nil {
+				block = conf
+			}
+			cfg, ok := blocks[block]
+			if !ok {
+				cfg = conf.TLSConfigQUIC
+				blocks[block] = cfg
+				configs = append(configs, cfg)
+			}
+			// Several blocks for one zone, e.g. with views, can't be told apart by the server name.
+			if other, ok := byZone[zone]; ok && other != cfg {
+				return nil, nil, fmt.Errorf("zone %s on %s has different TLS configurations in several server blocks", zone, addr)
+			}
+			byZone[zone] = cfg
+		}
+	}
+	if len(configs) == 0 {
+		return nil, nil, nil
+	}
+	if len(without) > 0 {
+		return nil, nil, fmt.Errorf("zone %s on %s has no TLS configuration, but other server blocks on this listener do", without[0], addr)
+	}
+	if len(configs) == 1 {
+		return configs[0], configs, nil
+	}
+
+	def := byZone[names[0]]
+	if cfg, ok := byZone["."]; ok {
+		def = cfg
+	}
+	listener := def.Clone()
+	listener.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		return configForName(names, byZone, def, hello.ServerName), nil
+	}
+	return listener, configs, nil
+}
+
+// configForName returns the TLS config in byZone of the longest of zones that matches the server name,
+// or def if none does.
+func configForName(zones plugin.Zones, byZone map[string]*tls.Config, def *tls.Config, serverName string) *tls.Config {
+	if serverName == "" {
+		return def
+	}
+	if zone := zones.Matches(dns.Fqdn(strings.ToLower(serverName))); zone != "" {
+		return byZone[zone]
+	}
+	return def
+}
+
+// tlsConfigFor returns the TLS config a client asking for serverName is handed.
+func (s *ServerQUIC) tlsConfigFor(serverName string) *tls.Config {
+	if s.tlsConfig.GetConfigForClient == nil {
+		return s.tlsConfig
+	}
+	cfg, err := s.tlsConfig.GetConfigForClient(&tls.ClientHelloInfo{ServerName: serverName})
+	if err != nil || cfg == nil {
+		return s.tlsConfig
+	}
+	return cfg
+}
+
+// doqALPNs returns the ALPN tokens a DoQ listener offers, in order of preference, with the draft tokens if
+// legacy is set.
+func doqALPNs(legacy bool) []string {
+	if !legacy {
+		return []string{transport.DoQALPN}
+	}
+	return transport.DoQALPNs
+}
+
+// withALPN returns copies of cfg, the TLS config of a listener, and of the configs of its server blocks that
+// offer protos and reject handshakes that negotiated none of them. The TLS stack already rejects clients
+// offering only other tokens, this rejects those offering none at all.
+func withALPN(cfg *tls.Config, blocks []*tls.Config, protos []string) (*tls.Config, []*tls.Config) {
+	return cloneTLSConfigs(cfg, blocks, func(c *tls.Config) {
+		c.NextProtos = protos
+		verify := c.VerifyConnection
+		c.VerifyConnection = func(cs tls.ConnectionState) error {
+			if !containsString(protos, cs.NegotiatedProtocol) {
+				return fmt.Errorf("no DoQ ALPN negotiated, expected one of %s", strings.Join(protos, ","))
+			}
+			if verify != nil {
+				return verify(cs)
+			}
+			return nil
+		}
+	})
+}
+
+// cloneTLSConfigs returns copies of cfg, the TLS config of a listener, and of blocks, the configs of its
+// server blocks, all changed by f. If cfg hands out one of blocks per handshake, see quicTLSConfig, the
+// copy hands out its copy. The copies are made once, so the session ticket keys stay the same for all
+// handshakes.
+func cloneTLSConfigs(cfg *tls.Config, blocks []*tls.Config, f func(*tls.Config)) (*tls.Config, []*tls.Config) {
+	copies := make(map[*tls.Config]*tls.Config, len(blocks))
+	cloned := make([]*tls.Config, len(blocks))
+	for i, b := range blocks {
+		c := b.Clone()
+		f(c)
+		copies[b] = c
+		cloned[i] = c
+	}
+	listener, ok := copies[cfg]
+	if !ok {
+		listener = cfg.Clone()
+		f(listener)
+	}
+	if get := cfg.GetConfigForClient; get != nil {
+		listener.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			c, err := get(hello)
+			if cp, ok := copies[c]; ok {
+				return cp, err
+			}
+			return c, err
+		}
+	}
+	return listener, cloned
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
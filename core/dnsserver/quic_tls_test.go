@@ -0,0 +1,165 @@
+package dnsserver
+
+import (
+	"crypto/tls"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQUICTLSConfig(t *testing.T) {
+	orgTLS := &tls.Config{ServerName: "org"}
+	netTLS := &tls.Config{ServerName: "net"}
+
+	// Two keys of one server block, each with a TLS config of its own.
+	orgFirst := &Config{Zone: "example.org.", TLSConfigQUIC: orgTLS}
+	orgFirst.firstConfigInBlock = orgFirst
+	orgSub := &Config{Zone: "sub.example.org.", TLSConfigQUIC: orgTLS.Clone(), firstConfigInBlock: orgFirst}
+	netConf := &Config{Zone: "example.net.", TLSConfigQUIC: netTLS}
+
+	single, configs, err := quicTLSConfig("quic://:853", map[string][]*Config{
+		"example.org.":     {orgFirst},
+		"sub.example.org.": {orgSub},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if single != orgTLS || len(configs) != 1 {
+		t.Errorf("Expected the TLS config of the only server block, got %v and %d configs", single, len(configs))
+	}
+
+	listener, configs, err := quicTLSConfig("quic://:853", map[string][]*Config{
+		"example.org.":     {orgFirst},
+		"sub.example.org.": {orgSub},
+		"example.net.":     {netConf},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 2 {
+		t.Errorf("Expected 2 TLS configs, got %d", len(configs))
+	}
+	s := &ServerQUIC{tlsConfig: listener}
+	tests := []struct {
+		serverName string
+		expected   *tls.Config
+	}{
+		{"example.org", orgTLS},
+		{"DNS.Example.ORG", orgTLS},
+		{"ns.sub.example.org", orgTLS},
+		{"dns.example.net", netTLS},
+		{"example.com", netTLS}, // example.net. is the first zone
+		{"", netTLS},
+	}
+	for i, tc := range tests {
+		if cfg := s.tlsConfigFor(tc.serverName); cfg != tc.expected {
+			t.Errorf("Test %d: expected the config %q for %q, got %q", i, tc.expected.ServerName, tc.serverName, cfg.ServerName)
+		}
+	}
+
+	root := &Config{Zone: ".", TLSConfigQUIC: &tls.Config{ServerName: "root"}}
+	listener, _, err = quicTLSConfig("quic://:853", map[string][]*Config{".": {root}, "example.net.": {netConf}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = &ServerQUIC{tlsConfig: listener}
+	if cfg := s.tlsConfigFor(""); cfg != root.TLSConfigQUIC {
+		t.Errorf("Expected the config of the root zone without a server name, got %q", cfg.ServerName)
+	}
+	if cfg := s.tlsConfigFor("example.net"); cfg != netTLS {
+		t.Errorf("Expected the config of example.net., got %q", cfg.ServerName)
+	}
+}
+
+func TestQUICTLSConfigConflicts(t *testing.T) {
+	org := &Config{Zone: "example.org.", TLSConfigQUIC: &tls.Config{}}
+	netConf := &Config{Zone: "example.net.", TLSConfigQUIC: &tls.Config{}}
+	plain := &Config{Zone: "example.com."}
+	view := &Config{Zone: "example.org.", TLSConfigQUIC: &tls.Config{}}
+
+	for i, zones := range []map[string][]*Config{
+		{"example.org.": {org}, "example.com.": {plain}},
+		{"example.org.": {org, view}, "example.net.": {netConf}},
+	} {
+		if _, _, err := quicTLSConfig("quic://:853", zones); err == nil {
+			t.Errorf("Test %d: expected an error", i)
+		}
+	}
+
+	cfg, _, err := quicTLSConfig("quic://:853", map[string][]*Config{"example.com.": {plain}})
+	if err != nil || cfg != nil {
+		t.Errorf("Expected no TLS config and no error without TLS, got %v and %v", cfg, err)
+	}
+}
+
+func TestWithALPN(t *testing.T) {
+	cert := selfSigned(t, time.Now().Add(time.Hour))
+	var verified int32
+	cfg := &tls.Config{
+		Certificates:     []tls.Certificate{cert},
+		NextProtos:       []string{"doq", "doq-i02", "dq"},
+		VerifyConnection: func(tls.ConnectionState) error { atomic.AddInt32(&verified, 1); return nil },
+	}
+
+	tests := []struct {
+		legacy     bool
+		clientALPN []string
+		shouldErr  bool
+	}{
+		{false, []string{"doq"}, false},
+		{false, []string{"doq-i02", "doq"}, false},
+		{false, nil, true},
+		{false, []string{"doq-i02"}, true},
+		{false, []string{"dq"}, true},
+		{true, []string{"doq-i02"}, false},
+		{true, []string{"doq-i11"}, false},
+		{true, []string{"doq-i00"}, false},
+		{true, []string{"h3"}, true},
+		{true, nil, true},
+	}
+	// net.Pipe doesn't buffer, the alerts of rejected handshakes would block.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for i, tc := range tests {
+		listener, configs := withALPN(cfg, []*tls.Config{cfg}, doqALPNs(tc.legacy))
+		if len(cfg.NextProtos) != 3 || listener != configs[0] {
+			t.Fatalf("Test %d: expected a single copy of the config", i)
+		}
+		go func() {
+			s, err := l.Accept()
+			if err != nil {
+				return
+			}
+			server := tls.Server(s, listener)
+			if server.Handshake() == nil {
+				server.Write([]byte{0})
+			}
+			s.Close()
+		}()
+		c, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		// With TLS 1.3 the server verifies the connection after the client's handshake completed, a
+		// rejection only shows when reading.
+		client := tls.Client(c, &tls.Config{InsecureSkipVerify: true, NextProtos: tc.clientALPN})
+		err = client.Handshake()
+		if err == nil {
+			_, err = client.Read(make([]byte, 1))
+		}
+		c.Close()
+		if tc.shouldErr && err == nil {
+			t.Errorf("Test %d: expected the handshake with %v to fail", i, tc.clientALPN)
+		}
+		if !tc.shouldErr && err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+		}
+	}
+	if verified := atomic.LoadInt32(&verified); verified != 5 {
+		t.Errorf("Expected the VerifyConnection of the config to be called for the 5 accepted handshakes, got %d", verified)
+	}
+}
@@ -0,0 +1,105 @@
+package dnsserver
+
+import (
+	"context"
+
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/plugin/pkg/trace"
+
+	ot "github.com/opentracing/opentracing-go"
+	otext "github.com/opentracing/opentracing-go/ext"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"github.com/quic-go/quic-go"
+)
+
+// QUICSpanKey is the context key for the *QUICSpan of a query received over DoQ, if the server is traced.
+type QUICSpanKey struct{}
+
+// QUICSpan holds the span of the DoQ stream a query came in on. The trace plugin starts its span as a
+// child of it, so the time spent reading and writing the stream, e.g. on a slow SCION path, can be told
+// apart from the time spent in the plugins.
+type QUICSpan struct {
+	// Span is nil if the query isn't sampled.
+	Span ot.Span
+}
+
+// Names of the spans of DoQ connections and streams.
+const (
+	quicSessionSpanName = "doq.session"
+	quicStreamSpanName  = "doq.stream"
+	quicReadSpanName    = "doq.read"
+	quicWriteSpanName   = "doq.write"
+)
+
+// startStreamSpan returns ctx with the QUICSpan of a new stream of session, if the server is traced. The
+// span is nil if the stream isn't sampled; it follows from the span of the session.
+func (s *ServerQUIC) startStreamSpan(ctx context.Context, session quic.Connection, qs *quicSession) (context.Context, ot.Span) {
+	if s.trace == nil {
+		return ctx, nil
+	}
+	tracer := s.trace.Tracer()
+	if tracer == nil {
+		return ctx, nil
+	}
+	if sampler, ok := s.trace.(trace.Sampler); ok && !sampler.Sample() {
+		return context.WithValue(ctx, QUICSpanKey{}, &QUICSpan{}), nil
+	}
+
+	span := tracer.StartSpan(quicStreamSpanName, ot.FollowsFrom(qs.sessionSpan(s, tracer, session).Context()))
+	if c, ok := pkgscion.ClientOf(session.RemoteAddr()); ok && c.Path != "" {
+		span.SetTag("scion.path", c.Path)
+		if c.Hops > 0 {
+			span.SetTag("scion.hops", c.Hops)
+		}
+	}
+	return context.WithValue(ctx, QUICSpanKey{}, &QUICSpan{Span: span}), span
+}
+
+// sessionSpan returns the span of the connection, starting it, back dated to when the connection was
+// accepted, if this is its first traced stream.
+func (qs *quicSession) sessionSpan(s *ServerQUIC, tracer ot.Tracer, session quic.Connection) ot.Span {
+	qs.spanMu.Lock()
+	defer qs.spanMu.Unlock()
+	if qs.span != nil {
+		return qs.span
+	}
+	qs.span = tracer.StartSpan(quicSessionSpanName, ot.StartTime(qs.created), otext.SpanKindRPCServer)
+	qs.span.SetTag("doq.transport", s.transport)
+	qs.span.SetTag("doq.server", s.Addr)
+	otext.PeerAddress.Set(qs.span, session.RemoteAddr().String())
+	if c, ok := pkgscion.ClientOf(session.RemoteAddr()); ok {
+		qs.span.SetTag("scion.ia", c.IA.String())
+	}
+	return qs.span
+}
+
+// finishSpan finishes the span of the connection, if it has one.
+func (qs *quicSession) finishSpan() {
+	qs.spanMu.Lock()
+	defer qs.spanMu.Unlock()
+	if qs.span != nil {
+		qs.span.Finish()
+	}
+}
+
+// startQUICChildSpan starts the span name as child of the span of the stream in ctx. It returns nil if
+// the stream isn't traced.
+func startQUICChildSpan(ctx context.Context, name string) ot.Span {
+	qs, ok := ctx.Value(QUICSpanKey{}).(*QUICSpan)
+	if !ok || qs.Span == nil {
+		return nil
+	}
+	return qs.Span.Tracer().StartSpan(name, ot.ChildOf(qs.Span.Context()))
+}
+
+// finishQUICSpan finishes span, if it isn't nil, marking it as failed if err isn't nil.
+func finishQUICSpan(span ot.Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		otext.Error.Set(span, true)
+		span.LogFields(otlog.Event("error"), otlog.Error(err))
+	}
+	span.Finish()
+}
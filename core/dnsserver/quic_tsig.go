@@ -0,0 +1,38 @@
+package dnsserver
+
+import (
+	"encoding/binary"
+
+	"github.com/miekg/dns"
+)
+
+// verifyTsig verifies the TSIG record t of the query buf with the secrets of the server. The result is what
+// the writer of the query returns from TsigStatus, which the tsig plugin acts on.
+func (s *ServerQUIC) verifyTsig(buf []byte, t *dns.TSIG) error {
+	secret, ok := s.tsigSecret[t.Hdr.Name]
+	if !ok {
+		return dns.ErrSecret
+	}
+	return dns.TsigVerify(buf, secret, "", false)
+}
+
+// packResponse packs m with a message ID of 0, as DoQ requires. A response with a TSIG record, added by the
+// tsig plugin or a zone transfer, is signed: requestMAC is the MAC of the query, or of the previous message
+// of a zone transfer. The MAC of m is returned, if it is signed.
+func (s *ServerQUIC) packResponse(m *dns.Msg, requestMAC string, timersOnly bool) ([]byte, string, error) {
+	t := m.IsTsig()
+	if t == nil {
+		m.Id = 0
+		buf, err := m.Pack()
+		return buf, "", err
+	}
+	// The MAC covers the original ID of the query, which the TSIG record keeps. Without a secret for the
+	// key, the tsig plugin set the error of the record and it isn't signed.
+	m.Id = t.OrigId
+	buf, mac, err := dns.TsigGenerate(m, s.tsigSecret[t.Hdr.Name], requestMAC, timersOnly)
+	if err != nil {
+		return nil, "", err
+	}
+	binary.BigEndian.PutUint16(buf, 0)
+	return buf, mac, nil
+}
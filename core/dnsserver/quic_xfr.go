@@ -0,0 +1,117 @@
+package dnsserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/coredns/coredns/plugin/metrics/vars"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+// XFRStreamKey is the context key that is set for zone transfers received by quic and squic servers. They
+// are answered with a sequence of messages on the stream of the query.
+type XFRStreamKey struct{}
+
+// XFRStream returns true if the zone transfer in ctx is answered on a DoQ stream, where each message of the
+// transfer is written as it comes, with its own length prefix. https://www.rfc-editor.org/rfc/rfc9250.html#section-4.2
+func XFRStream(ctx context.Context) bool {
+	xfr, _ := ctx.Value(XFRStreamKey{}).(bool)
+	return xfr
+}
+
+// isXFR returns true if m asks for a zone transfer.
+func isXFR(m *dns.Msg) bool {
+	if len(m.Question) != 1 {
+		return false
+	}
+	return m.Question[0].Qtype == dns.TypeAXFR || m.Question[0].Qtype == dns.TypeIXFR
+}
+
+var errXFRMsgTooLarge = errors.New("zone transfer message exceeds the maximum message size")
+
+// xfrWriter is the DoHWriter of a zone transfer on a DoQ stream. Unlike the DoHWriter it doesn't record the
+// messages, every message is written to the stream at once, so a zone isn't held in memory while it is
+// transferred and the secondary can process it as it arrives.
+type xfrWriter struct {
+	*DoHWriter
+
+	s       *ServerQUIC
+	stream  quic.Stream
+	session quic.Connection
+
+	// n is the number of messages written, err the error that aborted the transfer.
+	n   int
+	err error
+}
+
+// WriteMsg writes m to the stream, with a message ID of 0 as DoQ requires.
+func (w *xfrWriter) WriteMsg(m *dns.Msg) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.noCompression {
+		m.Compress = false
+	}
+	// Plugins may have set an ID of their own, responses must use 0 as well. The messages of a signed
+	// transfer are chained by their MACs.
+	buf, mac, err := w.s.packResponse(m, w.tsigMAC, w.tsigTimersOnly)
+	if mac != "" {
+		w.tsigMAC = mac
+	}
+	if err != nil {
+		w.s.log.Errorf("Failed to pack zone transfer message to %s: %s", w.session.RemoteAddr(), err)
+		w.abort(err)
+		return err
+	}
+	if len(buf) > w.s.maxMsgSize {
+		// Part of the zone may already be sent, the transfer can't be answered with an error anymore.
+		w.s.log.Errorf("Zone transfer message of %d bytes to %s exceeds the maximum message size of %d", len(buf), w.session.RemoteAddr(), w.s.maxMsgSize)
+		w.abort(errXFRMsgTooLarge)
+		return w.err
+	}
+
+	written, err := w.stream.Write(addPrefix(buf))
+	vars.QUICBytesCount.WithLabelValues(w.s.Addr, w.s.transport, "out").Add(float64(written))
+	if err != nil {
+		// The client cancelled the stream or the connection is gone.
+		w.s.log.Debugf("Failed to write zone transfer message %d to %s: %s", w.n+1, w.session.RemoteAddr(), err)
+		w.s.canceledByClient(err)
+		w.err = err
+		return err
+	}
+	w.n++
+	return nil
+}
+
+// abort resets the stream, the secondary must not take what it received so far for the whole zone.
+func (w *xfrWriter) abort(err error) {
+	w.err = err
+	w.s.resetStream(w.stream, transport.DoQInternalError)
+}
+
+// serveXFR answers the zone transfer msg on stream, writing the messages of the transfer as the chain
+// produces them.
+func (s *ServerQUIC) serveXFR(ctx context.Context, stream quic.Stream, session quic.Connection, dw *DoHWriter, msg *dns.Msg) {
+	w := &xfrWriter{DoHWriter: dw, s: s, stream: stream, session: session}
+	if ctx, ok := s.queryPolicy.apply(ctx, msg); ok {
+		// Zone transfers aren't answered from 0-RTT data, the handshake completed and the peer is known.
+		ctx = context.WithValue(ctx, QUICPeerKey{}, s.peer(session))
+		s.ServeDNS(context.WithValue(ctx, XFRStreamKey{}, true), w, msg)
+	} else {
+		refused := new(dns.Msg)
+		refused.SetRcode(msg, dns.RcodeRefused)
+		w.WriteMsg(refused)
+	}
+
+	if w.n == 0 && w.err == nil {
+		// No response means the query is dropped, let the client know it shouldn't wait.
+		s.resetStream(stream, transport.DoQRequestCancelled)
+		return
+	}
+	if w.n > 1 {
+		s.log.Debugf("Answered %s from %s with %d messages", msg.Question[0].String(), session.RemoteAddr(), w.n)
+	}
+}
@@ -0,0 +1,126 @@
+package dnsserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+// writeStream is a quic.Stream that records what is written to it.
+type writeStream struct {
+	quic.Stream
+	buf bytes.Buffer
+}
+
+func (s *writeStream) Write(b []byte) (int, error) { return s.buf.Write(b) }
+
+// remoteConn is a quic.Connection that only has a remote address.
+type remoteConn struct{ quic.Connection }
+
+func (remoteConn) RemoteAddr() net.Addr { return &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 4242} }
+
+func TestXFRWriter(t *testing.T) {
+	s := &ServerQUIC{Server: &Server{Addr: "quic://:853"}, transport: transport.QUIC, maxMsgSize: dns.MaxMsgSize, log: clog.NewWithServer("quic://:853")}
+	stream := new(writeStream)
+	w := &xfrWriter{DoHWriter: &DoHWriter{}, s: s, stream: stream, session: remoteConn{}}
+
+	m := new(dns.Msg)
+	m.SetAxfr("example.org.")
+	m.Id = 42
+	soa := test.SOA("example.org. 3600 IN SOA ns.example.org. admin.example.org. 1 3600 600 86400 300")
+	for _, rrs := range [][]dns.RR{{soa, test.A("a.example.org. 3600 IN A 127.0.0.1")}, {soa}} {
+		resp := new(dns.Msg)
+		resp.SetReply(m)
+		resp.Answer = rrs
+		if err := w.WriteMsg(resp); err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+	}
+	if w.n != 2 {
+		t.Errorf("Expected 2 messages, got %d", w.n)
+	}
+
+	// Every message is on the stream at once, with a length prefix and a message ID of 0.
+	b := stream.buf.Bytes()
+	for i := 0; i < 2; i++ {
+		if len(b) < 2 {
+			t.Fatalf("Expected message %d on the stream", i+1)
+		}
+		l := int(binary.BigEndian.Uint16(b))
+		resp := new(dns.Msg)
+		if err := resp.Unpack(b[2 : 2+l]); err != nil {
+			t.Fatalf("Expected message %d to unpack, got %s", i+1, err)
+		}
+		if resp.Id != 0 {
+			t.Errorf("Expected message ID 0, got %d", resp.Id)
+		}
+		b = b[2+l:]
+	}
+	if len(b) != 0 {
+		t.Errorf("Expected nothing after the messages, got %d bytes", len(b))
+	}
+}
+
+func TestIsXFR(t *testing.T) {
+	axfr, ixfr, a := new(dns.Msg), new(dns.Msg), new(dns.Msg)
+	axfr.SetAxfr("example.org.")
+	ixfr.SetIxfr("example.org.", 1, "ns.example.org.", "admin.example.org.")
+	a.SetQuestion("example.org.", dns.TypeA)
+
+	if !isXFR(axfr) || !isXFR(ixfr) {
+		t.Errorf("Expected AXFR and IXFR to be zone transfers")
+	}
+	if isXFR(a) {
+		t.Errorf("Expected an A query not to be a zone transfer")
+	}
+	if XFRStream(context.TODO()) {
+		t.Errorf("Expected no zone transfer stream in an empty context")
+	}
+}
+
+func TestPackResponseTsig(t *testing.T) {
+	const key, secret = "example.key.", "c2VjcmV0c2VjcmV0c2VjcmV0"
+	s := &ServerQUIC{Server: &Server{Addr: "quic://:853", tsigSecret: map[string]string{key: secret}}}
+
+	// A DoQ query has a message ID of 0, which the signature covers.
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeSOA)
+	m.Id = 0
+	m.SetTsig(key, dns.HmacSHA256, 300, time.Now().Unix())
+	buf, mac, err := dns.TsigGenerate(m, secret, "", false)
+	if err != nil {
+		t.Fatalf("Expected no error signing the query, got %s", err)
+	}
+	req := new(dns.Msg)
+	if err := req.Unpack(buf); err != nil {
+		t.Fatalf("Expected the query to unpack, got %s", err)
+	}
+	if err := s.verifyTsig(buf, req.IsTsig()); err != nil {
+		t.Fatalf("Expected the query to verify, got %s", err)
+	}
+
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	resp.Id = 42
+	resp.SetTsig(key, dns.HmacSHA256, 300, time.Now().Unix())
+	out, _, err := s.packResponse(resp, mac, false)
+	if err != nil {
+		t.Fatalf("Expected no error signing the response, got %s", err)
+	}
+	if id := binary.BigEndian.Uint16(out); id != 0 {
+		t.Errorf("Expected message ID 0, got %d", id)
+	}
+	if err := dns.TsigVerify(out, secret, mac, false); err != nil {
+		t.Errorf("Expected the response to verify, got %s", err)
+	}
+}
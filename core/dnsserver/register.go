@@ -3,12 +3,14 @@ package dnsserver
 import (
 	"fmt"
 	"net"
+	"strings"
 	"time"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/caddy/caddyfile"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/parse"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 	"github.com/coredns/coredns/plugin/pkg/transport"
 
 	"github.com/miekg/dns"
@@ -31,7 +33,15 @@ func init() {
 }
 
 func newContext(i *caddy.Instance) caddy.Context {
-	return &dnsContext{keysToConfigs: make(map[string]*Config)}
+	h := &dnsContext{keysToConfigs: make(map[string]*Config), scion: pkgscion.New()}
+	// The SCION defaults of this instance become the process wide ones when it starts, before the plugins'
+	// startup functions run, and not while it is set up: on a reload, the running instance keeps serving
+	// with its own defaults until then. If the reload fails, the running instance needs them back.
+	i.OnStartup = append(i.OnStartup, func() error { return pkgscion.Set(h.scion) })
+	i.OnRestartFailed = append(i.OnRestartFailed, func() error { return pkgscion.Set(h.scion) })
+	// The DoQ servers of the failed instance may have claimed the sockets of the running ones.
+	i.OnRestartFailed = append(i.OnRestartFailed, func() error { releaseQUICSockets(); return nil })
+	return h
 }
 
 type dnsContext struct {
@@ -39,6 +49,10 @@ type dnsContext struct {
 
 	// configs is the master list of all site configs.
 	configs []*Config
+
+	// scion holds the SCION defaults of this instance, scionSet is true once a plugin has set them.
+	scion    pkgscion.Defaults
+	scionSet bool
 }
 
 func (h *dnsContext) saveConfig(key string, cfg *Config) {
@@ -58,6 +72,7 @@ func (h *dnsContext) InspectServerBlocks(sourceFile string, serverBlocks []caddy
 		// Walk the s.Keys and expand any reverse address in their proper DNS in-addr zones. If the expansions leads for
 		// more than one reverse zone, replace the current value and add the rest to s.Keys.
 		zoneAddrs := []zoneAddr{}
+		defaultPorts := []bool{}
 		for ik, k := range s.Keys {
 			trans, k1 := parse.Transport(k) // get rid of any dns:// or other scheme.
 			hosts, port, err := plugin.SplitHostPort(k1)
@@ -76,6 +91,7 @@ func (h *dnsContext) InspectServerBlocks(sourceFile string, serverBlocks []caddy
 				return nil, err
 			}
 
+			defaultPort := port == ""
 			if port == "" {
 				switch trans {
 				case transport.DNS:
@@ -101,6 +117,7 @@ func (h *dnsContext) InspectServerBlocks(sourceFile string, serverBlocks []caddy
 			}
 			for i := range hosts {
 				zoneAddrs = append(zoneAddrs, zoneAddr{Zone: dns.Fqdn(hosts[i]), Port: port, Transport: trans})
+				defaultPorts = append(defaultPorts, defaultPort)
 			}
 		}
 
@@ -117,6 +134,7 @@ func (h *dnsContext) InspectServerBlocks(sourceFile string, serverBlocks []caddy
 				ListenHosts: []string{""},
 				Port:        za.Port,
 				Transport:   za.Transport,
+				defaultPort: defaultPorts[ik],
 			}
 
 			// Set reference to the first config in the current block.
@@ -131,6 +149,7 @@ func (h *dnsContext) InspectServerBlocks(sourceFile string, serverBlocks []caddy
 			h.saveConfig(keyConfig, cfg)
 		}
 	}
+
 	return serverBlocks, nil
 }
 
@@ -151,7 +170,14 @@ func (h *dnsContext) MakeServers() ([]caddy.Server, error) {
 		c.ReadTimeout = c.firstConfigInBlock.ReadTimeout
 		c.WriteTimeout = c.firstConfigInBlock.WriteTimeout
 		c.IdleTimeout = c.firstConfigInBlock.IdleTimeout
+		c.QUIC = c.firstConfigInBlock.QUIC
+		c.MaxMsgSize = c.firstConfigInBlock.MaxMsgSize
 		c.TsigSecret = c.firstConfigInBlock.TsigSecret
+
+		// squic listeners without an explicit port use the DoQ port from the scion plugin.
+		if c.Transport == transport.SQUIC && c.defaultPort {
+			c.Port = h.scion.DoQPort
+		}
 	}
 
 	// we must map (group) each config to a bind address
@@ -284,6 +310,17 @@ func (h *dnsContext) validateZonesAndListeningAddresses() error {
 		for _, h := range conf.ListenHosts {
 			// Validate the overlapping of ZoneAddr
 			akey := zoneAddr{Transport: conf.Transport, Zone: conf.Zone, Address: h, Port: conf.Port}
+			if conf.Transport == transport.SQUIC {
+				// The bind address of a squic server block may name an ISD-AS and a port of its own.
+				la, err := pkgscion.ParseListenAddr(h)
+				if err != nil {
+					return err
+				}
+				akey.Address = la.Host
+				if la.Port != "" {
+					akey.Port = la.Port
+				}
+			}
 			var existZone, overlapZone *zoneAddr
 			if len(conf.FilterFuncs) > 0 {
 				// This config has filters. Check for overlap with other (unfiltered) configs.
@@ -313,6 +350,17 @@ func groupConfigsByListenAddr(configs []*Config) (map[string][]*Config, error) {
 	groups := make(map[string][]*Config)
 	for _, conf := range configs {
 		for _, h := range conf.ListenHosts {
+			if conf.Transport == transport.SQUIC {
+				addrstr, err := listenAddrSQUIC(h, conf.Port)
+				if err != nil {
+					return nil, err
+				}
+				if addrstr, err = sharedAddrSQUIC(groups, addrstr); err != nil {
+					return nil, err
+				}
+				groups[addrstr] = append(groups[addrstr], conf)
+				continue
+			}
 			addr, err := net.ResolveTCPAddr("tcp", net.JoinHostPort(h, conf.Port))
 			if err != nil {
 				return nil, err
@@ -325,6 +373,56 @@ func groupConfigsByListenAddr(configs []*Config) (map[string][]*Config, error) {
 	return groups, nil
 }
 
+// listenAddrSQUIC returns the listen address of a squic server for host, which may include an ISD-AS and
+// a port of its own, see pkgscion.ParseListenAddr.
+func listenAddrSQUIC(host, port string) (string, error) {
+	la, err := pkgscion.ParseListenAddr(host)
+	if err != nil {
+		return "", err
+	}
+	if la.Port == "" {
+		la.Port = port
+	}
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(la.Host, la.Port))
+	if err != nil {
+		return "", err
+	}
+	la.Host = addr.IP.String()
+	if addr.IP == nil {
+		la.Host = ""
+	}
+	return transport.SQUIC + "://" + la.String(), nil
+}
+
+// sharedAddrSQUIC returns the address of the group in groups that listens on the same socket as the squic
+// address addr, or addr if there is none. Server blocks naming the ISD-AS of a socket share it with those
+// that don't, the group then gets the address with the ISD-AS.
+func sharedAddrSQUIC(groups map[string][]*Config, addr string) (string, error) {
+	la, err := pkgscion.ParseListenAddr(addr[len(transport.SQUIC+"://"):])
+	if err != nil {
+		return "", err
+	}
+	for other, group := range groups {
+		if !strings.HasPrefix(other, transport.SQUIC+"://") || other == addr {
+			continue
+		}
+		ola, err := pkgscion.ParseListenAddr(other[len(transport.SQUIC+"://"):])
+		if err != nil || ola.Host != la.Host || ola.Port != la.Port {
+			continue
+		}
+		switch {
+		case la.IA.IsZero():
+			return other, nil
+		case ola.IA.IsZero():
+			delete(groups, other)
+			groups[addr] = append(group, groups[addr]...)
+			return addr, nil
+		}
+		return "", fmt.Errorf("squic listeners on %s expect different ISD-ASes, %s and %s", net.JoinHostPort(la.Host, la.Port), ola.IA, la.IA)
+	}
+	return addr, nil
+}
+
 // DefaultPort is the default port.
 const DefaultPort = transport.Port
 
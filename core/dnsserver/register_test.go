@@ -2,6 +2,9 @@ package dnsserver
 
 import (
 	"testing"
+
+	"github.com/coredns/caddy"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 )
 
 func TestHandler(t *testing.T) {
@@ -96,6 +99,43 @@ func TestGroupingServers(t *testing.T) {
 			{Transport: "dns", Zone: "com.", Port: "53", ListenHosts: []string{""}}},
 			expectedGroups: []string{"dns://127.0.0.1:53", "dns://[::1]:53", "dns://:53"},
 			failing:        false},
+
+		// squic binds with their own ISD-AS and port -> 1 group per address
+		{configs: []*Config{
+			{Transport: "squic", Zone: ".", Port: "8853", ListenHosts: []string{"", "19-ffaa:1:1067,127.0.0.1", "17-ffaa:0:1,[::1]:9953"}},
+		},
+			expectedGroups: []string{"squic://:8853", "squic://19-ffaa:1:1067,[127.0.0.1]:8853", "squic://17-ffaa:0:1,[::1]:9953"},
+			failing:        false},
+
+		// squic blocks on different ports -> 1 group per port
+		{configs: []*Config{
+			{Transport: "squic", Zone: "example.org.", Port: "8853", ListenHosts: []string{""}},
+			{Transport: "squic", Zone: "example.net.", Port: "9853", ListenHosts: []string{""}},
+		},
+			expectedGroups: []string{"squic://:8853", "squic://:9853"},
+			failing:        false},
+
+		// squic blocks on the same socket, one naming its ISD-AS -> 1 group with the ISD-AS
+		{configs: []*Config{
+			{Transport: "squic", Zone: "example.org.", Port: "8853", ListenHosts: []string{"127.0.0.1"}},
+			{Transport: "squic", Zone: "example.net.", Port: "8853", ListenHosts: []string{"19-ffaa:1:1067,127.0.0.1"}},
+			{Transport: "squic", Zone: "example.com.", Port: "8853", ListenHosts: []string{"127.0.0.1"}},
+		},
+			expectedGroups: []string{"squic://19-ffaa:1:1067,[127.0.0.1]:8853"},
+			failing:        false},
+
+		// squic blocks on the same socket expecting different ISD-ASes
+		{configs: []*Config{
+			{Transport: "squic", Zone: "example.org.", Port: "8853", ListenHosts: []string{"19-ffaa:1:1067,127.0.0.1"}},
+			{Transport: "squic", Zone: "example.net.", Port: "8853", ListenHosts: []string{"17-ffaa:0:1,127.0.0.1"}},
+		},
+			failing: true},
+
+		// squic bind with an invalid ISD-AS
+		{configs: []*Config{
+			{Transport: "squic", Zone: ".", Port: "8853", ListenHosts: []string{"19,127.0.0.1"}},
+		},
+			failing: true},
 	} {
 		groups, err := groupConfigsByListenAddr(test.configs)
 		if err != nil {
@@ -118,3 +158,83 @@ func TestGroupingServers(t *testing.T) {
 		}
 	}
 }
+
+func TestMakeServersBlockSettings(t *testing.T) {
+	// Setup only runs for the first key of a server block, like "dns://example.org dns://example.net".
+	first := &Config{Transport: "dns", Zone: "example.org.", Port: "1053", ListenHosts: []string{""},
+		QUIC: QUICConfig{ReplySelector: pkgscion.ReplySelectorAvoid, ReplySelectorArgs: "17"}}
+	first.firstConfigInBlock = first
+	second := &Config{Transport: "dns", Zone: "example.net.", Port: "1053", ListenHosts: []string{""}, firstConfigInBlock: first}
+
+	h := &dnsContext{configs: []*Config{first, second}}
+	if _, err := h.MakeServers(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if second.QUIC.ReplySelector != pkgscion.ReplySelectorAvoid || second.QUIC.ReplySelectorArgs != "17" {
+		t.Errorf("Expected the reply selector of the first key, avoid 17, got %s %s", second.QUIC.ReplySelector, second.QUIC.ReplySelectorArgs)
+	}
+}
+
+func TestValidateSQUICListenAddresses(t *testing.T) {
+	for i, test := range []struct {
+		configs []*Config
+		failing bool
+	}{
+		// The same zone on one socket, once with the ISD-AS and once with the port in the bind address.
+		{configs: []*Config{
+			{Transport: "squic", Zone: "example.org.", Port: "8853", ListenHosts: []string{"19-ffaa:1:1067,127.0.0.1"}},
+			{Transport: "squic", Zone: "example.org.", Port: "9953", ListenHosts: []string{"[127.0.0.1]:8853"}},
+		}, failing: true},
+		// The same zone on different ports.
+		{configs: []*Config{
+			{Transport: "squic", Zone: "example.org.", Port: "8853", ListenHosts: []string{"19-ffaa:1:1067,127.0.0.1"}},
+			{Transport: "squic", Zone: "example.org.", Port: "8853", ListenHosts: []string{"19-ffaa:1:1067,[127.0.0.1]:9953"}},
+		}, failing: false},
+	} {
+		h := &dnsContext{configs: test.configs}
+		err := h.validateZonesAndListeningAddresses()
+		if test.failing && err == nil {
+			t.Errorf("Test %d: expected an error", i)
+		}
+		if !test.failing && err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+		}
+	}
+}
+
+func TestSCIONDefaultsRestart(t *testing.T) {
+	defer pkgscion.Reset()
+
+	i := &caddy.Instance{}
+	h := newContext(i).(*dnsContext)
+	h.scion.DoQPort = "853"
+	runHooks(t, i.OnStartup)
+
+	// A reload sets up the next instance, which has other defaults. The running instance keeps serving
+	// with its own until the next one starts.
+	next := &caddy.Instance{}
+	hn := newContext(next).(*dnsContext)
+	hn.scion.DoQPort = "8853"
+	if p := pkgscion.Get().DoQPort; p != "853" {
+		t.Errorf("Expected the DoQ port of the running instance, 853, got %s", p)
+	}
+	runHooks(t, next.OnStartup)
+	if p := pkgscion.Get().DoQPort; p != "8853" {
+		t.Errorf("Expected the DoQ port of the started instance, 8853, got %s", p)
+	}
+
+	// The next instance fails to start its servers.
+	runHooks(t, i.OnRestartFailed)
+	if p := pkgscion.Get().DoQPort; p != "853" {
+		t.Errorf("Expected the DoQ port of the running instance, 853, got %s", p)
+	}
+}
+
+func runHooks(t *testing.T, hooks []func() error) {
+	t.Helper()
+	for _, fn := range hooks {
+		if err := fn(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
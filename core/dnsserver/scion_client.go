@@ -0,0 +1,17 @@
+package dnsserver
+
+import (
+	"context"
+
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+)
+
+// SCIONClientKey is the context key for the pkgscion.Client a query received by a squic server came from.
+type SCIONClientKey struct{}
+
+// SCIONClient returns the SCION client the query in ctx came from. It returns false for queries that
+// didn't arrive over SCION.
+func SCIONClient(ctx context.Context) (pkgscion.Client, bool) {
+	c, ok := ctx.Value(SCIONClientKey{}).(pkgscion.Client)
+	return c, ok
+}
@@ -0,0 +1,21 @@
+package dnsserver
+
+import (
+	"github.com/coredns/caddy"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+)
+
+// SCIONDefaults returns the SCION defaults of the server instance c sets up. It returns false if no plugin
+// has set them, the defaults are the built-in ones then.
+func SCIONDefaults(c *caddy.Controller) (pkgscion.Defaults, bool) {
+	h := c.Context().(*dnsContext)
+	return h.scion, h.scionSet
+}
+
+// SetSCIONDefaults sets the SCION defaults of the server instance c sets up. They are made the process
+// wide defaults, see pkgscion.Set, where pan and the plugins pick them up, once the instance starts.
+func SetSCIONDefaults(c *caddy.Controller, d pkgscion.Defaults) error {
+	h := c.Context().(*dnsContext)
+	h.scion, h.scionSet = d, true
+	return nil
+}
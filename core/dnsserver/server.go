@@ -47,6 +47,7 @@ type Server struct {
 	idleTimeout  time.Duration        // Idle timeout for TCP
 	readTimeout  time.Duration        // Read timeout for TCP
 	writeTimeout time.Duration        // Write timeout for TCP
+	maxMsgSize   MsgSizes             // maximum message sizes per transport
 
 	tsigSecret map[string]string
 }
@@ -97,6 +98,7 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 		if site.IdleTimeout != 0 {
 			s.idleTimeout = site.IdleTimeout
 		}
+		s.maxMsgSize.merge(site.MaxMsgSize)
 
 		// copy tsig secrets
 		for key, secret := range site.TsigSecret {
@@ -172,9 +174,13 @@ func (s *Server) Serve(l net.Listener) error {
 // This implements caddy.UDPServer interface.
 func (s *Server) ServePacket(p net.PacketConn) error {
 	s.m.Lock()
-	s.server[udp] = &dns.Server{PacketConn: p, Net: "udp", Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+	maxSize := s.maxMsgSize.UDP
+	s.server[udp] = &dns.Server{PacketConn: p, Net: "udp", UDPSize: maxSize, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
 		ctx := context.WithValue(context.Background(), Key{}, s)
 		ctx = context.WithValue(ctx, LoopKey{}, 0)
+		if maxSize > 0 {
+			w = &truncatingWriter{ResponseWriter: w, max: maxSize}
+		}
 		s.ServeDNS(ctx, w, r)
 	}), TsigSecret: s.tsigSecret}
 	s.m.Unlock()
@@ -287,9 +293,12 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 	state := request.Request{W: w, Req: r}
 
 	// there are no "truncated" responses in QUIC anymore
-	if state.Proto() != "squic" {
+	switch {
+	case XFRStream(ctx):
+		// Zone transfers on a DoQ stream are written as they are, the transfer plugin sizes its messages.
+	case state.Proto() != "squic":
 		w = request.NewScrubWriter(r, w)
-	} else {
+	default:
 		w = request.NewNoDiscardScrubWriter(r, w)
 	}
 
@@ -88,6 +88,9 @@ func (s *ServergRPC) Listen() (net.Listener, error) {
 	if err != nil {
 		return nil, err
 	}
+	s.m.Lock()
+	s.listenAddr = l.Addr()
+	s.m.Unlock()
 	return l, nil
 }
 
@@ -102,9 +105,10 @@ func (s *ServergRPC) OnStartupComplete() {
 	}
 
 	out := startUpZones(transport.GRPC+"://", s.Addr, s.zones)
-	if out != "" {
-		fmt.Print(out)
-	}
+	s.m.Lock()
+	out += listenerInfo(transport.GRPC, s.boundAddr(s.listenAddr), s.tlsConfig)
+	s.m.Unlock()
+	fmt.Print(out)
 }
 
 // Stop stops the server. It blocks until the server is
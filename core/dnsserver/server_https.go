@@ -112,6 +112,9 @@ func (s *ServerHTTPS) Listen() (net.Listener, error) {
 	if err != nil {
 		return nil, err
 	}
+	s.m.Lock()
+	s.listenAddr = l.Addr()
+	s.m.Unlock()
 	return l, nil
 }
 
@@ -126,9 +129,10 @@ func (s *ServerHTTPS) OnStartupComplete() {
 	}
 
 	out := startUpZones(transport.HTTPS+"://", s.Addr, s.zones)
-	if out != "" {
-		fmt.Print(out)
-	}
+	s.m.Lock()
+	out += listenerInfo(transport.HTTPS, s.boundAddr(s.listenAddr), s.tlsConfig)
+	s.m.Unlock()
+	fmt.Print(out)
 }
 
 // Stop stops the server. It blocks until the server is totally stopped.
@@ -6,58 +6,330 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/caddyserver/caddy"
+	"github.com/coredns/coredns/plugin/metrics/vars"
+	"github.com/coredns/coredns/plugin/pkg/clientaddr"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/reuseport"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 	"github.com/coredns/coredns/plugin/pkg/transport"
 	"github.com/miekg/dns"
 	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/logging"
 )
 
-// maxQuicIdleTimeout - maximum QUIC idle timeout.
+// DefaultQUICIdleTimeout - default QUIC idle timeout.
 // Default value in quic-go is 30, but our internal tests show that
 // a higher value works better for clients written with ngtcp2
-const maxQuicIdleTimeout = 5 * time.Minute
+const DefaultQUICIdleTimeout = 5 * time.Minute
 
 const minDNSPacketSize = 12 + 5
 
+const (
+	// DefaultMaxQUICStreams is the default maximum number of concurrent streams per connection.
+	DefaultMaxQUICStreams = 256
+	// DefaultQUICStreamWorkers is the default number of streams handled concurrently by a server.
+	DefaultQUICStreamWorkers = 1024
+	// DefaultQUICAcceptLoops is the default number of loops accepting new connections per server.
+	DefaultQUICAcceptLoops = 1
+)
+
 // Implemented according to https://tools.ietf.org/html/draft-huitema-dprive-dnsoquic-00
 // ServerQUIC represents an instance of a DNS-over-QUIC server.
 type ServerQUIC struct {
 	*Server
+	// tlsConfig is the config of the listener, it hands out one of tlsConfigs, those of the server blocks,
+	// per handshake if there are several.
 	tlsConfig  *tls.Config
-	listen     quic.Listener
+	tlsConfigs []*tls.Config
+	listen     quicListener
 	listenAddr net.Addr
 
+	// transport is either quic or squic.
+	transport string
+	// log includes the listen address, so multiple servers in one process can be told apart.
+	log clog.P
+	// listenQUIC creates the QUIC listener on the packet conn, this is where quic and squic differ.
+	listenQUIC func(net.PacketConn, *tls.Config, *quic.Config) (quic.Listener, error)
+	// listenQUICEarly does the same for a listener that accepts 0-RTT connections.
+	listenQUICEarly func(net.PacketConn, *tls.Config, *quic.Config) (quic.EarlyListener, error)
+	// allow0RTT answers replay safe queries from 0-RTT data.
+	allow0RTT bool
+	// clientAddrKey, if set, makes the server trust client addresses added by forwarders holding this key.
+	clientAddrKey []byte
+	// validator, if not nil, makes clients validate their address before the handshake.
+	validator *addressValidator
+	// queryPolicy is how the RD and CD bits and the no-cache option are treated.
+	queryPolicy queryPolicy
+	// noCompression sends responses without name compression, so their size doesn't depend on the names in them.
+	noCompression bool
+	// telemetry turns QUIC connection events into metrics, see quicTelemetry.
+	telemetry bool
+	// qlog writes the qlog files of the connections, if not nil.
+	qlog *qlogDir
+	// keyLogFile, if set, is where the TLS session keys are written to, keyLog is the open file.
+	keyLogFile string
+	keyLog     io.Closer
+	// logHandshakes logs the negotiated parameters of every handshake, and the reason of failed ones.
+	logHandshakes bool
+	// replySelector, if set, overrides the reply path selector of the scion plugin, see ServerSQUIC.
+	replySelector     string
+	replySelectorArgs string
+	// connRate, if its QPS isn't 0, limits the queries per connection, sourceLimit those per source.
+	connRate    QUICRate
+	sourceLimit *sourceLimiter
+
+	sessions *quicSessions
+	// socket is the socket the server accepts connections on, next is the server of a reloaded Corefile
+	// that took it over, see quicSockets.
+	socket *quicSocket
+	next   *ServerQUIC
+	// idleReap closes sessions that had no open streams for this long, 0 disables reaping.
+	idleReap time.Duration
+	// stop is closed when the server stops, from then on no new sessions are accepted.
+	stop chan struct{}
+	// drainTimeout is how long Stop waits for the streams of open sessions to finish.
+	drainTimeout time.Duration
+	// certExpiryWarning is how long before the certificate expires warnings are logged.
+	certExpiryWarning time.Duration
+
+	// maxMsgSize is the largest query we read and response we write.
+	maxMsgSize int
+
+	// maxStreams limits the concurrent streams a client may open on a connection.
+	maxStreams int
+	// idleTimeout, keepAlive and the receive windows end up in the quic.Config.
+	idleTimeout  time.Duration
+	keepAlive    time.Duration
+	streamWindow QUICWindow
+	connWindow   QUICWindow
+	// streamWorkers bounds the number of streams handled concurrently, over all connections.
+	streamWorkers chan struct{}
+	// acceptLoops is the number of loops accepting new connections from the listener.
+	acceptLoops int
+	// maxSessions, if not 0, is the number of open sessions beyond which new ones are refused.
+	// openSessions counts the sessions from their accept until they are closed or handed over.
+	maxSessions  int
+	openSessions int64
+
+	// selfCheck makes the server query itself once it is listening, see runSelfCheck. selfChecked is
+	// closed once the check is done, selfCheckErr is then set if it failed.
+	selfCheck     bool
+	selfCheckName string
+	selfCheckFail bool
+	selfChecked   chan struct{}
+	selfCheckErr  error
+
+	// authenticators accept or reject new connections.
+	authenticators []QUICAuthenticator
+
 	bytesPool *sync.Pool
 }
 
 // NewServerQUIC returns a new CoreDNS QUIC server and compiles all plugin in to it.
 func NewServerQUIC(addr string, group []*Config) (*ServerQUIC, error) {
+	s, err := newServerQUIC(transport.QUIC, addr, group)
+	if err != nil {
+		return nil, err
+	}
+	s.listenQUIC = func(p net.PacketConn, tlsConf *tls.Config, conf *quic.Config) (quic.Listener, error) {
+		return quic.Listen(p, tlsConf, conf)
+	}
+	s.listenQUICEarly = func(p net.PacketConn, tlsConf *tls.Config, conf *quic.Config) (quic.EarlyListener, error) {
+		return quic.ListenEarly(p, tlsConf, conf)
+	}
+	return s, nil
+}
+
+// newServerQUIC returns a ServerQUIC for trans, without a way to listen yet.
+func newServerQUIC(trans, addr string, group []*Config) (*ServerQUIC, error) {
 	s, err := NewServer(addr, group)
 	if err != nil {
 		return nil, err
 	}
-	// The *tls* plugin must make sure that multiple conflicting
-	// TLS configuration return an error: it can only be specified once.
-	var tlsConfig *tls.Config
+	// Server blocks sharing the listener may have TLS configs of their own, see quicTLSConfig.
+	tlsConfig, tlsConfigs, err := quicTLSConfig(addr, s.zones)
+	if err != nil {
+		return nil, err
+	}
+	var idleReap time.Duration
+	var selfCheck, selfCheckFail, allow0RTT, noCompression, telemetry bool
+	var selfCheckName string
+	var keepAlive time.Duration
+	var clientAddrKey []byte
+	var validator *addressValidator
+	var policy queryPolicy
+	var connRate QUICRate
+	var sourceLimit *sourceLimiter
+	var qlog *qlogDir
+	var keyLogFile string
+	var logHandshakes, legacyALPN bool
+	var replySelector, replySelectorArgs string
+	drainTimeout := s.graceTimeout
+	certExpiryWarning := DefaultCertExpiryWarning
+	var streamWindow, connWindow QUICWindow
+	maxStreams := DefaultMaxQUICStreams
+	workers := DefaultQUICStreamWorkers
+	acceptLoops := DefaultQUICAcceptLoops
+	var maxSessions int
+	idleTimeout := DefaultQUICIdleTimeout
 	for _, z := range s.zones {
 		for _, conf := range z {
-			// Should we error if some configs *don't* have TLS?
-			tlsConfig = conf.TLSConfigQUIC
+			if conf.QUIC.MaxStreams != 0 {
+				maxStreams = conf.QUIC.MaxStreams
+			}
+			if conf.QUIC.IdleTimeout != 0 {
+				idleTimeout = conf.QUIC.IdleTimeout
+			}
+			if conf.QUIC.KeepAlive != 0 {
+				keepAlive = conf.QUIC.KeepAlive
+			}
+			if conf.QUIC.DrainTimeout != 0 {
+				drainTimeout = conf.QUIC.DrainTimeout
+			}
+			if conf.QUIC.CertExpiryWarning != 0 {
+				certExpiryWarning = conf.QUIC.CertExpiryWarning
+			}
+			if conf.QUIC.StreamWindow.Initial != 0 {
+				streamWindow = conf.QUIC.StreamWindow
+			}
+			if conf.QUIC.ConnectionWindow.Initial != 0 {
+				connWindow = conf.QUIC.ConnectionWindow
+			}
+			if conf.QUIC.MaxWorkerPoolSize != 0 {
+				workers = conf.QUIC.MaxWorkerPoolSize
+			}
+			if conf.QUIC.AcceptLoops != 0 {
+				acceptLoops = conf.QUIC.AcceptLoops
+			}
+			if conf.QUIC.MaxSessions != 0 {
+				maxSessions = conf.QUIC.MaxSessions
+			}
+			if conf.QUIC.IdleReap != 0 {
+				idleReap = conf.QUIC.IdleReap
+			}
+			if conf.QUIC.SelfCheck {
+				selfCheck = true
+				selfCheckName = conf.QUIC.SelfCheckName
+				selfCheckFail = conf.QUIC.SelfCheckFail
+			}
+			if conf.QUIC.Allow0RTT {
+				allow0RTT = true
+			}
+			if conf.QUIC.AddressValidation {
+				validator = &addressValidator{server: addr, threshold: conf.QUIC.AddressValidationThreshold}
+			}
+			if conf.QUIC.NonRecursive != "" {
+				policy.nonRecursive = conf.QUIC.NonRecursive
+			}
+			if conf.QUIC.CheckingDisabled != "" {
+				policy.checkingDisabled = conf.QUIC.CheckingDisabled
+			}
+			if conf.QUIC.CacheBypass {
+				policy.cacheBypass = true
+			}
+			if conf.QUIC.NoCompression {
+				noCompression = true
+			}
+			if conf.QUIC.Telemetry {
+				telemetry = true
+			}
+			if conf.QUIC.QlogDir != "" {
+				qlog = &qlogDir{dir: conf.QUIC.QlogDir, maxFiles: conf.QUIC.QlogMaxFiles, transport: trans, log: clog.NewWithServer(addr)}
+			}
+			if conf.QUIC.KeyLogFile != "" {
+				keyLogFile = conf.QUIC.KeyLogFile
+			}
+			if conf.QUIC.LogHandshakes {
+				logHandshakes = true
+			}
+			if conf.QUIC.LegacyALPN {
+				legacyALPN = true
+			}
+			if conf.QUIC.ReplySelector != "" {
+				replySelector, replySelectorArgs = conf.QUIC.ReplySelector, conf.QUIC.ReplySelectorArgs
+			}
+			if conf.QUIC.ConnectionRate.QPS != 0 {
+				connRate = conf.QUIC.ConnectionRate
+			}
+			if conf.QUIC.SourceRate.QPS != 0 {
+				sourceLimit = newSourceLimiter(conf.QUIC.SourceRate)
+			}
+			if conf.QUIC.ClientAddrKey != nil {
+				clientAddrKey = conf.QUIC.ClientAddrKey
+			}
 		}
 	}
 
+	if tlsConfig != nil {
+		// Whatever ALPNs the TLS configs list, only DoQ is offered and a client has to agree on it.
+		tlsConfig, tlsConfigs = withALPN(tlsConfig, tlsConfigs, doqALPNs(legacyALPN))
+	}
+
+	if allow0RTT && tlsConfig != nil && tlsConfig.SessionTicketsDisabled {
+		clog.Warningf("0-RTT is enabled for %s, but session tickets are disabled, clients can't resume", addr)
+	}
+
+	maxMsgSize := s.maxMsgSize.ForTransport(trans)
 	bytesPool := sync.Pool{
 		New: func() interface{} {
-			return make([]byte, dns.MaxMsgSize)
+			// Room for the 2-byte length prefix and the largest message we accept.
+			return make([]byte, 2+maxMsgSize)
 		},
 	}
 
-	return &ServerQUIC{Server: s, tlsConfig: tlsConfig, bytesPool: &bytesPool}, nil
+	return &ServerQUIC{
+		Server:     s,
+		tlsConfig:  tlsConfig,
+		tlsConfigs: tlsConfigs,
+		transport:  trans,
+		log:        clog.NewWithServer(addr),
+		sessions:   newQUICSessions(),
+		idleReap:   idleReap,
+		stop:       make(chan struct{}),
+		bytesPool:  &bytesPool,
+
+		drainTimeout:      drainTimeout,
+		certExpiryWarning: certExpiryWarning,
+
+		maxMsgSize: maxMsgSize,
+
+		maxStreams:    maxStreams,
+		streamWorkers: make(chan struct{}, workers),
+		acceptLoops:   acceptLoops,
+		maxSessions:   maxSessions,
+		idleTimeout:   idleTimeout,
+		keepAlive:     keepAlive,
+		streamWindow:  streamWindow,
+		connWindow:    connWindow,
+		allow0RTT:     allow0RTT,
+		clientAddrKey: clientAddrKey,
+		noCompression: noCompression,
+		telemetry:     telemetry,
+		qlog:          qlog,
+		keyLogFile:    keyLogFile,
+		logHandshakes: logHandshakes,
+		connRate:      connRate,
+		sourceLimit:   sourceLimit,
+		validator:     validator,
+		queryPolicy:   policy,
+
+		replySelector:     replySelector,
+		replySelectorArgs: replySelectorArgs,
+
+		selfCheck:     selfCheck,
+		selfCheckName: selfCheckName,
+		selfCheckFail: selfCheckFail,
+		selfChecked:   make(chan struct{}),
+
+		authenticators: quicAuthenticators(group),
+	}, nil
 }
 
 // Compile-time check to ensure Server implements the caddy.GracefulServer interface
@@ -73,24 +345,125 @@ func (s *ServerQUIC) ServePacket(p net.PacketConn) error {
 	s.m.Lock()
 
 	if s.tlsConfig == nil {
+		s.m.Unlock()
 		return errors.New("cannot run a QUIC server without TLS config")
 	}
 
-	l, err := quic.Listen(p, s.tlsConfig, &quic.Config{MaxIdleTimeout: maxQuicIdleTimeout})
-	if err != nil {
-		return err
+	tlsConfig := s.tlsConfig
+	if s.keyLogFile != "" {
+		f, err := openKeyLog(s.keyLogFile)
+		if err != nil {
+			s.m.Unlock()
+			return err
+		}
+		s.keyLog = f
+		tlsConfig = withKeyLog(s.tlsConfig, s.tlsConfigs, f)
+		s.log.Warningf("Writing the TLS session keys to %s, they decrypt all DoQ traffic of this server", s.keyLogFile)
 	}
+
+	var l quicListener
+	sock := s.takeOverSocket(p, tlsConfig)
+	if sock != nil {
+		l = sock.listen
+		s.log.Infof("Took over the DoQ connections of the previous server")
+	} else {
+		var err error
+		sock = newQUICSocket(s, p, tlsConfig)
+		if s.allow0RTT {
+			var el quic.EarlyListener
+			el, err = s.listenQUICEarly(p, sock.listenerTLSConfig(), s.quicConfig())
+			l = earlyListener{el}
+		} else {
+			l, err = s.listenQUIC(p, sock.listenerTLSConfig(), s.quicConfig())
+		}
+		if err != nil {
+			s.m.Unlock()
+			return err
+		}
+		sock.listen = l
+		sock.register()
+	}
+	s.socket = sock
 	s.listen = l
 	s.listenAddr = l.Addr()
 	s.m.Unlock()
 
+	registerEndpoint(s, Endpoint{Transport: s.transport, Addr: l.Addr(), ALPN: s.tlsConfig.NextProtos})
+
+	if s.idleReap > 0 {
+		go s.reapIdleSessions()
+	}
+	if s.selfCheck {
+		go s.runSelfCheck()
+	}
+	go s.checkCertificates()
+
+	// The listener may live on in a successor, stopping only ends the accept loops.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// Several loops accept, so a burst of new connections isn't serialized on a single one.
+	errs := make(chan error, s.acceptLoops)
+	for i := 0; i < s.acceptLoops; i++ {
+		go func() { errs <- s.acceptSessions(ctx) }()
+	}
+	var err error
+	for i := 0; i < s.acceptLoops; i++ {
+		if e := <-errs; e != nil && err == nil {
+			// Not stopped by us, report the listener as down, so we're no longer reported healthy.
+			s.log.Errorf("Stopped accepting connections: %s", e)
+			failEndpoint(s, e)
+			err = e
+		}
+	}
+	return err
+}
+
+// acceptSessions accepts new sessions until the listener fails, or until ctx is canceled because s stopped,
+// then it returns nil.
+func (s *ServerQUIC) acceptSessions(ctx context.Context) error {
 	for {
-		session, err := s.listen.Accept(context.Background())
+		session, err := s.listen.Accept(ctx)
 		if err != nil {
+			select {
+			case <-s.stop:
+				return nil
+			default:
+			}
 			return err
 		}
+		srv := s
+		select {
+		case <-s.stop:
+			// Accepted while the successor took over, it serves the session.
+			if srv = s.successor(); srv == nil {
+				// Draining, don't take on new clients.
+				_ = session.CloseWithError(transport.DoQNoError, "")
+				continue
+			}
+		default:
+		}
+		vars.QUICSessionsCount.WithLabelValues(s.Addr, s.transport).Inc()
+		if !srv.reserveSession() {
+			// Shed the load predictably: clients retry later or with another server, those connected
+			// keep being served.
+			s.log.Debugf("Refused connection from %s: %d connections open", session.RemoteAddr(), srv.maxSessions)
+			vars.QUICSessionsRefusedCount.WithLabelValues(s.Addr, s.transport).Inc()
+			_ = session.CloseWithError(transport.DoQExcessiveLoad, "excessive load")
+			continue
+		}
+		if srv.logHandshakes {
+			go srv.logHandshake(session)
+		}
 
-		go s.handleQUICSession(session)
+		go srv.handleQUICSession(session, time.Now())
 	}
 }
 
@@ -99,124 +472,587 @@ func (s *ServerQUIC) Listen() (net.Listener, error) { return nil, nil }
 
 // ListenPacket implements caddy.UDPServer interface.
 func (s *ServerQUIC) ListenPacket() (net.PacketConn, error) {
-	p, err := reuseport.ListenPacket("udp", s.Addr[len(transport.QUIC+"://"):])
-	if err != nil {
-		return nil, err
+	addr := s.Addr[len(transport.QUIC+"://"):]
+	p := takeActivatedSocket(addr)
+	if p == nil {
+		var err error
+		if p, err = reuseport.ListenPacket("udp", addr); err != nil {
+			return nil, err
+		}
 	}
+	s.m.Lock()
+	s.listenAddr = p.LocalAddr()
+	s.m.Unlock()
 
 	return p, nil
 }
 
-// Stop stops the server. It blocks until the server is totally stopped.
+// Stop stops the server. It blocks until the server is totally stopped. New sessions are refused
+// right away, open sessions are closed once their streams finished, or after the drain timeout.
+// If the server of a reloaded Corefile took over the socket, the sessions are handed over to it
+// instead, once their streams finished.
 func (s *ServerQUIC) Stop() error {
+	unregisterEndpoint(s)
+
+	next := s.handOver()
+	s.m.Lock()
+	s.next = next
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	s.m.Unlock()
+
+	if next != nil {
+		s.handOverSessions()
+	} else {
+		s.drain()
+	}
+
 	s.m.Lock()
 	defer s.m.Unlock()
+	if s.keyLog != nil {
+		s.keyLog.Close()
+		s.keyLog = nil
+	}
+	if s.listen == nil || next != nil {
+		return nil
+	}
 	return s.listen.Close()
 }
 
+// drainInterval is how often drain checks for sessions without open streams.
+const drainInterval = 50 * time.Millisecond
+
+// drain closes the sessions once they have no open streams. Sessions still busy after s.drainTimeout are
+// closed regardless.
+func (s *ServerQUIC) drain() {
+	deadline := time.Now().Add(s.drainTimeout)
+	for s.sessions.len() > 0 && time.Now().Before(deadline) {
+		for _, session := range s.sessions.withoutStreams() {
+			_ = session.CloseWithError(transport.DoQNoError, "")
+		}
+		time.Sleep(drainInterval)
+	}
+	if n := s.sessions.len(); n > 0 {
+		s.log.Infof("Closing %d DoQ connections with open streams after the drain timeout of %s", n, s.drainTimeout)
+	}
+	for _, session := range s.sessions.all() {
+		_ = session.CloseWithError(transport.DoQNoError, "")
+	}
+}
+
 // OnStartupComplete lists the sites served by this server
 // and any relevant information, assuming Quiet is false.
 func (s *ServerQUIC) OnStartupComplete() {
+	// Only called on startup, not on reloads: a broken setup stops CoreDNS before clients notice.
+	if s.selfCheck && s.selfCheckFail {
+		if err := s.selfCheckResult(); err != nil {
+			s.log.Fatalf("Stopping: %s", err)
+		}
+	}
 	if Quiet {
 		return
 	}
 
-	out := startUpZones(transport.QUIC+"://", s.Addr, s.zones)
-	if out != "" {
-		fmt.Print(out)
+	out := startUpZones(s.transport+"://", s.Addr, s.zones)
+	s.m.Lock()
+	out += listenerInfo(s.transport, s.boundAddr(s.listenAddr), s.tlsConfig)
+	s.m.Unlock()
+	fmt.Print(out)
+}
+
+// reserveSession counts a new session, it returns false if s has maxSessions open already.
+func (s *ServerQUIC) reserveSession() bool {
+	n := atomic.AddInt64(&s.openSessions, 1)
+	if s.maxSessions > 0 && n > int64(s.maxSessions) {
+		atomic.AddInt64(&s.openSessions, -1)
+		return false
 	}
+	return true
 }
 
-func (s *ServerQUIC) handleQUICSession(session quic.Connection) {
+// handOverSession passes session on to next, which counts it regardless of its limit, the client is
+// connected already.
+func (s *ServerQUIC) handOverSession(next *ServerQUIC, session quic.Connection, created time.Time) {
+	atomic.AddInt64(&next.openSessions, 1)
+	go next.handleQUICSession(session, created)
+}
+
+// handleQUICSession serves the streams of session, which was accepted at created, by s or by the server
+// of the Corefile before a reload. The session must have been counted in s.openSessions.
+func (s *ServerQUIC) handleQUICSession(session quic.Connection, created time.Time) {
+	defer atomic.AddInt64(&s.openSessions, -1)
+	ctx := context.WithValue(context.Background(), Key{}, s.Server)
+	if len(s.authenticators) > 0 {
+		// The client certificates are only known once the handshake completed.
+		select {
+		case <-s.handshakeComplete(session):
+		case <-session.Context().Done():
+			return
+		}
+		identity, err := authenticate(session.Context(), s.authenticators, s.peer(session))
+		if err != nil {
+			s.log.Infof("Rejected DoQ connection from %s: %s", session.RemoteAddr(), err)
+			vars.QUICAuthRejectedCount.WithLabelValues(s.Addr).Inc()
+			_ = session.CloseWithError(transport.DoQProtocolError, "unauthorized")
+			return
+		}
+		if identity != nil {
+			ctx = context.WithValue(ctx, QUICIdentityKey{}, identity)
+		}
+	}
+
+	qs := s.sessions.add(session, created)
+	handedOver := false
+	defer func() {
+		s.sessions.remove(session)
+		qs.finishSpan()
+		if !handedOver {
+			vars.QUICConnectionAge.WithLabelValues(s.Addr).Observe(time.Since(qs.created).Seconds())
+		}
+	}()
+	// Sessions added once the successor took over aren't told to stop accepting streams.
+	if next := s.successor(); next != nil {
+		handedOver = true
+		s.handOverSession(next, session, created)
+		return
+	}
+
 	for {
 		// The stub to resolver DNS traffic follows a simple pattern in which
 		// the client sends a query, and the server provides a response.  This
 		// design specifies that for each subsequent query on a QUIC connection
 		// the client MUST select the next available client-initiated
 		// bidirectional stream
-		stream, err := session.AcceptStream(context.Background())
+		stream, err := session.AcceptStream(qs.accept)
 		if err != nil {
-			fmt.Print("ERROR[session.AcceptStream]:" + err.Error())
-
-			_ = session.CloseWithError(0, "")
+			if next := s.successor(); next != nil && qs.accept.Err() != nil {
+				// The streams in flight are finished by s, the next ones are served by the successor.
+				handedOver = true
+				s.handOverSession(next, session, created)
+				return
+			}
+			s.log.Debugf("Connection from %s closed: %s", session.RemoteAddr(), err)
+			_ = session.CloseWithError(transport.DoQNoError, "")
+			return
+		}
+		qs.streamStarted()
+		if scope := s.rateLimited(session, qs); scope != "" {
+			s.log.Debugf("Rate limited query from %s per %s", session.RemoteAddr(), scope)
+			vars.QUICRateLimitedCount.WithLabelValues(s.Addr, scope).Inc()
+			s.resetStream(stream, transport.DoQExcessiveLoad)
+			qs.streamDone()
+			continue
+		}
+		// Wait for a free worker. While we wait no further streams are accepted, so the stream
+		// limit of the connection pushes back on the client.
+		select {
+		case s.streamWorkers <- struct{}{}:
+		case <-session.Context().Done():
+			qs.streamDone()
 			return
 		}
 		go func() {
-			s.handleQUICStream(stream, session)
+			active := vars.QUICActiveStreams.WithLabelValues(s.Addr, s.transport)
+			active.Inc()
+			defer func() {
+				active.Dec()
+				<-s.streamWorkers
+				qs.streamDone()
+			}()
+			// A single bad stream must not take the server down.
+			defer func() {
+				if rec := recover(); rec != nil {
+					s.log.Errorf("Recovered from panic handling a stream from %s: %v", session.RemoteAddr(), rec)
+					vars.Panic.Inc()
+					s.resetStream(stream, transport.DoQInternalError)
+				}
+			}()
+			ctx, span := s.startStreamSpan(s.withPeer(ctx, session), session, qs)
+			s.handleQUICStream(ctx, stream, session)
 			_ = stream.Close()
+			finishQUICSpan(span, nil)
 		}()
 	}
 }
 
+// quicConfig returns the configuration for the QUIC listener.
+func (s *ServerQUIC) quicConfig() *quic.Config {
+	conf := &quic.Config{
+		MaxIdleTimeout:     s.idleTimeout,
+		KeepAlivePeriod:    s.keepAlive,
+		MaxIncomingStreams: int64(s.maxStreams),
+		// DoQ only uses bidirectional streams.
+		MaxIncomingUniStreams: -1,
+
+		InitialStreamReceiveWindow:     s.streamWindow.Initial,
+		MaxStreamReceiveWindow:         s.streamWindow.Max,
+		InitialConnectionReceiveWindow: s.connWindow.Initial,
+		MaxConnectionReceiveWindow:     s.connWindow.Max,
+	}
+	if s.allow0RTT {
+		conf.Allow0RTT = func(net.Addr) bool { return true }
+	}
+	if s.transport == transport.SQUIC {
+		// The reply path is chosen per packet by pan, a packet size discovered on one path may not fit
+		// through the next one. Stay at the initial packet size, which every usable path carries.
+		conf.DisablePathMTUDiscovery = true
+	}
+	if s.validator != nil {
+		conf.RequireAddressValidation = s.validator.RequireAddressValidation
+	}
+	conf.Tracer = &quicHandshakeTimer{server: s.Addr, transport: s.transport}
+	if s.telemetry {
+		conf.Tracer = logging.NewMultiplexedTracer(conf.Tracer, &quicTelemetry{server: s.Addr})
+	}
+	if s.qlog != nil {
+		conf.Tracer = logging.NewMultiplexedTracer(conf.Tracer, s.qlog.tracer())
+	}
+	if s.logHandshakes {
+		conf.Tracer = logging.NewMultiplexedTracer(conf.Tracer, &quicHandshakeLogger{log: s.log})
+	}
+	return conf
+}
+
+// rateLimited returns the scope, connection or source, of the rate limit a new stream of session exceeds, or
+// the empty string if it is within the limits.
+func (s *ServerQUIC) rateLimited(session quic.Connection, qs *quicSession) string {
+	now := time.Now()
+	if s.connRate.QPS != 0 && !qs.limit.take(s.connRate, now) {
+		return "connection"
+	}
+	if s.sourceLimit != nil && !s.sourceLimit.allow(session.RemoteAddr(), now) {
+		return "source"
+	}
+	return ""
+}
+
+// handshakeComplete returns a channel that is closed once the handshake of session completed. Without 0-RTT
+// connections are only accepted after that.
+func (s *ServerQUIC) handshakeComplete(session quic.Connection) <-chan struct{} {
+	if early, ok := session.(quic.EarlyConnection); ok && s.allow0RTT {
+		return early.HandshakeComplete()
+	}
+	return handshakeDone
+}
+
+// peer returns the QUICPeer of session, its handshake must have completed.
+func (s *ServerQUIC) peer(session quic.Connection) QUICPeer {
+	return QUICPeer{Transport: s.transport, RemoteAddr: session.RemoteAddr(), TLS: session.ConnectionState().TLS.ConnectionState}
+}
+
+var handshakeDone = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+// replaySafe returns true if answering m more than once has no side effects, so it may be answered
+// from 0-RTT data. Zone transfers are excluded as well, as they are expensive.
+func replaySafe(m *dns.Msg) bool {
+	if m.Opcode != dns.OpcodeQuery || len(m.Question) != 1 {
+		return false
+	}
+	switch m.Question[0].Qtype {
+	case dns.TypeAXFR, dns.TypeIXFR:
+		return false
+	}
+	return true
+}
+
+// quicListener is implemented by quic.Listener and, through earlyListener, by quic.EarlyListener.
+type quicListener interface {
+	Accept(context.Context) (quic.Connection, error)
+	Addr() net.Addr
+	Close() error
+}
+
+type earlyListener struct{ quic.EarlyListener }
+
+func (l earlyListener) Accept(ctx context.Context) (quic.Connection, error) {
+	return l.EarlyListener.Accept(ctx)
+}
+
+// reapIdleSessions periodically closes the sessions that had no open streams for s.idleReap.
+func (s *ServerQUIC) reapIdleSessions() {
+	interval := s.idleReap / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			for _, session := range s.sessions.idle(s.idleReap) {
+				_ = session.CloseWithError(transport.DoQNoError, "idle")
+				vars.QUICIdleReapedCount.WithLabelValues(s.Addr).Inc()
+			}
+		}
+	}
+}
+
+// clientAddr returns the client address a forwarder added to msg, or raddr if there is none or it doesn't
+// verify. The option is removed from msg either way.
+func (s *ServerQUIC) clientAddr(msg *dns.Msg, raddr net.Addr) net.Addr {
+	client, err := clientaddr.Get(msg, s.clientAddrKey, time.Now())
+	if err != nil {
+		if err != clientaddr.ErrNotFound {
+			s.log.Debugf("Ignoring client address in query from %s: %s", raddr, err)
+		}
+		return raddr
+	}
+	addr, err := clientaddr.Addr(client)
+	if err != nil {
+		s.log.Debugf("Ignoring client address %q in query from %s: %s", client, raddr, err)
+		return raddr
+	}
+	return addr
+}
+
 // handleQUICStream reads DNS queries from the stream, processes them,
-// and writes back the responses
-func (s *ServerQUIC) handleQUICStream(stream quic.Stream, session quic.Connection) {
-	var b []byte
-	b = s.bytesPool.Get().([]byte)
+// and writes back the responses. The queries are served with ctx, which carries
+// the server and the identity of the connection.
+func (s *ServerQUIC) handleQUICStream(ctx context.Context, stream quic.Stream, session quic.Connection) {
+	var b []byte = s.bytesPool.Get().([]byte)
 	defer s.bytesPool.Put(b)
 
 	// The client MUST send the DNS query over the selected stream, and MUST
 	// indicate through the STREAM FIN mechanism that no further data will
 	// be sent on that stream.
-	// FIN is indicated via error so we should simply ignore it and
-	// check the size instead.
-	n, _ := stream.Read(b)
-	if n < minDNSPacketSize {
+	readSpan := startQUICChildSpan(ctx, quicReadSpanName)
+	n, err := readQuery(stream, b[:2+s.maxMsgSize])
+	finishQUICSpan(readSpan, err)
+	if err != nil {
+		if err == errQueryTooLarge {
+			s.log.Errorf("Query from %s exceeds the maximum message size of %d", session.RemoteAddr(), s.maxMsgSize)
+			s.resetStream(stream, transport.DoQExcessiveLoad)
+			return
+		}
+		if s.canceledByClient(err) {
+			return
+		}
 		// Invalid DNS query, this stream should be ignored
+		s.resetStream(stream, transport.DoQProtocolError)
 		return
 	}
+	vars.QUICBytesCount.WithLabelValues(s.Addr, s.transport, "in").Add(float64(2 + n))
 
 	msg := new(dns.Msg)
-	msg_len := binary.BigEndian.Uint16(b[:2])
-	if int(msg_len) != n-2 {
-		panic(fmt.Sprintf("message size mismatch: %d vs %d", msg_len, n))
-	}
-	err := msg.Unpack(b[2:])
+	err = msg.Unpack(b[2 : 2+n])
 	if err != nil {
-		fmt.Println(b[:n])
 		// Invalid content
-		fmt.Print("handleQUICStream encountered invalid content: " + err.Error())
+		s.log.Debugf("Invalid query from %s: %s", session.RemoteAddr(), err)
+		s.resetStream(stream, transport.DoQProtocolError)
+		return
+	}
+
+	// The message ID of DoQ queries MUST be 0, receiving any other ID is a protocol error.
+	// https://www.rfc-editor.org/rfc/rfc9250.html#section-4.2.1
+	if msg.Id != 0 {
+		s.log.Debugf("Query from %s has non-zero message ID %d", session.RemoteAddr(), msg.Id)
+		s.resetStream(stream, transport.DoQProtocolError)
 		return
 	}
 
 	// If any message sent on a DoQ connection contains an edns-tcp-keepalive EDNS(0) Option,
 	// this is a fatal error and the recipient of the defective message MUST forcibly abort
 	// the connection immediately.
-	// https://datatracker.ietf.org/doc/html/draft-ietf-dprive-dnsoquic-02#section-6.6.2
-	if opt := msg.IsEdns0(); opt != nil {
-		for _, option := range opt.Option {
-			// Check for EDNS TCP keepalive option
-			if option.Option() == dns.EDNS0TCPKEEPALIVE {
-				// Already closing the connection so we don't care about the error
-				_ = session.CloseWithError(0, "")
+	// https://www.rfc-editor.org/rfc/rfc9250.html#section-5.5.2
+	if hasTCPKeepalive(msg) {
+		s.log.Debugf("Query from %s has an edns-tcp-keepalive option, closing the connection", session.RemoteAddr())
+		// Already closing the connection so we don't care about the error
+		_ = session.CloseWithError(transport.DoQProtocolError, "edns-tcp-keepalive")
+		return
+	}
+
+	select {
+	case <-s.handshakeComplete(session):
+	default:
+		vars.QUICEarlyQueriesCount.WithLabelValues(s.Addr).Inc()
+		if !replaySafe(msg) {
+			// 0-RTT data can be replayed by an attacker, only act on this query once the handshake proved the
+			// client is live. https://www.rfc-editor.org/rfc/rfc9250.html#section-4.5
+			select {
+			case <-s.handshakeComplete(session):
+			case <-session.Context().Done():
+				s.resetStream(stream, transport.DoQRequestCancelled)
+				return
 			}
 		}
 	}
 
 	// Consider renaming DoHWriter or creating a new struct for QUIC
-	dw := &DoHWriter{laddr: s.listenAddr, raddr: session.RemoteAddr()}
+	dw := &DoHWriter{laddr: s.listenAddr, raddr: session.RemoteAddr(), noCompression: s.noCompression}
+	if s.clientAddrKey != nil {
+		dw.raddr = s.clientAddr(msg, session.RemoteAddr())
+	}
+	if t := msg.IsTsig(); t != nil {
+		dw.tsigStatus, dw.tsigMAC = s.verifyTsig(b[2:2+n], t), t.MAC
+	}
+	if c, ok := pkgscion.ClientOf(dw.raddr); ok {
+		ctx = context.WithValue(ctx, SCIONClientKey{}, c)
+	}
 
-	// We just call the normal chain handler - all error handling is done there.
-	// We should expect a packet to be returned that we can send to the client.
-	ctx := context.WithValue(context.Background(), Key{}, s.Server)
-	s.ServeDNS(ctx, dw, msg)
+	if isXFR(msg) {
+		s.serveXFR(ctx, stream, session, dw, msg)
+		return
+	}
+
+	if ctx, ok := s.queryPolicy.apply(ctx, msg); ok {
+		// We just call the normal chain handler - all error handling is done there.
+		// We should expect a packet to be returned that we can send to the client.
+		s.ServeDNS(ctx, dw, msg)
+	} else {
+		refused := new(dns.Msg)
+		refused.SetRcode(msg, dns.RcodeRefused)
+		dw.WriteMsg(refused)
+	}
 
 	if dw.Msg == nil {
-		fmt.Println("message was nil -> stream closed!")
-		_ = stream.Close()
+		// No response means the query is dropped, let the client know it shouldn't wait.
+		s.resetStream(stream, transport.DoQRequestCancelled)
 		return
 	}
+	ln := len(dw.Msgs)
+	if ln > 1 {
+		// Responses that don't fit into the client's buffer are split into multiple messages on the same stream.
+		s.log.Debugf("Answering %s from %s with %d messages", dw.Msg.Question[0].String(), session.RemoteAddr(), ln)
+	}
+
+	writeSpan := startQUICChildSpan(ctx, quicWriteSpanName)
+	if writeSpan != nil {
+		writeSpan.SetTag("doq.messages", ln)
+	}
+	var writeErr error
+	defer func() { finishQUICSpan(writeSpan, writeErr) }()
+
+	mac := dw.tsigMAC
+	for i, response := range dw.Msgs {
+
+		// Plugins may have set an ID of their own, i.e. when forwarding, responses must use 0 as well.
+		// Signed responses are chained by their MACs.
+		buf, macOut, err := s.packResponse(response, mac, i > 0)
+		if macOut != "" {
+			mac = macOut
+		}
+		if err != nil {
+			writeErr = err
+			s.log.Errorf("Failed to pack response to %s: %s", session.RemoteAddr(), err)
+			s.resetStream(stream, transport.DoQInternalError)
+			return
+		}
+		if len(buf) > s.maxMsgSize {
+			s.log.Errorf("Response of %d bytes to %s exceeds the maximum message size of %d", len(buf), session.RemoteAddr(), s.maxMsgSize)
+			m := new(dns.Msg)
+			m.SetRcode(msg, dns.RcodeServerFailure)
+			buf, _ = m.Pack()
+		}
+
+		written, err := stream.Write(addPrefix(buf))
+		vars.QUICBytesCount.WithLabelValues(s.Addr, s.transport, "out").Add(float64(written))
+		if err != nil {
+			writeErr = err
+			// The client cancelled the stream or the connection is gone.
+			s.log.Debugf("Failed to write response %d/%d to %s: %s", i+1, ln, session.RemoteAddr(), err)
+			s.canceledByClient(err)
+			return
+		}
+	}
+}
 
-	// Write the response
-	buf, _ := dw.Msg.Pack()
-	fmt.Println("encoded response(without fst 2 bytes): ", buf)
-	n, e := stream.Write(addPrefix(buf))
-	if e != nil {
-		fmt.Println(e.Error())
-		if n != len(buf) {
-			panic("stream write failure!")
+// hasTCPKeepalive returns true if m carries an edns-tcp-keepalive option, which is not allowed in DoQ.
+func hasTCPKeepalive(m *dns.Msg) bool {
+	opt := m.IsEdns0()
+	if opt == nil {
+		return false
+	}
+	for _, option := range opt.Option {
+		if option.Option() == dns.EDNS0TCPKEEPALIVE {
+			return true
 		}
 	}
+	return false
+}
+
+var (
+	errQueryTooLarge = errors.New("query exceeds the maximum message size")
+	errQueryTooShort = errors.New("query too short")
+	errTrailingData  = errors.New("data after the query")
+)
+
+// readQuery reads a length prefixed query from r into b and returns the length of the query, which
+// starts at b[2]. The query may arrive in any number of reads, after it the stream must be
+// finished, any trailing data is an error.
+func readQuery(r io.Reader, b []byte) (int, error) {
+	if _, err := io.ReadFull(r, b[:2]); err != nil {
+		return 0, err
+	}
+	n := int(binary.BigEndian.Uint16(b[:2]))
+	if n > len(b)-2 {
+		return 0, errQueryTooLarge
+	}
+	if n < minDNSPacketSize {
+		return 0, errQueryTooShort
+	}
+	if _, err := io.ReadFull(r, b[2:2+n]); err != nil {
+		return 0, err
+	}
+
+	// Wait for the STREAM FIN, which is signaled with io.EOF.
+	var extra [1]byte
+	for {
+		m, err := r.Read(extra[:])
+		if m > 0 {
+			return 0, errTrailingData
+		}
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// resetStream aborts both directions of stream with the DoQ error code.
+func (s *ServerQUIC) resetStream(stream quic.Stream, code quic.StreamErrorCode) {
+	stream.CancelRead(code)
+	stream.CancelWrite(code)
+	vars.QUICStreamErrorCount.WithLabelValues(s.Addr, s.transport, doqErrorName(code), "server").Inc()
+}
+
+// canceledByClient returns true if err is the client aborting the stream, which is counted with the
+// DoQ error code the client sent.
+func (s *ServerQUIC) canceledByClient(err error) bool {
+	var serr *quic.StreamError
+	if !errors.As(err, &serr) || !serr.Remote {
+		return false
+	}
+	vars.QUICStreamErrorCount.WithLabelValues(s.Addr, s.transport, doqErrorName(serr.ErrorCode), "client").Inc()
+	return true
+}
+
+// doqErrorName returns the name of the DoQ error code for metrics.
+func doqErrorName(code quic.StreamErrorCode) string {
+	switch code {
+	case transport.DoQNoError:
+		return "no_error"
+	case transport.DoQInternalError:
+		return "internal_error"
+	case transport.DoQProtocolError:
+		return "protocol_error"
+	case transport.DoQRequestCancelled:
+		return "request_cancelled"
+	case transport.DoQExcessiveLoad:
+		return "excessive_load"
+	}
+	return "unknown"
 }
 
 // addPrefix adds a 2-byte prefix with the DNS message length.
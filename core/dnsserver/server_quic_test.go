@@ -0,0 +1,170 @@
+package dnsserver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/coredns/coredns/plugin/metrics/vars"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/quic-go/quic-go"
+)
+
+func TestReadQuery(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	query, _ := m.Pack()
+	prefixed := addPrefix(query)
+
+	tests := []struct {
+		name        string
+		r           io.Reader
+		size        int
+		expectedErr error
+	}{
+		{"single read", bytes.NewReader(prefixed), dns.MaxMsgSize, nil},
+		{"one byte per read", iotest.OneByteReader(bytes.NewReader(prefixed)), dns.MaxMsgSize, nil},
+		{"too large", bytes.NewReader(prefixed), len(query) - 1, errQueryTooLarge},
+		{"too short", bytes.NewReader(addPrefix(query[:10])), dns.MaxMsgSize, errQueryTooShort},
+		{"trailing data", bytes.NewReader(append(prefixed, 0)), dns.MaxMsgSize, errTrailingData},
+		{"truncated", bytes.NewReader(prefixed[:len(prefixed)-1]), dns.MaxMsgSize, io.ErrUnexpectedEOF},
+		{"no FIN", io.MultiReader(bytes.NewReader(prefixed), iotest.ErrReader(iotest.ErrTimeout)), dns.MaxMsgSize, iotest.ErrTimeout},
+	}
+
+	for _, tc := range tests {
+		b := make([]byte, 2+tc.size)
+		n, err := readQuery(tc.r, b)
+		if !errors.Is(err, tc.expectedErr) {
+			t.Errorf("Test %q: expected error %v, got %v", tc.name, tc.expectedErr, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !bytes.Equal(b[2:2+n], query) {
+			t.Errorf("Test %q: expected the query to be read", tc.name)
+		}
+	}
+}
+
+func TestReplaySafe(t *testing.T) {
+	query := func(qtype uint16) *dns.Msg {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", qtype)
+		return m
+	}
+	notify := new(dns.Msg)
+	notify.SetNotify("example.org.")
+	update := new(dns.Msg)
+	update.SetUpdate("example.org.")
+
+	tests := []struct {
+		m        *dns.Msg
+		expected bool
+	}{
+		{query(dns.TypeA), true},
+		{query(dns.TypeSVCB), true},
+		{query(dns.TypeAXFR), false},
+		{query(dns.TypeIXFR), false},
+		{notify, false},
+		{update, false},
+		{new(dns.Msg), false},
+	}
+	for i, tc := range tests {
+		if got := replaySafe(tc.m); got != tc.expected {
+			t.Errorf("Test %d: expected %t, got %t", i, tc.expected, got)
+		}
+	}
+}
+
+func TestHasTCPKeepalive(t *testing.T) {
+	plain := new(dns.Msg)
+	plain.SetQuestion("example.org.", dns.TypeA)
+
+	edns := plain.Copy()
+	edns.SetEdns0(4096, false)
+
+	keepalive := edns.Copy()
+	opt := keepalive.IsEdns0()
+	opt.Option = append(opt.Option, &dns.EDNS0_LOCAL{Code: dns.EDNS0NSID}, &dns.EDNS0_TCP_KEEPALIVE{Code: dns.EDNS0TCPKEEPALIVE})
+
+	tests := []struct {
+		m        *dns.Msg
+		expected bool
+	}{
+		{plain, false},
+		{edns, false},
+		{keepalive, true},
+	}
+	for i, tc := range tests {
+		if got := hasTCPKeepalive(tc.m); got != tc.expected {
+			t.Errorf("Test %d: expected %t, got %t", i, tc.expected, got)
+		}
+	}
+}
+
+func TestDoQErrorName(t *testing.T) {
+	tests := []struct {
+		code     quic.StreamErrorCode
+		expected string
+	}{
+		{transport.DoQNoError, "no_error"},
+		{transport.DoQProtocolError, "protocol_error"},
+		{transport.DoQExcessiveLoad, "excessive_load"},
+		{0x42, "unknown"},
+	}
+	for i, tc := range tests {
+		if got := doqErrorName(tc.code); got != tc.expected {
+			t.Errorf("Test %d: expected %s, got %s", i, tc.expected, got)
+		}
+	}
+}
+
+func TestCanceledByClient(t *testing.T) {
+	s := &ServerQUIC{Server: &Server{Addr: "squic://:8853"}, transport: transport.SQUIC}
+	counter := vars.QUICStreamErrorCount.WithLabelValues(s.Addr, s.transport, "request_cancelled", "client")
+	before := testutil.ToFloat64(counter)
+
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{&quic.StreamError{ErrorCode: transport.DoQRequestCancelled, Remote: true}, true},
+		{fmt.Errorf("read: %w", &quic.StreamError{ErrorCode: transport.DoQRequestCancelled, Remote: true}), true},
+		{&quic.StreamError{ErrorCode: transport.DoQRequestCancelled}, false},
+		{io.ErrUnexpectedEOF, false},
+	}
+	for i, tc := range tests {
+		if got := s.canceledByClient(tc.err); got != tc.expected {
+			t.Errorf("Test %d: expected %t, got %t", i, tc.expected, got)
+		}
+	}
+	if got := testutil.ToFloat64(counter); got != before+2 {
+		t.Errorf("Expected %v stream errors by the client, got %v", before+2, got)
+	}
+}
+
+func TestReserveSession(t *testing.T) {
+	s := &ServerQUIC{maxSessions: 2}
+	for i := 0; i < 2; i++ {
+		if !s.reserveSession() {
+			t.Fatalf("Expected session %d to be accepted", i)
+		}
+	}
+	if s.reserveSession() {
+		t.Error("Expected the session beyond max_sessions to be refused")
+	}
+	if s.openSessions != 2 {
+		t.Errorf("Expected a refused session not to be counted, got %d open", s.openSessions)
+	}
+
+	if unlimited := (&ServerQUIC{}); !unlimited.reserveSession() {
+		t.Error("Expected sessions to be accepted without max_sessions")
+	}
+}
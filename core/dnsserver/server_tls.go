@@ -17,7 +17,8 @@ import (
 // ServerTLS represents an instance of a TLS-over-DNS-server.
 type ServerTLS struct {
 	*Server
-	tlsConfig *tls.Config
+	tlsConfig  *tls.Config
+	listenAddr net.Addr
 }
 
 // NewServerTLS returns a new CoreDNS TLS server and compiles all plugin in to it.
@@ -79,6 +80,9 @@ func (s *ServerTLS) Listen() (net.Listener, error) {
 	if err != nil {
 		return nil, err
 	}
+	s.m.Lock()
+	s.listenAddr = l.Addr()
+	s.m.Unlock()
 	return l, nil
 }
 
@@ -93,9 +97,10 @@ func (s *ServerTLS) OnStartupComplete() {
 	}
 
 	out := startUpZones(transport.TLS+"://", s.Addr, s.zones)
-	if out != "" {
-		fmt.Print(out)
-	}
+	s.m.Lock()
+	out += listenerInfo(transport.TLS, s.boundAddr(s.listenAddr), s.tlsConfig)
+	s.m.Unlock()
+	fmt.Print(out)
 }
 
 const (
@@ -12,9 +12,15 @@ package dnsserver
 var Directives = []string{
 	"metadata",
 	"geoip",
+	"quota",
 	"cancel",
 	"tls",
+	"acme",
 	"timeouts",
+	"quic",
+	"msgsize",
+	"scion",
+	"scion_policy",
 	"reload",
 	"nsid",
 	"bufsize",
@@ -31,11 +37,17 @@ var Directives = []string{
 	"dnstap",
 	"local",
 	"dns64",
+	"scion64",
 	"acl",
+	"maintenance",
 	"any",
+	"ddr",
 	"chaos",
 	"loadbalance",
 	"tsig",
+	"ttl",
+	"warmup",
+	"steer",
 	"cache",
 	"rewrite",
 	"header",
@@ -44,6 +56,7 @@ var Directives = []string{
 	"minimal",
 	"template",
 	"transfer",
+	"autoreverse",
 	"hosts",
 	"route53",
 	"azure",
@@ -54,6 +67,7 @@ var Directives = []string{
 	"rhine",
 	"auto",
 	"secondary",
+	"stub",
 	"etcd",
 	"loop",
 	"forward",
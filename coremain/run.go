@@ -12,6 +12,7 @@ import (
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
 )
 
 func init() {
@@ -24,6 +25,7 @@ func init() {
 	flag.StringVar(&caddy.PidFile, "pidfile", "", "Path to write pid file")
 	flag.BoolVar(&version, "version", false, "Show version")
 	flag.BoolVar(&dnsserver.Quiet, "quiet", false, "Quiet mode (no initialization output)")
+	flag.BoolVar(&logJSON, "log.json", false, "Write the logs as JSON objects, one per line")
 
 	caddy.RegisterCaddyfileLoader("flag", caddy.LoaderFunc(confLoader))
 	caddy.SetDefaultCaddyfileLoader("default", caddy.LoaderFunc(defaultLoader))
@@ -46,6 +48,9 @@ func Run() {
 
 	log.SetOutput(os.Stdout)
 	log.SetFlags(0) // Set to 0 because we're doing our own time, with timezone
+	if logJSON {
+		clog.JSON.Set()
+	}
 
 	if version {
 		showVersion()
@@ -169,6 +174,7 @@ var (
 	conf    string
 	version bool
 	plugins bool
+	logJSON bool
 )
 
 // Build information obtained with the help of -ldflags
@@ -8,6 +8,7 @@ import (
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metrics"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 	"github.com/coredns/coredns/request"
 
 	"github.com/infobloxopen/go-trees/iptree"
@@ -33,11 +34,14 @@ type action int
 
 // policy defines the ACL policy for DNS queries.
 // A policy performs the specified action (block/allow) on all DNS queries
-// matched by source IP or QTYPE.
+// matched by source IP, or the ISD-AS of SCION clients, or QTYPE, and, if
+// certs are given, the client certificate of DoQ clients.
 type policy struct {
 	action action
 	qtypes map[uint16]struct{}
 	filter *iptree.Tree
+	ias    []pkgscion.IAPattern
+	certs  []string
 }
 
 const (
@@ -67,7 +71,7 @@ RulesCheckLoop:
 			continue
 		}
 
-		action := matchWithPolicies(rule.policies, w, r)
+		action := matchWithPolicies(ctx, rule.policies, w, r)
 		switch action {
 		case actionDrop:
 			{
@@ -109,11 +113,14 @@ RulesCheckLoop:
 
 // matchWithPolicies matches the DNS query with a list of ACL polices and returns suitable
 // action against the query.
-func matchWithPolicies(policies []policy, w dns.ResponseWriter, r *dns.Msg) action {
+func matchWithPolicies(ctx context.Context, policies []policy, w dns.ResponseWriter, r *dns.Msg) action {
 	state := request.Request{W: w, Req: r}
 
 	var ip net.IP
-	if idx := strings.IndexByte(state.IP(), '%'); idx >= 0 {
+	scionClient, isSCION := pkgscion.ClientOf(w.RemoteAddr())
+	if isSCION {
+		ip = net.ParseIP(scionClient.Host)
+	} else if idx := strings.IndexByte(state.IP(), '%'); idx >= 0 {
 		ip = net.ParseIP(state.IP()[:idx])
 	} else {
 		ip = net.ParseIP(state.IP())
@@ -135,7 +142,10 @@ func matchWithPolicies(policies []policy, w dns.ResponseWriter, r *dns.Msg) acti
 		}
 
 		_, contained := policy.filter.GetByIP(ip)
-		if !contained {
+		if !contained && !(isSCION && matchIA(policy.ias, scionClient.IA)) {
+			continue
+		}
+		if len(policy.certs) > 0 && !matchCert(ctx, policy.certs) {
 			continue
 		}
 
@@ -145,6 +155,16 @@ func matchWithPolicies(policies []policy, w dns.ResponseWriter, r *dns.Msg) acti
 	return actionNone
 }
 
+// matchIA returns true if ia matches any of the patterns.
+func matchIA(patterns []pkgscion.IAPattern, ia pkgscion.IA) bool {
+	for _, p := range patterns {
+		if p.Matches(ia) {
+			return true
+		}
+	}
+	return false
+}
+
 // Name implements the plugin.Handler interface.
 func (a ACL) Name() string {
 	return "acl"
@@ -2,9 +2,13 @@ package acl
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/url"
 	"testing"
 
 	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin/test"
 
 	"github.com/miekg/dns"
@@ -597,3 +601,117 @@ func TestACLServeDNS(t *testing.T) {
 		})
 	}
 }
+
+func TestACLServeDNSSCION(t *testing.T) {
+	tests := []struct {
+		config    string
+		source    string
+		wantRcode int
+	}{
+		{`acl {
+			block net 19-ffaa:1:*
+		}`, "19-ffaa:1:1067,[10.0.0.1]:40000", dns.RcodeRefused},
+		{`acl {
+			block net 19-ffaa:1:*
+		}`, "19-ffaa:0:1067,[10.0.0.1]:40000", dns.RcodeSuccess},
+		{`acl {
+			block net 17-* 10.0.0.0/8
+		}`, "19-ffaa:1:1067,[10.0.0.1]:40000", dns.RcodeRefused},
+		{`acl {
+			allow net *-ffaa:1:1067
+			block
+		}`, "17-ffaa:1:1067,[192.168.0.1]:40000", dns.RcodeSuccess},
+		{`acl {
+			allow net *-ffaa:1:1067
+			block
+		}`, "17-ffaa:1:1068,[192.168.0.1]:40000", dns.RcodeRefused},
+	}
+	for i, tc := range tests {
+		a, err := parse(NewTestControllerWithZones(tc.config, []string{"."}))
+		if err != nil {
+			t.Fatalf("Test %d: cannot parse acl from config: %v", i, err)
+		}
+		a.Next = test.NextHandler(dns.RcodeSuccess, nil)
+		w := &testResponseWriter{ResponseWriter: test.ResponseWriter{RemoteSCION: tc.source}}
+		m := new(dns.Msg)
+		m.SetQuestion("www.example.org.", dns.TypeA)
+		rcode, _ := a.ServeDNS(context.Background(), w, m)
+		if w.Msg != nil {
+			rcode = w.Rcode
+		}
+		if rcode != tc.wantRcode {
+			t.Errorf("Test %d: expected rcode %d for %s, got %d", i, tc.wantRcode, tc.source, rcode)
+		}
+	}
+}
+
+func TestACLServeDNSCert(t *testing.T) {
+	secondary := &x509.Certificate{DNSNames: []string{"ns2.secondaries.example.org"}}
+	client := &x509.Certificate{DNSNames: []string{"client.example.net"}, URIs: []*url.URL{{Scheme: "spiffe", Host: "example.net", Path: "/resolver"}}}
+
+	config := `acl example.org {
+		allow type AXFR IXFR cert *.secondaries.example.org
+		block type AXFR IXFR
+		allow cert spiffe://example.net/resolver
+		block
+	}`
+	tests := []struct {
+		cert      *x509.Certificate // nil for queries without a verified client certificate
+		qtype     uint16
+		wantRcode int
+	}{
+		{secondary, dns.TypeAXFR, dns.RcodeSuccess},
+		{client, dns.TypeAXFR, dns.RcodeRefused},
+		{nil, dns.TypeAXFR, dns.RcodeRefused},
+		{client, dns.TypeA, dns.RcodeSuccess},
+		{secondary, dns.TypeA, dns.RcodeRefused},
+		{nil, dns.TypeA, dns.RcodeRefused},
+	}
+
+	a, err := parse(NewTestControllerWithZones(config, []string{"example.org."}))
+	if err != nil {
+		t.Fatalf("Cannot parse acl from config: %v", err)
+	}
+	a.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	for i, tc := range tests {
+		ctx := context.Background()
+		if tc.cert != nil {
+			peer := dnsserver.QUICPeer{Transport: "quic", TLS: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tc.cert}}}}
+			ctx = context.WithValue(ctx, dnsserver.QUICPeerKey{}, peer)
+		}
+		w := &testResponseWriter{}
+		w.setRemoteIP("10.0.0.1")
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", tc.qtype)
+		rcode, _ := a.ServeDNS(ctx, w, m)
+		if w.Msg != nil {
+			rcode = w.Rcode
+		}
+		if rcode != tc.wantRcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, tc.wantRcode, rcode)
+		}
+	}
+}
+
+func TestMatchSAN(t *testing.T) {
+	cert := &x509.Certificate{DNSNames: []string{"ns2.Secondaries.example.org"}, EmailAddresses: []string{"hostmaster@example.org"}}
+	tests := []struct {
+		pattern string
+		match   bool
+	}{
+		{"*", true},
+		{"ns2.secondaries.example.org", true},
+		{"ns2.secondaries.example.org.", true},
+		{"*.secondaries.example.org", true},
+		{"*.example.org", true},
+		{"*.ns2.secondaries.example.org", false},
+		{"ns1.secondaries.example.org", false},
+		{"hostmaster@example.org", true},
+		{"spiffe://example.org/ns2", false},
+	}
+	for i, tc := range tests {
+		if match := matchSAN(cert, tc.pattern); match != tc.match {
+			t.Errorf("Test %d: expected %t for %q, got %t", i, tc.match, tc.pattern, match)
+		}
+	}
+}
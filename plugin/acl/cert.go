@@ -0,0 +1,63 @@
+package acl
+
+import (
+	"context"
+	"crypto/x509"
+	"strings"
+
+	"github.com/coredns/coredns/core/dnsserver"
+
+	"github.com/miekg/dns"
+)
+
+// matchCert returns true if the DoQ client of the query in ctx presented a certificate that the server
+// verified, with a subject alternative name that matches one of patterns. Only the quic and squic servers
+// know client certificates, and only if the tls plugin asks for them with client_auth.
+func matchCert(ctx context.Context, patterns []string) bool {
+	peer, ok := dnsserver.QUICPeerOf(ctx)
+	if !ok || len(peer.TLS.VerifiedChains) == 0 || len(peer.TLS.VerifiedChains[0]) == 0 {
+		return false
+	}
+	leaf := peer.TLS.VerifiedChains[0][0]
+	for _, p := range patterns {
+		if matchSAN(leaf, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSAN returns true if pattern matches a subject alternative name of cert. The pattern * matches any
+// certificate, *.example.org all DNS names below example.org. Other patterns must be equal to a DNS
+// name, a URI or an email address.
+func matchSAN(cert *x509.Certificate, pattern string) bool {
+	if pattern == "*" {
+		return true
+	}
+	if strings.HasPrefix(pattern, "*.") {
+		parent := dns.Fqdn(pattern[2:])
+		for _, name := range cert.DNSNames {
+			name = dns.Fqdn(name)
+			if !dns.IsSubDomain(name, parent) && dns.IsSubDomain(parent, name) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, name := range cert.DNSNames {
+		if strings.EqualFold(dns.Fqdn(name), dns.Fqdn(pattern)) {
+			return true
+		}
+	}
+	for _, u := range cert.URIs {
+		if u.String() == pattern {
+			return true
+		}
+	}
+	for _, email := range cert.EmailAddresses {
+		if strings.EqualFold(email, pattern) {
+			return true
+		}
+	}
+	return false
+}
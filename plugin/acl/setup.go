@@ -7,6 +7,7 @@ import (
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 
 	"github.com/infobloxopen/go-trees/iptree"
 	"github.com/miekg/dns"
@@ -71,7 +72,7 @@ func parse(c *caddy.Controller) (ACL, error) {
 			remainingTokens := c.RemainingArgs()
 			for len(remainingTokens) > 0 {
 				if !isPreservedIdentifier(remainingTokens[0]) {
-					return a, c.Errf("unexpected token %q; expect 'type | net'", remainingTokens[0])
+					return a, c.Errf("unexpected token %q; expect 'type | net | cert'", remainingTokens[0])
 				}
 				section := strings.ToLower(remainingTokens[0])
 
@@ -107,6 +108,14 @@ func parse(c *caddy.Controller) (ACL, error) {
 							p.filter = newDefaultFilter()
 							break
 						}
+						if strings.Contains(token, "-") {
+							ia, err := pkgscion.ParseIAPattern(token)
+							if err != nil {
+								return a, c.Errf("illegal ISD-AS pattern %q", token)
+							}
+							p.ias = append(p.ias, ia)
+							continue
+						}
 						token = normalize(token)
 						_, source, err := net.ParseCIDR(token)
 						if err != nil {
@@ -114,8 +123,10 @@ func parse(c *caddy.Controller) (ACL, error) {
 						}
 						p.filter.InplaceInsertNet(source, struct{}{})
 					}
+				case "cert":
+					p.certs = append(p.certs, tokens...)
 				default:
-					return a, c.Errf("unexpected token %q; expect 'type | net'", section)
+					return a, c.Errf("unexpected token %q; expect 'type | net | cert'", section)
 				}
 			}
 
@@ -138,7 +149,7 @@ func parse(c *caddy.Controller) (ACL, error) {
 
 func isPreservedIdentifier(token string) bool {
 	identifier := strings.ToLower(token)
-	return identifier == "type" || identifier == "net"
+	return identifier == "type" || identifier == "net" || identifier == "cert"
 }
 
 // normalize appends '/32' for any single IPv4 address and '/128' for IPv6.
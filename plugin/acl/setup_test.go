@@ -95,6 +95,38 @@ func TestSetup(t *testing.T) {
 			}`,
 			false,
 		},
+		{
+			"SCION 1",
+			`acl example.org {
+				block net 19-ffaa:1:* 17-* 192.168.3.0/24
+				allow net *-ffaa:1:1067
+			}`,
+			false,
+		},
+		{
+			"SCION 2",
+			`acl example.org {
+				block net 19-ffaa:1
+			}`,
+			true,
+		},
+		{
+			"Cert 1",
+			`acl example.org {
+				allow type AXFR IXFR cert *.secondaries.example.org net 19-ffaa:1:*
+				block type AXFR IXFR
+				allow cert *
+				block
+			}`,
+			false,
+		},
+		{
+			"Cert 2",
+			`acl example.org {
+				allow cert
+			}`,
+			true,
+		},
 		{
 			"Missing argument 1",
 			`acl {
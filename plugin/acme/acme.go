@@ -0,0 +1,49 @@
+// Package acme implements a plugin that obtains and renews the certificate of the DNS-over-QUIC listeners
+// from an ACME CA, such as Let's Encrypt.
+package acme
+
+import (
+	"context"
+	"strings"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// ACME answers the TXT queries for the DNS-01 challenges of the certificates being obtained, all other
+// queries are passed on.
+type ACME struct {
+	Next plugin.Handler
+
+	manager *manager
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (a ACME) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	qname := strings.ToLower(state.Name())
+
+	if state.QType() != dns.TypeTXT || !strings.HasPrefix(qname, challengePrefix) {
+		return plugin.NextOrFailure(a.Name(), a.Next, ctx, w, r)
+	}
+	values := challenges.lookupTXT(qname)
+	if len(values) == 0 {
+		return plugin.NextOrFailure(a.Name(), a.Next, ctx, w, r)
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+	for _, v := range values {
+		// A TTL of 0, so resolvers of the CA don't hold on to the value of a previous attempt.
+		hdr := dns.RR_Header{Name: state.QName(), Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 0}
+		m.Answer = append(m.Answer, &dns.TXT{Hdr: hdr, Txt: []string{v}})
+	}
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the plugin.Handler interface.
+func (a ACME) Name() string { return "acme" }
@@ -0,0 +1,68 @@
+package acme
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestServeDNS(t *testing.T) {
+	a := ACME{Next: test.NextHandler(dns.RcodeRefused, nil)}
+
+	challenges.addTXT("example.org", "token-1")
+	challenges.addTXT("*.example.org", "token-2")
+	defer challenges.removeTXT("example.org", "token-1")
+
+	tests := []struct {
+		qname    string
+		qtype    uint16
+		rcode    int
+		expected []string
+	}{
+		{"_acme-challenge.example.org.", dns.TypeTXT, dns.RcodeSuccess, []string{"token-1", "token-2"}},
+		{"_ACME-Challenge.Example.org.", dns.TypeTXT, dns.RcodeSuccess, []string{"token-1", "token-2"}},
+		// Passed on to the next plugin.
+		{"_acme-challenge.example.org.", dns.TypeA, dns.RcodeRefused, nil},
+		{"_acme-challenge.example.net.", dns.TypeTXT, dns.RcodeRefused, nil},
+		{"example.org.", dns.TypeTXT, dns.RcodeRefused, nil},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		rcode, _ := a.ServeDNS(context.TODO(), rec, m)
+		if rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, tc.rcode, rcode)
+			continue
+		}
+		if tc.expected == nil {
+			continue
+		}
+		if len(rec.Msg.Answer) != len(tc.expected) {
+			t.Fatalf("Test %d: expected %d answers, got %d", i, len(tc.expected), len(rec.Msg.Answer))
+		}
+		for j, rr := range rec.Msg.Answer {
+			txt := rr.(*dns.TXT)
+			if txt.Txt[0] != tc.expected[j] {
+				t.Errorf("Test %d: expected %q, got %q", i, tc.expected[j], txt.Txt[0])
+			}
+			if txt.Hdr.Name != tc.qname {
+				t.Errorf("Test %d: expected owner %s, got %s", i, tc.qname, txt.Hdr.Name)
+			}
+		}
+		if !rec.Msg.Authoritative {
+			t.Errorf("Test %d: expected an authoritative answer", i)
+		}
+	}
+
+	// Withdrawn challenges are no longer answered.
+	challenges.removeTXT("*.example.org", "token-2")
+	if values := challenges.lookupTXT("_acme-challenge.example.org."); len(values) != 1 || values[0] != "token-1" {
+		t.Errorf("Expected only token-1 to be left, got %v", values)
+	}
+}
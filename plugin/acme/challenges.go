@@ -0,0 +1,98 @@
+package acme
+
+import (
+	"crypto/tls"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// challengePrefix is the label below which the TXT records of DNS-01 challenges are published.
+const challengePrefix = "_acme-challenge."
+
+// challenges holds the pending challenges of all certificates being obtained. It's process wide, so the
+// DNS-01 records can be answered by every server block with the acme plugin, in particular the ones the
+// CA queries over plain DNS.
+var challenges = newChallengeStore()
+
+// challengeStore holds the TXT values of pending DNS-01 challenges by owner name, and the certificates of
+// pending TLS-ALPN-01 challenges by domain.
+type challengeStore struct {
+	sync.RWMutex
+	txt  map[string][]string
+	alpn map[string]*tls.Certificate
+}
+
+func newChallengeStore() *challengeStore {
+	return &challengeStore{txt: make(map[string][]string), alpn: make(map[string]*tls.Certificate)}
+}
+
+// challengeName returns the owner name of the DNS-01 challenge for domain, wildcards are validated at the
+// name they're below of.
+func challengeName(domain string) string {
+	return challengePrefix + dns.Fqdn(strings.ToLower(strings.TrimPrefix(domain, "*.")))
+}
+
+// addTXT publishes value for the DNS-01 challenge of domain.
+func (c *challengeStore) addTXT(domain, value string) {
+	name := challengeName(domain)
+	c.Lock()
+	c.txt[name] = append(c.txt[name], value)
+	c.Unlock()
+}
+
+// removeTXT withdraws value from the DNS-01 challenge of domain.
+func (c *challengeStore) removeTXT(domain, value string) {
+	name := challengeName(domain)
+	c.Lock()
+	defer c.Unlock()
+	values := c.txt[name][:0]
+	for _, v := range c.txt[name] {
+		if v != value {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		delete(c.txt, name)
+		return
+	}
+	c.txt[name] = values
+}
+
+// lookupTXT returns the values published for the lower cased owner name qname.
+func (c *challengeStore) lookupTXT(qname string) []string {
+	c.RLock()
+	defer c.RUnlock()
+	values := c.txt[qname]
+	if len(values) == 0 {
+		return nil
+	}
+	return append([]string(nil), values...)
+}
+
+// addALPN publishes cert for the TLS-ALPN-01 challenge of domain.
+func (c *challengeStore) addALPN(domain string, cert *tls.Certificate) {
+	c.Lock()
+	c.alpn[strings.ToLower(domain)] = cert
+	c.Unlock()
+}
+
+// removeALPN withdraws the TLS-ALPN-01 challenge of domain.
+func (c *challengeStore) removeALPN(domain string) {
+	c.Lock()
+	delete(c.alpn, strings.ToLower(domain))
+	c.Unlock()
+}
+
+// alpnCertificate is the GetCertificate of the TLS-ALPN-01 listener.
+func (c *challengeStore) alpnCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.RLock()
+	defer c.RUnlock()
+	cert, ok := c.alpn[strings.ToLower(hello.ServerName)]
+	if !ok {
+		return nil, errors.New("no pending challenge for " + hello.ServerName)
+	}
+	return cert, nil
+}
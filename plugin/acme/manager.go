@@ -0,0 +1,377 @@
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+// Challenge types, see RFC 8555 section 8 and RFC 8737.
+const (
+	challengeDNS01     = "dns-01"
+	challengeTLSALPN01 = "tls-alpn-01"
+)
+
+const (
+	// defaultRenewBefore is how long before it expires the certificate is renewed if not configured.
+	defaultRenewBefore = 30 * 24 * time.Hour
+	// checkInterval is how often the certificate is checked for renewal.
+	checkInterval = 12 * time.Hour
+	// minRetry and maxRetry bound the backoff after a failed attempt.
+	minRetry = time.Minute
+	maxRetry = 2 * time.Hour
+	// obtainTimeout bounds a single attempt to obtain a certificate, including validation.
+	obtainTimeout = 10 * time.Minute
+)
+
+// manager obtains the certificate for domains, stores it in storage and renews it before it expires. The
+// certificate is handed out by getCertificate, the GetCertificate of the DoQ listeners.
+type manager struct {
+	domains     []string
+	email       string
+	ca          string
+	challenge   string
+	alpnAddr    string
+	storage     string
+	renewBefore time.Duration
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	leaf *x509.Certificate
+
+	stop chan struct{}
+}
+
+// getCertificate implements tls.Config.GetCertificate.
+func (m *manager) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.cert == nil {
+		return nil, fmt.Errorf("no certificate for %s obtained yet", strings.Join(m.domains, ", "))
+	}
+	return m.cert, nil
+}
+
+// needsRenewal returns true if there is no certificate, or it expires within renewBefore from now.
+func (m *manager) needsRenewal(now time.Time) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.leaf == nil || now.Add(m.renewBefore).After(m.leaf.NotAfter)
+}
+
+// set makes cert the certificate handed out.
+func (m *manager) set(cert *tls.Certificate, leaf *x509.Certificate) {
+	m.mu.Lock()
+	m.cert = cert
+	m.leaf = leaf
+	m.mu.Unlock()
+}
+
+// start loads the stored certificate, and obtains or renews it in the background. It must be in the
+// background, as the CA validates DNS-01 challenges by querying us.
+func (m *manager) start() error {
+	if err := os.MkdirAll(m.storage, 0o700); err != nil {
+		return err
+	}
+	if err := m.load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warningf("Ignoring stored certificate for %s: %s", m.domains[0], err)
+	}
+	m.stop = make(chan struct{})
+	go m.run(m.stop)
+	return nil
+}
+
+func (m *manager) shutdown() error {
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+	return nil
+}
+
+func (m *manager) run(stop <-chan struct{}) {
+	retry := minRetry
+	for {
+		wait := checkInterval
+		if m.needsRenewal(time.Now()) {
+			if err := m.renew(stop); err != nil {
+				log.Errorf("Failed to obtain certificate for %s, retrying in %s: %s", strings.Join(m.domains, ", "), retry, err)
+				wait = retry
+				retry *= 2
+				if retry > maxRetry {
+					retry = maxRetry
+				}
+			} else {
+				retry = minRetry
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// renew obtains a new certificate, stores and uses it.
+func (m *manager) renew(stop <-chan struct{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), obtainTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	certPEM, keyPEM, err := m.obtain(ctx)
+	if err != nil {
+		return err
+	}
+	cert, leaf, err := parseCertificate(certPEM, keyPEM)
+	if err != nil {
+		return err
+	}
+	if err := m.save(certPEM, keyPEM); err != nil {
+		// We have the certificate, but will obtain it again on the next start.
+		log.Warningf("Failed to store certificate for %s: %s", m.domains[0], err)
+	}
+	m.set(cert, leaf)
+	log.Infof("Obtained certificate for %s, valid until %s", strings.Join(m.domains, ", "), leaf.NotAfter.UTC().Format("2006-01-02"))
+	return nil
+}
+
+// obtain runs an ACME order for the domains and returns the PEM encoded certificate chain and key.
+func (m *manager) obtain(ctx context.Context) (certPEM, keyPEM []byte, err error) {
+	accountKey, err := m.accountKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	client := &acme.Client{Key: accountKey, DirectoryURL: m.ca, UserAgent: "CoreDNS"}
+
+	account := &acme.Account{}
+	if m.email != "" {
+		account.Contact = []string{"mailto:" + m.email}
+	}
+	if _, err := client.Register(ctx, account, acme.AcceptTOS); err != nil && err != acme.ErrAccountAlreadyExists {
+		return nil, nil, fmt.Errorf("registering account: %w", err)
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(m.domains...))
+	if err != nil {
+		return nil, nil, err
+	}
+	if order.Status != acme.StatusReady {
+		if m.challenge == challengeTLSALPN01 {
+			ln, err := m.listenALPN()
+			if err != nil {
+				return nil, nil, err
+			}
+			defer ln.Close()
+		}
+		for _, u := range order.AuthzURLs {
+			if err := m.authorize(ctx, client, u); err != nil {
+				return nil, nil, err
+			}
+		}
+		if order, err = client.WaitOrder(ctx, order.URI); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: m.domains[0]},
+		DNSNames: m.domains,
+	}, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	der, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, b := range der {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: b})...)
+	}
+	keyPEM, err = encodeKey(key)
+	return certPEM, keyPEM, err
+}
+
+// authorize completes the challenge of the authorization at url.
+func (m *manager) authorize(ctx context.Context, client *acme.Client, url string) error {
+	z, err := client.GetAuthorization(ctx, url)
+	if err != nil {
+		return err
+	}
+	if z.Status == acme.StatusValid {
+		return nil
+	}
+	var chal *acme.Challenge
+	for _, c := range z.Challenges {
+		if c.Type == m.challenge {
+			chal = c
+			break
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("CA offers no %s challenge for %s", m.challenge, z.Identifier.Value)
+	}
+
+	domain := z.Identifier.Value
+	switch m.challenge {
+	case challengeDNS01:
+		value, err := client.DNS01ChallengeRecord(chal.Token)
+		if err != nil {
+			return err
+		}
+		challenges.addTXT(domain, value)
+		defer challenges.removeTXT(domain, value)
+	case challengeTLSALPN01:
+		cert, err := client.TLSALPN01ChallengeCert(chal.Token, domain)
+		if err != nil {
+			return err
+		}
+		challenges.addALPN(domain, &cert)
+		defer challenges.removeALPN(domain)
+	}
+
+	if _, err := client.Accept(ctx, chal); err != nil {
+		return err
+	}
+	if _, err := client.WaitAuthorization(ctx, z.URI); err != nil {
+		return fmt.Errorf("%s challenge for %s failed: %w", m.challenge, domain, err)
+	}
+	return nil
+}
+
+// listenALPN starts the TCP listener the CA connects to for TLS-ALPN-01 challenges. It only completes the
+// handshake, which is all the validation needs.
+func (m *manager) listenALPN() (net.Listener, error) {
+	ln, err := tls.Listen("tcp", m.alpnAddr, &tls.Config{
+		NextProtos:     []string{acme.ALPNProto},
+		GetCertificate: challenges.alpnCertificate,
+	})
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				conn.SetDeadline(time.Now().Add(10 * time.Second))
+				conn.(*tls.Conn).Handshake()
+				conn.Close()
+			}()
+		}
+	}()
+	return ln, nil
+}
+
+// file returns the path of the stored file name.
+func (m *manager) file(name string) string { return filepath.Join(m.storage, name) }
+
+// certName returns the base name of the stored certificate and key, wildcards are stored as _.
+func (m *manager) certName() string { return strings.ReplaceAll(m.domains[0], "*", "_") }
+
+// load reads the stored certificate, if it is for our domains.
+func (m *manager) load() error {
+	certPEM, err := os.ReadFile(m.file(m.certName() + ".crt"))
+	if err != nil {
+		return err
+	}
+	keyPEM, err := os.ReadFile(m.file(m.certName() + ".key"))
+	if err != nil {
+		return err
+	}
+	cert, leaf, err := parseCertificate(certPEM, keyPEM)
+	if err != nil {
+		return err
+	}
+	for _, d := range m.domains {
+		if err := leaf.VerifyHostname(strings.Replace(d, "*", "x", 1)); err != nil {
+			return fmt.Errorf("stored certificate is not valid for %s", d)
+		}
+	}
+	m.set(cert, leaf)
+	return nil
+}
+
+// save stores the certificate and its key.
+func (m *manager) save(certPEM, keyPEM []byte) error {
+	if err := os.WriteFile(m.file(m.certName()+".key"), keyPEM, 0o600); err != nil {
+		return err
+	}
+	return os.WriteFile(m.file(m.certName()+".crt"), certPEM, 0o644)
+}
+
+// accountKey returns the stored key of the ACME account, it is created if there is none.
+func (m *manager) accountKey() (crypto.Signer, error) {
+	path := m.file("account.key")
+	if b, err := os.ReadFile(path); err == nil {
+		block, _ := pem.Decode(b)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM data in %s", path)
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	b, err := encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return key, os.WriteFile(path, b, 0o600)
+}
+
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// parseCertificate returns the certificate of the PEM encoded chain and key, and its parsed leaf.
+func parseCertificate(certPEM, keyPEM []byte) (*tls.Certificate, *x509.Certificate, error) {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	cert.Leaf = leaf
+	return &cert, leaf, nil
+}
@@ -0,0 +1,89 @@
+package acme
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// selfSigned returns a PEM encoded certificate for names that expires in validFor, and its key.
+func selfSigned(t *testing.T, validFor time.Duration, names ...string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(validFor),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM, err = encodeKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), keyPEM
+}
+
+func TestManagerStorage(t *testing.T) {
+	m := &manager{domains: []string{"example.org", "*.example.org"}, storage: t.TempDir(), renewBefore: 30 * 24 * time.Hour}
+
+	if _, err := m.getCertificate(&tls.ClientHelloInfo{}); err == nil {
+		t.Error("Expected an error without certificate")
+	}
+	if !m.needsRenewal(time.Now()) {
+		t.Error("Expected renewal without certificate")
+	}
+
+	certPEM, keyPEM := selfSigned(t, 10*24*time.Hour, "example.org", "*.example.org")
+	if err := m.save(certPEM, keyPEM); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.load(); err != nil {
+		t.Fatalf("Expected the stored certificate to load, got %s", err)
+	}
+	if _, err := m.getCertificate(&tls.ClientHelloInfo{}); err != nil {
+		t.Errorf("Expected a certificate, got %s", err)
+	}
+	if !m.needsRenewal(time.Now()) {
+		t.Error("Expected renewal of a certificate expiring within renew_before")
+	}
+	m.renewBefore = 24 * time.Hour
+	if m.needsRenewal(time.Now()) {
+		t.Error("Expected no renewal of a certificate expiring after renew_before")
+	}
+
+	// A stored certificate for other domains isn't used.
+	other := &manager{domains: []string{"example.org", "example.net"}, storage: m.storage}
+	if err := other.load(); err == nil {
+		t.Error("Expected the certificate not to load for example.net")
+	}
+}
+
+func TestAccountKey(t *testing.T) {
+	m := &manager{storage: t.TempDir()}
+	key, err := m.accountKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := m.accountKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.(*ecdsa.PrivateKey).Equal(again) {
+		t.Error("Expected the stored account key to be reused")
+	}
+}
@@ -0,0 +1,164 @@
+package acme
+
+import (
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	ptls "github.com/coredns/coredns/plugin/pkg/tls"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"golang.org/x/crypto/acme"
+)
+
+var log = clog.NewWithPlugin("acme")
+
+func init() { plugin.Register("acme", setup) }
+
+// doqProtos are the ALPNs of the DoQ listeners, as set by the tls plugin.
+var doqProtos = []string{transport.DoQALPN}
+
+const (
+	// defaultStorage is where the account key and certificates are stored if not configured, relative to
+	// the root.
+	defaultStorage = "acme"
+	// defaultALPNAddr is where the CA connects to for TLS-ALPN-01 challenges.
+	defaultALPNAddr = ":443"
+)
+
+func setup(c *caddy.Controller) error {
+	m, err := parse(c)
+	if err != nil {
+		return plugin.Error("acme", err)
+	}
+
+	config := dnsserver.GetConfig(c)
+	tlsConfig, err := ptls.NewTLSConfigFromArgs()
+	if err != nil {
+		return plugin.Error("acme", err)
+	}
+	tlsConfig.GetCertificate = m.getCertificate
+	tlsDoQ := tlsConfig.Clone()
+	tlsDoQ.NextProtos = doqProtos
+
+	config.TLSConfig = tlsConfig
+	config.TLSConfigQUIC = tlsDoQ
+
+	c.OnStartup(m.start)
+	c.OnShutdown(m.shutdown)
+
+	config.AddPlugin(func(next plugin.Handler) plugin.Handler {
+		return ACME{Next: next, manager: m}
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller) (*manager, error) {
+	config := dnsserver.GetConfig(c)
+	if config.TLSConfig != nil {
+		return nil, c.Err("TLS already configured for this server instance")
+	}
+
+	m := &manager{
+		ca:          acme.LetsEncryptURL,
+		challenge:   challengeDNS01,
+		alpnAddr:    defaultALPNAddr,
+		storage:     defaultStorage,
+		renewBefore: defaultRenewBefore,
+	}
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		for _, z := range plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys) {
+			if z == "." {
+				continue
+			}
+			m.domains = append(m.domains, strings.TrimSuffix(z, "."))
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "email":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				m.email = args[0]
+			case "ca":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				if u, err := url.Parse(args[0]); err != nil || u.Scheme != "https" {
+					return nil, c.Errf("invalid ca URL '%s'", args[0])
+				}
+				m.ca = args[0]
+			case "challenge":
+				args := c.RemainingArgs()
+				if len(args) < 1 || len(args) > 2 {
+					return nil, c.ArgErr()
+				}
+				switch args[0] {
+				case challengeDNS01:
+					if len(args) > 1 {
+						return nil, c.ArgErr()
+					}
+				case challengeTLSALPN01:
+					if len(args) > 1 {
+						if _, _, err := net.SplitHostPort(args[1]); err != nil {
+							return nil, c.Errf("invalid address '%s'", args[1])
+						}
+						m.alpnAddr = args[1]
+					}
+				default:
+					return nil, c.Errf("unknown challenge '%s'", args[0])
+				}
+				m.challenge = args[0]
+			case "storage":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				m.storage = args[0]
+			case "renew_before":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				d, err := time.ParseDuration(args[0])
+				if err != nil || d <= 0 {
+					return nil, c.Errf("invalid renew_before duration '%s'", args[0])
+				}
+				m.renewBefore = d
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	if len(m.domains) == 0 {
+		return nil, c.Err("no domains to obtain a certificate for")
+	}
+	if m.challenge == challengeTLSALPN01 {
+		for _, d := range m.domains {
+			if strings.HasPrefix(d, "*.") {
+				return nil, c.Errf("wildcard domain '%s' needs the %s challenge", d, challengeDNS01)
+			}
+		}
+	}
+	if !filepath.IsAbs(m.storage) && config.Root != "" {
+		m.storage = filepath.Join(config.Root, m.storage)
+	}
+	return m, nil
+}
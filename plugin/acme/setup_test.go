@@ -0,0 +1,111 @@
+package acme
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input               string
+		shouldErr           bool
+		expectedDomains     []string
+		expectedChallenge   string
+		expectedALPNAddr    string
+		expectedRenewBefore time.Duration
+		expectedErrContent  string // substring from the expected error. Empty for positive cases.
+	}{
+		{`acme example.org`, false, []string{"example.org"}, challengeDNS01, defaultALPNAddr, defaultRenewBefore, ""},
+		{`acme example.org example.net {
+			email hostmaster@example.org
+			ca https://acme-staging-v02.api.letsencrypt.org/directory
+			challenge tls-alpn-01 :8443
+			storage /var/lib/coredns/acme
+			renew_before 720h
+		}`, false, []string{"example.org", "example.net"}, challengeTLSALPN01, ":8443", 720 * time.Hour, ""},
+		{`acme example.org {
+			challenge dns-01
+		}`, false, []string{"example.org"}, challengeDNS01, defaultALPNAddr, defaultRenewBefore, ""},
+		// fails
+		{`acme .`, true, nil, "", "", 0, "no domains"},
+		{`acme example.org {
+			challenge http-01
+		}`, true, nil, "", "", 0, "unknown challenge"},
+		{`acme example.org {
+			challenge dns-01 :53
+		}`, true, nil, "", "", 0, "Wrong argument count"},
+		{`acme example.org {
+			challenge tls-alpn-01 443
+		}`, true, nil, "", "", 0, "invalid address"},
+		{`acme example.org {
+			ca http://localhost/directory
+		}`, true, nil, "", "", 0, "invalid ca URL"},
+		{`acme example.org {
+			renew_before 0s
+		}`, true, nil, "", "", 0, "invalid renew_before"},
+		{`acme example.org {
+			email
+		}`, true, nil, "", "", 0, "Wrong argument count"},
+		{`acme example.org {
+			key /etc/key.pem
+		}`, true, nil, "", "", 0, "unknown property"},
+		{`acme example.org
+		acme example.net`, true, nil, "", "", 0, "once per Server Block"},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		m, err := parse(c)
+
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			} else if !strings.Contains(err.Error(), test.expectedErrContent) {
+				t.Errorf("Test %d: expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(m.domains, test.expectedDomains) {
+			t.Errorf("Test %d: expected domains %v, got %v", i, test.expectedDomains, m.domains)
+		}
+		if m.challenge != test.expectedChallenge {
+			t.Errorf("Test %d: expected challenge %s, got %s", i, test.expectedChallenge, m.challenge)
+		}
+		if m.alpnAddr != test.expectedALPNAddr {
+			t.Errorf("Test %d: expected address %s, got %s", i, test.expectedALPNAddr, m.alpnAddr)
+		}
+		if m.renewBefore != test.expectedRenewBefore {
+			t.Errorf("Test %d: expected renew_before %s, got %s", i, test.expectedRenewBefore, m.renewBefore)
+		}
+	}
+}
+
+func TestSetupTLSConfig(t *testing.T) {
+	c := caddy.NewTestController("dns", `acme example.org`)
+	if err := setup(c); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	config := dnsserver.GetConfig(c)
+	if config.TLSConfigQUIC == nil || config.TLSConfigQUIC.GetCertificate == nil {
+		t.Fatal("Expected a DoQ TLS config that gets the certificate from acme")
+	}
+	if !reflect.DeepEqual(config.TLSConfigQUIC.NextProtos, doqProtos) {
+		t.Errorf("Expected ALPNs %v, got %v", doqProtos, config.TLSConfigQUIC.NextProtos)
+	}
+
+	// The certificate comes either from acme or the tls plugin.
+	c = caddy.NewTestController("dns", `acme example.org`)
+	dnsserver.GetConfig(c).TLSConfig = config.TLSConfig
+	if _, err := parse(c); err == nil || !strings.Contains(err.Error(), "TLS already configured") {
+		t.Errorf("Expected TLS already configured error, got %v", err)
+	}
+}
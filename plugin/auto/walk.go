@@ -4,8 +4,11 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 
 	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 
 	"github.com/miekg/dns"
 )
@@ -84,7 +87,8 @@ func (a Auto) Walk() error {
 }
 
 // matches re to filename, if it is a match, the subexpression will be used to expand
-// template to an origin. When match is true that origin is returned. Origin is fully qualified.
+// template to an origin. When match is true that origin is returned. Origin is fully qualified,
+// and SCION reverse zones are named as in the reverse names, see scionOrigin.
 func matches(re *regexp.Regexp, filename, template string) (match bool, origin string) {
 	base := filepath.Base(filename)
 
@@ -98,7 +102,31 @@ func matches(re *regexp.Regexp, filename, template string) (match bool, origin s
 		return false, ""
 	}
 
-	origin = dns.Fqdn(string(by))
+	origin = scionOrigin(dns.Fqdn(string(by)))
 
 	return true, origin
 }
+
+// scionOrigin returns origin with the ISD-AS of a reverse zone under scion.arpa. written as in the
+// reverse names, e.g. 19-ffaa:1:1067.scion.arpa. as 19-ffaa-1-1067.scion.arpa., so files can be named
+// with the usual notation of the ISD-AS. Other origins are returned unchanged.
+func scionOrigin(origin string) string {
+	suffix := dnsutil.SCIONReverseSuffix()
+	if len(origin) <= len(suffix) || !dns.IsSubDomain(suffix, origin) {
+		return origin
+	}
+	head := origin[:len(origin)-len(suffix)-1]
+	i := strings.LastIndex(head, ".")
+
+	label := head[i+1:]
+	if strings.Count(label, "-") == 3 {
+		// 19-ffaa-1-1067
+		parts := strings.SplitN(label, "-", 2)
+		label = parts[0] + "-" + strings.Replace(parts[1], "-", ":", -1)
+	}
+	ia, err := pkgscion.ParseIA(label)
+	if err != nil {
+		return origin
+	}
+	return head[:i+1] + strings.Replace(ia.String(), ":", "-", -1) + "." + suffix
+}
@@ -65,6 +65,31 @@ func TestWalkNonExistent(t *testing.T) {
 	a.Walk()
 }
 
+func TestMatchesSCION(t *testing.T) {
+	re := regexp.MustCompile(`db\.(.*)`)
+	tests := []struct {
+		filename string
+		match    bool
+		origin   string
+	}{
+		{"db.example.org", true, "example.org."},
+		{"db.19-ffaa-1-1067.scion.arpa", true, "19-ffaa-1-1067.scion.arpa."},
+		{"db.19-ffaa:1:1067.scion.arpa", true, "19-ffaa-1-1067.scion.arpa."},
+		{"db.0.127.in-addr.19-FFAA:0001:1067.scion.arpa", true, "0.127.in-addr.19-ffaa-1-1067.scion.arpa."},
+		{"db.1-64512.scion.arpa.", true, "1-64512.scion.arpa."},
+		{"db.scion.arpa", true, "scion.arpa."},
+		{"db.example.scion.arpa", true, "example.scion.arpa."},
+		{"db.19-ffaa:1:1067.example.org", true, "19-ffaa:1:1067.example.org."},
+		{"19-ffaa-1-1067.scion.arpa", false, ""},
+	}
+	for i, tc := range tests {
+		match, origin := matches(re, tc.filename, `${1}`)
+		if match != tc.match || origin != tc.origin {
+			t.Errorf("Test %d: expected %t and %q for %s, got %t and %q", i, tc.match, tc.origin, tc.filename, match, origin)
+		}
+	}
+}
+
 func createFiles() (string, error) {
 	dir, err := os.MkdirTemp(os.TempDir(), "coredns")
 	if err != nil {
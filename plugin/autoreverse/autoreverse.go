@@ -0,0 +1,195 @@
+// Package autoreverse implements a plugin that synthesizes the PTR records under scion.arpa. for the
+// SCION addresses published in forward zones.
+package autoreverse
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/transfer"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+// AutoReverse answers PTR queries for the reverse names of SCION addresses with the names in Zones that
+// have a TXT record scion=ISD-AS,[IP] for the address, or scion=ISD-AS,SVC for service addresses like
+// the control service CS.
+type AutoReverse struct {
+	Next plugin.Handler
+	// Zones are the forward zones whose SCION addresses are reversed.
+	Zones []string
+	TTL   uint32
+
+	// Transferers are the plugins the forward zones are read from.
+	Transferers []transfer.Transferer
+
+	*index
+}
+
+// index holds the names of the reverse names, per forward zone.
+type index struct {
+	sync.RWMutex
+	zones map[string]*zoneIndex
+}
+
+// zoneIndex holds the names of a forward zone by the reverse names of their SCION addresses.
+type zoneIndex struct {
+	serial uint32
+	ptr    map[string][]string
+}
+
+func newIndex() *index { return &index{zones: make(map[string]*zoneIndex)} }
+
+// ServeDNS implements the plugin.Handler interface.
+func (a AutoReverse) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	qname := state.Name()
+
+	if state.QType() != dns.TypePTR || dnsutil.IsReverse(qname) != 3 {
+		return plugin.NextOrFailure(a.Name(), a.Next, ctx, w, r)
+	}
+	names := a.lookup(qname)
+	if len(names) == 0 {
+		return plugin.NextOrFailure(a.Name(), a.Next, ctx, w, r)
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+	for _, n := range names {
+		m.Answer = append(m.Answer, &dns.PTR{
+			Hdr: dns.RR_Header{Name: state.QName(), Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: a.TTL},
+			Ptr: n,
+		})
+	}
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the plugin.Handler interface.
+func (a AutoReverse) Name() string { return "autoreverse" }
+
+// lookup returns the names of all forward zones that have the SCION address of the reverse name.
+func (a AutoReverse) lookup(name string) []string {
+	a.RLock()
+	defer a.RUnlock()
+	var names []string
+	for _, zi := range a.zones {
+		names = append(names, zi.ptr[name]...)
+	}
+	sort.Strings(names)
+	return dedup(names)
+}
+
+// Refresh reads the forward zones from the Transferers and updates the PTR records. Zones whose serial
+// didn't change are not read again. A zone that can't be read keeps its PTR records.
+func (a AutoReverse) Refresh() {
+	for _, zone := range a.Zones {
+		a.RLock()
+		var serial uint32
+		if zi, ok := a.zones[zone]; ok {
+			serial = zi.serial
+		}
+		a.RUnlock()
+
+		zi, err := a.read(zone, serial)
+		if err != nil {
+			log.Warningf("Failed to read zone `%s': %s", zone, err)
+			continue
+		}
+		if zi == nil {
+			continue
+		}
+		a.Lock()
+		a.zones[zone] = zi
+		a.Unlock()
+	}
+}
+
+// read transfers zone from the first Transferer that is authoritative for it and indexes its SCION
+// addresses. It returns nil if the serial of the zone is still serial.
+func (a AutoReverse) read(zone string, serial uint32) (*zoneIndex, error) {
+	for _, t := range a.Transferers {
+		ch, err := t.Transfer(zone, serial)
+		if err == transfer.ErrNotAuthoritative {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		zi := &zoneIndex{ptr: make(map[string][]string)}
+		n := 0
+		for rrs := range ch {
+			for _, rr := range rrs {
+				n++
+				switch x := rr.(type) {
+				case *dns.SOA:
+					zi.serial = x.Serial
+				case *dns.TXT:
+					zi.add(x)
+				}
+			}
+		}
+		if serial != 0 && n == 1 && zi.serial == serial {
+			// Only the SOA was sent, the zone didn't change.
+			return nil, nil
+		}
+		return zi, nil
+	}
+	return nil, transfer.ErrNotAuthoritative
+}
+
+// add indexes the owner of txt under the reverse names of the SCION addresses in it.
+func (zi *zoneIndex) add(txt *dns.TXT) {
+	owner := strings.ToLower(txt.Hdr.Name)
+	if strings.HasPrefix(owner, "*.") {
+		return
+	}
+	for _, t := range txt.Txt {
+		if !strings.HasPrefix(t, "scion=") {
+			continue
+		}
+		rev, err := reverse(strings.TrimPrefix(t, "scion="))
+		if err != nil {
+			continue
+		}
+		zi.ptr[rev] = append(zi.ptr[rev], owner)
+	}
+}
+
+// reverse returns the reverse name of the SCION host or service address addr.
+func reverse(addr string) (string, error) {
+	if svc, err := dnsutil.ParseSCIONServiceAddress(addr); err == nil {
+		return dnsutil.ReverseSCIONAddr(svc.String())
+	}
+	a, err := pan.ParseUDPAddr(addr)
+	if err != nil {
+		// Addresses are published without a port.
+		if a, err = pan.ParseUDPAddr(addr + ":0"); err != nil {
+			return "", err
+		}
+	}
+	return dnsutil.ReverseSCIONAddr(a.WithPort(0).String())
+}
+
+// dedup removes the duplicates from the sorted ss.
+func dedup(ss []string) []string {
+	if len(ss) < 2 {
+		return ss
+	}
+	j := 0
+	for i := 1; i < len(ss); i++ {
+		if ss[i] != ss[j] {
+			j++
+			ss[j] = ss[i]
+		}
+	}
+	return ss[:j+1]
+}
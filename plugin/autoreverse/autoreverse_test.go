@@ -0,0 +1,131 @@
+package autoreverse
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/plugin/transfer"
+
+	"github.com/miekg/dns"
+)
+
+// zoneTransferer is a transfer.Transferer for a single zone, which only sends the SOA if the serial
+// didn't change.
+type zoneTransferer struct {
+	zone string
+	rrs  []dns.RR // the SOA first
+}
+
+func (z *zoneTransferer) Transfer(zone string, serial uint32) (<-chan []dns.RR, error) {
+	if zone != z.zone {
+		return nil, transfer.ErrNotAuthoritative
+	}
+	ch := make(chan []dns.RR, 2)
+	if serial == z.rrs[0].(*dns.SOA).Serial {
+		ch <- z.rrs[:1]
+	} else {
+		ch <- z.rrs
+		ch <- z.rrs[:1]
+	}
+	close(ch)
+	return ch, nil
+}
+
+func TestAutoReverse(t *testing.T) {
+	org := &zoneTransferer{zone: "example.org.", rrs: []dns.RR{
+		test.SOA("example.org. 3600 IN SOA ns.example.org. admin.example.org. 1 7200 3600 1209600 3600"),
+		test.TXT(`www.example.org. 3600 IN TXT "scion=19-ffaa:1:1067,[10.0.0.1]"`),
+		test.TXT(`WEB.example.org. 3600 IN TXT "scion=19-ffaa:1:1067,[10.0.0.1]"`),
+		test.TXT(`v6.example.org. 3600 IN TXT "scion=19-ffaa:1:1067,[2001:db8::1]"`),
+		test.TXT(`*.example.org. 3600 IN TXT "scion=19-ffaa:1:1067,[10.0.0.9]"`),
+		test.TXT(`txt.example.org. 3600 IN TXT "v=spf1 -all"`),
+		test.TXT(`cs.example.org. 3600 IN TXT "scion=19-ffaa:1:1067,CS"`),
+	}}
+	netZone := &zoneTransferer{zone: "example.net.", rrs: []dns.RR{
+		test.SOA("example.net. 3600 IN SOA ns.example.net. admin.example.net. 1 7200 3600 1209600 3600"),
+		test.TXT(`www.example.net. 3600 IN TXT "scion=19-ffaa:1:1067,[10.0.0.1]" "scion=19-ffaa:1:fe4,[10.0.0.2]"`),
+	}}
+	a := AutoReverse{
+		Next:        test.NextHandler(dns.RcodeNameError, nil),
+		Zones:       []string{"example.org.", "example.net."},
+		TTL:         300,
+		Transferers: []transfer.Transferer{org, netZone},
+		index:       newIndex(),
+	}
+	a.Refresh()
+
+	tests := []struct {
+		qname    string
+		qtype    uint16
+		expected []string
+		rcode    int
+	}{
+		{"1.0.0.10.in-addr.19-ffaa-1-1067.scion.arpa.", dns.TypePTR, []string{"web.example.org.", "www.example.net.", "www.example.org."}, dns.RcodeSuccess},
+		{"2.0.0.10.in-addr.19-ffaa-1-fe4.scion.arpa.", dns.TypePTR, []string{"www.example.net."}, dns.RcodeSuccess},
+		{"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.19-ffaa-1-1067.scion.arpa.", dns.TypePTR, []string{"v6.example.org."}, dns.RcodeSuccess},
+		{"cs.svc.19-ffaa-1-1067.scion.arpa.", dns.TypePTR, []string{"cs.example.org."}, dns.RcodeSuccess},
+		{"ds.svc.19-ffaa-1-1067.scion.arpa.", dns.TypePTR, nil, dns.RcodeNameError},
+		// Wildcards have no name to point to.
+		{"9.0.0.10.in-addr.19-ffaa-1-1067.scion.arpa.", dns.TypePTR, nil, dns.RcodeNameError},
+		// Other ASes, types and reverse trees are passed on.
+		{"1.0.0.10.in-addr.19-ffaa-1-fe4.scion.arpa.", dns.TypePTR, nil, dns.RcodeNameError},
+		{"1.0.0.10.in-addr.19-ffaa-1-1067.scion.arpa.", dns.TypeTXT, nil, dns.RcodeNameError},
+		{"1.0.0.10.in-addr.arpa.", dns.TypePTR, nil, dns.RcodeNameError},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		code, err := a.ServeDNS(context.TODO(), rec, m)
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if code != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, tc.rcode, code)
+		}
+		if tc.expected == nil {
+			continue
+		}
+		if len(rec.Msg.Answer) != len(tc.expected) {
+			t.Fatalf("Test %d: expected %d answers, got %d", i, len(tc.expected), len(rec.Msg.Answer))
+		}
+		for j, rr := range rec.Msg.Answer {
+			ptr := rr.(*dns.PTR)
+			if ptr.Ptr != tc.expected[j] {
+				t.Errorf("Test %d: expected %s, got %s", i, tc.expected[j], ptr.Ptr)
+			}
+			if ptr.Hdr.Ttl != 300 {
+				t.Errorf("Test %d: expected TTL 300, got %d", i, ptr.Hdr.Ttl)
+			}
+		}
+	}
+}
+
+func TestAutoReverseRefresh(t *testing.T) {
+	org := &zoneTransferer{zone: "example.org.", rrs: []dns.RR{
+		test.SOA("example.org. 3600 IN SOA ns.example.org. admin.example.org. 1 7200 3600 1209600 3600"),
+		test.TXT(`www.example.org. 3600 IN TXT "scion=19-ffaa:1:1067,[10.0.0.1]"`),
+	}}
+	a := AutoReverse{Zones: []string{"example.org.", "example.com."}, Transferers: []transfer.Transferer{org}, index: newIndex()}
+	a.Refresh()
+	const rev = "1.0.0.10.in-addr.19-ffaa-1-1067.scion.arpa."
+	if names := a.lookup(rev); len(names) != 1 {
+		t.Fatalf("Expected 1 name, got %v", names)
+	}
+
+	// A zone with the same serial isn't read again.
+	org.rrs = append(org.rrs[:1], test.TXT(`web.example.org. 3600 IN TXT "scion=19-ffaa:1:1067,[10.0.0.1]"`))
+	a.Refresh()
+	if names := a.lookup(rev); len(names) != 1 || names[0] != "www.example.org." {
+		t.Fatalf("Expected www.example.org., got %v", names)
+	}
+
+	org.rrs[0] = test.SOA("example.org. 3600 IN SOA ns.example.org. admin.example.org. 2 7200 3600 1209600 3600")
+	a.Refresh()
+	if names := a.lookup(rev); len(names) != 1 || names[0] != "web.example.org." {
+		t.Fatalf("Expected web.example.org., got %v", names)
+	}
+}
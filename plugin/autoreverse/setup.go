@@ -0,0 +1,124 @@
+package autoreverse
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/transfer"
+
+	"github.com/miekg/dns"
+)
+
+var log = clog.NewWithPlugin("autoreverse")
+
+func init() { plugin.Register("autoreverse", setup) }
+
+const (
+	// defaultTTL is the TTL of the PTR records if not configured.
+	defaultTTL = 3600
+	// defaultReload is how often the forward zones are checked for changes if not configured.
+	defaultReload = time.Minute
+)
+
+func setup(c *caddy.Controller) error {
+	a, reload, err := parse(c)
+	if err != nil {
+		return plugin.Error("autoreverse", err)
+	}
+
+	stop := make(chan struct{})
+
+	c.OnStartup(func() error {
+		// Find all plugins that serve zones, the forward zones are read from them.
+		for _, pl := range dnsserver.GetConfig(c).Handlers() {
+			if t, ok := pl.(transfer.Transferer); ok {
+				a.Transferers = append(a.Transferers, t)
+			}
+		}
+		a.Refresh()
+		if reload == 0 {
+			return nil
+		}
+		go func() {
+			ticker := time.NewTicker(reload)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					a.Refresh()
+				}
+			}
+		}()
+		return nil
+	})
+
+	c.OnShutdown(func() error {
+		close(stop)
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		a.Next = next
+		return a
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller) (*AutoReverse, time.Duration, error) {
+	a := &AutoReverse{TTL: defaultTTL, index: newIndex()}
+	reload := defaultReload
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, 0, plugin.ErrOnce
+		}
+		i++
+
+		// The reverse zones of the server block, e.g. scion.arpa., aren't forward zones.
+		d, _ := dnsserver.SCIONDefaults(c)
+		for _, z := range plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys) {
+			if !dns.IsSubDomain("arpa.", z) && !dns.IsSubDomain(d.ReverseSuffix, z) {
+				a.Zones = append(a.Zones, z)
+			}
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "ttl":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, 0, c.ArgErr()
+				}
+				ttl, err := strconv.ParseUint(args[0], 10, 32)
+				if err != nil {
+					return nil, 0, c.Errf("invalid ttl '%s'", args[0])
+				}
+				a.TTL = uint32(ttl)
+			case "reload":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, 0, c.ArgErr()
+				}
+				d, err := time.ParseDuration(args[0])
+				if err != nil || d < 0 {
+					return nil, 0, c.Errf("invalid reload duration '%s'", args[0])
+				}
+				reload = d
+			default:
+				return nil, 0, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	if len(a.Zones) == 0 {
+		return nil, 0, c.Err("no forward zones to reverse")
+	}
+	return a, reload, nil
+}
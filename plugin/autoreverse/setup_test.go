@@ -0,0 +1,70 @@
+package autoreverse
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input              string
+		shouldErr          bool
+		expectedZones      []string
+		expectedTTL        uint32
+		expectedReload     time.Duration
+		expectedErrContent string // substring from the expected error. Empty for positive cases.
+	}{
+		{`autoreverse example.org example.net`, false, []string{"example.org.", "example.net."}, defaultTTL, defaultReload, ""},
+		{`autoreverse example.org scion.arpa 0.10.in-addr.19-ffaa-1-1067.scion.arpa {
+			ttl 300
+			reload 0
+		}`, false, []string{"example.org."}, 300, 0, ""},
+		// fails
+		{`autoreverse scion.arpa`, true, nil, 0, 0, "no forward zones"},
+		{`autoreverse example.org {
+			ttl
+		}`, true, nil, 0, 0, "Wrong argument count"},
+		{`autoreverse example.org {
+			reload -1s
+		}`, true, nil, 0, 0, "invalid reload duration"},
+		{`autoreverse example.org {
+			refresh 1s
+		}`, true, nil, 0, 0, "unknown property"},
+		{`autoreverse example.org
+		autoreverse example.net`, true, nil, 0, 0, "once per Server Block"},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		a, reload, err := parse(c)
+
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			} else if !strings.Contains(err.Error(), test.expectedErrContent) {
+				t.Errorf("Test %d: expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: expected no error but found %v for input %s", i, err, test.input)
+		}
+		if len(a.Zones) != len(test.expectedZones) {
+			t.Fatalf("Test %d: expected zones %v, got %v", i, test.expectedZones, a.Zones)
+		}
+		for j := range a.Zones {
+			if a.Zones[j] != test.expectedZones[j] {
+				t.Errorf("Test %d: expected zones %v, got %v", i, test.expectedZones, a.Zones)
+			}
+		}
+		if a.TTL != test.expectedTTL {
+			t.Errorf("Test %d: expected TTL %d, got %d", i, test.expectedTTL, a.TTL)
+		}
+		if reload != test.expectedReload {
+			t.Errorf("Test %d: expected reload %s, got %s", i, test.expectedReload, reload)
+		}
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strings"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/log"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/plugin/pkg/transport"
 )
 
 func setup(c *caddy.Controller) error {
@@ -26,12 +29,17 @@ func setup(c *caddy.Controller) error {
 			return plugin.Error("bind", err)
 		}
 
-		ips, err := listIP(b.addrs, ifaces)
+		list := listIP
+		if config.Transport == transport.SQUIC {
+			list = listSCION
+		}
+
+		ips, err := list(b.addrs, ifaces)
 		if err != nil {
 			return plugin.Error("bind", err)
 		}
 
-		except, err := listIP(b.except, ifaces)
+		except, err := list(b.except, ifaces)
 		if err != nil {
 			return plugin.Error("bind", err)
 		}
@@ -99,6 +107,32 @@ func listIP(args []string, ifaces []net.Interface) ([]string, error) {
 	return all, nil
 }
 
+// listSCION returns a list of listen addresses for a squic server. Next to IP addresses and interface
+// names, the arguments may carry the ISD-AS the listener must be in and a port, as in
+// 19-ffaa:1:1067,[10.0.0.1]:8853 or 19-ffaa:1:1067,eth0, see pkgscion.ParseListenAddr.
+func listSCION(args []string, ifaces []net.Interface) ([]string, error) {
+	all := []string{}
+	for _, a := range args {
+		prefix := ""
+		if ia, rest, ok := strings.Cut(a, ","); ok {
+			prefix, a = ia+",", rest
+		}
+		ips, err := listIP([]string{a}, ifaces)
+		if err != nil {
+			// Not an IP address or interface name, but maybe one with a port.
+			ips = []string{a}
+		}
+		for _, ip := range ips {
+			la, err := pkgscion.ParseListenAddr(prefix + ip)
+			if err != nil {
+				return nil, fmt.Errorf("not a valid SCION listen address or interface name: %q: %s", prefix+ip, err)
+			}
+			all = append(all, la.String())
+		}
+	}
+	return all, nil
+}
+
 // isIn checks if a string array contains an element
 func isIn(s string, list []string) bool {
 	is := false
@@ -45,3 +45,43 @@ func TestSetup(t *testing.T) {
 		}
 	}
 }
+
+func TestSetupSCION(t *testing.T) {
+	for i, test := range []struct {
+		config   string
+		expected []string
+		failing  bool
+	}{
+		{`bind 1.2.3.4`, []string{"1.2.3.4"}, false},
+		{`bind 19-ffaa:1:1067,1.2.3.4 [::5]:8853`, []string{"19-ffaa:1:1067,1.2.3.4", "[::5]:8853"}, false},
+		{`bind 19-ffaa:1:1067,[1.2.3.4]:8853 17-ffaa:0:1,1.2.3.4:9953`, []string{"19-ffaa:1:1067,[1.2.3.4]:8853", "17-ffaa:0:1,[1.2.3.4]:9953"}, false},
+		{`bind 19-ffaa:1:1067,lo`, []string{"19-ffaa:1:1067,127.0.0.1", "19-ffaa:1:1067,::1"}, false},
+		{"bind 19-ffaa:1:1067,lo {\nexcept 19-ffaa:1:1067,127.0.0.1\n}\n", []string{"19-ffaa:1:1067,::1"}, false},
+		{`bind 19,1.2.3.4`, nil, true},
+		{`bind 19-ffaa:1:1067,noone`, nil, true},
+		{`bind 1.2.3.4:http`, nil, true},
+	} {
+		c := caddy.NewTestController("dns", test.config)
+		dnsserver.GetConfig(c).Transport = "squic"
+		err := setup(c)
+		if err != nil {
+			if !test.failing {
+				t.Fatalf("Test %d, expected no errors, but got: %v", i, err)
+			}
+			continue
+		}
+		if test.failing {
+			t.Fatalf("Test %d, expected to failed but did not, returned values", i)
+		}
+		cfg := dnsserver.GetConfig(c)
+		if len(cfg.ListenHosts) != len(test.expected) {
+			t.Errorf("Test %d : expected the config's ListenHosts size to be %d, was %d", i, len(test.expected), len(cfg.ListenHosts))
+			continue
+		}
+		for i, v := range test.expected {
+			if got, want := cfg.ListenHosts[i], v; got != want {
+				t.Errorf("Test %d : expected the config's ListenHost to be %s, was %s", i, want, got)
+			}
+		}
+	}
+}
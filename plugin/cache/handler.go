@@ -5,6 +5,7 @@ import (
 	"math"
 	"time"
 
+	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metadata"
 	"github.com/coredns/coredns/plugin/metrics"
@@ -21,7 +22,7 @@ func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 	ad := r.AuthenticatedData
 
 	zone := plugin.Zones(c.Zones).Matches(state.Name())
-	if zone == "" {
+	if zone == "" || dnsserver.NoCache(ctx) {
 		return plugin.NextOrFailure(c.Name(), c.Next, ctx, w, rc)
 	}
 
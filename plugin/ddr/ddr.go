@@ -0,0 +1,119 @@
+// Package ddr implements a plugin that advertises the server's own DNS-over-QUIC endpoints with
+// SVCB records, as used by Discovery of Designated Resolvers (RFC 9462).
+package ddr
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// resolverName is the special use name clients query to discover designated resolvers.
+const resolverName = "_dns.resolver.arpa."
+
+// SVCBKeySCION is the (private use) SvcParamKey carrying the SCION address of an sdoq endpoint.
+const SVCBKeySCION dns.SVCBKey = 65280
+
+// DDR is the ddr plugin.
+type DDR struct {
+	Next plugin.Handler
+
+	// target is the name clients authenticate the endpoints with.
+	target string
+	ttl    uint32
+	// endpoints returns the endpoints to advertise.
+	endpoints func() []dnsserver.Endpoint
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (d *DDR) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	qname := state.Name()
+	if qname != resolverName && qname != "_dns."+d.target {
+		return plugin.NextOrFailure(d.Name(), d.Next, ctx, w, r)
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+	if state.QType() == dns.TypeSVCB {
+		m.Answer = d.svcb(qname)
+	}
+	if len(m.Answer) == 0 {
+		m.Ns = d.soa(qname)
+	}
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the plugin.Handler interface.
+func (d *DDR) Name() string { return "ddr" }
+
+// svcb returns a SVCB record for each of the endpoints that work.
+func (d *DDR) svcb(qname string) []dns.RR {
+	eps := d.endpoints()
+	rrs := make([]dns.RR, 0, len(eps))
+	for _, ep := range eps {
+		if ep.Err != nil {
+			continue
+		}
+		rr := &dns.SVCB{
+			Hdr:      dns.RR_Header{Name: qname, Rrtype: dns.TypeSVCB, Class: dns.ClassINET, Ttl: d.ttl},
+			Priority: uint16(len(rrs) + 1),
+			Target:   d.target,
+		}
+		rr.Value = params(ep)
+		rrs = append(rrs, rr)
+	}
+	return rrs
+}
+
+// soa returns the SOA record that makes the negative answers for qname cacheable. The zone is the name
+// following _dns, i.e. resolver.arpa or the target.
+func (d *DDR) soa(qname string) []dns.RR {
+	zone := strings.TrimPrefix(qname, "_dns.")
+	hdr := dns.RR_Header{Name: zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: d.ttl}
+	return []dns.RR{&dns.SOA{Hdr: hdr, Ns: d.target, Mbox: "hostmaster." + d.target, Serial: 1, Minttl: d.ttl}}
+}
+
+// params returns the ALPN protocols, the port and address hints of ep. A SCION endpoint gets its SCION
+// address instead of the port and hints, in the mandatory key SVCBKeySCION, so clients that don't
+// speak SCION ignore it rather than dialing it over IP.
+func params(ep dnsserver.Endpoint) []dns.SVCBKeyValue {
+	var kv []dns.SVCBKeyValue
+	if ep.Transport == transport.SQUIC {
+		kv = append(kv, &dns.SVCBMandatory{Code: []dns.SVCBKey{SVCBKeySCION}})
+	}
+	if len(ep.ALPN) > 0 {
+		kv = append(kv, &dns.SVCBAlpn{Alpn: ep.ALPN})
+	}
+	if ep.Transport == transport.SQUIC {
+		return append(kv, &dns.SVCBLocal{KeyCode: SVCBKeySCION, Data: []byte(ep.Addr.String())})
+	}
+
+	var (
+		ip   net.IP
+		port int
+	)
+	if a, ok := ep.Addr.(*net.UDPAddr); ok {
+		ip, port = a.IP, a.Port
+	}
+
+	kv = append(kv, &dns.SVCBPort{Port: uint16(port)})
+	switch {
+	case ip == nil || ip.IsUnspecified():
+		// Listening on all addresses, the client already knows one.
+	case ip.To4() != nil:
+		kv = append(kv, &dns.SVCBIPv4Hint{Hint: []net.IP{ip}})
+	default:
+		kv = append(kv, &dns.SVCBIPv6Hint{Hint: []net.IP{ip}})
+	}
+	return kv
+}
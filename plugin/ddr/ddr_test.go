@@ -0,0 +1,85 @@
+package ddr
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+func TestDDR(t *testing.T) {
+	d := &DDR{
+		Next:   test.NextHandler(dns.RcodeNameError, nil),
+		target: "ns1.example.org.",
+		ttl:    300,
+		endpoints: func() []dnsserver.Endpoint {
+			return []dnsserver.Endpoint{
+				{Transport: "quic", Addr: &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 853}, ALPN: []string{"doq"}},
+				{Transport: "quic", Addr: &net.UDPAddr{IP: net.IPv6unspecified, Port: 8853}, ALPN: []string{"doq"}},
+				{Transport: "quic", Addr: &net.UDPAddr{IP: net.ParseIP("192.0.2.2"), Port: 853}, ALPN: []string{"doq"}, Err: errors.New("accept failed")},
+				{Transport: "squic", Addr: pan.MustParseUDPAddr("19-ffaa:1:1067,[10.0.0.1]:8853"), ALPN: []string{"doq"}},
+			}
+		},
+	}
+
+	tests := []struct {
+		qname         string
+		qtype         uint16
+		expectedRcode int
+		expected      []string
+		expectedSOA   string // owner of the SOA in the authority section of a negative answer
+	}{
+		{"_dns.resolver.arpa.", dns.TypeSVCB, dns.RcodeSuccess, []string{
+			`_dns.resolver.arpa.	300	IN	SVCB	1 ns1.example.org. alpn="doq" port="853" ipv4hint="192.0.2.1"`,
+			`_dns.resolver.arpa.	300	IN	SVCB	2 ns1.example.org. alpn="doq" port="8853"`,
+			`_dns.resolver.arpa.	300	IN	SVCB	3 ns1.example.org. mandatory="key65280" alpn="doq" key65280="19-ffaa:1:1067,10.0.0.1:8853"`,
+		}, ""},
+		{"_dns.ns1.example.org.", dns.TypeSVCB, dns.RcodeSuccess, []string{
+			`_dns.ns1.example.org.	300	IN	SVCB	1 ns1.example.org. alpn="doq" port="853" ipv4hint="192.0.2.1"`,
+			`_dns.ns1.example.org.	300	IN	SVCB	2 ns1.example.org. alpn="doq" port="8853"`,
+			`_dns.ns1.example.org.	300	IN	SVCB	3 ns1.example.org. mandatory="key65280" alpn="doq" key65280="19-ffaa:1:1067,10.0.0.1:8853"`,
+		}, ""},
+		{"_dns.resolver.arpa.", dns.TypeA, dns.RcodeSuccess, nil, "resolver.arpa."},
+		{"_dns.ns1.example.org.", dns.TypeAAAA, dns.RcodeSuccess, nil, "ns1.example.org."},
+		{"example.org.", dns.TypeSVCB, dns.RcodeNameError, nil, ""},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		rcode, _ := d.ServeDNS(context.TODO(), rec, m)
+		if rcode != tc.expectedRcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, tc.expectedRcode, rcode)
+			continue
+		}
+		if rec.Msg == nil {
+			continue
+		}
+		if len(rec.Msg.Answer) != len(tc.expected) {
+			t.Fatalf("Test %d: expected %d answers, got %d", i, len(tc.expected), len(rec.Msg.Answer))
+		}
+		for j, rr := range rec.Msg.Answer {
+			if rr.String() != tc.expected[j] {
+				t.Errorf("Test %d: expected %q, got %q", i, tc.expected[j], rr.String())
+			}
+		}
+		if tc.expectedSOA == "" {
+			if len(rec.Msg.Ns) != 0 {
+				t.Errorf("Test %d: expected no authority section, got %v", i, rec.Msg.Ns)
+			}
+			continue
+		}
+		if len(rec.Msg.Ns) != 1 || rec.Msg.Ns[0].Header().Rrtype != dns.TypeSOA || rec.Msg.Ns[0].Header().Name != tc.expectedSOA {
+			t.Errorf("Test %d: expected the SOA of %s in the authority section, got %v", i, tc.expectedSOA, rec.Msg.Ns)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package ddr
+
+import (
+	"strconv"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/miekg/dns"
+)
+
+func init() { plugin.Register("ddr", setup) }
+
+// defaultTTL is the TTL of the SVCB records if not configured.
+const defaultTTL = 300
+
+func setup(c *caddy.Controller) error {
+	d, err := parse(c)
+	if err != nil {
+		return plugin.Error("ddr", err)
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		d.Next = next
+		return d
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller) (*DDR, error) {
+	d := &DDR{ttl: defaultTTL, endpoints: dnsserver.Endpoints}
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		args := c.RemainingArgs()
+		if len(args) != 1 {
+			return nil, c.ArgErr()
+		}
+		if _, ok := dns.IsDomainName(args[0]); !ok {
+			return nil, c.Errf("invalid target name '%s'", args[0])
+		}
+		d.target = dns.Fqdn(args[0])
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "ttl":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				ttl, err := strconv.ParseUint(args[0], 10, 32)
+				if err != nil {
+					return nil, c.Errf("invalid ttl '%s': %s", args[0], err)
+				}
+				d.ttl = uint32(ttl)
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,60 @@
+package ddr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input              string
+		shouldErr          bool
+		expectedTarget     string
+		expectedTTL        uint32
+		expectedErrContent string
+	}{
+		{`ddr ns1.example.org`, false, "ns1.example.org.", defaultTTL, ""},
+		{`ddr ns1.example.org. {
+			ttl 3600
+		}`, false, "ns1.example.org.", 3600, ""},
+		// fails
+		{`ddr`, true, "", 0, "Wrong argument count"},
+		{`ddr ns1.example.org ns2.example.org`, true, "", 0, "Wrong argument count"},
+		{`ddr ns1.example.org {
+			ttl -1
+		}`, true, "", 0, "invalid ttl"},
+		{`ddr ns1.example.org {
+			giraffe
+		}`, true, "", 0, "unknown property"},
+		{`ddr ns1.example.org
+		ddr ns2.example.org`, true, "", 0, "plugin can only be used once"},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		d, err := parse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error but found none for input %s", i, test.input)
+		}
+
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			}
+			if !strings.Contains(err.Error(), test.expectedErrContent) {
+				t.Errorf("Test %d: Expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
+			}
+			continue
+		}
+
+		if d.target != test.expectedTarget {
+			t.Errorf("Test %d: Expected target %s, got %s", i, test.expectedTarget, d.target)
+		}
+		if d.ttl != test.expectedTTL {
+			t.Errorf("Test %d: Expected ttl %d, got %d", i, test.expectedTTL, d.ttl)
+		}
+	}
+}
@@ -13,7 +13,11 @@ import (
 )
 
 func testCase(t *testing.T, tapq, tapr *tap.Message, q, r *dns.Msg) {
-	w := writer{t: t}
+	testCaseWriter(t, tapq, tapr, q, r, &test.ResponseWriter{}, "")
+}
+
+func testCaseWriter(t *testing.T, tapq, tapr *tap.Message, q, r *dns.Msg, rw dns.ResponseWriter, extra string) {
+	w := writer{t: t, extra: extra}
 	w.queue = append(w.queue, tapq, tapr)
 	h := Dnstap{
 		Next: test.HandlerFunc(func(_ context.Context,
@@ -22,7 +26,7 @@ func testCase(t *testing.T, tapq, tapr *tap.Message, q, r *dns.Msg) {
 		}),
 		io: &w,
 	}
-	_, err := h.ServeDNS(context.TODO(), &test.ResponseWriter{}, q)
+	_, err := h.ServeDNS(context.TODO(), rw, q)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -31,6 +35,7 @@ func testCase(t *testing.T, tapq, tapr *tap.Message, q, r *dns.Msg) {
 type writer struct {
 	t     *testing.T
 	queue []*tap.Message
+	extra string
 }
 
 func (w *writer) Dnstap(e *tap.Dnstap) {
@@ -53,6 +58,9 @@ func (w *writer) Dnstap(e *tap.Dnstap) {
 	if *ex.SocketFamily != *got.SocketFamily {
 		w.t.Errorf("Expected socket family %d, got %d", *ex.SocketFamily, *got.SocketFamily)
 	}
+	if string(e.Extra) != w.extra {
+		w.t.Errorf("Expected extra %q, got %q", w.extra, e.Extra)
+	}
 	w.queue = w.queue[1:]
 }
 
@@ -71,6 +79,23 @@ func TestDnstap(t *testing.T) {
 	testCase(t, tapq, tapr, q, r)
 }
 
+func TestDnstapSCION(t *testing.T) {
+	q := test.Case{Qname: "example.org", Qtype: dns.TypeA}.Msg()
+	r := test.Case{
+		Qname: "example.org.", Qtype: dns.TypeA,
+		Answer: []dns.RR{
+			test.A("example.org. 3600	IN	A 10.0.0.1"),
+		},
+	}.Msg()
+	tapq := testMessage()
+	tapq.QueryAddress = net.IP(tapq.QueryAddress).To4()
+	msg.SetType(tapq, tap.Message_CLIENT_QUERY)
+	tapr := testMessage()
+	tapr.QueryAddress = net.IP(tapr.QueryAddress).To4()
+	msg.SetType(tapr, tap.Message_CLIENT_RESPONSE)
+	testCaseWriter(t, tapq, tapr, q, r, &test.ResponseWriter{RemoteSCION: "19-ffaa:1:1067,[10.240.0.1]:40212"}, "scion-query=19-ffaa:1:1067,10.240.0.1:40212")
+}
+
 func testMessage() *tap.Message {
 	inet := tap.SocketFamily_INET
 	udp := tap.SocketProtocol_UDP
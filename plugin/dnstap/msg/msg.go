@@ -3,9 +3,11 @@ package msg
 import (
 	"fmt"
 	"net"
+	"strings"
 	"time"
 
 	tap "github.com/dnstap/golang-dnstap"
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
 )
 
 var (
@@ -41,6 +43,19 @@ func SetQueryAddress(t *tap.Message, addr net.Addr) error {
 			t.SocketFamily = &familyINET6
 		}
 		return nil
+	case pan.UDPAddr, *pan.UDPAddr:
+		// SCION runs over UDP, the ISD-AS only goes into the Extra field, see SCIONExtra.
+		sa, _ := scionAddr(a)
+		t.SocketProtocol = &protoUDP
+		t.QueryAddress = sa.IP.IPAddr().IP
+
+		p := uint32(sa.Port)
+		t.QueryPort = &p
+
+		if sa.IP.Is6() {
+			t.SocketFamily = &familyINET6
+		}
+		return nil
 	default:
 		return fmt.Errorf("unknown address type: %T", a)
 	}
@@ -72,6 +87,18 @@ func SetResponseAddress(t *tap.Message, addr net.Addr) error {
 			t.SocketFamily = &familyINET6
 		}
 		return nil
+	case pan.UDPAddr, *pan.UDPAddr:
+		sa, _ := scionAddr(a)
+		t.SocketProtocol = &protoUDP
+		t.ResponseAddress = sa.IP.IPAddr().IP
+
+		p := uint32(sa.Port)
+		t.ResponsePort = &p
+
+		if sa.IP.Is6() {
+			t.SocketFamily = &familyINET6
+		}
+		return nil
 	default:
 		return fmt.Errorf("unknown address type: %T", a)
 	}
@@ -95,3 +122,34 @@ func SetResponseTime(t *tap.Message, ti time.Time) {
 
 // SetType sets the type in t.
 func SetType(t *tap.Message, typ tap.Message_Type) { t.Type = &typ }
+
+// SCIONExtra returns the SCION addresses among the query and response address, for the Extra field of
+// the dnstap message, as the message itself has no room for the ISD-AS. They are formatted as
+// "scion-query=ISD-AS,[IP]:port scion-response=ISD-AS,[IP]:port", addresses that are not SCION
+// addresses, or nil, are left out. If neither is a SCION address, nil is returned.
+func SCIONExtra(query, response net.Addr) []byte {
+	var extra []string
+	if a, ok := scionAddr(query); ok {
+		extra = append(extra, "scion-query="+a.String())
+	}
+	if a, ok := scionAddr(response); ok {
+		extra = append(extra, "scion-response="+a.String())
+	}
+	if len(extra) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(extra, " "))
+}
+
+// scionAddr returns addr as SCION address, if it is one.
+func scionAddr(addr net.Addr) (pan.UDPAddr, bool) {
+	switch a := addr.(type) {
+	case pan.UDPAddr:
+		return a, true
+	case *pan.UDPAddr:
+		if a != nil {
+			return *a, true
+		}
+	}
+	return pan.UDPAddr{}, false
+}
@@ -0,0 +1,374 @@
+package file
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/coredns/coredns/plugin/file/tree"
+	"github.com/coredns/coredns/plugin/pkg/lru"
+
+	"github.com/miekg/dns"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltStore keeps the records of a zone in a bolt database on disk. Elements are only decoded when they are
+// looked up, and only the most recently used ones are kept in memory, so zones larger than the memory of the
+// server can be served. The database is a cache of the zone file: it is created empty for every load of the
+// zone and removed once the zone is replaced, so it is never synced to disk.
+type boltStore struct {
+	db   *bolt.DB
+	path string
+
+	mu      sync.Mutex
+	pending []dns.RR // inserted records that aren't written yet
+	count   int
+	gen     uint64 // changed on every insert and delete, so seek doesn't cache an element read before one
+	cache   elemCache
+}
+
+var bucketName = []byte("names")
+
+// maxPending is the number of inserted records written to the database in one transaction.
+const maxPending = 1000
+
+// NewBoltStore returns a StoreFunc for stores that keep the records in a bolt database in dir, and cache
+// up to cacheSize elements in memory.
+func NewBoltStore(dir string, cacheSize int) StoreFunc {
+	return func(origin string) (Store, error) {
+		name := strings.TrimSuffix(origin, ".")
+		if name == "" {
+			name = "root"
+		}
+		f, err := os.CreateTemp(dir, filepath.Base(name)+".*.db")
+		if err != nil {
+			return nil, err
+		}
+		path := f.Name()
+		f.Close()
+
+		db, err := bolt.Open(path, 0600, &bolt.Options{NoSync: true, NoFreelistSync: true})
+		if err != nil {
+			os.Remove(path)
+			return nil, err
+		}
+		if err := db.Update(func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(bucketName)
+			return err
+		}); err != nil {
+			db.Close()
+			os.Remove(path)
+			return nil, err
+		}
+		return &boltStore{db: db, path: path, cache: newElemCache(cacheSize)}, nil
+	}
+}
+
+// Insert implements Store.
+func (s *boltStore) Insert(rr dns.RR) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = append(s.pending, rr)
+	s.gen++
+	s.cache.Remove(strings.ToLower(rr.Header().Name))
+	if len(s.pending) >= maxPending {
+		s.flush()
+	}
+}
+
+// Delete implements Store.
+func (s *boltStore) Delete(rr dns.RR) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flush()
+
+	name := rr.Header().Name
+	key := boltKey(name)
+	removed := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketName)
+		v := b.Get(key)
+		if v == nil {
+			return nil
+		}
+		rrs, err := decodeRRs(v)
+		if err != nil {
+			return err
+		}
+		kept := rrs[:0]
+		for _, r := range rrs {
+			if r.Header().Rrtype != rr.Header().Rrtype {
+				kept = append(kept, r)
+			}
+		}
+		if len(kept) == 0 {
+			removed = true
+			return b.Delete(key)
+		}
+		return b.Put(key, encodeRRs(nil, kept))
+	})
+	if err != nil {
+		log.Errorf("Failed to delete %s from zone store: %s", name, err)
+	} else if removed {
+		s.count--
+	}
+	s.gen++
+	s.cache.Remove(strings.ToLower(name))
+}
+
+// Search implements Store.
+func (s *boltStore) Search(qname string) (*tree.Elem, bool) {
+	qname = strings.ToLower(qname)
+	if e, ok := s.cache.get(qname); ok {
+		return e, true
+	}
+	return s.seek(qname, func(c *bolt.Cursor, key []byte) ([]byte, []byte) {
+		k, v := c.Seek(key)
+		if string(k) != string(key) {
+			return nil, nil
+		}
+		return k, v
+	})
+}
+
+// Prev implements Store.
+func (s *boltStore) Prev(qname string) (*tree.Elem, bool) {
+	return s.seek(qname, func(c *bolt.Cursor, key []byte) ([]byte, []byte) {
+		k, v := c.Seek(key)
+		if k == nil {
+			return c.Last()
+		}
+		if string(k) != string(key) {
+			return c.Prev()
+		}
+		return k, v
+	})
+}
+
+// Next implements Store.
+func (s *boltStore) Next(qname string) (*tree.Elem, bool) {
+	return s.seek(qname, func(c *bolt.Cursor, key []byte) ([]byte, []byte) { return c.Seek(key) })
+}
+
+// seek returns the element that move positions a cursor on, for the key of qname.
+func (s *boltStore) seek(qname string, move func(c *bolt.Cursor, key []byte) ([]byte, []byte)) (*tree.Elem, bool) {
+	s.mu.Lock()
+	s.flush()
+	gen := s.gen
+	s.mu.Unlock()
+
+	var e *tree.Elem
+	err := s.db.View(func(tx *bolt.Tx) error {
+		k, v := move(tx.Bucket(bucketName).Cursor(), boltKey(qname))
+		if k == nil {
+			return nil
+		}
+		rrs, err := decodeRRs(v)
+		if err != nil {
+			return err
+		}
+		e = newElem(rrs)
+		return nil
+	})
+	if err != nil {
+		log.Errorf("Failed to look up %s in zone store: %s", qname, err)
+		return nil, false
+	}
+	if e == nil {
+		return nil, false
+	}
+	// Only cache e if the store didn't change since it was read, otherwise it may be stale.
+	s.mu.Lock()
+	if s.gen == gen {
+		s.cache.add(e)
+	}
+	s.mu.Unlock()
+	return e, true
+}
+
+// Glue implements Store.
+func (s *boltStore) Glue(nsrrs []dns.RR, do bool, scion bool) []dns.RR {
+	return tree.GlueOf(s, nsrrs, do, scion)
+}
+
+// Walk implements Store.
+func (s *boltStore) Walk(fn func(*tree.Elem, map[uint16][]dns.RR) error) error {
+	s.mu.Lock()
+	s.flush()
+	s.mu.Unlock()
+
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).ForEach(func(_, v []byte) error {
+			rrs, err := decodeRRs(v)
+			if err != nil {
+				return err
+			}
+			m := make(map[uint16][]dns.RR)
+			for _, rr := range rrs {
+				m[rr.Header().Rrtype] = append(m[rr.Header().Rrtype], rr)
+			}
+			return fn(newElem(rrs), m)
+		})
+	})
+}
+
+// AuthWalk implements Store.
+func (s *boltStore) AuthWalk(fn func(*tree.Elem, map[uint16][]dns.RR, bool) error) error {
+	return tree.AuthWalkOf(s, fn)
+}
+
+// All implements Store.
+func (s *boltStore) All() []*tree.Elem {
+	var all []*tree.Elem
+	s.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+		all = append(all, e)
+		return nil
+	})
+	return all
+}
+
+// Len implements Store.
+func (s *boltStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flush()
+	return s.count
+}
+
+// Close closes and removes the database.
+func (s *boltStore) Close() error {
+	err := s.db.Close()
+	if rmErr := os.Remove(s.path); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// flush writes the pending records to the database. s.mu must be held.
+func (s *boltStore) flush() {
+	if len(s.pending) == 0 {
+		return
+	}
+	added := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketName)
+		for _, rr := range s.pending {
+			key := boltKey(rr.Header().Name)
+			v := b.Get(key)
+			if v == nil {
+				added++
+			}
+			// The value returned by Get is only valid until the next write, copy it.
+			if err := b.Put(key, encodeRRs(append([]byte(nil), v...), []dns.RR{rr})); err != nil {
+				return err
+			}
+			s.cache.Remove(strings.ToLower(rr.Header().Name))
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("Failed to write %d records to zone store: %s", len(s.pending), err)
+	} else {
+		s.count += added
+	}
+	s.gen++
+	s.pending = s.pending[:0]
+}
+
+func newElem(rrs []dns.RR) *tree.Elem {
+	e := &tree.Elem{}
+	for _, rr := range rrs {
+		e.Insert(rr)
+	}
+	return e
+}
+
+// boltKey returns the key of name. Keys sort in the canonical order of names (RFC 4034, section 6.1): the
+// labels are lowercased, unescaped and reversed, each preceded by a zero byte.
+func boltKey(name string) []byte {
+	labels := dns.SplitDomainName(strings.ToLower(name))
+	key := []byte{0}
+	for i := len(labels) - 1; i >= 0; i-- {
+		key = append(key, 0)
+		key = appendLabel(key, labels[i])
+	}
+	return key
+}
+
+// appendLabel appends label to b, with escapes like \. and \DDD resolved.
+func appendLabel(b []byte, label string) []byte {
+	for i := 0; i < len(label); i++ {
+		if label[i] != '\\' || i+1 >= len(label) {
+			b = append(b, label[i])
+			continue
+		}
+		if i+3 < len(label) && isDigit(label[i+1]) && isDigit(label[i+2]) && isDigit(label[i+3]) {
+			b = append(b, (label[i+1]-'0')*100+(label[i+2]-'0')*10+(label[i+3]-'0'))
+			i += 3
+			continue
+		}
+		b = append(b, label[i+1])
+		i++
+	}
+	return b
+}
+
+func isDigit(b byte) bool { return b >= '0' && b <= '9' }
+
+// encodeRRs appends rrs in wire format to b, each preceded by its length.
+func encodeRRs(b []byte, rrs []dns.RR) []byte {
+	for _, rr := range rrs {
+		buf := make([]byte, dns.Len(rr)+1)
+		n, err := dns.PackRR(rr, buf, 0, nil, false)
+		if err != nil {
+			log.Errorf("Failed to pack %s: %s", rr, err)
+			continue
+		}
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+		b = append(b, buf[:n]...)
+	}
+	return b
+}
+
+var errShortValue = errors.New("short value in zone store")
+
+// decodeRRs decodes the records encoded by encodeRRs.
+func decodeRRs(v []byte) ([]dns.RR, error) {
+	var rrs []dns.RR
+	for len(v) > 0 {
+		if len(v) < 2 {
+			return nil, errShortValue
+		}
+		n := int(binary.BigEndian.Uint16(v))
+		if len(v) < 2+n {
+			return nil, errShortValue
+		}
+		rr, _, err := dns.UnpackRR(v[2:2+n], 0)
+		if err != nil {
+			return nil, err
+		}
+		rrs = append(rrs, rr)
+		v = v[2+n:]
+	}
+	return rrs, nil
+}
+
+// elemCache is a least recently used cache of elements, by their lower cased name.
+type elemCache struct {
+	*lru.Cache
+}
+
+func newElemCache(size int) elemCache { return elemCache{lru.New(size)} }
+
+func (c elemCache) get(name string) (*tree.Elem, bool) {
+	v, ok := c.Get(name)
+	if !ok {
+		return nil, false
+	}
+	return v.(*tree.Elem), true
+}
+
+func (c elemCache) add(e *tree.Elem) { c.Add(strings.ToLower(e.Name()), e) }
@@ -0,0 +1,194 @@
+package file
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/file/tree"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestBoltStoreOrder(t *testing.T) {
+	dir := t.TempDir()
+	mem, err := Parse(strings.NewReader(dbMiekNLSigned), testzone, "stdin", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	disk, err := ParseWithStore(strings.NewReader(dbMiekNLSigned), testzone, "stdin", 0, NewBoltStore(dir, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mem.Len() != disk.Len() {
+		t.Errorf("Expected %d names, got %d", mem.Len(), disk.Len())
+	}
+	memAll, diskAll := mem.All(), disk.All()
+	if len(memAll) != len(diskAll) {
+		t.Fatalf("Expected %d elements, got %d", len(memAll), len(diskAll))
+	}
+	for i := range memAll {
+		if memAll[i].Name() != diskAll[i].Name() {
+			t.Errorf("Expected element %d to be %s, got %s", i, memAll[i].Name(), diskAll[i].Name())
+		}
+		if len(memAll[i].All()) != len(diskAll[i].All()) {
+			t.Errorf("Expected %d records for %s, got %d", len(memAll[i].All()), memAll[i].Name(), len(diskAll[i].All()))
+		}
+	}
+
+	for _, name := range []string{"miek.nl.", "a.miek.nl.", "b.miek.nl.", "www.miek.nl.", "zzz.miek.nl.", "Archive.miek.nl."} {
+		for _, f := range []struct {
+			op        string
+			mem, disk func(string) (*tree.Elem, bool)
+		}{
+			{"Search", mem.Search, disk.Search},
+			{"Prev", mem.Prev, disk.Prev},
+			{"Next", mem.Next, disk.Next},
+		} {
+			e1, ok1 := f.mem(strings.ToLower(name))
+			e2, ok2 := f.disk(name)
+			if ok1 != ok2 {
+				t.Errorf("Expected %s(%s) to return %t, got %t", f.op, name, ok1, ok2)
+				continue
+			}
+			if ok1 && e1.Name() != e2.Name() {
+				t.Errorf("Expected %s(%s) to return %s, got %s", f.op, name, e1.Name(), e2.Name())
+			}
+		}
+	}
+
+	closeStore(disk.Store)
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("Expected the database to be removed, got %d files", len(files))
+	}
+}
+
+func TestBoltStoreDelete(t *testing.T) {
+	s, err := NewBoltStore(t.TempDir(), 10)("miek.nl.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeStore(s)
+
+	s.Insert(test.A("a.miek.nl. 1800 IN A 127.0.0.1"))
+	s.Insert(test.A("a.miek.nl. 1800 IN A 127.0.0.2"))
+	s.Insert(test.AAAA("a.miek.nl. 1800 IN AAAA ::1"))
+	if e, ok := s.Search("a.miek.nl."); !ok || len(e.Type(dns.TypeA)) != 2 {
+		t.Fatalf("Expected 2 A records for a.miek.nl., got %v", e)
+	}
+
+	s.Delete(test.A("a.miek.nl. 1800 IN A 127.0.0.1"))
+	e, ok := s.Search("a.miek.nl.")
+	if !ok || len(e.Type(dns.TypeA)) != 0 || len(e.Type(dns.TypeAAAA)) != 1 {
+		t.Fatalf("Expected only the AAAA record for a.miek.nl., got %v", e)
+	}
+	s.Delete(test.AAAA("a.miek.nl. 1800 IN AAAA ::1"))
+	if _, ok := s.Search("a.miek.nl."); ok {
+		t.Errorf("Expected a.miek.nl. to be deleted")
+	}
+	if s.Len() != 0 {
+		t.Errorf("Expected an empty store, got %d names", s.Len())
+	}
+}
+
+func TestBoltStoreInsertAfterSearch(t *testing.T) {
+	s, err := NewBoltStore(t.TempDir(), 10)("miek.nl.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeStore(s)
+
+	s.Insert(test.A("a.miek.nl. 1800 IN A 127.0.0.1"))
+	if e, ok := s.Search("a.miek.nl."); !ok || len(e.Type(dns.TypeA)) != 1 {
+		t.Fatalf("Expected 1 A record for a.miek.nl., got %v", e)
+	}
+	s.Insert(test.A("a.miek.nl. 1800 IN A 127.0.0.2"))
+	if e, ok := s.Search("a.miek.nl."); !ok || len(e.Type(dns.TypeA)) != 2 {
+		t.Errorf("Expected 2 A records for a.miek.nl., got %v", e)
+	}
+}
+
+func TestElemCache(t *testing.T) {
+	c := newElemCache(2)
+	for _, name := range []string{"a.miek.nl.", "b.miek.nl."} {
+		c.add(newElem([]dns.RR{test.A(name + " 1800 IN A 127.0.0.1")}))
+	}
+	c.get("a.miek.nl.")
+	c.add(newElem([]dns.RR{test.A("c.miek.nl. 1800 IN A 127.0.0.1")}))
+
+	for name, want := range map[string]bool{"a.miek.nl.": true, "b.miek.nl.": false, "c.miek.nl.": true} {
+		if _, ok := c.get(name); ok != want {
+			t.Errorf("Expected %s to be cached: %t, got %t", name, want, ok)
+		}
+	}
+}
+
+func TestBoltStoreLookup(t *testing.T) {
+	for _, tc := range []struct {
+		db    string
+		cases []test.Case
+	}{
+		{dbMiekNL, dnsTestCases},
+		{dbMiekNLSigned, dnssecTestCases},
+	} {
+		zone, err := ParseWithStore(strings.NewReader(tc.db), testzone, "stdin", 0, NewBoltStore(t.TempDir(), 10))
+		if err != nil {
+			t.Fatalf("Expected no error when reading zone, got %q", err)
+		}
+		defer closeStore(zone.Store)
+
+		fm := File{Next: test.ErrorHandler(), Zones: Zones{Z: map[string]*Zone{testzone: zone}, Names: []string{testzone}}}
+		for _, c := range tc.cases {
+			rec := dnstest.NewRecorder(&test.ResponseWriter{})
+			if _, err := fm.ServeDNS(context.TODO(), rec, c.Msg()); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+				continue
+			}
+			if err := test.SortAndCheck(rec.Msg, c); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
+
+func TestBoltStoreRetire(t *testing.T) {
+	dir := t.TempDir()
+	zone, err := ParseWithStore(strings.NewReader(dbMiekNL), testzone, "stdin", 0, NewBoltStore(dir, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := func() int {
+		f, _ := os.ReadDir(dir)
+		return len(f)
+	}
+
+	// A store that is replaced while a lookup uses it, is closed when the lookup is done.
+	_, s := zone.holdStore()
+	retireStore(s)
+	if files() != 1 {
+		t.Fatalf("Expected the database of a store in use to be kept")
+	}
+	if _, ok := s.Search("a.miek.nl."); !ok {
+		t.Errorf("Expected a store in use to still answer")
+	}
+	releaseStore(s)
+	if files() != 0 {
+		t.Errorf("Expected the database to be removed after the last release")
+	}
+
+	// Without users it is closed right away.
+	zone, err = ParseWithStore(strings.NewReader(dbMiekNL), testzone, "stdin", 0, NewBoltStore(dir, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, s = zone.holdStore()
+	releaseStore(s)
+	retireStore(zone.Store)
+	if files() != 0 {
+		t.Errorf("Expected the database of a store without users to be removed")
+	}
+}
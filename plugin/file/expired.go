@@ -0,0 +1,94 @@
+package file
+
+import (
+	"github.com/coredns/coredns/plugin/pkg/edns"
+
+	"github.com/miekg/dns"
+)
+
+// ExpiredPolicy determines how a secondary zone answers once it is expired, i.e. when it couldn't
+// be refreshed from any of its primaries before the SOA expire timer fired.
+type ExpiredPolicy int
+
+const (
+	// ExpiredServfail answers with SERVFAIL and an extended DNS error.
+	ExpiredServfail ExpiredPolicy = iota
+	// ExpiredServeStale answers from the expired zone data, with the TTLs capped at StaleTTL.
+	ExpiredServeStale
+	// ExpiredRefuse answers with REFUSED and an extended DNS error.
+	ExpiredRefuse
+)
+
+// DefaultStaleTTL is the TTL cap for answers from an expired zone if not configured.
+const DefaultStaleTTL = 30
+
+// setExpired marks z as expired or not, transitions are logged and show up in the metrics.
+func (z *Zone) setExpired(expired bool) {
+	z.Lock()
+	was := z.Expired
+	z.Expired = expired
+	z.Unlock()
+
+	if expired {
+		ZoneExpired.WithLabelValues(z.origin).Set(1)
+	} else {
+		ZoneExpired.WithLabelValues(z.origin).Set(0)
+	}
+	if expired && !was {
+		ZoneExpiredCount.WithLabelValues(z.origin).Inc()
+		log.Errorf("Zone %s is expired, no primary could be reached before the SOA expire timer fired", z.origin)
+	}
+	if !expired && was {
+		log.Infof("Zone %s is no longer expired", z.origin)
+	}
+}
+
+// writeExpired writes the answer for a query to the expired zone according to the policy, for
+// policies that don't answer from the zone data.
+func (z *Zone) writeExpired(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	switch z.ExpiredPolicy {
+	case ExpiredRefuse:
+		m.SetRcode(r, dns.RcodeRefused)
+		edns.SetExtendedError(m, r, dns.ExtendedErrorCodeNotAuthoritative, "zone "+z.origin+" is expired")
+	default:
+		m.SetRcode(r, dns.RcodeServerFailure)
+		edns.SetExtendedError(m, r, dns.ExtendedErrorCodeNoReachableAuthority, "zone "+z.origin+" is expired")
+	}
+	w.WriteMsg(m)
+}
+
+// staleAnswer caps the TTLs in m at the stale TTL of z and marks m, the response to r, as a stale
+// answer.
+func (z *Zone) staleAnswer(m, r *dns.Msg) {
+	ttl := z.StaleTTL
+	if ttl == 0 {
+		ttl = DefaultStaleTTL
+	}
+	m.Answer = capTTL(m.Answer, ttl)
+	m.Ns = capTTL(m.Ns, ttl)
+	m.Extra = capTTL(m.Extra, ttl)
+	edns.SetExtendedError(m, r, dns.ExtendedErrorCodeStaleAnswer, "zone "+z.origin+" is expired")
+}
+
+// capTTL returns section with the TTLs capped at ttl. Both the slice and the records may be shared
+// with the zone, so they are copied instead of modified in place.
+func capTTL(section []dns.RR, ttl uint32) []dns.RR {
+	var capped []dns.RR
+	for i, rr := range section {
+		if rr.Header().Ttl <= ttl {
+			continue
+		}
+		if capped == nil {
+			capped = make([]dns.RR, len(section))
+			copy(capped, section)
+		}
+		rr = dns.Copy(rr)
+		rr.Header().Ttl = ttl
+		capped[i] = rr
+	}
+	if capped == nil {
+		return section
+	}
+	return capped
+}
@@ -0,0 +1,92 @@
+package file
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestExpiredPolicy(t *testing.T) {
+	tests := []struct {
+		policy        ExpiredPolicy
+		staleTTL      uint32
+		expectedRcode int
+		expectedCode  uint16
+		expectedTTL   uint32 // TTL of the answer, zero if there is no answer
+	}{
+		{ExpiredServfail, 0, dns.RcodeServerFailure, dns.ExtendedErrorCodeNoReachableAuthority, 0},
+		{ExpiredRefuse, 0, dns.RcodeRefused, dns.ExtendedErrorCodeNotAuthoritative, 0},
+		{ExpiredServeStale, 0, dns.RcodeSuccess, dns.ExtendedErrorCodeStaleAnswer, DefaultStaleTTL},
+		{ExpiredServeStale, 10, dns.RcodeSuccess, dns.ExtendedErrorCodeStaleAnswer, 10},
+	}
+
+	for i, tc := range tests {
+		zone, err := Parse(strings.NewReader(dbMiekNL), testzone, "stdin", 0)
+		if err != nil {
+			t.Fatalf("Expected no error when reading zone, got %q", err)
+		}
+		zone.ExpiredPolicy = tc.policy
+		zone.StaleTTL = tc.staleTTL
+		zone.setExpired(true)
+
+		fm := File{Next: test.ErrorHandler(), Zones: Zones{Z: map[string]*Zone{testzone: zone}, Names: []string{testzone}}}
+
+		m := new(dns.Msg)
+		m.SetQuestion("a.miek.nl.", dns.TypeA)
+		m.SetEdns0(4096, false)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := fm.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Test %d: expected no error, got %v", i, err)
+		}
+
+		if rec.Msg.Rcode != tc.expectedRcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, tc.expectedRcode, rec.Msg.Rcode)
+		}
+		opt := rec.Msg.IsEdns0()
+		if opt == nil || len(opt.Option) != 1 {
+			t.Fatalf("Test %d: expected an extended error", i)
+		}
+		if ede, ok := opt.Option[0].(*dns.EDNS0_EDE); !ok || ede.InfoCode != tc.expectedCode {
+			t.Errorf("Test %d: expected extended error %d, got %v", i, tc.expectedCode, opt.Option[0])
+		}
+
+		// Without EDNS in the query, the response can't carry the extended error.
+		m.Extra = nil
+		rec = dnstest.NewRecorder(&test.ResponseWriter{})
+		fm.ServeDNS(context.TODO(), rec, m)
+		if opt := rec.Msg.IsEdns0(); opt != nil {
+			t.Errorf("Test %d: expected no OPT record for a query without one, got %s", i, opt)
+		}
+
+		if tc.expectedTTL == 0 {
+			continue
+		}
+		if len(rec.Msg.Answer) == 0 {
+			t.Fatalf("Test %d: expected an answer", i)
+		}
+		if ttl := rec.Msg.Answer[0].Header().Ttl; ttl != tc.expectedTTL {
+			t.Errorf("Test %d: expected TTL %d, got %d", i, tc.expectedTTL, ttl)
+		}
+	}
+
+	// The stale answers must not modify the zone itself.
+	zone, _ := Parse(strings.NewReader(dbMiekNL), testzone, "stdin", 0)
+	zone.ExpiredPolicy = ExpiredServeStale
+	zone.setExpired(true)
+	m := new(dns.Msg)
+	m.SetQuestion("a.miek.nl.", dns.TypeA)
+	state := request.Request{Req: m, W: &test.ResponseWriter{}}
+	answer, _, _, _ := zone.Lookup(context.TODO(), state, "a.miek.nl.", dns.TypeA)
+	before := answer[0].Header().Ttl
+	zone.staleAnswer(&dns.Msg{Answer: answer}, m)
+	answer, _, _, _ = zone.Lookup(context.TODO(), state, "a.miek.nl.", dns.TypeA)
+	if answer[0].Header().Ttl != before {
+		t.Errorf("Expected the zone TTL to stay %d, got %d", before, answer[0].Header().Ttl)
+	}
+}
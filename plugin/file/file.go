@@ -5,9 +5,11 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/coredns/coredns/plugin"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 	"github.com/coredns/coredns/plugin/transfer"
 	"github.com/coredns/coredns/request"
 
@@ -60,15 +62,11 @@ func (f File) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (i
 			m.Authoritative = true
 			w.WriteMsg(m)
 
-			log.Infof("Notify from %s for %s: checking transfer", state.IP(), zone)
-			ok, err := z.shouldTransfer()
-			if ok {
-				z.TransferIn()
+			// The primaries are checked by Update, which serializes the checks with the refreshes of the zone.
+			if z.notify() {
+				log.Infof("Notify from %s for %s: checking transfer", state.IP(), zone)
 			} else {
-				log.Infof("Notify from %s for %s: no SOA serial increase seen", state.IP(), zone)
-			}
-			if err != nil {
-				log.Warningf("Notify from %s for %s: failed primary check: %s", state.IP(), zone, err)
+				log.Debugf("Notify from %s for %s: check already pending", state.IP(), zone)
 			}
 			return dns.RcodeSuccess, nil
 		}
@@ -79,9 +77,10 @@ func (f File) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (i
 	z.RLock()
 	exp := z.Expired
 	z.RUnlock()
-	if exp {
-		log.Errorf("Zone %s is expired", zone)
-		return dns.RcodeServerFailure, nil
+	if exp && z.ExpiredPolicy != ExpiredServeStale {
+		log.Debugf("Zone %s is expired", zone)
+		z.writeExpired(w, r)
+		return dns.RcodeSuccess, nil
 	}
 
 	answer, ns, extra, result := z.Lookup(ctx, state, qname, r.Question[0].Qtype)
@@ -109,6 +108,10 @@ func (f File) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (i
 		m.Rcode = dns.RcodeServerFailure
 	}
 
+	if exp {
+		z.staleAnswer(m, r)
+	}
+
 	w.WriteMsg(m)
 	return dns.RcodeSuccess, nil
 }
@@ -131,10 +134,27 @@ func (s *serialErr) Error() string {
 // If serial >= 0 it will reload the zone, if the SOA hasn't changed
 // it returns an error indicating nothing was read.
 func Parse(f io.Reader, origin, fileName string, serial int64) (*Zone, error) {
-	zp := dns.NewZoneParser(f, dns.Fqdn(origin), fileName)
+	return ParseWithStore(f, origin, fileName, serial, nil)
+}
+
+// ParseWithStore is like Parse, but the records of the zone are kept in a store returned by newStore. If
+// newStore is nil, the records are kept in memory.
+func ParseWithStore(f io.Reader, origin, fileName string, serial int64, newStore StoreFunc) (_ *Zone, err error) {
+	sr := NewSCIONOriginReader(f, fileName)
+	zp := dns.NewZoneParser(sr, dns.Fqdn(origin), fileName)
 	zp.SetIncludeAllowed(true)
 	z := NewZone(origin, fileName)
+	z.NewStore = newStore
+	if z.Store, err = z.newStore(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			closeStore(z.Store)
+		}
+	}()
 	seenSOA := false
+	var scions []dns.RR
 	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
 		if err := zp.Err(); err != nil {
 			return nil, err
@@ -155,10 +175,51 @@ func Parse(f io.Reader, origin, fileName string, serial int64) (*Zone, error) {
 		if err := z.Insert(rr); err != nil {
 			return nil, err
 		}
+		if rr.Header().Rrtype == pkgscion.TypeSCION {
+			scions = append(scions, rr)
+		}
+	}
+	if err := sr.Err(); err != nil {
+		return nil, err
 	}
 	if !seenSOA {
 		return nil, fmt.Errorf("file %q has no SOA record for origin %s", fileName, origin)
 	}
+	z.insertSCIONTXT(scions)
 
 	return z, nil
 }
+
+// insertSCIONTXT inserts a TXT record "scion=ISD-AS,[IP]" for each SCION address record in scions, as
+// SCION resolvers only look up the TXT records. Addresses that have a TXT record in the zone already
+// are skipped. Nothing is inserted in signed zones, or at names with signatures: the new record would
+// have no signature, and their NSEC or NSEC3 records may deny that TXT records exist.
+func (z *Zone) insertSCIONTXT(scions []dns.RR) {
+	if apex, found := z.Store.Search(z.origin); found && len(apex.Type(dns.TypeDNSKEY)) > 0 {
+		return
+	}
+	for _, rr := range scions {
+		s, ok := pkgscion.SCIONOf(rr)
+		if !ok {
+			continue
+		}
+		hdr := *rr.Header()
+		if elem, found := z.Store.Search(hdr.Name); found {
+			if hasTXT(elem.Type(dns.TypeTXT), s.TXT()) || len(elem.Type(dns.TypeRRSIG)) > 0 {
+				continue
+			}
+		}
+		hdr.Rrtype = dns.TypeTXT
+		z.Store.Insert(&dns.TXT{Hdr: hdr, Txt: []string{s.TXT()}})
+	}
+}
+
+// hasTXT returns true if one of the TXT records in rrs has the text txt.
+func hasTXT(rrs []dns.RR, txt string) bool {
+	for _, rr := range rrs {
+		if t, ok := rr.(*dns.TXT); ok && strings.Join(t.Txt, "") == txt {
+			return true
+		}
+	}
+	return false
+}
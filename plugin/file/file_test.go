@@ -3,6 +3,10 @@ package file
 import (
 	"strings"
 	"testing"
+
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+
+	"github.com/miekg/dns"
 )
 
 func BenchmarkFileParseInsert(b *testing.B) {
@@ -29,3 +33,88 @@ www          IN  A      192.168.0.14
 mail         IN  A      192.168.0.15
 imap         IN  CNAME  mail
 `
+
+func TestParseSCION(t *testing.T) {
+	z, err := Parse(strings.NewReader(dbSCION), "example.org.", "stdin", 0)
+	if err != nil {
+		t.Fatalf("Expected no error when reading zone, got %q", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		txt  []string
+	}{
+		{"ns1.example.org.", []string{"scion=19-ffaa:1:1067,[192.0.2.1]"}},
+		// The TXT record is in the zone already.
+		{"ns2.example.org.", []string{"scion=19-ffaa:1:1068,[192.0.2.2]"}},
+		{"ns3.example.org.", []string{"v=spf1 -all", "scion=19-ffaa:1:1069,[2001:db8::3]"}},
+	} {
+		elem, found := z.Store.Search(tc.name)
+		if !found {
+			t.Fatalf("Expected %s in the zone", tc.name)
+		}
+		if scions := elem.Type(pkgscion.TypeSCION); len(scions) != 1 {
+			t.Errorf("Expected a SCION record for %s, got %v", tc.name, scions)
+		}
+		txts := elem.Type(dns.TypeTXT)
+		if len(txts) != len(tc.txt) {
+			t.Fatalf("Expected %d TXT records for %s, got %v", len(tc.txt), tc.name, txts)
+		}
+		for i, txt := range tc.txt {
+			if got := txts[i].(*dns.TXT).Txt[0]; got != txt {
+				t.Errorf("Expected TXT %q for %s, got %q", txt, tc.name, got)
+			}
+		}
+	}
+}
+
+const dbSCION = `
+$TTL         1M
+$ORIGIN      example.org.
+
+@            IN  SOA    ns1 hostmaster 1 7200 1800 86400 100
+             IN  NS     ns1
+ns1          IN  SCION  19-ffaa:1:1067,192.0.2.1
+ns2          IN  SCION  19-ffaa:1:1068,[192.0.2.2]
+             IN  TXT    "scion=19-ffaa:1:1068,[192.0.2.2]"
+ns3          IN  TXT    "v=spf1 -all"
+             IN  SCION  19-ffaa:1:1069,2001:db8::3
+`
+
+func TestParseSCIONSigned(t *testing.T) {
+	for _, db := range []string{dbSCIONSigned, dbSCIONSignedName} {
+		z, err := Parse(strings.NewReader(db), "example.org.", "stdin", 0)
+		if err != nil {
+			t.Fatalf("Expected no error when reading zone, got %q", err)
+		}
+		elem, found := z.Store.Search("ns1.example.org.")
+		if !found {
+			t.Fatal("Expected ns1.example.org. in the zone")
+		}
+		if txts := elem.Type(dns.TypeTXT); len(txts) != 0 {
+			t.Errorf("Expected no TXT records for ns1.example.org., got %v", txts)
+		}
+	}
+}
+
+const dbSCIONSigned = `
+$TTL         1M
+$ORIGIN      example.org.
+
+@            IN  SOA    ns1 hostmaster 1 7200 1800 86400 100
+             IN  NS     ns1
+             IN  DNSKEY 256 3 13 eNMYFZYb6e0oJOV47IPo5f/UHy7wY9aBebotvcKakIYLyyGscBmXJQhbKLt/LhrMNDE2Q96hQnI5PdTBeOLzhQ==
+ns1          IN  SCION  19-ffaa:1:1067,192.0.2.1
+`
+
+// dbSCIONSignedName has no DNSKEY, but a signature at the name of the SCION record.
+const dbSCIONSignedName = `
+$TTL         1M
+$ORIGIN      example.org.
+
+@            IN  SOA    ns1 hostmaster 1 7200 1800 86400 100
+             IN  NS     ns1
+ns1          IN  SCION  19-ffaa:1:1067,192.0.2.1
+             IN  A      192.0.2.1
+             IN  RRSIG  A 13 3 60 20300101000000 20200101000000 45330 example.org. aGVsbG8gd29ybGQ=
+`
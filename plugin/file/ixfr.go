@@ -0,0 +1,184 @@
+package file
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/file/tree"
+
+	"github.com/miekg/dns"
+)
+
+var errIXFR = errors.New("malformed incremental zone transfer")
+
+// transferQuery returns the query that transfers z from a primary: an IXFR for the serial of z once it
+// is loaded, an AXFR otherwise.
+func (z *Zone) transferQuery() *dns.Msg {
+	m := new(dns.Msg)
+	z.RLock()
+	soa := z.Apex.SOA
+	z.RUnlock()
+	if soa == nil {
+		m.SetAxfr(z.origin)
+		return m
+	}
+	m.SetIxfr(z.origin, soa.Serial, soa.Ns, soa.Mbox)
+	return m
+}
+
+// ixfrDelta is a difference sequence of an incremental zone transfer: the records deleted from, and
+// the ones added to, a version of the zone. https://www.rfc-editor.org/rfc/rfc1995#section-4
+type ixfrDelta struct {
+	del []dns.RR
+	add []dns.RR
+}
+
+// ixfrDeltas splits the answer rrs to an IXFR into its difference sequences. It returns false if rrs is
+// a full zone, which primaries send if they can't answer incrementally.
+func ixfrDeltas(rrs []dns.RR) ([]ixfrDelta, bool, error) {
+	if len(rrs) == 0 {
+		return nil, false, errIXFR
+	}
+	first, ok := rrs[0].(*dns.SOA)
+	if !ok {
+		return nil, false, errIXFR
+	}
+	if len(rrs) == 1 {
+		// Only the SOA: the zone is current.
+		return nil, true, nil
+	}
+	if soa, ok := rrs[1].(*dns.SOA); !ok || soa.Serial == first.Serial {
+		return nil, false, nil
+	}
+	if last, ok := rrs[len(rrs)-1].(*dns.SOA); !ok || last.Serial != first.Serial {
+		return nil, false, errIXFR
+	}
+
+	var (
+		deltas []ixfrDelta
+		adding bool
+	)
+	for _, rr := range rrs[1 : len(rrs)-1] {
+		if _, ok := rr.(*dns.SOA); ok {
+			// The old SOA starts a sequence with its deletions, the new SOA starts its additions.
+			if len(deltas) == 0 || adding {
+				deltas = append(deltas, ixfrDelta{})
+				adding = false
+			} else {
+				adding = true
+			}
+		}
+		d := &deltas[len(deltas)-1]
+		if adding {
+			d.add = append(d.add, rr)
+		} else {
+			d.del = append(d.del, rr)
+		}
+	}
+	if !adding {
+		// The last sequence has no new SOA.
+		return nil, false, errIXFR
+	}
+	return deltas, true, nil
+}
+
+// applyIXFR fills z1 from the answer rrs to an IXFR: with the records of z and the differences in rrs
+// applied, or, if rrs is a full zone, with rrs alone. z itself isn't changed, lookups keep using it until
+// z1 is set live.
+func (z *Zone) applyIXFR(z1 *Zone, rrs []dns.RR) error {
+	deltas, incremental, err := ixfrDeltas(rrs)
+	if err != nil {
+		return err
+	}
+	if !incremental {
+		log.Infof("Primary answered the incremental transfer of `%s' with the full zone", z.origin)
+		for _, rr := range rrs {
+			if err := z1.Insert(rr); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	apex, store := z.holdStore()
+	defer releaseStore(store)
+	z1.Apex = Apex{
+		SOA:    apex.SOA,
+		NS:     append([]dns.RR(nil), apex.NS...),
+		SIGSOA: append([]dns.RR(nil), apex.SIGSOA...),
+		SIGNS:  append([]dns.RR(nil), apex.SIGNS...),
+	}
+	store.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+		for _, rr := range e.All() {
+			z1.Store.Insert(rr)
+		}
+		return nil
+	})
+
+	for _, d := range deltas {
+		for _, rr := range d.del {
+			z1.remove(rr)
+		}
+		for _, rr := range d.add {
+			// Adding a record that exists already doesn't duplicate it.
+			z1.remove(rr)
+			if err := z1.Insert(rr); err != nil {
+				return err
+			}
+		}
+	}
+	log.Debugf("Applied %d incremental changes to `%s'", len(deltas), z.origin)
+	return nil
+}
+
+// remove removes the record rr from z, other records with its name and type are kept. The SOA record
+// isn't removed, it is replaced by the next one inserted.
+func (z *Zone) remove(rr dns.RR) {
+	name := strings.ToLower(rr.Header().Name)
+
+	switch rr.Header().Rrtype {
+	case dns.TypeSOA:
+		return
+	case dns.TypeNS:
+		if name == z.origin {
+			z.Apex.NS = without(z.Apex.NS, rr)
+			return
+		}
+	case dns.TypeRRSIG:
+		switch rr.(*dns.RRSIG).TypeCovered {
+		case dns.TypeSOA:
+			z.Apex.SIGSOA = without(z.Apex.SIGSOA, rr)
+			return
+		case dns.TypeNS:
+			if name == z.origin {
+				z.Apex.SIGNS = without(z.Apex.SIGNS, rr)
+				return
+			}
+		}
+	}
+
+	e, ok := z.Store.Search(name)
+	if !ok {
+		return
+	}
+	rrs := e.Type(rr.Header().Rrtype)
+	kept := without(rrs, rr)
+	if len(kept) == len(rrs) {
+		return
+	}
+	z.Store.Delete(rr)
+	for _, k := range kept {
+		z.Store.Insert(k)
+	}
+}
+
+// without returns rrs without the records equal to rr.
+func without(rrs []dns.RR, rr dns.RR) []dns.RR {
+	kept := make([]dns.RR, 0, len(rrs))
+	for _, r := range rrs {
+		if !dns.IsDuplicate(r, rr) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
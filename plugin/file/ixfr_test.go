@@ -0,0 +1,127 @@
+package file
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// ixfrPrimary answers AXFRs with version 1 of testZone and IXFRs from version 1 with the changes to version
+// 2. With noIXFR set it refuses IXFRs.
+type ixfrPrimary struct {
+	noIXFR bool
+	ixfrs  int
+}
+
+func ixfrSOA(serial int) dns.RR {
+	return test.SOA(fmt.Sprintf("%s IN SOA bla. bla. %d 0 0 0 0", testZone, serial))
+}
+
+func (p *ixfrPrimary) Handler(w dns.ResponseWriter, req *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(req)
+	switch req.Question[0].Qtype {
+	case dns.TypeAXFR:
+		m.Answer = []dns.RR{
+			ixfrSOA(1),
+			test.A("a." + testZone + " IN A 127.0.0.1"),
+			test.A("b." + testZone + " IN A 127.0.0.2"),
+			ixfrSOA(1),
+		}
+	case dns.TypeIXFR:
+		p.ixfrs++
+		if p.noIXFR {
+			m.Rcode = dns.RcodeNotImplemented
+			break
+		}
+		m.Answer = []dns.RR{
+			ixfrSOA(2),
+			ixfrSOA(1),
+			test.A("b." + testZone + " IN A 127.0.0.2"),
+			ixfrSOA(2),
+			test.A("c." + testZone + " IN A 127.0.0.3"),
+			ixfrSOA(2),
+		}
+	}
+	w.WriteMsg(m)
+	// A transfer is read until the primary closes the connection.
+	w.Close()
+}
+
+func TestTransferInIXFR(t *testing.T) {
+	for _, noIXFR := range []bool{false, true} {
+		p := &ixfrPrimary{noIXFR: noIXFR}
+		s := dnstest.NewServer(p.Handler)
+
+		z := new(Zone)
+		z.origin = testZone
+		z.TransferFrom = []string{s.Addr}
+
+		// The first transfer is a full one.
+		if err := z.TransferIn(); err != nil {
+			t.Fatalf("Unable to run TransferIn: %v", err)
+		}
+		if p.ixfrs != 0 {
+			t.Errorf("Expected an AXFR to load the zone, got %d IXFRs", p.ixfrs)
+		}
+
+		if err := z.TransferIn(); err != nil {
+			t.Fatalf("Unable to run TransferIn: %v", err)
+		}
+		s.Close()
+		if p.ixfrs != 1 {
+			t.Errorf("Expected an IXFR to refresh the zone, got %d", p.ixfrs)
+		}
+
+		serial, present := uint32(2), []string{"a.", "c."}
+		if noIXFR {
+			// The full transfer after the refused IXFR has version 1.
+			serial, present = 1, []string{"a.", "b."}
+		}
+		if z.Apex.SOA.Serial != serial {
+			t.Errorf("Expected serial %d, got %d", serial, z.Apex.SOA.Serial)
+		}
+		if z.Store.Len() != 2 {
+			t.Errorf("Expected 2 names in the zone, got %d", z.Store.Len())
+		}
+		for _, name := range present {
+			if _, ok := z.Store.Search(name + testZone); !ok {
+				t.Errorf("Expected %s in the zone", name+testZone)
+			}
+		}
+	}
+}
+
+func TestIXFRDeltas(t *testing.T) {
+	a := test.A("a." + testZone + " IN A 127.0.0.1")
+	b := test.A("b." + testZone + " IN A 127.0.0.2")
+
+	tests := []struct {
+		rrs         []dns.RR
+		deltas      int
+		incremental bool
+		err         bool
+	}{
+		{[]dns.RR{ixfrSOA(2)}, 0, true, false},
+		{[]dns.RR{ixfrSOA(2), a, ixfrSOA(2)}, 0, false, false},
+		{[]dns.RR{ixfrSOA(2), ixfrSOA(2)}, 0, false, false},
+		{[]dns.RR{ixfrSOA(3), ixfrSOA(1), a, ixfrSOA(2), ixfrSOA(2), ixfrSOA(3), b, ixfrSOA(3)}, 2, true, false},
+		// No new SOA in the last sequence.
+		{[]dns.RR{ixfrSOA(2), ixfrSOA(1), a, ixfrSOA(2)}, 0, false, true},
+		{[]dns.RR{a}, 0, false, true},
+	}
+	for i, tc := range tests {
+		deltas, incremental, err := ixfrDeltas(tc.rrs)
+		if (err != nil) != tc.err {
+			t.Errorf("Test %d: expected error %t, got %v", i, tc.err, err)
+			continue
+		}
+		if len(deltas) != tc.deltas || incremental != tc.incremental {
+			t.Errorf("Test %d: expected %d deltas and incremental %t, got %d and %t", i, tc.deltas, tc.incremental, len(deltas), incremental)
+		}
+	}
+}
@@ -37,10 +37,8 @@ func (z *Zone) Lookup(ctx context.Context, state request.Request, qname string,
 	// If z is a secondary zone we might not have transferred it, meaning we have
 	// all zone context setup, except the actual record. This means (for one thing) the apex
 	// is empty and we don't have a SOA record.
-	z.RLock()
-	ap := z.Apex
-	tr := z.Tree
-	z.RUnlock()
+	ap, tr := z.holdStore()
+	defer releaseStore(tr)
 	if ap.SOA == nil {
 		return nil, nil, nil, ServerFailure
 	}
@@ -341,7 +339,7 @@ func (z *Zone) externalLookup(ctx context.Context, state request.Request, elem *
 	}
 
 	targetName := rrs[0].(*dns.CNAME).Target
-	elem, _ = z.Tree.Search(targetName)
+	elem, _ = z.Store.Search(targetName)
 	if elem == nil {
 		lookupRRs, result := z.doLookup(ctx, state, targetName, qtype)
 		rrs = append(rrs, lookupRRs...)
@@ -361,7 +359,7 @@ Redo:
 			rrs = append(rrs, sigs...)
 		}
 		targetName := cname[0].(*dns.CNAME).Target
-		elem, _ = z.Tree.Search(targetName)
+		elem, _ = z.Store.Search(targetName)
 		if elem == nil {
 			lookupRRs, result := z.doLookup(ctx, state, targetName, qtype)
 			rrs = append(rrs, lookupRRs...)
@@ -425,7 +423,7 @@ func (z *Zone) additionalProcessing(answer []dns.RR, do bool) (extra []dns.RR) {
 			continue
 		}
 
-		elem, _ := z.Tree.Search(name)
+		elem, _ := z.Store.Search(name)
 		if elem == nil {
 			continue
 		}
@@ -0,0 +1,32 @@
+package file
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// ZoneExpiredCount is the number of times a secondary zone expired.
+	ZoneExpiredCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "secondary",
+		Name:      "zone_expired_total",
+		Help:      "Counter of the number of times a secondary zone expired.",
+	}, []string{"zone"})
+	// ZoneExpired is 1 while a secondary zone is expired and 0 otherwise.
+	ZoneExpired = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "secondary",
+		Name:      "zone_expired",
+		Help:      "Gauge that is 1 while a secondary zone is expired.",
+	}, []string{"zone"})
+	// SQUICErrorCount is the number of failed exchanges with SCION primaries per kind of failure.
+	SQUICErrorCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "secondary",
+		Name:      "squic_errors_total",
+		Help:      "Counter of failed exchanges with SCION primaries per zone and kind of failure.",
+	}, []string{"zone", "kind"})
+)
@@ -1,13 +1,26 @@
 package file
 
 import (
+	"context"
 	"net"
+	"strings"
+	"time"
 
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/pkg/parse"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
 )
 
+// notifyInterval is the least time between two checks of the primaries triggered by notifies. Notifies
+// that arrive in the meantime are coalesced into the next check.
+var notifyInterval = 5 * time.Second
+
+// lookupTimeout bounds looking up the addresses or the name of a primary.
+var lookupTimeout = 2 * time.Second
+
 // isNotify checks if state is a notify message and if so, will *also* check if it
 // is from one of the configured masters. If not it will not be a valid notify
 // message. If the zone z is not a secondary zone the message will also be ignored.
@@ -18,16 +31,72 @@ func (z *Zone) isNotify(state request.Request) bool {
 	if len(z.TransferFrom) == 0 {
 		return false
 	}
-	// If remote IP matches we accept.
-	remote := state.IP()
+	// If remote IP, or SCION address, matches we accept.
+	_, scion := pkgscion.ClientOf(state.W.RemoteAddr())
+	remote := dnsutil.HostOf(state.W.RemoteAddr().String())
 	for _, f := range z.TransferFrom {
-		from, _, err := net.SplitHostPort(f)
-		if err != nil {
-			continue
-		}
-		if from == remote {
+		_, addr := parse.Transport(f)
+		if dnsutil.HostOf(addr) == remote {
 			return true
 		}
 	}
+	// Primaries given by domain name, as resolved on the last refresh.
+	if hosts := z.primaryHosts.Load(); hosts != nil {
+		if scion {
+			return hosts.scion[remote]
+		}
+		return hosts.ip[remote]
+	}
 	return false
 }
+
+// primaryHostSet holds the hosts of the primaries given by domain name, as dnsutil.HostOf returns them.
+type primaryHostSet struct {
+	ip    map[string]bool
+	scion map[string]bool
+}
+
+// resolvePrimaryHosts resolves the primaries of z that are given by domain name, for isNotify: to their
+// SCION address, as resolvePrimary does, for notifies over SCION, and to their IP addresses otherwise. It
+// runs on every refresh, so notifies don't wait for the lookups.
+func (z *Zone) resolvePrimaryHosts(ctx context.Context) {
+	hosts := &primaryHostSet{ip: map[string]bool{}, scion: map[string]bool{}}
+	for _, tr := range z.TransferFrom {
+		_, addr := parse.Transport(tr)
+		host := dnsutil.HostOf(addr)
+		if dnsutil.IsSCIONAddress(addr) || net.ParseIP(host) != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+		if scaddr := z.lookupSCION(ctx, host); scaddr != "" {
+			hosts.scion[dnsutil.HostOf(scaddr)] = true
+		}
+		for _, ip := range z.lookupIPs(ctx, host) {
+			hosts.ip[ip] = true
+		}
+		cancel()
+	}
+	z.primaryHosts.Store(hosts)
+}
+
+// lookupIPs returns the IP addresses of the primary name, from the hosts plugin or the DNS.
+func (z *Zone) lookupIPs(ctx context.Context, name string) []string {
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		if a, err := z.LookupAddrInHosts(dns.Fqdn(name), qtype); err == nil && a != "" {
+			return []string{a}
+		}
+	}
+	ips, _ := net.DefaultResolver.LookupHost(ctx, strings.TrimSuffix(name, "."))
+	return ips
+}
+
+// notify makes Update check the primaries of z at once, instead of at the next refresh. It doesn't
+// block: if a check is pending already, the notify is coalesced into it and false is returned.
+func (z *Zone) notify() bool {
+	select {
+	case z.notified <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
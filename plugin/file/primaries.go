@@ -0,0 +1,257 @@
+package file
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/pkg/doqclient"
+	"github.com/coredns/coredns/plugin/pkg/parse"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
+	"github.com/miekg/dns/resolvapi"
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+// raceGrace is how long querySOAs waits for the other primaries once the first one answered, so a slow or
+// unreachable primary doesn't hold up the others.
+var raceGrace = time.Second
+
+var errSOATimeout = errors.New("SOA query timed out, another primary answered first")
+
+// primarySOA is the serial of the zone at a primary, or the error querying it.
+type primarySOA struct {
+	primary string
+	serial  uint32
+	err     error
+}
+
+// querySOAs queries the SOA serials of the zone at primaries in parallel. It returns once all of them
+// answered, or raceGrace after the first one did. The results are in the order of primaries, the ones that
+// didn't answer in time have errSOATimeout.
+func (z *Zone) querySOAs(primaries []string) []primarySOA {
+	soas := make([]primarySOA, len(primaries))
+	for i, tr := range primaries {
+		soas[i] = primarySOA{primary: tr, err: errSOATimeout}
+	}
+	if len(primaries) == 1 {
+		soas[0].serial, soas[0].err = z.querySOA(primaries[0])
+		return soas
+	}
+
+	type result struct {
+		i      int
+		serial uint32
+		err    error
+	}
+	// Buffered, so the queries that are still running once we return don't block.
+	results := make(chan result, len(primaries))
+	for i, tr := range primaries {
+		go func(i int, tr string) {
+			serial, err := z.querySOA(tr)
+			results <- result{i, serial, err}
+		}(i, tr)
+	}
+
+	var grace <-chan time.Time
+	for n := 0; n < len(primaries); n++ {
+		select {
+		case r := <-results:
+			soas[r.i].serial, soas[r.i].err = r.serial, r.err
+			if r.err == nil && grace == nil {
+				grace = time.After(raceGrace)
+			}
+		case <-grace:
+			return soas
+		}
+	}
+	return soas
+}
+
+// rankPrimaries orders the primaries of soas by the serial they have, highest first, so the zone is
+// transferred from the most recent one. Primaries that couldn't be queried come last, in their configured
+// order.
+func rankPrimaries(soas []primarySOA) []string {
+	ranked := make([]primarySOA, len(soas))
+	copy(ranked, soas)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.err != nil || b.err != nil {
+			return a.err == nil && b.err != nil
+		}
+		return less(b.serial, a.serial)
+	})
+	primaries := make([]string, len(ranked))
+	for i, p := range ranked {
+		primaries[i] = p.primary
+	}
+	return primaries
+}
+
+// transferOrder returns the primaries of z in the order TransferIn tries them: with more than one, those
+// that may be used at now are ranked by their serial, see rankPrimaries. Primaries outside of their
+// transfer windows come last, TransferIn skips them.
+func (z *Zone) transferOrder(loaded bool, now time.Time) []string {
+	if len(z.TransferFrom) < 2 {
+		return z.TransferFrom
+	}
+	var usable, skipped []string
+	for _, tr := range z.TransferFrom {
+		if loaded && !z.inTransferWindow(tr, now) {
+			skipped = append(skipped, tr)
+			continue
+		}
+		usable = append(usable, tr)
+	}
+	if len(usable) < 2 {
+		return z.TransferFrom
+	}
+	return append(rankPrimaries(z.querySOAs(usable)), skipped...)
+}
+
+// primary is a primary of the zone, resolved to the network and address it is dialed on.
+type primary struct {
+	net    string // tcp, tcp-tls or squic
+	addr   string
+	tlsCfg *tls.Config // for all but tcp
+}
+
+// resolvePrimary resolves the primary tr, in the form of parse.TransferAddr. A domain name without a
+// transport is transferred from over squic if it has a SCION address, in the hosts plugin or in the DNS,
+// and over TCP otherwise.
+func (z *Zone) resolvePrimary(tr string) (primary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	trans, addr := parse.Transport(tr)
+	if trans == transport.SQUIC {
+		if a, err := dnsutil.ParseSCIONAddress(addr); err == nil {
+			if a.Port == 0 {
+				addr = a.WithPort(uint16(pkgscion.Get().Port())).String()
+			}
+			p := primary{net: transport.SQUIC, addr: addr, tlsCfg: z.transferTLSConfig()}
+			if err := z.useTRCs(p.tlsCfg, addr); err != nil {
+				return primary{}, err
+			}
+			// Check if we find our primary Server in hosts file, otherwise look up its name. Without a
+			// name the handshake would fail anyway.
+			if name, err := z.LookupInHosts(addr); err == nil && name != "" {
+				p.tlsCfg.ServerName = name
+			} else if names, err := resolvapi.LookupUDPAddr(ctx, addr); err == nil && len(names) > 0 {
+				p.tlsCfg.ServerName = names[0]
+			} else {
+				return p, z.squicError(&doqclient.Error{Kind: doqclient.KindTLSNameMissing, Transport: transport.SQUIC, Addr: addr, Err: err}, addr, true)
+			}
+			return p, nil
+		}
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		// A domain name without a port, the transport depends on its addresses.
+		host = addr
+		port = ""
+	}
+	tlsCfg := z.transferTLSConfig()
+	tlsCfg.ServerName = strings.TrimSuffix(host, ".")
+
+	if trans == transport.TLS {
+		if err := z.useTRCs(tlsCfg, ""); err != nil {
+			return primary{}, err
+		}
+		return primary{net: "tcp-tls", addr: addr, tlsCfg: tlsCfg}, nil
+	}
+	if net.ParseIP(host) != nil {
+		return primary{net: "tcp", addr: addr}, nil
+	}
+
+	if trans == transport.SQUIC || port == "" {
+		if scaddr := z.lookupSCION(ctx, host); scaddr != "" {
+			a, err := pan.ParseUDPAddr(scaddr)
+			if err != nil {
+				return primary{}, fmt.Errorf("invalid SCION address %q for primary %q: %s", scaddr, host, err)
+			}
+			if a.Port == 0 {
+				p := pkgscion.Get().Port()
+				if port != "" {
+					p, _ = strconv.Atoi(port)
+				}
+				a = a.WithPort(uint16(p))
+			}
+			if err := z.useTRCs(tlsCfg, scaddr); err != nil {
+				return primary{}, err
+			}
+			return primary{net: transport.SQUIC, addr: a.IA.String() + ",[" + a.IP.String() + "]:" + strconv.Itoa(int(a.Port)), tlsCfg: tlsCfg}, nil
+		}
+		if trans == transport.SQUIC {
+			return primary{}, fmt.Errorf("no SCION address for primary %q", host)
+		}
+		port = transport.Port
+	}
+	if a, err := z.LookupAddrInHosts(dns.Fqdn(host), dns.TypeA); err == nil && a != "" {
+		host = a
+	}
+	// Otherwise the dialer resolves the name.
+	return primary{net: "tcp", addr: net.JoinHostPort(strings.TrimSuffix(host, "."), port)}, nil
+}
+
+// useTRCs makes cfg verify the primary against TransferTRCs, if set. For a primary at the SCION address
+// addr, the AS certificate must be for its ISD-AS; addr is empty for primaries over IP, which must have
+// TransferTRCIA.
+func (z *Zone) useTRCs(cfg *tls.Config, addr string) error {
+	if z.TransferTRCs == nil {
+		return nil
+	}
+	ia := z.TransferTRCIA
+	if addr != "" {
+		var err error
+		if ia, err = pkgscion.AddrIA(addr); err != nil {
+			return err
+		}
+	}
+	return z.TransferTRCs.ConfigureClient(cfg, ia)
+}
+
+// lookupSCION returns the SCION address of the primary name, from the hosts plugin or the DNS. It returns
+// the empty string if the primary has none, or if ctx is done before the DNS answered.
+func (z *Zone) lookupSCION(ctx context.Context, name string) string {
+	if addr, err := z.LookupAddrInHosts(dns.Fqdn(name), pkgscion.TypeSCION); err == nil && addr != "" {
+		return addr
+	}
+	// LookupSCIONAddress can't be canceled, don't wait for it beyond ctx.
+	found := make(chan string, 1)
+	go func() {
+		addrs, err := resolvapi.LookupSCIONAddress(dns.Fqdn(name))
+		if err != nil || len(addrs) == 0 {
+			found <- ""
+			return
+		}
+		found <- addrs[0]
+	}()
+	select {
+	case addr := <-found:
+		return addr
+	case <-ctx.Done():
+		return ""
+	}
+}
+
+// dialPrimary dials p for a zone transfer.
+func (z *Zone) dialPrimary(p primary) (*dns.Conn, error) {
+	if p.net == transport.SQUIC {
+		conn, err := dialSCION(p.addr, p.tlsCfg)
+		if err != nil {
+			return nil, z.squicError(err, p.addr, true)
+		}
+		return conn, nil
+	}
+	return (&dns.Client{Net: p.net, TLSConfig: p.tlsCfg}).Dial(p.addr)
+}
@@ -0,0 +1,59 @@
+package file
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+)
+
+func TestRankPrimaries(t *testing.T) {
+	down := errors.New("down")
+	soas := []primarySOA{
+		{primary: "a", err: down},
+		{primary: "b", serial: 10},
+		{primary: "c", err: down},
+		{primary: "d", serial: 12},
+		{primary: "e", serial: 10},
+	}
+	got := rankPrimaries(soas)
+	if exp := []string{"d", "b", "e", "a", "c"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("Expected %v, got %v", exp, got)
+	}
+
+	// Serial arithmetic: 1 is newer than 4294967295.
+	soas = []primarySOA{{primary: "a", serial: 4294967295}, {primary: "b", serial: 1}}
+	got = rankPrimaries(soas)
+	if exp := []string{"b", "a"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("Expected %v, got %v", exp, got)
+	}
+}
+
+func TestTransferInHighestSerial(t *testing.T) {
+	old, recent := &soa{250}, &soa{300}
+	s1 := dnstest.NewServer(old.Handler)
+	defer s1.Close()
+	s2 := dnstest.NewServer(recent.Handler)
+	defer s2.Close()
+
+	z := new(Zone)
+	z.origin = testZone
+	// The first primary is unreachable, the second one is behind.
+	z.TransferFrom = []string{"127.0.0.1:1", s1.Addr, s2.Addr}
+
+	if err := z.TransferIn(); err != nil {
+		t.Fatalf("Unable to run TransferIn: %v", err)
+	}
+	if z.Apex.SOA.Serial != recent.serial {
+		t.Errorf("Expected the zone from the primary with serial %d, got %d", recent.serial, z.Apex.SOA.Serial)
+	}
+
+	should, err := z.shouldTransfer()
+	if err != nil {
+		t.Fatalf("Unable to run shouldTransfer: %v", err)
+	}
+	if should {
+		t.Errorf("Expected no transfer, the zone has the highest serial of the primaries")
+	}
+}
@@ -0,0 +1,85 @@
+package file
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Backoff determines how the retry interval of a secondary zone grows while none of its primaries can
+// be refreshed from.
+type Backoff int
+
+const (
+	// BackoffConstant retries every SOA retry interval.
+	BackoffConstant Backoff = iota
+	// BackoffExponential doubles the retry interval after every failed retry, up to MaxRetry, or the
+	// SOA expire interval without it.
+	BackoffExponential
+)
+
+// RefreshPolicy bounds the SOA timers of a secondary zone and randomizes its checks of the primaries.
+// Zero bounds mean the SOA timers are used as they are.
+type RefreshPolicy struct {
+	MinRefresh, MaxRefresh time.Duration
+	MinRetry, MaxRetry     time.Duration
+	Backoff                Backoff
+	// RefreshJitter and RetryJitter are the maximum random delays before the primaries are checked, so
+	// secondaries don't all hit them at once.
+	RefreshJitter, RetryJitter time.Duration
+}
+
+// DefaultRefreshPolicy uses the SOA timers with a jitter of 5s on refresh and 2s on retry.
+var DefaultRefreshPolicy = RefreshPolicy{RefreshJitter: 5 * time.Second, RetryJitter: 2 * time.Second}
+
+// refresh returns the refresh interval for soa.
+func (p RefreshPolicy) refresh(soa *dns.SOA) time.Duration {
+	return clamp(time.Second*time.Duration(soa.Refresh), p.MinRefresh, p.MaxRefresh)
+}
+
+// retry returns the interval before retry number attempt, counted from 0, for soa.
+func (p RefreshPolicy) retry(soa *dns.SOA, attempt int) time.Duration {
+	d := clamp(time.Second*time.Duration(soa.Retry), p.MinRetry, p.MaxRetry)
+	if p.Backoff != BackoffExponential || d <= 0 {
+		return d
+	}
+	max := p.MaxRetry
+	if max == 0 {
+		max = time.Second * time.Duration(soa.Expire)
+	}
+	for i := 0; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if max > 0 && d > max {
+		return max
+	}
+	return d
+}
+
+// clamp returns d within [min, max], a zero bound isn't applied.
+func clamp(d, min, max time.Duration) time.Duration {
+	if min > 0 && d < min {
+		d = min
+	}
+	if max > 0 && d > max {
+		d = max
+	}
+	return d
+}
+
+// sleepJitter sleeps for a random duration in [0, max). It returns false if ctx is done before.
+func sleepJitter(ctx context.Context, max time.Duration) bool {
+	if max <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(time.Duration(rand.Int63n(int64(max))))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,56 @@
+package file
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/test"
+)
+
+func TestRefreshPolicy(t *testing.T) {
+	soa := test.SOA(testZone + " IN SOA bla. bla. 1 3600 60 86400 0")
+
+	tests := []struct {
+		policy  RefreshPolicy
+		attempt int
+		refresh time.Duration
+		retry   time.Duration
+	}{
+		{DefaultRefreshPolicy, 3, time.Hour, time.Minute},
+		{RefreshPolicy{MinRefresh: 2 * time.Hour, MaxRetry: 30 * time.Second}, 0, 2 * time.Hour, 30 * time.Second},
+		{RefreshPolicy{MaxRefresh: 10 * time.Minute, MinRetry: 5 * time.Minute}, 0, 10 * time.Minute, 5 * time.Minute},
+		{RefreshPolicy{Backoff: BackoffExponential}, 0, time.Hour, time.Minute},
+		{RefreshPolicy{Backoff: BackoffExponential}, 3, time.Hour, 8 * time.Minute},
+		{RefreshPolicy{Backoff: BackoffExponential, MaxRetry: 5 * time.Minute}, 3, time.Hour, 5 * time.Minute},
+		// Without a maximum, the expire interval caps the backoff.
+		{RefreshPolicy{Backoff: BackoffExponential}, 20, time.Hour, 24 * time.Hour},
+	}
+	for i, tc := range tests {
+		if r := tc.policy.refresh(soa); r != tc.refresh {
+			t.Errorf("Test %d: expected refresh %s, got %s", i, tc.refresh, r)
+		}
+		if r := tc.policy.retry(soa, tc.attempt); r != tc.retry {
+			t.Errorf("Test %d: expected retry %s, got %s", i, tc.retry, r)
+		}
+	}
+}
+
+func TestUpdateStops(t *testing.T) {
+	z := NewZone(testZone, "stdin")
+	z.Apex.SOA = test.SOA(testZone + " IN SOA bla. bla. 1 3600 60 86400 0")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- z.Update(ctx) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Expected %s, got %v", context.Canceled, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Update to stop once its context is done")
+	}
+}
@@ -27,7 +27,7 @@ func (z *Zone) Reload(t *transfer.Transfer) error {
 				}
 
 				serial := z.SOASerialIfDefined()
-				zone, err := Parse(reader, z.origin, zFile, serial)
+				zone, err := ParseWithStore(reader, z.origin, zFile, serial, z.NewStore)
 				reader.Close()
 				if err != nil {
 					if _, ok := err.(*serialErr); !ok {
@@ -38,9 +38,11 @@ func (z *Zone) Reload(t *transfer.Transfer) error {
 
 				// copy elements we need
 				z.Lock()
+				old := z.Store
 				z.Apex = zone.Apex
-				z.Tree = zone.Tree
+				z.Store = zone.Store
 				z.Unlock()
+				retireStore(old)
 
 				log.Infof("Successfully reloaded zone %q in %q with %d SOA serial", z.origin, zFile, z.Apex.SOA.Serial)
 				if t != nil {
@@ -0,0 +1,209 @@
+package file
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"unicode"
+
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+
+	"github.com/miekg/dns"
+)
+
+// SCIONOriginDirective sets the ISD-AS of the SCION address records that follow it in a zone file and
+// give only the IP, as in
+//
+//	$SCION-ORIGIN 19-ffaa:1:1067
+//	ns1  IN  SCION  192.0.2.1
+//
+// It applies until the next $SCION-ORIGIN directive, but not to the files included with $INCLUDE.
+const SCIONOriginDirective = "$SCION-ORIGIN"
+
+// SCIONOriginReader reads a zone file and resolves the $SCION-ORIGIN directives, which the zone parser
+// doesn't know. It removes the directives and writes the ISD-AS into the relative SCION address
+// records, keeping the line numbers the same. Records in parentheses may span lines. An invalid
+// directive stops the reading, see Err.
+type SCIONOriginReader struct {
+	r        *bufio.Reader
+	fileName string
+	ia       string
+	line     int
+	buf      []byte
+	err      error
+
+	// The record that continues on the next line, if parens > 0.
+	parens int
+	state  recordState
+}
+
+// recordState is how far resolve got in a record.
+type recordState int
+
+const (
+	recordType  recordState = iota // before the type, after the owner, TTL and class
+	recordRdata                    // a SCION address record, before its address
+	recordDone                     // the rest of the record doesn't matter
+)
+
+// NewSCIONOriginReader returns a SCIONOriginReader reading the zone file fileName from r.
+func NewSCIONOriginReader(r io.Reader, fileName string) *SCIONOriginReader {
+	return &SCIONOriginReader{r: bufio.NewReader(r), fileName: fileName}
+}
+
+// Read implements io.Reader.
+func (s *SCIONOriginReader) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		line, err := s.r.ReadString('\n')
+		if line == "" {
+			return 0, err
+		}
+		s.line++
+		s.buf = []byte(s.resolve(line))
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+// Err returns the error in a $SCION-ORIGIN directive, if any. The zone parser only sees that the zone
+// file ended early.
+func (s *SCIONOriginReader) Err() error { return s.err }
+
+// resolve returns line with a $SCION-ORIGIN directive removed, or with the ISD-AS written into a
+// relative SCION address record.
+func (s *SCIONOriginReader) resolve(line string) string {
+	tokens := zoneTokens(line)
+	continued := s.parens > 0
+	if !continued && len(tokens) > 0 && tokens[0].start == 0 && strings.EqualFold(tokens[0].value, SCIONOriginDirective) {
+		if len(tokens) != 2 {
+			s.err = fmt.Errorf("%s: %s needs one ISD-AS at line %d", s.fileName, SCIONOriginDirective, s.line)
+			return ""
+		}
+		ia, err := pkgscion.ParseIA(tokens[1].value)
+		if err != nil {
+			s.err = fmt.Errorf("%s: invalid %s at line %d: %s", s.fileName, SCIONOriginDirective, s.line, err)
+			return ""
+		}
+		s.ia = ia.String()
+		if strings.HasSuffix(line, "\n") {
+			return "\n"
+		}
+		return ""
+	}
+	if !continued {
+		s.state = recordType
+	}
+
+	insert := -1
+	for _, t := range tokens {
+		switch t.value {
+		case "(":
+			s.parens++
+			continue
+		case ")":
+			if s.parens > 0 {
+				s.parens--
+			}
+			continue
+		}
+		switch s.state {
+		case recordType:
+			// The first token of a line that starts a record and doesn't start with a blank is the owner.
+			if t.start == 0 && !continued {
+				continue
+			}
+			if strings.EqualFold(t.value, "SCION") {
+				s.state = recordRdata
+			} else if !isTTLOrClass(t.value) {
+				// The record is of another type.
+				s.state = recordDone
+			}
+		case recordRdata:
+			if isRelativeSCION(t.value) {
+				insert = t.start
+			}
+			s.state = recordDone
+		}
+	}
+	if insert < 0 || s.ia == "" {
+		return line
+	}
+	return line[:insert] + s.ia + "," + line[insert:]
+}
+
+// isTTLOrClass returns true if s is the TTL or class of a record, which may come before its type.
+func isTTLOrClass(s string) bool {
+	if s[0] >= '0' && s[0] <= '9' {
+		return true
+	}
+	_, ok := dns.StringToClass[strings.ToUpper(s)]
+	return ok || strings.HasPrefix(strings.ToUpper(s), "CLASS")
+}
+
+// isRelativeSCION returns true if s is the address of a SCION address record without the ISD-AS.
+func isRelativeSCION(s string) bool {
+	if strings.Contains(s, ",") {
+		return false
+	}
+	return net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")) != nil
+}
+
+type zoneToken struct {
+	value string
+	start int
+}
+
+// zoneTokens splits a line of a zone file into its blank separated tokens, up to a comment. Parentheses
+// are tokens of their own. Quoted strings are skipped, as they never hold the type or address of a SCION
+// address record.
+func zoneTokens(line string) []zoneToken {
+	var tokens []zoneToken
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			tokens = append(tokens, zoneToken{line[start:end], start})
+			start = -1
+		}
+	}
+	quoted, escaped := false, false
+	for i, c := range line {
+		if escaped {
+			escaped = false
+			continue
+		}
+		if c == '\\' {
+			escaped = true
+			if !quoted && start < 0 {
+				start = i
+			}
+			continue
+		}
+		if quoted {
+			quoted = c != '"'
+			continue
+		}
+		switch {
+		case c == ';':
+			flush(i)
+			return tokens
+		case c == '"':
+			flush(i)
+			quoted = true
+		case c == '(' || c == ')':
+			flush(i)
+			tokens = append(tokens, zoneToken{string(c), i})
+		case unicode.IsSpace(c):
+			flush(i)
+		case start < 0:
+			start = i
+		}
+	}
+	flush(len(line))
+	return tokens
+}
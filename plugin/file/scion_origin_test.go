@@ -0,0 +1,95 @@
+package file
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+)
+
+func TestSCIONOriginReader(t *testing.T) {
+	tests := []struct {
+		in        string
+		expected  string
+		shouldErr bool
+	}{
+		{"ns1 IN SCION 192.0.2.1\n", "ns1 IN SCION 192.0.2.1\n", false},
+		{"$SCION-ORIGIN 19-ffaa:1:1067\nns1 IN SCION 192.0.2.1\n", "\nns1 IN SCION 19-ffaa:1:1067,192.0.2.1\n", false},
+		{"$scion-origin 19-ffaa:1:1067 ; comment\nns1 3600 IN SCION [2001:db8::1]", "\nns1 3600 IN SCION 19-ffaa:1:1067,[2001:db8::1]", false},
+		{"$SCION-ORIGIN 19-ffaa:1:1067\n    SCION 192.0.2.1 ; ns1\n", "\n    SCION 19-ffaa:1:1067,192.0.2.1 ; ns1\n", false},
+		{"$SCION-ORIGIN 19-ffaa:1:1067\nns1 IN SCION ( 192.0.2.1 )\n", "\nns1 IN SCION ( 19-ffaa:1:1067,192.0.2.1 )\n", false},
+		{"$SCION-ORIGIN 19-ffaa:1:1067\nns1 IN SCION (192.0.2.1)\n", "\nns1 IN SCION (19-ffaa:1:1067,192.0.2.1)\n", false},
+		{"$SCION-ORIGIN 19-ffaa:1:1067\nns1 IN SCION (\n    192.0.2.1 ; ns1\n)\nns2 IN SCION 192.0.2.2\n", "\nns1 IN SCION (\n    19-ffaa:1:1067,192.0.2.1 ; ns1\n)\nns2 IN SCION 19-ffaa:1:1067,192.0.2.2\n", false},
+		{"$SCION-ORIGIN 19-ffaa:1:1067\nns1 ( IN\nSCION\n192.0.2.1 )\n", "\nns1 ( IN\nSCION\n19-ffaa:1:1067,192.0.2.1 )\n", false},
+		{"$SCION-ORIGIN 19-ffaa:1:1067\n@ IN SOA ns1 hostmaster (\n    1 7200 1800\n    SCION 192.0.2.1 )\n", "\n@ IN SOA ns1 hostmaster (\n    1 7200 1800\n    SCION 192.0.2.1 )\n", false},
+		{"$SCION-ORIGIN 19-ffaa:1:1067\nns1 IN SCION 19-ffaa:1:1068,192.0.2.1\n", "\nns1 IN SCION 19-ffaa:1:1068,192.0.2.1\n", false},
+		{"$SCION-ORIGIN 19-ffaa:1:1067\nscion IN A 192.0.2.1\n", "\nscion IN A 192.0.2.1\n", false},
+		{"$SCION-ORIGIN 19-ffaa:1:1067\nns1 IN TXT \"SCION 192.0.2.1\"\n", "\nns1 IN TXT \"SCION 192.0.2.1\"\n", false},
+		{"$SCION-ORIGIN 19-ffaa:1:1067\nns1 IN TXT SCION 192.0.2.1\n", "\nns1 IN TXT SCION 192.0.2.1\n", false},
+		{"$SCION-ORIGIN 19-ffaa:1:1067\na SCION 192.0.2.1\n$SCION-ORIGIN 1-64512\nb SCION 192.0.2.2\n", "\na SCION 19-ffaa:1:1067,192.0.2.1\n\nb SCION 1-64512,192.0.2.2\n", false},
+		{"$SCION-ORIGIN\n", "", true},
+		{"$SCION-ORIGIN 19-ffaa:1:1067 1-64512\n", "", true},
+		{"$SCION-ORIGIN ffaa:1:1067\n", "", true},
+	}
+	for i, tc := range tests {
+		sr := NewSCIONOriginReader(strings.NewReader(tc.in), "stdin")
+		out, err := io.ReadAll(sr)
+		if tc.shouldErr {
+			if err == nil || sr.Err() == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if string(out) != tc.expected {
+			t.Errorf("Test %d: expected %q, got %q", i, tc.expected, string(out))
+		}
+	}
+}
+
+func TestParseSCIONOrigin(t *testing.T) {
+	z, err := Parse(strings.NewReader(dbSCIONOrigin), "example.org.", "stdin", 0)
+	if err != nil {
+		t.Fatalf("Expected no error when reading zone, got %q", err)
+	}
+	for name, expected := range map[string]string{
+		"ns1.example.org.": "19-ffaa:1:1067,[192.0.2.1]",
+		"ns2.example.org.": "19-ffaa:1:1067,[2001:db8::2]",
+		"ns3.example.org.": "19-ffaa:1:1068,[192.0.2.3]",
+		"ns4.example.org.": "19-ffaa:1:1067,[192.0.2.4]",
+	} {
+		elem, found := z.Store.Search(name)
+		if !found {
+			t.Fatalf("Expected %s in the zone", name)
+		}
+		scions := elem.Type(pkgscion.TypeSCION)
+		if len(scions) != 1 {
+			t.Fatalf("Expected a SCION record for %s, got %v", name, scions)
+		}
+		if s, _ := pkgscion.SCIONOf(scions[0]); s.String() != expected {
+			t.Errorf("Expected %s for %s, got %s", expected, name, s)
+		}
+	}
+
+	_, err = Parse(strings.NewReader("$SCION-ORIGIN 19\n"+dbSCIONOrigin), "example.org.", "stdin", 0)
+	if err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected an error for the invalid $SCION-ORIGIN, got %v", err)
+	}
+}
+
+const dbSCIONOrigin = `
+$TTL         1M
+$ORIGIN      example.org.
+$SCION-ORIGIN 19-ffaa:1:1067
+
+@            IN  SOA    ns1 hostmaster 1 7200 1800 86400 100
+             IN  NS     ns1
+ns1          IN  SCION  192.0.2.1
+ns2          IN  SCION  [2001:db8::2]
+ns3          IN  SCION  19-ffaa:1:1068,192.0.2.3
+ns4          IN  SCION  (
+                 192.0.2.4 )
+`
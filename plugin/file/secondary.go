@@ -4,185 +4,122 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
-	"math/rand"
-	"net"
-	"net/url"
-	"strconv"
+	"fmt"
 	"time"
 
-	util "github.com/miekg/dns/dnsutil"
-
-	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/pkg/doqclient"
+	"github.com/coredns/coredns/plugin/pkg/rcode"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/plugin/pkg/transport"
 	"github.com/miekg/dns"
-	"github.com/miekg/dns/resolvapi"
 	"github.com/netsec-ethz/scion-apps/pkg/pan"
-	"inet.af/netaddr"
 )
 
-// TransferIn retrieves the zone from the masters, parses it and sets it live.
+// TransferIn retrieves the zone from the masters, parses it and sets it live. Once the zone is loaded,
+// primaries are only used within their transfer windows, and only the changes since its serial are
+// transferred with IXFR. With several primaries, the one with the highest serial is tried first.
 func (z *Zone) TransferIn() error {
 	if len(z.TransferFrom) == 0 {
 		return nil
 	}
-	m := new(dns.Msg)
-	m.SetAxfr(z.origin)
 
-	z1 := z.CopyWithoutApex()
 	var (
+		z1  *Zone
 		Err error
 		tr  string
 	)
+	loaded := z.Apex.SOA != nil
+	now := time.Now()
 
 Transfer:
-	for _, tr = range z.TransferFrom {
-		// tr can be either IPv4/6 , SCION Address or domain-name or url i.e. squic://ns1.exmple.org:8853
-
-		t := new(dns.Transfer)
-		var client *dns.Client
-		var netw string = "udp"
-		var tlsCfg *tls.Config
-
-		if _, ok := dns.IsDomainName(tr); ok {
-			// we only have the domain-name of our primary NS
-			// so we need to resolve it first
-			var addressForHost string
-
-			// check first if we find a SCION address for it
-			addr, eee := z.LookupAddrInHosts(dns.Fqdn(tr), dns.TypeTXT)
-			if eee == nil && addr != "" {
-				// found scion address for host in hostsfile
-				addressForHost = addr
-
-			} else {
-				// didnt find it in hostsfile, so resolve it
-				scaddrs, err := resolvapi.LookupSCIONAddress(dns.Fqdn(tr))
-				if err != nil {
-					return err
-				}
-				if len(scaddrs) > 0 {
-					addressForHost = scaddrs[0]
-				}
-			}
-
-			if addressForHost != "" {
-				netw = "squic"
-				tlsCfg = z.Config.TLSConfigQUIC.Clone()
-				tlsCfg.ServerName = dns.Fqdn(tr)
-				// use the default SCION DoQ Port if none was given
-				tr = util.WithPortIfNotSet(addressForHost, 8853)
-
-				goto dialPrimary
-			} else {
-				// fallback to IPv4/6 addresses
-				addr, eee := z.LookupAddrInHosts(dns.Fqdn(tr), dns.TypeA)
-				if eee == nil && addr != "" {
-					// found scion address for host in hostsfile
-					addressForHost = addr
-					tr = addressForHost
-				}
-				goto dialPrimary
-				// the dns.Client relies on the net.Dialer, which knows how to resolve domain-names to IP addresses
+	for _, tr = range z.transferOrder(loaded, now) {
+		if loaded && !z.inTransferWindow(tr, now) {
+			log.Debugf("Skipping transfer of `%s' from %q: outside of its transfer window", z.origin, tr)
+			if Err == nil {
+				Err = errOutsideWindow
 			}
+			continue
 		}
 
-		if u, e := url.Parse(tr); e == nil {
-
-			netw = u.Scheme
-			host, p, e := net.SplitHostPort(u.Host)
-			if e != nil {
-				return e
-			}
-			var addressForHost string
-
-			addr, eee := z.LookupAddrInHosts(host, dns.TypeTXT)
-			if eee == nil && addr != "" {
-				// found address for host in hostsfile
-				addressForHost = addr
-			} else {
-
-				// if 'host' has only IPv4/6 addresses, they are resolved by net.Dialer )DialContext
-				// in Client.Dial automatically
-				// only for SCION addresses the host might potentially have we need to do this ourselves
-				scaddrs, err := resolvapi.LookupSCIONAddress(host)
-				if err != nil {
-					// resolution failed, probably because scion capable sdns resolver is not running locally
-					if netw == "squic" {
-						// we wanted to dial primary with SCION, but didnt get its SCION address, how sad
-						return err
-					}
-					goto dialPrimary // fallback to old legacy way of things
-				}
-				if len(scaddrs) == 0 {
-					if netw == "squic" {
-						return errors.New("schema is squic:// but no SCION Address could be resolved for host")
-					}
-					goto dialPrimary
-				}
-				addressForHost = scaddrs[0]
-			}
-
-			tlsCfg = z.Config.TLSConfigQUIC.Clone()
-			tlsCfg.ServerName = host
-
-			if p != "" {
-				port, eee := strconv.Atoi(p)
-				if eee != nil {
-					return eee
-				}
-
-				tr = util.WithPortIfNotSet(addressForHost, port)
-
-			} else {
-				// use the default SCION DoQ Port if none was given
-				tr = util.WithPortIfNotSet(addressForHost, 8853)
-
-			}
-			goto dialPrimary
+		pr, err := z.resolvePrimary(tr)
+		if err != nil {
+			log.Errorf("Failed to resolve primary %q of `%s': %v", tr, z.origin, err)
+			Err = err
+			continue
 		}
+		squic := pr.net == transport.SQUIC
 
-		if _, er := pan.ParseUDPAddr(tr); er == nil {
-			netw = "squic"
-			tlsCfg = z.Config.TLSConfigQUIC.Clone()
-			//tlsCfg.ServerName = "localhost"
-			// Check if we find our primary Server in hosts file
-			// otherwise the Client does the lookup in DialContext()
-			if result, err := z.LookupInHosts(tr); err == nil {
-				tlsCfg.ServerName = result
-			} else { // (be a good proggy,do not rely on knowledge about dns.Client impl and lookup the servername ourselves)
-				if hostname, err := resolvapi.LookupUDPAddr(context.TODO(), tr); err == nil {
-					tlsCfg.ServerName = hostname[0]
-				} else {
-					// there would be no point in dialing the primary,
-					// as without its serverName in the tlsCfg the handshake would fail anyway
-					return err
-				}
-			}
+		// Every primary starts from an empty store, so records of a failed attempt don't end up
+		// in the zone.
+		if z1 != nil {
+			closeStore(z1.Store)
 		}
-
-		if _, er := netaddr.ParseIPPort(tr); er == nil {
-			// tr is an ordenary IPv4/6 address, so nothing to do
-			goto dialPrimary
+		z1 = z.CopyWithoutApex()
+		if z1.Store, err = z.newStore(); err != nil {
+			return err
 		}
 
+		m := z.transferQuery()
+		t := new(dns.Transfer)
+
 	dialPrimary:
-		client = &dns.Client{Net: netw, TLSConfig: tlsCfg}
-		var e error
-		t.Conn, e = client.Dial(tr)
-		if e != nil {
-			return e
+		t.Conn, err = z.dialPrimary(pr)
+		if err != nil {
+			log.Errorf("Failed to dial primary %q of `%s': %v", tr, z.origin, err)
+			Err = err
+			continue Transfer
+		}
+		// DoQ requires a message ID of 0, RFC 9250 section 4.2.1.
+		if squic {
+			m.Id = 0
+		} else {
+			m.Id = dns.Id()
+		}
+		var tp *tsigProvider
+		if z.TransferKey != nil {
+			tp = z.TransferKey.provider()
+			t.TsigProvider = tp
+			z.TransferKey.sign(m)
+		}
+		c, err := t.In(m, pr.addr)
+		if err != nil && squic {
+			err = z.squicError(err, pr.addr, false)
 		}
-		c, err := t.In(m, tr)
 		if err != nil {
 			log.Errorf("Failed to setup transfer `%s' with `%q': %v", z.origin, tr, err)
 			Err = err
 			continue Transfer
 		}
+		var p *pacer
+		if squic && z.TransferRate > 0 {
+			p = newPacer(z.TransferRate)
+		}
+		// The answer to an IXFR is only applied once it is complete.
+		ixfr := m.Question[0].Qtype == dns.TypeIXFR
+		var rrs []dns.RR
 		for env := range c {
 			if env.Error != nil {
+				if squic {
+					env.Error = z.squicError(env.Error, pr.addr, false)
+				}
+				if ixfr && len(rrs) == 0 {
+					// The primary may not transfer incrementally, ask it for the full zone.
+					log.Infof("Failed incremental transfer of `%s' from %q, trying a full transfer: %v", z.origin, tr, env.Error)
+					m, t = new(dns.Msg), new(dns.Transfer)
+					m.SetAxfr(z.origin)
+					goto dialPrimary
+				}
 				log.Errorf("Failed to transfer `%s' from %q: %v", z.origin, tr, env.Error)
 				Err = env.Error
 				continue Transfer
 			}
+			if p != nil {
+				p.wait((&dns.Msg{Answer: env.RR}).Len())
+			}
+			if ixfr {
+				rrs = append(rrs, env.RR...)
+				continue
+			}
 			for _, rr := range env.RR {
 				if err := z1.Insert(rr); err != nil {
 					log.Errorf("Failed to parse transfer `%s' from: %q: %v", z.origin, tr, err)
@@ -191,71 +128,169 @@ Transfer:
 				}
 			}
 		}
+		if tp != nil && !tp.verified {
+			log.Errorf("Failed to transfer `%s' from %q: %v", z.origin, tr, errUnsigned)
+			Err = errUnsigned
+			continue Transfer
+		}
+		if ixfr {
+			if err := z.applyIXFR(z1, rrs); err != nil {
+				log.Errorf("Failed to apply transfer `%s' from %q: %v", z.origin, tr, err)
+				Err = err
+				continue Transfer
+			}
+		}
 		Err = nil
 		break
 	}
 	if Err != nil {
+		if z1 != nil {
+			closeStore(z1.Store)
+		}
 		return Err
 	}
 
 	z.Lock()
-	z.Tree = z1.Tree
+	old := z.Store
+	z.Store = z1.Store
 	z.Apex = z1.Apex
-	z.Expired = false
 	z.Unlock()
+	retireStore(old)
+	z.setExpired(false)
 	log.Infof("Transferred: %s from %s", z.origin, tr)
 	return nil
 }
 
 // shouldTransfer checks the primaries of zone, retrieves the SOA record, checks the current serial
 // and the remote serial and will return true if the remote one is higher than the locally configured one.
+// The primaries are queried in parallel, see querySOAs.
 func (z *Zone) shouldTransfer() (bool, error) {
-	var c *dns.Client
-
-	m := new(dns.Msg)
-	m.SetQuestion(z.origin, dns.TypeSOA)
-
 	var Err error
 	serial := -1
-
-Transfer:
-	for _, tr := range z.TransferFrom {
-		Err = nil
-
-		if dnsutil.IsSCIONAddress(tr) {
-			tlsCfg := z.Config.TLSConfigQUIC.Clone()
-
-			// Check if we find our primary Server in hosts file
-			if result, err := z.LookupInHosts(tr); err == nil {
-				tlsCfg.ServerName = result
-			}
-
-			// otherwise the Client does the lookup in DialContext()
-			c = &dns.Client{Net: "squic", TLSConfig: tlsCfg}
-		} else {
-			c = new(dns.Client)
-			c.Net = "tcp" // do this query over TCP to minimize spoofing
-		}
-
-		ret, _, err := c.Exchange(m, tr)
-		if err != nil || ret.Rcode != dns.RcodeSuccess {
-			Err = err
+	for _, p := range z.querySOAs(z.TransferFrom) {
+		if p.err != nil {
+			Err = p.err
 			continue
 		}
-		for _, a := range ret.Answer {
-			if a.Header().Rrtype == dns.TypeSOA {
-				serial = int(a.(*dns.SOA).Serial)
-				break Transfer
-			}
+		if serial == -1 || less(uint32(serial), p.serial) {
+			serial = int(p.serial)
 		}
 	}
 	if serial == -1 {
 		return false, Err
 	}
 	if z.Apex.SOA == nil {
-		return true, Err
+		return true, nil
+	}
+	return less(z.Apex.SOA.Serial, uint32(serial)), nil
+}
+
+// querySOA retrieves the serial of the SOA record of zone from the primary tr.
+func (z *Zone) querySOA(tr string) (uint32, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(z.origin, dns.TypeSOA)
+
+	p, err := z.resolvePrimary(tr)
+	if err != nil {
+		return 0, err
+	}
+	var ret *dns.Msg
+	if p.net == transport.SQUIC {
+		ret, err = exchangeSCION(m, p.addr, p.tlsCfg, z.TransferKey)
+		if err != nil {
+			err = z.squicError(err, p.addr, false)
+		}
+	} else {
+		// Over TCP, or TLS, to minimize spoofing.
+		c := &dns.Client{Net: p.net, TLSConfig: p.tlsCfg}
+		m.Id = dns.Id()
+		if z.TransferKey != nil {
+			c.TsigSecret = z.TransferKey.secrets()
+			z.TransferKey.sign(m)
+		}
+		ret, _, err = c.Exchange(m, p.addr)
+		if err == nil && z.TransferKey != nil && ret.IsTsig() == nil {
+			// The client only verifies answers that are signed.
+			err = errUnsigned
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	if ret.Rcode != dns.RcodeSuccess {
+		return 0, fmt.Errorf("SOA query for `%s' to %q failed: rcode was %q", z.origin, tr, rcode.ToString(ret.Rcode))
 	}
-	return less(z.Apex.SOA.Serial, uint32(serial)), Err
+	for _, a := range ret.Answer {
+		if soa, ok := a.(*dns.SOA); ok {
+			return soa.Serial, nil
+		}
+	}
+	return 0, fmt.Errorf("no SOA record for `%s' from %q", z.origin, tr)
+}
+
+// squicError classifies err from the SCION primary tr and counts it per kind.
+func (z *Zone) squicError(err error, tr string, dialing bool) error {
+	err = doqclient.Classify(err, transport.SQUIC, tr, dialing)
+	SQUICErrorCount.WithLabelValues(z.origin, doqclient.KindOf(err)).Inc()
+	return err
+}
+
+// transferTLSConfig returns the TLS config to dial the primaries over DoQ with. The client certificate and
+// the CAs that verify the primaries are those of TransferTLS, if it is set, and of the server otherwise.
+func (z *Zone) transferTLSConfig() *tls.Config {
+	cfg := new(tls.Config)
+	if z.Config != nil && z.Config.TLSConfigQUIC != nil {
+		cfg = z.Config.TLSConfigQUIC.Clone()
+	}
+	if z.TransferTLS != nil {
+		cfg.Certificates = z.TransferTLS.Certificates
+		cfg.RootCAs = z.TransferTLS.RootCAs
+	}
+	return cfg
+}
+
+// scionDialTimeout bounds dialing a primary over SCION, including the path lookup.
+const scionDialTimeout = 10 * time.Second
+
+// dialSCION dials the primary at the SCION address addr, only using the paths allowed by the SCION path policy.
+func dialSCION(addr string, tlsCfg *tls.Config) (*dns.Conn, error) {
+	policy, err := pkgscion.Get().PathPolicy()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), scionDialTimeout)
+	defer cancel()
+	session, err := doqclient.DialSCION(ctx, addr, policy, tlsCfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The connection of the transfer needs the SCION connection below the QUIC session as well.
+	s, ok := session.(*pan.QUICEarlySession)
+	if !ok {
+		session.CloseWithError(transport.DoQInternalError, "")
+		return nil, fmt.Errorf("unexpected QUIC session %T to %q", session, addr)
+	}
+	return &dns.Conn{QuicEarlySession: s}, nil
+}
+
+// exchangeSCION sends m to the primary at the SCION address addr over DoQ, see dialSCION. With key, m is
+// signed and the answer must be signed as well.
+func exchangeSCION(m *dns.Msg, addr string, tlsCfg *tls.Config, key *TransferKey) (*dns.Msg, error) {
+	policy, err := pkgscion.Get().PathPolicy()
+	if err != nil {
+		return nil, err
+	}
+	c := doqclient.New(transport.SQUIC, tlsCfg)
+	c.Policy = policy
+	if key != nil {
+		// DoQ requires a message ID of 0, RFC 9250 section 4.2.1, which the signature covers.
+		m.Id = 0
+		c.TsigSecret = key.secrets()
+		key.sign(m)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), scionDialTimeout)
+	defer cancel()
+	return c.Exchange(ctx, m, addr)
 }
 
 // less returns true of a is smaller than b when taking RFC 1982 serial arithmetic into account.
@@ -266,64 +301,121 @@ func less(a, b uint32) bool {
 	return (a - b) > MaxSerialIncrement
 }
 
-// Update updates the secondary zone according to its SOA. It will run for the life time of the server
-// and uses the SOA parameters. Every refresh it will check for a new SOA number. If that fails (for all
-// server) it will retry every retry interval. If the zone failed to transfer before the expire, the zone
-// will be marked expired.
-func (z *Zone) Update() error {
+// Update updates the secondary zone according to its SOA. It will run until ctx is done, usually for the
+// life time of the server, and uses the SOA parameters within the bounds of the RefreshPolicy. Every refresh
+// it will check for a new SOA number. If that fails (for all server) it will retry every retry interval,
+// backing off if the policy says so. If the zone failed to transfer before the expire, the zone will be
+// marked expired. A notify from a primary triggers the check at once, at most once per notifyInterval.
+func (z *Zone) Update(ctx context.Context) error {
 	// If we don't have a SOA, we don't have a zone, wait for it to appear.
 	for z.Apex.SOA == nil {
-		time.Sleep(1 * time.Second)
+		select {
+		case <-time.After(1 * time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
+	p := z.RefreshPolicy
 	retryActive := false
+	retries := 0
+	var lastNotify time.Time
 
 Restart:
-	refresh := time.Second * time.Duration(z.Apex.SOA.Refresh)
-	retry := time.Second * time.Duration(z.Apex.SOA.Retry)
+	z.resolvePrimaryHosts(ctx)
+	refresh := p.refresh(z.Apex.SOA)
 	expire := time.Second * time.Duration(z.Apex.SOA.Expire)
 
 	refreshTicker := time.NewTicker(refresh)
-	retryTicker := time.NewTicker(retry)
+	retryTimer := time.NewTimer(p.retry(z.Apex.SOA, 0))
 	expireTicker := time.NewTicker(expire)
+	stop := func() {
+		refreshTicker.Stop()
+		retryTimer.Stop()
+		expireTicker.Stop()
+	}
 
 	for {
 		select {
+		case <-ctx.Done():
+			stop()
+			return ctx.Err()
+
 		case <-expireTicker.C:
 			if !retryActive {
 				break
 			}
-			z.Expired = true
+			z.setExpired(true)
 
-		case <-retryTicker.C:
+		case <-retryTimer.C:
 			if !retryActive {
+				retryTimer.Reset(p.retry(z.Apex.SOA, 0))
 				break
 			}
 
-			time.Sleep(jitter(2000)) // 2s randomize
+			if !sleepJitter(ctx, p.RetryJitter) {
+				stop()
+				return ctx.Err()
+			}
 
 			ok, err := z.shouldTransfer()
 			if err != nil {
 				log.Warningf("Failed retry check %s", err)
+				retries++
+				retryTimer.Reset(p.retry(z.Apex.SOA, retries))
 				continue
 			}
 
 			if ok {
-				if err := z.TransferIn(); err != nil {
+				if err := z.TransferIn(); err != nil && !z.transferDeferred(err) {
 					// transfer failed, leave retryActive true
+					retries++
+					retryTimer.Reset(p.retry(z.Apex.SOA, retries))
 					break
 				}
 			}
 
 			// no errors, stop timers and restart
-			retryActive = false
-			refreshTicker.Stop()
-			retryTicker.Stop()
-			expireTicker.Stop()
+			retryActive, retries = false, 0
+			stop()
 			goto Restart
 
-		case <-refreshTicker.C:
+		case <-z.notified:
+			if wait := notifyInterval - time.Since(lastNotify); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					stop()
+					return ctx.Err()
+				}
+			}
+			lastNotify = time.Now()
 
-			time.Sleep(jitter(5000)) // 5s randomize
+			ok, err := z.shouldTransfer()
+			if err != nil {
+				log.Warningf("Failed notify check %s", err)
+				continue
+			}
+
+			if ok {
+				if err := z.TransferIn(); err != nil && !z.transferDeferred(err) {
+					// transfer failed
+					retryActive = true
+					break
+				}
+			} else {
+				log.Infof("Notify for %s: no SOA serial increase seen", z.origin)
+			}
+
+			// no errors, stop timers and restart
+			retryActive, retries = false, 0
+			stop()
+			goto Restart
+
+		case <-refreshTicker.C:
+			if !sleepJitter(ctx, p.RefreshJitter) {
+				stop()
+				return ctx.Err()
+			}
 
 			ok, err := z.shouldTransfer()
 			if err != nil {
@@ -333,7 +425,7 @@ Restart:
 			}
 
 			if ok {
-				if err := z.TransferIn(); err != nil {
+				if err := z.TransferIn(); err != nil && !z.transferDeferred(err) {
 					// transfer failed
 					retryActive = true
 					break
@@ -341,19 +433,22 @@ Restart:
 			}
 
 			// no errors, stop timers and restart
-			retryActive = false
-			refreshTicker.Stop()
-			retryTicker.Stop()
-			expireTicker.Stop()
+			retryActive, retries = false, 0
+			stop()
 			goto Restart
 		}
 	}
 }
 
-// jitter returns a random duration between [0,n) * time.Millisecond
-func jitter(n int) time.Duration {
-	r := rand.Intn(n)
-	return time.Duration(r) * time.Millisecond
+// transferDeferred returns true if err is TransferIn waiting for the transfer windows of the primaries.
+// They answered the SOA query, so the zone doesn't expire in the meantime, and the transfer is done on
+// the first refresh within a window.
+func (z *Zone) transferDeferred(err error) bool {
+	if !errors.Is(err, errOutsideWindow) {
+		return false
+	}
+	log.Infof("Deferring transfer of `%s': %s", z.origin, err)
+	return true
 }
 
 // MaxSerialIncrement is the maximum difference between two serial numbers. If the difference between
@@ -1,8 +1,11 @@
 package file
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
 	"github.com/coredns/coredns/plugin/test"
@@ -52,6 +55,8 @@ func (s *soa) Handler(w dns.ResponseWriter, req *dns.Msg) {
 		m.Answer[2] = test.A(fmt.Sprintf("%s IN A 127.0.0.1", testZone))
 		m.Answer[3] = test.SOA(fmt.Sprintf("%s IN SOA bla. bla. %d 0 0 0 0 ", testZone, s.serial))
 		w.WriteMsg(m)
+		// A transfer is read until the primary closes the connection.
+		w.Close()
 	}
 }
 
@@ -121,6 +126,66 @@ func TestTransferIn(t *testing.T) {
 	}
 }
 
+func TestTransferInAbortedPrimary(t *testing.T) {
+	// The first primary breaks off the transfer after sending a record.
+	s1 := dnstest.NewServer(func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(req)
+		m.Answer = []dns.RR{
+			test.SOA(fmt.Sprintf("%s IN SOA bla. bla. 300 0 0 0 0", testZone)),
+			test.A("aborted." + testZone + " IN A 127.0.0.1"),
+		}
+		w.WriteMsg(m)
+		w.Close()
+	})
+	defer s1.Close()
+	soa := soa{250}
+	s2 := dnstest.NewServer(soa.Handler)
+	defer s2.Close()
+
+	z := new(Zone)
+	z.origin = testZone
+	z.TransferFrom = []string{s1.Addr, s2.Addr}
+
+	if err := z.TransferIn(); err != nil {
+		t.Fatalf("Unable to run TransferIn: %v", err)
+	}
+	if z.Apex.SOA.Serial != soa.serial {
+		t.Errorf("Expected serial %d, got %d", soa.serial, z.Apex.SOA.Serial)
+	}
+	if _, ok := z.Store.Search("aborted." + testZone); ok {
+		t.Errorf("Expected no records from the aborted transfer")
+	}
+}
+
+func TestUpdateOutsideWindow(t *testing.T) {
+	soa := soa{250}
+	s := dnstest.NewServer(soa.Handler)
+	defer s.Close()
+
+	z := NewZone(testZone, "stdin")
+	z.TransferFrom = []string{s.Addr}
+	// The primary has a newer serial, but the window only opens in two hours.
+	now := time.Now()
+	start := time.Duration((now.Hour()+2)%24) * time.Hour
+	z.TransferWindows = map[string][]TransferWindow{s.Addr: {{Start: start, End: start + time.Hour}}}
+	z.Apex.SOA = test.SOA(fmt.Sprintf("%s IN SOA bla. bla. %d 1 1 2 0", testZone, soa.serial-1))
+
+	if err := z.TransferIn(); !errors.Is(err, errOutsideWindow) {
+		t.Fatalf("Expected the transfer to wait for the window, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3500*time.Millisecond)
+	defer cancel()
+	z.Update(ctx)
+	if z.Expired {
+		t.Error("Expected the zone not to expire while the primary answers")
+	}
+	if z.Apex.SOA.Serial != soa.serial-1 {
+		t.Errorf("Expected no transfer outside of the window, got serial %d", z.Apex.SOA.Serial)
+	}
+}
+
 func TestIsNotify(t *testing.T) {
 	z := new(Zone)
 	z.origin = testZone
@@ -136,6 +201,36 @@ func TestIsNotify(t *testing.T) {
 	if z.isNotify(state) {
 		t.Fatal("Should have been invalid notify")
 	}
+
+	// A primary given by name is resolved.
+	state.W = &test.ResponseWriter{RemoteIP: "127.0.0.1"}
+	z.TransferFrom = []string{"localhost"}
+	if z.isNotify(state) {
+		t.Error("Should have been invalid notify from primary localhost before it is resolved")
+	}
+	for _, tr := range []string{"localhost", "localhost.", "tls://localhost:853"} {
+		z.TransferFrom = []string{tr}
+		z.resolvePrimaryHosts(context.Background())
+		if !z.isNotify(state) {
+			t.Errorf("Should have been valid notify from primary %s", tr)
+		}
+	}
+	z.TransferFrom = []string{"ns1.example.invalid."}
+	z.resolvePrimaryHosts(context.Background())
+	if z.isNotify(state) {
+		t.Error("Should have been invalid notify from primary ns1.example.invalid.")
+	}
+
+	// A notify over SCION is matched against the SCION address of the primary.
+	state.W = &test.ResponseWriter{RemoteSCION: "19-ffaa:1:1067,[10.0.0.1]:40000"}
+	z.TransferFrom = []string{"squic://19-ffaa:1:1067,[10.0.0.1]:853"}
+	if !z.isNotify(state) {
+		t.Error("Should have been valid notify from SCION primary 19-ffaa:1:1067,[10.0.0.1]")
+	}
+	z.TransferFrom = []string{"10.0.0.1:53"}
+	if z.isNotify(state) {
+		t.Error("Should have been invalid notify over SCION from primary 10.0.0.1")
+	}
 }
 
 func newRequest(zone string, qtype uint16) request.Request {
@@ -144,3 +239,18 @@ func newRequest(zone string, qtype uint16) request.Request {
 	m.SetEdns0(4097, true)
 	return request.Request{W: &test.ResponseWriter{}, Req: m}
 }
+
+func TestNotifyCoalesced(t *testing.T) {
+	z := NewZone(testZone, "stdin")
+	if !z.notify() {
+		t.Fatal("Expected the first notify to trigger a check")
+	}
+	// Update hasn't picked up the first notify yet.
+	if z.notify() {
+		t.Error("Expected the second notify to be coalesced")
+	}
+	<-z.notified
+	if !z.notify() {
+		t.Error("Expected a notify after the check to trigger another one")
+	}
+}
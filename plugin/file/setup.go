@@ -4,6 +4,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/coredns/caddy"
@@ -75,6 +76,7 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 
 	var openErr error
 	reload := 1 * time.Minute
+	var newStore StoreFunc
 
 	for c.Next() {
 		// file db.file [zones...]
@@ -88,6 +90,33 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 			fileName = filepath.Join(config.Root, fileName)
 		}
 
+		for c.NextBlock() {
+			switch c.Val() {
+			case "reload":
+				t := c.RemainingArgs()
+				if len(t) < 1 {
+					return Zones{}, errors.New("reload duration value is expected")
+				}
+				d, err := time.ParseDuration(t[0])
+				if err != nil {
+					return Zones{}, plugin.Error("file", err)
+				}
+				reload = d
+			case "store":
+				s, err := parseStore(c, config.Root)
+				if err != nil {
+					return Zones{}, err
+				}
+				newStore = s
+			case "upstream":
+				// remove soon
+				c.RemainingArgs()
+
+			default:
+				return Zones{}, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+
 		reader, err := os.Open(filepath.Clean(fileName))
 		if err != nil {
 			openErr = err
@@ -98,9 +127,10 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 
 			for i := range origins {
 				z[origins[i]] = NewZone(origins[i], fileName)
+				z[origins[i]].NewStore = newStore
 				if openErr == nil {
 					reader.Seek(0, 0)
-					zone, err := Parse(reader, origins[i], fileName, 0)
+					zone, err := ParseWithStore(reader, origins[i], fileName, 0, newStore)
 					if err != nil {
 						return err
 					}
@@ -115,27 +145,6 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 			return Zones{}, err
 		}
 
-		for c.NextBlock() {
-			switch c.Val() {
-			case "reload":
-				t := c.RemainingArgs()
-				if len(t) < 1 {
-					return Zones{}, errors.New("reload duration value is expected")
-				}
-				d, err := time.ParseDuration(t[0])
-				if err != nil {
-					return Zones{}, plugin.Error("file", err)
-				}
-				reload = d
-			case "upstream":
-				// remove soon
-				c.RemainingArgs()
-
-			default:
-				return Zones{}, c.Errf("unknown property '%s'", c.Val())
-			}
-		}
-
 		for i := range origins {
 			z[origins[i]].ReloadInterval = reload
 			z[origins[i]].Upstream = upstream.New()
@@ -151,3 +160,44 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 	}
 	return Zones{Z: z, Names: names}, nil
 }
+
+// defaultStoreCache is the number of elements a bolt store keeps in memory by default.
+const defaultStoreCache = 10000
+
+// parseStore parses the arguments of the store property: "memory", or "bolt DIR [CACHE]".
+func parseStore(c *caddy.Controller, root string) (StoreFunc, error) {
+	args := c.RemainingArgs()
+	if len(args) == 0 {
+		return nil, c.ArgErr()
+	}
+	switch args[0] {
+	case "memory":
+		if len(args) != 1 {
+			return nil, c.ArgErr()
+		}
+		return nil, nil
+	case "bolt":
+		if len(args) < 2 || len(args) > 3 {
+			return nil, c.ArgErr()
+		}
+		dir := args[1]
+		if !filepath.IsAbs(dir) && root != "" {
+			dir = filepath.Join(root, dir)
+		}
+		if fi, err := os.Stat(dir); err != nil {
+			return nil, err
+		} else if !fi.IsDir() {
+			return nil, c.Errf("store directory %q is not a directory", dir)
+		}
+		size := defaultStoreCache
+		if len(args) == 3 {
+			n, err := strconv.Atoi(args[2])
+			if err != nil || n < 0 {
+				return nil, c.Errf("invalid store cache size '%s'", args[2])
+			}
+			size = n
+		}
+		return NewBoltStore(dir, size), nil
+	}
+	return nil, c.Errf("unknown store '%s'", args[0])
+}
@@ -21,6 +21,8 @@ func TestFileParse(t *testing.T) {
 	}
 	defer rm()
 
+	storeDir := t.TempDir()
+
 	tests := []struct {
 		inputFileRules string
 		shouldErr      bool
@@ -41,6 +43,20 @@ func TestFileParse(t *testing.T) {
 			false,
 			Zones{Names: []string{"10.in-addr.arpa."}},
 		},
+		{
+			`file ` + zoneFileName1 + ` miek.nl. {
+				store bolt ` + storeDir + `
+			}`,
+			false,
+			Zones{Names: []string{"miek.nl."}},
+		},
+		{
+			`file ` + zoneFileName1 + ` miek.nl. {
+				store memory
+			}`,
+			false,
+			Zones{Names: []string{"miek.nl."}},
+		},
 		// errors.
 		{
 			`file ` + zoneFileName1 + ` miek.nl {
@@ -68,6 +84,27 @@ func TestFileParse(t *testing.T) {
 			true,
 			Zones{},
 		},
+		{
+			`file ` + zoneFileName1 + ` example.net. {
+				store badger ` + storeDir + `
+			}`,
+			true,
+			Zones{},
+		},
+		{
+			`file ` + zoneFileName1 + ` example.net. {
+				store bolt ` + storeDir + ` many
+			}`,
+			true,
+			Zones{},
+		},
+		{
+			`file ` + zoneFileName1 + ` example.net. {
+				store bolt ` + zoneFileName1 + `
+			}`,
+			true,
+			Zones{},
+		},
 	}
 
 	for i, test := range tests {
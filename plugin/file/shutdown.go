@@ -5,5 +5,8 @@ func (z *Zone) OnShutdown() error {
 	if 0 < z.ReloadInterval {
 		z.reloadShutdown <- true
 	}
+	z.RLock()
+	retireStore(z.Store)
+	z.RUnlock()
 	return nil
 }
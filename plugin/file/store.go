@@ -0,0 +1,114 @@
+package file
+
+import (
+	"sync"
+
+	"github.com/coredns/coredns/plugin/file/tree"
+
+	"github.com/miekg/dns"
+)
+
+// Store holds the records of a zone, apart from the SOA and NS records at the apex. A *tree.Tree keeps
+// them in memory, which is the default; a bolt store keeps them on disk, see NewBoltStore.
+type Store interface {
+	Insert(rr dns.RR)
+	// Delete removes all records with the name and type of rr.
+	Delete(rr dns.RR)
+	Search(qname string) (*tree.Elem, bool)
+	// Prev and Next return the element with the greatest name before, and the smallest name after, qname in
+	// canonical order, or the element of qname itself.
+	Prev(qname string) (*tree.Elem, bool)
+	Next(qname string) (*tree.Elem, bool)
+	Glue(nsrrs []dns.RR, do bool, scion bool) []dns.RR
+	// Walk and AuthWalk call fn for all elements in canonical order, see tree.Tree.
+	Walk(fn func(*tree.Elem, map[uint16][]dns.RR) error) error
+	AuthWalk(fn func(*tree.Elem, map[uint16][]dns.RR, bool) error) error
+	All() []*tree.Elem
+	Len() int
+}
+
+// StoreFunc returns a new, empty, Store for the zone origin.
+type StoreFunc func(origin string) (Store, error)
+
+var _ Store = (*tree.Tree)(nil)
+
+// newStore returns a new, empty, store for z.
+func (z *Zone) newStore() (Store, error) {
+	if z.NewStore == nil {
+		return &tree.Tree{}, nil
+	}
+	return z.NewStore(z.origin)
+}
+
+// storeUsers counts the lookups and transfers that use a store that has to be closed. A store that is
+// replaced while it is in use is closed when the last of them is done.
+var storeUsers = struct {
+	sync.Mutex
+	n       map[Store]int
+	retired map[Store]bool
+}{n: map[Store]int{}, retired: map[Store]bool{}}
+
+// holdStore returns the apex and store of z. The store stays open until it is released with
+// releaseStore, even if it is replaced in the meantime.
+func (z *Zone) holdStore() (Apex, Store) {
+	z.RLock()
+	defer z.RUnlock()
+	acquireStore(z.Store)
+	return z.Apex, z.Store
+}
+
+// acquireStore marks s as in use, it is released with releaseStore.
+func acquireStore(s Store) {
+	if _, ok := s.(interface{ Close() error }); !ok {
+		return
+	}
+	storeUsers.Lock()
+	storeUsers.n[s]++
+	storeUsers.Unlock()
+}
+
+// releaseStore releases s, and closes it if it is retired and this was its last user.
+func releaseStore(s Store) {
+	if _, ok := s.(interface{ Close() error }); !ok {
+		return
+	}
+	storeUsers.Lock()
+	storeUsers.n[s]--
+	last := storeUsers.n[s] <= 0
+	if last {
+		delete(storeUsers.n, s)
+	}
+	retired := last && storeUsers.retired[s]
+	if retired {
+		delete(storeUsers.retired, s)
+	}
+	storeUsers.Unlock()
+	if retired {
+		closeStore(s)
+	}
+}
+
+// retireStore closes s, a store that was replaced, once no lookup or transfer uses it anymore.
+func retireStore(s Store) {
+	if _, ok := s.(interface{ Close() error }); !ok {
+		return
+	}
+	storeUsers.Lock()
+	inUse := storeUsers.n[s] > 0
+	if inUse {
+		storeUsers.retired[s] = true
+	}
+	storeUsers.Unlock()
+	if !inUse {
+		closeStore(s)
+	}
+}
+
+// closeStore releases the resources of s, if it has any.
+func closeStore(s Store) {
+	if c, ok := s.(interface{ Close() error }); ok {
+		if err := c.Close(); err != nil {
+			log.Warningf("Failed to close zone store: %s", err)
+		}
+	}
+}
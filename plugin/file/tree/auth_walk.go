@@ -4,6 +4,11 @@ import (
 	"github.com/miekg/dns"
 )
 
+// Walker walks all elements in order, it is implemented by Tree and by the other stores of zone data.
+type Walker interface {
+	Walk(fn func(*Elem, map[uint16][]dns.RR) error) error
+}
+
 // AuthWalk performs fn on all authoritative values stored in the tree in
 // pre-order depth first. If a non-nil error is returned the AuthWalk was interrupted
 // by an fn returning that error. If fn alters stored values' sort
@@ -12,47 +17,33 @@ import (
 // The fn function will be called with 3 arguments, the current element, a map containing all
 // the RRs for this element and a boolean if this name is considered authoritative.
 func (t *Tree) AuthWalk(fn func(*Elem, map[uint16][]dns.RR, bool) error) error {
-	if t.Root == nil {
-		return nil
-	}
-	return t.Root.authwalk(make(map[string]struct{}), fn)
+	return AuthWalkOf(t, fn)
 }
 
-func (n *Node) authwalk(ns map[string]struct{}, fn func(*Elem, map[uint16][]dns.RR, bool) error) error {
-	if n.Left != nil {
-		if err := n.Left.authwalk(ns, fn); err != nil {
-			return err
+// AuthWalkOf is AuthWalk for all elements of w.
+func AuthWalkOf(w Walker, fn func(*Elem, map[uint16][]dns.RR, bool) error) error {
+	ns := make(map[string]struct{})
+	return w.Walk(func(e *Elem, rrs map[uint16][]dns.RR) error {
+		// Check if the current name is a subdomain of *any* of the delegated names we've seen, if so, skip this name.
+		// The ordering of the walk guarantees we see parents first.
+		if e.Type(dns.TypeNS) != nil {
+			ns[e.Name()] = struct{}{}
 		}
-	}
-
-	// Check if the current name is a subdomain of *any* of the delegated names we've seen, if so, skip this name.
-	// The ordering of the tree and how we walk if guarantees we see parents first.
-	if n.Elem.Type(dns.TypeNS) != nil {
-		ns[n.Elem.Name()] = struct{}{}
-	}
 
-	auth := true
-	i := 0
-	for {
-		j, end := dns.NextLabel(n.Elem.Name(), i)
-		if end {
-			break
+		auth := true
+		i := 0
+		for {
+			j, end := dns.NextLabel(e.Name(), i)
+			if end {
+				break
+			}
+			if _, ok := ns[e.Name()[j:]]; ok {
+				auth = false
+				break
+			}
+			i++
 		}
-		if _, ok := ns[n.Elem.Name()[j:]]; ok {
-			auth = false
-			break
-		}
-		i++
-	}
-
-	if err := fn(n.Elem, n.Elem.m, auth); err != nil {
-		return err
-	}
 
-	if n.Right != nil {
-		if err := n.Right.authwalk(ns, fn); err != nil {
-			return err
-		}
-	}
-	return nil
+		return fn(e, rrs, auth)
+	})
 }
@@ -1,6 +1,10 @@
 package tree
 
-import "github.com/miekg/dns"
+import (
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+
+	"github.com/miekg/dns"
+)
 
 // Elem is an element in the tree.
 type Elem struct {
@@ -32,6 +36,7 @@ func (e *Elem) Type(qtype uint16) (result []dns.RR) {
 		result = append(result, e.m[dns.TypeA]...)
 		result = append(result, e.m[dns.TypeAAAA]...)
 		result = append(result, e.m[dns.TypeTXT]...)
+		result = append(result, e.m[pkgscion.TypeSCION]...)
 		// did i forget anything here ?!
 	} else {
 		result = e.m[qtype]
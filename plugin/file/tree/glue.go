@@ -2,23 +2,32 @@ package tree
 
 import (
 	"github.com/coredns/coredns/plugin/file/rrutil"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 
 	"github.com/miekg/dns"
 )
 
+// Searcher finds the element of a name, it is implemented by Tree and by the other stores of zone data.
+type Searcher interface {
+	Search(qname string) (*Elem, bool)
+}
+
 // Glue returns any potential glue records for nsrrs.
-func (t *Tree) Glue(nsrrs []dns.RR, do bool, scion bool) []dns.RR {
+func (t *Tree) Glue(nsrrs []dns.RR, do bool, scion bool) []dns.RR { return GlueOf(t, nsrrs, do, scion) }
+
+// GlueOf returns any potential glue records for nsrrs found in s.
+func GlueOf(s Searcher, nsrrs []dns.RR, do bool, scion bool) []dns.RR {
 	glue := []dns.RR{}
 	for _, rr := range nsrrs {
 		if ns, ok := rr.(*dns.NS); ok && dns.IsSubDomain(ns.Header().Name, ns.Ns) {
-			glue = append(glue, t.searchGlue(ns.Ns, do, scion)...)
+			glue = append(glue, searchGlue(s, ns.Ns, do, scion)...)
 		}
 	}
 	return glue
 }
 
-// searchGlue looks up A and AAAA for name.
-func (t *Tree) searchGlue(name string, do bool, scion bool) []dns.RR {
+// searchGlue looks up A and AAAA for name, or its SCION addresses, TXT and SCION records, if scion is set.
+func searchGlue(t Searcher, name string, do bool, scion bool) []dns.RR {
 	glue := []dns.RR{}
 
 	// A
@@ -45,9 +54,10 @@ func (t *Tree) searchGlue(name string, do bool, scion bool) []dns.RR {
 
 		if elem, found := t.Search(name); found {
 			glue = append(glue, elem.Type(dns.TypeTXT)...)
+			glue = append(glue, elem.Type(pkgscion.TypeSCION)...)
 			if do {
 				sigs := elem.Type(dns.TypeRRSIG)
-				sigs = rrutil.SubTypeSignature(sigs, dns.TypeTXT)
+				sigs = append(rrutil.SubTypeSignature(sigs, dns.TypeTXT), rrutil.SubTypeSignature(sigs, pkgscion.TypeSCION)...)
 				glue = append(glue, sigs...)
 			}
 		}
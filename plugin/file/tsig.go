@@ -0,0 +1,107 @@
+package file
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// tsigFudge is the time difference allowed between the secondary and its primaries when signing queries.
+const tsigFudge = 300
+
+var errUnsigned = errors.New("transfer is not signed with the TSIG key")
+
+// TransferKey is the TSIG key that signs the queries to the primaries of a secondary zone. Their answers
+// must be signed with it as well. https://www.rfc-editor.org/rfc/rfc8945
+type TransferKey struct {
+	// Name is the name of the key, in canonical form.
+	Name string
+	// Algorithm is one of the HMAC algorithms, e.g. dns.HmacSHA256.
+	Algorithm string
+	// Secret is the base64 encoded secret of the key.
+	Secret string
+}
+
+// sign adds a TSIG record for k to m, which is signed once it is packed. The ID of m must be set already.
+func (k *TransferKey) sign(m *dns.Msg) {
+	m.SetTsig(k.Name, k.Algorithm, tsigFudge, time.Now().Unix())
+}
+
+// secrets returns the secret of k in the form of dns.Client.TsigSecret.
+func (k *TransferKey) secrets() map[string]string {
+	return map[string]string{k.Name: k.Secret}
+}
+
+// provider returns the dns.TsigProvider that verifies a transfer signed with k.
+func (k *TransferKey) provider() *tsigProvider {
+	return &tsigProvider{TsigProvider: hmacProvider(*k)}
+}
+
+// tsigProvider wraps a dns.TsigProvider and records whether any message was verified, so a transfer the
+// primary didn't sign at all can be told apart from one that was verified.
+type tsigProvider struct {
+	dns.TsigProvider
+	verified bool
+}
+
+func (p *tsigProvider) Verify(msg []byte, t *dns.TSIG) error {
+	if err := p.TsigProvider.Verify(msg, t); err != nil {
+		return err
+	}
+	p.verified = true
+	return nil
+}
+
+// hmacProvider is the HMAC dns.TsigProvider of a TransferKey. The dns package has the same one, but
+// doesn't export it.
+type hmacProvider TransferKey
+
+func (k hmacProvider) Generate(msg []byte, t *dns.TSIG) ([]byte, error) {
+	if t.Hdr.Name != k.Name {
+		return nil, dns.ErrSecret
+	}
+	secret, err := base64.StdEncoding.DecodeString(k.Secret)
+	if err != nil {
+		return nil, err
+	}
+	var h hash.Hash
+	switch dns.CanonicalName(t.Algorithm) {
+	case dns.HmacSHA1:
+		h = hmac.New(sha1.New, secret)
+	case dns.HmacSHA224:
+		h = hmac.New(sha256.New224, secret)
+	case dns.HmacSHA256:
+		h = hmac.New(sha256.New, secret)
+	case dns.HmacSHA384:
+		h = hmac.New(sha512.New384, secret)
+	case dns.HmacSHA512:
+		h = hmac.New(sha512.New, secret)
+	default:
+		return nil, dns.ErrKeyAlg
+	}
+	h.Write(msg)
+	return h.Sum(nil), nil
+}
+
+func (k hmacProvider) Verify(msg []byte, t *dns.TSIG) error {
+	b, err := k.Generate(msg, t)
+	if err != nil {
+		return err
+	}
+	mac, err := hex.DecodeString(t.MAC)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(b, mac) {
+		return dns.ErrSig
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+
+	"github.com/miekg/dns"
+)
+
+var testKey = &TransferKey{Name: "example.key.", Algorithm: dns.HmacSHA256, Secret: "c2VjcmV0c2VjcmV0c2VjcmV0"}
+
+func TestTsigProvider(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetAxfr(testZone)
+	testKey.sign(m)
+	buf, _, err := dns.TsigGenerate(m, testKey.Secret, "", false)
+	if err != nil {
+		t.Fatalf("Expected no error signing, got %s", err)
+	}
+
+	p := testKey.provider()
+	if err := dns.TsigVerifyWithProvider(buf, p, "", false); err != nil {
+		t.Errorf("Expected the signature to verify, got %s", err)
+	}
+	if !p.verified {
+		t.Errorf("Expected the provider to record the verification")
+	}
+
+	// Verifying strips the TSIG record from buf and signing it from m, so sign a new message.
+	m = new(dns.Msg)
+	m.SetAxfr(testZone)
+	testKey.sign(m)
+	buf, _, err = dns.TsigGenerate(m, testKey.Secret, "", false)
+	if err != nil {
+		t.Fatalf("Expected no error signing, got %s", err)
+	}
+	other := &TransferKey{Name: testKey.Name, Algorithm: testKey.Algorithm, Secret: "b3RoZXJvdGhlcm90aGVy"}
+	if err := dns.TsigVerifyWithProvider(buf, other.provider(), "", false); err != dns.ErrSig {
+		t.Errorf("Expected %s with another secret, got %v", dns.ErrSig, err)
+	}
+}
+
+func TestTransferInUnsigned(t *testing.T) {
+	p := new(ixfrPrimary)
+	s := dnstest.NewServer(p.Handler)
+	defer s.Close()
+
+	z := new(Zone)
+	z.origin = testZone
+	z.TransferFrom = []string{s.Addr}
+	z.TransferKey = testKey
+
+	if err := z.TransferIn(); err != errUnsigned {
+		t.Errorf("Expected %s for a transfer the primary didn't sign, got %v", errUnsigned, err)
+	}
+	if z.Apex.SOA != nil {
+		t.Errorf("Expected the unsigned zone not to be loaded")
+	}
+}
@@ -0,0 +1,94 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TransferWindow is a daily time span, in local time, during which a zone may be transferred from a primary.
+// If End is before Start the window wraps around midnight.
+type TransferWindow struct {
+	Start time.Duration // offset from midnight
+	End   time.Duration // offset from midnight
+}
+
+// ParseTransferWindow parses a window in the form HH:MM-HH:MM.
+func ParseTransferWindow(s string) (TransferWindow, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return TransferWindow{}, fmt.Errorf("transfer window %q is not of the form HH:MM-HH:MM", s)
+	}
+	start, err := parseTimeOfDay(from)
+	if err != nil {
+		return TransferWindow{}, err
+	}
+	end, err := parseTimeOfDay(to)
+	if err != nil {
+		return TransferWindow{}, err
+	}
+	if start == end {
+		return TransferWindow{}, fmt.Errorf("transfer window %q is empty", s)
+	}
+	return TransferWindow{Start: start, End: end}, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: %v", s, err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains returns true if t falls within the window.
+func (w TransferWindow) Contains(t time.Time) bool {
+	h, m, s := t.Clock()
+	d := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+	if w.Start < w.End {
+		return d >= w.Start && d < w.End
+	}
+	return d >= w.Start || d < w.End
+}
+
+func (w TransferWindow) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", int(w.Start.Hours()), int(w.Start.Minutes())%60, int(w.End.Hours()), int(w.End.Minutes())%60)
+}
+
+// inTransferWindow returns true if the zone may be transferred from primary at time t. Primaries
+// without any windows may always be used.
+func (z *Zone) inTransferWindow(primary string, t time.Time) bool {
+	windows, ok := z.TransferWindows[primary]
+	if !ok {
+		return true
+	}
+	for _, w := range windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// errOutsideWindow is returned by TransferIn when no primary could be used because of their transfer windows.
+var errOutsideWindow = errors.New("outside of the transfer windows of all primaries")
+
+// pacer limits the rate at which a transfer is read. Reading slower makes the QUIC flow control
+// throttle the primary, so the transfer doesn't use more than its share of the link.
+type pacer struct {
+	rate  int64 // bytes per second
+	start time.Time
+	n     int64
+}
+
+func newPacer(rate int64) *pacer { return &pacer{rate: rate, start: time.Now()} }
+
+// wait accounts for n bytes read and sleeps until the average rate is at or below p.rate.
+func (p *pacer) wait(n int) {
+	p.n += int64(n)
+	due := time.Duration(float64(p.n) / float64(p.rate) * float64(time.Second))
+	if d := due - time.Since(p.start); d > 0 {
+		time.Sleep(d)
+	}
+}
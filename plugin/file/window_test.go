@@ -0,0 +1,78 @@
+package file
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTransferWindow(t *testing.T) {
+	tests := []struct {
+		in        string
+		shouldErr bool
+		expected  string
+	}{
+		{"02:00-04:00", false, "02:00-04:00"},
+		{"23:30-01:15", false, "23:30-01:15"},
+		{"2:00-4:00", false, "02:00-04:00"},
+		{"02:00", true, ""},
+		{"02:00-24:00", true, ""},
+		{"02:00-02:00", true, ""},
+		{"two-four", true, ""},
+	}
+	for i, tc := range tests {
+		w, err := ParseTransferWindow(tc.in)
+		if err == nil && tc.shouldErr {
+			t.Errorf("Test %d: expected error, got none", i)
+			continue
+		}
+		if err != nil && !tc.shouldErr {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+			continue
+		}
+		if err == nil && w.String() != tc.expected {
+			t.Errorf("Test %d: expected window %s, got %s", i, tc.expected, w)
+		}
+	}
+}
+
+func TestTransferWindowContains(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2023, 5, 1, h, m, 0, 0, time.Local) }
+
+	day, _ := ParseTransferWindow("02:00-04:00")
+	night, _ := ParseTransferWindow("23:00-01:00")
+
+	tests := []struct {
+		w        TransferWindow
+		t        time.Time
+		expected bool
+	}{
+		{day, at(2, 0), true},
+		{day, at(3, 59), true},
+		{day, at(4, 0), false},
+		{day, at(1, 59), false},
+		{night, at(23, 30), true},
+		{night, at(0, 30), true},
+		{night, at(1, 0), false},
+		{night, at(12, 0), false},
+	}
+	for i, tc := range tests {
+		if got := tc.w.Contains(tc.t); got != tc.expected {
+			t.Errorf("Test %d: expected %s to contain %s: %t, got %t", i, tc.w, tc.t.Format("15:04"), tc.expected, got)
+		}
+	}
+}
+
+func TestInTransferWindow(t *testing.T) {
+	w, _ := ParseTransferWindow("02:00-04:00")
+	z := NewZone("example.org.", "stdin")
+	z.TransferFrom = []string{"10.0.1.1:53", "10.0.1.2:53"}
+	z.TransferWindows = map[string][]TransferWindow{"10.0.1.1:53": {w}}
+
+	noon := time.Date(2023, 5, 1, 12, 0, 0, 0, time.Local)
+	if z.inTransferWindow("10.0.1.1:53", noon) {
+		t.Errorf("Expected 10.0.1.1:53 to be outside of its window")
+	}
+	if !z.inTransferWindow("10.0.1.2:53", noon) {
+		t.Errorf("Expected 10.0.1.2:53 without windows to always be usable")
+	}
+}
@@ -25,8 +25,10 @@ func (z *Zone) Transfer(serial uint32) (<-chan []dns.RR, error) {
 		return nil, err
 	}
 
+	_, store := z.holdStore()
 	ch := make(chan []dns.RR)
 	go func() {
+		defer releaseStore(store)
 		if serial != 0 && apex[0].(*dns.SOA).Serial == serial { // ixfr fallback, only send SOA
 			ch <- []dns.RR{apex[0]}
 
@@ -35,7 +37,7 @@ func (z *Zone) Transfer(serial uint32) (<-chan []dns.RR, error) {
 		}
 
 		ch <- apex
-		z.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error { ch <- e.All(); return nil })
+		store.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error { ch <- e.All(); return nil })
 		ch <- []dns.RR{apex[0]}
 
 		close(ch)
@@ -2,11 +2,13 @@ package file
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/coredns/coredns/core/dnsserver"
@@ -14,6 +16,7 @@ import (
 	"github.com/coredns/coredns/plugin/hosts"
 	"github.com/coredns/coredns/plugin/pkg/dnsutil"
 	"github.com/coredns/coredns/plugin/pkg/nonwriter"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
 
 	"github.com/miekg/dns"
@@ -29,17 +32,44 @@ type Zone struct {
 	origin  string
 	origLen int
 	file    string
-	*tree.Tree
+	Store
+	// NewStore returns the store for the zone when it is (re)loaded, nil means an in-memory tree.
+	NewStore StoreFunc
 	Apex
 	Expired bool
+	// ExpiredPolicy determines the answers once the zone is expired.
+	ExpiredPolicy ExpiredPolicy
+	// StaleTTL caps the TTLs of answers from an expired zone with ExpiredServeStale.
+	StaleTTL uint32
 
 	sync.RWMutex
 
 	StartupOnce  sync.Once
 	TransferFrom []string
+	// TransferWindows restricts refreshing the zone from a primary in TransferFrom to the given times of day.
+	TransferWindows map[string][]TransferWindow
+	// TransferRate caps the bandwidth of transfers over squic in bytes per second, zero means no limit.
+	TransferRate int64
+	// TransferKey signs the queries to the primaries with TSIG, nil means they aren't signed.
+	TransferKey *TransferKey
+	// TransferTLS holds the client certificate presented to the primaries over DoQ, and the CAs that
+	// verify them. Nil means those of the server are used.
+	TransferTLS *tls.Config
+	// TransferTRCs verifies the primaries against SCION TRCs instead of the CAs of TransferTLS. Nil means
+	// the CAs are used. The AS certificate of a primary over SCION must be for the ISD-AS of its address,
+	// that of a primary over IP for TransferTRCIA.
+	TransferTRCs  *pkgscion.TRCs
+	TransferTRCIA pkgscion.IA
+
+	// RefreshPolicy bounds the SOA timers Update uses to keep a secondary zone up to date.
+	RefreshPolicy RefreshPolicy
 
 	ReloadInterval time.Duration
 	reloadShutdown chan bool
+	// notified wakes up Update after a notify from a primary, see notify.
+	notified chan struct{}
+	// primaryHosts holds the resolved primaries given by domain name, see resolvePrimaryHosts.
+	primaryHosts atomic.Pointer[primaryHostSet]
 
 	Upstream *upstream.Upstream // Upstream for looking up external names during the resolution process.
 }
@@ -48,7 +78,9 @@ type Zone struct {
 // \details this method computes the reverse name corresponsing to address and looks for a matching PTR record in the hosts file
 // returns  the domain-name with that address
 func (z *Zone) LookupInHosts(address string) (string, error) {
-
+	if z.Config == nil {
+		return "", errors.New("no hostsfile")
+	}
 	if host := z.Config.Handler("hosts"); host != nil {
 		var ho hosts.Hosts
 		var ok bool
@@ -80,8 +112,12 @@ func (z *Zone) LookupInHosts(address string) (string, error) {
 	return "", errors.New("no host with this address found in hostsfile")
 }
 
-// searches address of type 'qtype' dns.TypeA|AAAA|TXT for 'domainname' in hostsfile
+// searches address of type 'qtype' dns.TypeA|AAAA|TXT|SCION for 'domainname' in hostsfile, SCION addresses
+// are returned as ISD-AS,[IP]
 func (z *Zone) LookupAddrInHosts(domainname string, qtype uint16) (string, error) {
+	if z.Config == nil {
+		return "", errors.New("no hostsfile")
+	}
 	if host := z.Config.Handler("hosts"); host != nil {
 		var ho hosts.Hosts
 		var ok bool
@@ -102,7 +138,7 @@ func (z *Zone) LookupAddrInHosts(domainname string, qtype uint16) (string, error
 					{
 						a := ans.(*dns.A)
 						if a != nil {
-							return string(a.A), nil
+							return a.A.String(), nil
 						} else {
 							return "", nil
 						}
@@ -111,7 +147,7 @@ func (z *Zone) LookupAddrInHosts(domainname string, qtype uint16) (string, error
 					{
 						a := ans.(*dns.AAAA)
 						if a != nil {
-							return string(a.AAAA), nil
+							return a.AAAA.String(), nil
 						} else {
 							return "", nil
 						}
@@ -125,6 +161,11 @@ func (z *Zone) LookupAddrInHosts(domainname string, qtype uint16) (string, error
 							return "", nil
 						}
 					}
+				case pkgscion.TypeSCION:
+					if s, ok := pkgscion.SCIONOf(ans); ok {
+						return s.String(), nil
+					}
+					return "", nil
 
 				}
 			} else {
@@ -151,8 +192,10 @@ func NewZone(name, file string) *Zone {
 		origin:         dns.Fqdn(name),
 		origLen:        dns.CountLabel(dns.Fqdn(name)),
 		file:           filepath.Clean(file),
-		Tree:           &tree.Tree{},
+		Store:          &tree.Tree{},
 		reloadShutdown: make(chan bool),
+		notified:       make(chan struct{}, 1),
+		RefreshPolicy:  DefaultRefreshPolicy,
 	}
 }
 
@@ -160,7 +203,12 @@ func NewZone(name, file string) *Zone {
 func (z *Zone) Copy() *Zone {
 	z1 := NewZone(z.origin, z.file)
 	z1.TransferFrom = z.TransferFrom
+	z1.TransferKey = z.TransferKey
+	z1.TransferTLS = z.TransferTLS
+	z1.TransferTRCs = z.TransferTRCs
+	z1.TransferTRCIA = z.TransferTRCIA
 	z1.Expired = z.Expired
+	z1.NewStore = z.NewStore
 
 	z1.Apex = z.Apex
 	return z1
@@ -171,6 +219,7 @@ func (z *Zone) CopyWithoutApex() *Zone {
 	z1 := NewZone(z.origin, z.file)
 	z1.TransferFrom = z.TransferFrom
 	z1.Expired = z.Expired
+	z1.NewStore = z.NewStore
 
 	return z1
 }
@@ -215,7 +264,7 @@ func (z *Zone) Insert(r dns.RR) error {
 		r.(*dns.SRV).Target = strings.ToLower(r.(*dns.SRV).Target)
 	}
 
-	z.Tree.Insert(r)
+	z.Store.Insert(r)
 	return nil
 }
 
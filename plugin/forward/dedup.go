@@ -0,0 +1,88 @@
+package forward
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/proxy"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// connect sends the query in state to p. With dedup enabled, identical queries to p that are in flight
+// are sent only once, and all callers get a copy of the reply. The shared query doesn't run with the
+// context of the caller that sent it, which may give up before the others, but with the values of it and
+// the forward timeout.
+func (f *Forward) connect(ctx context.Context, p *proxy.Proxy, state request.Request, opts proxy.Options) (*dns.Msg, error) {
+	if f.inflight == nil {
+		return p.Connect(ctx, state, opts)
+	}
+
+	first := false
+	v, err := f.inflight.Do(dedupKey(p.Addr(), state, opts), func() (interface{}, error) {
+		first = true
+		ctx, cancel := context.WithTimeout(detached{ctx}, defaultTimeout)
+		defer cancel()
+		return p.Connect(ctx, state, opts)
+	})
+	if !first {
+		DedupSuppressedCount.WithLabelValues(p.Addr()).Inc()
+	}
+	ret, _ := v.(*dns.Msg)
+	if ret == nil {
+		return nil, err
+	}
+	// The reply is shared by all callers, each gets its own copy to write back.
+	ret = ret.Copy()
+	ret.Id = state.Req.Id
+	return ret, err
+}
+
+// detached is a context with the values of its parent, which is never canceled and has no deadline.
+type detached struct{ parent context.Context }
+
+func (detached) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detached) Done() <-chan struct{}               { return nil }
+func (detached) Err() error                          { return nil }
+func (d detached) Value(key interface{}) interface{} { return d.parent.Value(key) }
+
+// dedupKey returns the key of the query in state to the upstream addr. Queries that get the same reply
+// from the upstream have the same key: the question, including the case of the name, the flags and EDNS0
+// options must be identical.
+func dedupKey(addr string, state request.Request, opts proxy.Options) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(addr))
+	h.Write([]byte{0})
+	h.Write([]byte(state.Proto()))
+	h.Write([]byte{0})
+
+	q := state.Req.Question[0]
+	h.Write([]byte(q.Name))
+	var b [8]byte
+	binary.BigEndian.PutUint16(b[0:], q.Qtype)
+	binary.BigEndian.PutUint16(b[2:], q.Qclass)
+	b[4] = boolByte(state.Req.RecursionDesired)
+	b[5] = boolByte(state.Req.CheckingDisabled)
+	b[6] = boolByte(opts.ForceTCP)
+	b[7] = boolByte(opts.PreferUDP)
+	h.Write(b[:])
+
+	if o := state.Req.IsEdns0(); o != nil {
+		h.Write([]byte(o.String()))
+	}
+	// The client's address is added to the query, so it can't be shared with other clients.
+	if opts.ClientAddrKey != "" {
+		h.Write([]byte(state.W.RemoteAddr().String()))
+	}
+	return h.Sum64()
+}
+
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
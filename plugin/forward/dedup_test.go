@@ -0,0 +1,112 @@
+package forward
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/proxy"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestDedup(t *testing.T) {
+	var queries int32
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		atomic.AddInt32(&queries, 1)
+		time.Sleep(200 * time.Millisecond)
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	c := caddy.NewTestController("dns", "forward . "+s.Addr+" {\ndedup\n}\n")
+	fs, err := parseForward(c)
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %s", err)
+	}
+	f := fs[0]
+	f.OnStartup()
+	defer f.OnShutdown()
+
+	const clients = 10
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m := new(dns.Msg)
+			m.SetQuestion("example.org.", dns.TypeA)
+			rec := dnstest.NewRecorder(&test.ResponseWriter{})
+			if _, err := f.ServeDNS(context.TODO(), rec, m); err != nil {
+				t.Errorf("Expected to receive reply, got %s", err)
+				return
+			}
+			if rec.Msg.Id != m.Id {
+				t.Errorf("Expected reply with id %d, got %d", m.Id, rec.Msg.Id)
+			}
+			if len(rec.Msg.Answer) != 1 {
+				t.Errorf("Expected 1 answer, got %d", len(rec.Msg.Answer))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if q := atomic.LoadInt32(&queries); q != 1 {
+		t.Errorf("Expected 1 query upstream, got %d", q)
+	}
+}
+
+func TestDedupKey(t *testing.T) {
+	query := func(name string, qtype uint16, do bool) *dns.Msg {
+		m := new(dns.Msg)
+		m.SetQuestion(name, qtype)
+		if do {
+			m.SetEdns0(4096, true)
+		}
+		return m
+	}
+	key := func(m *dns.Msg) uint64 {
+		return dedupKey("127.0.0.1:53", request.Request{W: &test.ResponseWriter{}, Req: m}, proxy.Options{})
+	}
+
+	base := key(query("example.org.", dns.TypeA, false))
+	if key(query("example.org.", dns.TypeA, false)) != base {
+		t.Errorf("Expected identical queries to have the same key")
+	}
+	for _, m := range []*dns.Msg{
+		query("Example.org.", dns.TypeA, false),
+		query("example.org.", dns.TypeAAAA, false),
+		query("example.org.", dns.TypeA, true),
+	} {
+		if key(m) == base {
+			t.Errorf("Expected %s to have a different key", m.Question[0].String())
+		}
+	}
+}
+
+func TestDedupDetached(t *testing.T) {
+	type key struct{}
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "value"))
+	ctx, done := context.WithTimeout(detached{parent}, defaultTimeout)
+	defer done()
+	cancel()
+
+	if err := ctx.Err(); err != nil {
+		t.Errorf("Expected the shared query to outlive the canceled caller, got %s", err)
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		t.Error("Expected the shared query to have a deadline")
+	}
+	if v := ctx.Value(key{}); v != "value" {
+		t.Errorf("Expected the value of the caller, got %v", v)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/coredns/coredns/plugin/dnstap/msg"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
 	"github.com/coredns/coredns/plugin/pkg/proxy"
 	"github.com/coredns/coredns/request"
 
@@ -15,22 +16,29 @@ import (
 
 // toDnstap will send the forward and received message to the dnstap plugin.
 func toDnstap(f *Forward, host string, state request.Request, opts proxy.Options, reply *dns.Msg, start time.Time) {
-	h, p, _ := net.SplitHostPort(host)      // this is preparsed and can't err here
-	port, _ := strconv.ParseUint(p, 10, 32) // same here
-	ip := net.ParseIP(h)
+	var ta net.Addr
+	if sa, err := dnsutil.ParseSCIONAddress(host); err == nil {
+		// A squic upstream, SCION runs over UDP.
+		ta = sa
+	} else {
+		h, p, _ := net.SplitHostPort(host)      // this is preparsed and can't err here
+		port, _ := strconv.ParseUint(p, 10, 32) // same here
+		ip := net.ParseIP(h)
 
-	var ta net.Addr = &net.UDPAddr{IP: ip, Port: int(port)}
-	t := state.Proto()
-	switch {
-	case opts.ForceTCP:
-		t = "tcp"
-	case opts.PreferUDP:
-		t = "udp"
-	}
+		ta = &net.UDPAddr{IP: ip, Port: int(port)}
+		t := state.Proto()
+		switch {
+		case opts.ForceTCP:
+			t = "tcp"
+		case opts.PreferUDP:
+			t = "udp"
+		}
 
-	if t == "tcp" {
-		ta = &net.TCPAddr{IP: ip, Port: int(port)}
+		if t == "tcp" {
+			ta = &net.TCPAddr{IP: ip, Port: int(port)}
+		}
 	}
+	extra := msg.SCIONExtra(state.W.RemoteAddr(), ta)
 
 	for _, t := range f.tapPlugins {
 		// Query
@@ -45,7 +53,7 @@ func toDnstap(f *Forward, host string, state request.Request, opts proxy.Options
 			q.QueryMessage = buf
 		}
 		msg.SetType(q, tap.Message_FORWARDER_QUERY)
-		t.TapMessage(q)
+		t.TapMessageWithExtra(q, extra)
 
 		// Response
 		if reply != nil {
@@ -59,7 +67,7 @@ func toDnstap(f *Forward, host string, state request.Request, opts proxy.Options
 			msg.SetResponseAddress(r, ta)
 			msg.SetResponseTime(r, time.Now())
 			msg.SetType(r, tap.Message_FORWARDER_RESPONSE)
-			t.TapMessage(r)
+			t.TapMessageWithExtra(r, extra)
 		}
 	}
 }
@@ -0,0 +1,43 @@
+package forward
+
+import (
+	"errors"
+
+	"github.com/coredns/coredns/plugin/pkg/doqclient"
+	"github.com/coredns/coredns/plugin/pkg/proxy"
+
+	"github.com/miekg/dns"
+)
+
+// extendedError returns the Extended DNS Error (RFC 8914) code and text for err, returned by a squic
+// upstream. It tells the client that the inter-domain path is the problem and not us. Ok is false if
+// err isn't a SCION failure.
+//
+// Upstreams that can't be reached at all, because there is no path, a router answered with an SCMP
+// error or the handshake timed out, get No Reachable Authority. Failures of our SCION stack and of
+// established connections get Network Error, a missing TLS server name is a configuration error.
+func extendedError(err error) (code uint16, text string, ok bool) {
+	var (
+		pathErr  *proxy.PathError
+		squicErr *doqclient.Error
+	)
+	switch {
+	case errors.As(err, &pathErr):
+		return dns.ExtendedErrorCodeNoReachableAuthority, pathErr.Error(), true
+	case errors.As(err, &squicErr):
+	default:
+		return 0, "", false
+	}
+
+	text = squicErr.Transport + " upstream " + squicErr.Addr + " failed: " + squicErr.Kind
+	switch squicErr.Kind {
+	case doqclient.KindNoPath, doqclient.KindHandshakeTimeout:
+		return dns.ExtendedErrorCodeNoReachableAuthority, text, true
+	case doqclient.KindSCMP:
+		// The SCMP message names the router that sent it, which is what an operator needs to know.
+		return dns.ExtendedErrorCodeNoReachableAuthority, text + ": " + squicErr.Err.Error(), true
+	case doqclient.KindTLSNameMissing:
+		return dns.ExtendedErrorCodeOther, text, true
+	}
+	return dns.ExtendedErrorCodeNetworkError, text, true
+}
@@ -0,0 +1,46 @@
+package forward
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/doqclient"
+	"github.com/coredns/coredns/plugin/pkg/proxy"
+
+	"github.com/miekg/dns"
+)
+
+func TestExtendedError(t *testing.T) {
+	const addr = "19-ffaa:1:1067,[127.0.0.1]:853"
+	tests := []struct {
+		err  error
+		ok   bool
+		code uint16
+		text string
+	}{
+		{errors.New("connection refused"), false, 0, ""},
+		{&proxy.PathError{IA: "19-ffaa:1:1067", Err: errors.New("all paths down")}, true, dns.ExtendedErrorCodeNoReachableAuthority, "no SCION path to upstream AS 19-ffaa:1:1067"},
+		{&doqclient.Error{Kind: doqclient.KindNoPath, Addr: addr, Err: doqclient.ErrNoPath}, true, dns.ExtendedErrorCodeNoReachableAuthority, "failed: no_path"},
+		{&doqclient.Error{Kind: doqclient.KindHandshakeTimeout, Addr: addr, Err: doqclient.ErrHandshakeTimeout}, true, dns.ExtendedErrorCodeNoReachableAuthority, "failed: handshake_timeout"},
+		{&doqclient.Error{Kind: doqclient.KindSCMP, Addr: addr, Err: errors.New("SCMP DestinationUnreachable from 19-ffaa:1:1,10.0.0.1")}, true, dns.ExtendedErrorCodeNoReachableAuthority, "failed: scmp_error: SCMP DestinationUnreachable from 19-ffaa:1:1,10.0.0.1"},
+		{&doqclient.Error{Kind: doqclient.KindTLSNameMissing, Addr: addr, Err: doqclient.ErrTLSNameMissing}, true, dns.ExtendedErrorCodeOther, "failed: tls_name_missing"},
+		{&doqclient.Error{Kind: doqclient.KindDaemonUnreachable, Addr: addr, Err: doqclient.ErrDaemonUnreachable}, true, dns.ExtendedErrorCodeNetworkError, "failed: daemon_unreachable"},
+		{fmt.Errorf("exchange: %w", &doqclient.Error{Kind: doqclient.KindStreamReset, Transport: "squic", Addr: addr, Err: doqclient.ErrStreamReset}), true, dns.ExtendedErrorCodeNetworkError, "squic upstream " + addr + " failed: stream_reset"},
+	}
+
+	for i, tc := range tests {
+		code, text, ok := extendedError(tc.err)
+		if ok != tc.ok {
+			t.Errorf("Test %d: expected ok %t, got %t", i, tc.ok, ok)
+			continue
+		}
+		if code != tc.code {
+			t.Errorf("Test %d: expected code %d, got %d", i, tc.code, code)
+		}
+		if !strings.Contains(text, tc.text) {
+			t.Errorf("Test %d: expected text to contain %q, got %q", i, tc.text, text)
+		}
+	}
+}
@@ -11,12 +11,16 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/debug"
 	"github.com/coredns/coredns/plugin/dnstap"
 	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/proxy"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/plugin/pkg/singleflight"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -27,8 +31,10 @@ import (
 var log = clog.NewWithPlugin("forward")
 
 const (
-	defaultExpire = 10 * time.Second
-	hcInterval    = 500 * time.Millisecond
+	defaultExpire      = 10 * time.Second
+	hcInterval         = 500 * time.Millisecond
+	defaultFallbackTTL = 5 * time.Minute
+	defaultRaceStagger = 50 * time.Millisecond
 )
 
 // Forward represents a plugin instance that can proxy requests to another (DNS) server. It has a list
@@ -48,9 +54,34 @@ type Forward struct {
 	maxfails      uint32
 	expire        time.Duration
 	maxConcurrent int64
+	maxStreams    int64
+
+	// upstreamTLS and upstreamServerName override tlsConfig and tlsServerName for single upstreams, by
+	// their address.
+	upstreamTLS        map[string]*tls.Config
+	upstreamServerName map[string]string
+
+	// tlsTRCs verifies the upstreams against SCION TRCs instead of the root CAs of tlsConfig, upstreamTRCs
+	// does so for single upstreams. Nil means the root CAs are used. upstreamTRCIAs pins the ISD-AS the
+	// certificate of an upstream must be for, upstreams without a SCION address need one.
+	tlsTRCs        *pkgscion.TRCs
+	upstreamTRCs   map[string]*pkgscion.TRCs
+	upstreamTRCIAs map[string]pkgscion.IA
 
 	opts proxy.Options // also here for testing
 
+	// inflight deduplicates identical queries in flight to the same upstream, nil if disabled.
+	inflight *singleflight.Group
+
+	// fallbacks are the transports tried, in order, when an upstream fails over its own, and fallbackTTL is
+	// how long one that answered is used before the upstream's own transport is tried again.
+	fallbacks   []string
+	fallbackTTL time.Duration
+
+	// race is how long a query waits for an upstream before it is sent again over another SCION path or the
+	// first fallback transport, 0 if queries aren't raced.
+	race time.Duration
+
 	// ErrLimitExceeded indicates that a query was rejected because the number of concurrent queries has exceeded
 	// the maximum allowed (maxConcurrent)
 	ErrLimitExceeded error
@@ -62,7 +93,7 @@ type Forward struct {
 
 // New returns a new Forward.
 func New() *Forward {
-	f := &Forward{maxfails: 2, tlsConfig: new(tls.Config), expire: defaultExpire, p: new(random), from: ".", hcInterval: hcInterval, opts: proxy.Options{ForceTCP: false, PreferUDP: false, HCRecursionDesired: true, HCDomain: "."}}
+	f := &Forward{maxfails: 2, tlsConfig: new(tls.Config), expire: defaultExpire, fallbackTTL: defaultFallbackTTL, p: new(random), from: ".", hcInterval: hcInterval, opts: proxy.Options{ForceTCP: false, PreferUDP: false, HCRecursionDesired: true, HCDomain: "."}}
 	return f
 }
 
@@ -92,6 +123,14 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 	if !f.match(state) {
 		return plugin.NextOrFailure(f.Name(), f.Next, ctx, w, r)
 	}
+	if dnsserver.NoRecursion(ctx) {
+		// The client asked for local data only.
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeRefused)
+		edns.SetExtendedError(m, r, dns.ExtendedErrorCodeNotAuthoritative, "recursion not desired")
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	}
 
 	if f.maxConcurrent > 0 {
 		count := atomic.AddInt64(&(f.concurrent), 1)
@@ -149,7 +188,7 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 		opts := f.opts
 
 		for {
-			ret, err = proxy.Connect(ctx, state, opts)
+			ret, err = f.connect(ctx, proxy, state, opts)
 			if err == ErrCachedClosed { // Remote side closed conn, can only happen with TCP.
 				continue
 			}
@@ -171,6 +210,10 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 
 		upstreamErr = err
 
+		if err == ErrStreamLimit {
+			// The upstream is busy, not broken: don't add to its load with a health check or a retry.
+			break
+		}
 		if err != nil {
 			// Kick off health check to see if *our* upstream is broken.
 			if f.maxfails != 0 {
@@ -198,6 +241,13 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 	}
 
 	if upstreamErr != nil {
+		if code, text, ok := extendedError(upstreamErr); ok {
+			m := new(dns.Msg)
+			m.SetRcode(r, dns.RcodeServerFailure)
+			edns.SetExtendedError(m, r, code, text)
+			w.WriteMsg(m)
+			return 0, upstreamErr
+		}
 		return dns.RcodeServerFailure, upstreamErr
 	}
 
@@ -241,6 +291,8 @@ var (
 	ErrNoForward = errors.New("no forwarder defined")
 	// ErrCachedClosed means cached connection was closed by peer.
 	ErrCachedClosed = errors.New("cached connection was closed by peer")
+	// ErrStreamLimit means too many queries were in flight to a DoQ or h3 upstream, see max_streams.
+	ErrStreamLimit = proxy.ErrStreamLimit
 )
 
 // Options holds various Options that can be set.
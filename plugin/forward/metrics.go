@@ -21,4 +21,10 @@ var (
 		Name:      "max_concurrent_rejects_total",
 		Help:      "Counter of the number of queries rejected because the concurrent queries were at maximum.",
 	})
+	DedupSuppressedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "forward",
+		Name:      "dedup_suppressed_total",
+		Help:      "Counter of queries that were not sent upstream because an identical query was in flight.",
+	}, []string{"to"})
 )
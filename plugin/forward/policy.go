@@ -1,6 +1,7 @@
 package forward
 
 import (
+	"sort"
 	"sync/atomic"
 	"time"
 
@@ -66,4 +67,17 @@ func (r *sequential) List(p []*proxy.Proxy) []*proxy.Proxy {
 	return p
 }
 
+// lowestRTT is a policy that selects hosts by their smoothed round trip time, fastest first. Hosts that
+// didn't answer yet come first, so every host is measured.
+type lowestRTT struct{}
+
+func (r *lowestRTT) String() string { return "lowest_rtt" }
+
+func (r *lowestRTT) List(p []*proxy.Proxy) []*proxy.Proxy {
+	sorted := make([]*proxy.Proxy, len(p))
+	copy(sorted, p)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].RTT() < sorted[j].RTT() })
+	return sorted
+}
+
 var rn = rand.New(time.Now().UnixNano())
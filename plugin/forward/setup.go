@@ -2,9 +2,12 @@ package forward
 
 import (
 	"crypto/tls"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"net"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/coredns/caddy"
@@ -13,6 +16,8 @@ import (
 	"github.com/coredns/coredns/plugin/dnstap"
 	"github.com/coredns/coredns/plugin/pkg/parse"
 	"github.com/coredns/coredns/plugin/pkg/proxy"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/plugin/pkg/singleflight"
 	pkgtls "github.com/coredns/coredns/plugin/pkg/tls"
 	"github.com/coredns/coredns/plugin/pkg/transport"
 
@@ -121,7 +126,7 @@ func parseStanza(c *caddy.Controller) (*Forward, error) {
 	}
 
 	transports := make([]string, len(toHosts))
-	allowedTrans := map[string]bool{"dns": true, "tls": true, "squic": true}
+	allowedTrans := map[string]bool{"dns": true, "tls": true, "quic": true, "squic": true, "h3": true}
 	for i, host := range toHosts {
 		trans, h := parse.Transport(host)
 
@@ -148,27 +153,140 @@ func parseStanza(c *caddy.Controller) (*Forward, error) {
 	f.tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(len(f.proxies))
 
 	for i := range f.proxies {
-		if transports[i] == transport.SQUIC {
-			f.tlsConfig.NextProtos = []string{"doq", "dq", "doq-i00", "doq-i02"}
+		tlsConfig, err := f.tlsConfigFor(f.proxies[i].Addr(), transports[i])
+		if err != nil {
+			return f, err
 		}
-
 		// Only set this for proxies that need it.
-		if transports[i] == transport.TLS || transports[i] == transport.SQUIC {
-			f.proxies[i].SetTLSConfig(f.tlsConfig)
+		if transports[i] == transport.TLS || transports[i] == transport.SQUIC || transports[i] == transport.QUIC || transports[i] == transport.H3 {
+			f.proxies[i].SetTLSConfig(tlsConfig)
 		}
 
 		f.proxies[i].SetExpire(f.expire)
+		f.proxies[i].SetMaxStreams(f.maxStreams)
 		f.proxies[i].GetHealthchecker().SetRecursionDesired(f.opts.HCRecursionDesired)
 		// when TLS is used, checks are set to tcp-tls
 		if f.opts.ForceTCP && transports[i] != transport.TLS {
 			f.proxies[i].GetHealthchecker().SetTCPTransport()
 		}
 		f.proxies[i].GetHealthchecker().SetDomain(f.opts.HCDomain)
+
+		if len(f.fallbacks) > 0 {
+			fps, err := fallbackProxies(f, tlsConfig, transports[i], f.proxies[i].Addr())
+			if err != nil {
+				return f, err
+			}
+			f.proxies[i].SetFallbacks(fps, f.fallbackTTL)
+		}
+		if f.race > 0 && !f.proxies[i].SetRace(f.race) {
+			log.Warningf("Upstream %s has neither another SCION path nor a fallback, its queries are not raced", f.proxies[i].Addr())
+		}
 	}
 
 	return f, nil
 }
 
+// tlsConfigFor returns the TLS config for the upstream addr with transport trans: a copy of f.tlsConfig, or
+// of the one upstream_tls configured for it, with the server name of upstream_tls_servername, the TRCs
+// and the ALPNs of trans. It fails if the upstream is to be verified against TRCs, but its ISD-AS isn't
+// known.
+func (f *Forward) tlsConfigFor(addr, trans string) (*tls.Config, error) {
+	cfg, ok := f.upstreamTLS[addr]
+	name, named := f.upstreamServerName[addr]
+	trcs, pinned := f.upstreamTRCs[addr]
+	if !pinned {
+		trcs = f.tlsTRCs
+	}
+	// Plain DNS upstreams only use TLS for their fallbacks.
+	if trans == transport.DNS && len(f.fallbacks) == 0 {
+		trcs = nil
+	}
+	if ok {
+		cfg = cfg.Clone()
+		cfg.ServerName = f.tlsServerName
+		cfg.ClientSessionCache = tls.NewLRUClientSessionCache(1)
+	} else {
+		cfg = f.tlsConfig.Clone()
+	}
+	if named {
+		cfg.ServerName = name
+	}
+	if trcs != nil {
+		// The AS certificate must be for the ISD-AS given with upstream_tls_trc or, for an upstream over
+		// SCION, the one in its address. The server name isn't verified, so any other AS could pose as it.
+		ia, ok := f.upstreamTRCIAs[addr]
+		if !ok {
+			ia, _ = pkgscion.AddrIA(addr)
+		}
+		if err := trcs.ConfigureClient(cfg, ia); err != nil {
+			return nil, fmt.Errorf("upstream %s: %s, give it with upstream_tls_trc", addr, err)
+		}
+	}
+	cfg.NextProtos = nil
+	if trans == transport.SQUIC || trans == transport.QUIC {
+		cfg.NextProtos = transport.DoQALPNs
+	}
+	return cfg, nil
+}
+
+// upstreamAddr returns the address of the upstream to in f, written as in the list of upstreams.
+func upstreamAddr(f *Forward, to string) (string, error) {
+	hosts, err := parse.HostPortOrFile(to)
+	if err != nil {
+		return "", err
+	}
+	if len(hosts) == 1 {
+		_, addr := parse.Transport(hosts[0])
+		for _, p := range f.proxies {
+			if p.Addr() == addr {
+				return addr, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("'%s' is not an upstream of forward", to)
+}
+
+// fallbackProxies returns the proxies for the upstream addr, with transport trans, over the fallback
+// transports of f. They use the IP address of the upstream, for a squic upstream the one in its SCION
+// address, with the default port of the transport, and a copy of tlsConfig.
+func fallbackProxies(f *Forward, tlsConfig *tls.Config, trans, addr string) ([]*proxy.Proxy, error) {
+	host := addr
+	if i := strings.LastIndex(host, ","); i >= 0 {
+		// ISD-AS,[IP]:port
+		host = host[i+1:]
+	}
+	ip, _, err := net.SplitHostPort(host)
+	if err != nil {
+		return nil, fmt.Errorf("fallback: no IP address in upstream %q: %s", addr, err)
+	}
+
+	var fps []*proxy.Proxy
+	for _, fb := range f.fallbacks {
+		if fb == trans {
+			continue
+		}
+		var p *proxy.Proxy
+		switch fb {
+		case transport.QUIC:
+			p = proxy.NewProxy(net.JoinHostPort(ip, transport.QUICPort), fb)
+			tlsConfig := tlsConfig.Clone()
+			tlsConfig.NextProtos = transport.DoQALPNs
+			p.SetTLSConfig(tlsConfig)
+		case transport.TLS:
+			p = proxy.NewProxy(net.JoinHostPort(ip, transport.TLSPort), fb)
+			tlsConfig := tlsConfig.Clone()
+			tlsConfig.NextProtos = nil
+			p.SetTLSConfig(tlsConfig)
+		case transport.DNS:
+			p = proxy.NewProxy(net.JoinHostPort(ip, transport.Port), fb)
+		}
+		p.SetExpire(f.expire)
+		p.SetMaxStreams(f.maxStreams)
+		fps = append(fps, p)
+	}
+	return fps, nil
+}
+
 func parseBlock(c *caddy.Controller, f *Forward) error {
 	switch c.Val() {
 	case "except":
@@ -246,6 +364,78 @@ func parseBlock(c *caddy.Controller, f *Forward) error {
 			return c.ArgErr()
 		}
 		f.tlsServerName = c.Val()
+	case "upstream_tls":
+		args := c.RemainingArgs()
+		if len(args) == 0 || len(args) > 4 {
+			return c.ArgErr()
+		}
+		addr, err := upstreamAddr(f, args[0])
+		if err != nil {
+			return fmt.Errorf("upstream_tls: %s", err)
+		}
+		tlsConfig, err := pkgtls.NewTLSConfigFromArgs(args[1:]...)
+		if err != nil {
+			return err
+		}
+		if f.upstreamTLS == nil {
+			f.upstreamTLS = make(map[string]*tls.Config)
+		}
+		f.upstreamTLS[addr] = tlsConfig
+	case "upstream_tls_servername":
+		args := c.RemainingArgs()
+		if len(args) != 2 {
+			return c.ArgErr()
+		}
+		addr, err := upstreamAddr(f, args[0])
+		if err != nil {
+			return fmt.Errorf("upstream_tls_servername: %s", err)
+		}
+		if f.upstreamServerName == nil {
+			f.upstreamServerName = make(map[string]string)
+		}
+		f.upstreamServerName[addr] = args[1]
+	case "tls_trc":
+		if !c.NextArg() {
+			return c.ArgErr()
+		}
+		trcs, err := pkgscion.LoadTRCs(c.Val())
+		if err != nil {
+			return fmt.Errorf("tls_trc: %s", err)
+		}
+		if c.NextArg() {
+			return c.ArgErr()
+		}
+		f.tlsTRCs = trcs
+	case "upstream_tls_trc":
+		args := c.RemainingArgs()
+		if len(args) != 2 && len(args) != 3 {
+			return c.ArgErr()
+		}
+		addr, err := upstreamAddr(f, args[0])
+		if err != nil {
+			return fmt.Errorf("upstream_tls_trc: %s", err)
+		}
+		trcs, err := pkgscion.LoadTRCs(args[1])
+		if err != nil {
+			return fmt.Errorf("upstream_tls_trc: %s", err)
+		}
+		if len(args) == 3 {
+			ia, err := pkgscion.ParseIA(args[2])
+			if err != nil {
+				return fmt.Errorf("upstream_tls_trc: %s", err)
+			}
+			if addrIA, err := pkgscion.AddrIA(addr); err == nil && addrIA != ia {
+				return fmt.Errorf("upstream_tls_trc: %s isn't the ISD-AS of %s", ia, addr)
+			}
+			if f.upstreamTRCIAs == nil {
+				f.upstreamTRCIAs = make(map[string]pkgscion.IA)
+			}
+			f.upstreamTRCIAs[addr] = ia
+		}
+		if f.upstreamTRCs == nil {
+			f.upstreamTRCs = make(map[string]*pkgscion.TRCs)
+		}
+		f.upstreamTRCs[addr] = trcs
 	case "expire":
 		if !c.NextArg() {
 			return c.ArgErr()
@@ -269,6 +459,8 @@ func parseBlock(c *caddy.Controller, f *Forward) error {
 			f.p = &roundRobin{}
 		case "sequential":
 			f.p = &sequential{}
+		case "lowest_rtt":
+			f.p = &lowestRTT{}
 		default:
 			return c.Errf("unknown policy '%s'", x)
 		}
@@ -285,6 +477,75 @@ func parseBlock(c *caddy.Controller, f *Forward) error {
 		}
 		f.ErrLimitExceeded = errors.New("concurrent queries exceeded maximum " + c.Val())
 		f.maxConcurrent = int64(n)
+	case "max_streams":
+		if !c.NextArg() {
+			return c.ArgErr()
+		}
+		n, err := strconv.Atoi(c.Val())
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return fmt.Errorf("max_streams can't be negative: %d", n)
+		}
+		f.maxStreams = int64(n)
+	case "client_address":
+		if !c.NextArg() {
+			return c.ArgErr()
+		}
+		key, err := base64.StdEncoding.DecodeString(c.Val())
+		if err != nil || len(key) == 0 {
+			return c.Errf("invalid client_address key '%s'", c.Val())
+		}
+		f.opts.ClientAddrKey = string(key)
+	case "dedup":
+		if c.NextArg() {
+			return c.ArgErr()
+		}
+		f.inflight = new(singleflight.Group)
+	case "fallback":
+		args := c.RemainingArgs()
+		f.fallbacks = nil
+		for i := 0; i < len(args); i++ {
+			switch args[i] {
+			case transport.QUIC, transport.TLS, transport.DNS:
+				f.fallbacks = append(f.fallbacks, args[i])
+			case "ttl":
+				if i != len(args)-2 {
+					return c.ArgErr()
+				}
+				i++
+				dur, err := time.ParseDuration(args[i])
+				if err != nil {
+					return err
+				}
+				if dur <= 0 {
+					return fmt.Errorf("fallback: ttl must be positive: %s", dur)
+				}
+				f.fallbackTTL = dur
+			default:
+				return fmt.Errorf("fallback: '%s' is not supported as a fallback transport", args[i])
+			}
+		}
+		if len(f.fallbacks) == 0 {
+			return c.ArgErr()
+		}
+	case "race":
+		f.race = defaultRaceStagger
+		args := c.RemainingArgs()
+		if len(args) > 1 {
+			return c.ArgErr()
+		}
+		if len(args) == 1 {
+			dur, err := time.ParseDuration(args[0])
+			if err != nil {
+				return err
+			}
+			if dur <= 0 {
+				return fmt.Errorf("race: stagger must be positive: %s", dur)
+			}
+			f.race = dur
+		}
 
 	default:
 		return c.Errf("unknown property '%s'", c.Val())
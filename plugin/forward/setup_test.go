@@ -1,6 +1,7 @@
 package forward
 
 import (
+	"crypto/tls"
 	"os"
 	"reflect"
 	"strings"
@@ -9,6 +10,7 @@ import (
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin/pkg/proxy"
+	"github.com/coredns/coredns/plugin/pkg/transport"
 
 	"github.com/miekg/dns"
 )
@@ -31,6 +33,14 @@ func TestSetup(t *testing.T) {
 		{"forward . 127.0.0.1 {\nforce_tcp\n}\n", false, ".", nil, 2, proxy.Options{ForceTCP: true, HCRecursionDesired: true, HCDomain: "."}, ""},
 		{"forward . 127.0.0.1 {\nprefer_udp\n}\n", false, ".", nil, 2, proxy.Options{PreferUDP: true, HCRecursionDesired: true, HCDomain: "."}, ""},
 		{"forward . 127.0.0.1 {\nforce_tcp\nprefer_udp\n}\n", false, ".", nil, 2, proxy.Options{PreferUDP: true, ForceTCP: true, HCRecursionDesired: true, HCDomain: "."}, ""},
+		{"forward . 127.0.0.1 {\nclient_address c2VjcmV0\n}\n", false, ".", nil, 2, proxy.Options{ClientAddrKey: "secret", HCRecursionDesired: true, HCDomain: "."}, ""},
+		{"forward . 127.0.0.1 {\ndedup\n}\n", false, ".", nil, 2, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, ""},
+		{"forward . 127.0.0.1 {\nfallback tls\n}\n", false, ".", nil, 2, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, ""},
+		{"forward . 127.0.0.1 {\nfallback quic tls ttl 1m\n}\n", false, ".", nil, 2, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, ""},
+		{"forward . h3://127.0.0.1 {\ntls_servername dns.example.org\n}\n", false, ".", nil, 2, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, ""},
+		{"forward . 127.0.0.1 {\nfallback tls\nrace\n}\n", false, ".", nil, 2, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, ""},
+		{"forward . 127.0.0.1 {\nrace 20ms\n}\n", false, ".", nil, 2, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, ""},
+		{"forward . 127.0.0.1 {\nmax_streams 100\n}\n", false, ".", nil, 2, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, ""},
 		{"forward . 127.0.0.1:53", false, ".", nil, 2, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, ""},
 		{"forward . 127.0.0.1:8080", false, ".", nil, 2, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, ""},
 		{"forward . [::1]:53", false, ".", nil, 2, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, ""},
@@ -42,6 +52,15 @@ func TestSetup(t *testing.T) {
 		// negative
 		{"forward . a27.0.0.1", true, "", nil, 0, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, "not an IP"},
 		{"forward . 127.0.0.1 {\nblaatl\n}\n", true, "", nil, 0, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, "unknown property"},
+		{"forward . 127.0.0.1 {\nclient_address !!\n}\n", true, "", nil, 0, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, "invalid client_address key"},
+		{"forward . 127.0.0.1 {\ndedup yes\n}\n", true, "", nil, 0, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, "Wrong argument count"},
+		{"forward . 127.0.0.1 {\nfallback\n}\n", true, "", nil, 0, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, "Wrong argument count"},
+		{"forward . 127.0.0.1 {\nfallback grpc\n}\n", true, "", nil, 0, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, "not supported as a fallback transport"},
+		{"forward . 127.0.0.1 {\nfallback tls ttl\n}\n", true, "", nil, 0, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, "Wrong argument count"},
+		{"forward . 127.0.0.1 {\nfallback tls ttl -1s\n}\n", true, "", nil, 0, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, "ttl must be positive"},
+		{"forward . 127.0.0.1 {\nrace 10ms 20ms\n}\n", true, "", nil, 0, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, "Wrong argument count"},
+		{"forward . 127.0.0.1 {\nmax_streams -1\n}\n", true, "", nil, 0, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, "can't be negative"},
+		{"forward . 127.0.0.1 {\nrace 0s\n}\n", true, "", nil, 0, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, "stagger must be positive"},
 		{"forward . 127.0.0.1 {\nhealth_check 0.5s domain\n}\n", true, "", nil, 0, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, "Wrong argument count or unexpected line ending after 'domain'"},
 		{"forward . https://127.0.0.1 \n", true, ".", nil, 2, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, "'https' is not supported as a destination protocol in forward: https://127.0.0.1"},
 		{"forward xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx 127.0.0.1 \n", true, ".", nil, 2, proxy.Options{HCRecursionDesired: true, HCDomain: "."}, "unable to normalize 'xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx'"},
@@ -134,6 +153,150 @@ func TestSetupTLS(t *testing.T) {
 	}
 }
 
+func TestSetupFallback(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"forward . squic://19-ffaa:1:fe4,[10.0.0.1]:8853 {\nfallback quic tls dns\n}\n", []string{"10.0.0.1:8853", "10.0.0.1:853", "10.0.0.1:53"}},
+		{"forward . tls://10.0.0.1 {\nfallback tls dns\n}\n", []string{"10.0.0.1:53"}},
+		{"forward . [2003::1]:5353 {\nfallback tls\n}\n", []string{"[2003::1]:853"}},
+		{"forward . 10.0.0.1", nil},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		fs, err := parseForward(c)
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		var addrs []string
+		for _, p := range fs[0].proxies[0].Fallbacks() {
+			addrs = append(addrs, p.Addr())
+		}
+		if !reflect.DeepEqual(addrs, test.expected) {
+			t.Errorf("Test %d: expected fallbacks %v, got %v", i, test.expected, addrs)
+		}
+	}
+}
+
+func TestSetupUpstreamTLS(t *testing.T) {
+	input := `forward . squic://19-ffaa:1:fe4,[10.0.0.1]:8853 tls://10.0.0.2 10.0.0.3 {
+tls_servername dns.example.net
+upstream_tls_servername squic://19-ffaa:1:fe4,[10.0.0.1]:8853 ns.example.org
+upstream_tls tls://10.0.0.2
+fallback tls
+}
+`
+	c := caddy.NewTestController("dns", input)
+	fs, err := parseForward(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	f := fs[0]
+
+	for i, expected := range []string{"ns.example.org", "dns.example.net"} {
+		cfg := f.proxies[i].GetHealthchecker().GetTLSConfig()
+		if cfg.ServerName != expected {
+			t.Errorf("Test %d: expected server name %q, got %q", i, expected, cfg.ServerName)
+		}
+		if cfg == f.tlsConfig {
+			t.Errorf("Test %d: expected a TLS config of its own", i)
+		}
+	}
+	if f.tlsConfig.ServerName != "dns.example.net" {
+		t.Errorf("Expected the server name of the other upstreams to be unchanged, got %q", f.tlsConfig.ServerName)
+	}
+	fb := f.proxies[0].Fallbacks()[0].GetHealthchecker().GetTLSConfig()
+	if fb.ServerName != "ns.example.org" {
+		t.Errorf("Expected the fallback to use the server name of its upstream, got %q", fb.ServerName)
+	}
+
+	for i, input := range []string{
+		"forward . 10.0.0.1 {\nupstream_tls_servername 10.0.0.2 ns.example.org\n}\n",
+		"forward . 10.0.0.1 {\nupstream_tls_servername 10.0.0.1\n}\n",
+		"forward . 10.0.0.1 {\nupstream_tls 10.0.0.1 a b c d\n}\n",
+	} {
+		c := caddy.NewTestController("dns", input)
+		if _, err := parseForward(c); err == nil {
+			t.Errorf("Test %d: expected an error", i)
+		}
+	}
+}
+
+func TestSetupUpstreamALPN(t *testing.T) {
+	c := caddy.NewTestController("dns", "forward . quic://10.0.0.1 tls://10.0.0.2 h3://10.0.0.3 {\ntls_servername dns.example.net\n}\n")
+	fs, err := parseForward(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	f := fs[0]
+
+	for i, expected := range [][]string{transport.DoQALPNs, nil, nil} {
+		cfg := f.proxies[i].GetHealthchecker().GetTLSConfig()
+		if !reflect.DeepEqual(cfg.NextProtos, expected) {
+			t.Errorf("Test %d: expected ALPNs %v, got %v", i, expected, cfg.NextProtos)
+		}
+	}
+	if f.tlsConfig.NextProtos != nil {
+		t.Errorf("Expected the shared TLS config to be unchanged, got ALPNs %v", f.tlsConfig.NextProtos)
+	}
+}
+
+func TestSetupUpstreamTLSTRC(t *testing.T) {
+	input := `forward . squic://19-ffaa:1:fe4,[10.0.0.1]:8853 tls://10.0.0.2 {
+upstream_tls_trc squic://19-ffaa:1:fe4,[10.0.0.1]:8853 ../pkg/scion/testdata/trc
+}
+`
+	c := caddy.NewTestController("dns", input)
+	fs, err := parseForward(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	f := fs[0]
+
+	cfg := f.proxies[0].GetHealthchecker().GetTLSConfig()
+	if !cfg.InsecureSkipVerify || cfg.VerifyConnection == nil {
+		t.Error("Expected the squic upstream to be verified against the TRCs")
+	}
+	if err := cfg.VerifyConnection(tls.ConnectionState{}); err == nil {
+		t.Error("Expected an error without a certificate chain")
+	}
+	cfg = f.proxies[1].GetHealthchecker().GetTLSConfig()
+	if cfg.InsecureSkipVerify || cfg.VerifyConnection != nil {
+		t.Error("Expected the other upstream to be verified against the root CAs")
+	}
+
+	// An upstream over IP is only verified against the TRCs with the ISD-AS it must have.
+	c = caddy.NewTestController("dns", "forward . tls://10.0.0.2 {\nupstream_tls_trc tls://10.0.0.2 ../pkg/scion/testdata/trc 19-ffaa:1:fe4\n}\n")
+	fs, err = parseForward(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if cfg := fs[0].proxies[0].GetHealthchecker().GetTLSConfig(); cfg.VerifyConnection == nil {
+		t.Error("Expected the tls upstream with an ISD-AS to be verified against the TRCs")
+	}
+
+	for i, input := range []string{
+		"forward . 10.0.0.1 {\ntls_trc\n}\n",
+		"forward . 10.0.0.1 {\ntls_trc testdata/missing\n}\n",
+		"forward . 10.0.0.1 {\nupstream_tls_trc 10.0.0.2 ../pkg/scion/testdata/trc\n}\n",
+		"forward . 10.0.0.1 {\nupstream_tls_trc 10.0.0.1\n}\n",
+		// Upstreams over IP without an ISD-AS.
+		"forward . tls://10.0.0.2 {\ntls_trc ../pkg/scion/testdata/trc\n}\n",
+		"forward . squic://19-ffaa:1:fe4,[10.0.0.1]:8853 quic://10.0.0.2 {\ntls_trc ../pkg/scion/testdata/trc\n}\n",
+		"forward . tls://10.0.0.2 {\nupstream_tls_trc tls://10.0.0.2 ../pkg/scion/testdata/trc\n}\n",
+		"forward . tls://10.0.0.2 {\nupstream_tls_trc tls://10.0.0.2 ../pkg/scion/testdata/trc 19\n}\n",
+		// Another ISD-AS than the one in the address.
+		"forward . squic://19-ffaa:1:fe4,[10.0.0.1]:8853 {\nupstream_tls_trc squic://19-ffaa:1:fe4,[10.0.0.1]:8853 ../pkg/scion/testdata/trc 19-ffaa:1:fe5\n}\n",
+	} {
+		c := caddy.NewTestController("dns", input)
+		if _, err := parseForward(c); err == nil {
+			t.Errorf("Test %d: expected an error", i)
+		}
+	}
+}
+
 func TestSetupResolvconf(t *testing.T) {
 	const resolv = "resolv.conf"
 	if err := os.WriteFile(resolv,
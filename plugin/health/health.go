@@ -3,12 +3,14 @@ package health
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/url"
 	"time"
 
+	"github.com/coredns/coredns/core/dnsserver"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/reuseport"
 )
@@ -55,7 +57,14 @@ func (h *health) OnStartup() error {
 	h.nlSetup = true
 
 	h.mux.HandleFunc(h.healthURI.Path, func(w http.ResponseWriter, r *http.Request) {
-		// We're always healthy.
+		// We're healthy, unless a DoQ listener stopped accepting connections or failed its self check.
+		if failed := dnsserver.FailedEndpoints(); len(failed) > 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			for _, ep := range failed {
+				fmt.Fprintf(w, "%s://%s: %s\n", ep.Transport, ep.Addr, ep.Err)
+			}
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 		io.WriteString(w, http.StatusText(http.StatusOK))
 	})
@@ -7,6 +7,7 @@ import (
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/dnsutil"
 	"github.com/coredns/coredns/plugin/pkg/fall"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 	"github.com/coredns/coredns/request"
 	"github.com/netsec-ethz/scion-apps/pkg/pan"
 
@@ -39,7 +40,16 @@ func (h Hosts) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (
 
 	switch state.QType() {
 	case dns.TypePTR:
-		names := h.LookupStaticAddr(dnsutil.ExtractAddressFromReverse(qname))
+		var addr string
+		if dnsutil.IsReverse(qname) == 3 {
+			var err error
+			if addr, err = dnsutil.UnReverseSCION(qname); err != nil {
+				log.Debugf("Ignoring PTR query: %s", err)
+			}
+		} else {
+			addr = dnsutil.ExtractAddressFromReverse(qname)
+		}
+		names := h.LookupStaticAddr(addr)
 		if len(names) == 0 {
 			// If this doesn't match we need to fall through regardless of h.Fallthrough
 			return plugin.NextOrFailure(h.Name(), h.Next, ctx, w, r)
@@ -52,8 +62,11 @@ func (h Hosts) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (
 		ips := h.LookupStaticHostV6(qname)
 		answers = aaaa(qname, h.options.ttl, ips)
 	case dns.TypeTXT:
-		ips := h.LookupStaticSCIONHost(qname)
-		answers = txt(qname, h.options.ttl, ips)
+		addrs := h.LookupStaticSCIONHost(qname)
+		answers = txt(qname, h.options.ttl, addrs)
+	case pkgscion.TypeSCION:
+		addrs := h.LookupStaticSCIONHost(qname)
+		answers = scion(qname, h.options.ttl, addrs)
 	}
 
 	// Only on NXDOMAIN we will fallthrough.
@@ -83,6 +96,9 @@ func (h Hosts) otherRecordsExist(qname string) bool {
 	if len(h.LookupStaticHostV6(qname)) > 0 {
 		return true
 	}
+	if len(h.LookupStaticSCIONHost(qname)) > 0 {
+		return true
+	}
 	return false
 }
 
@@ -113,18 +129,45 @@ func aaaa(zone string, ttl uint32, ips []net.IP) []dns.RR {
 	return answers
 }
 
-// txt takes a slice of net.IPs and returns a slice of TXT RRs.
-func txt(zone string, ttl uint32, ips []pan.UDPAddr) []dns.RR {
-	answers := make([]dns.RR, len(ips))
-	for i, ip := range ips {
+// txt takes a slice of SCION addresses and returns a slice of TXT RRs of the form scion=ISD-AS,[IP], the
+// form SCION resolvers look up.
+func txt(zone string, ttl uint32, addrs []pan.UDPAddr) []dns.RR {
+	answers := make([]dns.RR, 0, len(addrs))
+	for _, addr := range addrs {
+		s, err := scionRdata(addr)
+		if err != nil {
+			continue
+		}
 		r := new(dns.TXT)
 		r.Hdr = dns.RR_Header{Name: zone, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: ttl}
-		r.Txt = []string{ip.String()} // maybe prepend "scion=" prefix back here again
-		answers[i] = r
+		r.Txt = []string{s.TXT()}
+		answers = append(answers, r)
 	}
 	return answers
 }
 
+// scion takes a slice of SCION addresses and returns a slice of SCION RRs.
+func scion(zone string, ttl uint32, addrs []pan.UDPAddr) []dns.RR {
+	answers := make([]dns.RR, 0, len(addrs))
+	for _, addr := range addrs {
+		s, err := scionRdata(addr)
+		if err != nil {
+			continue
+		}
+		answers = append(answers, pkgscion.NewSCION(zone, ttl, s.IA, s.IP))
+	}
+	return answers
+}
+
+// scionRdata returns the SCION record data for addr, its port is dropped.
+func scionRdata(addr pan.UDPAddr) (*pkgscion.SCION, error) {
+	ia, err := pkgscion.ParseIA(addr.IA.String())
+	if err != nil {
+		return nil, err
+	}
+	return &pkgscion.SCION{IA: ia, IP: net.ParseIP(addr.IP.String())}, nil
+}
+
 // ptr takes a slice of host names and filters out the ones that aren't in Origins, if specified, and returns a slice of PTR RRs.
 func (h *Hosts) ptr(zone string, ttl uint32, names []string) []dns.RR {
 	answers := make([]dns.RR, len(names))
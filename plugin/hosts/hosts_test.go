@@ -7,6 +7,7 @@ import (
 
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
 	"github.com/coredns/coredns/plugin/pkg/fall"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 	"github.com/coredns/coredns/plugin/test"
 
 	"github.com/miekg/dns"
@@ -107,6 +108,40 @@ var hostsTestCases = []test.Case{
 		Qname: "fallthrough-example.org.", Qtype: dns.TypeAAAA,
 		Answer: []dns.RR{}, Rcode: dns.RcodeSuccess,
 	},
+	{
+		Qname: "ns1.example.org.", Qtype: dns.TypeTXT,
+		Answer: []dns.RR{
+			test.TXT(`ns1.example.org. 3600 IN TXT "scion=19-ffaa:1:1067,[127.0.0.1]"`),
+		},
+	},
+	{
+		Qname: "ns1.example.org.", Qtype: dns.TypeA,
+		Answer: []dns.RR{},
+	},
+}
+
+func TestLookupSCION(t *testing.T) {
+	h := Hosts{
+		Next: test.NextHandler(dns.RcodeNameError, nil),
+		Hostsfile: &Hostsfile{
+			Origins: []string{"."},
+			hmap:    newMap(),
+			inline:  newMap(),
+			options: newOptions(),
+		},
+	}
+	h.hmap = h.parse(strings.NewReader(hostsExample))
+
+	m := new(dns.Msg)
+	m.SetQuestion("ns2.example.org.", pkgscion.TypeSCION)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := h.ServeDNS(context.Background(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := "ns2.example.org.\t3600\tIN\tSCION\t19-ffaa:1:1067,[2001:db8::2]"
+	if len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].String() != expected {
+		t.Errorf("Expected %q, got %v", expected, rec.Msg.Answer)
+	}
 }
 
 const hostsExample = `
@@ -115,6 +150,8 @@ const hostsExample = `
 10.0.0.1 example.org
 ::FFFF:10.0.0.2 example.com
 10.0.0.3 fallthrough-example.org
+19-ffaa:1:1067,[127.0.0.1] ns1.example.org
+19-ffaa:1:1067,[2001:db8::2]:8853 ns2.example.org
 reload 5s
 timeout 3600
 `
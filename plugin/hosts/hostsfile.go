@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
 	"github.com/netsec-ethz/scion-apps/pkg/pan"
 )
 
@@ -71,7 +72,7 @@ func newMap() *Map {
 	}
 }
 
-// Len returns the total number of addresses in the hostmap, this includes V4/V6 and any reverse addresses.
+// Len returns the total number of addresses in the hostmap, this includes V4/V6, SCION and any reverse addresses.
 func (h *Map) Len() int {
 	l := 0
 	for _, v4 := range h.name4 {
@@ -80,6 +81,9 @@ func (h *Map) Len() int {
 	for _, v6 := range h.name6 {
 		l += len(v6)
 	}
+	for _, s := range h.scion {
+		l += len(s)
+	}
 	for _, a := range h.addr {
 		l += len(a)
 	}
@@ -173,6 +177,7 @@ func (h *Hostsfile) parse(r io.Reader) *Map {
 		family := 0
 		addr := parseIP(string(f[0]))
 		var saddr pan.UDPAddr
+		var svc dnsutil.SVCAddr
 		var er error
 		if addr != nil {
 			if addr.To4() != nil {
@@ -194,6 +199,9 @@ func (h *Hostsfile) parse(r io.Reader) *Map {
 					family = 4
 				}
 
+			} else if svc, er = dnsutil.ParseSCIONServiceAddress(raw); er == nil {
+				// Service addresses only have names for reverse lookups.
+				family = 5
 			} else { // address is garbage
 				continue
 			}
@@ -212,6 +220,7 @@ func (h *Hostsfile) parse(r io.Reader) *Map {
 				hmap.name6[name] = append(hmap.name6[name], addr)
 			case 3, 4:
 				hmap.scion[name] = append(hmap.scion[name], saddr)
+			case 5:
 			default:
 				continue
 			}
@@ -223,6 +232,8 @@ func (h *Hostsfile) parse(r io.Reader) *Map {
 				hmap.addr[addr.String()] = append(hmap.addr[addr.String()], name)
 			case 3, 4:
 				hmap.addr[saddr.String()] = append(hmap.addr[saddr.String()], name)
+			case 5:
+				hmap.addr[svc.String()] = append(hmap.addr[svc.String()], name)
 			}
 
 		}
@@ -282,6 +293,7 @@ func (h *Hostsfile) LookupStaticHostV6(host string) []net.IP {
 	return append(ip1, ip2...)
 }
 
+// LookupStaticSCIONHost looks up the SCION addresses for the given host from the hosts file.
 func (h *Hostsfile) LookupStaticSCIONHost(host string) []pan.UDPAddr {
 	host = strings.ToLower(host)
 	ip1 := h.lookupStaticSCIONHost(h.hmap.scion, host)
@@ -298,6 +310,8 @@ func (h *Hostsfile) LookupStaticAddr(addr string) []string {
 	} else {
 		if a, err := pan.ParseUDPAddr(addr); err == nil {
 			addr = a.String()
+		} else if svc, err := dnsutil.ParseSCIONServiceAddress(addr); err == nil {
+			addr = svc.String()
 		} else {
 			return nil
 		}
@@ -65,6 +65,11 @@ var (
 	fe80::3%lo0					localhost	localhost.localdomain`
 	casehosts = `127.0.0.1	PreserveMe	PreserveMe.local
 		::1		PreserveMe	PreserveMe.local`
+	svchosts = `19-ffaa:1:1067,CS	cs.example.org
+	19-ffaa:1:1067,ds_a	ds.example.org	discovery.example.org
+	# Multicast and unknown services must be ignored.
+	19-ffaa:1:1067,CS_M	loki
+	19-ffaa:1:1067,XY	loki`
 )
 
 var lookupStaticHostTests = []struct {
@@ -194,6 +199,15 @@ var lookupStaticAddrTests = []struct {
 			{"::1", []string{"PreserveMe.", "PreserveMe.local."}},
 		},
 	},
+	{
+		svchosts,
+		[]staticIPEntry{
+			{"19-ffaa:1:1067,CS", []string{"cs.example.org."}},
+			{"19-ffaa:1:1067,cs", []string{"cs.example.org."}},
+			{"19-ffaa:1:1067,DS", []string{"ds.example.org.", "discovery.example.org."}},
+			{"19-ffaa:1:1067,Wildcard", nil},
+		},
+	},
 }
 
 func TestLookupStaticAddr(t *testing.T) {
@@ -709,8 +709,8 @@ func serviceModified(oldObj, newObj interface{}) (intSvc, extSvc bool) {
 		}
 	}
 
-	// ExternalName is mutable, affecting internal zone records
-	intSvc = oldSvc.ExternalName != newSvc.ExternalName
+	// ExternalName and the SCION address are mutable, affecting internal zone records
+	intSvc = oldSvc.ExternalName != newSvc.ExternalName || oldSvc.SCION != newSvc.SCION
 
 	if intSvc && extSvc {
 		return intSvc, extSvc
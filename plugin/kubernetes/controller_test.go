@@ -215,6 +215,12 @@ func TestServiceModified(t *testing.T) {
 			ichanged: true,
 			echanged: false,
 		},
+		{
+			oldSvc:   &object.Service{SCION: "19-ffaa:1:1067,[10.0.0.1]"},
+			newSvc:   &object.Service{SCION: "19-ffaa:1:1068,[10.0.0.1]"},
+			ichanged: true,
+			echanged: false,
+		},
 		{
 			oldSvc:   &object.Service{Ports: []api.ServicePort{{Name: "test1"}}},
 			newSvc:   &object.Service{Ports: []api.ServicePort{{Name: "test2"}}},
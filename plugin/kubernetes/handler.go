@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/coredns/coredns/plugin"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -35,6 +36,17 @@ func (k Kubernetes) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.M
 		records, truncated, err = plugin.AAAA(ctx, &k, zone, state, nil, plugin.Options{})
 	case dns.TypeTXT:
 		records, truncated, err = plugin.TXT(ctx, &k, zone, state, nil, plugin.Options{})
+		if err == nil {
+			records = append(records, k.scionRecords(state, dns.TypeTXT)...)
+		}
+	case pkgscion.TypeSCION:
+		// Do a fake A lookup, so we can distinguish between NODATA and NXDOMAIN
+		fake := state.NewWithQuestion(state.QName(), dns.TypeA)
+		fake.Zone = state.Zone
+		_, _, err = plugin.A(ctx, &k, zone, fake, nil, plugin.Options{})
+		if err == nil {
+			records = k.scionRecords(state, pkgscion.TypeSCION)
+		}
 	case dns.TypeCNAME:
 		records, err = plugin.CNAME(ctx, &k, zone, state, plugin.Options{})
 	case dns.TypePTR:
@@ -16,6 +16,8 @@ type Pod struct {
 	PodIP     string
 	Name      string
 	Namespace string
+	// SCION is the SCION address from the SCIONAnnotation, if any.
+	SCION string
 
 	*Empty
 }
@@ -33,6 +35,7 @@ func ToPod(obj meta.Object) (meta.Object, error) {
 		PodIP:     apiPod.Status.PodIP,
 		Namespace: apiPod.GetNamespace(),
 		Name:      apiPod.GetName(),
+		SCION:     apiPod.GetAnnotations()[SCIONAnnotation],
 	}
 	t := apiPod.ObjectMeta.DeletionTimestamp
 	if t != nil && !(*t).Time.IsZero() {
@@ -55,6 +58,7 @@ func (p *Pod) DeepCopyObject() runtime.Object {
 		PodIP:     p.PodIP,
 		Namespace: p.Namespace,
 		Name:      p.Name,
+		SCION:     p.SCION,
 	}
 	return p1
 }
@@ -23,9 +23,16 @@ type Service struct {
 	// ExternalIPs we may want to export.
 	ExternalIPs []string
 
+	// SCION is the SCION address from the SCIONAnnotation, if any.
+	SCION string
+
 	*Empty
 }
 
+// SCIONAnnotation is the annotation of services and pods that holds their SCION address, as in
+// "19-ffaa:1:1067,[10.0.0.1]".
+const SCIONAnnotation = "scion.coredns.io/address"
+
 // ServiceKey returns a string using for the index.
 func ServiceKey(name, namespace string) string { return name + "." + namespace }
 
@@ -42,6 +49,7 @@ func ToService(obj meta.Object) (meta.Object, error) {
 		Index:        ServiceKey(svc.GetName(), svc.GetNamespace()),
 		Type:         svc.Spec.Type,
 		ExternalName: svc.Spec.ExternalName,
+		SCION:        svc.GetAnnotations()[SCIONAnnotation],
 
 		ExternalIPs: make([]string, len(svc.Status.LoadBalancer.Ingress)+len(svc.Spec.ExternalIPs)),
 	}
@@ -91,6 +99,7 @@ func (s *Service) DeepCopyObject() runtime.Object {
 		Index:        s.Index,
 		Type:         s.Type,
 		ExternalName: s.ExternalName,
+		SCION:        s.SCION,
 		ClusterIPs:   make([]string, len(s.ClusterIPs)),
 		Ports:        make([]api.ServicePort, len(s.Ports)),
 		ExternalIPs:  make([]string, len(s.ExternalIPs)),
@@ -0,0 +1,70 @@
+package kubernetes
+
+import (
+	"strings"
+
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	api "k8s.io/api/core/v1"
+)
+
+// scionRecords returns the SCION address records of the service or pod in state, as SCION records, or
+// as TXT records "scion=ISD-AS,[IP]" if qtype is TXT. The SCION addresses come from the
+// object.SCIONAnnotation of the service, or of the pod in the verified pod mode. Addresses that don't
+// parse are skipped.
+func (k *Kubernetes) scionRecords(state request.Request, qtype uint16) []dns.RR {
+	r, err := parseRequest(state.Name(), state.Zone)
+	if err != nil || r.port != "" || r.protocol != "" || r.endpoint != "" || r.service == "" {
+		return nil
+	}
+	if !k.namespaceExposed(r.namespace) {
+		return nil
+	}
+
+	var addrs []string
+	switch r.podOrSvc {
+	case Svc:
+		for _, svc := range k.APIConn.SvcIndex(object.ServiceKey(r.service, r.namespace)) {
+			if svc.SCION == "" || svc.Type == api.ServiceTypeExternalName {
+				continue
+			}
+			if match(r.namespace, svc.Namespace) && match(r.service, svc.Name) {
+				addrs = append(addrs, svc.SCION)
+			}
+		}
+	case Pod:
+		if k.podMode != podModeVerified {
+			return nil
+		}
+		ip := strings.ReplaceAll(r.service, "-", ":")
+		if strings.Count(r.service, "-") == 3 && !strings.Contains(r.service, "--") {
+			ip = strings.ReplaceAll(r.service, "-", ".")
+		}
+		for _, p := range k.APIConn.PodIndex(ip) {
+			if p.SCION != "" && ip == p.PodIP && match(r.namespace, p.Namespace) {
+				addrs = append(addrs, p.SCION)
+			}
+		}
+	}
+
+	var records []dns.RR
+	for _, addr := range addrs {
+		s := new(pkgscion.SCION)
+		if err := s.Parse([]string{addr}); err != nil {
+			log.Warningf("Ignoring the SCION address %q of %s: %s", addr, state.Name(), err)
+			continue
+		}
+		if qtype == dns.TypeTXT {
+			records = append(records, &dns.TXT{
+				Hdr: dns.RR_Header{Name: state.QName(), Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: k.ttl},
+				Txt: []string{s.TXT()},
+			})
+			continue
+		}
+		records = append(records, pkgscion.NewSCION(state.QName(), k.ttl, s.IA, s.IP))
+	}
+	return records
+}
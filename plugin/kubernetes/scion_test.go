@@ -0,0 +1,145 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	api "k8s.io/api/core/v1"
+)
+
+type APIConnSCIONTest struct {
+	APIConnServeTest
+}
+
+func (a APIConnSCIONTest) SvcIndex(s string) []*object.Service {
+	switch s {
+	case "svcscion.testns":
+		return []*object.Service{{
+			Name:       "svcscion",
+			Namespace:  "testns",
+			Type:       api.ServiceTypeClusterIP,
+			ClusterIPs: []string{"10.0.0.20"},
+			Ports: []api.ServicePort{
+				{Name: "http", Protocol: "tcp", Port: 80},
+			},
+			SCION: "19-ffaa:1:1067,[10.0.0.20]",
+		}}
+	case "svcbadscion.testns":
+		return []*object.Service{{
+			Name:       "svcbadscion",
+			Namespace:  "testns",
+			Type:       api.ServiceTypeClusterIP,
+			ClusterIPs: []string{"10.0.0.21"},
+			Ports: []api.ServicePort{
+				{Name: "http", Protocol: "tcp", Port: 80},
+			},
+			SCION: "10.0.0.21",
+		}}
+	}
+	return a.APIConnServeTest.SvcIndex(s)
+}
+
+func (a APIConnSCIONTest) PodIndex(ip string) []*object.Pod {
+	pods := a.APIConnServeTest.PodIndex(ip)
+	for _, p := range pods {
+		p.SCION = "19-ffaa:1:1068,[10.240.0.1]"
+	}
+	return pods
+}
+
+func scionRR(name, addr string) dns.RR {
+	s := new(pkgscion.SCION)
+	if err := s.Parse([]string{addr}); err != nil {
+		panic(err)
+	}
+	return pkgscion.NewSCION(name, 5, s.IA, s.IP)
+}
+
+var dnsSCIONCases = []test.Case{
+	{
+		Qname: "svcscion.testns.svc.cluster.local.", Qtype: pkgscion.TypeSCION,
+		Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			scionRR("svcscion.testns.svc.cluster.local.", "19-ffaa:1:1067,[10.0.0.20]"),
+		},
+	},
+	{
+		Qname: "svcscion.testns.svc.cluster.local.", Qtype: dns.TypeTXT,
+		Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.TXT(`svcscion.testns.svc.cluster.local.	5	IN	TXT	"scion=19-ffaa:1:1067,[10.0.0.20]"`),
+		},
+	},
+	{
+		Qname: "svcscion.testns.svc.cluster.local.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.A("svcscion.testns.svc.cluster.local.	5	IN	A	10.0.0.20"),
+		},
+	},
+	// Service without the annotation
+	{
+		Qname: "svc1.testns.svc.cluster.local.", Qtype: pkgscion.TypeSCION,
+		Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{
+			test.SOA("cluster.local.	5	IN	SOA	ns.dns.cluster.local. hostmaster.cluster.local. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// Service with an invalid annotation
+	{
+		Qname: "svcbadscion.testns.svc.cluster.local.", Qtype: pkgscion.TypeSCION,
+		Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{
+			test.SOA("cluster.local.	5	IN	SOA	ns.dns.cluster.local. hostmaster.cluster.local. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	{
+		Qname: "nosvc.testns.svc.cluster.local.", Qtype: pkgscion.TypeSCION,
+		Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.SOA("cluster.local.	5	IN	SOA	ns.dns.cluster.local. hostmaster.cluster.local. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	{
+		Qname: "10-240-0-1.podns.pod.cluster.local.", Qtype: pkgscion.TypeSCION,
+		Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			scionRR("10-240-0-1.podns.pod.cluster.local.", "19-ffaa:1:1068,[10.240.0.1]"),
+		},
+	},
+}
+
+func TestServeDNSSCION(t *testing.T) {
+	k := New([]string{"cluster.local."})
+	k.APIConn = &APIConnSCIONTest{}
+	k.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	k.podMode = podModeVerified
+	ctx := context.TODO()
+
+	for i, tc := range dnsSCIONCases {
+		r := tc.Msg()
+
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		_, err := k.ServeDNS(ctx, w, r)
+		if err != nil {
+			t.Errorf("Test %d expected no error, got %v", i, err)
+			return
+		}
+
+		resp := w.Msg
+		if resp == nil {
+			t.Fatalf("Test %d, got nil message and no error for %q", i, r.Question[0].Name)
+		}
+
+		if err := test.SortAndCheck(resp, tc); err != nil {
+			t.Errorf("Test %d, %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package local
+
+import (
+	"net"
+	"os"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// host holds the name and addresses of the host CoreDNS runs on, to answer queries for them.
+type host struct {
+	name string // fully qualified and lowercase, empty if unknown
+	ips  []net.IP
+}
+
+// newHost returns the host with the name from the kernel and the addresses of its interfaces.
+func newHost() *host {
+	h := &host{}
+	if name, err := os.Hostname(); err == nil && name != "" && name != "localhost" {
+		if _, ok := dns.IsDomainName(name); ok {
+			h.name = strings.ToLower(dns.Fqdn(name))
+		}
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		log.Warningf("Failed to get the addresses of the host: %s", err)
+		return h
+	}
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok || ipnet.IP.IsLoopback() || ipnet.IP.IsLinkLocalUnicast() || ipnet.IP.IsMulticast() {
+			continue
+		}
+		h.ips = append(h.ips, ipnet.IP)
+	}
+	return h
+}
+
+// scionAddrs returns the SCION addresses of the host, ISD-AS,[IP], if the local ISD-AS is configured
+// with the scion plugin.
+func (h *host) scionAddrs() []string {
+	ia := pkgscion.Get().LocalIA
+	if ia.IsZero() {
+		return nil
+	}
+	addrs := make([]string, len(h.ips))
+	for i, ip := range h.ips {
+		addrs[i] = ia.String() + ",[" + ip.String() + "]"
+	}
+	return addrs
+}
+
+// isReverse returns true if qname is the reverse name of one of the host's IP or SCION addresses.
+func (h *host) isReverse(qname string) bool {
+	for _, ip := range h.ips {
+		if rev, err := dns.ReverseAddr(ip.String()); err == nil && rev == qname {
+			return true
+		}
+	}
+	for _, addr := range h.scionAddrs() {
+		// ReverseSCIONAddr wants a port, it's not part of the reverse name.
+		if rev, err := dnsutil.ReverseSCIONAddr(addr + ":0"); err == nil && strings.ToLower(rev) == qname {
+			return true
+		}
+	}
+	return false
+}
+
+// reply returns the reply to state if it asks for the host's name or the reverse of one of its
+// addresses, or nil otherwise.
+func (h *host) reply(state request.Request) *dns.Msg {
+	if h.name == "" {
+		return nil
+	}
+	qname := state.Name()
+
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	m.Authoritative = true
+
+	if h.isReverse(qname) {
+		if state.QType() == dns.TypePTR {
+			hdr := dns.RR_Header{Name: state.QName(), Ttl: hostTTL, Class: dns.ClassINET, Rrtype: dns.TypePTR}
+			m.Answer = []dns.RR{&dns.PTR{Hdr: hdr, Ptr: h.name}}
+			return m
+		}
+		m.Ns = h.soa(state.QName())
+		return m
+	}
+	if qname != h.name {
+		return nil
+	}
+
+	hdr := dns.RR_Header{Name: state.QName(), Ttl: hostTTL, Class: dns.ClassINET, Rrtype: state.QType()}
+	switch state.QType() {
+	case dns.TypeA:
+		for _, ip := range h.ips {
+			if ip4 := ip.To4(); ip4 != nil {
+				m.Answer = append(m.Answer, &dns.A{Hdr: hdr, A: ip4})
+			}
+		}
+	case dns.TypeAAAA:
+		for _, ip := range h.ips {
+			if ip.To4() == nil {
+				m.Answer = append(m.Answer, &dns.AAAA{Hdr: hdr, AAAA: ip})
+			}
+		}
+	case dns.TypeTXT:
+		for _, addr := range h.scionAddrs() {
+			m.Answer = append(m.Answer, &dns.TXT{Hdr: hdr, Txt: []string{"scion=" + addr}})
+		}
+	}
+	if len(m.Answer) == 0 {
+		// nodata
+		m.Ns = h.soa(state.QName())
+	}
+	return m
+}
+
+func (h *host) soa(origin string) []dns.RR {
+	hdr := dns.RR_Header{Name: origin, Ttl: hostTTL, Class: dns.ClassINET, Rrtype: dns.TypeSOA}
+	return []dns.RR{&dns.SOA{Hdr: hdr, Ns: h.name, Mbox: "root." + h.name, Serial: 1, Minttl: hostTTL}}
+}
+
+// hostTTL is the TTL of the answers for the host, the addresses of the host may change, unlike localhost.
+const hostTTL = 60
@@ -17,6 +17,8 @@ var log = clog.NewWithPlugin("local")
 // Local is a plugin that returns standard replies for local queries.
 type Local struct {
 	Next plugin.Handler
+
+	host *host // if not nil, queries for the host's own name and addresses are answered as well
 }
 
 var zones = []string{"localhost.", "0.in-addr.arpa.", "127.in-addr.arpa.", "255.in-addr.arpa."}
@@ -46,6 +48,13 @@ func (l Local) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (
 		return 0, nil
 	}
 
+	if l.host != nil {
+		if m := l.host.reply(state); m != nil {
+			w.WriteMsg(m)
+			return 0, nil
+		}
+	}
+
 	zone := plugin.Zones(zones).Matches(qname)
 	if zone == "" {
 		return plugin.NextOrFailure(l.Name(), l.Next, ctx, w, r)
@@ -53,6 +62,7 @@ func (l Local) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (
 
 	m := new(dns.Msg)
 	m.SetReply(r)
+	m.Authoritative = true
 	zone = qname[len(qname)-len(zone):]
 
 	switch q := state.Name(); q {
@@ -110,6 +120,7 @@ func (l Local) Name() string { return "local" }
 func doLocalhost(state request.Request) *dns.Msg {
 	m := new(dns.Msg)
 	m.SetReply(state.Req)
+	m.Authoritative = true
 	switch state.QType() {
 	case dns.TypeA:
 		hdr := dns.RR_Header{Name: state.QName(), Ttl: ttl, Class: dns.ClassINET, Rrtype: dns.TypeA}
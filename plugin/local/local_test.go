@@ -2,9 +2,12 @@ package local
 
 import (
 	"context"
+	"net"
 	"testing"
 
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 	"github.com/coredns/coredns/plugin/test"
 
 	"github.com/miekg/dns"
@@ -75,3 +78,86 @@ func TestLocal(t *testing.T) {
 		}
 	}
 }
+
+func TestLocalHost(t *testing.T) {
+	ia, err := pkgscion.ParseIA("19-ffaa:1:1067")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := pkgscion.New()
+	d.LocalIA = ia
+	pkgscion.Set(d)
+	defer pkgscion.Reset()
+
+	scionReverse, err := dnsutil.ReverseSCIONAddr("19-ffaa:1:1067,[10.0.0.1]:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := &Local{
+		Next: test.NextHandler(dns.RcodeRefused, nil),
+		host: &host{name: "ns1.example.org.", ips: []net.IP{net.ParseIP("10.0.0.1").To4(), net.ParseIP("2001:db8::1")}},
+	}
+	tests := []struct {
+		question string
+		qtype    uint16
+		rcode    int
+		answer   []string
+	}{
+		{"ns1.example.org.", dns.TypeA, dns.RcodeSuccess, []string{"10.0.0.1"}},
+		{"NS1.example.org.", dns.TypeAAAA, dns.RcodeSuccess, []string{"2001:db8::1"}},
+		{"ns1.example.org.", dns.TypeTXT, dns.RcodeSuccess, []string{"scion=19-ffaa:1:1067,[10.0.0.1]", "scion=19-ffaa:1:1067,[2001:db8::1]"}},
+		{"ns1.example.org.", dns.TypeMX, dns.RcodeSuccess, nil},
+		{"1.0.0.10.in-addr.arpa.", dns.TypePTR, dns.RcodeSuccess, []string{"ns1.example.org."}},
+		{scionReverse, dns.TypePTR, dns.RcodeSuccess, []string{"ns1.example.org."}},
+		{"2.0.0.10.in-addr.arpa.", dns.TypePTR, dns.RcodeRefused, nil},
+		{"ns2.example.org.", dns.TypeA, dns.RcodeRefused, nil},
+	}
+
+	for i, tc := range tests {
+		req := new(dns.Msg)
+		req.SetQuestion(tc.question, tc.qtype)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		rcode, err := l.ServeDNS(context.TODO(), rec, req)
+		if err != nil {
+			t.Errorf("Test %d, expected no error, but got %q", i, err)
+			continue
+		}
+		if rcode == dns.RcodeRefused {
+			if tc.rcode != dns.RcodeRefused {
+				t.Errorf("Test %d, expected %s to be answered", i, tc.question)
+			}
+			continue
+		}
+		if tc.rcode == dns.RcodeRefused {
+			t.Errorf("Test %d, expected %s to be passed on, got %s", i, tc.question, rec.Msg)
+			continue
+		}
+		if !rec.Msg.Authoritative {
+			t.Errorf("Test %d, expected an authoritative answer", i)
+		}
+		if len(rec.Msg.Answer) != len(tc.answer) {
+			t.Errorf("Test %d, expected %d answers, got %d", i, len(tc.answer), len(rec.Msg.Answer))
+			continue
+		}
+		if len(tc.answer) == 0 && len(rec.Msg.Ns) == 0 {
+			t.Errorf("Test %d, expected a SOA record in the authority section", i)
+		}
+		for j, rr := range rec.Msg.Answer {
+			var value string
+			switch rr := rr.(type) {
+			case *dns.A:
+				value = rr.A.String()
+			case *dns.AAAA:
+				value = rr.AAAA.String()
+			case *dns.TXT:
+				value = rr.Txt[0]
+			case *dns.PTR:
+				value = rr.Ptr
+			}
+			if value != tc.answer[j] {
+				t.Errorf("Test %d, expected answer %s, got %s", i, tc.answer[j], value)
+			}
+		}
+	}
+}
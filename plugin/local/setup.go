@@ -9,7 +9,7 @@ import (
 func init() { plugin.Register("local", setup) }
 
 func setup(c *caddy.Controller) error {
-	l := Local{}
+	l := Local{host: newHost()}
 
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		l.Next = next
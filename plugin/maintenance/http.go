@@ -0,0 +1,104 @@
+package maintenance
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/reuseport"
+
+	"github.com/miekg/dns"
+)
+
+// The HTTP API:
+//
+//	GET    /zones       lists the disabled zones, one per line
+//	PUT    /zones/ZONE  disables ZONE
+//	DELETE /zones/ZONE  enables ZONE again
+const zonesPath = "/zones"
+
+func handler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, zonesPath), "/")
+	if name == "" {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		for _, z := range zones.list() {
+			fmt.Fprintln(w, z)
+		}
+		return
+	}
+
+	if _, ok := dns.IsDomainName(name); !ok {
+		http.Error(w, "invalid zone name", http.StatusBadRequest)
+		return
+	}
+	zone := plugin.Name(name).Normalize()
+
+	switch r.Method {
+	case http.MethodPut:
+		if !zones.disable(zone) {
+			http.Error(w, "zone "+zone+" is not served by a server block with maintenance", http.StatusNotFound)
+			return
+		}
+	case http.MethodDelete:
+		zones.enable(zone)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// servers holds the HTTP listeners, server blocks using the same address share one.
+var servers = struct {
+	sync.Mutex
+	m map[string]*server
+}{m: make(map[string]*server)}
+
+type server struct {
+	ln   net.Listener
+	refs int
+}
+
+// listen starts the HTTP API on addr, unless it is already running.
+func listen(addr string) error {
+	servers.Lock()
+	defer servers.Unlock()
+	if s, ok := servers.m[addr]; ok {
+		s.refs++
+		return nil
+	}
+
+	ln, err := reuseport.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(zonesPath, handler)
+	mux.HandleFunc(zonesPath+"/", handler)
+	go func() { http.Serve(ln, mux) }()
+
+	servers.m[addr] = &server{ln: ln, refs: 1}
+	return nil
+}
+
+// stop stops the HTTP API on addr once the last server block using it stopped.
+func stop(addr string) error {
+	servers.Lock()
+	defer servers.Unlock()
+	s, ok := servers.m[addr]
+	if !ok {
+		return nil
+	}
+	s.refs--
+	if s.refs > 0 {
+		return nil
+	}
+	delete(servers.m, addr)
+	return s.ln.Close()
+}
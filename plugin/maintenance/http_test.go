@@ -0,0 +1,39 @@
+package maintenance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	zones = newZoneSet()
+	zones.add("example.org.")
+
+	tests := []struct {
+		method         string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{http.MethodPut, "/zones/example.org", http.StatusNoContent, ""},
+		{http.MethodPut, "/zones/sub.Example.org.", http.StatusNoContent, ""},
+		{http.MethodPut, "/zones/example.net", http.StatusNotFound, ""},
+		{http.MethodGet, "/zones", http.StatusOK, "example.org.\nsub.example.org.\n"},
+		{http.MethodDelete, "/zones/example.org.", http.StatusNoContent, ""},
+		{http.MethodGet, "/zones/", http.StatusOK, "sub.example.org.\n"},
+		{http.MethodPost, "/zones/example.org.", http.StatusMethodNotAllowed, ""},
+		{http.MethodDelete, "/zones", http.StatusMethodNotAllowed, ""},
+	}
+	for i, tc := range tests {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(tc.method, tc.path, nil))
+
+		if rec.Code != tc.expectedStatus {
+			t.Errorf("Test %d: expected status %d, got %d", i, tc.expectedStatus, rec.Code)
+		}
+		if tc.expectedBody != "" && rec.Body.String() != tc.expectedBody {
+			t.Errorf("Test %d: expected body %q, got %q", i, tc.expectedBody, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Package maintenance implements a plugin that takes zones out of service at runtime.
+package maintenance
+
+import (
+	"context"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+var log = clog.NewWithPlugin("maintenance")
+
+// Maintenance answers queries for disabled zones with REFUSED.
+type Maintenance struct {
+	Next  plugin.Handler
+	Zones []string
+
+	// Addr is the address of the HTTP endpoint used to disable and enable zones.
+	Addr string
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (m Maintenance) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	zone := plugin.Zones(m.Zones).Matches(state.Name())
+	if zone == "" {
+		return plugin.NextOrFailure(m.Name(), m.Next, ctx, w, r)
+	}
+	disabled := zones.match(state.Name())
+	if disabled == "" {
+		return plugin.NextOrFailure(m.Name(), m.Next, ctx, w, r)
+	}
+
+	resp := new(dns.Msg)
+	resp.SetRcode(r, dns.RcodeRefused)
+	edns.SetExtendedError(resp, r, dns.ExtendedErrorCodeNotAuthoritative, "zone "+disabled+" is disabled")
+	w.WriteMsg(resp)
+
+	RefusedCount.WithLabelValues(metrics.WithServer(ctx), disabled).Inc()
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the plugin.Handler interface.
+func (m Maintenance) Name() string { return "maintenance" }
@@ -0,0 +1,61 @@
+package maintenance
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestMaintenance(t *testing.T) {
+	zones = newZoneSet()
+	zones.add("example.org.", "example.net.")
+	zones.disable("sub.example.org.")
+	zones.disable("example.net.")
+
+	m := Maintenance{Next: test.NextHandler(dns.RcodeSuccess, nil), Zones: []string{"example.org.", "example.net."}}
+
+	tests := []struct {
+		qname         string
+		expectedRcode int
+		expectedEDE   bool
+	}{
+		{"www.example.org.", dns.RcodeSuccess, false},
+		{"sub.example.org.", dns.RcodeRefused, true},
+		{"www.sub.example.org.", dns.RcodeRefused, true},
+		{"example.net.", dns.RcodeRefused, true},
+		{"example.com.", dns.RcodeSuccess, false},
+	}
+	for i, tc := range tests {
+		req := new(dns.Msg)
+		req.SetQuestion(tc.qname, dns.TypeA)
+		req.SetEdns0(4096, false)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		if _, err := m.ServeDNS(context.TODO(), rec, req); err != nil {
+			t.Fatalf("Test %d: expected no error, got %v", i, err)
+		}
+		if rec.Msg == nil {
+			// Passed on to the next plugin, which doesn't write.
+			if tc.expectedRcode != dns.RcodeSuccess {
+				t.Errorf("Test %d: expected rcode %d, got no response", i, tc.expectedRcode)
+			}
+			continue
+		}
+		if rec.Msg.Rcode != tc.expectedRcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, tc.expectedRcode, rec.Msg.Rcode)
+		}
+		opt := rec.Msg.IsEdns0()
+		if hasEDE := opt != nil && len(opt.Option) > 0; hasEDE != tc.expectedEDE {
+			t.Errorf("Test %d: expected extended error %t, got %t", i, tc.expectedEDE, hasEDE)
+		}
+	}
+
+	zones.enable("example.net.")
+	if d := zones.match("example.net."); d != "" {
+		t.Errorf("Expected example.net. to be enabled, still matches %s", d)
+	}
+}
@@ -0,0 +1,26 @@
+package maintenance
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// ZoneDisabled is 1 for zones that are disabled.
+	ZoneDisabled = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "maintenance",
+		Name:      "zone_disabled",
+		Help:      "Gauge that is 1 while a zone is disabled.",
+	}, []string{"zone"})
+
+	// RefusedCount is the number of queries refused because their zone is disabled.
+	RefusedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "maintenance",
+		Name:      "refused_total",
+		Help:      "Counter of queries refused because their zone is disabled.",
+	}, []string{"server", "zone"})
+)
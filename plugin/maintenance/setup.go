@@ -0,0 +1,70 @@
+package maintenance
+
+import (
+	"net"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+)
+
+func init() { plugin.Register("maintenance", setup) }
+
+// defaultAddr is where the HTTP API listens if no address is given, only reachable from the host itself.
+const defaultAddr = "localhost:8182"
+
+func setup(c *caddy.Controller) error {
+	m, err := parse(c)
+	if err != nil {
+		return plugin.Error("maintenance", err)
+	}
+
+	startup := func() error {
+		zones.add(m.Zones...)
+		return listen(m.Addr)
+	}
+	shutdown := func() error {
+		zones.reset()
+		return stop(m.Addr)
+	}
+	c.OnStartup(startup)
+	c.OnRestart(shutdown)
+	c.OnFinalShutdown(shutdown)
+	c.OnRestartFailed(startup)
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		m.Next = next
+		return m
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller) (Maintenance, error) {
+	m := Maintenance{Addr: defaultAddr}
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return m, plugin.ErrOnce
+		}
+		i++
+
+		args := c.RemainingArgs()
+		switch len(args) {
+		case 0:
+		case 1:
+			if _, _, err := net.SplitHostPort(args[0]); err != nil {
+				return m, c.Errf("invalid address '%s': %s", args[0], err)
+			}
+			m.Addr = args[0]
+		default:
+			return m, c.ArgErr()
+		}
+		if c.NextBlock() {
+			return m, c.Errf("unknown property '%s'", c.Val())
+		}
+	}
+	m.Zones = plugin.OriginsFromArgsOrServerBlock(nil, c.ServerBlockKeys)
+	return m, nil
+}
@@ -0,0 +1,50 @@
+package maintenance
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input              string
+		shouldErr          bool
+		expectedAddr       string
+		expectedErrContent string // substring from the expected error. Empty for positive cases.
+	}{
+		{`maintenance`, false, defaultAddr, ""},
+		{`maintenance localhost:9000`, false, "localhost:9000", ""},
+		{`maintenance :9000`, false, ":9000", ""},
+		// fails
+		{`maintenance localhost`, true, "", "invalid address"},
+		{`maintenance localhost:9000 localhost:9001`, true, "", "Wrong argument count"},
+		{`maintenance {
+			disable example.org
+		}`, true, "", "unknown property"},
+		{`maintenance
+		maintenance`, true, "", "this plugin"},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		m, err := parse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error but found none for input %s", i, test.input)
+		}
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			}
+			if !strings.Contains(err.Error(), test.expectedErrContent) {
+				t.Errorf("Test %d: Expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
+			}
+			continue
+		}
+		if m.Addr != test.expectedAddr {
+			t.Errorf("Test %d: Expected address %s, got %s", i, test.expectedAddr, m.Addr)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package maintenance
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/coredns/coredns/plugin"
+)
+
+// zones holds the disabled zones. It is shared by all server blocks and survives reloads, so a zone
+// taken out of service stays out of service until it is enabled again or CoreDNS is restarted.
+var zones = newZoneSet()
+
+type zoneSet struct {
+	sync.RWMutex
+	// known are the zones of all server blocks with the maintenance plugin, only these can be disabled.
+	known    map[string]struct{}
+	disabled map[string]struct{}
+}
+
+func newZoneSet() *zoneSet {
+	return &zoneSet{known: make(map[string]struct{}), disabled: make(map[string]struct{})}
+}
+
+// add makes the zones known.
+func (z *zoneSet) add(names ...string) {
+	z.Lock()
+	defer z.Unlock()
+	for _, n := range names {
+		z.known[n] = struct{}{}
+	}
+}
+
+// reset forgets the known zones, the disabled ones are kept.
+func (z *zoneSet) reset() {
+	z.Lock()
+	defer z.Unlock()
+	z.known = make(map[string]struct{})
+}
+
+// disable takes zone out of service, it returns false if zone isn't known.
+func (z *zoneSet) disable(zone string) bool {
+	z.Lock()
+	defer z.Unlock()
+	if !z.isKnown(zone) {
+		return false
+	}
+	if _, ok := z.disabled[zone]; !ok {
+		log.Infof("Zone %s disabled", zone)
+		ZoneDisabled.WithLabelValues(zone).Set(1)
+	}
+	z.disabled[zone] = struct{}{}
+	return true
+}
+
+// enable brings zone back into service.
+func (z *zoneSet) enable(zone string) {
+	z.Lock()
+	defer z.Unlock()
+	if _, ok := z.disabled[zone]; ok {
+		log.Infof("Zone %s enabled", zone)
+		ZoneDisabled.WithLabelValues(zone).Set(0)
+	}
+	delete(z.disabled, zone)
+}
+
+// isKnown returns true if zone is, or is below, a known zone. The caller must hold the lock.
+func (z *zoneSet) isKnown(zone string) bool {
+	for k := range z.known {
+		if plugin.Name(k).Matches(zone) {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns the most specific disabled zone qname falls in, or the empty string.
+func (z *zoneSet) match(qname string) string {
+	z.RLock()
+	defer z.RUnlock()
+	if len(z.disabled) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(z.disabled))
+	for n := range z.disabled {
+		names = append(names, n)
+	}
+	return plugin.Zones(names).Matches(qname)
+}
+
+// list returns the disabled zones, sorted.
+func (z *zoneSet) list() []string {
+	z.RLock()
+	defer z.RUnlock()
+	names := make([]string, 0, len(z.disabled))
+	for n := range z.disabled {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,29 @@
+package metadata
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+)
+
+// scionMetadata adds the labels describing the SCION client of a query received by a squic server.
+func scionMetadata(ctx context.Context) context.Context {
+	c, ok := dnsserver.SCIONClient(ctx)
+	if !ok {
+		return ctx
+	}
+	SetValueFunc(ctx, "scion/ia", func() string { return c.IA.String() })
+	SetValueFunc(ctx, "scion/isd", func() string { return strconv.FormatUint(uint64(c.IA.ISD), 10) })
+	SetValueFunc(ctx, "scion/as", func() string { return pkgscion.FormatAS(c.IA.AS) })
+	SetValueFunc(ctx, "scion/host", func() string { return c.Host })
+	SetValueFunc(ctx, "scion/path", func() string { return c.Path })
+	SetValueFunc(ctx, "scion/hops", func() string {
+		if c.Hops == 0 {
+			return ""
+		}
+		return strconv.Itoa(c.Hops)
+	})
+	return ctx
+}
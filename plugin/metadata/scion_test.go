@@ -0,0 +1,47 @@
+package metadata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestSCIONMetadata(t *testing.T) {
+	m := Metadata{Zones: []string{"."}}
+	r := new(dns.Msg)
+	r.SetQuestion("example.org.", dns.TypeA)
+	state := request.Request{W: &test.ResponseWriter{}, Req: r}
+
+	ctx := m.Collect(context.TODO(), state)
+	if f := ValueFunc(ctx, "scion/ia"); f != nil {
+		t.Errorf("Expected no scion/ia label for a query over IP, got %q", f())
+	}
+
+	ia, _ := pkgscion.ParseIA("19-ffaa:1:1067")
+	client := pkgscion.Client{IA: ia, Host: "10.0.0.1", Port: 40000, Path: "a1b2"}
+	ctx = m.Collect(context.WithValue(context.TODO(), dnsserver.SCIONClientKey{}, client), state)
+
+	for label, expected := range map[string]string{
+		"scion/ia":   "19-ffaa:1:1067",
+		"scion/isd":  "19",
+		"scion/as":   "ffaa:1:1067",
+		"scion/host": "10.0.0.1",
+		"scion/path": "a1b2",
+		"scion/hops": "",
+	} {
+		f := ValueFunc(ctx, label)
+		if f == nil {
+			t.Errorf("Expected label %s to be set", label)
+			continue
+		}
+		if got := f(); got != expected {
+			t.Errorf("Expected %s to be %q, got %q", label, expected, got)
+		}
+	}
+}
@@ -3,10 +3,12 @@ package metrics
 import (
 	"context"
 	"path/filepath"
+	"strings"
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metrics/vars"
 	"github.com/coredns/coredns/plugin/pkg/rcode"
+	"github.com/coredns/coredns/plugin/pkg/transport"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -35,6 +37,10 @@ func (m *Metrics) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 	}
 	plugin := m.authoritativePlugin(rw.Caller)
 	vars.Report(WithServer(ctx), state, zone, WithView(ctx), rcode.ToString(rc), plugin, rw.Len, rw.Start)
+	// Packing the response twice is only worth it for DoQ, where no_compression of the quic plugin applies.
+	if trans, _, _ := strings.Cut(WithServer(ctx), "://"); rw.Msg != nil && (trans == transport.QUIC || trans == transport.SQUIC) {
+		vars.ReportCompression(WithServer(ctx), trans, rw.Msg)
+	}
 
 	return status, err
 }
@@ -2,13 +2,17 @@ package metrics
 
 import (
 	"context"
+	"strings"
 	"testing"
 
+	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics/vars"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
 	"github.com/coredns/coredns/plugin/test"
 
 	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestMetrics(t *testing.T) {
@@ -80,3 +84,35 @@ func TestMetrics(t *testing.T) {
 		}
 	}
 }
+
+func TestMetricsCompression(t *testing.T) {
+	met := New("localhost:0")
+	met.AddZone("example.org.")
+	met.Next = test.HandlerFunc(func(_ context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Compress = true
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+
+	for _, tc := range []struct {
+		server   string
+		expected float64
+	}{
+		{"dns://:53", 0},
+		{"squic://:8853", 1},
+		{"quic://:853", 1},
+	} {
+		ctx := context.WithValue(context.TODO(), dnsserver.Key{}, &dnsserver.Server{Addr: tc.server})
+		req := new(dns.Msg)
+		req.SetQuestion("example.org.", dns.TypeA)
+		if _, err := met.ServeDNS(ctx, dnstest.NewRecorder(&test.ResponseWriter{}), req); err != nil {
+			t.Fatalf("Expected no error, but got %s", err)
+		}
+		trans, _, _ := strings.Cut(tc.server, "://")
+		if got := testutil.ToFloat64(vars.ResponseCompression.WithLabelValues(tc.server, trans, "no_gain")); got != tc.expected {
+			t.Errorf("Expected %v compressed responses for %s, got %v", tc.expected, tc.server, got)
+		}
+	}
+}
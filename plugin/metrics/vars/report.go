@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
 )
 
 // Report reports the metrics data associated with request. This function is exported because it is also
@@ -31,3 +33,22 @@ func Report(server string, req request.Request, zone, view, rcode, plugin string
 
 	ResponseRcode.WithLabelValues(server, zone, view, rcode, plugin).Inc()
 }
+
+// ReportCompression reports whether the response m is packed with name compression and how many bytes
+// compression saves on it, or would save if it is packed without.
+func ReportCompression(server, proto string, m *dns.Msg) {
+	plain, compressed := *m, *m
+	plain.Compress = false
+	compressed.Compress = true
+	saved := plain.Len() - compressed.Len()
+
+	outcome := "uncompressed"
+	if m.Compress {
+		outcome = "no_gain"
+		if saved > 0 {
+			outcome = "saved"
+		}
+	}
+	ResponseCompression.WithLabelValues(server, proto, outcome).Inc()
+	ResponseCompressionSavings.WithLabelValues(server, proto).Observe(float64(saved))
+}
@@ -47,6 +47,21 @@ var (
 		Buckets:   []float64{0, 100, 200, 300, 400, 511, 1023, 2047, 4095, 8291, 16e3, 32e3, 48e3, 64e3},
 	}, []string{"server", "zone", "view", "proto"})
 
+	ResponseCompression = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "response_compression_total",
+		Help:      "Counter of responses per transport and whether name compression was used and saved space.",
+	}, []string{"server", "proto", "outcome"})
+
+	ResponseCompressionSavings = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "response_compression_savings_bytes",
+		Help:      "Size in bytes name compression saves, or would save, on the returned response.",
+		Buckets:   []float64{0, 16, 32, 64, 128, 256, 512, 1024, 2048, 4096},
+	}, []string{"server", "proto"})
+
 	ResponseRcode = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: plugin.Namespace,
 		Subsystem: subsystem,
@@ -72,6 +87,128 @@ var (
 		Name:      "https_responses_total",
 		Help:      "Counter of DoH responses per server and http status code.",
 	}, []string{"server", "status"})
+
+	QUICIdleReapedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "quic_idle_reaped_total",
+		Help:      "Counter of DoQ connections closed by the server because they were idle.",
+	}, []string{"server"})
+
+	QUICAuthRejectedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "quic_auth_rejected_total",
+		Help:      "Counter of DoQ connections rejected by an authenticator.",
+	}, []string{"server"})
+
+	QUICEarlyQueriesCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "quic_early_queries_total",
+		Help:      "Counter of DoQ queries received before the handshake completed, i.e. in 0-RTT data.",
+	}, []string{"server"})
+
+	QUICRetryCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "quic_retries_total",
+		Help:      "Counter of new DoQ connections that had to validate their address with a Retry.",
+	}, []string{"server"})
+
+	QUICCertificateExpiry = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "quic_certificate_expiry_timestamp_seconds",
+		Help:      "Gauge of the time (in seconds since the epoch) the certificates of DoQ listeners expire.",
+	}, []string{"server", "name"})
+
+	QUICConnectionAge = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "quic_connection_age_seconds",
+		Help:      "Histogram of the age (in seconds) of DoQ connections when they are closed.",
+		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800, 3600, 4 * 3600},
+	}, []string{"server"})
+
+	QUICRateLimitedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "quic_rate_limited_total",
+		Help:      "Counter of DoQ queries refused because a rate limit per connection or source was exceeded.",
+	}, []string{"server", "scope"})
+
+	QUICHandshakeRTT = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "quic_handshake_rtt_seconds",
+		Help:      "Histogram of the smoothed RTT (in seconds) of DoQ connections once their handshake is confirmed.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .2, .3, .5, 1, 2},
+	}, []string{"server"})
+
+	QUICPacketsLostCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "quic_packets_lost_total",
+		Help:      "Counter of packets sent on DoQ connections that were declared lost.",
+	}, []string{"server"})
+
+	QUICCongestionRecoveryCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "quic_congestion_recoveries_total",
+		Help:      "Counter of DoQ connections entering congestion recovery.",
+	}, []string{"server"})
+
+	QUICPathValidationCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "quic_path_validation_frames_total",
+		Help:      "Counter of PATH_CHALLENGE and PATH_RESPONSE frames on DoQ connections per frame and direction.",
+	}, []string{"server", "frame", "direction"})
+
+	QUICSessionsCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "quic_sessions_total",
+		Help:      "Counter of DoQ connections accepted per server and transport.",
+	}, []string{"server", "transport"})
+
+	QUICSessionsRefusedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "quic_sessions_refused_total",
+		Help:      "Counter of DoQ connections refused per server and transport because the maximum of open connections was reached.",
+	}, []string{"server", "transport"})
+
+	QUICActiveStreams = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "quic_active_streams",
+		Help:      "Gauge of the DoQ streams currently being handled per server and transport.",
+	}, []string{"server", "transport"})
+
+	QUICHandshakeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "quic_handshake_duration_seconds",
+		Help:      "Histogram of the time (in seconds) from the first packet of a DoQ connection until its handshake is confirmed.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .2, .3, .5, 1, 2, 5},
+	}, []string{"server", "transport"})
+
+	QUICStreamErrorCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "quic_stream_errors_total",
+		Help:      "Counter of DoQ streams aborted by the server or the client per DoQ error code.",
+	}, []string{"server", "transport", "code", "by"})
+
+	QUICBytesCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "quic_bytes_total",
+		Help:      "Counter of DoQ message bytes, including the length prefix, received (in) and sent (out).",
+	}, []string{"server", "transport", "direction"})
 )
 
 const (
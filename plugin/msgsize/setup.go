@@ -0,0 +1,67 @@
+// Package msgsize implements the msgsize plugin, which bounds the DNS message size per transport.
+package msgsize
+
+import (
+	"strconv"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/miekg/dns"
+)
+
+func init() { plugin.Register("msgsize", setup) }
+
+func setup(c *caddy.Controller) error {
+	err := parseMsgSize(c)
+	if err != nil {
+		return plugin.Error("msgsize", err)
+	}
+	return nil
+}
+
+func parseMsgSize(c *caddy.Controller) error {
+	config := dnsserver.GetConfig(c)
+
+	for c.Next() {
+		if len(c.RemainingArgs()) > 0 {
+			return c.ArgErr()
+		}
+
+		b := 0
+		for c.NextBlock() {
+			var size *int
+			switch c.Val() {
+			case "udp":
+				size = &config.MaxMsgSize.UDP
+			case "quic":
+				size = &config.MaxMsgSize.QUIC
+			case "squic":
+				size = &config.MaxMsgSize.SQUIC
+			case "transfer":
+				size = &config.MaxMsgSize.Transfer
+			default:
+				return c.Errf("unknown property '%s'", c.Val())
+			}
+			args := c.RemainingArgs()
+			if len(args) != 1 {
+				return c.ArgErr()
+			}
+			s, err := strconv.Atoi(args[0])
+			if err != nil {
+				return c.Errf("invalid size '%s': %s", args[0], err)
+			}
+			if s < dns.MinMsgSize || s > dns.MaxMsgSize {
+				return c.Errf("size %d for %s must be between %d and %d", s, c.Val(), dns.MinMsgSize, dns.MaxMsgSize)
+			}
+			*size = s
+			b++
+		}
+
+		if b == 0 {
+			return c.Err("msgsize block with no options specified")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package msgsize
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+)
+
+func TestMsgSize(t *testing.T) {
+	tests := []struct {
+		input              string
+		shouldErr          bool
+		expected           dnsserver.MsgSizes
+		expectedErrContent string // substring from the expected error. Empty for positive cases.
+	}{
+		// positive
+		{`msgsize {
+			udp 1232
+		}`, false, dnsserver.MsgSizes{UDP: 1232}, ""},
+		{`msgsize {
+			udp 1232
+			quic 4096
+			squic 2048
+			transfer 16384
+		}`, false, dnsserver.MsgSizes{UDP: 1232, QUIC: 4096, SQUIC: 2048, Transfer: 16384}, ""},
+		// negative
+		{`msgsize`, true, dnsserver.MsgSizes{}, "block with no options specified"},
+		{`msgsize 1232`, true, dnsserver.MsgSizes{}, "Wrong argument count"},
+		{`msgsize {
+			udp
+		}`, true, dnsserver.MsgSizes{}, "Wrong argument count"},
+		{`msgsize {
+			udp 100
+		}`, true, dnsserver.MsgSizes{}, "must be between"},
+		{`msgsize {
+			squic 70000
+		}`, true, dnsserver.MsgSizes{}, "must be between"},
+		{`msgsize {
+			quic large
+		}`, true, dnsserver.MsgSizes{}, "invalid size"},
+		{`msgsize {
+			tcp 1232
+		}`, true, dnsserver.MsgSizes{}, "unknown property"},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		err := setup(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error but found none for input %s", i, test.input)
+		}
+
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			}
+
+			if !strings.Contains(err.Error(), test.expectedErrContent) {
+				t.Errorf("Test %d: Expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
+			}
+			continue
+		}
+
+		if got := dnsserver.GetConfig(c).MaxMsgSize; got != test.expected {
+			t.Errorf("Test %d: Expected sizes %+v, got %+v", i, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Package clientaddr carries the address of the original client in an EDNS0 option, when a query is
+// forwarded to another CoreDNS over squic. The option is authenticated with a key shared by both
+// servers, so the upstream can trust it and use the client's address, instead of the forwarder's,
+// for ACLs, views and logging.
+//
+// The option data is: an 8 byte timestamp (unix seconds), the length of the address (1 byte), the
+// address, and a HMAC-SHA256 over the timestamp, the address and the question of the query.
+package clientaddr
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+// Code is the EDNS0 option code, from the range for local use.
+const Code = dns.EDNS0LOCALSTART + 1
+
+// MaxAge is how old an option may be when it is verified, this limits replays of captured queries.
+const MaxAge = 30 * time.Second
+
+var (
+	// ErrNotFound is returned when the message has no client address option.
+	ErrNotFound = errors.New("no client address option")
+	// ErrInvalid is returned when the option is malformed or its MAC doesn't verify.
+	ErrInvalid = errors.New("invalid client address option")
+	// ErrExpired is returned when the option is older than MaxAge.
+	ErrExpired = errors.New("expired client address option")
+)
+
+// Set adds the client address addr to m, authenticated with key. Any existing client address option is
+// replaced. If m has no OPT record, one is added: the response from the upstream then has one too, which
+// Strip removes again.
+func Set(m *dns.Msg, addr string, key []byte, now time.Time) {
+	if len(addr) > 255 || len(m.Question) == 0 {
+		return
+	}
+	o := m.IsEdns0()
+	if o == nil {
+		m.SetEdns0(dns.DefaultMsgSize, false)
+		o = m.IsEdns0()
+	}
+	remove(o)
+
+	data := make([]byte, 9, 9+len(addr)+sha256.Size)
+	binary.BigEndian.PutUint64(data, uint64(now.Unix()))
+	data[8] = byte(len(addr))
+	data = append(data, addr...)
+	data = append(data, mac(key, data, m.Question[0])...)
+
+	o.Option = append(o.Option, &dns.EDNS0_LOCAL{Code: Code, Data: data})
+}
+
+// Get returns the client address from m, if the option verifies with key. The option is removed from m
+// in any case, so it isn't passed on.
+func Get(m *dns.Msg, key []byte, now time.Time) (string, error) {
+	o := m.IsEdns0()
+	if o == nil {
+		return "", ErrNotFound
+	}
+	local := remove(o)
+	if local == nil {
+		return "", ErrNotFound
+	}
+
+	data := local.Data
+	if len(data) < 9 || len(m.Question) == 0 {
+		return "", ErrInvalid
+	}
+	n := 9 + int(data[8])
+	if len(data) != n+sha256.Size {
+		return "", ErrInvalid
+	}
+	if !hmac.Equal(data[n:], mac(key, data[:n], m.Question[0])) {
+		return "", ErrInvalid
+	}
+	ts := time.Unix(int64(binary.BigEndian.Uint64(data)), 0)
+	if age := now.Sub(ts); age > MaxAge || age < -MaxAge {
+		return "", ErrExpired
+	}
+	return string(data[9:n]), nil
+}
+
+// Strip removes the OPT record from resp, the response to a query Set was used on, if the original
+// query req didn't have one. A client that didn't use EDNS must not get an OPT record back (RFC 6891).
+func Strip(resp, req *dns.Msg) {
+	if req.IsEdns0() != nil {
+		return
+	}
+	extra := resp.Extra[:0]
+	for _, rr := range resp.Extra {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			extra = append(extra, rr)
+		}
+	}
+	resp.Extra = extra
+}
+
+// Addr parses a client address as returned by Get. SCION addresses are returned as pan.UDPAddr, like the
+// remote address of squic clients, others as *net.UDPAddr.
+func Addr(s string) (net.Addr, error) {
+	if a, err := pan.ParseUDPAddr(s); err == nil {
+		return a, nil
+	}
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return nil, err
+	}
+	return net.ResolveUDPAddr("udp", net.JoinHostPort(host, port))
+}
+
+// remove deletes the client address options from o and returns the last one.
+func remove(o *dns.OPT) *dns.EDNS0_LOCAL {
+	var found *dns.EDNS0_LOCAL
+	opts := o.Option[:0]
+	for _, opt := range o.Option {
+		if l, ok := opt.(*dns.EDNS0_LOCAL); ok && l.Code == Code {
+			found = l
+			continue
+		}
+		opts = append(opts, opt)
+	}
+	o.Option = opts
+	return found
+}
+
+func mac(key, data []byte, q dns.Question) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	h.Write([]byte(strings.ToLower(q.Name)))
+	var b [4]byte
+	binary.BigEndian.PutUint16(b[:], q.Qtype)
+	binary.BigEndian.PutUint16(b[2:], q.Qclass)
+	h.Write(b[:])
+	return h.Sum(nil)
+}
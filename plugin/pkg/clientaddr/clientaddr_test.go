@@ -0,0 +1,117 @@
+package clientaddr
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestSetGet(t *testing.T) {
+	key := []byte("secret")
+	now := time.Now()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	Set(m, "192.0.2.1:5353", key, now)
+
+	// Pack and unpack, as if sent over the wire.
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := new(dns.Msg)
+	if err := received.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	addr, err := Get(received.Copy(), key, now.Add(time.Second))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if addr != "192.0.2.1:5353" {
+		t.Errorf("Expected address %s, got %s", "192.0.2.1:5353", addr)
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(*dns.Msg)
+		key      []byte
+		now      time.Time
+		expected error
+	}{
+		{"wrong key", nil, []byte("other"), now, ErrInvalid},
+		{"expired", nil, key, now.Add(time.Minute), ErrExpired},
+		{"other question", func(m *dns.Msg) { m.Question[0].Qtype = dns.TypeAAAA }, key, now, ErrInvalid},
+		{"no option", func(m *dns.Msg) { m.Extra = nil }, key, now, ErrNotFound},
+	}
+	for _, tc := range tests {
+		m := received.Copy()
+		if tc.modify != nil {
+			tc.modify(m)
+		}
+		if _, err := Get(m, tc.key, tc.now); err != tc.expected {
+			t.Errorf("Test %q: expected error %v, got %v", tc.name, tc.expected, err)
+		}
+		if o := m.IsEdns0(); o != nil && len(o.Option) != 0 {
+			t.Errorf("Test %q: expected the option to be removed", tc.name)
+		}
+	}
+}
+
+func TestSetReplaces(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	Set(m, "192.0.2.1:53", []byte("a"), time.Now())
+	Set(m, "192.0.2.2:53", []byte("a"), time.Now())
+
+	if n := len(m.IsEdns0().Option); n != 1 {
+		t.Fatalf("Expected 1 option, got %d", n)
+	}
+	if addr, _ := Get(m, []byte("a"), time.Now()); addr != "192.0.2.2:53" {
+		t.Errorf("Expected the last address, got %s", addr)
+	}
+}
+
+func TestStrip(t *testing.T) {
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	query := req.Copy()
+	Set(query, "192.0.2.1:53", []byte("a"), time.Now())
+
+	resp := new(dns.Msg)
+	resp.SetReply(query)
+	resp.Extra = []dns.RR{test.A("ns.example.org. 3600 IN A 192.0.2.53")}
+	resp.SetEdns0(dns.DefaultMsgSize, false)
+
+	withEdns := req.Copy()
+	withEdns.SetEdns0(4096, false)
+	Strip(resp, withEdns)
+	if resp.IsEdns0() == nil {
+		t.Errorf("Expected the OPT record to be kept for a query with one")
+	}
+
+	Strip(resp, req)
+	if resp.IsEdns0() != nil {
+		t.Errorf("Expected the OPT record to be removed for a query without one")
+	}
+	if len(resp.Extra) != 1 {
+		t.Errorf("Expected 1 other additional record, got %d", len(resp.Extra))
+	}
+}
+
+func TestAddr(t *testing.T) {
+	a, err := Addr("192.0.2.1:5353")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, ok := a.(*net.UDPAddr); !ok || u.Port != 5353 {
+		t.Errorf("Expected *net.UDPAddr with port 5353, got %v", a)
+	}
+	if _, err := Addr("not an address"); err == nil {
+		t.Errorf("Expected error for invalid address")
+	}
+}
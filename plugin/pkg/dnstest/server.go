@@ -20,13 +20,12 @@ type Server struct {
 // NewServer starts and returns a new Server. The caller should call Close when
 // finished, to shut it down.
 func NewServer(f dns.HandlerFunc) *Server {
-	dns.HandleFunc(".", f)
-
 	ch1 := make(chan bool)
 	ch2 := make(chan bool)
 
-	s1 := &dns.Server{} // udp
-	s2 := &dns.Server{} // tcp
+	// Each server has its own handler, so tests can run several of them side by side.
+	s1 := &dns.Server{Handler: f} // udp
+	s2 := &dns.Server{Handler: f} // tcp
 
 	for i := 0; i < 5; i++ { // 5 attempts
 		s2.Listener, _ = reuseport.Listen("tcp", ":0")
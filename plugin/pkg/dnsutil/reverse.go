@@ -4,13 +4,19 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"regexp"
 	"strings"
+	"sync"
 
+	"github.com/coredns/coredns/plugin/pkg/cidr"
+
+	"github.com/miekg/dns"
 	"github.com/netsec-ethz/scion-apps/pkg/pan"
 )
 
+// IsSCIONAddress returns true if address is a SCION address, see ParseSCIONAddress.
 func IsSCIONAddress(address string) bool {
-	_, err := pan.ParseUDPAddr(address)
+	_, err := ParseSCIONAddress(address)
 	return err == nil
 }
 
@@ -19,6 +25,9 @@ func AddressToReverse(address string) (string, error) {
 		return ReverseSCIONAddr(address)
 
 	}
+	if svc, err := ParseSCIONServiceAddress(address); err == nil {
+		return reverseSVC(svc), nil
+	}
 	if ip4 := ParseIPv4(address); ip4 != nil {
 		return InvertIPv4(address)
 
@@ -33,7 +42,7 @@ func AddressToReverse(address string) (string, error) {
 // into an IP address or SCION address. This works for ipv4 or ipv6.
 //
 // 54.119.58.176.in-addr.arpa. becomes 176.58.119.54.
-// 1.0.0.127.in-addr.19-ffaa-1-1067.scion.arpa. => 19-ffaa:1:1067,[127.0.0.1]
+// 1.0.0.127.in-addr.19-ffaa-1-1067.scion.arpa. becomes 19-ffaa:1:1067,127.0.0.1.
 // If the conversion fails the empty string is returned.
 func ExtractAddressFromReverse(reverseName string) string {
 	search := ""
@@ -46,9 +55,12 @@ func ExtractAddressFromReverse(reverseName string) string {
 	case strings.HasSuffix(reverseName, IP6arpa):
 		search = strings.TrimSuffix(reverseName, IP6arpa)
 		f = reverse6
-	case strings.HasSuffix(reverseName, SCIONarpa):
-
-		return UnReverseSCION(reverseName)
+	case strings.HasSuffix(reverseName, scionArpa()):
+		addr, err := UnReverseSCION(reverseName)
+		if err != nil {
+			return ""
+		}
+		return addr
 
 	default:
 		return ""
@@ -60,7 +72,7 @@ func ExtractAddressFromReverse(reverseName string) string {
 
 // IsReverse returns 0 is name is not in a reverse zone. Anything > 0 indicates
 // name is in a reverse zone. The returned integer will be 1 for in-addr.arpa. (IPv4)
-// and 2 for ip6.arpa. (IPv6) 3 for .scion.arpa.
+// and 2 for ip6.arpa. (IPv6) 3 for .scion.arpa., or the suffix set with SetSCIONReverseSuffix.
 func IsReverse(name string) int {
 	if strings.HasSuffix(name, IP4arpa) {
 		return 1
@@ -68,7 +80,7 @@ func IsReverse(name string) int {
 	if strings.HasSuffix(name, IP6arpa) {
 		return 2
 	}
-	if strings.HasSuffix(name, SCIONarpa) {
+	if strings.HasSuffix(name, scionArpa()) {
 		return 3
 	}
 	return 0
@@ -114,9 +126,41 @@ const (
 	// IP6arpa is the reverse tree suffix for v6 IP addresses.
 	IP6arpa = ".ip6.arpa."
 
+	// SCIONarpa is the default reverse tree suffix for SCION addresses, see SetSCIONReverseSuffix.
 	SCIONarpa = ".scion.arpa."
+	// SCIONReverseSuffixDefault is the default domain of the reverse names of SCION addresses.
+	SCIONReverseSuffixDefault = "scion.arpa."
 )
 
+var (
+	suffixMu    sync.RWMutex
+	scionSuffix = SCIONarpa
+)
+
+// SetSCIONReverseSuffix sets the domain the reverse names of SCION addresses are in, instead of
+// scion.arpa., e.g. scion.test. for testbeds. The setting is process wide, so all plugins agree on it.
+func SetSCIONReverseSuffix(domain string) error {
+	domain = strings.ToLower(dns.Fqdn(domain))
+	if _, ok := dns.IsDomainName(domain); !ok || domain == "." {
+		return fmt.Errorf("invalid reverse suffix %q", domain)
+	}
+	suffixMu.Lock()
+	scionSuffix = "." + domain
+	suffixMu.Unlock()
+	return nil
+}
+
+// SCIONReverseSuffix returns the domain the reverse names of SCION addresses are in, scion.arpa. by
+// default.
+func SCIONReverseSuffix() string { return strings.TrimPrefix(scionArpa(), ".") }
+
+// scionArpa returns the reverse tree suffix for SCION addresses, with a leading dot like SCIONarpa.
+func scionArpa() string {
+	suffixMu.RLock()
+	defer suffixMu.RUnlock()
+	return scionSuffix
+}
+
 // Bigger than we need, not too big to worry about overflow
 const big = 0xFFFFFF
 
@@ -194,49 +238,58 @@ func ParseIPv4(s string) net.IP {
 	return net.IPv4(p[0], p[1], p[2], p[3])
 }
 
-// HostPart.IS-AS.scion.arpa. => IS-AS,[HostPart]
-// TODO: better change signature to return (string, error)
-func UnReverseSCION(revAddr string) string {
-	// TODO: it would be better to have a designated IsReverseSCIONAddress(string) bool
-	// that matches a regex here
-	if !strings.HasSuffix(revAddr, SCIONarpa) {
-		return revAddr
-	}
-	addr := strings.TrimSuffix(revAddr, SCIONarpa)
-
-	if strings.Contains(addr, InAddr4) {
-		token := strings.Split(addr, InAddr4)
-		// we expect two token here: the left is the reversed HostPart, the right the IS-AS Part
-		if len(token) != 2 {
-			return revAddr
-		}
-		hostpart4 := reverse(strings.Split(token[0], "."))
-		isasPart, e := uninvertISAS(token[1])
-		if e == nil {
-			return isasPart + "," + hostpart4
-		} else {
-			return revAddr
-		}
+// ErrNotReverseSCION is returned by UnReverseSCION for names that are not well-formed reverse names of
+// SCION addresses.
+var ErrNotReverseSCION = errors.New("not a reverse name of a SCION address")
 
-	} else if strings.Contains(addr, InAddr6) {
-		token := strings.Split(addr, InAddr6)
-		// we expect two token here: the left is the reversed HostPart, the right the IS-AS Part
-		if len(token) != 2 {
-			return revAddr
-		}
+// reverseSCION matches the reverse names of SCION addresses: the reversed IPv4 address below in-addr, or
+// the 32 reversed nibbles of the IPv6 address below ip6, or the service name below svc, followed by the
+// inverted ISD-AS. The reverse suffix is matched separately, as it can be changed.
+var reverseSCION = regexp.MustCompile(`(?i)^(?:((?:[0-9]{1,3}\.){3}[0-9]{1,3})\.in-addr|((?:[0-9a-f]\.){31}[0-9a-f])\.ip6|(cs|ds|wildcard)\.svc)\.([0-9]+-[0-9a-f]{1,4}-[0-9a-f]{1,4}-[0-9a-f]{1,4})$`)
 
-		hostpart6 := reverse6(strings.Split(token[0], "."))
-		isasPart, e := uninvertISAS(token[1])
-		if e == nil {
-			return isasPart + "," + hostpart6
-		} else {
-			return revAddr
-		}
+// IsReverseSCIONAddress returns true if name is the reverse name of a SCION address, as created by
+// ReverseSCIONAddr.
+func IsReverseSCIONAddress(name string) bool {
+	_, err := UnReverseSCION(name)
+	return err == nil
+}
 
-	} else {
+// UnReverseSCION turns the reverse name of a SCION address back into the address:
+// HostPart.IS-AS.scion.arpa. => IS-AS,[HostPart]
+// i.e. 1.0.0.127.in-addr.19-ffaa-1-1067.scion.arpa. => 19-ffaa:1:1067,127.0.0.1 and
+// b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.19-ffaa-1-1067.scion.arpa. => 19-ffaa:1:1067,[2001:db8::567:89ab]
+// and for service addresses cs.svc.19-ffaa-1-1067.scion.arpa. => 19-ffaa:1:1067,CS
+// Malformed names return an error wrapping ErrNotReverseSCION.
+func UnReverseSCION(revAddr string) (string, error) {
+	suffix := scionArpa()
+	if len(revAddr) <= len(suffix) || !strings.EqualFold(revAddr[len(revAddr)-len(suffix):], suffix) {
+		return "", fmt.Errorf("%w: %q", ErrNotReverseSCION, revAddr)
+	}
+	match := reverseSCION.FindStringSubmatch(revAddr[:len(revAddr)-len(suffix)])
+	if match == nil {
+		return "", fmt.Errorf("%w: %q", ErrNotReverseSCION, revAddr)
+	}
+	isasPart, err := uninvertISAS(strings.ToLower(match[4]))
+	if err != nil {
+		return "", fmt.Errorf("%w: %q: %s", ErrNotReverseSCION, revAddr, err)
+	}
 
-		return revAddr
+	if match[1] != "" {
+		hostpart4 := reverse(strings.Split(match[1], "."))
+		if hostpart4 == "" {
+			return "", fmt.Errorf("%w: %q: invalid IPv4 address", ErrNotReverseSCION, revAddr)
+		}
+		return isasPart + "," + hostpart4, nil
+	}
+	if match[3] != "" {
+		svc, _ := svcName(strings.ToUpper(match[3]))
+		return isasPart + "," + svc, nil
+	}
+	hostpart6 := reverse6(strings.Split(match[2], "."))
+	if hostpart6 == "" {
+		return "", fmt.Errorf("%w: %q: invalid IPv6 address", ErrNotReverseSCION, revAddr)
 	}
+	return isasPart + ",[" + hostpart6 + "]", nil
 }
 
 func invertISAS(s string) string {
@@ -254,10 +307,15 @@ func uninvertISAS(invISAS string) (string, error) {
 
 // computes the inverse address for rDNS lookup
 // i.e. 19-ffaa:1:1067,[127.0.0.1] => 1.0.0.127.in-addr.19-ffaa-1-1067.scion.arpa.
+// and 19-ffaa:1:1067,[2001:db8::567:89ab] => b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.19-ffaa-1-1067.scion.arpa.
+// and for service addresses 19-ffaa:1:1067,CS => cs.svc.19-ffaa-1-1067.scion.arpa.
 // returns Address unchanged if its no valid SCION address
 func ReverseSCIONAddr(scaddr string) (string, error) {
-	addr, err := pan.ParseUDPAddr(scaddr)
+	addr, err := ParseSCIONAddress(scaddr)
 	if err != nil {
+		if svc, serr := ParseSCIONServiceAddress(scaddr); serr == nil {
+			return reverseSVC(svc), nil
+		}
 		// if it wasnt a valid SCION address, we were passed
 		// just act as the identity Fcn
 		return scaddr, err
@@ -271,20 +329,52 @@ func ReverseSCIONAddr(scaddr string) (string, error) {
 		if err != nil {
 			return scaddr, err
 		}
-		invName = revIP + InAddr4 + invIA + SCIONarpa
+		invName = revIP + InAddr4 + invIA + scionArpa()
 		return invName, nil
 	} else if addr.IP.Is6() {
-		tmpIP, err := InvertIPv6(addr.IP.StringExpanded())
+		revIP, err = InvertIPv6(addr.IP.String())
 		if err != nil {
 			return scaddr, err
 		}
-		revIP = strings.Replace(tmpIP, ":", ".", -1)
-		invName = revIP + InAddr4 + invIA + SCIONarpa
+		invName = revIP + InAddr6 + invIA + scionArpa()
 		return invName, nil
 	}
 	return scaddr, errors.New("your AS's host addressing scheme is neither IPv4 nor 6 and not supported for rDNS lookup yet")
 }
 
+// ReverseZoneFromPrefix returns the reverse zones under scion.arpa. that are authoritative for the hosts in
+// the IP prefix of the AS ia. The zones fall on label boundaries, so a prefix that doesn't is split into
+// the zones of its subnets. i.e. 19-ffaa:1:1067 and 127.0.0.0/16 => 0.127.in-addr.19-ffaa-1-1067.scion.arpa.
+// and 19-ffaa:1:1067 and 10.0.0.0/15 => 0.10.in-addr.19-ffaa-1-1067.scion.arpa. and
+// 1.10.in-addr.19-ffaa-1-1067.scion.arpa.
+func ReverseZoneFromPrefix(ia, prefix string) ([]string, error) {
+	isdas, err := pan.ParseIA(ia)
+	if err != nil {
+		return nil, err
+	}
+	_, n, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return nil, err
+	}
+	invIA := invertISAS(isdas.String())
+
+	zones := []string{}
+	for _, z := range cidr.Reverse(cidr.Split(n)) {
+		// The zone of a /0 has no labels before the arpa suffix.
+		z = "." + z
+		switch {
+		case strings.HasSuffix(z, IP4arpa):
+			z = strings.TrimSuffix(z, IP4arpa) + InAddr4
+		case strings.HasSuffix(z, IP6arpa):
+			z = strings.TrimSuffix(z, IP6arpa) + InAddr6
+		default:
+			continue
+		}
+		zones = append(zones, strings.TrimPrefix(z, ".")+invIA+scionArpa())
+	}
+	return zones, nil
+}
+
 func ParseIPv6(s string) (ip net.IP) {
 	ip = make(net.IP, net.IPv6len)
 	ellipsis := -1 // position of ellipsis in ip
@@ -401,18 +491,22 @@ func InvertIPv4(ip string) (invertedIP string, err error) {
 	return invertedIP, nil
 }
 
+// InvertIPv6 returns the nibbles of the IPv6 address ip in reverse order, separated by dots, as in
+// the ip6.arpa. names of RFC 3596. ip may be compressed or expanded.
+// i.e. 2001:db8::567:89ab => b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2
 func InvertIPv6(ip string) (invertedIP string, err error) {
-
-	octets := strings.Split(ip, ":")
-	if len(octets) != 15 {
+	addr := ParseIPv6(ip)
+	if addr == nil {
 		return "", fmt.Errorf("%v is not an IPV6", ip)
 	}
 
-	for i := 15; i >= 0; i-- {
-		invertedIP += octets[i]
+	const hexDigit = "0123456789abcdef"
+	buf := make([]byte, 0, 4*net.IPv6len)
+	for i := net.IPv6len - 1; i >= 0; i-- {
+		buf = append(buf, hexDigit[addr[i]&0xF], '.', hexDigit[addr[i]>>4])
 		if i != 0 {
-			invertedIP += ":"
+			buf = append(buf, '.')
 		}
 	}
-	return invertedIP, nil
+	return string(buf), nil
 }
@@ -1,6 +1,7 @@
 package dnsutil
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -68,3 +69,156 @@ func TestIsReverse(t *testing.T) {
 		}
 	}
 }
+
+func TestInvertIPv6(t *testing.T) {
+	tests := []struct {
+		ip       string
+		expected string
+		err      bool
+	}{
+		{"2001:db8::567:89ab", "b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2", false},
+		{"2001:0db8:0000:0000:0000:0000:0567:89ab", "b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2", false},
+		{"2001:DB8::567:89AB", "b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2", false},
+		{"::1", "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0", false},
+		{"127.0.0.1", "", true},
+		{"2001:db8::567::89ab", "", true},
+	}
+	for i, tc := range tests {
+		got, err := InvertIPv6(tc.ip)
+		if (err != nil) != tc.err {
+			t.Errorf("Test %d, expected error %t, got %v", i, tc.err, err)
+		}
+		if got != tc.expected {
+			t.Errorf("Test %d, expected '%s', got '%s'", i, tc.expected, got)
+		}
+	}
+}
+
+func TestReverseSCIONAddr(t *testing.T) {
+	tests := []struct {
+		addr     string
+		expected string
+	}{
+		{"19-ffaa:1:1067,[127.0.0.1]:0", "1.0.0.127.in-addr.19-ffaa-1-1067.scion.arpa."},
+		{"19-ffaa:1:1067,[2001:db8::567:89ab]:0", "b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.19-ffaa-1-1067.scion.arpa."},
+		{"19-ffaa:1:1067,[2001:0db8:0000:0000:0000:0000:0567:89ab]:0", "b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.19-ffaa-1-1067.scion.arpa."},
+		{"19-ffaa:1:1067,CS", "cs.svc.19-ffaa-1-1067.scion.arpa."},
+		{"19-ffaa:1:1067,ds_a", "ds.svc.19-ffaa-1-1067.scion.arpa."},
+	}
+	for i, tc := range tests {
+		got, err := ReverseSCIONAddr(tc.addr)
+		if err != nil {
+			t.Fatalf("Test %d, expected no error, got %v", i, err)
+		}
+		if got != tc.expected {
+			t.Errorf("Test %d, expected '%s', got '%s'", i, tc.expected, got)
+		}
+	}
+}
+
+func TestUnReverseSCION(t *testing.T) {
+	tests := []struct {
+		reverseName     string
+		expectedAddress string
+	}{
+		{"1.0.0.127.in-addr.19-ffaa-1-1067.scion.arpa.", "19-ffaa:1:1067,127.0.0.1"},
+		{"b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.19-ffaa-1-1067.scion.arpa.", "19-ffaa:1:1067,[2001:db8::567:89ab]"},
+		{"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.ip6.19-ffaa-1-1067.scion.arpa.", "19-ffaa:1:1067,[::1]"},
+		{"cs.svc.19-ffaa-1-1067.scion.arpa.", "19-ffaa:1:1067,CS"},
+		{"wildcard.svc.19-ffaa-1-1067.scion.arpa.", "19-ffaa:1:1067,Wildcard"},
+		// Malformed names.
+		{"xy.svc.19-ffaa-1-1067.scion.arpa.", ""},
+		{"d.0.1.0.0.2.ip6.19-ffaa-1-1067.scion.arpa.", ""},
+		{"89ab.567.0.0.0.0.db8.2001.ip6.19-ffaa-1-1067.scion.arpa.", ""},
+		{"1.0.0.256.in-addr.19-ffaa-1-1067.scion.arpa.", ""},
+		{"0.0.127.in-addr.19-ffaa-1-1067.scion.arpa.", ""},
+		{"1.0.0.127.in-addr.19-ffaa-1067.scion.arpa.", ""},
+		{"1.0.0.127.19-ffaa-1-1067.scion.arpa.", ""},
+		{"1.0.0.127.in-addr.19-ffaa-1-1067.scion.arpa", ""},
+	}
+	for i, tc := range tests {
+		got, err := UnReverseSCION(tc.reverseName)
+		if tc.expectedAddress == "" {
+			if !errors.Is(err, ErrNotReverseSCION) {
+				t.Errorf("Test %d, expected ErrNotReverseSCION, got %v", i, err)
+			}
+		} else if err != nil {
+			t.Errorf("Test %d, expected no error, got %v", i, err)
+		}
+		if got != tc.expectedAddress {
+			t.Errorf("Test %d, expected '%s', got '%s'", i, tc.expectedAddress, got)
+		}
+		if ok := IsReverseSCIONAddress(tc.reverseName); ok != (tc.expectedAddress != "") {
+			t.Errorf("Test %d, expected IsReverseSCIONAddress to be %t", i, !ok)
+		}
+		if rev := ExtractAddressFromReverse(tc.reverseName); rev != got {
+			t.Errorf("Test %d, expected '%s' from ExtractAddressFromReverse, got '%s'", i, got, rev)
+		}
+	}
+}
+
+func TestReverseZoneFromPrefix(t *testing.T) {
+	tests := []struct {
+		ia       string
+		prefix   string
+		expected []string
+		err      bool
+	}{
+		{"19-ffaa:1:1067", "127.0.0.0/16", []string{"0.127.in-addr.19-ffaa-1-1067.scion.arpa."}, false},
+		{"19-ffaa:1:1067", "127.0.0.1/32", []string{"1.0.0.127.in-addr.19-ffaa-1-1067.scion.arpa."}, false},
+		{"19-ffaa:1:1067", "10.0.0.0/15", []string{"0.10.in-addr.19-ffaa-1-1067.scion.arpa.", "1.10.in-addr.19-ffaa-1-1067.scion.arpa."}, false},
+		{"19-ffaa:1:1067", "0.0.0.0/0", []string{"in-addr.19-ffaa-1-1067.scion.arpa."}, false},
+		{"19-ffaa:1:1067", "2001:db8::/32", []string{"8.b.d.0.1.0.0.2.ip6.19-ffaa-1-1067.scion.arpa."}, false},
+		{"19-ffaa:1:1067", "2001:db8::/31", []string{"8.b.d.0.1.0.0.2.ip6.19-ffaa-1-1067.scion.arpa.", "9.b.d.0.1.0.0.2.ip6.19-ffaa-1-1067.scion.arpa."}, false},
+		{"19-ffaa:1:1067", "127.0.0.1", nil, true},
+		{"ffaa:1:1067", "127.0.0.0/8", nil, true},
+	}
+	for i, tc := range tests {
+		got, err := ReverseZoneFromPrefix(tc.ia, tc.prefix)
+		if (err != nil) != tc.err {
+			t.Fatalf("Test %d, expected error %t, got %v", i, tc.err, err)
+		}
+		if len(got) != len(tc.expected) {
+			t.Fatalf("Test %d, expected %v, got %v", i, tc.expected, got)
+		}
+		for j := range got {
+			if got[j] != tc.expected[j] {
+				t.Errorf("Test %d, expected '%s', got '%s'", i, tc.expected[j], got[j])
+			}
+		}
+	}
+}
+
+func TestSCIONReverseSuffix(t *testing.T) {
+	if err := SetSCIONReverseSuffix("SCION.test"); err != nil {
+		t.Fatal(err)
+	}
+	defer SetSCIONReverseSuffix(SCIONReverseSuffixDefault)
+
+	if s := SCIONReverseSuffix(); s != "scion.test." {
+		t.Errorf("Expected suffix scion.test., got %s", s)
+	}
+	rev, err := ReverseSCIONAddr("19-ffaa:1:1067,[127.0.0.1]:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev != "1.0.0.127.in-addr.19-ffaa-1-1067.scion.test." {
+		t.Errorf("Expected reverse name under scion.test., got %s", rev)
+	}
+	if addr, err := UnReverseSCION(rev); err != nil || addr != "19-ffaa:1:1067,127.0.0.1" {
+		t.Errorf("Expected 19-ffaa:1:1067,127.0.0.1, got %q, %v", addr, err)
+	}
+	if IsReverse(rev) != 3 {
+		t.Errorf("Expected %s to be a SCION reverse name", rev)
+	}
+	if IsReverseSCIONAddress("1.0.0.127.in-addr.19-ffaa-1-1067.scion.arpa.") {
+		t.Errorf("Expected names under scion.arpa. not to be SCION reverse names")
+	}
+	if zones, _ := ReverseZoneFromPrefix("19-ffaa:1:1067", "127.0.0.0/8"); len(zones) != 1 || zones[0] != "127.in-addr.19-ffaa-1-1067.scion.test." {
+		t.Errorf("Expected the reverse zone under scion.test., got %v", zones)
+	}
+
+	if err := SetSCIONReverseSuffix("."); err == nil {
+		t.Errorf("Expected error for the root as reverse suffix")
+	}
+}
@@ -0,0 +1,46 @@
+package dnsutil
+
+import (
+	"net"
+
+	"github.com/coredns/coredns/plugin/pkg/lru"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+// addrCacheSize is the number of addresses ParseSCIONAddress remembers.
+const addrCacheSize = 1024
+
+// parsedAddr is the result of parsing an address.
+type parsedAddr struct {
+	addr pan.UDPAddr
+	err  error
+}
+
+var addrs = lru.New(addrCacheSize)
+
+// ParseSCIONAddress parses the SCION address s, like pan.ParseUDPAddr. The results for the most recently
+// used addresses, including the errors, are cached, as the same few addresses are parsed over and over,
+// often per query.
+func ParseSCIONAddress(s string) (pan.UDPAddr, error) {
+	if v, ok := addrs.Get(s); ok {
+		p := v.(parsedAddr)
+		return p.addr, p.err
+	}
+	a, err := pan.ParseUDPAddr(s)
+	addrs.Add(s, parsedAddr{addr: a, err: err})
+	return a, err
+}
+
+// HostOf strips the port from addr, which may be a SCION address. The ISD-AS of SCION addresses is kept,
+// their IP is always in brackets, e.g. 19-ffaa:1:1067,[10.0.0.1]. Addresses without a port are returned
+// as they are, with the brackets of SCION addresses added.
+func HostOf(addr string) string {
+	if a, err := ParseSCIONAddress(addr); err == nil {
+		return a.IA.String() + ",[" + a.IP.String() + "]"
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
@@ -0,0 +1,82 @@
+package dnsutil
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+func TestParseSCIONAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		err  bool
+	}{
+		{"19-ffaa:1:1067,[127.0.0.1]:8853", false},
+		{"19-ffaa:1:1067,[::1]:8853", false},
+		{"127.0.0.1:53", true},
+		{"example.org.", true},
+	}
+	for i, tc := range tests {
+		// The second time the result comes from the cache.
+		for j := 0; j < 2; j++ {
+			a, err := ParseSCIONAddress(tc.addr)
+			if (err != nil) != tc.err {
+				t.Fatalf("Test %d: expected error %t, got %v", i, tc.err, err)
+			}
+			if err != nil {
+				continue
+			}
+			if expected := pan.MustParseUDPAddr(tc.addr); a != expected {
+				t.Errorf("Test %d: expected %s, got %s", i, expected, a)
+			}
+		}
+		if IsSCIONAddress(tc.addr) == tc.err {
+			t.Errorf("Test %d: expected IsSCIONAddress to be %t", i, !tc.err)
+		}
+	}
+}
+
+func TestHostOf(t *testing.T) {
+	tests := []struct {
+		addr, host string
+	}{
+		{"10.240.0.1:53", "10.240.0.1"},
+		{"[::1]:53", "::1"},
+		{"19-ffaa:1:fe4,[10.240.0.1]:8853", "19-ffaa:1:fe4,[10.240.0.1]"},
+		{"19-ffaa:1:fe4,10.240.0.1:30041", "19-ffaa:1:fe4,[10.240.0.1]"},
+		{"19-ffaa:1:fe4,[::1]", "19-ffaa:1:fe4,[::1]"},
+		{"ns1.example.org", "ns1.example.org"},
+	}
+	for i, tc := range tests {
+		if got := HostOf(tc.addr); got != tc.host {
+			t.Errorf("Test %d: expected host %q of %q, got %q", i, tc.host, tc.addr, got)
+		}
+	}
+}
+
+func BenchmarkParseUDPAddr(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		pan.ParseUDPAddr("19-ffaa:1:1067,[127.0.0.1]:8853")
+	}
+}
+
+func BenchmarkParseSCIONAddress(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ParseSCIONAddress("19-ffaa:1:1067,[127.0.0.1]:8853")
+	}
+}
+
+func BenchmarkParseSCIONAddressParallel(b *testing.B) {
+	addrs := make([]string, 64)
+	for i := range addrs {
+		addrs[i] = "19-ffaa:1:1067,[127.0.0.1]:" + strconv.Itoa(8853+i)
+	}
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			ParseSCIONAddress(addrs[i%len(addrs)])
+			i++
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package dnsutil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+// SCION anycast service names, as used in service addresses like 19-ffaa:1:1067,CS.
+const (
+	SVCControl  = "CS"
+	SVCDiscover = "DS"
+	SVCWildcard = "Wildcard"
+)
+
+// InSVC is the label below which the reverse names of service addresses are, like InAddr4 for IPv4 hosts.
+const InSVC = ".svc."
+
+// SVCAddr is a SCION service address: the anycast address of a service, e.g. the control service,
+// in an AS.
+type SVCAddr struct {
+	IA  pan.IA
+	SVC string // one of SVCControl, SVCDiscover or SVCWildcard
+}
+
+// String returns the service address in the form ISD-AS,SVC, e.g. 19-ffaa:1:1067,CS.
+func (a SVCAddr) String() string { return a.IA.String() + "," + a.SVC }
+
+// ParseSCIONServiceAddress parses the service address s of the form ISD-AS,SVC, e.g. 19-ffaa:1:1067,CS.
+// The service name is case insensitive and may carry the anycast suffix _A, multicast addresses are not
+// supported.
+func ParseSCIONServiceAddress(s string) (SVCAddr, error) {
+	i := strings.LastIndex(s, ",")
+	if i < 0 {
+		return SVCAddr{}, fmt.Errorf("%q is not a SCION service address", s)
+	}
+	ia, err := pan.ParseIA(s[:i])
+	if err != nil {
+		return SVCAddr{}, err
+	}
+	svc, ok := svcName(strings.TrimSuffix(strings.ToUpper(s[i+1:]), "_A"))
+	if !ok {
+		return SVCAddr{}, fmt.Errorf("unknown SCION service %q", s[i+1:])
+	}
+	return SVCAddr{IA: ia, SVC: svc}, nil
+}
+
+// IsSCIONServiceAddress returns true if address is a SCION service address, see ParseSCIONServiceAddress.
+func IsSCIONServiceAddress(address string) bool {
+	_, err := ParseSCIONServiceAddress(address)
+	return err == nil
+}
+
+// svcName returns the canonical name of the upper cased service name s.
+func svcName(s string) (string, bool) {
+	switch s {
+	case SVCControl, SVCDiscover:
+		return s, true
+	case strings.ToUpper(SVCWildcard):
+		return SVCWildcard, true
+	}
+	return "", false
+}
+
+// reverseSVC returns the reverse name of the service address a,
+// i.e. 19-ffaa:1:1067,CS => cs.svc.19-ffaa-1-1067.scion.arpa.
+func reverseSVC(a SVCAddr) string {
+	return strings.ToLower(a.SVC) + InSVC + invertISAS(a.IA.String()) + scionArpa()
+}
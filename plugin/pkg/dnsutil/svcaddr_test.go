@@ -0,0 +1,36 @@
+package dnsutil
+
+import "testing"
+
+func TestParseSCIONServiceAddress(t *testing.T) {
+	tests := []struct {
+		addr     string
+		expected string
+	}{
+		{"19-ffaa:1:1067,CS", "19-ffaa:1:1067,CS"},
+		{"19-ffaa:1:1067,cs_a", "19-ffaa:1:1067,CS"},
+		{"19-ffaa:1:1067,DS", "19-ffaa:1:1067,DS"},
+		{"19-ffaa:1:1067,WILDCARD", "19-ffaa:1:1067,Wildcard"},
+		// Not service addresses.
+		{"19-ffaa:1:1067,CS_M", ""},
+		{"19-ffaa:1:1067,XY", ""},
+		{"19-ffaa:1:1067,[127.0.0.1]", ""},
+		{"19-ffaa,CS", ""},
+		{"CS", ""},
+	}
+	for i, tc := range tests {
+		a, err := ParseSCIONServiceAddress(tc.addr)
+		if tc.expected == "" {
+			if err == nil {
+				t.Errorf("Test %d: expected error for %q, got %s", i, tc.addr, a)
+			}
+		} else if err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+		} else if a.String() != tc.expected {
+			t.Errorf("Test %d: expected %s, got %s", i, tc.expected, a)
+		}
+		if IsSCIONServiceAddress(tc.addr) != (tc.expected != "") {
+			t.Errorf("Test %d: expected IsSCIONServiceAddress to be %t", i, tc.expected != "")
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package doqclient
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+// ErrClosed is returned when using a Conn after its last reference has been closed.
+var ErrClosed = errors.New("DNS-over-QUIC connection closed")
+
+// Conn is a reference counted DNS-over-QUIC connection.
+type Conn struct {
+	session quic.Connection
+
+	mu      sync.Mutex
+	refs    int
+	closing bool
+	// inflight tracks the streams that are in use, Close waits for them before closing the session.
+	inflight sync.WaitGroup
+	// release is called when the last reference has been closed.
+	release func(*Conn)
+}
+
+func newConn(session quic.Connection, release func(*Conn)) *Conn {
+	return &Conn{session: session, refs: 1, release: release}
+}
+
+// acquire adds a reference to c. It returns false if c is already closing.
+func (c *Conn) acquire() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closing {
+		return false
+	}
+	select {
+	case <-c.session.Context().Done():
+		// The session is gone, don't hand it out anymore.
+		return false
+	default:
+	}
+	c.refs++
+	return true
+}
+
+// Refs returns the number of references to c.
+func (c *Conn) Refs() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.refs
+}
+
+// Session returns the underlying QUIC connection.
+func (c *Conn) Session() quic.Connection { return c.session }
+
+// Close releases a reference to c. When the last reference is released, Close waits for all
+// streams in flight to finish and then closes the QUIC connection.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	if c.refs == 0 {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.refs--
+	if c.refs > 0 {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closing = true
+	c.mu.Unlock()
+
+	if c.release != nil {
+		c.release(c)
+	}
+	c.inflight.Wait()
+	return c.session.CloseWithError(transport.DoQNoError, "")
+}
+
+// begin registers a stream in flight, it returns false if the connection is closing.
+func (c *Conn) begin() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closing {
+		return false
+	}
+	c.inflight.Add(1)
+	return true
+}
+
+// Exchange sends m on a new stream and returns the response. As DoQ requires it, m is sent with a
+// message ID of 0, the response carries the ID of m again. m itself is not modified.
+func (c *Conn) Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	return c.exchange(ctx, m, nil)
+}
+
+// exchange is Exchange, but if m has a TSIG record and tsigSecret the secret of its key, m is signed and
+// the response must be signed as well.
+func (c *Conn) exchange(ctx context.Context, m *dns.Msg, tsigSecret map[string]string) (*dns.Msg, error) {
+	if !c.begin() {
+		return nil, ErrClosed
+	}
+	defer c.inflight.Done()
+
+	var (
+		buf         []byte
+		secret, mac string
+		err         error
+	)
+	if t := m.IsTsig(); t != nil && tsigSecret != nil {
+		var ok bool
+		if secret, ok = tsigSecret[t.Hdr.Name]; !ok {
+			return nil, dns.ErrSecret
+		}
+		// The MAC covers the ID of m, which the TSIG record keeps, so it can be set to 0 afterwards.
+		// Signing takes the TSIG record off the message, m is copied to keep it.
+		buf, mac, err = dns.TsigGenerate(m.Copy(), secret, "", false)
+	} else {
+		buf, err = m.Pack()
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The message ID is the first field of the header.
+	binary.BigEndian.PutUint16(buf, 0)
+
+	stream, err := c.session.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetDeadline(deadline)
+	}
+
+	if _, err := stream.Write(addPrefix(buf)); err != nil {
+		stream.CancelRead(transport.DoQRequestCancelled)
+		return nil, err
+	}
+	// Closing the stream sends the STREAM FIN, telling the server no more data follows.
+	stream.Close()
+
+	ret, buf, err := readMsg(stream)
+	if err != nil && ctx.Err() != nil {
+		// We gave up waiting, tell the server it doesn't need to answer anymore.
+		stream.CancelRead(transport.DoQRequestCancelled)
+		return nil, err
+	}
+	stream.CancelRead(transport.DoQNoError)
+	if err != nil {
+		return nil, err
+	}
+	if mac != "" {
+		// A signed query must get a signed response, else it could have been stripped on the way.
+		if ret.IsTsig() == nil {
+			return nil, dns.ErrNoSig
+		}
+		if err := dns.TsigVerify(buf, secret, mac, false); err != nil {
+			return nil, err
+		}
+	}
+	ret.Id = m.Id
+	return ret, nil
+}
+
+// readMsg reads a single length prefixed DNS message from r. It returns the message as it was read as well.
+func readMsg(r io.Reader) (*dns.Msg, []byte, error) {
+	var l [2]byte
+	if _, err := io.ReadFull(r, l[:]); err != nil {
+		return nil, nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(l[:]))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, nil, err
+	}
+	m := new(dns.Msg)
+	if err := m.Unpack(buf); err != nil {
+		return nil, nil, err
+	}
+	return m, buf, nil
+}
+
+// addPrefix adds a 2-byte prefix with the DNS message length.
+func addPrefix(b []byte) []byte {
+	m := make([]byte, 2+len(b))
+	binary.BigEndian.PutUint16(m, uint16(len(b)))
+	copy(m[2:], b)
+	return m
+}
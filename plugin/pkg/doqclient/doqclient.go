@@ -0,0 +1,169 @@
+// Package doqclient implements a DNS-over-QUIC client, both over IP (quic) and over SCION (squic).
+//
+// Connections created by a Client are shared: dialing an address that already has an open
+// connection returns that connection with its reference count incremented. Each Dial must be paired
+// with a Close, the underlying QUIC connection is closed once the last reference is released and
+// all streams in flight have finished. This makes it safe to share a single SCION QUIC session
+// across goroutines.
+package doqclient
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"sync"
+	"time"
+
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+	"github.com/quic-go/quic-go"
+	"inet.af/netaddr"
+)
+
+// DefaultIdleTimeout is the QUIC idle timeout used when the Client has no QUICConfig.
+const DefaultIdleTimeout = 5 * time.Minute
+
+// ErrUnknownNet is returned when the Client's Net is neither quic nor squic.
+var ErrUnknownNet = errors.New("unknown network for DNS-over-QUIC client")
+
+// Client is a DNS-over-QUIC client.
+type Client struct {
+	// Net is either transport.QUIC or transport.SQUIC.
+	Net string
+	// TLSConfig is used for the handshake. ServerName must be set.
+	TLSConfig *tls.Config
+	// QUICConfig is used for new connections, if nil an idle timeout of DefaultIdleTimeout is used.
+	QUICConfig *quic.Config
+	// Policy is the path policy for SCION connections, may be nil.
+	Policy pan.Policy
+	// Selector returns the path selector for a new SCION connection. If nil, a connection uses the first
+	// path the policy allows for as long as it works.
+	Selector func() pan.Selector
+	// TsigSecret holds the TSIG secrets by key name. Queries with a TSIG record are signed with the secret
+	// of its key, and their responses must be signed as well.
+	TsigSecret map[string]string
+
+	mu    sync.Mutex
+	conns map[string]*Conn
+}
+
+// New returns a new Client for net.
+func New(net string, tlsConfig *tls.Config) *Client {
+	return &Client{Net: net, TLSConfig: tlsConfig}
+}
+
+// Dial returns a connection to addr, which is shared with all other users of the Client that dialed
+// the same address. The returned Conn must be closed when no longer needed.
+func (c *Client) Dial(ctx context.Context, addr string) (*Conn, error) {
+	c.mu.Lock()
+	if conn, ok := c.conns[addr]; ok && conn.acquire() {
+		c.mu.Unlock()
+		return conn, nil
+	}
+	c.mu.Unlock()
+
+	session, err := c.dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Someone else may have dialed the same address in the meantime, prefer the existing connection.
+	if conn, ok := c.conns[addr]; ok && conn.acquire() {
+		go session.CloseWithError(transport.DoQNoError, "")
+		return conn, nil
+	}
+	if c.conns == nil {
+		c.conns = make(map[string]*Conn)
+	}
+	conn := newConn(session, func(conn *Conn) { c.forget(addr, conn) })
+	c.conns[addr] = conn
+	return conn, nil
+}
+
+// Exchange sends m to addr and returns the response. It uses a shared connection to addr.
+func (c *Client) Exchange(ctx context.Context, m *dns.Msg, addr string) (*dns.Msg, error) {
+	conn, err := c.Dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	ret, err := conn.exchange(ctx, m, c.TsigSecret)
+	if err != nil && c.Net == transport.SQUIC {
+		err = Classify(err, c.Net, addr, false)
+	}
+	return ret, err
+}
+
+// forget removes conn from the shared connections, if it is still the one used for addr.
+func (c *Client) forget(addr string, conn *Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conns[addr] == conn {
+		delete(c.conns, addr)
+	}
+}
+
+func (c *Client) quicConfig() *quic.Config {
+	if c.QUICConfig != nil {
+		return c.QUICConfig
+	}
+	return &quic.Config{MaxIdleTimeout: DefaultIdleTimeout}
+}
+
+func (c *Client) dial(ctx context.Context, addr string) (quic.Connection, error) {
+	switch c.Net {
+	case transport.QUIC:
+		session, err := quic.DialAddrEarlyContext(ctx, addr, c.TLSConfig, c.quicConfig())
+		if err != nil {
+			return nil, err
+		}
+		return session, nil
+	case transport.SQUIC:
+		var selector pan.Selector
+		if c.Selector != nil {
+			selector = c.Selector()
+		}
+		session, err := DialSCIONSelector(ctx, addr, c.Policy, selector, c.TLSConfig, c.quicConfig())
+		if err != nil {
+			return nil, err
+		}
+		return session, nil
+	}
+	return nil, ErrUnknownNet
+}
+
+// DialSCION dials a QUIC connection over SCION to addr, which must be a SCION address
+// like 19-ffaa:1:1067,[127.0.0.1]:8853. The TLS ServerName is used as the SNI. Only paths
+// with an MTU large enough for QUIC are used and path MTU discovery is disabled, as pan may
+// switch to another path during the connection. Errors are returned as an *Error.
+func DialSCION(ctx context.Context, addr string, policy pan.Policy, tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+	return DialSCIONSelector(ctx, addr, policy, nil, tlsConfig, quicConfig)
+}
+
+// DialSCIONSelector is like DialSCION, but selector picks the path among the ones policy allows. With a nil
+// selector the first path is used for as long as it works.
+func DialSCIONSelector(ctx context.Context, addr string, policy pan.Policy, selector pan.Selector, tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+	if tlsConfig == nil || (tlsConfig.ServerName == "" && !tlsConfig.InsecureSkipVerify) {
+		return nil, &Error{Kind: KindTLSNameMissing, Transport: transport.SQUIC, Addr: addr, Err: ErrTLSNameMissing}
+	}
+	remote, err := pan.ParseUDPAddr(addr)
+	if err != nil {
+		return nil, Classify(err, transport.SQUIC, addr, true)
+	}
+	if quicConfig == nil {
+		quicConfig = &quic.Config{}
+	}
+	quicConfig = quicConfig.Clone()
+	quicConfig.DisablePathMTUDiscovery = true
+	var local netaddr.IPPort
+	session, err := pan.DialQUICEarly(ctx, local, remote, pkgscion.QUICPolicy(policy), selector, tlsConfig.ServerName, tlsConfig, quicConfig)
+	if err != nil {
+		return nil, Classify(err, transport.SQUIC, addr, true)
+	}
+	return session, nil
+}
@@ -0,0 +1,186 @@
+package doqclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+func TestSharedConn(t *testing.T) {
+	addr, stop := startServer(t, 0)
+	defer stop()
+
+	c := New(transport.QUIC, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"doq"}})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c1, err := c.Dial(ctx, addr)
+	if err != nil {
+		t.Fatalf("Expected no error dialing, got %s", err)
+	}
+	c2, err := c.Dial(ctx, addr)
+	if err != nil {
+		t.Fatalf("Expected no error dialing, got %s", err)
+	}
+	if c1 != c2 {
+		t.Fatalf("Expected the connection to be shared")
+	}
+	if c1.Refs() != 2 {
+		t.Errorf("Expected 2 references, got %d", c1.Refs())
+	}
+
+	if err := c1.Close(); err != nil {
+		t.Errorf("Expected no error closing, got %s", err)
+	}
+	// c2 still holds a reference, so the connection must still be usable.
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	if _, err := c2.Exchange(ctx, m); err != nil {
+		t.Errorf("Expected no error after closing a single reference, got %s", err)
+	}
+
+	if err := c2.Close(); err != nil {
+		t.Errorf("Expected no error closing, got %s", err)
+	}
+	if _, err := c2.Exchange(ctx, m); err != ErrClosed {
+		t.Errorf("Expected ErrClosed after closing the last reference, got %v", err)
+	}
+	if err := c2.Close(); err != ErrClosed {
+		t.Errorf("Expected ErrClosed when closing twice, got %v", err)
+	}
+
+	// A new Dial must result in a new connection.
+	c3, err := c.Dial(ctx, addr)
+	if err != nil {
+		t.Fatalf("Expected no error dialing, got %s", err)
+	}
+	defer c3.Close()
+	if c3 == c1 {
+		t.Errorf("Expected a new connection after the last reference was closed")
+	}
+}
+
+func TestCloseWaitsForInflight(t *testing.T) {
+	addr, stop := startServer(t, 200*time.Millisecond)
+	defer stop()
+
+	c := New(transport.QUIC, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"doq"}})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := c.Dial(ctx, addr)
+	if err != nil {
+		t.Fatalf("Expected no error dialing, got %s", err)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	errc := make(chan error)
+	go func() {
+		_, err := conn.Exchange(ctx, m)
+		errc <- err
+	}()
+
+	// Give the exchange time to open its stream, then close while the response is delayed.
+	time.Sleep(50 * time.Millisecond)
+	if err := conn.Close(); err != nil {
+		t.Errorf("Expected no error closing, got %s", err)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("Expected the in-flight exchange to finish, got %s", err)
+	}
+}
+
+func TestExchangeMessageID(t *testing.T) {
+	addr, stop := startServer(t, 0)
+	defer stop()
+
+	c := New(transport.QUIC, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"doq"}})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.Id = 4242
+	// The server rejects queries with any other ID than 0.
+	ret, err := c.Exchange(ctx, m, addr)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if ret.Id != 4242 {
+		t.Errorf("Expected the response to carry ID %d, got %d", 4242, ret.Id)
+	}
+	if m.Id != 4242 {
+		t.Errorf("Expected the query to be unmodified, got ID %d", m.Id)
+	}
+}
+
+// startServer starts a DoQ server that answers every query with an empty response after delay.
+func startServer(t *testing.T, delay time.Duration) (string, func()) {
+	l, err := quic.ListenAddr("127.0.0.1:0", serverTLSConfig(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			session, err := l.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			go func() {
+				for {
+					stream, err := session.AcceptStream(context.Background())
+					if err != nil {
+						return
+					}
+					go func() {
+						defer stream.Close()
+						buf, err := io.ReadAll(stream)
+						if err != nil || len(buf) < 2 {
+							return
+						}
+						m := new(dns.Msg)
+						if err := m.Unpack(buf[2:]); err != nil || m.Id != 0 {
+							stream.CancelRead(transport.DoQProtocolError)
+							return
+						}
+						time.Sleep(delay)
+						ret := new(dns.Msg)
+						ret.SetReply(m)
+						out, _ := ret.Pack()
+						stream.Write(addPrefix(out))
+					}()
+				}
+			}()
+		}
+	}()
+	return l.Addr().String(), func() { l.Close() }
+}
+
+func serverTLSConfig(t *testing.T) *tls.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		NextProtos:   []string{"doq"},
+	}
+}
@@ -0,0 +1,130 @@
+package doqclient
+
+import (
+	"context"
+	"errors"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+	"github.com/quic-go/quic-go"
+	"github.com/scionproto/scion/go/lib/daemon"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Kinds of DoQ failures. They are used in log messages and as the kind label of metrics.
+const (
+	KindNoPath            = "no_path"
+	KindDaemonUnreachable = "daemon_unreachable"
+	KindTLSNameMissing    = "tls_name_missing"
+	KindHandshakeTimeout  = "handshake_timeout"
+	KindStreamReset       = "stream_reset"
+	KindSCMP              = "scmp_error"
+	KindOther             = "other"
+)
+
+// Errors to test for with errors.Is, all errors of a kind match its error.
+var (
+	// ErrNoPath means there is no SCION path to the remote AS, or all paths to it are down.
+	ErrNoPath = errors.New("no SCION path")
+	// ErrDaemonUnreachable means the SCION daemon can't be contacted to look up paths.
+	ErrDaemonUnreachable = errors.New("SCION daemon unreachable")
+	// ErrTLSNameMissing means no TLS server name is known to verify the remote with.
+	ErrTLSNameMissing = errors.New("no TLS server name")
+	// ErrHandshakeTimeout means the QUIC handshake didn't complete in time.
+	ErrHandshakeTimeout = errors.New("QUIC handshake timed out")
+	// ErrStreamReset means the remote reset the stream of a query, or the connection was closed while
+	// it was in flight.
+	ErrStreamReset = errors.New("DoQ stream reset")
+	// ErrSCMP means a router on the path answered with an SCMP error, e.g. destination unreachable.
+	ErrSCMP = errors.New("SCMP error")
+)
+
+var kindErrors = map[string]error{
+	KindNoPath:            ErrNoPath,
+	KindDaemonUnreachable: ErrDaemonUnreachable,
+	KindTLSNameMissing:    ErrTLSNameMissing,
+	KindHandshakeTimeout:  ErrHandshakeTimeout,
+	KindStreamReset:       ErrStreamReset,
+	KindSCMP:              ErrSCMP,
+}
+
+// Error is a failed DoQ exchange, classified by Kind.
+type Error struct {
+	Kind string
+	// Transport is the transport of the remote, e.g. squic or quic.
+	Transport string
+	// Addr is the address of the remote.
+	Addr string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Transport + " " + e.Kind + " " + e.Addr + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is returns true if target is the error of e's kind, e.g. ErrNoPath.
+func (e *Error) Is(target error) bool {
+	err, ok := kindErrors[e.Kind]
+	return ok && err == target
+}
+
+// Classify returns err as an *Error for the remote addr over the transport trans. Errors that already
+// are, are returned as is. If dialing is true, running out of time counts as a handshake timeout.
+func Classify(err error, trans, addr string, dialing bool) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return &Error{Kind: kind(err, dialing), Transport: trans, Addr: addr, Err: err}
+}
+
+// KindOf returns the kind of err, KindOther if it isn't classified.
+func KindOf(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	for k, target := range kindErrors {
+		if errors.Is(err, target) {
+			return k
+		}
+	}
+	return KindOther
+}
+
+func kind(err error, dialing bool) string {
+	var (
+		streamErr    *quic.StreamError
+		appErr       *quic.ApplicationError
+		handshakeErr *quic.HandshakeTimeoutError
+		scmpErr      pan.SCMPError
+	)
+	switch {
+	case errors.Is(err, pan.ErrNoPath):
+		return KindNoPath
+	case errors.As(err, &scmpErr):
+		return KindSCMP
+	case errors.As(err, &handshakeErr):
+		return KindHandshakeTimeout
+	case dialing && errors.Is(err, context.DeadlineExceeded):
+		return KindHandshakeTimeout
+	case errors.As(err, &streamErr), !dialing && errors.As(err, &appErr):
+		return KindStreamReset
+	case errors.Is(err, daemon.ErrUnableToConnect), daemonUnavailable(err):
+		return KindDaemonUnreachable
+	}
+	return KindOther
+}
+
+// daemonUnavailable returns true if err is the gRPC status of a SCION daemon that can't be reached. pan
+// passes on the errors of the daemon's path lookups as they are.
+func daemonUnavailable(err error) bool {
+	var s interface{ GRPCStatus() *status.Status }
+	return errors.As(err, &s) && s.GRPCStatus().Code() == codes.Unavailable
+}
@@ -0,0 +1,71 @@
+package doqclient
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+	"github.com/quic-go/quic-go"
+	"github.com/scionproto/scion/go/lib/daemon"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err      error
+		dialing  bool
+		expected string
+		target   error
+	}{
+		{fmt.Errorf("%w to 19-ffaa:1:1067", pan.ErrNoPath), true, KindNoPath, ErrNoPath},
+		{fmt.Errorf("write: %w", pan.ErrNoPath), false, KindNoPath, ErrNoPath},
+		{errors.New("no path to 19-ffaa:1:1067"), true, KindOther, nil},
+		{status.Error(codes.Unavailable, "connection refused"), true, KindDaemonUnreachable, ErrDaemonUnreachable},
+		{fmt.Errorf("connecting: %w", daemon.ErrUnableToConnect), true, KindDaemonUnreachable, ErrDaemonUnreachable},
+		{errors.New("connecting to SCION daemon: connection refused"), true, KindOther, nil},
+		{&quic.HandshakeTimeoutError{}, true, KindHandshakeTimeout, ErrHandshakeTimeout},
+		{fmt.Errorf("dial: %w", context.DeadlineExceeded), true, KindHandshakeTimeout, ErrHandshakeTimeout},
+		{fmt.Errorf("read: %w", context.DeadlineExceeded), false, KindOther, nil},
+		{&quic.StreamError{StreamID: 4, ErrorCode: 2}, false, KindStreamReset, ErrStreamReset},
+		{&quic.ApplicationError{ErrorCode: 2}, false, KindStreamReset, ErrStreamReset},
+		{fmt.Errorf("write: %w", pan.SCMPError{}), false, KindSCMP, ErrSCMP},
+		{errors.New("something else"), false, KindOther, nil},
+	}
+	for i, tc := range tests {
+		err := Classify(tc.err, "squic", "19-ffaa:1:1067,[127.0.0.1]:853", tc.dialing)
+		if kind := KindOf(err); kind != tc.expected {
+			t.Errorf("Test %d: expected kind %s, got %s", i, tc.expected, kind)
+		}
+		if tc.target != nil && !errors.Is(err, tc.target) {
+			t.Errorf("Test %d: expected %v to be %v", i, err, tc.target)
+		}
+		if !errors.Is(err, tc.err) {
+			t.Errorf("Test %d: expected %v to wrap %v", i, err, tc.err)
+		}
+	}
+
+	if Classify(nil, "", "", false) != nil {
+		t.Errorf("Expected nil error to stay nil")
+	}
+	err := Classify(pan.ErrNoPath, "squic", "a", true)
+	if Classify(err, "squic", "b", false) != err {
+		t.Errorf("Expected classified error to be returned as is")
+	}
+	if err := Classify(errors.New("refused"), "quic", "127.0.0.1:853", true); !strings.HasPrefix(err.Error(), "quic other 127.0.0.1:853") {
+		t.Errorf("Expected the error to name the transport, got %q", err)
+	}
+}
+
+func TestDialSCIONNoServerName(t *testing.T) {
+	for _, cfg := range []*tls.Config{nil, {}} {
+		_, err := DialSCION(context.Background(), "19-ffaa:1:1067,[127.0.0.1]:853", nil, cfg, nil)
+		if !errors.Is(err, ErrTLSNameMissing) {
+			t.Errorf("Expected %v, got %v", ErrTLSNameMissing, err)
+		}
+	}
+}
@@ -73,3 +73,19 @@ func Size(proto string, size uint16) uint16 {
 	}
 	return size
 }
+
+// SetExtendedError adds an Extended DNS Error (RFC 8914) option with code and text to m, the response
+// to r. If r doesn't have an OPT record, nothing is added, as the response must not have one either
+// (RFC 6891). If m doesn't have an OPT record yet, one is added like the one of r.
+func SetExtendedError(m, r *dns.Msg, code uint16, text string) {
+	ro := r.IsEdns0()
+	if ro == nil {
+		return
+	}
+	o := m.IsEdns0()
+	if o == nil {
+		m.SetEdns0(ro.UDPSize(), ro.Do())
+		o = m.IsEdns0()
+	}
+	o.Option = append(o.Option, &dns.EDNS0_EDE{InfoCode: code, ExtraText: text})
+}
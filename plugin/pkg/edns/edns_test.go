@@ -47,3 +47,42 @@ func ednsMsg() *dns.Msg {
 	m.Extra = append(m.Extra, o)
 	return m
 }
+
+func TestSetExtendedError(t *testing.T) {
+	r := ednsMsg()
+	m := new(dns.Msg)
+	m.SetRcode(r, dns.RcodeServerFailure)
+
+	SetExtendedError(m, r, dns.ExtendedErrorCodeNetworkError, "no SCION path to 19-ffaa:1:1067")
+
+	o := m.IsEdns0()
+	if o == nil {
+		t.Fatalf("Expected OPT record, got none")
+	}
+	if len(o.Option) != 1 {
+		t.Fatalf("Expected 1 option, got %d", len(o.Option))
+	}
+	ede, ok := o.Option[0].(*dns.EDNS0_EDE)
+	if !ok {
+		t.Fatalf("Expected EDE option, got %T", o.Option[0])
+	}
+	if ede.InfoCode != dns.ExtendedErrorCodeNetworkError {
+		t.Errorf("Expected info code %d, got %d", dns.ExtendedErrorCodeNetworkError, ede.InfoCode)
+	}
+	if ede.ExtraText != "no SCION path to 19-ffaa:1:1067" {
+		t.Errorf("Expected extra text to be set, got %q", ede.ExtraText)
+	}
+}
+
+func TestSetExtendedErrorNoEdns(t *testing.T) {
+	r := new(dns.Msg)
+	r.SetQuestion("example.org.", dns.TypeA)
+	m := new(dns.Msg)
+	m.SetRcode(r, dns.RcodeServerFailure)
+
+	SetExtendedError(m, r, dns.ExtendedErrorCodeNetworkError, "no SCION path to 19-ffaa:1:1067")
+
+	if o := m.IsEdns0(); o != nil {
+		t.Errorf("Expected no OPT record in the response to a query without one, got %s", o)
+	}
+}
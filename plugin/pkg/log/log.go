@@ -6,14 +6,19 @@
 // log.Info("this is some logging"), will log on the Info level.
 //
 // log.Debug("this is debug output"), will log in the Debug level, etc.
+//
+// If JSON is set, every log is written as a JSON object on a line of its own instead.
 package log
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	golog "log"
 	"os"
+	"strings"
 	"sync"
+	"time"
 )
 
 // D controls whether we should output debug logs. If true, we do, once set
@@ -25,21 +30,21 @@ type d struct {
 	sync.RWMutex
 }
 
-// Set enables debug logging.
+// Set enables debug logging, or JSON output.
 func (d *d) Set() {
 	d.Lock()
 	d.on = true
 	d.Unlock()
 }
 
-// Clear disables debug logging.
+// Clear disables debug logging, or JSON output.
 func (d *d) Clear() {
 	d.Lock()
 	d.on = false
 	d.Unlock()
 }
 
-// Value returns if debug logging is enabled.
+// Value returns if debug logging, or JSON output, is enabled.
 func (d *d) Value() bool {
 	d.RLock()
 	b := d.on
@@ -47,14 +52,50 @@ func (d *d) Value() bool {
 	return b
 }
 
+// JSON controls whether the logs are written as JSON objects, with the fields ts, level, logger
+// (the plugin or server, if any) and msg, instead of as text.
+var JSON = &d{}
+
+// entry is a log written as JSON.
+type entry struct {
+	Time   string `json:"ts"`
+	Level  string `json:"level"`
+	Logger string `json:"logger,omitempty"`
+	Msg    string `json:"msg"`
+}
+
+// jsonMu serializes the writes of JSON logs, which bypass the std lib logger.
+var jsonMu sync.Mutex
+
+// output writes msg of level, for the logger with prefix, e.g. "plugin/<name>: ".
+func output(level, prefix, msg string) {
+	if !JSON.Value() {
+		golog.Print(level, prefix, msg)
+		return
+	}
+	b, err := json.Marshal(entry{
+		Time:   time.Now().Format(time.RFC3339Nano),
+		Level:  strings.ToLower(strings.Trim(level, "[] ")),
+		Logger: strings.TrimSuffix(prefix, ": "),
+		Msg:    msg,
+	})
+	if err != nil {
+		golog.Print(level, prefix, msg)
+		return
+	}
+	jsonMu.Lock()
+	defer jsonMu.Unlock()
+	golog.Writer().Write(append(b, '\n'))
+}
+
 // logf calls log.Printf prefixed with level.
 func logf(level, format string, v ...interface{}) {
-	golog.Print(level, fmt.Sprintf(format, v...))
+	output(level, "", fmt.Sprintf(format, v...))
 }
 
 // log calls log.Print prefixed with level.
 func log(level string, v ...interface{}) {
-	golog.Print(level, fmt.Sprint(v...))
+	output(level, "", fmt.Sprint(v...))
 }
 
 // Debug is equivalent to log.Print(), but prefixed with "[DEBUG] ". It only outputs something
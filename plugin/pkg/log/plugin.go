@@ -14,12 +14,16 @@ type P struct {
 // I.e [INFO] plugin/<name>: message.
 func NewWithPlugin(name string) P { return P{"plugin/" + name + ": "} }
 
+// NewWithServer returns a logger that includes the server, i.e. its listen address, in the log message.
+// I.e [INFO] squic://:8853: message.
+func NewWithServer(addr string) P { return P{addr + ": "} }
+
 func (p P) logf(level, format string, v ...interface{}) {
-	log(level, p.plugin, fmt.Sprintf(format, v...))
+	output(level, p.plugin, fmt.Sprintf(format, v...))
 }
 
 func (p P) log(level string, v ...interface{}) {
-	log(level+p.plugin, v...)
+	output(level, p.plugin, fmt.Sprint(v...))
 }
 
 // Debug logs as log.Debug.
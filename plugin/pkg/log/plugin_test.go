@@ -2,9 +2,11 @@ package log
 
 import (
 	"bytes"
+	"encoding/json"
 	golog "log"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestPlugins(t *testing.T) {
@@ -19,3 +21,44 @@ func TestPlugins(t *testing.T) {
 		t.Errorf("Expected log to be %s, got %s", info+ts, x)
 	}
 }
+
+func TestServers(t *testing.T) {
+	var f bytes.Buffer
+	const ts = "test"
+	golog.SetOutput(&f)
+
+	lg := NewWithServer("squic://:8853")
+
+	lg.Info(ts)
+	if x := f.String(); !strings.Contains(x, "[INFO] squic://:8853: test") {
+		t.Errorf("Expected log to contain the server, got %s", x)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var f bytes.Buffer
+	golog.SetOutput(&f)
+	JSON.Set()
+	defer JSON.Clear()
+
+	lg := NewWithServer("squic://:8853")
+	lg.Warningf("%d streams", 2)
+
+	var e entry
+	if err := json.Unmarshal(f.Bytes(), &e); err != nil {
+		t.Fatalf("Expected a JSON log, got %q: %s", f.String(), err)
+	}
+	if e.Level != "warning" || e.Logger != "squic://:8853" || e.Msg != "2 streams" {
+		t.Errorf("Expected a warning of squic://:8853, got %+v", e)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, e.Time); err != nil {
+		t.Errorf("Expected a timestamp, got %q", e.Time)
+	}
+
+	f.Reset()
+	Info("test")
+	e = entry{}
+	if err := json.Unmarshal(f.Bytes(), &e); err != nil || e.Level != "info" || e.Logger != "" {
+		t.Errorf("Expected an info log without logger, got %q", f.String())
+	}
+}
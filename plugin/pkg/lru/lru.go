@@ -0,0 +1,77 @@
+// Package lru implements a cache that holds a fixed number of values and evicts the least recently used
+// one when it is full. Unlike package cache, eviction is not random, so the values used over and over stay
+// in even a small cache.
+package lru
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache is a least recently used cache with string keys. It is safe for concurrent use.
+type Cache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List // of *entry, the most recently used first
+	items map[string]*list.Element
+}
+
+type entry struct {
+	key   string
+	value interface{}
+}
+
+// New returns a new cache for size values. A cache of size 0 holds nothing.
+func New(size int) *Cache {
+	return &Cache{size: size, ll: list.New(), items: make(map[string]*list.Element, size)}
+}
+
+// Get returns the value stored under key and marks it as the most recently used.
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*entry).value, true
+}
+
+// Add stores value under key, replacing the value already stored there. If the cache is full, the least
+// recently used value is evicted.
+func (c *Cache) Add(key string, value interface{}) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		e.Value.(*entry).value = value
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&entry{key: key, value: value})
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry).key)
+	}
+}
+
+// Remove removes the value stored under key.
+func (c *Cache) Remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.ll.Remove(e)
+		delete(c.items, key)
+	}
+}
+
+// Len returns the number of values in the cache.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
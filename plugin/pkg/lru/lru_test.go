@@ -0,0 +1,42 @@
+package lru
+
+import "testing"
+
+func TestCache(t *testing.T) {
+	c := New(2)
+	c.Add("a", 1)
+	c.Add("b", 2)
+	// a is now more recently used than b, which is evicted by c.
+	if v, ok := c.Get("a"); !ok || v.(int) != 1 {
+		t.Fatalf("Expected a to be cached with 1, got %v", v)
+	}
+	c.Add("c", 3)
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := c.Get(k); !ok {
+			t.Errorf("Expected %s to be cached", k)
+		}
+	}
+
+	c.Add("a", 4)
+	if v, _ := c.Get("a"); v.(int) != 4 {
+		t.Errorf("Expected a to be replaced with 4, got %v", v)
+	}
+	c.Remove("c")
+	if _, ok := c.Get("c"); ok {
+		t.Error("Expected c to be removed")
+	}
+	if l := c.Len(); l != 1 {
+		t.Errorf("Expected 1 cached value, got %d", l)
+	}
+}
+
+func TestCacheZeroSize(t *testing.T) {
+	c := New(0)
+	c.Add("a", 1)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected a cache of size 0 to hold nothing")
+	}
+}
@@ -36,6 +36,25 @@ func HostPortOrFile(s ...string) ([]string, error) {
 	for _, h := range s {
 		trans, host := Transport(h)
 
+		if trans == transport.DNS || trans == transport.SQUIC || trans == transport.H3 {
+			// A SCION address, ISD-AS,[IP]:port, is reached over squic, or DoH over HTTP/3.
+			if scaddr, err := pan.ParseUDPAddr(host); err == nil {
+				if trans == transport.DNS {
+					trans = transport.SQUIC
+				}
+				if scaddr.Port == 0 {
+					port := transport.QUICPort
+					if trans == transport.H3 {
+						port = transport.HTTPSPort
+					}
+					p, _ := strconv.Atoi(port)
+					scaddr = scaddr.WithPort(uint16(p))
+				}
+				servers = append(servers, trans+"://"+scaddr.String())
+				continue
+			}
+		}
+
 		addr, port, err := net.SplitHostPort(host)
 
 		if err != nil {
@@ -55,10 +74,14 @@ func HostPortOrFile(s ...string) ([]string, error) {
 				ss = net.JoinHostPort(host, transport.Port)
 			case transport.TLS:
 				ss = transport.TLS + "://" + net.JoinHostPort(host, transport.TLSPort)
+			case transport.QUIC:
+				ss = transport.QUIC + "://" + net.JoinHostPort(host, transport.QUICPort)
 			case transport.GRPC:
 				ss = transport.GRPC + "://" + net.JoinHostPort(host, transport.GRPCPort)
 			case transport.HTTPS:
 				ss = transport.HTTPS + "://" + net.JoinHostPort(host, transport.HTTPSPort)
+			case transport.H3:
+				ss = transport.H3 + "://" + net.JoinHostPort(host, transport.HTTPSPort)
 			}
 			servers = append(servers, ss)
 			continue
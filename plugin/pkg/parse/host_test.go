@@ -58,6 +58,26 @@ func TestHostPortOrFile(t *testing.T) {
 			"",
 			true,
 		},
+		{
+			"squic://19-ffaa:1:fe4,[10.0.0.1]:8853",
+			"squic://19-ffaa:1:fe4,10.0.0.1:8853",
+			false,
+		},
+		{
+			"19-ffaa:1:fe4,[10.0.0.1]",
+			"squic://19-ffaa:1:fe4,10.0.0.1:8853",
+			false,
+		},
+		{
+			"h3://19-ffaa:1:fe4,[10.0.0.1]",
+			"h3://19-ffaa:1:fe4,10.0.0.1:443",
+			false,
+		},
+		{
+			"h3://8.8.8.8",
+			"h3://8.8.8.8:443",
+			false,
+		},
 	}
 
 	err := os.WriteFile("resolv.conf", []byte("nameserver 127.0.0.1\n"), 0600)
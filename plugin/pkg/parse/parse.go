@@ -3,13 +3,19 @@ package parse
 
 import (
 	"fmt"
+	"net"
+	"strconv"
+	"strings"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/plugin/pkg/transport"
+
 	"github.com/miekg/dns"
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
 )
 
-// TransferIn parses transfer statements: 'transfer from [address...]'.
+// TransferIn parses transfer statements: 'transfer from [address...]'. The addresses are normalized with
+// TransferAddr.
 func TransferIn(c *caddy.Controller) (froms []string, err error) {
 	if !c.NextArg() {
 		return nil, c.ArgErr()
@@ -24,21 +30,117 @@ func TransferIn(c *caddy.Controller) (froms []string, err error) {
 			return nil, c.ArgErr()
 		}
 		for i := range froms {
-			if froms[i] != "*" {
-				// TODO: add possibility that 'from' is a domain-name or url here (i.e. squic://ns1.primary.example.org:8853)
-				if _, ok := dns.IsDomainName(froms[i]); ok {
-					continue // nothing to do
-				}
-				normalized, err := HostPort(froms[i], transport.Port)
-
-				if err != nil {
-					return nil, err
-				}
-				froms[i] = normalized
-			} else {
+			if froms[i] == "*" {
 				return nil, fmt.Errorf("can't use '*' in transfer from")
 			}
+			normalized, err := TransferAddr(froms[i])
+			if err != nil {
+				return nil, err
+			}
+			froms[i] = normalized
 		}
 	}
 	return froms, nil
 }
+
+// TransferAddr normalizes the address of a primary to transfer a zone from. The scheme of the result
+// carries the transport:
+//
+//   - an IP address, with an optional port, becomes IP:port, transferred over TCP on port 53 by default;
+//   - a SCION address becomes squic://ISD-AS,IP:port, or squic://ISD-AS,[IP] without a port;
+//   - a domain name without a port becomes the fully qualified, lowercased name, e.g. ns1.example.org.; it
+//     is transferred over squic if the name has a SCION address, and over TCP otherwise;
+//   - a domain name with a port becomes name:port, transferred over TCP;
+//   - tls:// addresses keep their scheme, with the default port added. squic:// addresses keep their
+//     scheme and are left without a port if they have none, so the DoQ port of the scion plugin applies
+//     when the primary is resolved. squic:// needs a SCION address or a domain name, which must have a
+//     SCION address.
+//
+// DoQ over IP (quic://) is not supported for zone transfers.
+func TransferAddr(s string) (string, error) {
+	trans, addr := Transport(s)
+	switch trans {
+	case transport.DNS, transport.TLS, transport.SQUIC:
+	default:
+		return "", fmt.Errorf("can't transfer over %s: `%s'", trans, s)
+	}
+
+	if scaddr, err := pan.ParseUDPAddr(addr); err == nil {
+		if trans != transport.DNS && trans != transport.SQUIC {
+			return "", fmt.Errorf("SCION address is only supported over %s: `%s'", transport.SQUIC, s)
+		}
+		if scaddr.Port == 0 {
+			return transport.SQUIC + "://" + scaddr.IA.String() + ",[" + scaddr.IP.String() + "]", nil
+		}
+		return transport.SQUIC + "://" + scaddr.String(), nil
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	hasPort := err == nil
+	if !hasPort {
+		host = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+		port = defaultPort(trans)
+	}
+
+	if ip := net.ParseIP(stripZone(host)); ip != nil {
+		switch trans {
+		case transport.DNS:
+			return net.JoinHostPort(host, port), nil
+		case transport.SQUIC:
+			return "", fmt.Errorf("%s needs a SCION address or a domain name: `%s'", transport.SQUIC, s)
+		}
+		return trans + "://" + net.JoinHostPort(host, port), nil
+	}
+
+	if !isHostname(host) {
+		return "", fmt.Errorf("must specify an IP address, SCION address or domain name: `%s'", s)
+	}
+	name := strings.ToLower(dns.Fqdn(host))
+	if trans == transport.SQUIC && !hasPort {
+		return trans + "://" + strings.TrimSuffix(name, "."), nil
+	}
+	hostPort := net.JoinHostPort(strings.TrimSuffix(name, "."), port)
+	if trans != transport.DNS {
+		return trans + "://" + hostPort, nil
+	}
+	if !hasPort {
+		return name, nil
+	}
+	return hostPort, nil
+}
+
+// defaultPort returns the default port of trans.
+func defaultPort(trans string) string {
+	switch trans {
+	case transport.TLS:
+		return transport.TLSPort
+	}
+	return transport.Port
+}
+
+// isHostname returns true if s is a domain name made of letters, digits, hyphens and underscores.
+func isHostname(s string) bool {
+	s = strings.TrimSuffix(s, ".")
+	if s == "" {
+		return false
+	}
+	if _, ok := dns.IsDomainName(s); !ok {
+		return false
+	}
+	labels := strings.Split(s, ".")
+	if _, err := strconv.Atoi(labels[len(labels)-1]); err == nil {
+		// Not a name, but a malformed IP address.
+		return false
+	}
+	for _, label := range labels {
+		if label == "" {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
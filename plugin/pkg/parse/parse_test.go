@@ -21,6 +21,29 @@ func TestTransferIn(t *testing.T) {
 			`from 127.0.0.1 127.0.0.2`,
 			false, []string{"127.0.0.1:53", "127.0.0.2:53"},
 		},
+		// Domain names, URLs and SCION addresses
+		{
+			`from NS1.example.org ns1.example.org:5300 19-ffaa:1:fe4,[127.0.0.1] 19-ffaa:1:fe4,[127.0.0.1]:53`,
+			false, []string{"ns1.example.org.", "ns1.example.org:5300", "squic://19-ffaa:1:fe4,[127.0.0.1]", "squic://19-ffaa:1:fe4,127.0.0.1:53"},
+		},
+		{
+			`from squic://ns1.example.org squic://ns1.example.org:853 tls://[::1] dns://127.0.0.1`,
+			false, []string{"squic://ns1.example.org", "squic://ns1.example.org:853", "tls://[::1]:853", "127.0.0.1:53"},
+		},
+		// Bad transfer from squic:// without a SCION address or name
+		{
+			`from squic://127.0.0.1`,
+			true, []string{},
+		},
+		// Bad transfer from a transport that can't transfer
+		{
+			`from https://ns1.example.org`,
+			true, []string{},
+		},
+		{
+			`from quic://127.0.0.1`,
+			true, []string{},
+		},
 		// Bad transfer from garbage
 		{
 			`from !@#$%^&*()`,
@@ -49,6 +72,9 @@ func TestTransferIn(t *testing.T) {
 		}
 
 		if test.expectedFrom != nil {
+			if !test.shouldErr && len(froms) != len(test.expectedFrom) {
+				t.Fatalf("Test %d expected %d addresses, got %d", i, len(test.expectedFrom), len(froms))
+			}
 			for j, got := range froms {
 				if got != test.expectedFrom[j] {
 					t.Fatalf("Test %d expected %v, got %v", i, test.expectedFrom[j], got)
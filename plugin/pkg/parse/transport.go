@@ -34,6 +34,10 @@ func Transport(s string) (trans string, addr string) {
 	case strings.HasPrefix(s, transport.SQUIC+"://"):
 		s = s[len(transport.SQUIC+"://"):]
 		return transport.SQUIC, s
+
+	case strings.HasPrefix(s, transport.H3+"://"):
+		s = s[len(transport.H3+"://"):]
+		return transport.H3, s
 	}
 
 	return transport.DNS, s
@@ -16,6 +16,7 @@ func TestTransport(t *testing.T) {
 		{"grpc://example.org:1443 ", transport.GRPC},
 		{"tls://example.org ", transport.TLS},
 		{"https://example.org ", transport.HTTPS},
+		{"h3://example.org ", transport.H3},
 	} {
 		actual, _ := Transport(test.input)
 		if actual != test.expected {
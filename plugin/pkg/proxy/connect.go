@@ -6,7 +6,6 @@ package proxy
 
 import (
 	"context"
-	"fmt"
 	"io"
 	"net"
 	"net/url"
@@ -14,10 +13,8 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/coredns/coredns/plugin/pkg/transport"
 	"github.com/coredns/coredns/request"
-	"github.com/netsec-ethz/scion-apps/pkg/pan"
-	"github.com/quic-go/quic-go"
-	"inet.af/netaddr"
 
 	"github.com/miekg/dns"
 )
@@ -52,9 +49,9 @@ func (t *Transport) updateDialTimeout(newDialTime time.Duration) {
 // Dial dials the address configured in transport, potentially reusing a connection or creating a new one.
 func (t *Transport) Dial(proto string) (*persistConn, bool, error) {
 	// If tls has been configured; use it.
-	/*if t.tlsConfig != nil {
+	if t.tlsConfig != nil {
 		proto = "tcp-tls"
-	}*/
+	}
 
 	t.dial <- proto
 	pc := <-t.ret
@@ -72,26 +69,6 @@ func (t *Transport) Dial(proto string) (*persistConn, bool, error) {
 		t.updateDialTimeout(time.Since(reqTime))
 		return &persistConn{c: conn}, false, err
 	}
-	if proto == "squic" {
-
-		var local netaddr.IPPort
-
-		var hostForSNI string = t.tlsConfig.ServerName
-		var remote pan.UDPAddr
-		remote, er := pan.ParseUDPAddr(t.addr)
-		if er != nil {
-			fmt.Printf("parse of pan.UPDAddr failed with: %v in dns.Client \n", t.addr)
-		}
-		dialCtx, cancel := context.WithTimeout(context.Background(), timeout)
-		defer cancel()
-		quEarlySess, err := pan.DialQUICEarly(dialCtx, local, remote, nil, nil, hostForSNI, t.tlsConfig, &quic.Config{MaxIdleTimeout: 5 * time.Minute})
-
-		if err != nil {
-			fmt.Printf("dialQUIC failed in dns.Client: %v \n", err.Error())
-		}
-		return &persistConn{c: &dns.Conn{QuicEarlySession: quEarlySess}}, false, err
-	}
-
 	conn, err := dns.DialTimeout(proto, t.addr, timeout)
 	t.updateDialTimeout(time.Since(reqTime))
 	return &persistConn{c: conn}, false, err
@@ -99,6 +76,48 @@ func (t *Transport) Dial(proto string) (*persistConn, bool, error) {
 
 // Connect selects an upstream, sends the request and waits for a response.
 func (p *Proxy) Connect(ctx context.Context, state request.Request, opts Options) (*dns.Msg, error) {
+	if p.race != nil {
+		return p.connectRace(ctx, state, opts)
+	}
+	if p.fallback != nil {
+		return p.connectFallback(ctx, state, opts)
+	}
+	return p.connect(ctx, state, opts)
+}
+
+// connect sends the request to p over its own transport and records the round trip time.
+func (p *Proxy) connect(ctx context.Context, state request.Request, opts Options) (*dns.Msg, error) {
+	start := time.Now()
+	var (
+		ret *dns.Msg
+		err error
+	)
+	switch p.trans {
+	case transport.SQUIC, transport.QUIC, transport.H3:
+		if !p.acquireStream() {
+			return nil, ErrStreamLimit
+		}
+		defer p.releaseStream()
+		if p.trans == transport.H3 {
+			ret, err = p.connectH3(ctx, state, opts)
+		} else {
+			ret, err = p.connectSQUIC(ctx, state, opts)
+		}
+	default:
+		ret, err = p.connectDNS(ctx, state, opts)
+	}
+	switch {
+	case err == nil:
+		p.updateRTT(time.Since(start))
+	case isTimeout(err) && ctx.Err() == nil:
+		// An upstream that doesn't answer is as slow as it gets.
+		p.updateRTT(maxTimeout)
+	}
+	return ret, err
+}
+
+// connectDNS sends the request to p over UDP, TCP or TLS.
+func (p *Proxy) connectDNS(ctx context.Context, state request.Request, opts Options) (*dns.Msg, error) {
 	start := time.Now()
 
 	proto := ""
@@ -110,25 +129,15 @@ func (p *Proxy) Connect(ctx context.Context, state request.Request, opts Options
 	default:
 		proto = state.Proto()
 	}
-	// if server is listening on SCION Address i.e. squic://19-ffaa:1:1067,127.0.0.1:8853  opts.transport is 'squic'.
-	// The Proxy 'p' might have another though
-
-	// only if proxy's Address can successfully be parsed to a SCION pan.UDPAddr
-	// it really is a SCION server himself
-	if _, ok := pan.ParseUDPAddr(p.Addr()); ok == nil {
-		fmt.Printf("forwarded to SCION proxy: %v \n", p.Addr())
-	} else {
-		// Server and Proxy/Forwarder dont use the same Transport
-		// so parse it from Proxy's URL
-
-		var proxy_proto *url.URL
-		var err error
-		proxy_proto, err = url.Parse(p.addr)
-		if err == nil {
-			proto = proxy_proto.Scheme
-		} else { // anything that doesnt specify a scheme i.e. 8.8.8.8 will default to dns://8.8.8.8 plain old UDP
-			proto = "udp"
-		}
+	// Server and Proxy/Forwarder dont use the same Transport
+	// so parse it from Proxy's URL
+	var proxy_proto *url.URL
+	var err error
+	proxy_proto, err = url.Parse(p.addr)
+	if err == nil {
+		proto = proxy_proto.Scheme
+	} else { // anything that doesnt specify a scheme i.e. 8.8.8.8 will default to dns://8.8.8.8 plain old UDP
+		proto = "udp"
 	}
 
 	pc, cached, err := p.transport.Dial(proto)
@@ -2,6 +2,10 @@ package proxy
 
 import (
 	"errors"
+
+	"github.com/coredns/coredns/plugin/pkg/doqclient"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
 )
 
 var (
@@ -11,8 +15,52 @@ var (
 	ErrNoForward = errors.New("no forwarder defined")
 	// ErrCachedClosed means cached connection was closed by peer.
 	ErrCachedClosed = errors.New("cached connection was closed by peer")
+	// ErrStreamLimit means a query wasn't sent because too many are in flight to the upstream, see
+	// Proxy.SetMaxStreams.
+	ErrStreamLimit = errors.New("too many concurrent streams to upstream")
 )
 
+// PathError is returned when a squic upstream can't be reached, because there is no
+// SCION path to its AS or all paths to it are down.
+type PathError struct {
+	// IA is the ISD-AS of the upstream.
+	IA  string
+	Err error
+}
+
+func (e *PathError) Error() string {
+	return "no SCION path to upstream AS " + e.IA + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *PathError) Unwrap() error { return e.Err }
+
+// Is returns true for doqclient.ErrNoPath, so a PathError is of kind doqclient.KindNoPath.
+func (e *PathError) Is(target error) bool { return target == doqclient.ErrNoPath }
+
+// isNoPathError returns true if err signals that there is no usable path: pan's ErrNoPath, or an error of
+// kind doqclient.KindNoPath, such as a PathError.
+func isNoPathError(err error) bool {
+	return errors.Is(err, pan.ErrNoPath) || errors.Is(err, doqclient.ErrNoPath)
+}
+
+// squicError classifies err, returned by the DoQ upstream p, and counts it by its kind.
+func (p *Proxy) squicError(err error, dialing bool) error {
+	err = classify(err, p.trans, p.addr, dialing)
+	SQUICErrorCount.WithLabelValues(p.addr, doqclient.KindOf(err)).Add(1)
+	return err
+}
+
+// classify classifies err, returned by the upstream addr over trans, with doqclient.Classify. A PathError
+// is returned as is.
+func classify(err error, trans, addr string, dialing bool) error {
+	var pathErr *PathError
+	if errors.As(err, &pathErr) {
+		return err
+	}
+	return doqclient.Classify(err, trans, addr, dialing)
+}
+
 // Options holds various Options that can be set.
 type Options struct {
 	// ForceTCP use TCP protocol for upstream DNS request. Has precedence over PreferUDP flag
@@ -23,4 +71,7 @@ type Options struct {
 	HCRecursionDesired bool
 	// HCDomain sets domain for Proxy healthcheck requests
 	HCDomain string
+	// ClientAddrKey, if set, authenticates the client address that is added to requests sent over squic.
+	// It holds the raw key bytes, a string keeps Options comparable.
+	ClientAddrKey string
 }
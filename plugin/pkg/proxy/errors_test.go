@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/doqclient"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+func TestPathError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{fmt.Errorf("%w to 19-ffaa:1:1067", pan.ErrNoPath), true},
+		{&doqclient.Error{Kind: doqclient.KindNoPath, Err: errors.New("no path")}, true},
+		{&PathError{IA: "19-ffaa:1:1067", Err: errors.New("timeout")}, true},
+		{errors.New("no path to 19-ffaa:1:1067"), false},
+		{errors.New("timeout: no recent network activity"), false},
+	}
+	for i, tc := range tests {
+		if got := isNoPathError(tc.err); got != tc.expected {
+			t.Errorf("Test %d: expected %t for %q, got %t", i, tc.expected, tc.err, got)
+		}
+	}
+
+	inner := fmt.Errorf("%w to 19-ffaa:1:1067", pan.ErrNoPath)
+	var err error = &PathError{IA: "19-ffaa:1:1067", Err: inner}
+	if !errors.Is(err, inner) {
+		t.Errorf("Expected PathError to unwrap to the underlying error")
+	}
+	var pathErr *PathError
+	if !errors.As(err, &pathErr) || pathErr.IA != "19-ffaa:1:1067" {
+		t.Errorf("Expected errors.As to find the PathError")
+	}
+}
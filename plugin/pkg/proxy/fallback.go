@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// fallback is the same upstream reached over other transports, i.e. over IP if the upstream is a squic one,
+// which are tried in order when the preferred transport of the proxy fails.
+type fallback struct {
+	proxies []*Proxy
+	ttl     time.Duration
+
+	mu sync.Mutex
+	// current is the index in proxies of the transport that worked last, -1 for the preferred one. It
+	// is used until expires, then the preferred transport is tried first again.
+	current int
+	expires time.Time
+}
+
+// SetFallbacks sets the proxies for the same upstream over other transports, in order of preference. When
+// p fails to answer, they are tried in order, and the first that answers is used for all queries for ttl.
+func (p *Proxy) SetFallbacks(ps []*Proxy, ttl time.Duration) {
+	p.fallback = &fallback{proxies: ps, ttl: ttl, current: -1}
+}
+
+// Fallbacks returns the proxies set with SetFallbacks.
+func (p *Proxy) Fallbacks() []*Proxy {
+	if p.fallback == nil {
+		return nil
+	}
+	return p.fallback.proxies
+}
+
+// connectFallback sends the request in state to p, or, if p failed before, to the fallback that answered
+// last, and on to the next fallbacks until one answers.
+func (p *Proxy) connectFallback(ctx context.Context, state request.Request, opts Options) (*dns.Msg, error) {
+	fb := p.fallback
+	var (
+		ret *dns.Msg
+		err error
+	)
+	for i := fb.first(time.Now()); i < len(fb.proxies); i++ {
+		if i < 0 {
+			ret, err = p.connect(ctx, state, opts)
+		} else {
+			ret, err = fb.proxies[i].Connect(ctx, state, opts)
+		}
+		if err == nil {
+			if i < 0 {
+				return ret, nil
+			}
+			fp := fb.proxies[i]
+			FallbackCount.WithLabelValues(p.addr, fp.trans).Add(1)
+			if fb.worked(i, time.Now()) {
+				log.Infof("Upstream %s failed, using %s://%s for %s", p.addr, fp.trans, fp.addr, fb.ttl)
+			}
+			return ret, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return ret, err
+}
+
+// first returns the index of the transport to try first at now.
+func (fb *fallback) first(now time.Time) int {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	if fb.current >= 0 && now.After(fb.expires) {
+		fb.current = -1
+	}
+	return fb.current
+}
+
+// worked records that the fallback with index i answered at now. It returns true if that is a switch to
+// another transport.
+func (fb *fallback) worked(i int, now time.Time) bool {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	if i == fb.current {
+		return false
+	}
+	fb.current = i
+	fb.expires = now.Add(fb.ttl)
+	return true
+}
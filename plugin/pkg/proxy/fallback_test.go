@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/doqclient"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+func TestFallback(t *testing.T) {
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	// The squic upstream has no path, its queries must end up at the same upstream over UDP.
+	dials := 0
+	p := NewProxy("19-ffaa:1:fe4,[127.0.0.1]:8853", transport.SQUIC)
+	p.SetTLSConfig(&tls.Config{InsecureSkipVerify: true})
+	p.transport.dialQUIC = func(context.Context, string, *tls.Config) (quic.Connection, error) {
+		dials++
+		return nil, &PathError{IA: "19-ffaa:1:fe4", Err: doqclient.ErrNoPath}
+	}
+	fp := NewProxy(s.Addr, transport.DNS)
+	fp.Start(5 * time.Second)
+	defer fp.Stop()
+	p.SetFallbacks([]*Proxy{fp}, 100*time.Millisecond)
+
+	if p.Down(1) {
+		t.Errorf("Expected a proxy with fallbacks to never be down")
+	}
+
+	exchange := func() {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		req := request.Request{Req: m, W: dnstest.NewRecorder(&test.ResponseWriter{})}
+		resp, err := p.Connect(context.Background(), req, Options{PreferUDP: true})
+		if err != nil {
+			t.Fatalf("Expected the fallback to answer, got %s", err)
+		}
+		if len(resp.Answer) != 1 {
+			t.Errorf("Expected 1 answer, got %d", len(resp.Answer))
+		}
+	}
+
+	exchange()
+	exchange()
+	if dials != 1 {
+		t.Errorf("Expected the fallback to be remembered after the first query, got %d dials", dials)
+	}
+
+	// Once the TTL passed, the preferred transport is tried again.
+	time.Sleep(150 * time.Millisecond)
+	exchange()
+	if dials != 2 {
+		t.Errorf("Expected the preferred transport to be tried after the TTL, got %d dials", dials)
+	}
+}
@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/clientaddr"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/pkg/doh"
+	"github.com/coredns/coredns/plugin/pkg/doqclient"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// errNotEarly is returned if a dialed QUIC connection can't be used for HTTP/3.
+var errNotEarly = errors.New("QUIC connection doesn't support 0-RTT")
+
+// newRoundTripper returns the HTTP/3 round tripper for the h3 upstream of t. Whatever the URL of a request,
+// its connections go to t.addr, dialed with t.dialQUIC: over SCION for an upstream with a SCION address,
+// over IP otherwise. The round tripper keeps the connection open for further requests.
+func (t *Transport) newRoundTripper() *http3.RoundTripper {
+	return &http3.RoundTripper{
+		QuicConfig: &quic.Config{MaxIdleTimeout: doqclient.DefaultIdleTimeout},
+		Dial: func(ctx context.Context, _ string, tlsCfg *tls.Config, _ *quic.Config) (quic.EarlyConnection, error) {
+			ConnCacheMissesCount.WithLabelValues(t.addr, "h3").Add(1)
+			reqTime := time.Now()
+			ctx, cancel := context.WithTimeout(ctx, t.dialTimeout())
+			defer cancel()
+			conn, err := t.dialQUIC(ctx, t.addr, t.resumableTLSConfig(tlsCfg))
+			t.updateDialTimeout(time.Since(reqTime))
+			if err != nil {
+				// The round tripper returns the error as is, only here it is known to be from dialing.
+				return nil, classify(err, transport.H3, t.addr, true)
+			}
+			early, ok := conn.(quic.EarlyConnection)
+			if !ok {
+				conn.CloseWithError(quic.ApplicationErrorCode(http3.ErrCodeNoError), "")
+				return nil, errNotEarly
+			}
+			return early, nil
+		},
+	}
+}
+
+// h3URL returns the URL of the DoH endpoint of the h3 upstream p, without the path. Its host is the TLS
+// server name if there is one, as the upstream may serve several names, otherwise the address of p.
+func (p *Proxy) h3URL() string {
+	host, port, err := net.SplitHostPort(p.addr)
+	if err != nil {
+		// ISD-AS,[IP]:port, which is a valid URL host in the form [ISD-AS,IP]:port.
+		host, port = "", ""
+		if a, err := dnsutil.ParseSCIONAddress(p.addr); err == nil {
+			host, port = fmt.Sprintf("%s,%s", a.IA, a.IP), strconv.Itoa(int(a.Port))
+		}
+	}
+	if cfg := p.transport.tlsConfig; cfg != nil && cfg.ServerName != "" {
+		host = cfg.ServerName
+	}
+	return "https://" + net.JoinHostPort(host, port)
+}
+
+// exchangeH3 sends m to the h3 upstream p and reads the response.
+func (p *Proxy) exchangeH3(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	req, err := doh.NewRequest(http.MethodGet, p.h3URL(), m)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.transport.h3.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected HTTP status from %s: %s", p.addr, resp.Status)
+	}
+	return doh.ResponseToMsg(resp)
+}
+
+// connectH3 sends the request in state to the h3 upstream p and waits for the response.
+func (p *Proxy) connectH3(ctx context.Context, state request.Request, opts Options) (*dns.Msg, error) {
+	start := time.Now()
+
+	// A message ID of 0 makes responses cacheable by HTTP caches, as RFC 8484 recommends. The request is
+	// copied, so it can be sent to other upstreams concurrently.
+	req := state.Req.Copy()
+	req.Id = 0
+	if opts.ClientAddrKey != "" {
+		// Let the upstream know who the query is really from.
+		clientaddr.Set(req, state.RemoteAddr(), []byte(opts.ClientAddrKey), time.Now())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, maxTimeout+p.readTimeout)
+	defer cancel()
+	ret, err := p.exchangeH3(ctx, req)
+	if err != nil {
+		return nil, p.squicError(err, false)
+	}
+	ret.Id = state.Req.Id
+	if opts.ClientAddrKey != "" {
+		clientaddr.Strip(ret, state.Req)
+	}
+
+	rc, ok := dns.RcodeToString[ret.Rcode]
+	if !ok {
+		rc = strconv.Itoa(ret.Rcode)
+	}
+
+	RequestCount.WithLabelValues(p.addr).Add(1)
+	RcodeCount.WithLabelValues(rc, p.addr).Add(1)
+	RequestDuration.WithLabelValues(p.addr, rc).Observe(time.Since(start).Seconds())
+
+	return ret, nil
+}
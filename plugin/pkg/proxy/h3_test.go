@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/doh"
+	"github.com/coredns/coredns/plugin/pkg/doqclient"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+func TestConnectH3(t *testing.T) {
+	l, err := quic.ListenAddrEarly("127.0.0.1:0", http3.ConfigureTLSConfig(&tls.Config{Certificates: []tls.Certificate{testCertificate(t)}}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts := make(chan string, 10)
+	s := &http3.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts <- r.Host
+		m, err := doh.RequestToMsg(r)
+		if err != nil || r.URL.Path != doh.Path {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		ret := new(dns.Msg)
+		ret.SetReply(m)
+		ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+		buf, _ := ret.Pack()
+		w.Header().Set("Content-Type", doh.MimeType)
+		w.Write(buf)
+	})}
+	go s.ServeListener(l)
+	defer s.Close()
+
+	p := NewProxy(l.Addr().String(), transport.H3)
+	p.SetTLSConfig(&tls.Config{InsecureSkipVerify: true, ServerName: "dns.example.org"})
+	defer p.transport.h3.Close()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.Id = 4242
+	req := request.Request{Req: m, W: dnstest.NewRecorder(&test.ResponseWriter{})}
+	resp, err := p.Connect(context.Background(), req, Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if resp.Id != 4242 {
+		t.Errorf("Expected the response to carry ID %d, got %d", 4242, resp.Id)
+	}
+	if len(resp.Answer) != 1 {
+		t.Errorf("Expected 1 answer, got %d", len(resp.Answer))
+	}
+	if host := <-hosts; host != "dns.example.org:"+p.addr[len("127.0.0.1:"):] {
+		t.Errorf("Expected the request to be for the TLS server name, got host %q", host)
+	}
+
+	if err := p.health.Check(p); err != nil {
+		t.Errorf("Expected the health check to succeed, got %s", err)
+	}
+}
+
+func TestConnectH3Error(t *testing.T) {
+	// Nothing answers on the socket, the handshake never completes.
+	l, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	p := NewProxy(l.LocalAddr().String(), transport.H3)
+	p.SetTLSConfig(&tls.Config{InsecureSkipVerify: true})
+	defer p.transport.h3.Close()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	req := request.Request{Req: m, W: dnstest.NewRecorder(&test.ResponseWriter{})}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = p.Connect(ctx, req, Options{})
+	var doqErr *doqclient.Error
+	if !errors.As(err, &doqErr) {
+		t.Fatalf("Expected a classified error, got %v", err)
+	}
+	if doqErr.Transport != transport.H3 || doqErr.Kind != doqclient.KindHandshakeTimeout {
+		t.Errorf("Expected an h3 handshake timeout, got %s", err)
+	}
+}
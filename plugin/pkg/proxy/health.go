@@ -48,17 +48,8 @@ func NewHealthChecker(trans string, recursionDesired bool, domain string) Health
 			recursionDesired: recursionDesired,
 			domain:           domain,
 		}
-	case transport.SQUIC:
-		c := new(dns.Client)
-		c.Net = "squic"
-		c.ReadTimeout = 1 * time.Second
-		c.WriteTimeout = 1 * time.Second
-
-		return &dnsHc{
-			c:                c,
-			recursionDesired: recursionDesired,
-			domain:           domain,
-		}
+	case transport.SQUIC, transport.QUIC, transport.H3:
+		return newSQUICHc(recursionDesired, domain)
 	}
 
 	log.Warningf("No healthchecker for transport %q", trans)
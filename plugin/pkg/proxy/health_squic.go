@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
+)
+
+// squicHc is a health checker for a DNS-over-QUIC endpoint reached over SCION. The checks are sent on
+// the connection the queries use, so they only use the paths allowed by the SCION path policy, and a
+// check doesn't cost a handshake of its own. It checks quic upstreams, reached over IP, and h3 upstreams
+// the same way.
+type squicHc struct {
+	tlsConfig        *tls.Config
+	recursionDesired bool
+	domain           string
+	readTimeout      time.Duration
+	writeTimeout     time.Duration
+}
+
+func newSQUICHc(recursionDesired bool, domain string) *squicHc {
+	return &squicHc{
+		recursionDesired: recursionDesired,
+		domain:           domain,
+		readTimeout:      1 * time.Second,
+		writeTimeout:     1 * time.Second,
+	}
+}
+
+// SetTLSConfig only records cfg, the checks use the TLS config of the proxy's transport.
+func (h *squicHc) SetTLSConfig(cfg *tls.Config) { h.tlsConfig = cfg }
+func (h *squicHc) GetTLSConfig() *tls.Config    { return h.tlsConfig }
+
+func (h *squicHc) SetRecursionDesired(recursionDesired bool) {
+	h.recursionDesired = recursionDesired
+}
+func (h *squicHc) GetRecursionDesired() bool {
+	return h.recursionDesired
+}
+
+func (h *squicHc) SetDomain(domain string) {
+	h.domain = domain
+}
+func (h *squicHc) GetDomain() string {
+	return h.domain
+}
+
+// SetTCPTransport is a noop, DNS-over-QUIC has no TCP fallback.
+func (h *squicHc) SetTCPTransport() {}
+
+func (h *squicHc) GetReadTimeout() time.Duration {
+	return h.readTimeout
+}
+
+func (h *squicHc) SetReadTimeout(t time.Duration) {
+	h.readTimeout = t
+}
+
+func (h *squicHc) GetWriteTimeout() time.Duration {
+	return h.writeTimeout
+}
+
+func (h *squicHc) SetWriteTimeout(t time.Duration) {
+	h.writeTimeout = t
+}
+
+// Check is used as the up.Func in the up.Probe. If there is no path to the AS of the upstream, it is
+// taken out of rotation right away instead of after max_fails failed checks, the checks continue until it
+// answers again.
+func (h *squicHc) Check(p *Proxy) error {
+	err := h.send(p)
+	if err != nil {
+		HealthcheckFailureCount.WithLabelValues(p.addr).Add(1)
+		if isNoPathError(err) {
+			if atomic.SwapUint32(&p.fails, unreachableFails) != unreachableFails {
+				log.Warningf("Taking squic upstream %s out of rotation: %s", p.addr, err)
+			}
+			return err
+		}
+		p.incrementFails()
+		return err
+	}
+
+	if atomic.SwapUint32(&p.fails, 0) == unreachableFails {
+		log.Infof("squic upstream %s is reachable again", p.addr)
+	}
+	return nil
+}
+
+// unreachableFails is the number of fails of a squic upstream without a path to its AS, it is down for
+// any max_fails.
+const unreachableFails = math.MaxUint32
+
+func (h *squicHc) send(p *Proxy) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.writeTimeout+h.readTimeout)
+	defer cancel()
+
+	ping := new(dns.Msg)
+	ping.SetQuestion(h.domain, dns.TypeNS)
+	ping.MsgHdr.RecursionDesired = h.recursionDesired
+	ping.Id = 0
+
+	if p.trans == transport.H3 {
+		_, err := p.exchangeH3(ctx, ping)
+		return err
+	}
+	c, _, err := p.transport.dialSQUIC(ctx)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	_, err = c.exchange(ctx, ping, deadline)
+	return err
+}
@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+// pathRTTExpire is how long the round trip time measured over a SCION path is trusted. Once it expired, the
+// path is tried again, so a path that was slow at some point isn't avoided forever.
+const pathRTTExpire = time.Minute
+
+// RTT returns the smoothed round trip time of the queries to p, 0 if p didn't answer any query yet.
+func (p *Proxy) RTT() time.Duration { return time.Duration(atomic.LoadInt64(&p.avgRTT)) }
+
+// updateRTT moves the smoothed round trip time of p towards rtt.
+func (p *Proxy) updateRTT(rtt time.Duration) {
+	if !atomic.CompareAndSwapInt64(&p.avgRTT, 0, int64(rtt)) {
+		averageTimeout(&p.avgRTT, rtt, cumulativeAvgWeight)
+	}
+	RTT.WithLabelValues(p.addr).Set(p.RTT().Seconds())
+}
+
+// isTimeout returns true if err is a network timeout.
+func isTimeout(err error) bool {
+	var nerr net.Error
+	return errors.As(err, &nerr) && nerr.Timeout()
+}
+
+// pathRTT is the smoothed round trip time of the queries over a SCION path.
+type pathRTT struct {
+	avg  int64
+	seen time.Time
+}
+
+// pathSelector is the pan.Selector of the connection to a squic upstream. It keeps using the path with the
+// lowest smoothed round trip time, after it measured each path the policy allows, in the policy's order.
+// It outlives the connections, so a new connection starts on the fastest path known.
+type pathSelector struct {
+	addr string
+
+	mu      sync.Mutex
+	paths   []*pan.Path
+	current int
+	rtts    map[pan.PathFingerprint]*pathRTT
+}
+
+func newPathSelector(addr string) *pathSelector {
+	return &pathSelector{addr: addr, rtts: make(map[pan.PathFingerprint]*pathRTT)}
+}
+
+// Path implements pan.Selector.
+func (s *pathSelector) Path() *pan.Path {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.paths) == 0 {
+		return nil
+	}
+	return s.paths[s.current]
+}
+
+// Initialize implements pan.Selector.
+func (s *pathSelector) Initialize(_, _ pan.UDPAddr, paths []*pan.Path) { s.Refresh(paths) }
+
+// Refresh implements pan.Selector.
+func (s *pathSelector) Refresh(paths []*pan.Path) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paths = paths
+	s.pick(time.Now())
+}
+
+// PathDown implements pan.Selector. A path that is down counts as one that doesn't answer.
+func (s *pathSelector) PathDown(pf pan.PathFingerprint, pi pan.PathInterface) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, path := range s.paths {
+		if path.Fingerprint == pf || onPath(path, pi) {
+			s.rtts[path.Fingerprint] = &pathRTT{avg: int64(maxTimeout), seen: now}
+		}
+	}
+	s.pick(now)
+}
+
+// Close implements pan.Selector.
+func (s *pathSelector) Close() error { return nil }
+
+// observe records that a query over path took rtt, and switches to another path if that one is faster now.
+func (s *pathSelector) observe(path *pan.Path, rtt time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	r, ok := s.rtts[path.Fingerprint]
+	if !ok || now.Sub(r.seen) > pathRTTExpire {
+		r = &pathRTT{avg: int64(rtt)}
+		s.rtts[path.Fingerprint] = r
+	} else {
+		averageTimeout(&r.avg, rtt, cumulativeAvgWeight)
+	}
+	r.seen = now
+	PathRTT.WithLabelValues(s.addr, path.String()).Set(time.Duration(r.avg).Seconds())
+	s.pick(now)
+}
+
+// pick makes the path with the lowest round trip time the current one. A path without a recent measurement
+// is picked first, to measure it. s.mu must be held.
+func (s *pathSelector) pick(now time.Time) {
+	best, bestRTT := 0, time.Duration(-1)
+	for i, path := range s.paths {
+		rtt := time.Duration(0)
+		if r, ok := s.rtts[path.Fingerprint]; ok && now.Sub(r.seen) <= pathRTTExpire {
+			rtt = time.Duration(r.avg)
+		}
+		if bestRTT < 0 || rtt < bestRTT {
+			best, bestRTT = i, rtt
+		}
+	}
+	s.current = best
+}
+
+// onPath returns true if the interface pi is on path.
+func onPath(path *pan.Path, pi pan.PathInterface) bool {
+	if path.Metadata == nil {
+		return false
+	}
+	for _, i := range path.Metadata.Interfaces {
+		if i == pi {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+func TestRTT(t *testing.T) {
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	p := NewProxy(s.Addr, transport.DNS)
+	p.Start(5 * time.Second)
+	defer p.Stop()
+
+	if p.RTT() != 0 {
+		t.Errorf("Expected no RTT before the first query, got %s", p.RTT())
+	}
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	req := request.Request{Req: m, W: dnstest.NewRecorder(&test.ResponseWriter{})}
+	if _, err := p.Connect(context.Background(), req, Options{PreferUDP: true}); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if p.RTT() <= 0 || p.RTT() >= maxTimeout {
+		t.Errorf("Expected the RTT of the query, got %s", p.RTT())
+	}
+
+	// A timeout moves the RTT towards maxTimeout.
+	rtt := p.RTT()
+	p.updateRTT(maxTimeout)
+	if p.RTT() <= rtt {
+		t.Errorf("Expected the RTT to grow after a timeout, got %s", p.RTT())
+	}
+}
+
+func TestPathSelector(t *testing.T) {
+	a := &pan.Path{Fingerprint: "a"}
+	b := &pan.Path{Fingerprint: "b"}
+	c := &pan.Path{Fingerprint: "c"}
+
+	s := newPathSelector("19-ffaa:1:fe4,[127.0.0.1]:8853")
+	if s.Path() != nil {
+		t.Errorf("Expected no path before Initialize")
+	}
+	s.Initialize(pan.UDPAddr{}, pan.UDPAddr{}, []*pan.Path{a, b, c})
+
+	// Every path is measured once, in the order of the policy, then the fastest one is kept.
+	for _, o := range []struct {
+		path *pan.Path
+		rtt  time.Duration
+	}{{a, 30 * time.Millisecond}, {b, 10 * time.Millisecond}, {c, 20 * time.Millisecond}} {
+		if s.Path() != o.path {
+			t.Fatalf("Expected path %s to be measured, got %s", o.path.Fingerprint, s.Path().Fingerprint)
+		}
+		s.observe(o.path, o.rtt)
+	}
+	if s.Path() != b {
+		t.Errorf("Expected the fastest path b, got %s", s.Path().Fingerprint)
+	}
+
+	// A path that is down is avoided.
+	s.PathDown("b", pan.PathInterface{})
+	if s.Path() != c {
+		t.Errorf("Expected path c after b went down, got %s", s.Path().Fingerprint)
+	}
+
+	// The measurements survive a refresh of the paths.
+	s.Refresh([]*pan.Path{c, a})
+	if s.Path() != c {
+		t.Errorf("Expected path c after a refresh, got %s", s.Path().Fingerprint)
+	}
+}
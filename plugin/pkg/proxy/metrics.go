@@ -46,4 +46,40 @@ var (
 		Name:      "conn_cache_misses_total",
 		Help:      "Counter of connection cache misses per upstream and protocol.",
 	}, []string{"to", "proto"})
+	SQUICErrorCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "proxy",
+		Name:      "squic_errors_total",
+		Help:      "Counter of failed requests to squic, quic and h3 upstreams per upstream and kind of failure.",
+	}, []string{"to", "kind"})
+	FallbackCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "proxy",
+		Name:      "fallbacks_total",
+		Help:      "Counter of requests answered over a fallback transport per upstream and transport.",
+	}, []string{"to", "transport"})
+	RaceCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "proxy",
+		Name:      "race_wins_total",
+		Help:      "Counter of raced requests per upstream and whether the upstream or the racer answered first.",
+	}, []string{"to", "winner"})
+	RTT = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "proxy",
+		Name:      "rtt_seconds",
+		Help:      "Gauge of the smoothed round trip time of the requests per upstream.",
+	}, []string{"to"})
+	PathRTT = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "proxy",
+		Name:      "path_rtt_seconds",
+		Help:      "Gauge of the smoothed round trip time of the requests per squic upstream and SCION path.",
+	}, []string{"to", "path"})
+	StreamLimitCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "proxy",
+		Name:      "stream_limit_rejects_total",
+		Help:      "Counter of requests not sent because the concurrent streams to the upstream were at maximum.",
+	}, []string{"to"})
 )
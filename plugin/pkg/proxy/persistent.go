@@ -1,11 +1,17 @@
 package proxy
 
 import (
+	"context"
 	"crypto/tls"
 	"sort"
+	"sync"
 	"time"
 
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
 	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
 )
 
 // a persistConn hold the dns.Conn and the last used time.
@@ -22,6 +28,19 @@ type Transport struct {
 	addr        string
 	tlsConfig   *tls.Config
 
+	// squic is the connection to a squic or quic upstream, shared by all queries, see squicConn.
+	squicMu sync.Mutex
+	squic   *squicConn
+	// doq is the transport of a DoQ upstream, squic or quic, and dialQUIC dials its connection.
+	doq      string
+	dialQUIC func(context.Context, string, *tls.Config) (quic.Connection, error)
+	// paths selects the SCION path of the connection to a squic upstream.
+	paths *pathSelector
+	// h3 sends the requests to an h3 upstream, it dials its connections with dialQUIC.
+	h3 *http3.RoundTripper
+	// sessions caches the TLS session tickets of a DoQ or h3 upstream, see resumableTLSConfig.
+	sessions tls.ClientSessionCache
+
 	dial  chan string
 	yield chan *persistConn
 	ret   chan *persistConn
@@ -34,11 +53,15 @@ func newTransport(addr string) *Transport {
 		conns:       [typeTotalCount][]*persistConn{},
 		expire:      defaultExpire,
 		addr:        addr,
+		doq:         transport.SQUIC,
+		paths:       newPathSelector(addr),
+		sessions:    tls.NewLRUClientSessionCache(1),
 		dial:        make(chan string),
 		yield:       make(chan *persistConn),
 		ret:         make(chan *persistConn),
 		stop:        make(chan bool),
 	}
+	t.dialQUIC = t.dialSCION
 	return t
 }
 
@@ -140,14 +163,31 @@ func (t *Transport) Yield(pc *persistConn) {
 // Start starts the transport's connection manager.
 func (t *Transport) Start() { go t.connManager() }
 
-// Stop stops the transport's connection manager.
-func (t *Transport) Stop() { close(t.stop) }
+// Stop stops the transport's connection manager and closes the connection to a DoQ upstream.
+func (t *Transport) Stop() {
+	close(t.stop)
+
+	t.squicMu.Lock()
+	defer t.squicMu.Unlock()
+	if t.squic != nil {
+		go t.squic.close()
+		t.squic = nil
+	}
+	if t.h3 != nil {
+		t.h3.Close()
+	}
+}
 
 // SetExpire sets the connection expire time in transport.
 func (t *Transport) SetExpire(expire time.Duration) { t.expire = expire }
 
 // SetTLSConfig sets the TLS config in transport.
-func (t *Transport) SetTLSConfig(cfg *tls.Config) { t.tlsConfig = cfg }
+func (t *Transport) SetTLSConfig(cfg *tls.Config) {
+	t.tlsConfig = cfg
+	if t.h3 != nil {
+		t.h3.TLSClientConfig = cfg
+	}
+}
 
 const (
 	defaultExpire  = 10 * time.Second
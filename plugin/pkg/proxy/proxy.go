@@ -7,17 +7,33 @@ import (
 	"time"
 
 	"github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/transport"
 	"github.com/coredns/coredns/plugin/pkg/up"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
 )
 
 // Proxy defines an upstream host.
 type Proxy struct {
+	// avgRTT is the smoothed round trip time and streams the number of open streams to a DoQ or h3 upstream,
+	// first for the alignment of atomic access on 32-bit platforms.
+	avgRTT  int64
+	streams int64
+
 	fails uint32
 	addr  string
+	trans string
 
 	transport *Transport
 
 	readTimeout time.Duration
+	// maxStreams limits streams, 0 means no limit.
+	maxStreams int64
+
+	// fallback, if not nil, has the proxies for the same upstream over other transports.
+	fallback *fallback
+	// race, if not nil, has the proxy queries are raced against.
+	race *race
 
 	// health checking
 	probe  *up.Probe
@@ -28,11 +44,21 @@ type Proxy struct {
 func NewProxy(addr, trans string) *Proxy {
 	p := &Proxy{
 		addr:        addr,
+		trans:       trans,
 		fails:       0,
 		probe:       up.New(),
 		readTimeout: 2 * time.Second,
 		transport:   newTransport(addr),
 	}
+	if trans == transport.QUIC {
+		p.transport.doq, p.transport.dialQUIC = transport.QUIC, dialIP
+	}
+	if trans == transport.H3 {
+		if _, err := pan.ParseUDPAddr(addr); err != nil {
+			p.transport.dialQUIC = dialIP
+		}
+		p.transport.doq, p.transport.h3 = transport.H3, p.transport.newRoundTripper()
+	}
 	p.health = NewHealthChecker(trans, true, ".")
 	runtime.SetFinalizer(p, (*Proxy).finalizer)
 	return p
@@ -69,9 +95,10 @@ func (p *Proxy) Healthcheck() {
 	})
 }
 
-// Down returns true if this proxy is down, i.e. has *more* fails than maxfails.
+// Down returns true if this proxy is down, i.e. has *more* fails than maxfails. A proxy with fallbacks is
+// never down, while it fails its queries go to the fallbacks.
 func (p *Proxy) Down(maxfails uint32) bool {
-	if maxfails == 0 {
+	if maxfails == 0 || p.fallback != nil {
 		return false
 	}
 
@@ -87,8 +114,33 @@ func (p *Proxy) finalizer() { p.transport.Stop() }
 func (p *Proxy) Start(duration time.Duration) {
 	p.probe.Start(duration)
 	p.transport.Start()
+	for _, fp := range p.Fallbacks() {
+		fp.transport.Start()
+	}
+	if p.race != nil && p.fallback == nil {
+		// Without fallbacks the racer is a proxy of its own.
+		p.race.racer.transport.Start()
+	}
+}
+
+// SetMaxStreams limits the number of queries sent concurrently on streams of the QUIC connection to a squic,
+// quic or h3 upstream to max. Queries over the limit fail with ErrStreamLimit. 0 means no limit.
+func (p *Proxy) SetMaxStreams(max int64) { p.maxStreams = max }
+
+// acquireStream reserves a stream for a query, it returns false if the limit of streams is reached. A
+// reserved stream must be given back with releaseStream.
+func (p *Proxy) acquireStream() bool {
+	n := atomic.AddInt64(&p.streams, 1)
+	if p.maxStreams > 0 && n > p.maxStreams {
+		atomic.AddInt64(&p.streams, -1)
+		StreamLimitCount.WithLabelValues(p.addr).Add(1)
+		return false
+	}
+	return true
 }
 
+func (p *Proxy) releaseStream() { atomic.AddInt64(&p.streams, -1) }
+
 func (p *Proxy) SetReadTimeout(duration time.Duration) {
 	p.readTimeout = duration
 }
@@ -0,0 +1,122 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+	"github.com/quic-go/quic-go"
+)
+
+// race is the second proxy a query to p is sent to when p doesn't answer within stagger.
+type race struct {
+	racer   *Proxy
+	stagger time.Duration
+}
+
+// SetRace makes p race its queries: a query that isn't answered within stagger is sent again to the first
+// fallback of p, i.e. over IP if p is a squic upstream, or, without fallbacks, to p over another SCION path
+// than the one its own connection uses. The first answer wins, the other query is cancelled. It returns
+// false if p can't be raced, because it has neither fallbacks nor a SCION address.
+func (p *Proxy) SetRace(stagger time.Duration) bool {
+	var racer *Proxy
+	switch {
+	case len(p.Fallbacks()) > 0:
+		racer = p.Fallbacks()[0]
+	case p.trans == transport.SQUIC:
+		racer = NewProxy(p.addr, transport.SQUIC)
+		racer.SetTLSConfig(p.transport.tlsConfig)
+		racer.transport.dialQUIC = dialSCIONDisjoint
+	default:
+		return false
+	}
+	p.race = &race{racer: racer, stagger: stagger}
+	return true
+}
+
+// dialSCIONDisjoint dials like dialSCION, over a path that shares as few interfaces as possible with the
+// first path of the policy.
+func dialSCIONDisjoint(ctx context.Context, addr string, tlsConfig *tls.Config) (quic.Connection, error) {
+	policy, err := pkgscion.Get().PathPolicy()
+	if err != nil {
+		return nil, err
+	}
+	return dialSCIONPolicy(ctx, addr, pan.PolicyChain{pkgscion.QUICPolicy(policy), pkgscion.DisjointPolicy{}}, nil, tlsConfig)
+}
+
+type raceResult struct {
+	ret   *dns.Msg
+	err   error
+	racer bool
+}
+
+// connectRace sends the request in state to p and, if there is no answer within the stagger, to the racer.
+func (p *Proxy) connectRace(ctx context.Context, state request.Request, opts Options) (*dns.Msg, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	// Cancels the query that lost.
+	defer cancel()
+
+	results := make(chan raceResult, 2)
+	send := func(pp *Proxy, racer bool) {
+		// Both queries may be in flight at the same time, each gets its own copy of the request.
+		s := request.Request{Req: state.Req.Copy(), W: state.W}
+		var res raceResult
+		if racer {
+			res.ret, res.err = pp.Connect(ctx, s, opts)
+		} else {
+			res.ret, res.err = pp.connect(ctx, s, opts)
+		}
+		res.racer = racer
+		results <- res
+	}
+
+	go send(p, false)
+	stagger := time.NewTimer(p.race.stagger)
+	defer stagger.Stop()
+
+	inflight := 1
+	raced := false
+	var last raceResult
+	for inflight > 0 {
+		select {
+		case <-stagger.C:
+			if raced {
+				// The timer fired while the first query failed.
+				continue
+			}
+			raced = true
+			inflight++
+			go send(p.race.racer, true)
+		case res := <-results:
+			inflight--
+			if res.err == nil {
+				if raced {
+					RaceCount.WithLabelValues(p.addr, winner(res.racer)).Add(1)
+				}
+				return res.ret, nil
+			}
+			last = res
+			if !raced {
+				// Don't wait for the stagger when the query already failed.
+				stagger.Stop()
+				raced = true
+				inflight++
+				go send(p.race.racer, true)
+			}
+		}
+	}
+	return last.ret, last.err
+}
+
+func winner(racer bool) string {
+	if racer {
+		return "racer"
+	}
+	return "upstream"
+}
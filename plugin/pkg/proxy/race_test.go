@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+func TestRace(t *testing.T) {
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	// The path of the squic upstream hangs, the query must be answered over UDP, and the hanging one
+	// cancelled.
+	cancelled := make(chan struct{})
+	p := NewProxy("19-ffaa:1:fe4,[127.0.0.1]:8853", transport.SQUIC)
+	p.SetTLSConfig(&tls.Config{InsecureSkipVerify: true})
+	p.transport.dialQUIC = func(ctx context.Context, _ string, _ *tls.Config) (quic.Connection, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	}
+	fp := NewProxy(s.Addr, transport.DNS)
+	p.SetFallbacks([]*Proxy{fp}, time.Minute)
+	if !p.SetRace(10 * time.Millisecond) {
+		t.Fatalf("Expected a proxy with fallbacks to be raced")
+	}
+	p.Start(5 * time.Second)
+	defer p.Stop()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	req := request.Request{Req: m, W: dnstest.NewRecorder(&test.ResponseWriter{})}
+	resp, err := p.Connect(context.Background(), req, Options{PreferUDP: true})
+	if err != nil {
+		t.Fatalf("Expected the racer to answer, got %s", err)
+	}
+	if len(resp.Answer) != 1 {
+		t.Errorf("Expected 1 answer, got %d", len(resp.Answer))
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Errorf("Expected the query that lost to be cancelled")
+	}
+}
+
+func TestRaceSQUIC(t *testing.T) {
+	addr, stop := startDoQServer(t)
+	defer stop()
+
+	p := NewProxy(addr, transport.SQUIC)
+	p.SetTLSConfig(&tls.Config{InsecureSkipVerify: true, NextProtos: []string{"doq"}})
+	p.transport.dialQUIC = func(ctx context.Context, _ string, _ *tls.Config) (quic.Connection, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if !p.SetRace(10 * time.Millisecond) {
+		t.Fatalf("Expected a squic proxy to be raced")
+	}
+	// The racer dials over another path, in tests over plain QUIC.
+	p.race.racer.transport.dialQUIC = func(ctx context.Context, addr string, tlsConfig *tls.Config) (quic.Connection, error) {
+		return quic.DialAddrEarlyContext(ctx, addr, tlsConfig, nil)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	req := request.Request{Req: m, W: dnstest.NewRecorder(&test.ResponseWriter{})}
+	resp, err := p.Connect(context.Background(), req, Options{})
+	if err != nil {
+		t.Fatalf("Expected the racer to answer, got %s", err)
+	}
+	if len(resp.Answer) != 1 {
+		t.Errorf("Expected 1 answer, got %d", len(resp.Answer))
+	}
+
+	if NewProxy("127.0.0.1:53", transport.DNS).SetRace(time.Millisecond) {
+		t.Errorf("Expected a DNS proxy without fallbacks not to be raced")
+	}
+}
@@ -0,0 +1,253 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/clientaddr"
+	"github.com/coredns/coredns/plugin/pkg/doqclient"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+	"github.com/quic-go/quic-go"
+)
+
+// squicConn is the persistent DNS-over-QUIC connection to a squic, or quic, upstream. Unlike a persistConn it isn't
+// taken out of the cache while a query uses it: every query is sent on a stream of its own, so a single
+// connection carries all queries and health checks to the upstream concurrently. It doesn't expire like a
+// persistConn either: as dialing costs a path lookup and a handshake, it is kept until the upstream closes
+// it or it was idle for the QUIC idle timeout.
+type squicConn struct {
+	session quic.Connection
+	// handshake makes the connection usable again after the upstream rejected its 0-RTT data.
+	handshake sync.Once
+}
+
+// rejected0RTT waits for the full handshake of c after the upstream rejected its 0-RTT data, i.e. because
+// the session ticket expired. Streams opened before fail with quic.Err0RTTRejected, new ones can be opened
+// afterwards.
+func (c *squicConn) rejected0RTT() {
+	c.handshake.Do(func() {
+		if early, ok := c.session.(quic.EarlyConnection); ok {
+			early.NextConnection()
+		}
+	})
+}
+
+// alive returns false once the QUIC connection is closed, by either side or because it was idle.
+func (c *squicConn) alive() bool {
+	select {
+	case <-c.session.Context().Done():
+		return false
+	default:
+		return true
+	}
+}
+
+func (c *squicConn) close() { _ = c.session.CloseWithError(transport.DoQNoError, "") }
+
+// exchange sends m on a new stream and reads the response. The message ID must already be 0, as DoQ
+// requires. The stream is aborted if there is no response before deadline.
+func (c *squicConn) exchange(ctx context.Context, m *dns.Msg, deadline time.Time) (*dns.Msg, error) {
+	buf, err := m.Pack()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	stream, err := c.session.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream.SetDeadline(deadline)
+
+	// Abort the stream as soon as the query isn't wanted anymore, i.e. because it lost a race.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			stream.CancelWrite(transport.DoQRequestCancelled)
+			stream.CancelRead(transport.DoQRequestCancelled)
+		case <-done:
+		}
+	}()
+
+	b := make([]byte, 2+len(buf))
+	binary.BigEndian.PutUint16(b, uint16(len(buf)))
+	copy(b[2:], buf)
+	if _, err := stream.Write(b); err != nil {
+		stream.CancelRead(transport.DoQRequestCancelled)
+		return nil, err
+	}
+	// Closing the stream sends the STREAM FIN, telling the upstream no more data follows.
+	stream.Close()
+
+	var l [2]byte
+	if _, err := io.ReadFull(stream, l[:]); err != nil {
+		stream.CancelRead(transport.DoQRequestCancelled)
+		return nil, err
+	}
+	buf = make([]byte, binary.BigEndian.Uint16(l[:]))
+	if _, err := io.ReadFull(stream, buf); err != nil {
+		stream.CancelRead(transport.DoQRequestCancelled)
+		return nil, err
+	}
+	stream.CancelRead(transport.DoQNoError)
+
+	ret := new(dns.Msg)
+	if err := ret.Unpack(buf); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// dialIP dials a QUIC connection to the quic upstream addr.
+func dialIP(ctx context.Context, addr string, tlsConfig *tls.Config) (quic.Connection, error) {
+	return quic.DialAddrEarlyContext(ctx, addr, tlsConfig, &quic.Config{MaxIdleTimeout: doqclient.DefaultIdleTimeout})
+}
+
+// dialSCION dials a QUIC connection to the squic upstream addr, over the paths the scion plugin allows,
+// preferring the fastest one.
+func (t *Transport) dialSCION(ctx context.Context, addr string, tlsConfig *tls.Config) (quic.Connection, error) {
+	policy, err := pkgscion.Get().PathPolicy()
+	if err != nil {
+		return nil, err
+	}
+	return dialSCIONPolicy(ctx, addr, policy, t.paths, tlsConfig)
+}
+
+// dialSCIONPolicy dials a QUIC connection to the squic upstream addr, over the paths policy allows. selector
+// may be nil to use the first path of the policy.
+func dialSCIONPolicy(ctx context.Context, addr string, policy pan.Policy, selector pan.Selector, tlsConfig *tls.Config) (quic.Connection, error) {
+	session, err := doqclient.DialSCIONSelector(ctx, addr, policy, selector, tlsConfig, &quic.Config{MaxIdleTimeout: doqclient.DefaultIdleTimeout})
+	if err != nil {
+		if isNoPathError(err) {
+			if remote, perr := pan.ParseUDPAddr(addr); perr == nil {
+				err = &PathError{IA: remote.IA.String(), Err: err}
+			}
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+// dialSQUIC returns the connection to the squic upstream, reusing the cached one unless it is closed. The
+// returned bool is true if the connection came from the cache.
+func (t *Transport) dialSQUIC(ctx context.Context) (*squicConn, bool, error) {
+	// Holding the lock while dialing makes concurrent queries wait for a single new connection.
+	t.squicMu.Lock()
+	defer t.squicMu.Unlock()
+
+	if c := t.squic; c != nil {
+		if c.alive() {
+			ConnCacheHitsCount.WithLabelValues(t.addr, t.doq).Add(1)
+			return c, true, nil
+		}
+		t.squic = nil
+		go c.close()
+	}
+	ConnCacheMissesCount.WithLabelValues(t.addr, t.doq).Add(1)
+
+	reqTime := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, t.dialTimeout())
+	defer cancel()
+	session, err := t.dialQUIC(ctx, t.addr, t.resumableTLSConfig(t.tlsConfig))
+	t.updateDialTimeout(time.Since(reqTime))
+	if err != nil {
+		return nil, false, err
+	}
+	t.squic = &squicConn{session: session}
+	return t.squic, false, nil
+}
+
+// resumableTLSConfig returns a copy of cfg that stores the session tickets of the upstream in t. A new
+// connection to the upstream then resumes the TLS session, and as it is dialed early, the first query is
+// sent as 0-RTT data, without waiting a round trip for the handshake, which may be long over SCION.
+func (t *Transport) resumableTLSConfig(cfg *tls.Config) *tls.Config {
+	if cfg == nil {
+		return nil
+	}
+	cfg = cfg.Clone()
+	cfg.ClientSessionCache = t.sessions
+	return cfg
+}
+
+// dropSQUIC removes c from the cache, if it still is the cached connection, and closes it.
+func (t *Transport) dropSQUIC(c *squicConn) {
+	t.squicMu.Lock()
+	if t.squic == c {
+		t.squic = nil
+	}
+	t.squicMu.Unlock()
+	c.close()
+}
+
+// connectSQUIC sends the request in state to the squic or quic upstream p and waits for the response.
+func (p *Proxy) connectSQUIC(ctx context.Context, state request.Request, opts Options) (*dns.Msg, error) {
+	start := time.Now()
+
+	c, cached, err := p.transport.dialSQUIC(ctx)
+	if err != nil {
+		return nil, p.squicError(err, true)
+	}
+
+	// DoQ requires a message ID of 0, the stream already ties the response to the query. The request
+	// is copied, so it can be sent to other upstreams concurrently.
+	req := state.Req.Copy()
+	req.Id = 0
+	if opts.ClientAddrKey != "" {
+		// Let the upstream know who the query is really from.
+		clientaddr.Set(req, state.RemoteAddr(), []byte(opts.ClientAddrKey), time.Now())
+	}
+
+	// The path the query is sent on, to record its round trip time, nil for a quic upstream.
+	path, sent := p.transport.paths.Path(), time.Now()
+	ret, err := c.exchange(ctx, req, time.Now().Add(maxTimeout+p.readTimeout))
+	if errors.Is(err, quic.Err0RTTRejected) {
+		// The query was sent as 0-RTT data the upstream didn't accept, send it again after the handshake.
+		c.rejected0RTT()
+		path, sent = p.transport.paths.Path(), time.Now()
+		ret, err = c.exchange(ctx, req, time.Now().Add(maxTimeout+p.readTimeout))
+	}
+	if err != nil && cached && !c.alive() {
+		// The upstream closed the connection while it was cached, i.e. because it was idle, try once more
+		// on a new one.
+		p.transport.dropSQUIC(c)
+		if c, _, err = p.transport.dialSQUIC(ctx); err != nil {
+			return nil, p.squicError(err, true)
+		}
+		path, sent = p.transport.paths.Path(), time.Now()
+		ret, err = c.exchange(ctx, req, time.Now().Add(maxTimeout+p.readTimeout))
+	}
+	if err != nil {
+		return nil, p.squicError(err, false)
+	}
+	if path != nil {
+		p.transport.paths.observe(path, time.Since(sent))
+	}
+	ret.Id = state.Req.Id
+	if opts.ClientAddrKey != "" {
+		clientaddr.Strip(ret, state.Req)
+	}
+
+	rc, ok := dns.RcodeToString[ret.Rcode]
+	if !ok {
+		rc = strconv.Itoa(ret.Rcode)
+	}
+
+	RequestCount.WithLabelValues(p.addr).Add(1)
+	RcodeCount.WithLabelValues(rc, p.addr).Add(1)
+	RequestDuration.WithLabelValues(p.addr, rc).Observe(time.Since(start).Seconds())
+
+	return ret, nil
+}
@@ -0,0 +1,252 @@
+package proxy
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/doqclient"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+func TestConnectSQUIC(t *testing.T) {
+	addr, stop := startDoQServer(t)
+	defer stop()
+
+	// There is no SCION network in tests, the upstream is reached over plain QUIC instead.
+	p := NewProxy(addr, transport.SQUIC)
+	p.SetTLSConfig(&tls.Config{InsecureSkipVerify: true, NextProtos: []string{"doq"}})
+	p.transport.dialQUIC = func(ctx context.Context, addr string, tlsConfig *tls.Config) (quic.Connection, error) {
+		return quic.DialAddrEarlyContext(ctx, addr, tlsConfig, nil)
+	}
+
+	exchange := func() {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		m.Id = 4242
+		req := request.Request{Req: m, W: dnstest.NewRecorder(&test.ResponseWriter{})}
+
+		resp, err := p.Connect(context.Background(), req, Options{})
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+		if resp.Id != 4242 {
+			t.Errorf("Expected the response to carry ID %d, got %d", 4242, resp.Id)
+		}
+		if len(resp.Answer) != 1 {
+			t.Errorf("Expected 1 answer, got %d", len(resp.Answer))
+		}
+		if m.Id != 4242 {
+			t.Errorf("Expected the query to be unmodified, got ID %d", m.Id)
+		}
+	}
+
+	exchange()
+	first := p.transport.squic
+	exchange()
+	if p.transport.squic != first {
+		t.Errorf("Expected the connection to be reused")
+	}
+	// Health checks share the connection of the queries.
+	if err := p.health.Check(p); err != nil {
+		t.Errorf("Expected the health check to succeed, got %s", err)
+	}
+	if p.transport.squic != first {
+		t.Errorf("Expected the health check to reuse the connection")
+	}
+
+	// A connection that is gone is replaced.
+	first.close()
+	<-first.session.Context().Done()
+	exchange()
+	if p.transport.squic == first {
+		t.Errorf("Expected a new connection after the cached one was closed")
+	}
+}
+
+// startDoQServer starts a DoQ server over IP that answers every query with an A record. Queries with
+// another ID than 0 are rejected. It accepts 0-RTT data.
+func startDoQServer(t *testing.T) (string, func()) {
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{testCertificate(t)},
+		NextProtos:   []string{"doq"},
+	}
+
+	l, err := quic.ListenAddrEarly("127.0.0.1:0", tlsConfig, &quic.Config{Allow0RTT: func(net.Addr) bool { return true }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			session, err := l.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			go func() {
+				for {
+					stream, err := session.AcceptStream(context.Background())
+					if err != nil {
+						return
+					}
+					go func() {
+						defer stream.Close()
+						buf, err := io.ReadAll(stream)
+						if err != nil || len(buf) < 2 {
+							return
+						}
+						m := new(dns.Msg)
+						if err := m.Unpack(buf[2:]); err != nil || m.Id != 0 {
+							stream.CancelRead(transport.DoQProtocolError)
+							stream.CancelWrite(transport.DoQProtocolError)
+							return
+						}
+						ret := new(dns.Msg)
+						ret.SetReply(m)
+						ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+						out, _ := ret.Pack()
+						b := make([]byte, 2+len(out))
+						binary.BigEndian.PutUint16(b, uint16(len(out)))
+						copy(b[2:], out)
+						stream.Write(b)
+					}()
+				}
+			}()
+		}
+	}()
+	return l.Addr().String(), func() { l.Close() }
+}
+
+// testCertificate returns a self-signed certificate for test servers.
+func testCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestSQUICResumption(t *testing.T) {
+	addr, stop := startDoQServer(t)
+	defer stop()
+
+	p := NewProxy(addr, transport.SQUIC)
+	p.SetTLSConfig(&tls.Config{InsecureSkipVerify: true, NextProtos: []string{"doq"}})
+	p.transport.dialQUIC = func(ctx context.Context, addr string, tlsConfig *tls.Config) (quic.Connection, error) {
+		return quic.DialAddrEarlyContext(ctx, addr, tlsConfig, nil)
+	}
+
+	exchange := func() *squicConn {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		req := request.Request{Req: m, W: dnstest.NewRecorder(&test.ResponseWriter{})}
+		if _, err := p.Connect(context.Background(), req, Options{}); err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+		c := p.transport.squic
+		<-c.session.(quic.EarlyConnection).HandshakeComplete()
+		return c
+	}
+
+	first := exchange()
+	if first.session.ConnectionState().TLS.DidResume {
+		t.Errorf("Expected the first connection to do a full handshake")
+	}
+	// The session ticket arrives after the handshake, give it a moment before the connection is closed.
+	time.Sleep(50 * time.Millisecond)
+	first.close()
+	<-first.session.Context().Done()
+
+	second := exchange()
+	if !second.session.ConnectionState().TLS.DidResume {
+		t.Errorf("Expected the new connection to resume the TLS session")
+	}
+	if !second.session.ConnectionState().TLS.Used0RTT {
+		t.Errorf("Expected the query to be sent as 0-RTT data")
+	}
+}
+
+func TestSQUICHealthcheckUnreachable(t *testing.T) {
+	addr, stop := startDoQServer(t)
+	defer stop()
+
+	p := NewProxy(addr, transport.SQUIC)
+	p.SetTLSConfig(&tls.Config{InsecureSkipVerify: true, NextProtos: []string{"doq"}})
+	p.transport.dialQUIC = func(context.Context, string, *tls.Config) (quic.Connection, error) {
+		return nil, &PathError{IA: "19-ffaa:1:fe4", Err: doqclient.ErrNoPath}
+	}
+
+	if err := p.health.Check(p); err == nil {
+		t.Fatalf("Expected the health check to fail without a path")
+	}
+	if !p.Down(1000) {
+		t.Errorf("Expected the upstream to be down after a single check without a path")
+	}
+
+	p.transport.dialQUIC = func(ctx context.Context, addr string, tlsConfig *tls.Config) (quic.Connection, error) {
+		return quic.DialAddrEarlyContext(ctx, addr, tlsConfig, nil)
+	}
+	if err := p.health.Check(p); err != nil {
+		t.Fatalf("Expected the health check to succeed, got %s", err)
+	}
+	if p.Down(1) {
+		t.Errorf("Expected the upstream to be back in rotation")
+	}
+}
+
+func TestMaxStreams(t *testing.T) {
+	p := NewProxy("19-ffaa:1:fe4,[127.0.0.1]:8853", transport.SQUIC)
+	p.SetTLSConfig(&tls.Config{InsecureSkipVerify: true})
+	p.SetMaxStreams(1)
+	release := make(chan struct{})
+	p.transport.dialQUIC = func(context.Context, string, *tls.Config) (quic.Connection, error) {
+		<-release
+		return nil, &PathError{IA: "19-ffaa:1:fe4", Err: doqclient.ErrNoPath}
+	}
+
+	exchange := func() error {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		req := request.Request{Req: m, W: dnstest.NewRecorder(&test.ResponseWriter{})}
+		_, err := p.Connect(context.Background(), req, Options{})
+		return err
+	}
+
+	done := make(chan error)
+	go func() { done <- exchange() }()
+	for atomic.LoadInt64(&p.streams) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := exchange(); err != ErrStreamLimit {
+		t.Errorf("Expected %q for a query over the limit, got %v", ErrStreamLimit, err)
+	}
+
+	close(release)
+	if err := <-done; err == ErrStreamLimit {
+		t.Errorf("Expected the first query to be sent")
+	}
+	// The stream of the first query is given back.
+	if err := exchange(); err == ErrStreamLimit {
+		t.Errorf("Expected a query to be sent once the first one finished")
+	}
+}
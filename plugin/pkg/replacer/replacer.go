@@ -9,6 +9,7 @@ import (
 
 	"github.com/coredns/coredns/plugin/metadata"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -44,6 +45,9 @@ var labels = map[string]struct{}{
 	"{remote}": {},
 	"{port}":   {},
 	"{local}":  {},
+	// SCION values.
+	"{scion}":      {},
+	"{scion_path}": {},
 	// Header values.
 	headerReplacer + "id}":      {},
 	headerReplacer + "opcode}":  {},
@@ -75,6 +79,18 @@ func appendValue(b []byte, state request.Request, rr *dnstest.Recorder, label st
 		return append(b, state.Port()...)
 	case "{local}":
 		return appendAddrToRFC3986(b, state.LocalIP())
+	case "{scion}":
+		c, ok := pkgscion.ClientOf(state.W.RemoteAddr())
+		if !ok {
+			return append(b, EmptyValue...)
+		}
+		return append(append(append(append(b, c.IA.String()...), ",["...), c.Host...), ']')
+	case "{scion_path}":
+		c, ok := pkgscion.ClientOf(state.W.RemoteAddr())
+		if !ok || c.Path == "" {
+			return append(b, EmptyValue...)
+		}
+		return append(b, c.Path...)
 	// Header placeholders (case-insensitive).
 	case headerReplacer + "id}":
 		return strconv.AppendInt(b, int64(state.Req.Id), 10)
@@ -232,6 +232,8 @@ func TestLabels(t *testing.T) {
 		"{remote}":                  "10.240.0.1",
 		"{port}":                    "40212",
 		"{local}":                   "127.0.0.1",
+		"{scion}":                   "-",
+		"{scion_path}":              "-",
 		headerReplacer + "id}":      "1053",
 		headerReplacer + "opcode}":  "0",
 		headerReplacer + "do}":      "false",
@@ -259,6 +261,22 @@ func TestLabels(t *testing.T) {
 	}
 }
 
+func TestSCIONLabels(t *testing.T) {
+	w := dnstest.NewRecorder(&test.ResponseWriter{RemoteSCION: "19-ffaa:1:1067,[10.240.0.1]:40212"})
+	r := new(dns.Msg)
+	r.SetQuestion("example.org.", dns.TypeA)
+	state := request.Request{W: w, Req: r}
+
+	replacer := New()
+	if x := replacer.Replace(context.TODO(), state, nil, "{scion}"); x != "19-ffaa:1:1067,[10.240.0.1]" {
+		t.Errorf("Expected the SCION address of the client, got %q", x)
+	}
+	// No squic listener saw a packet of the client.
+	if x := replacer.Replace(context.TODO(), state, nil, "{scion_path}"); x != EmptyValue {
+		t.Errorf("Expected no path, got %q", x)
+	}
+}
+
 func BenchmarkReplacer(b *testing.B) {
 	w := dnstest.NewRecorder(&test.ResponseWriter{})
 	r := new(dns.Msg)
@@ -414,6 +432,8 @@ func TestNoResponseWasWritten(t *testing.T) {
 		"{remote}":                  "10.240.0.1",
 		"{port}":                    "40212",
 		"{local}":                   "127.0.0.1",
+		"{scion}":                   "-",
+		"{scion_path}":              "-",
 		headerReplacer + "id}":      "1053",
 		headerReplacer + "opcode}":  "0",
 		headerReplacer + "do}":      "false",
@@ -0,0 +1,88 @@
+package scion
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+// Client describes the SCION address of a client and the path its packets last came in on.
+type Client struct {
+	IA   IA
+	Host string
+	Port uint16
+	// Path is the fingerprint of the path, in hex. It is empty if the path isn't known, e.g.
+	// because the address was forwarded.
+	Path string
+	// Hops is the number of ASes on the path, 0 if it isn't known.
+	Hops int
+	// Route lists the ASes and interfaces of the path, like "19-ffaa:1:1067 1>2 19-ffaa:0:1", it is
+	// empty if the path or its metadata isn't known, see WithMetadata.
+	Route string
+}
+
+// String returns the address of c, like 19-ffaa:1:1067,[10.0.0.1]:40000.
+func (c Client) String() string {
+	return c.IA.String() + ",[" + c.Host + "]:" + strconv.Itoa(int(c.Port))
+}
+
+// ClientOf returns the client for addr. It returns false if addr is not a SCION address.
+func ClientOf(addr net.Addr) (Client, bool) {
+	var a pan.UDPAddr
+	switch t := addr.(type) {
+	case pan.UDPAddr:
+		a = t
+	case *pan.UDPAddr:
+		if t == nil {
+			return Client{}, false
+		}
+		a = *t
+	default:
+		return Client{}, false
+	}
+	ia, err := ParseIA(a.IA.String())
+	if err != nil {
+		return Client{}, false
+	}
+	c := Client{IA: ia, Host: a.IP.String(), Port: a.Port}
+	if p := WithMetadata(recentPath(a.String())); p != nil {
+		c.Path = fmt.Sprintf("%x", string(p.Fingerprint))
+		switch {
+		case p.Metadata != nil && len(p.Metadata.Interfaces) > 0:
+			c.Hops = len(p.Metadata.Interfaces)/2 + 1
+			c.Route = p.String()
+		case p.Fingerprint != "":
+			// Without metadata the fingerprint still has the interfaces, just not the ASes.
+			c.Hops = len(strings.Fields(string(p.Fingerprint)))/2 + 1
+		}
+	}
+	return c, true
+}
+
+// The path selectors of the open squic listeners, to look up the paths of clients.
+var (
+	selectorsMu sync.Mutex
+	selectors   = map[*pathSelector]bool{}
+)
+
+// recentPath returns the path the last packet from remote came in on, or nil. The paths are recorded
+// by the Record method of the selectors, which pan calls for every packet received.
+func recentPath(remote string) *pan.Path {
+	selectorsMu.Lock()
+	defer selectorsMu.Unlock()
+	now := time.Now()
+	for s := range selectors {
+		s.mu.Lock()
+		paths := s.current(remote, now)
+		s.mu.Unlock()
+		if len(paths) > 0 {
+			return paths[0].path
+		}
+	}
+	return nil
+}
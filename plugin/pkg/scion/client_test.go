@@ -0,0 +1,77 @@
+package scion
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+func TestClientOf(t *testing.T) {
+	if _, ok := ClientOf(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 53}); ok {
+		t.Errorf("Expected no SCION client for an IP address")
+	}
+
+	src, err := pan.ParseUDPAddr("19-ffaa:1:1067,[10.0.0.1]:40000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, ok := ClientOf(src)
+	if !ok {
+		t.Fatalf("Expected a SCION client for %s", src)
+	}
+	if c.IA.String() != "19-ffaa:1:1067" || c.Host != "10.0.0.1" || c.Port != 40000 {
+		t.Errorf("Expected 19-ffaa:1:1067,[10.0.0.1]:40000, got %s", c)
+	}
+	if c.Path != "" || c.Hops != 0 || c.Route != "" {
+		t.Errorf("Expected no path before any packet was received, got %q", c.Path)
+	}
+	if c, ok := ClientOf(&src); !ok || c.String() != "19-ffaa:1:1067,[10.0.0.1]:40000" {
+		t.Errorf("Expected the same client for a pointer to %s, got %s", src, c)
+	}
+	if _, ok := ClientOf((*pan.UDPAddr)(nil)); ok {
+		t.Errorf("Expected no SCION client for a nil address")
+	}
+
+	// The listener only knows the selector as a pan.ReplySelector.
+	s, err := Defaults{}.NewReplySelector()
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := pan.ParseIA("19-ffaa:1:fe4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	core, err := pan.ParseIA("19-ffaa:0:1301")
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := &pan.PathMetadata{Interfaces: []pan.PathInterface{
+		{IA: local, IfID: 1}, {IA: core, IfID: 4}, {IA: core, IfID: 5}, {IA: src.IA, IfID: 12},
+	}}
+	// Like the paths pan records for received packets, there is no metadata.
+	s.Record(src, reversePath(src, md))
+
+	c, _ = ClientOf(src)
+	if expected := fmt.Sprintf("%x", "1 4 5 12"); c.Path != expected {
+		t.Errorf("Expected path %q, got %q", expected, c.Path)
+	}
+	if c.Hops != 3 || c.Route != "" {
+		t.Errorf("Expected 3 hops and no route before the daemon was asked, got %d and %q", c.Hops, c.Route)
+	}
+
+	setDaemonPaths(t, src.IA, md)
+	c, _ = ClientOf(src)
+	if c.Hops != 3 {
+		t.Errorf("Expected 3 hops, got %d", c.Hops)
+	}
+	if expected := "19-ffaa:1:fe4 1>4 19-ffaa:0:1301 5>12 19-ffaa:1:1067"; c.Route != expected {
+		t.Errorf("Expected route %q, got %q", expected, c.Route)
+	}
+
+	s.Close()
+	if c, _ = ClientOf(src); c.Path != "" {
+		t.Errorf("Expected no path once the listener is closed, got %q", c.Path)
+	}
+}
@@ -0,0 +1,144 @@
+package scion
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IA is an ISD-AS pair as used in SCION addresses, i.e. 19-ffaa:1:1067.
+type IA struct {
+	ISD uint16
+	AS  uint64
+}
+
+// maxBGPAS is the largest AS number that is printed in decimal notation.
+const maxBGPAS = 1<<32 - 1
+
+// ParseIA parses an ISD-AS string like 19-ffaa:1:1067 or 1-64512.
+func ParseIA(s string) (IA, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return IA{}, fmt.Errorf("invalid ISD-AS %q", s)
+	}
+	isd, err := strconv.ParseUint(parts[0], 10, 16)
+	if err != nil {
+		return IA{}, fmt.Errorf("invalid ISD in %q: %s", s, err)
+	}
+	as, err := ParseAS(parts[1])
+	if err != nil {
+		return IA{}, fmt.Errorf("invalid AS in %q: %s", s, err)
+	}
+	return IA{ISD: uint16(isd), AS: as}, nil
+}
+
+// ParseAS parses the AS part of an ISD-AS, which is either a decimal BGP AS number
+// or three colon separated groups of 16 bit hex values.
+func ParseAS(s string) (uint64, error) {
+	if !strings.Contains(s, ":") {
+		as, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return 0, err
+		}
+		return as, nil
+	}
+	groups := strings.Split(s, ":")
+	if len(groups) != 3 {
+		return 0, fmt.Errorf("wrong number of groups in %q", s)
+	}
+	var as uint64
+	for _, g := range groups {
+		v, err := strconv.ParseUint(g, 16, 16)
+		if err != nil {
+			return 0, err
+		}
+		as = as<<16 | v
+	}
+	return as, nil
+}
+
+// String returns the canonical string representation of ia.
+func (ia IA) String() string {
+	return strconv.FormatUint(uint64(ia.ISD), 10) + "-" + FormatAS(ia.AS)
+}
+
+// IsZero returns true if ia is the zero value.
+func (ia IA) IsZero() bool { return ia.ISD == 0 && ia.AS == 0 }
+
+// FormatAS formats as in decimal for BGP AS numbers and in colon separated hex groups otherwise.
+func FormatAS(as uint64) string {
+	if as <= maxBGPAS {
+		return strconv.FormatUint(as, 10)
+	}
+	return fmt.Sprintf("%x:%x:%x", (as>>32)&0xffff, (as>>16)&0xffff, as&0xffff)
+}
+
+// IAPattern matches ISD-AS pairs. The ISD, the AS or, for ASes in hex notation, any of the AS groups may
+// be a * wildcard, like in 19-*, 19-ffaa:1:* or *-ffaa:1:1067.
+type IAPattern struct {
+	ISD uint16 // 0 matches any ISD.
+	AS  uint64
+	// Mask has the bits of the AS set that must be equal to AS.
+	Mask uint64
+}
+
+// ParseIAPattern parses an ISD-AS pattern, see IAPattern.
+func ParseIAPattern(s string) (IAPattern, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return IAPattern{}, fmt.Errorf("invalid ISD-AS pattern %q", s)
+	}
+	var p IAPattern
+	if parts[0] != "*" {
+		isd, err := strconv.ParseUint(parts[0], 10, 16)
+		if err != nil || isd == 0 {
+			return IAPattern{}, fmt.Errorf("invalid ISD in %q", s)
+		}
+		p.ISD = uint16(isd)
+	}
+	if parts[1] == "*" {
+		return p, nil
+	}
+	if !strings.Contains(parts[1], ":") {
+		as, err := ParseAS(parts[1])
+		if err != nil {
+			return IAPattern{}, fmt.Errorf("invalid AS in %q: %s", s, err)
+		}
+		p.AS, p.Mask = as, 1<<48-1
+		return p, nil
+	}
+	groups := strings.Split(parts[1], ":")
+	if len(groups) != 3 {
+		return IAPattern{}, fmt.Errorf("invalid AS in %q: wrong number of groups", s)
+	}
+	for _, g := range groups {
+		p.AS, p.Mask = p.AS<<16, p.Mask<<16
+		if g == "*" {
+			continue
+		}
+		v, err := strconv.ParseUint(g, 16, 16)
+		if err != nil {
+			return IAPattern{}, fmt.Errorf("invalid AS in %q: %s", s, err)
+		}
+		p.AS |= v
+		p.Mask |= 0xffff
+	}
+	return p, nil
+}
+
+// Matches returns true if ia matches p.
+func (p IAPattern) Matches(ia IA) bool {
+	if p.ISD != 0 && p.ISD != ia.ISD {
+		return false
+	}
+	return ia.AS&p.Mask == p.AS
+}
+
+// AddrIA returns the ISD-AS of the SCION address addr, like 19-ffaa:1:1067,[10.0.0.1]:853.
+func AddrIA(addr string) (IA, error) {
+	i := strings.Index(addr, ",")
+	if i < 0 {
+		return IA{}, fmt.Errorf("not a SCION address %q", addr)
+	}
+	return ParseIA(addr[:i])
+}
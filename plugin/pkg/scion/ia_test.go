@@ -0,0 +1,78 @@
+package scion
+
+import "testing"
+
+func TestParseIA(t *testing.T) {
+	tests := []struct {
+		in        string
+		expected  IA
+		shouldErr bool
+	}{
+		{"19-ffaa:1:1067", IA{ISD: 19, AS: 0xffaa00011067}, false},
+		{"1-64512", IA{ISD: 1, AS: 64512}, false},
+		{"0-0", IA{}, false},
+		{"19", IA{}, true},
+		{"19-ffaa:1", IA{}, true},
+		{"19-ffaa:1:10670", IA{}, true},
+		{"70000-ffaa:1:1067", IA{}, true},
+		{"x-ffaa:1:1067", IA{}, true},
+		{"19-ffaa:1:1067-1", IA{}, true},
+	}
+	for i, tc := range tests {
+		ia, err := ParseIA(tc.in)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error for %q, got none", i, tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error for %q, got %s", i, tc.in, err)
+			continue
+		}
+		if ia != tc.expected {
+			t.Errorf("Test %d: expected %v, got %v", i, tc.expected, ia)
+		}
+		if ia.String() != tc.in {
+			t.Errorf("Test %d: expected %q to round trip, got %q", i, tc.in, ia.String())
+		}
+	}
+}
+
+func TestIAPattern(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		matches   []string
+		noMatches []string
+		shouldErr bool
+	}{
+		{"19-ffaa:1:1067", []string{"19-ffaa:1:1067"}, []string{"19-ffaa:1:1068", "17-ffaa:1:1067"}, false},
+		{"19-ffaa:1:*", []string{"19-ffaa:1:1067", "19-ffaa:1:0"}, []string{"19-ffaa:0:1067", "17-ffaa:1:1067"}, false},
+		{"19-*", []string{"19-ffaa:1:1067", "19-64512"}, []string{"17-ffaa:1:1067"}, false},
+		{"*-ffaa:1:1067", []string{"19-ffaa:1:1067", "17-ffaa:1:1067"}, []string{"19-ffaa:1:1068"}, false},
+		{"*-*", []string{"19-ffaa:1:1067", "1-64512"}, nil, false},
+		{"1-64512", []string{"1-64512"}, []string{"1-64513", "2-64512"}, false},
+		{"19", nil, nil, true},
+		{"0-*", nil, nil, true},
+		{"19-ffaa:*", nil, nil, true},
+		{"19-ffaa:1:x", nil, nil, true},
+		{"19-6451*", nil, nil, true},
+	}
+	for i, tc := range tests {
+		p, err := ParseIAPattern(tc.pattern)
+		if (err != nil) != tc.shouldErr {
+			t.Errorf("Test %d: expected error %t for %q, got %v", i, tc.shouldErr, tc.pattern, err)
+			continue
+		}
+		for _, s := range tc.matches {
+			if ia, _ := ParseIA(s); !p.Matches(ia) {
+				t.Errorf("Test %d: expected %q to match %s", i, tc.pattern, s)
+			}
+		}
+		for _, s := range tc.noMatches {
+			if ia, _ := ParseIA(s); p.Matches(ia) {
+				t.Errorf("Test %d: expected %q not to match %s", i, tc.pattern, s)
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package scion
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ListenAddr is a local address a squic server listens on. It may name the AS the listener
+// is expected to be in, to catch a SCION daemon of another AS being used.
+type ListenAddr struct {
+	// IA is the ISD-AS of the listener, zero if any AS is fine.
+	IA IA
+	// Host is the IP to listen on, empty for all of them.
+	Host string
+	// Port is the port to listen on, it may be empty.
+	Port string
+}
+
+// ParseListenAddr parses a listen address in one of the forms IP, [IP]:PORT, ISD-AS,IP or
+// ISD-AS,[IP]:PORT. IPv4 addresses don't need the brackets.
+func ParseListenAddr(s string) (ListenAddr, error) {
+	var la ListenAddr
+	if ia, rest, ok := strings.Cut(s, ","); ok {
+		i, err := ParseIA(ia)
+		if err != nil {
+			return la, err
+		}
+		la.IA = i
+		s = rest
+	}
+	if host, port, err := net.SplitHostPort(s); err == nil {
+		p, err := strconv.Atoi(port)
+		if err != nil || p <= 0 || p > 65535 {
+			return la, fmt.Errorf("invalid port in listen address %q", s)
+		}
+		la.Host, la.Port = host, port
+	} else {
+		la.Host = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	}
+	if la.Host != "" && net.ParseIP(la.Host) == nil {
+		return la, fmt.Errorf("invalid IP %q in listen address", la.Host)
+	}
+	return la, nil
+}
+
+// String returns la in the form ISD-AS,[IP]:PORT, leaving out the ISD-AS when it is zero and the
+// port when it is empty.
+func (la ListenAddr) String() string {
+	switch {
+	case la.IA.IsZero() && la.Port == "":
+		return la.Host
+	case la.IA.IsZero():
+		return net.JoinHostPort(la.Host, la.Port)
+	case la.Port == "":
+		return la.IA.String() + "," + la.Host
+	}
+	// SCION addresses always put the host in brackets.
+	return la.IA.String() + ",[" + la.Host + "]:" + la.Port
+}
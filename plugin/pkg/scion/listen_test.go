@@ -0,0 +1,44 @@
+package scion
+
+import "testing"
+
+func TestParseListenAddr(t *testing.T) {
+	tests := []struct {
+		in        string
+		expected  ListenAddr
+		str       string
+		shouldErr bool
+	}{
+		{"10.0.0.1", ListenAddr{Host: "10.0.0.1"}, "10.0.0.1", false},
+		{"10.0.0.1:8853", ListenAddr{Host: "10.0.0.1", Port: "8853"}, "10.0.0.1:8853", false},
+		{":8853", ListenAddr{Port: "8853"}, ":8853", false},
+		{"::1", ListenAddr{Host: "::1"}, "::1", false},
+		{"[::1]:8853", ListenAddr{Host: "::1", Port: "8853"}, "[::1]:8853", false},
+		{"19-ffaa:1:1067,10.0.0.1", ListenAddr{IA: IA{ISD: 19, AS: 0xffaa00011067}, Host: "10.0.0.1"}, "19-ffaa:1:1067,10.0.0.1", false},
+		{"19-ffaa:1:1067,[10.0.0.1]:8853", ListenAddr{IA: IA{ISD: 19, AS: 0xffaa00011067}, Host: "10.0.0.1", Port: "8853"}, "19-ffaa:1:1067,[10.0.0.1]:8853", false},
+		{"19-ffaa:1:1067,[fd00::1]:8853", ListenAddr{IA: IA{ISD: 19, AS: 0xffaa00011067}, Host: "fd00::1", Port: "8853"}, "19-ffaa:1:1067,[fd00::1]:8853", false},
+		{"19,10.0.0.1", ListenAddr{}, "", true},
+		{"19-ffaa:1:1067,ns1", ListenAddr{}, "", true},
+		{"10.0.0.1:http", ListenAddr{}, "", true},
+		{"10.0.0.1:0", ListenAddr{}, "", true},
+	}
+	for i, tc := range tests {
+		la, err := ParseListenAddr(tc.in)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error for %q, got none", i, tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error for %q, got %s", i, tc.in, err)
+			continue
+		}
+		if la != tc.expected {
+			t.Errorf("Test %d: expected %v, got %v", i, tc.expected, la)
+		}
+		if la.String() != tc.str {
+			t.Errorf("Test %d: expected %q, got %q", i, tc.str, la.String())
+		}
+	}
+}
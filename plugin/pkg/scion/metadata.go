@@ -0,0 +1,166 @@
+package scion
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/daemon"
+)
+
+// pathMetadataTTL is how long the daemon's paths to an AS are used before they are queried again.
+const pathMetadataTTL = time.Minute
+
+// pathMetadataTimeout bounds a path query to the daemon.
+const pathMetadataTimeout = 5 * time.Second
+
+// queryMetadata returns the metadata of the daemon's paths from the local AS to dst, by fingerprint.
+// Tests replace it, so they don't need a daemon. It's guarded by metadataMu.
+var queryMetadata = daemonMetadata
+
+// asMetadata is the metadata of the paths to an AS.
+type asMetadata struct {
+	paths   map[pan.PathFingerprint]*pan.PathMetadata
+	fetched time.Time
+	pending bool
+}
+
+var (
+	metadataMu     sync.Mutex
+	metadata       = map[pan.IA]*asMetadata{}
+	metadataPruned time.Time
+)
+
+// WithMetadata returns p with the metadata of the daemon's path that has the same fingerprint. pan
+// builds the reply paths to clients from the packets received, and these only carry the interface
+// IDs, not the metadata. If p has metadata already, or none is known for it, p is returned as is.
+// The paths to an AS are queried in the background on first use, so the metadata of a client's path
+// is only available from one of its next packets on.
+func WithMetadata(p *pan.Path) *pan.Path {
+	if p == nil || p.Metadata != nil || p.Fingerprint == "" {
+		return p
+	}
+	md := lookupMetadata(p.Destination, p.Fingerprint, time.Now())
+	if md == nil {
+		return p
+	}
+	cp := *p
+	cp.Metadata = md
+	return &cp
+}
+
+// lookupMetadata returns the metadata of the path to dst with fingerprint fp, or nil if it isn't
+// known. It starts a query of the paths to dst if they are missing or stale.
+func lookupMetadata(dst pan.IA, fp pan.PathFingerprint, now time.Time) *pan.PathMetadata {
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+	if now.Sub(metadataPruned) > pathMetadataTTL {
+		// Forget the ASes no client came from lately.
+		for ia, m := range metadata {
+			if !m.pending && now.Sub(m.fetched) > 2*pathMetadataTTL {
+				delete(metadata, ia)
+			}
+		}
+		metadataPruned = now
+	}
+	m, ok := metadata[dst]
+	if !ok {
+		m = &asMetadata{}
+		metadata[dst] = m
+	}
+	if !m.pending && now.Sub(m.fetched) > pathMetadataTTL {
+		m.pending = true
+		go refreshMetadata(dst)
+	}
+	return m.paths[fp]
+}
+
+// refreshMetadata queries the paths to dst. If that fails, the paths known before are kept until the
+// next attempt.
+func refreshMetadata(dst pan.IA) {
+	metadataMu.Lock()
+	query := queryMetadata
+	metadataMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), pathMetadataTimeout)
+	defer cancel()
+	paths, err := query(ctx, dst)
+
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+	m, ok := metadata[dst]
+	if !ok {
+		m = &asMetadata{}
+		metadata[dst] = m
+	}
+	m.pending = false
+	m.fetched = time.Now()
+	if err == nil {
+		m.paths = paths
+	}
+}
+
+var (
+	daemonMu   sync.Mutex
+	daemonConn daemon.Connector
+)
+
+// daemonMetadata asks the SCION daemon for the paths to dst. The daemon is found the way pan finds
+// it.
+func daemonMetadata(ctx context.Context, dst pan.IA) (map[pan.PathFingerprint]*pan.PathMetadata, error) {
+	daemonMu.Lock()
+	if daemonConn == nil {
+		address := os.Getenv(DaemonAddressEnv)
+		if address == "" {
+			address = daemon.DefaultAPIAddress
+		}
+		conn, err := daemon.NewService(address).Connect(ctx)
+		if err != nil {
+			daemonMu.Unlock()
+			return nil, err
+		}
+		daemonConn = conn
+	}
+	conn := daemonConn
+	daemonMu.Unlock()
+
+	paths, err := conn.Paths(ctx, addr.IA(dst), 0, daemon.PathReqFlags{})
+	if err != nil {
+		return nil, err
+	}
+	mds := make(map[pan.PathFingerprint]*pan.PathMetadata, len(paths))
+	for _, p := range paths {
+		smd := p.Metadata()
+		if smd == nil {
+			continue
+		}
+		md := &pan.PathMetadata{
+			Interfaces: make([]pan.PathInterface, len(smd.Interfaces)),
+			MTU:        smd.MTU,
+			Latency:    smd.Latency,
+			Bandwidth:  smd.Bandwidth,
+		}
+		for i, pi := range smd.Interfaces {
+			md.Interfaces[i] = pan.PathInterface{IA: pan.IA(pi.IA), IfID: pan.IfID(pi.ID)}
+		}
+		mds[fingerprint(md.Interfaces)] = md
+	}
+	return mds, nil
+}
+
+// fingerprint returns the fingerprint pan gives a path through interfaces. pan doesn't export how
+// it builds them: the interface IDs in the order they are traversed, separated by spaces. Reply paths
+// get theirs from the hop fields of the packet, so these match as long as both are in the direction
+// from here to the client.
+func fingerprint(interfaces []pan.PathInterface) pan.PathFingerprint {
+	ids := make([]string, len(interfaces))
+	for i, pi := range interfaces {
+		ids[i] = fmt.Sprintf("%d", pi.IfID)
+	}
+	return pan.PathFingerprint(strings.Join(ids, " "))
+}
@@ -0,0 +1,79 @@
+package scion
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+// setDaemonPaths makes the daemon answer with mds for the paths to dst, and looks them up right away.
+func setDaemonPaths(t *testing.T, dst pan.IA, mds ...*pan.PathMetadata) {
+	metadataMu.Lock()
+	queryMetadata = func(_ context.Context, ia pan.IA) (map[pan.PathFingerprint]*pan.PathMetadata, error) {
+		paths := map[pan.PathFingerprint]*pan.PathMetadata{}
+		if ia == dst {
+			for _, md := range mds {
+				paths[fingerprint(md.Interfaces)] = md
+			}
+		}
+		return paths, nil
+	}
+	metadataMu.Unlock()
+	refreshMetadata(dst)
+
+	t.Cleanup(func() {
+		metadataMu.Lock()
+		defer metadataMu.Unlock()
+		queryMetadata = daemonMetadata
+		metadata = map[pan.IA]*asMetadata{}
+	})
+}
+
+// reversePath returns the path to remote like pan builds it from a packet received on it: only the
+// fingerprint is known, there is no metadata.
+func reversePath(remote pan.UDPAddr, md *pan.PathMetadata) *pan.Path {
+	return &pan.Path{Destination: remote.IA, Fingerprint: fingerprint(md.Interfaces)}
+}
+
+func TestWithMetadata(t *testing.T) {
+	client, err := pan.ParseUDPAddr("19-ffaa:1:1067,[10.0.0.1]:40000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	core, err := pan.ParseIA("19-ffaa:0:1301")
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := &pan.PathMetadata{
+		Interfaces: []pan.PathInterface{{IA: core, IfID: 4}, {IA: client.IA, IfID: 12}},
+		MTU:        1472,
+		Latency:    []time.Duration{5 * time.Millisecond},
+	}
+	if fp := fingerprint(md.Interfaces); fp != "4 12" {
+		t.Errorf("Expected fingerprint %q, got %q", "4 12", fp)
+	}
+
+	p := reversePath(client, md)
+	if got := WithMetadata(p); got != p {
+		t.Errorf("Expected the path as is before the daemon was asked, got %v", got)
+	}
+
+	setDaemonPaths(t, client.IA, md)
+	got := WithMetadata(p)
+	if got.Metadata != md {
+		t.Fatalf("Expected the daemon's metadata, got %v", got.Metadata)
+	}
+	if p.Metadata != nil {
+		t.Errorf("Expected the path pan handed in to stay unchanged")
+	}
+	if PayloadMTU(got) == 0 {
+		t.Errorf("Expected the MTU of the path to be known")
+	}
+
+	unknown := &pan.Path{Destination: client.IA, Fingerprint: "1 2"}
+	if got := WithMetadata(unknown); got.Metadata != nil {
+		t.Errorf("Expected no metadata for a path the daemon doesn't know, got %v", got.Metadata)
+	}
+}
@@ -0,0 +1,24 @@
+package scion
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Variables declared for monitoring.
+var (
+	PathDownCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "scion",
+		Name:      "path_down_total",
+		Help:      "Counter of SCMP path down notifications received by squic listeners, per ISD-AS of the interface that went down.",
+	}, []string{"ia"})
+	ReplyFailoverCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "scion",
+		Name:      "reply_path_failovers_total",
+		Help:      "Counter of replies by squic listeners whose path was down, per result.",
+	}, []string{"result"})
+)
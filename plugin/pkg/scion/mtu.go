@@ -0,0 +1,59 @@
+package scion
+
+import "github.com/netsec-ethz/scion-apps/pkg/pan"
+
+// QUICPacketSize is the size of the UDP payload of the first QUIC packets, quic-go doesn't go
+// below it. A SCION path that can't carry it blackholes the handshake.
+const QUICPacketSize = 1252
+
+// SCION header sizes in bytes, see https://docs.scion.org/en/latest/protocols/scion-header.html.
+const (
+	commonHeaderLen  = 12
+	addressHeaderLen = 16 + 2*16 // both ISD-ASes and, at most, IPv6 hosts
+	pathMetaLen      = 4
+	infoFieldLen     = 8
+	hopFieldLen      = 12
+	maxSegments      = 3
+	udpHeaderLen     = 8
+)
+
+// PayloadMTU returns the largest UDP payload that fits through p, or 0 if its MTU is unknown.
+// The size of the headers is estimated from above, so the result is on the safe side.
+func PayloadMTU(p *pan.Path) int {
+	if p == nil || p.Metadata == nil || p.Metadata.MTU == 0 {
+		return 0
+	}
+	// Every AS on the path has a hop field, the ASes where segments are joined have two.
+	hops := len(p.Metadata.Interfaces)/2 + 1 + maxSegments - 1
+	overhead := commonHeaderLen + addressHeaderLen + pathMetaLen + maxSegments*infoFieldLen + hops*hopFieldLen + udpHeaderLen
+	if mtu := int(p.Metadata.MTU) - overhead; mtu > 0 {
+		return mtu
+	}
+	return 0
+}
+
+// MTUPolicy is a pan.Policy that drops the paths whose MTU is too small to carry a UDP payload
+// of Min bytes. Paths with an unknown MTU are kept.
+type MTUPolicy struct {
+	Min int
+}
+
+// Filter implements pan.Policy.
+func (m MTUPolicy) Filter(paths []*pan.Path) []*pan.Path {
+	filtered := make([]*pan.Path, 0, len(paths))
+	for _, p := range paths {
+		if p.Metadata == nil || p.Metadata.MTU == 0 || PayloadMTU(p) >= m.Min {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// QUICPolicy returns policy extended to only use paths that can carry QUIC packets. policy may be nil.
+func QUICPolicy(policy pan.Policy) pan.Policy {
+	mtu := MTUPolicy{Min: QUICPacketSize}
+	if policy == nil {
+		return mtu
+	}
+	return pan.PolicyChain{policy, mtu}
+}
@@ -0,0 +1,32 @@
+package scion
+
+import (
+	"testing"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+func TestMTUPolicy(t *testing.T) {
+	path := func(mtu uint16, links int) *pan.Path {
+		return &pan.Path{Metadata: &pan.PathMetadata{MTU: mtu, Interfaces: make([]pan.PathInterface, 2*links)}}
+	}
+	unknown := &pan.Path{}
+	large := path(1472, 3)
+	small := path(1280, 3)
+	tiny := path(64, 1)
+
+	if mtu := PayloadMTU(unknown); mtu != 0 {
+		t.Errorf("Expected 0 for an unknown MTU, got %d", mtu)
+	}
+	if mtu := PayloadMTU(tiny); mtu != 0 {
+		t.Errorf("Expected 0 for a path smaller than the headers, got %d", mtu)
+	}
+	if PayloadMTU(large) <= PayloadMTU(path(1472, 6)) {
+		t.Errorf("Expected longer paths to leave less room for the payload")
+	}
+
+	filtered := MTUPolicy{Min: QUICPacketSize}.Filter([]*pan.Path{unknown, large, small, tiny})
+	if len(filtered) != 2 || filtered[0] != unknown || filtered[1] != large {
+		t.Errorf("Expected the paths with an unknown and a large MTU, got %v", filtered)
+	}
+}
@@ -0,0 +1,93 @@
+package scion
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+// ISDPolicy is a pan.Policy that drops the paths that traverse an ISD in Deny or, if Allow
+// isn't empty, an ISD not in Allow. Paths without metadata can't be checked and are dropped.
+type ISDPolicy struct {
+	Allow map[uint16]bool
+	Deny  map[uint16]bool
+}
+
+// Filter implements pan.Policy.
+func (i ISDPolicy) Filter(paths []*pan.Path) []*pan.Path {
+	filtered := make([]*pan.Path, 0, len(paths))
+	for _, p := range paths {
+		if i.accepts(p) {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+func (i ISDPolicy) accepts(p *pan.Path) bool {
+	if p.Metadata == nil || len(p.Metadata.Interfaces) == 0 {
+		return false
+	}
+	for _, intf := range p.Metadata.Interfaces {
+		ia, err := ParseIA(intf.IA.String())
+		if err != nil || i.Deny[ia.ISD] {
+			return false
+		}
+		if len(i.Allow) > 0 && !i.Allow[ia.ISD] {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseISDs parses a space separated list of ISD numbers.
+func ParseISDs(s string) (map[uint16]bool, error) {
+	isds := map[uint16]bool{}
+	for _, f := range strings.Fields(s) {
+		isd, err := strconv.ParseUint(f, 10, 16)
+		if err != nil || isd == 0 {
+			return nil, fmt.Errorf("invalid ISD %q", f)
+		}
+		isds[uint16(isd)] = true
+	}
+	return isds, nil
+}
+
+// DisjointPolicy is a pan.Policy for a second connection to a destination, which should use another path
+// than the first. pan uses the first path a policy returns, so DisjointPolicy drops that one, unless it
+// is the only path, and orders the others by the number of interfaces they share with it, fewest first.
+// Paths without metadata are put last.
+type DisjointPolicy struct{}
+
+// Filter implements pan.Policy.
+func (DisjointPolicy) Filter(paths []*pan.Path) []*pan.Path {
+	if len(paths) < 2 {
+		return paths
+	}
+	first := map[pan.PathInterface]bool{}
+	if paths[0].Metadata != nil {
+		for _, intf := range paths[0].Metadata.Interfaces {
+			first[intf] = true
+		}
+	}
+	shared := func(p *pan.Path) int {
+		if p.Metadata == nil || len(first) == 0 {
+			return len(first) + 1
+		}
+		n := 0
+		for _, intf := range p.Metadata.Interfaces {
+			if first[intf] {
+				n++
+			}
+		}
+		return n
+	}
+
+	others := make([]*pan.Path, len(paths)-1)
+	copy(others, paths[1:])
+	sort.SliceStable(others, func(i, j int) bool { return shared(others[i]) < shared(others[j]) })
+	return others
+}
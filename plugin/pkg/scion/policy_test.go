@@ -0,0 +1,99 @@
+package scion
+
+import (
+	"testing"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+func TestISDPolicy(t *testing.T) {
+	path := func(isds ...string) *pan.Path {
+		p := &pan.Path{Metadata: &pan.PathMetadata{}}
+		for _, isd := range isds {
+			ia, err := pan.ParseIA(isd + "-ffaa:0:1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.Metadata.Interfaces = append(p.Metadata.Interfaces, pan.PathInterface{IA: ia})
+		}
+		return p
+	}
+	unknown := &pan.Path{}
+	local := path("19", "19")
+	via17 := path("19", "17", "17", "18")
+	via18 := path("19", "18")
+
+	tests := []struct {
+		policy   ISDPolicy
+		expected []*pan.Path
+	}{
+		{ISDPolicy{Deny: map[uint16]bool{17: true}}, []*pan.Path{local, via18}},
+		{ISDPolicy{Allow: map[uint16]bool{19: true}}, []*pan.Path{local}},
+		{ISDPolicy{Allow: map[uint16]bool{17: true, 18: true, 19: true}, Deny: map[uint16]bool{18: true}}, []*pan.Path{local}},
+	}
+	for i, tc := range tests {
+		filtered := tc.policy.Filter([]*pan.Path{unknown, local, via17, via18})
+		if len(filtered) != len(tc.expected) {
+			t.Errorf("Test %d: expected %d paths, got %d", i, len(tc.expected), len(filtered))
+			continue
+		}
+		for j := range filtered {
+			if filtered[j] != tc.expected[j] {
+				t.Errorf("Test %d: expected path %d to be %v, got %v", i, j, tc.expected[j], filtered[j])
+			}
+		}
+	}
+}
+
+func TestPathPolicy(t *testing.T) {
+	tests := []struct {
+		d         Defaults
+		isNil     bool
+		shouldErr bool
+	}{
+		{New(), true, false},
+		{Defaults{Policy: "0*"}, false, false},
+		{Defaults{DenyISDs: "17 18"}, false, false},
+		{Defaults{Policy: "19-ffaa:1:1067 0*", AllowISDs: "19"}, false, false},
+		{Defaults{AllowISDs: "nineteen"}, true, true},
+		{Defaults{DenyISDs: "0"}, true, true},
+	}
+	for i, tc := range tests {
+		p, err := tc.d.PathPolicy()
+		if (err != nil) != tc.shouldErr {
+			t.Errorf("Test %d: expected error %t, got %v", i, tc.shouldErr, err)
+		}
+		if (p == nil) != tc.isNil {
+			t.Errorf("Test %d: expected nil policy %t, got %v", i, tc.isNil, p)
+		}
+	}
+}
+
+func TestDisjointPolicy(t *testing.T) {
+	path := func(ifids ...uint64) *pan.Path {
+		p := &pan.Path{Metadata: &pan.PathMetadata{}}
+		for _, ifid := range ifids {
+			p.Metadata.Interfaces = append(p.Metadata.Interfaces, pan.PathInterface{IfID: pan.IfID(ifid)})
+		}
+		return p
+	}
+	first := path(1, 2, 3, 4)
+	overlapping := path(1, 2, 5, 6)
+	disjoint := path(7, 8, 9, 10)
+	unknown := &pan.Path{}
+
+	filtered := DisjointPolicy{}.Filter([]*pan.Path{first, unknown, overlapping, disjoint})
+	expected := []*pan.Path{disjoint, overlapping, unknown}
+	if len(filtered) != len(expected) {
+		t.Fatalf("Expected %d paths, got %d", len(expected), len(filtered))
+	}
+	for i := range filtered {
+		if filtered[i] != expected[i] {
+			t.Errorf("Expected path %d to be %v, got %v", i, expected[i], filtered[i])
+		}
+	}
+
+	if only := (DisjointPolicy{}).Filter([]*pan.Path{first}); len(only) != 1 || only[0] != first {
+		t.Errorf("Expected the only path to be kept")
+	}
+}
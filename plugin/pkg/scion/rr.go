@@ -0,0 +1,141 @@
+package scion
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// TypeSCION is the RR type of SCION address records, from the private use range of RFC 6895. Its
+// presentation format is the SCION address of the host without a port, as in
+//
+//	ns1  IN  SCION  19-ffaa:1:1067,192.0.2.1
+//
+// The IP may also be given in brackets, the usual notation of SCION addresses.
+const TypeSCION = 0xff53
+
+// TXTPrefix starts the TXT records that SCION resolvers look up SCION addresses in, as in
+// "scion=19-ffaa:1:1067,[192.0.2.1]".
+const TXTPrefix = "scion="
+
+func init() {
+	dns.PrivateHandle("SCION", TypeSCION, func() dns.PrivateRdata { return new(SCION) })
+}
+
+// SCION is the rdata of a SCION address record. On the wire it is the ISD (2 octets) and AS (6 octets)
+// followed by the IPv4 or IPv6 address.
+type SCION struct {
+	IA IA
+	IP net.IP
+}
+
+// NewSCION returns a SCION address record for name with the ttl.
+func NewSCION(name string, ttl uint32, ia IA, ip net.IP) *dns.PrivateRR {
+	rr := dns.TypeToRR[TypeSCION]().(*dns.PrivateRR)
+	rr.Hdr = dns.RR_Header{Name: name, Rrtype: TypeSCION, Class: dns.ClassINET, Ttl: ttl}
+	*rr.Data.(*SCION) = SCION{IA: ia, IP: ip}
+	return rr
+}
+
+// SCIONOf returns the rdata of rr, if it is a SCION address record.
+func SCIONOf(rr dns.RR) (*SCION, bool) {
+	p, ok := rr.(*dns.PrivateRR)
+	if !ok || p.Hdr.Rrtype != TypeSCION {
+		return nil, false
+	}
+	s, ok := p.Data.(*SCION)
+	return s, ok
+}
+
+// String implements dns.PrivateRdata.
+func (s *SCION) String() string {
+	return s.IA.String() + ",[" + s.IP.String() + "]"
+}
+
+// TXT returns the TXT record text that SCION resolvers understand for the address of s.
+func (s *SCION) TXT() string { return TXTPrefix + s.String() }
+
+// Parse implements dns.PrivateRdata.
+func (s *SCION) Parse(txt []string) error {
+	if len(txt) != 1 {
+		return errors.New("SCION record needs one address")
+	}
+	i := strings.Index(txt[0], ",")
+	if i < 0 {
+		return fmt.Errorf("invalid SCION address %q", txt[0])
+	}
+	ia, err := ParseIA(txt[0][:i])
+	if err != nil {
+		return err
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(txt[0][i+1:], "["), "]")
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("invalid IP in SCION address %q", txt[0])
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	s.IA, s.IP = ia, ip
+	return nil
+}
+
+// Pack implements dns.PrivateRdata.
+func (s *SCION) Pack(buf []byte) (int, error) {
+	if len(buf) < s.Len() {
+		return 0, dns.ErrBuf
+	}
+	binary.BigEndian.PutUint16(buf, s.IA.ISD)
+	putAS(buf[2:8], s.IA.AS)
+	return 8 + copy(buf[8:], s.ip()), nil
+}
+
+// Unpack implements dns.PrivateRdata.
+func (s *SCION) Unpack(buf []byte) (int, error) {
+	if len(buf) != 8+net.IPv4len && len(buf) != 8+net.IPv6len {
+		return 0, fmt.Errorf("invalid SCION rdata length %d", len(buf))
+	}
+	s.IA = IA{ISD: binary.BigEndian.Uint16(buf), AS: getAS(buf[2:8])}
+	s.IP = append(net.IP(nil), buf[8:]...)
+	return len(buf), nil
+}
+
+// Copy implements dns.PrivateRdata.
+func (s *SCION) Copy(dest dns.PrivateRdata) error {
+	d, ok := dest.(*SCION)
+	if !ok {
+		return dns.ErrRdata
+	}
+	d.IA, d.IP = s.IA, append(net.IP(nil), s.IP...)
+	return nil
+}
+
+// Len implements dns.PrivateRdata.
+func (s *SCION) Len() int { return 8 + len(s.ip()) }
+
+// ip returns the IP of s in its shortest form.
+func (s *SCION) ip() net.IP {
+	if ip4 := s.IP.To4(); ip4 != nil {
+		return ip4
+	}
+	return s.IP
+}
+
+func putAS(b []byte, as uint64) {
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(as)
+		as >>= 8
+	}
+}
+
+func getAS(b []byte) uint64 {
+	var as uint64
+	for _, v := range b {
+		as = as<<8 | uint64(v)
+	}
+	return as
+}
@@ -0,0 +1,68 @@
+package scion
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestSCIONRR(t *testing.T) {
+	tests := []struct {
+		in        string
+		expected  string
+		shouldErr bool
+	}{
+		{"ns1.example.org. 3600 IN SCION 19-ffaa:1:1067,192.0.2.1", "ns1.example.org.\t3600\tIN\tSCION\t19-ffaa:1:1067,[192.0.2.1]", false},
+		{"ns1.example.org. 3600 IN SCION 19-ffaa:1:1067,[2001:db8::1]", "ns1.example.org.\t3600\tIN\tSCION\t19-ffaa:1:1067,[2001:db8::1]", false},
+		{"ns1.example.org. 3600 IN SCION 1-64512,[10.0.0.1]", "ns1.example.org.\t3600\tIN\tSCION\t1-64512,[10.0.0.1]", false},
+		{"ns1.example.org. 3600 IN SCION 19-ffaa:1:1067", "", true},
+		{"ns1.example.org. 3600 IN SCION 19-ffaa:1:1067,ns1", "", true},
+		{"ns1.example.org. 3600 IN SCION 19,192.0.2.1", "", true},
+		{"ns1.example.org. 3600 IN SCION 19-ffaa:1:1067,[192.0.2.1]:853", "", true},
+	}
+	for i, tc := range tests {
+		rr, err := dns.NewRR(tc.in)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error for %q, got none", i, tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: expected no error for %q, got %s", i, tc.in, err)
+		}
+		if rr.String() != tc.expected {
+			t.Errorf("Test %d: expected %q, got %q", i, tc.expected, rr.String())
+		}
+
+		// The record survives a round trip over the wire.
+		m := new(dns.Msg)
+		m.SetQuestion("ns1.example.org.", TypeSCION)
+		m.Answer = []dns.RR{rr}
+		buf, err := m.Pack()
+		if err != nil {
+			t.Fatalf("Test %d: failed to pack: %s", i, err)
+		}
+		m2 := new(dns.Msg)
+		if err := m2.Unpack(buf); err != nil {
+			t.Fatalf("Test %d: failed to unpack: %s", i, err)
+		}
+		if len(m2.Answer) != 1 || m2.Answer[0].String() != tc.expected {
+			t.Errorf("Test %d: expected %q after a round trip, got %v", i, tc.expected, m2.Answer)
+		}
+		if s, ok := SCIONOf(m2.Answer[0]); !ok || s.TXT() != TXTPrefix+s.String() {
+			t.Errorf("Test %d: expected a SCION record, got %v", i, m2.Answer[0])
+		}
+	}
+}
+
+func TestNewSCION(t *testing.T) {
+	ia, _ := ParseIA("19-ffaa:1:1067")
+	rr := NewSCION("ns1.example.org.", 300, ia, []byte{192, 0, 2, 1})
+	if s := rr.String(); s != "ns1.example.org.\t300\tIN\tSCION\t19-ffaa:1:1067,[192.0.2.1]" {
+		t.Errorf("Unexpected record %q", s)
+	}
+	if cp := dns.Copy(rr); cp.String() != rr.String() {
+		t.Errorf("Expected the copy to equal the record, got %q", cp.String())
+	}
+}
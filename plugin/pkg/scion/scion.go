@@ -0,0 +1,128 @@
+// Package scion holds the process wide SCION defaults that are shared by the squic server,
+// the proxy and the secondary, so each of them doesn't have to come up with its own settings.
+package scion
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+// DaemonAddressEnv is the environment variable pan reads the SCION daemon address from.
+const DaemonAddressEnv = "SCION_DAEMON_ADDRESS"
+
+// Reply selectors that can be used by squic listeners.
+const (
+	ReplySelectorDefault = "default"
+)
+
+// Defaults are the SCION wide settings.
+type Defaults struct {
+	// DaemonAddress is the address of the SCION daemon. If empty, pan's default is used.
+	DaemonAddress string
+	// LocalIA is the ISD-AS this instance is expected to run in. It is zero when not configured.
+	LocalIA IA
+	// Policy is a path policy sequence (in pan's sequence syntax) applied to outbound SCION dials.
+	Policy string
+	// AllowISDs and DenyISDs are space separated ISDs outbound SCION dials may, or may not, traverse.
+	AllowISDs string
+	DenyISDs  string
+	// DoQPort is the port for squic listeners and upstreams that don't specify one.
+	DoQPort string
+	// ReplySelector is the name of the reply path selector used by squic listeners,
+	// ReplySelectorArgs its space separated arguments.
+	ReplySelector     string
+	ReplySelectorArgs string
+	// ReverseSuffix is the domain the reverse names of SCION addresses are in, scion.arpa. by default.
+	ReverseSuffix string
+}
+
+var (
+	mu       sync.RWMutex
+	defaults = New()
+)
+
+// New returns Defaults with the built-in values.
+func New() Defaults {
+	return Defaults{DoQPort: transport.QUICPort, ReplySelector: ReplySelectorDefault, ReverseSuffix: dnsutil.SCIONReverseSuffixDefault}
+}
+
+// Get returns the current SCION defaults. These are the defaults of the running server instance, set
+// up with dnsserver.SetSCIONDefaults and applied when it started. Setup functions use
+// dnsserver.SCIONDefaults instead, as the instance they set up isn't running yet.
+func Get() Defaults {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaults
+}
+
+// Set replaces the SCION defaults with d. If d carries a daemon address it is
+// exported to the environment, so pan picks it up when it first contacts the daemon. The reverse
+// suffix is handed to dnsutil, which builds the reverse names.
+func Set(d Defaults) error {
+	if d.DaemonAddress != "" {
+		if err := os.Setenv(DaemonAddressEnv, d.DaemonAddress); err != nil {
+			return err
+		}
+	}
+	suffix := d.ReverseSuffix
+	if suffix == "" {
+		suffix = dnsutil.SCIONReverseSuffixDefault
+	}
+	if err := dnsutil.SetSCIONReverseSuffix(suffix); err != nil {
+		return err
+	}
+	mu.Lock()
+	defaults = d
+	mu.Unlock()
+	return nil
+}
+
+// Reset restores the built-in defaults.
+func Reset() { _ = Set(New()) }
+
+// Port returns the default DoQ port as an integer.
+func (d Defaults) Port() int {
+	p, err := strconv.Atoi(d.DoQPort)
+	if err != nil {
+		p, _ = strconv.Atoi(transport.QUICPort)
+	}
+	return p
+}
+
+// PathPolicy compiles the configured path policy, the sequence followed by the ISD allow and deny
+// lists. It returns nil if no policy is set.
+func (d Defaults) PathPolicy() (pan.Policy, error) {
+	var chain pan.PolicyChain
+	if d.Policy != "" {
+		seq, err := pan.NewSequence(d.Policy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path policy %q: %s", d.Policy, err)
+		}
+		chain = append(chain, seq)
+	}
+	if d.AllowISDs != "" || d.DenyISDs != "" {
+		allow, err := ParseISDs(d.AllowISDs)
+		if err != nil {
+			return nil, err
+		}
+		deny, err := ParseISDs(d.DenyISDs)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, ISDPolicy{Allow: allow, Deny: deny})
+	}
+	switch len(chain) {
+	case 0:
+		return nil, nil
+	case 1:
+		return chain[0], nil
+	}
+	return chain, nil
+}
@@ -0,0 +1,442 @@
+package scion
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+// Reply selectors in addition to ReplySelectorDefault.
+const (
+	// ReplySelectorLatency replies on the path with the lowest announced latency.
+	ReplySelectorLatency = "latency"
+	// ReplySelectorPinned keeps replying on the first path a client used, until it goes down.
+	ReplySelectorPinned = "pinned"
+	// ReplySelectorAvoid replies on paths that don't traverse the given ISDs.
+	ReplySelectorAvoid = "avoid"
+)
+
+// maxReplyPaths is the number of recently used paths remembered per client.
+const maxReplyPaths = 8
+
+// replyPathTTL is how long a path a client used is considered for replies.
+const replyPathTTL = 5 * time.Minute
+
+// pathDownTTL is how long a path or interface is considered down after an SCMP notification, unless a
+// packet arrives on it earlier.
+const pathDownTTL = time.Minute
+
+// replySelectors maps the reply selector names to their constructors, which get the arguments
+// given in the Corefile.
+var replySelectors = map[string]func(args []string) (pan.ReplySelector, error){
+	ReplySelectorDefault: func(args []string) (pan.ReplySelector, error) {
+		if len(args) > 0 {
+			return nil, fmt.Errorf("reply selector %q takes no arguments", ReplySelectorDefault)
+		}
+		return newPathSelector(nil), nil
+	},
+	ReplySelectorLatency: func(args []string) (pan.ReplySelector, error) {
+		if len(args) > 0 {
+			return nil, fmt.Errorf("reply selector %q takes no arguments", ReplySelectorLatency)
+		}
+		return newPathSelector(lowestLatency), nil
+	},
+	ReplySelectorPinned: func(args []string) (pan.ReplySelector, error) {
+		if len(args) > 0 {
+			return nil, fmt.Errorf("reply selector %q takes no arguments", ReplySelectorPinned)
+		}
+		return newPathSelector(oldest), nil
+	},
+	ReplySelectorAvoid: func(args []string) (pan.ReplySelector, error) {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("reply selector %q needs at least one ISD", ReplySelectorAvoid)
+		}
+		isds, err := ParseISDs(strings.Join(args, " "))
+		if err != nil {
+			return nil, err
+		}
+		return newPathSelector(avoiding(isds)), nil
+	},
+}
+
+// HasReplySelector returns true if a reply selector with name exists.
+func HasReplySelector(name string) bool {
+	_, ok := replySelectors[name]
+	return ok
+}
+
+// NewReplySelector returns a new reply selector of the configured kind.
+func (d Defaults) NewReplySelector() (pan.ReplySelector, error) {
+	name := d.ReplySelector
+	if name == "" {
+		name = ReplySelectorDefault
+	}
+	newSelector, ok := replySelectors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown reply selector %q", name)
+	}
+	return newSelector(strings.Fields(d.ReplySelectorArgs))
+}
+
+// chooser picks the reply path among the paths, most recently used first, a client's packets
+// came in on. It returns nil to leave the choice to pan's default.
+type chooser func(paths []*replyPath) *pan.Path
+
+type replyPath struct {
+	path  *pan.Path
+	first time.Time // first packet on this path
+	last  time.Time // last packet on this path
+}
+
+// pathSelector is a pan.ReplySelector that remembers the recent paths of each client, so the
+// reply path can be chosen among them. Everything else is left to pan's default selector, which
+// replies on the path of the last packet.
+type pathSelector struct {
+	*pan.DefaultReplySelector
+	choose chooser
+
+	mu      sync.Mutex
+	remotes map[string][]*replyPath // most recently used first
+	pruned  time.Time
+	// The paths and interfaces SCMP reported down, and when.
+	downPaths  map[pan.PathFingerprint]time.Time
+	downIfaces map[pan.PathInterface]time.Time
+}
+
+func newPathSelector(choose chooser) *pathSelector {
+	s := &pathSelector{
+		DefaultReplySelector: pan.NewDefaultReplySelector(),
+		choose:               choose,
+		remotes:              map[string][]*replyPath{},
+		downPaths:            map[pan.PathFingerprint]time.Time{},
+		downIfaces:           map[pan.PathInterface]time.Time{},
+	}
+	selectorsMu.Lock()
+	selectors[s] = true
+	selectorsMu.Unlock()
+	return s
+}
+
+// Close implements pan.ReplySelector.
+func (s *pathSelector) Close() error {
+	selectorsMu.Lock()
+	delete(selectors, s)
+	selectorsMu.Unlock()
+	return s.DefaultReplySelector.Close()
+}
+
+// Path implements pan.ReplySelector. If the chosen path was reported down, the reply is sent on
+// another recent path of the client, pan's default selector doesn't fail over by itself.
+func (s *pathSelector) Path(remote pan.UDPAddr) *pan.Path {
+	p := s.replyPath(remote)
+	if p == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	recent := s.current(remote.String(), now)
+	for _, rp := range recent {
+		if rp.path.Fingerprint == p.Fingerprint {
+			// pan's default selector hands out the path as it was received, without the metadata.
+			p = rp.path
+			break
+		}
+	}
+	if !s.down(p, now) {
+		return p
+	}
+	for _, rp := range recent {
+		if !s.down(rp.path, now) {
+			ReplyFailoverCount.WithLabelValues("switched").Inc()
+			return rp.path
+		}
+	}
+	// Nothing better is known, the client has to move to another path first.
+	ReplyFailoverCount.WithLabelValues("no_alternative").Inc()
+	return p
+}
+
+// replyPath returns the path the configured chooser, or pan's default selector, picks for remote. Paths
+// whose MTU is too small for QUIC's packets are only used if the client has no others.
+func (s *pathSelector) replyPath(remote pan.UDPAddr) *pan.Path {
+	policy := replyPolicy(remote.String())
+
+	s.mu.Lock()
+	paths := s.current(remote.String(), time.Now())
+	s.mu.Unlock()
+
+	// squic listeners don't discover the path MTU, their packets carry at most QUICPacketSize bytes of UDP
+	// payload.
+	fit := filter(paths, MTUPolicy{Min: QUICPacketSize})
+	if s.choose == nil && policy == nil && len(fit) == len(paths) {
+		return s.DefaultReplySelector.Path(remote)
+	}
+	if len(fit) > 0 {
+		paths = fit
+	}
+
+	if policy != nil {
+		paths = filter(paths, policy)
+		if len(paths) == 0 {
+			// Better to reply on a path the override doesn't like, than not at all.
+			return s.DefaultReplySelector.Path(remote)
+		}
+	}
+	choose := s.choose
+	if choose == nil {
+		choose = newest
+	}
+	if p := choose(paths); p != nil {
+		return p
+	}
+	return s.DefaultReplySelector.Path(remote)
+}
+
+// down returns true if p, or one of its interfaces, was reported down recently. s.mu must be held.
+func (s *pathSelector) down(p *pan.Path, now time.Time) bool {
+	if t, ok := s.downPaths[p.Fingerprint]; ok {
+		if now.Sub(t) < pathDownTTL {
+			return true
+		}
+		delete(s.downPaths, p.Fingerprint)
+	}
+	if p.Metadata == nil {
+		return false
+	}
+	for _, pi := range p.Metadata.Interfaces {
+		if t, ok := s.downIfaces[pi]; ok {
+			if now.Sub(t) < pathDownTTL {
+				return true
+			}
+			delete(s.downIfaces, pi)
+		}
+	}
+	return false
+}
+
+// Record implements pan.ReplySelector. It's called for every packet received, with the path from
+// here to remote. That path has no metadata, it's looked up by its fingerprint, see WithMetadata.
+func (s *pathSelector) Record(remote pan.UDPAddr, path *pan.Path) {
+	s.DefaultReplySelector.Record(remote, path)
+	if path == nil {
+		return
+	}
+	path = WithMetadata(path)
+	now := time.Now()
+	key := remote.String()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// A packet came in on path, so it's up again.
+	delete(s.downPaths, path.Fingerprint)
+	if path.Metadata != nil {
+		for _, pi := range path.Metadata.Interfaces {
+			delete(s.downIfaces, pi)
+		}
+	}
+	if now.Sub(s.pruned) > replyPathTTL {
+		// Forget the clients that went quiet.
+		for remote := range s.remotes {
+			s.current(remote, now)
+		}
+		s.pruned = now
+	}
+	paths := s.current(key, now)
+	for i, rp := range paths {
+		if rp.path.Fingerprint == path.Fingerprint {
+			if rp.path.Metadata == nil && path.Metadata != nil {
+				// The metadata wasn't known yet when the path was first used. The chooser may still
+				// read the old entry, so it's replaced rather than changed.
+				rp = &replyPath{path: path, first: rp.first}
+			}
+			rp.last = now
+			copy(paths[1:i+1], paths[:i])
+			paths[0] = rp
+			s.remotes[key] = paths
+			return
+		}
+	}
+	if len(paths) == maxReplyPaths {
+		paths = paths[:maxReplyPaths-1]
+	}
+	s.remotes[key] = append([]*replyPath{{path: path, first: now, last: now}}, paths...)
+}
+
+// PathDown implements pan.ReplySelector. It's called for the SCMP interface down and internal
+// connectivity down messages received by the listener, which name the path and the interface that
+// went down. All paths of clients through that interface are dropped.
+func (s *pathSelector) PathDown(pf pan.PathFingerprint, pi pan.PathInterface) {
+	PathDownCount.WithLabelValues(pi.IA.String()).Inc()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.downPaths[pf] = now
+	s.downIfaces[pi] = now
+	for key, paths := range s.remotes {
+		up := paths[:0]
+		for _, rp := range paths {
+			if !s.down(rp.path, now) {
+				up = append(up, rp)
+				continue
+			}
+			// pan's default selector only knows the path by its fingerprint.
+			s.downPaths[rp.path.Fingerprint] = now
+		}
+		s.remotes[key] = up
+	}
+}
+
+// current returns the paths of remote that were used recently. s.mu must be held.
+func (s *pathSelector) current(remote string, now time.Time) []*replyPath {
+	paths := s.remotes[remote]
+	recent := paths[:0]
+	for _, rp := range paths {
+		if now.Sub(rp.last) < replyPathTTL && (rp.path.Expiry.IsZero() || now.Before(rp.path.Expiry)) {
+			recent = append(recent, rp)
+		}
+	}
+	if len(recent) == 0 {
+		delete(s.remotes, remote)
+		return nil
+	}
+	s.remotes[remote] = recent
+	// Hand out a copy, s.remotes is modified in place.
+	return append([]*replyPath(nil), recent...)
+}
+
+func newest(paths []*replyPath) *pan.Path {
+	if len(paths) == 0 {
+		return nil
+	}
+	return paths[0].path
+}
+
+func oldest(paths []*replyPath) *pan.Path {
+	var found *replyPath
+	for _, rp := range paths {
+		if found == nil || rp.first.Before(found.first) {
+			found = rp
+		}
+	}
+	if found == nil {
+		return nil
+	}
+	return found.path
+}
+
+// lowestLatency returns the path with the lowest announced latency, paths without metadata come last.
+func lowestLatency(paths []*replyPath) *pan.Path {
+	var found *pan.Path
+	best := time.Duration(-1)
+	for _, rp := range paths {
+		l, ok := latency(rp.path)
+		if !ok {
+			continue
+		}
+		if best < 0 || l < best {
+			found, best = rp.path, l
+		}
+	}
+	if found == nil {
+		return newest(paths)
+	}
+	return found
+}
+
+// latency sums the announced latencies of the hops of p. It returns false if any of them is unknown.
+func latency(p *pan.Path) (time.Duration, bool) {
+	if p.Metadata == nil || len(p.Metadata.Latency) == 0 {
+		return 0, false
+	}
+	var sum time.Duration
+	for _, l := range p.Metadata.Latency {
+		if l < 0 {
+			return 0, false
+		}
+		sum += l
+	}
+	return sum, true
+}
+
+// avoiding returns a chooser for the most recent path that doesn't traverse any of isds. Paths
+// without metadata can't be checked and are avoided as well.
+func avoiding(isds map[uint16]bool) chooser {
+	return func(paths []*replyPath) *pan.Path {
+	Paths:
+		for _, rp := range paths {
+			if rp.path.Metadata == nil {
+				continue
+			}
+			for _, i := range rp.path.Metadata.Interfaces {
+				ia, err := ParseIA(i.IA.String())
+				if err != nil || isds[ia.ISD] {
+					continue Paths
+				}
+			}
+			return rp.path
+		}
+		return nil
+	}
+}
+
+func filter(paths []*replyPath, policy pan.Policy) []*replyPath {
+	candidates := make([]*pan.Path, len(paths))
+	for i, rp := range paths {
+		candidates[i] = rp.path
+	}
+	accepted := map[pan.PathFingerprint]bool{}
+	for _, p := range policy.Filter(candidates) {
+		accepted[p.Fingerprint] = true
+	}
+	filtered := make([]*replyPath, 0, len(paths))
+	for _, rp := range paths {
+		if accepted[rp.path.Fingerprint] {
+			filtered = append(filtered, rp)
+		}
+	}
+	return filtered
+}
+
+// Overrides of the reply path per client, see OverrideReplyPolicy.
+var (
+	overridesMu sync.Mutex
+	overrides   = map[string]replyOverride{}
+)
+
+type replyOverride struct {
+	policy  pan.Policy
+	expires time.Time
+}
+
+// OverrideReplyPolicy makes squic listeners reply to remote, the address of a SCION client, only on
+// paths accepted by policy, for the next ttl. Plugins can use this to steer the responses to a
+// client. As all queries of a client share a QUIC connection, whose packets can't be told apart by
+// the reply selector, the override applies to all packets sent to remote, not a single response. If
+// none of the recent paths of the client is accepted, the configured selector is used.
+func OverrideReplyPolicy(remote net.Addr, policy pan.Policy, ttl time.Duration) {
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+	overrides[remote.String()] = replyOverride{policy: policy, expires: time.Now().Add(ttl)}
+}
+
+// replyPolicy returns the policy overriding the reply paths to remote, or nil.
+func replyPolicy(remote string) pan.Policy {
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+	o, ok := overrides[remote]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(o.expires) {
+		delete(overrides, remote)
+		return nil
+	}
+	return o.policy
+}
@@ -0,0 +1,220 @@
+package scion
+
+import (
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+func TestReplySelectors(t *testing.T) {
+	for _, tc := range []struct {
+		d         Defaults
+		shouldErr bool
+	}{
+		{Defaults{}, false},
+		{Defaults{ReplySelector: ReplySelectorLatency}, false},
+		{Defaults{ReplySelector: ReplySelectorPinned}, false},
+		{Defaults{ReplySelector: ReplySelectorAvoid, ReplySelectorArgs: "17 18"}, false},
+		{Defaults{ReplySelector: ReplySelectorAvoid}, true},
+		{Defaults{ReplySelector: ReplySelectorAvoid, ReplySelectorArgs: "isd"}, true},
+		{Defaults{ReplySelector: ReplySelectorLatency, ReplySelectorArgs: "17"}, true},
+		{Defaults{ReplySelector: "fastest"}, true},
+	} {
+		s, err := tc.d.NewReplySelector()
+		if tc.shouldErr != (err != nil) {
+			t.Errorf("Selector %q %q: expected error %t, got %v", tc.d.ReplySelector, tc.d.ReplySelectorArgs, tc.shouldErr, err)
+		}
+		if s != nil {
+			s.Close()
+		}
+	}
+}
+
+func TestChoosers(t *testing.T) {
+	now := time.Now()
+	path := func(fp string, ms int, isds ...string) *replyPath {
+		p := &pan.Path{Fingerprint: pan.PathFingerprint(fp), Metadata: &pan.PathMetadata{}}
+		if ms >= 0 {
+			p.Metadata.Latency = []time.Duration{time.Duration(ms) * time.Millisecond, time.Millisecond}
+		}
+		for _, isd := range isds {
+			ia, err := pan.ParseIA(isd + "-ffaa:0:1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.Metadata.Interfaces = append(p.Metadata.Interfaces, pan.PathInterface{IA: ia})
+		}
+		return &replyPath{path: p, first: now.Add(-time.Duration(len(fp)) * time.Minute), last: now}
+	}
+	// Most recently used first.
+	paths := []*replyPath{path("a", 50, "19", "17"), path("bb", 20, "19", "18"), path("ccc", -1, "19")}
+
+	if p := newest(paths); p.Fingerprint != "a" {
+		t.Errorf("Expected the newest path a, got %s", p.Fingerprint)
+	}
+	if p := oldest(paths); p.Fingerprint != "ccc" {
+		t.Errorf("Expected the oldest path ccc, got %s", p.Fingerprint)
+	}
+	if p := lowestLatency(paths); p.Fingerprint != "bb" {
+		t.Errorf("Expected the fastest path bb, got %s", p.Fingerprint)
+	}
+	if p := avoiding(map[uint16]bool{17: true})(paths); p.Fingerprint != "bb" {
+		t.Errorf("Expected path bb that avoids ISD 17, got %s", p.Fingerprint)
+	}
+	if p := avoiding(map[uint16]bool{19: true})(paths); p != nil {
+		t.Errorf("Expected no path avoiding ISD 19, got %s", p.Fingerprint)
+	}
+	if p := lowestLatency(paths[2:]); p.Fingerprint != "ccc" {
+		t.Errorf("Expected the newest path without latencies, got %s", p.Fingerprint)
+	}
+}
+
+func TestReplySelectorPath(t *testing.T) {
+	client, err := pan.ParseUDPAddr("19-ffaa:1:1067,[10.0.0.1]:40000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := func(ms int, isd string, ifid pan.IfID) *pan.PathMetadata {
+		ia, err := pan.ParseIA(isd + "-ffaa:0:1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &pan.PathMetadata{
+			Interfaces: []pan.PathInterface{{IA: ia, IfID: ifid}, {IA: client.IA, IfID: ifid + 10}},
+			Latency:    []time.Duration{time.Duration(ms) * time.Millisecond},
+		}
+	}
+	fast, slow := md(10, "18", 1), md(50, "17", 2)
+	fastFP, slowFP := fingerprint(fast.Interfaces), fingerprint(slow.Interfaces)
+	setDaemonPaths(t, client.IA, fast, slow)
+
+	for _, tc := range []struct {
+		d        Defaults
+		expected pan.PathFingerprint
+	}{
+		{Defaults{}, slowFP},
+		{Defaults{ReplySelector: ReplySelectorLatency}, fastFP},
+		{Defaults{ReplySelector: ReplySelectorPinned}, fastFP},
+		{Defaults{ReplySelector: ReplySelectorAvoid, ReplySelectorArgs: "17"}, fastFP},
+		{Defaults{ReplySelector: ReplySelectorAvoid, ReplySelectorArgs: "18"}, slowFP},
+	} {
+		// The listener only knows the selector as a pan.ReplySelector.
+		var s pan.ReplySelector
+		s, err := tc.d.NewReplySelector()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Record(client, reversePath(client, fast))
+		s.Record(client, reversePath(client, slow))
+		if p := s.Path(client); p == nil || p.Fingerprint != tc.expected {
+			t.Errorf("Selector %q %q: expected reply path %s, got %v", tc.d.ReplySelector, tc.d.ReplySelectorArgs, tc.expected, p)
+		}
+		s.Close()
+	}
+
+	var s pan.ReplySelector = newPathSelector(nil)
+	defer s.Close()
+	s.Record(client, reversePath(client, fast))
+	s.Record(client, reversePath(client, slow))
+	OverrideReplyPolicy(client, pan.PolicyFunc(func(paths []*pan.Path) []*pan.Path {
+		var accepted []*pan.Path
+		for _, p := range paths {
+			if p.Fingerprint == fastFP {
+				accepted = append(accepted, p)
+			}
+		}
+		return accepted
+	}), time.Minute)
+	defer OverrideReplyPolicy(client, nil, 0)
+	if p := s.Path(client); p == nil || p.Fingerprint != fastFP {
+		t.Errorf("Expected the overridden reply path %s, got %v", fastFP, p)
+	}
+
+	// The daemon knows no paths to other, without metadata the latency selector takes the newest path.
+	other, err := pan.ParseUDPAddr("20-ffaa:1:1,[10.0.0.2]:40000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = newPathSelector(lowestLatency)
+	defer s.Close()
+	s.Record(other, reversePath(other, fast))
+	s.Record(other, reversePath(other, slow))
+	if p := s.Path(other); p == nil || p.Fingerprint != slowFP {
+		t.Errorf("Expected the newest reply path %s without metadata, got %v", slowFP, p)
+	}
+}
+
+func TestReplyPathFailover(t *testing.T) {
+	client, err := pan.ParseUDPAddr("19-ffaa:1:1067,[10.0.0.1]:40000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ia, err := pan.ParseIA("19-ffaa:0:1301")
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := func(ifid pan.IfID) *pan.PathMetadata {
+		return &pan.PathMetadata{Interfaces: []pan.PathInterface{{IA: ia, IfID: ifid}, {IA: client.IA, IfID: ifid + 10}}}
+	}
+	mdA, mdB, mdC := md(1), md(2), md(3)
+	setDaemonPaths(t, client.IA, mdA, mdB, mdC)
+	a, b, c := reversePath(client, mdA), reversePath(client, mdB), reversePath(client, mdC)
+
+	// pan reports SCMP path down messages through the pan.ReplySelector interface.
+	var s pan.ReplySelector = newPathSelector(nil)
+	defer s.Close()
+	s.Record(client, a)
+	s.Record(client, b)
+	if p := s.Path(client); p == nil || p.Fingerprint != b.Fingerprint {
+		t.Fatalf("Expected reply path b, got %v", p)
+	}
+
+	s.PathDown(b.Fingerprint, pan.PathInterface{IA: ia, IfID: 2})
+	if p := s.Path(client); p == nil || p.Fingerprint != a.Fingerprint {
+		t.Errorf("Expected reply path a once b is down, got %v", p)
+	}
+
+	// A packet on b shows that it's up again.
+	s.Record(client, b)
+	if p := s.Path(client); p == nil || p.Fingerprint != b.Fingerprint {
+		t.Errorf("Expected reply path b once it's up again, got %v", p)
+	}
+
+	// Paths through an interface that went down are avoided as well, the interfaces are known from
+	// the daemon's metadata.
+	s.Record(client, c)
+	s.PathDown("other", pan.PathInterface{IA: ia, IfID: 3})
+	if p := s.Path(client); p == nil || p.Fingerprint != b.Fingerprint {
+		t.Errorf("Expected reply path b once the interface of c is down, got %v", p)
+	}
+}
+
+func TestReplyPathMTU(t *testing.T) {
+	client, err := pan.ParseUDPAddr("19-ffaa:1:1067,[10.0.0.1]:40000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ia, err := pan.ParseIA("19-ffaa:0:1301")
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := func(ifid pan.IfID, mtu uint16) *pan.PathMetadata {
+		return &pan.PathMetadata{Interfaces: []pan.PathInterface{{IA: ia, IfID: ifid}, {IA: client.IA, IfID: ifid + 10}}, MTU: mtu}
+	}
+	large, small := md(1, 1472), md(2, 1280)
+	setDaemonPaths(t, client.IA, large, small)
+
+	var s pan.ReplySelector = newPathSelector(nil)
+	defer s.Close()
+	s.Record(client, reversePath(client, small))
+	if p := s.Path(client); p == nil || p.Fingerprint != fingerprint(small.Interfaces) {
+		t.Fatalf("Expected the small path, the only one of the client, got %v", p)
+	}
+
+	s.Record(client, reversePath(client, large))
+	s.Record(client, reversePath(client, small))
+	if p := s.Path(client); p == nil || p.Fingerprint != fingerprint(large.Interfaces) {
+		t.Errorf("Expected the large path, QUIC's packets don't fit through the small one, got %v", p)
+	}
+}
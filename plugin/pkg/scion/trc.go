@@ -0,0 +1,176 @@
+package scion
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/scrypto/cppki"
+)
+
+// TRCs verify certificates of the SCION control-plane PKI (CP-PKI), the AS certificates, against the trust
+// root configurations (TRCs) of the ISDs, instead of against WebPKI CAs. The TRCs are read from the *.trc
+// files in a directory, e.g. the certs directory of the SCION installation. They are read again when files
+// are added to or removed from it, so updated TRCs are picked up without a restart.
+type TRCs struct {
+	dir string
+
+	mu      sync.Mutex
+	trcs    []*cppki.TRC
+	modTime time.Time // modification time of dir when the TRCs were read
+}
+
+// LoadTRCs reads the TRCs in dir. It fails if there are none.
+func LoadTRCs(dir string) (*TRCs, error) {
+	t := &TRCs{dir: dir}
+	if _, err := t.current(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Dir returns the directory the TRCs are read from.
+func (t *TRCs) Dir() string { return t.dir }
+
+// current returns the TRCs, after reading them again if dir changed. If that fails, the TRCs read before
+// are kept.
+func (t *TRCs) current() ([]*cppki.TRC, error) {
+	fi, err := os.Stat(t.dir)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.trcs != nil && fi.ModTime().Equal(t.modTime) {
+		return t.trcs, nil
+	}
+	trcs, err := readTRCs(t.dir)
+	if err != nil {
+		if t.trcs != nil {
+			return t.trcs, nil
+		}
+		return nil, err
+	}
+	t.trcs = trcs
+	t.modTime = fi.ModTime()
+	return trcs, nil
+}
+
+// readTRCs reads the *.trc files in dir, which are DER or PEM encoded signed TRCs.
+func readTRCs(dir string) ([]*cppki.TRC, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.trc"))
+	if err != nil {
+		return nil, err
+	}
+	var trcs []*cppki.TRC
+	for _, f := range files {
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if block, _ := pem.Decode(raw); block != nil && block.Type == "TRC" {
+			raw = block.Bytes
+		}
+		signed, err := cppki.DecodeSignedTRC(raw)
+		if err != nil {
+			return nil, fmt.Errorf("decoding TRC %s: %s", f, err)
+		}
+		trc := signed.TRC
+		trcs = append(trcs, &trc)
+	}
+	if len(trcs) == 0 {
+		return nil, fmt.Errorf("no TRCs in %s", dir)
+	}
+	return trcs, nil
+}
+
+// errNoChain is returned if the peer didn't present an AS certificate and the CA certificate that issued it.
+var errNoChain = errors.New("no SCION certificate chain presented")
+
+// errNoIA is returned if a server is to be verified against the TRCs without knowing its ISD-AS. Any AS
+// certificate of any ISD would be accepted then.
+var errNoIA = errors.New("no ISD-AS to verify the server certificate against")
+
+// Verify verifies the certificate chain, as received in a TLS handshake, at now. The chain must be
+// an AS certificate and the CA certificate that issued it, and verify against a TRC of the ISD of the AS
+// that is valid at now. If ia is not zero, the AS certificate must be for ia. Verify returns the ISD-AS of
+// the AS certificate.
+func (t *TRCs) Verify(chain [][]byte, ia IA, now time.Time) (IA, error) {
+	if len(chain) != 2 {
+		return IA{}, errNoChain
+	}
+	certs := make([]*x509.Certificate, len(chain))
+	for i, raw := range chain {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return IA{}, err
+		}
+		certs[i] = cert
+	}
+	certIA, err := cppki.ExtractIA(certs[0].Subject)
+	if err != nil {
+		return IA{}, err
+	}
+	subject := IA{ISD: uint16(certIA.ISD()), AS: uint64(certIA.AS())}
+	if !ia.IsZero() && subject != ia {
+		return subject, fmt.Errorf("certificate is for %s, not %s", subject, ia)
+	}
+
+	all, err := t.current()
+	if err != nil {
+		return subject, err
+	}
+	var trcs []*cppki.TRC
+	for _, trc := range all {
+		if uint16(trc.ID.ISD) == subject.ISD && (trc.Validity.Contains(now) || trc.InGracePeriod(now)) {
+			trcs = append(trcs, trc)
+		}
+	}
+	if len(trcs) == 0 {
+		return subject, fmt.Errorf("no valid TRC of ISD %d", subject.ISD)
+	}
+	if err := cppki.VerifyChain(certs, cppki.VerifyOptions{TRC: trcs, CurrentTime: now}); err != nil {
+		return subject, err
+	}
+	return subject, nil
+}
+
+// ConfigureClient makes cfg verify the server certificate against the TRCs instead of the root CAs. The
+// server's AS certificate must be for ia. The server name isn't checked, AS certificates don't carry one,
+// so ia is what ties the certificate to the server: ConfigureClient fails if ia is zero.
+func (t *TRCs) ConfigureClient(cfg *tls.Config, ia IA) error {
+	if ia.IsZero() {
+		return errNoIA
+	}
+	cfg.InsecureSkipVerify = true
+	// VerifyConnection, unlike VerifyPeerCertificate, is called for resumed sessions as well.
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		_, err := t.Verify(rawCerts(cs.PeerCertificates), ia, time.Now())
+		return err
+	}
+	return nil
+}
+
+// ConfigureServer makes cfg require a client certificate that verifies against the TRCs, instead of
+// against the client CAs.
+func (t *TRCs) ConfigureServer(cfg *tls.Config) {
+	cfg.ClientAuth = tls.RequireAnyClientCert
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		_, err := t.Verify(rawCerts(cs.PeerCertificates), IA{}, time.Now())
+		return err
+	}
+}
+
+func rawCerts(certs []*x509.Certificate) [][]byte {
+	raw := make([][]byte, len(certs))
+	for i, c := range certs {
+		raw[i] = c.Raw
+	}
+	return raw
+}
@@ -0,0 +1,112 @@
+package scion
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"os"
+	"testing"
+	"time"
+)
+
+// The TRC and chains in testdata are those of the SCION cppki tests, their certificates are valid in 2020.
+var trcTestTime = time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func readChain(t *testing.T, path string) [][]byte {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chain [][]byte
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			return chain
+		}
+		chain = append(chain, block.Bytes)
+	}
+}
+
+func TestTRCsVerify(t *testing.T) {
+	trcs, err := LoadTRCs("testdata/trc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := readChain(t, "testdata/ISD1-ASff00_0_110.pem")
+	as110 := IA{ISD: 1, AS: 0xff0000000110}
+
+	tests := []struct {
+		chain     [][]byte
+		ia        IA
+		now       time.Time
+		shouldErr bool
+	}{
+		{chain, IA{}, trcTestTime, false},
+		{chain, as110, trcTestTime, false},
+		{chain, IA{ISD: 1, AS: 0xff0000000111}, trcTestTime, true},
+		{chain, IA{}, trcTestTime.AddDate(5, 0, 0), true},
+		{chain[:1], IA{}, trcTestTime, true},
+		// No TRC of ISD 2.
+		{readChain(t, "testdata/ISD2-ASff00_0_210.pem"), IA{}, trcTestTime, true},
+	}
+	for i, tc := range tests {
+		ia, err := trcs.Verify(tc.chain, tc.ia, tc.now)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if ia != as110 {
+			t.Errorf("Test %d: expected %s, got %s", i, as110, ia)
+		}
+	}
+}
+
+func TestConfigureClient(t *testing.T) {
+	trcs, err := LoadTRCs("testdata/trc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without an ISD-AS any AS certificate would authenticate the server.
+	cfg := &tls.Config{}
+	if err := trcs.ConfigureClient(cfg, IA{}); err == nil {
+		t.Error("Expected an error without an ISD-AS")
+	}
+	if cfg.InsecureSkipVerify || cfg.VerifyConnection != nil {
+		t.Error("Expected the config to be left alone without an ISD-AS")
+	}
+	if err := trcs.ConfigureClient(cfg, IA{ISD: 1, AS: 0xff0000000110}); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !cfg.InsecureSkipVerify || cfg.VerifyConnection == nil {
+		t.Error("Expected the server to be verified against the TRCs")
+	}
+}
+
+func TestLoadTRCs(t *testing.T) {
+	if _, err := LoadTRCs(t.TempDir()); err == nil {
+		t.Error("Expected error for a directory without TRCs, got none")
+	}
+	if _, err := LoadTRCs("testdata/missing"); err == nil {
+		t.Error("Expected error for a missing directory, got none")
+	}
+}
+
+func TestAddrIA(t *testing.T) {
+	ia, err := AddrIA("19-ffaa:1:1067,[10.0.0.1]:853")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ia != (IA{ISD: 19, AS: 0xffaa00011067}) {
+		t.Errorf("Expected 19-ffaa:1:1067, got %s", ia)
+	}
+	if _, err := AddrIA("10.0.0.1:853"); err == nil {
+		t.Error("Expected error for an IP address, got none")
+	}
+}
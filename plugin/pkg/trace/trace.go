@@ -11,3 +11,9 @@ type Trace interface {
 	plugin.Handler
 	Tracer() ot.Tracer
 }
+
+// Sampler is implemented by a Trace that only traces some of the queries. Servers that start spans of
+// their own, before the query reaches the Trace, ask it whether to trace the query.
+type Sampler interface {
+	Sample() bool
+}
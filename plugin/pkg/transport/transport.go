@@ -8,6 +8,7 @@ const (
 	QUIC  = "quic"
 	SQUIC = "squic"
 	HTTPS = "https"
+	H3    = "h3"
 )
 
 // Port numbers for the various transports.
@@ -18,7 +19,7 @@ const (
 	TLSPort = "853"
 	// GRPCPort is the default port for DNS-over-gRPC.
 	GRPCPort = "443"
-	// HTTPSPort is the default port for DNS-over-HTTPS.
+	// HTTPSPort is the default port for DNS-over-HTTPS, also over HTTP/3.
 	HTTPSPort = "443"
 	// QUICPort is the default port for DNS-over-QUIC.
 	// https://datatracker.ietf.org/doc/html/draft-ietf-dprive-dnsoquic-02#section-10.2.1
@@ -26,3 +27,29 @@ const (
 	// (Note that prior to version -02 of this draft, experiments were directed to use port 784.)
 	QUICPort = "8853"
 )
+
+// DoQALPN is the ALPN token of DNS-over-QUIC, RFC 9250.
+const DoQALPN = "doq"
+
+// DoQLegacyALPNs are the ALPN tokens of the DoQ drafts, which clients and servers that predate RFC 9250
+// still use.
+var DoQLegacyALPNs = []string{"doq-i11", "doq-i02", "doq-i00", "dq"}
+
+// DoQALPNs are the ALPN tokens DoQ clients offer and DoQ servers accept with legacy tokens enabled, in
+// order of preference. It must not be modified.
+var DoQALPNs = append([]string{DoQALPN}, DoQLegacyALPNs...)
+
+// DNS-over-QUIC application error codes, used when resetting streams and closing connections.
+// https://www.rfc-editor.org/rfc/rfc9250.html#section-4.3
+const (
+	// DoQNoError is used when closing a connection or stream without an error.
+	DoQNoError = 0x0
+	// DoQInternalError signals that an internal error made processing the query impossible.
+	DoQInternalError = 0x1
+	// DoQProtocolError signals a protocol error, such as a malformed query.
+	DoQProtocolError = 0x2
+	// DoQRequestCancelled signals that a query was cancelled.
+	DoQRequestCancelled = 0x3
+	// DoQExcessiveLoad signals that a connection or stream is closed due to excessive load.
+	DoQExcessiveLoad = 0x4
+)
@@ -0,0 +1,330 @@
+// Package quic implements the quic plugin, which configures the DNS-over-QUIC (quic and squic) servers.
+package quic
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/durations"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+)
+
+func init() { plugin.Register("quic", setup) }
+
+func setup(c *caddy.Controller) error {
+	err := parseQUIC(c)
+	if err != nil {
+		return plugin.Error("quic", err)
+	}
+	return nil
+}
+
+func parseQUIC(c *caddy.Controller) error {
+	config := dnsserver.GetConfig(c)
+
+	for c.Next() {
+		args := c.RemainingArgs()
+		if len(args) > 0 {
+			return c.ArgErr()
+		}
+
+		b := 0
+		for c.NextBlock() {
+			switch c.Val() {
+			case "idle_reap":
+				if !c.NextArg() {
+					return c.ArgErr()
+				}
+				dur, err := durations.NewDurationFromArg(c.Val())
+				if err != nil {
+					return c.Err(err.Error())
+				}
+				if dur <= 0 {
+					return c.Errf("idle_reap '%s' needs to be positive", dur)
+				}
+				config.QUIC.IdleReap = dur
+			case "idle_timeout", "keepalive", "drain", "cert_expiry_warning":
+				opt := c.Val()
+				if !c.NextArg() {
+					return c.ArgErr()
+				}
+				dur, err := durations.NewDurationFromArg(c.Val())
+				if err != nil {
+					return c.Err(err.Error())
+				}
+				if dur <= 0 {
+					return c.Errf("%s '%s' needs to be positive", opt, dur)
+				}
+				switch opt {
+				case "idle_timeout":
+					config.QUIC.IdleTimeout = dur
+				case "keepalive":
+					config.QUIC.KeepAlive = dur
+				case "drain":
+					config.QUIC.DrainTimeout = dur
+				default:
+					config.QUIC.CertExpiryWarning = dur
+				}
+			case "stream_window", "connection_window":
+				opt := c.Val()
+				args := c.RemainingArgs()
+				if len(args) < 1 || len(args) > 2 {
+					return c.ArgErr()
+				}
+				w, err := parseWindow(args)
+				if err != nil {
+					return c.Errf("%s %s", opt, err)
+				}
+				if opt == "stream_window" {
+					config.QUIC.StreamWindow = w
+				} else {
+					config.QUIC.ConnectionWindow = w
+				}
+			case "max_streams", "worker_pool_size", "accept_loops", "max_sessions":
+				opt := c.Val()
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return c.ArgErr()
+				}
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n <= 0 {
+					return c.Errf("%s '%s' must be a positive integer", opt, args[0])
+				}
+				switch opt {
+				case "max_streams":
+					config.QUIC.MaxStreams = n
+				case "worker_pool_size":
+					config.QUIC.MaxWorkerPoolSize = n
+				case "accept_loops":
+					config.QUIC.AcceptLoops = n
+				default:
+					config.QUIC.MaxSessions = n
+				}
+			case "allow_0rtt":
+				if c.NextArg() {
+					return c.ArgErr()
+				}
+				config.QUIC.Allow0RTT = true
+			case "address_validation":
+				args := c.RemainingArgs()
+				if len(args) > 1 {
+					return c.ArgErr()
+				}
+				config.QUIC.AddressValidation = true
+				if len(args) == 1 {
+					n, err := strconv.Atoi(args[0])
+					if err != nil || n <= 0 {
+						return c.Errf("address_validation threshold '%s' must be a positive integer", args[0])
+					}
+					config.QUIC.AddressValidationThreshold = n
+				}
+			case "non_recursive":
+				if !c.NextArg() {
+					return c.ArgErr()
+				}
+				switch c.Val() {
+				case dnsserver.NonRecursiveRecurse, dnsserver.NonRecursiveLocal, dnsserver.NonRecursiveRefuse:
+					config.QUIC.NonRecursive = c.Val()
+				default:
+					return c.Errf("unknown non_recursive mode '%s'", c.Val())
+				}
+				if c.NextArg() {
+					return c.ArgErr()
+				}
+			case "checking_disabled":
+				if !c.NextArg() {
+					return c.ArgErr()
+				}
+				switch c.Val() {
+				case dnsserver.CheckingDisabledPass, dnsserver.CheckingDisabledClear, dnsserver.CheckingDisabledNoCache:
+					config.QUIC.CheckingDisabled = c.Val()
+				default:
+					return c.Errf("unknown checking_disabled mode '%s'", c.Val())
+				}
+				if c.NextArg() {
+					return c.ArgErr()
+				}
+			case "cache_bypass":
+				if c.NextArg() {
+					return c.ArgErr()
+				}
+				config.QUIC.CacheBypass = true
+			case "no_compression":
+				if c.NextArg() {
+					return c.ArgErr()
+				}
+				config.QUIC.NoCompression = true
+			case "rate_limit":
+				args := c.RemainingArgs()
+				if len(args) < 2 || len(args) > 3 {
+					return c.ArgErr()
+				}
+				rate, err := parseRate(args[1:])
+				if err != nil {
+					return c.Errf("rate_limit %s", err)
+				}
+				switch args[0] {
+				case "connection":
+					config.QUIC.ConnectionRate = rate
+				case "source":
+					config.QUIC.SourceRate = rate
+				default:
+					return c.Errf("unknown rate_limit scope '%s'", args[0])
+				}
+			case "telemetry":
+				if c.NextArg() {
+					return c.ArgErr()
+				}
+				config.QUIC.Telemetry = true
+			case "qlog":
+				args := c.RemainingArgs()
+				if len(args) == 0 || len(args) > 2 {
+					return c.ArgErr()
+				}
+				if fi, err := os.Stat(args[0]); err != nil || !fi.IsDir() {
+					return c.Errf("qlog directory '%s' does not exist", args[0])
+				}
+				config.QUIC.QlogDir = args[0]
+				if len(args) == 2 {
+					n, err := strconv.Atoi(args[1])
+					if err != nil || n <= 0 {
+						return c.Errf("qlog max files '%s' must be a positive integer", args[1])
+					}
+					config.QUIC.QlogMaxFiles = n
+				}
+			case "keylog":
+				args := c.RemainingArgs()
+				if len(args) > 1 {
+					return c.ArgErr()
+				}
+				path := os.Getenv("SSLKEYLOGFILE")
+				if len(args) == 1 {
+					path = args[0]
+				}
+				if path == "" {
+					return c.Err("keylog needs a file, or SSLKEYLOGFILE to be set")
+				}
+				if fi, err := os.Stat(filepath.Dir(path)); err != nil || !fi.IsDir() {
+					return c.Errf("keylog directory '%s' does not exist", filepath.Dir(path))
+				}
+				config.QUIC.KeyLogFile = path
+			case "log_handshakes":
+				if c.NextArg() {
+					return c.ArgErr()
+				}
+				config.QUIC.LogHandshakes = true
+			case "legacy_alpn":
+				if c.NextArg() {
+					return c.ArgErr()
+				}
+				config.QUIC.LegacyALPN = true
+			case "reply_selector":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return c.ArgErr()
+				}
+				if !pkgscion.HasReplySelector(args[0]) {
+					return c.Errf("unknown reply selector '%s'", args[0])
+				}
+				d, _ := dnsserver.SCIONDefaults(c)
+				d.ReplySelector, d.ReplySelectorArgs = args[0], strings.Join(args[1:], " ")
+				// Check the arguments.
+				sel, err := d.NewReplySelector()
+				if err != nil {
+					return c.Err(err.Error())
+				}
+				sel.Close()
+				config.QUIC.ReplySelector, config.QUIC.ReplySelectorArgs = d.ReplySelector, d.ReplySelectorArgs
+			case "client_address":
+				if !c.NextArg() {
+					return c.ArgErr()
+				}
+				key, err := base64.StdEncoding.DecodeString(c.Val())
+				if err != nil || len(key) == 0 {
+					return c.Errf("invalid client_address key '%s'", c.Val())
+				}
+				if c.NextArg() {
+					return c.ArgErr()
+				}
+				config.QUIC.ClientAddrKey = key
+			case "self_check":
+				args := c.RemainingArgs()
+				if len(args) > 0 && args[len(args)-1] == "fail" {
+					config.QUIC.SelfCheckFail = true
+					args = args[:len(args)-1]
+				}
+				if len(args) > 1 {
+					return c.ArgErr()
+				}
+				config.QUIC.SelfCheck = true
+				if len(args) == 1 {
+					config.QUIC.SelfCheckName = args[0]
+				}
+			default:
+				return c.Errf("unknown option: '%s'", c.Val())
+			}
+			b++
+		}
+
+		if b == 0 {
+			return c.Err("quic block with no options specified")
+		}
+
+		idleTimeout := dnsserver.DefaultQUICIdleTimeout
+		if config.QUIC.IdleTimeout != 0 {
+			idleTimeout = config.QUIC.IdleTimeout
+		}
+		if config.QUIC.IdleReap >= idleTimeout {
+			return c.Errf("idle_reap '%s' needs to be shorter than the QUIC idle timeout of %s", config.QUIC.IdleReap, idleTimeout)
+		}
+		if config.QUIC.KeepAlive >= idleTimeout {
+			return c.Errf("keepalive '%s' needs to be shorter than the QUIC idle timeout of %s", config.QUIC.KeepAlive, idleTimeout)
+		}
+	}
+	return nil
+}
+
+// parseWindow parses INITIAL [MAX], without MAX the window doesn't grow beyond INITIAL.
+func parseWindow(args []string) (dnsserver.QUICWindow, error) {
+	var w dnsserver.QUICWindow
+	for i, a := range args {
+		n, err := strconv.ParseUint(a, 10, 64)
+		if err != nil || n == 0 {
+			return w, fmt.Errorf("'%s' must be a positive integer", a)
+		}
+		if i == 0 {
+			w.Initial, w.Max = n, n
+		} else {
+			w.Max = n
+		}
+	}
+	if w.Max < w.Initial {
+		return w, fmt.Errorf("maximum %d must not be smaller than initial %d", w.Max, w.Initial)
+	}
+	return w, nil
+}
+
+// parseRate parses QPS [BURST], the burst defaults to QPS rounded up.
+func parseRate(args []string) (dnsserver.QUICRate, error) {
+	qps, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || qps <= 0 || math.IsInf(qps, 0) || math.IsNaN(qps) {
+		return dnsserver.QUICRate{}, fmt.Errorf("'%s' must be a positive number", args[0])
+	}
+	rate := dnsserver.QUICRate{QPS: qps, Burst: int(math.Ceil(qps))}
+	if len(args) > 1 {
+		burst, err := strconv.Atoi(args[1])
+		if err != nil || burst <= 0 {
+			return dnsserver.QUICRate{}, fmt.Errorf("burst '%s' must be a positive integer", args[1])
+		}
+		rate.Burst = burst
+	}
+	return rate, nil
+}
@@ -0,0 +1,328 @@
+package quic
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+)
+
+func TestQUIC(t *testing.T) {
+	tests := []struct {
+		input              string
+		shouldErr          bool
+		expectedIdleReap   time.Duration
+		expectedErrContent string // substring from the expected error. Empty for positive cases.
+	}{
+		// positive
+		{`quic {
+			idle_reap 30s
+		}`, false, 30 * time.Second, ""},
+		{`quic {
+			idle_reap 2m
+		}`, false, 2 * time.Minute, ""},
+		{`quic {
+			self_check
+		}`, false, 0, ""},
+		{`quic {
+			self_check ns1.example.org fail
+		}`, false, 0, ""},
+		{`quic {
+			self_check fail
+		}`, false, 0, ""},
+		{`quic {
+			self_check ns1.example.org
+			idle_reap 1m
+		}`, false, time.Minute, ""},
+		{`quic {
+			max_streams 100
+			worker_pool_size 2000
+		}`, false, 0, ""},
+		{`quic {
+			accept_loops 4
+		}`, false, 0, ""},
+		{`quic {
+			max_sessions 10000
+		}`, false, 0, ""},
+		{`quic {
+			allow_0rtt
+		}`, false, 0, ""},
+		{`quic {
+			drain 30s
+		}`, false, 0, ""},
+		{`quic {
+			client_address c2VjcmV0
+		}`, false, 0, ""},
+		{`quic {
+			no_compression
+		}`, false, 0, ""},
+		{`quic {
+			telemetry
+		}`, false, 0, ""},
+		{`quic {
+			qlog .
+		}`, false, 0, ""},
+		{`quic {
+			keylog ./keys.log
+			log_handshakes
+		}`, false, 0, ""},
+		{`quic {
+			legacy_alpn
+		}`, false, 0, ""},
+		{`quic {
+			reply_selector avoid 17 18
+		}`, false, 0, ""},
+		{`quic {
+			rate_limit connection 10
+			rate_limit source 100 500
+		}`, false, 0, ""},
+		{`quic {
+			rate_limit source 0.5
+		}`, false, 0, ""},
+		{`quic {
+			address_validation
+		}`, false, 0, ""},
+		{`quic {
+			address_validation 100
+		}`, false, 0, ""},
+		{`quic {
+			non_recursive local
+			checking_disabled no_cache
+			cache_bypass
+		}`, false, 0, ""},
+		// negative
+		{`quic {
+			allow_0rtt yes
+		}`, true, 0, "Wrong argument count"},
+		{`quic {
+			non_recursive forward
+		}`, true, 0, "unknown non_recursive mode"},
+		{`quic {
+			checking_disabled
+		}`, true, 0, "Wrong argument count"},
+		{`quic {
+			checking_disabled clear pass
+		}`, true, 0, "Wrong argument count"},
+		{`quic {
+			cache_bypass yes
+		}`, true, 0, "Wrong argument count"},
+		{`quic {
+			address_validation 0
+		}`, true, 0, "must be a positive integer"},
+		{`quic {
+			address_validation 10 20
+		}`, true, 0, "Wrong argument count"},
+		{`quic {
+			no_compression yes
+		}`, true, 0, "Wrong argument count"},
+		{`quic {
+			telemetry on
+		}`, true, 0, "Wrong argument count"},
+		{`quic {
+			qlog
+		}`, true, 0, "Wrong argument count"},
+		{`quic {
+			qlog /does/not/exist
+		}`, true, 0, "does not exist"},
+		{`quic {
+			qlog . 0
+		}`, true, 0, "must be a positive integer"},
+		{`quic {
+			keylog a.log b.log
+		}`, true, 0, "Wrong argument count"},
+		{`quic {
+			keylog /does/not/exist/keys.log
+		}`, true, 0, "does not exist"},
+		{`quic {
+			log_handshakes yes
+		}`, true, 0, "Wrong argument count"},
+		{`quic {
+			legacy_alpn doq-i00
+		}`, true, 0, "Wrong argument count"},
+		{`quic {
+			reply_selector
+		}`, true, 0, "Wrong argument count"},
+		{`quic {
+			reply_selector fastest
+		}`, true, 0, "unknown reply selector"},
+		{`quic {
+			reply_selector latency 17
+		}`, true, 0, "takes no arguments"},
+		{`quic {
+			rate_limit connection
+		}`, true, 0, "Wrong argument count"},
+		{`quic {
+			rate_limit client 10
+		}`, true, 0, "unknown rate_limit scope"},
+		{`quic {
+			rate_limit source 0
+		}`, true, 0, "must be a positive number"},
+		{`quic {
+			rate_limit connection NaN
+		}`, true, 0, "must be a positive number"},
+		{`quic {
+			rate_limit source 10 0
+		}`, true, 0, "must be a positive integer"},
+		{`quic {
+			client_address
+		}`, true, 0, "Wrong argument count"},
+		{`quic {
+			client_address not-base64!
+		}`, true, 0, "invalid client_address key"},
+		{`quic {
+			max_streams 0
+		}`, true, 0, "must be a positive integer"},
+		{`quic {
+			worker_pool_size many
+		}`, true, 0, "must be a positive integer"},
+		{`quic {
+			accept_loops 0
+		}`, true, 0, "must be a positive integer"},
+		{`quic {
+			max_sessions -1
+		}`, true, 0, "must be a positive integer"},
+		{`quic {
+			max_streams
+		}`, true, 0, "Wrong argument count"},
+		{`quic {
+			self_check ns1.example.org ns2.example.org
+		}`, true, 0, "Wrong argument count"},
+		{`quic {
+			self_check ns1.example.org ns2.example.org fail
+		}`, true, 0, "Wrong argument count"},
+		{`quic`, true, 0, "block with no options specified"},
+		{`quic {
+			idle_reap 10m
+		}`, true, 0, "shorter than the QUIC idle timeout"},
+		{`quic {
+			idle_reap
+		}`, true, 0, "Wrong argument count"},
+		{`quic {
+			giraffe 30s
+		}`, true, 0, "unknown option"},
+		{`quic 30s`, true, 0, "Wrong argument count"},
+		{`quic {
+			idle_timeout 20s
+			idle_reap 30s
+		}`, true, 0, "shorter than the QUIC idle timeout of 20s"},
+		{`quic {
+			keepalive 5m
+		}`, true, 0, "keepalive '5m0s' needs to be shorter"},
+		{`quic {
+			idle_timeout 0s
+		}`, true, 0, "needs to be positive"},
+		{`quic {
+			drain -1s
+		}`, true, 0, "needs to be positive"},
+		{`quic {
+			stream_window 1024 512
+		}`, true, 0, "must not be smaller than initial"},
+		{`quic {
+			connection_window
+		}`, true, 0, "Wrong argument count"},
+		{`quic {
+			connection_window -1
+		}`, true, 0, "must be a positive integer"},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		err := setup(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error but found none for input %s", i, test.input)
+		}
+
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			}
+
+			if !strings.Contains(err.Error(), test.expectedErrContent) {
+				t.Errorf("Test %d: Expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
+			}
+			continue
+		}
+
+		if got := dnsserver.GetConfig(c).QUIC.IdleReap; got != test.expectedIdleReap {
+			t.Errorf("Test %d: Expected idle reap %s, got %s", i, test.expectedIdleReap, got)
+		}
+	}
+}
+
+func TestQUICConfig(t *testing.T) {
+	c := caddy.NewTestController("dns", `quic {
+		idle_timeout 10m
+		idle_reap 6m
+		keepalive 1m
+		drain 20s
+		cert_expiry_warning 720h
+		stream_window 65536
+		connection_window 131072 1048576
+	}`)
+	if err := setup(c); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	config := dnsserver.GetConfig(c)
+	if config.QUIC.IdleTimeout != 10*time.Minute {
+		t.Errorf("Expected idle timeout %s, got %s", 10*time.Minute, config.QUIC.IdleTimeout)
+	}
+	if config.QUIC.KeepAlive != time.Minute {
+		t.Errorf("Expected keepalive %s, got %s", time.Minute, config.QUIC.KeepAlive)
+	}
+	if config.QUIC.DrainTimeout != 20*time.Second {
+		t.Errorf("Expected drain timeout %s, got %s", 20*time.Second, config.QUIC.DrainTimeout)
+	}
+	if config.QUIC.CertExpiryWarning != 720*time.Hour {
+		t.Errorf("Expected certificate expiry warning %s, got %s", 720*time.Hour, config.QUIC.CertExpiryWarning)
+	}
+	if w := (dnsserver.QUICWindow{Initial: 65536, Max: 65536}); config.QUIC.StreamWindow != w {
+		t.Errorf("Expected stream window %v, got %v", w, config.QUIC.StreamWindow)
+	}
+	if w := (dnsserver.QUICWindow{Initial: 131072, Max: 1048576}); config.QUIC.ConnectionWindow != w {
+		t.Errorf("Expected connection window %v, got %v", w, config.QUIC.ConnectionWindow)
+	}
+}
+
+func TestQUICQlog(t *testing.T) {
+	dir := t.TempDir()
+	c := caddy.NewTestController("dns", `quic {
+		qlog `+dir+` 100
+	}`)
+	if err := setup(c); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	config := dnsserver.GetConfig(c)
+	if config.QUIC.QlogDir != dir {
+		t.Errorf("Expected qlog directory %s, got %s", dir, config.QUIC.QlogDir)
+	}
+	if config.QUIC.QlogMaxFiles != 100 {
+		t.Errorf("Expected at most 100 qlog files, got %d", config.QUIC.QlogMaxFiles)
+	}
+}
+
+func TestQUICKeyLog(t *testing.T) {
+	path := t.TempDir() + "/sslkeys.log"
+	t.Setenv("SSLKEYLOGFILE", path)
+	c := caddy.NewTestController("dns", `quic {
+		keylog
+	}`)
+	if err := setup(c); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if config := dnsserver.GetConfig(c); config.QUIC.KeyLogFile != path {
+		t.Errorf("Expected key log file %s from SSLKEYLOGFILE, got %s", path, config.QUIC.KeyLogFile)
+	}
+
+	t.Setenv("SSLKEYLOGFILE", "")
+	c = caddy.NewTestController("dns", `quic {
+		keylog
+	}`)
+	if err := setup(c); err == nil || !strings.Contains(err.Error(), "SSLKEYLOGFILE") {
+		t.Errorf("Expected an error without a file, got %v", err)
+	}
+}
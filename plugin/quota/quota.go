@@ -0,0 +1,89 @@
+// Package quota implements a plugin that counts the queries per client and exposes the counts as
+// metadata, so policy plugins can act on clients exceeding their quota.
+package quota
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metadata"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+const pluginName = "quota"
+
+// threshold names a level that is reached once a client sends Count queries within the window.
+type threshold struct {
+	Level string
+	Count uint64
+}
+
+// Quota is the quota plugin.
+type Quota struct {
+	Next plugin.Handler
+
+	window     time.Duration
+	thresholds []threshold // sorted by Count, ascending
+	clients    *clients
+	stop       chan struct{}
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (q *Quota) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	return plugin.NextOrFailure(pluginName, q.Next, ctx, w, r)
+}
+
+// Name implements the plugin.Handler interface.
+func (q *Quota) Name() string { return pluginName }
+
+// Metadata implements the metadata.Provider interface. It counts the query and sets the client,
+// its count over the window and the highest threshold level reached.
+func (q *Quota) Metadata(ctx context.Context, state request.Request) context.Context {
+	client := clientKey(state)
+	count := q.clients.add(client, time.Now())
+	level := q.level(count)
+
+	metadata.SetValueFunc(ctx, pluginName+"/client", func() string { return client })
+	metadata.SetValueFunc(ctx, pluginName+"/count", func() string { return strconv.FormatUint(count, 10) })
+	metadata.SetValueFunc(ctx, pluginName+"/level", func() string { return level })
+	return ctx
+}
+
+// level returns the level of the highest threshold count reaches, or the empty string.
+func (q *Quota) level(count uint64) string {
+	level := ""
+	for _, t := range q.thresholds {
+		if count < t.Count {
+			break
+		}
+		level = t.Level
+	}
+	return level
+}
+
+// expireClients periodically forgets about clients that have been quiet for a window.
+func (q *Quota) expireClients() {
+	ticker := time.NewTicker(q.window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.stop:
+			return
+		case now := <-ticker.C:
+			q.clients.expire(now)
+		}
+	}
+}
+
+// clientKey returns the ISD-AS for SCION clients and the IP address for all others.
+func clientKey(state request.Request) string {
+	if c, ok := pkgscion.ClientOf(state.W.RemoteAddr()); ok {
+		return c.IA.String()
+	}
+	return state.IP()
+}
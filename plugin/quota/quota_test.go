@@ -0,0 +1,80 @@
+package quota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestMetadata(t *testing.T) {
+	q := &Quota{
+		window:     time.Minute,
+		thresholds: []threshold{{"truncate", 2}, {"refuse", 3}},
+		clients:    newClients(time.Minute),
+	}
+
+	expected := []struct{ count, level string }{
+		{"1", ""},
+		{"2", "truncate"},
+		{"3", "refuse"},
+		{"4", "refuse"},
+	}
+	for i, e := range expected {
+		r := new(dns.Msg)
+		r.SetQuestion("example.org.", dns.TypeA)
+		state := request.Request{W: &test.ResponseWriter{}, Req: r}
+
+		ctx := metadata.ContextWithMetadata(context.TODO())
+		ctx = q.Metadata(ctx, state)
+
+		if got := metadata.ValueFunc(ctx, "quota/client")(); got != "10.240.0.1" {
+			t.Errorf("Test %d: expected client 10.240.0.1, got %s", i, got)
+		}
+		if got := metadata.ValueFunc(ctx, "quota/count")(); got != e.count {
+			t.Errorf("Test %d: expected count %s, got %s", i, e.count, got)
+		}
+		if got := metadata.ValueFunc(ctx, "quota/level")(); got != e.level {
+			t.Errorf("Test %d: expected level %q, got %q", i, e.level, got)
+		}
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	r := new(dns.Msg)
+	r.SetQuestion("example.org.", dns.TypeA)
+	w := &test.ResponseWriter{RemoteSCION: "19-ffaa:1:1067,[10.0.0.1]:40000"}
+	if got := clientKey(request.Request{W: w, Req: r}); got != "19-ffaa:1:1067" {
+		t.Errorf("Expected the ISD-AS 19-ffaa:1:1067 as key, got %s", got)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	c := newClients(10 * time.Second)
+	now := time.Unix(1000, 0)
+
+	c.add("a", now)
+	c.add("a", now.Add(5*time.Second))
+	if got := c.add("a", now.Add(9*time.Second)); got != 3 {
+		t.Errorf("Expected 3 queries within the window, got %d", got)
+	}
+	// The first query dropped out of the window.
+	if got := c.add("a", now.Add(10*time.Second)); got != 3 {
+		t.Errorf("Expected 3 queries within the window, got %d", got)
+	}
+	c.add("b", now.Add(20*time.Second))
+
+	c.expire(now.Add(25 * time.Second))
+	if got := c.len(); got != 1 {
+		t.Errorf("Expected 1 client after expiry, got %d", got)
+	}
+	c.expire(now.Add(40 * time.Second))
+	if got := c.len(); got != 0 {
+		t.Errorf("Expected no clients after expiry, got %d", got)
+	}
+}
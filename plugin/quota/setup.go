@@ -0,0 +1,95 @@
+package quota
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/durations"
+)
+
+func init() { plugin.Register(pluginName, setup) }
+
+// defaultWindow is the window the queries are counted over if not configured.
+const defaultWindow = time.Minute
+
+func setup(c *caddy.Controller) error {
+	q, err := parse(c)
+	if err != nil {
+		return plugin.Error(pluginName, err)
+	}
+
+	c.OnStartup(func() error {
+		go q.expireClients()
+		return nil
+	})
+	c.OnShutdown(func() error {
+		close(q.stop)
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		q.Next = next
+		return q
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller) (*Quota, error) {
+	q := &Quota{window: defaultWindow, stop: make(chan struct{})}
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		if len(c.RemainingArgs()) > 0 {
+			return nil, c.ArgErr()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "window":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				dur, err := durations.NewDurationFromArg(args[0])
+				if err != nil {
+					return nil, c.Err(err.Error())
+				}
+				if dur < time.Second {
+					return nil, c.Errf("window '%s' must be at least one second", dur)
+				}
+				q.window = dur
+			case "threshold":
+				args := c.RemainingArgs()
+				if len(args) != 2 {
+					return nil, c.ArgErr()
+				}
+				count, err := strconv.ParseUint(args[1], 10, 64)
+				if err != nil || count == 0 {
+					return nil, c.Errf("invalid count '%s' for threshold %s", args[1], args[0])
+				}
+				for _, t := range q.thresholds {
+					if t.Level == args[0] {
+						return nil, c.Errf("duplicate threshold %s", args[0])
+					}
+				}
+				q.thresholds = append(q.thresholds, threshold{Level: args[0], Count: count})
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+
+	sort.SliceStable(q.thresholds, func(i, j int) bool { return q.thresholds[i].Count < q.thresholds[j].Count })
+	q.clients = newClients(q.window)
+	return q, nil
+}
@@ -0,0 +1,79 @@
+package quota
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input              string
+		shouldErr          bool
+		expectedWindow     time.Duration
+		expectedThresholds []threshold
+		expectedErrContent string
+	}{
+		{`quota`, false, time.Minute, nil, ""},
+		{`quota {
+			window 10s
+		}`, false, 10 * time.Second, nil, ""},
+		{`quota {
+			threshold refuse 1000
+			threshold truncate 100
+		}`, false, time.Minute, []threshold{{"truncate", 100}, {"refuse", 1000}}, ""},
+		// fails
+		{`quota 10s`, true, 0, nil, "Wrong argument count"},
+		{`quota {
+			window 10ms
+		}`, true, 0, nil, "must be at least one second"},
+		{`quota {
+			threshold truncate
+		}`, true, 0, nil, "Wrong argument count"},
+		{`quota {
+			threshold truncate 0
+		}`, true, 0, nil, "invalid count"},
+		{`quota {
+			threshold truncate 10
+			threshold truncate 20
+		}`, true, 0, nil, "duplicate threshold"},
+		{`quota {
+			giraffe
+		}`, true, 0, nil, "unknown property"},
+		{`quota
+		quota`, true, 0, nil, "plugin can only be used once"},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		q, err := parse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error but found none for input %s", i, test.input)
+		}
+
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			}
+			if !strings.Contains(err.Error(), test.expectedErrContent) {
+				t.Errorf("Test %d: Expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
+			}
+			continue
+		}
+
+		if q.window != test.expectedWindow {
+			t.Errorf("Test %d: Expected window %s, got %s", i, test.expectedWindow, q.window)
+		}
+		if len(q.thresholds) != len(test.expectedThresholds) {
+			t.Fatalf("Test %d: Expected %d thresholds, got %d", i, len(test.expectedThresholds), len(q.thresholds))
+		}
+		for j := range q.thresholds {
+			if q.thresholds[j] != test.expectedThresholds[j] {
+				t.Errorf("Test %d: Expected threshold %v, got %v", i, test.expectedThresholds[j], q.thresholds[j])
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package quota
+
+import (
+	"sync"
+	"time"
+)
+
+// buckets is the number of buckets a window is divided in, the count is exact up to the length of
+// one bucket.
+const buckets = 10
+
+// counter is a rolling count of queries over the last window.
+type counter struct {
+	counts [buckets]uint64
+	epochs [buckets]int64
+	last   int64 // epoch of the last query, used for expiry
+}
+
+// add counts a query in epoch e and returns the count over the window.
+func (c *counter) add(e int64) uint64 {
+	i := e % buckets
+	if c.epochs[i] != e {
+		c.epochs[i] = e
+		c.counts[i] = 0
+	}
+	c.counts[i]++
+	c.last = e
+
+	sum := uint64(0)
+	for j := range c.counts {
+		if c.epochs[j] > e-buckets {
+			sum += c.counts[j]
+		}
+	}
+	return sum
+}
+
+// clients keeps a counter per client.
+type clients struct {
+	sync.Mutex
+	m      map[string]*counter
+	bucket time.Duration
+}
+
+func newClients(window time.Duration) *clients {
+	bucket := window / buckets
+	if bucket <= 0 {
+		bucket = 1
+	}
+	return &clients{m: make(map[string]*counter), bucket: bucket}
+}
+
+func (c *clients) epoch(now time.Time) int64 { return now.UnixNano() / int64(c.bucket) }
+
+// add counts a query from client at now and returns the client's count over the window.
+func (c *clients) add(client string, now time.Time) uint64 {
+	c.Lock()
+	defer c.Unlock()
+	cnt, ok := c.m[client]
+	if !ok {
+		cnt = &counter{}
+		c.m[client] = cnt
+	}
+	return cnt.add(c.epoch(now))
+}
+
+// expire removes the clients that haven't sent a query in the last window.
+func (c *clients) expire(now time.Time) {
+	e := c.epoch(now)
+	c.Lock()
+	defer c.Unlock()
+	for client, cnt := range c.m {
+		if cnt.last <= e-buckets {
+			delete(c.m, client)
+		}
+	}
+}
+
+// len returns the number of tracked clients.
+func (c *clients) len() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.m)
+}
@@ -0,0 +1,28 @@
+package ready
+
+import (
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+)
+
+// listener signals readiness once the DoQ server of a quic:// or squic:// server block accepts
+// connections. Only then the SCION (or UDP) socket is bound and the QUIC listener is up.
+type listener struct {
+	config *dnsserver.Config
+}
+
+// Ready implements the Readiness interface.
+func (l listener) Ready() bool {
+	ep, ok := dnsserver.EndpointOf(l.config)
+	return ok && ep.Err == nil
+}
+
+// listenerOf returns the readiness of the DoQ listener of the server block c and its name in the
+// list of plugins, ok is false for other transports.
+func listenerOf(c *dnsserver.Config) (r Readiness, name string, ok bool) {
+	switch c.Transport {
+	case transport.QUIC, transport.SQUIC:
+		return listener{config: c}, c.Transport, true
+	}
+	return nil, "", false
+}
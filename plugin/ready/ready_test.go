@@ -6,8 +6,10 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin/erratic"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/transport"
 	"github.com/coredns/coredns/plugin/test"
 
 	"github.com/miekg/dns"
@@ -67,3 +69,21 @@ func TestReady(t *testing.T) {
 	}
 	response.Body.Close()
 }
+
+func TestListenerOf(t *testing.T) {
+	if _, _, ok := listenerOf(&dnsserver.Config{Transport: transport.DNS}); ok {
+		t.Error("Expected no listener readiness for a dns server block")
+	}
+
+	r, name, ok := listenerOf(&dnsserver.Config{Zone: "example.org.", Transport: transport.SQUIC})
+	if !ok {
+		t.Fatal("Expected a listener readiness for a squic server block")
+	}
+	if name != transport.SQUIC {
+		t.Errorf("Expected name %q, got %q", transport.SQUIC, name)
+	}
+	// No squic server is running, so it can't be ready.
+	if r.Ready() {
+		t.Error("Expected the listener not to be ready")
+	}
+}
@@ -26,19 +26,11 @@ func setup(c *caddy.Controller) error {
 
 	c.OnStartup(func() error {
 		plugins.Reset()
-		for _, p := range dnsserver.GetConfig(c).Handlers() {
-			if r, ok := p.(Readiness); ok {
-				plugins.Append(r, p.Name())
-			}
-		}
+		appendReadiness(dnsserver.GetConfig(c))
 		return nil
 	})
 	c.OnRestartFailed(func() error {
-		for _, p := range dnsserver.GetConfig(c).Handlers() {
-			if r, ok := p.(Readiness); ok {
-				plugins.Append(r, p.Name())
-			}
-		}
+		appendReadiness(dnsserver.GetConfig(c))
 		return nil
 	})
 
@@ -48,6 +40,19 @@ func setup(c *caddy.Controller) error {
 	return nil
 }
 
+// appendReadiness adds the plugins of the server block config that signal readiness, and its DoQ
+// listener, to the list.
+func appendReadiness(config *dnsserver.Config) {
+	for _, p := range config.Handlers() {
+		if r, ok := p.(Readiness); ok {
+			plugins.Append(r, p.Name())
+		}
+	}
+	if r, name, ok := listenerOf(config); ok {
+		plugins.Append(r, name)
+	}
+}
+
 func parse(c *caddy.Controller) (string, error) {
 	addr := ":8181"
 	i := 0
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -122,6 +123,8 @@ const (
 	SubstringMatch = "substring"
 	// RegexMatch matches when the name in the question section of a request matches a regular expression
 	RegexMatch = "regex"
+	// SCIONMatch maps names between a legacy reverse tree and the reverse tree of a SCION AS
+	SCIONMatch = "scion"
 
 	// AnswerMatch matches an answer rewrite
 	AnswerMatch = "answer"
@@ -291,6 +294,66 @@ func (rule *regexNameRule) Rewrite(ctx context.Context, state request.Request) (
 	return rule.responseRuleFor(state)
 }
 
+// scionNameRule rewrites the current request between a legacy reverse tree, in-addr.arpa. or ip6.arpa.,
+// and the reverse tree of the hosts of a SCION AS under scion.arpa., e.g. 1.0.0.127.in-addr.arpa. to
+// 1.0.0.127.in-addr.19-ffaa-1-1067.scion.arpa.
+type scionNameRule struct {
+	nameRuleBase
+	legacy  string // in-addr.arpa. or ip6.arpa.
+	head    string // labels of the SCION reverse tree before the reverse suffix, e.g. in-addr.19-ffaa-1-1067.
+	toSCION bool
+}
+
+func newSCIONNameRule(nextAction string, auto bool, from, to string, answers ResponseRules) (Rule, error) {
+	rule := &scionNameRule{nameRuleBase: newNameRuleBase(nextAction, auto, "", answers)}
+	ia := to
+	switch {
+	case isLegacyReverse(from):
+		rule.legacy, rule.toSCION = plugin.Name(from).Normalize(), true
+	case isLegacyReverse(to):
+		rule.legacy, ia = plugin.Name(to).Normalize(), from
+	default:
+		return nil, fmt.Errorf("scion name rule needs in-addr.arpa or ip6.arpa and an ISD-AS, received: %s %s", from, to)
+	}
+	prefix := "0.0.0.0/0"
+	if rule.legacy == "ip6.arpa." {
+		prefix = "::/0"
+	}
+	zones, err := dnsutil.ReverseZoneFromPrefix(ia, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("scion name rule: invalid ISD-AS %q: %s", ia, err)
+	}
+	// The reverse suffix is looked up on every request, as it is set by the scion plugin.
+	rule.head = strings.TrimSuffix(zones[0], dnsutil.SCIONReverseSuffix())
+	return rule, nil
+}
+
+func (rule *scionNameRule) Rewrite(ctx context.Context, state request.Request) (ResponseRules, Result) {
+	from, to := rule.legacy, rule.head+dnsutil.SCIONReverseSuffix()
+	if !rule.toSCION {
+		from, to = to, from
+	}
+	if !dns.IsSubDomain(from, state.Name()) {
+		return nil, RewriteIgnored
+	}
+	state.Req.Question[0].Name = strings.TrimSuffix(state.Name(), from) + to
+	if !rule.auto {
+		return rule.static, RewriteDone
+	}
+	rewriter := newSuffixStringRewriter(to, from)
+	rules := ResponseRules{
+		&nameRewriterResponseRule{rewriter},
+		&valueRewriterResponseRule{rewriter},
+	}
+	return append(rules, rule.static...), RewriteDone
+}
+
+// isLegacyReverse returns true if s names the IPv4 or IPv6 reverse tree under arpa.
+func isLegacyReverse(s string) bool {
+	s = plugin.Name(s).Normalize()
+	return s == "in-addr.arpa." || s == "ip6.arpa."
+}
+
 // newNameRule creates a name matching rule based on exact, partial, or regex match
 func newNameRule(nextAction string, args ...string) (Rule, error) {
 	var matchType, rewriteQuestionFrom, rewriteQuestionTo string
@@ -350,8 +413,10 @@ func newNameRule(nextAction string, args ...string) (Rule, error) {
 		}
 		rewriteQuestionTo := plugin.Name(args[2]).Normalize()
 		return newRegexNameRule(nextAction, auto, rewriteQuestionFromPattern, rewriteQuestionTo, answers), nil
+	case SCIONMatch:
+		return newSCIONNameRule(nextAction, auto, args[1], args[2], answers)
 	default:
-		return nil, fmt.Errorf("name rule supports only exact, prefix, suffix, substring, and regex name matching, received: %s", matchType)
+		return nil, fmt.Errorf("name rule supports only exact, prefix, suffix, substring, regex, and scion name matching, received: %s", matchType)
 	}
 }
 
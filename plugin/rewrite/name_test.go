@@ -206,6 +206,55 @@ func TestRewriteNamePrefixSuffixAutoAnswer(t *testing.T) {
 	}
 }
 
+func TestRewriteNameSCION(t *testing.T) {
+	ctx, close := context.WithCancel(context.TODO())
+	defer close()
+
+	tests := []struct {
+		args     []string
+		question string
+		rewrite  string
+		expected string
+	}{
+		{[]string{"scion", "in-addr.arpa", "19-ffaa:1:1067", "answer", "auto"}, "1.0.0.127.in-addr.arpa.", "1.0.0.127.in-addr.19-ffaa-1-1067.scion.arpa.", "1.0.0.127.in-addr.arpa."},
+		{[]string{"scion", "in-addr.arpa.", "19-ffaa:1:1067"}, "1.0.0.127.in-addr.arpa.", "1.0.0.127.in-addr.19-ffaa-1-1067.scion.arpa.", "1.0.0.127.in-addr.19-ffaa-1-1067.scion.arpa."},
+		{[]string{"scion", "ip6.arpa", "19-ffaa:1:1067", "answer", "auto"}, "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.19-ffaa-1-1067.scion.arpa.", "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."},
+		{[]string{"scion", "19-ffaa:1:1067", "in-addr.arpa", "answer", "auto"}, "1.0.0.127.in-addr.19-ffaa-1-1067.scion.arpa.", "1.0.0.127.in-addr.arpa.", "1.0.0.127.in-addr.19-ffaa-1-1067.scion.arpa."},
+		{[]string{"scion", "in-addr.arpa", "19-ffaa:1:1067", "answer", "auto"}, "1.0.0.127.in-addr.19-ffaa-1-1067.scion.arpa.", "1.0.0.127.in-addr.19-ffaa-1-1067.scion.arpa.", "1.0.0.127.in-addr.19-ffaa-1-1067.scion.arpa."},
+		{[]string{"scion", "19-ffaa:1:1067", "in-addr.arpa", "answer", "auto"}, "1.0.0.127.in-addr.19-ffaa-1-1068.scion.arpa.", "1.0.0.127.in-addr.19-ffaa-1-1068.scion.arpa.", "1.0.0.127.in-addr.19-ffaa-1-1068.scion.arpa."},
+		{[]string{"scion", "in-addr.arpa", "19-ffaa:1:1067", "answer", "auto"}, "1.0.0.127.foo-in-addr.arpa.", "1.0.0.127.foo-in-addr.arpa.", "1.0.0.127.foo-in-addr.arpa."},
+	}
+	for i, tc := range tests {
+		r, err := newNameRule("stop", tc.args...)
+		if err != nil {
+			t.Fatalf("Test %d: Expected no error, got %s", i, err)
+		}
+
+		rw := Rewrite{
+			Next:         plugin.HandlerFunc(msgPrinter),
+			Rules:        []Rule{r},
+			RevertPolicy: NoRestorePolicy(),
+		}
+
+		m := new(dns.Msg)
+		m.SetQuestion(tc.question, dns.TypePTR)
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		_, err = rw.ServeDNS(ctx, rec, m)
+		if err != nil {
+			t.Fatalf("Test %d: Expected no error, got %s", i, err)
+		}
+		rewrite := rec.Msg.Question[0].Name
+		if rewrite != tc.rewrite {
+			t.Fatalf("Test %d: Expected question rewrite to %v, got %v", i, tc.rewrite, rewrite)
+		}
+		actual := rec.Msg.Answer[0].Header().Name
+		if actual != tc.expected {
+			t.Fatalf("Test %d: Expected answer rewrite to %v, got %v", i, tc.expected, actual)
+		}
+	}
+}
+
 func TestRewriteNameExactAnswer(t *testing.T) {
 	ctx, close := context.WithCancel(context.TODO())
 	defer close()
@@ -336,6 +385,12 @@ func TestNewNameRule(t *testing.T) {
 		{"stop", []string{"suffix", "staging.mydomain.com.", "coredns.rock.", "answer", "value", "(.*).coredns.rock", "{1}.staging.mydomain.com", "answer", "value", "(.*).coredns.rock", "{1}.staging.mydomain.com"}, false},
 		{"stop", []string{"suffix", "staging.mydomain.com.", "coredns.rock.", "answer", "value", "(.*).coredns.rock", "{1}.staging.mydomain.com", "name", "(.*).coredns.rock", "{1}.staging.mydomain.com"}, false},
 		{"stop", []string{"suffix", "staging.mydomain.com.", "coredns.rock.", "answer", "value", "(.*).coredns.rock", "{1}.staging.mydomain.com", "value", "(.*).coredns.rock"}, true},
+
+		{"stop", []string{"scion", "in-addr.arpa", "19-ffaa:1:1067"}, false},
+		{"stop", []string{"scion", "19-ffaa:1:1067", "ip6.arpa.", "answer", "auto"}, false},
+		{"stop", []string{"scion", "in-addr.arpa", "ip6.arpa"}, true},
+		{"stop", []string{"scion", "in-addr.arpa", "ffaa:1:1067"}, true},
+		{"stop", []string{"scion", "example.org", "19-ffaa:1:1067"}, true},
 	}
 	for i, tc := range tests {
 		failed := false
@@ -0,0 +1,111 @@
+// Package scion implements the scion plugin, which configures the SCION defaults of a server instance.
+package scion
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+
+	"github.com/miekg/dns"
+)
+
+func init() { plugin.Register("scion", setup) }
+
+func setup(c *caddy.Controller) error {
+	d, err := parse(c)
+	if err != nil {
+		return plugin.Error("scion", err)
+	}
+
+	// The defaults belong to the server instance, another server block may only define the same ones.
+	if cur, ok := dnsserver.SCIONDefaults(c); ok && cur != d {
+		return plugin.Error("scion", fmt.Errorf("conflicting scion blocks, the scion block can only be defined once"))
+	}
+	if err := dnsserver.SetSCIONDefaults(c, d); err != nil {
+		return plugin.Error("scion", err)
+	}
+	return nil
+}
+
+func parse(c *caddy.Controller) (pkgscion.Defaults, error) {
+	d := pkgscion.New()
+
+	for c.Next() {
+		if len(c.RemainingArgs()) > 0 {
+			return d, c.ArgErr()
+		}
+		for c.NextBlock() {
+			switch c.Val() {
+			case "daemon":
+				if !c.NextArg() {
+					return d, c.ArgErr()
+				}
+				if _, _, err := net.SplitHostPort(c.Val()); err != nil {
+					return d, c.Errf("invalid daemon address '%s': %s", c.Val(), err)
+				}
+				d.DaemonAddress = c.Val()
+			case "ia":
+				if !c.NextArg() {
+					return d, c.ArgErr()
+				}
+				ia, err := pkgscion.ParseIA(c.Val())
+				if err != nil {
+					return d, c.Err(err.Error())
+				}
+				d.LocalIA = ia
+			case "policy":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return d, c.ArgErr()
+				}
+				d.Policy = strings.Join(args, " ")
+				if _, err := d.PathPolicy(); err != nil {
+					return d, c.Err(err.Error())
+				}
+			case "doq_port":
+				if !c.NextArg() {
+					return d, c.ArgErr()
+				}
+				p, err := strconv.Atoi(c.Val())
+				if err != nil || p <= 0 || p > 65535 {
+					return d, c.Errf("invalid port '%s'", c.Val())
+				}
+				d.DoQPort = c.Val()
+			case "reply_selector":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return d, c.ArgErr()
+				}
+				if !pkgscion.HasReplySelector(args[0]) {
+					return d, c.Errf("unknown reply selector '%s'", args[0])
+				}
+				d.ReplySelector = args[0]
+				d.ReplySelectorArgs = strings.Join(args[1:], " ")
+				// Check the arguments.
+				s, err := d.NewReplySelector()
+				if err != nil {
+					return d, c.Err(err.Error())
+				}
+				s.Close()
+			case "reverse_suffix":
+				if !c.NextArg() {
+					return d, c.ArgErr()
+				}
+				name := strings.ToLower(dns.Fqdn(c.Val()))
+				if _, ok := dns.IsDomainName(name); !ok || name == "." {
+					return d, c.Errf("invalid reverse suffix '%s'", c.Val())
+				}
+				d.ReverseSuffix = name
+			default:
+				return d, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	return d, nil
+}
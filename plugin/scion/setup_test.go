@@ -0,0 +1,166 @@
+package scion
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/caddy/caddyfile"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input              string
+		shouldErr          bool
+		expectedErrContent string
+	}{
+		// positive
+		{`scion`, false, ""},
+		{`scion {
+			daemon 127.0.0.1:30255
+			ia 19-ffaa:1:1067
+			doq_port 853
+			reply_selector default
+		}`, false, ""},
+		{`scion {
+			policy 0*
+		}`, false, ""},
+		{`scion {
+			reply_selector latency
+		}`, false, ""},
+		{`scion {
+			reply_selector avoid 17 18
+		}`, false, ""},
+		{`scion {
+			reverse_suffix scion.test
+		}`, false, ""},
+		// negative
+		{`scion 19-ffaa:1:1067`, true, "Wrong argument count"},
+		{`scion {
+			daemon 127.0.0.1
+		}`, true, "invalid daemon address"},
+		{`scion {
+			ia 19
+		}`, true, "invalid ISD-AS"},
+		{`scion {
+			doq_port 70000
+		}`, true, "invalid port"},
+		{`scion {
+			reply_selector fastest
+		}`, true, "unknown reply selector"},
+		{`scion {
+			reply_selector avoid
+		}`, true, "needs at least one ISD"},
+		{`scion {
+			reply_selector pinned 17
+		}`, true, "takes no arguments"},
+		{`scion {
+			reverse_suffix
+		}`, true, "Wrong argument count"},
+		{`scion {
+			reverse_suffix .
+		}`, true, "invalid reverse suffix"},
+		{`scion {
+			giraffe
+		}`, true, "unknown property"},
+	}
+
+	for i, test := range tests {
+		pkgscion.Reset()
+		c := caddy.NewTestController("dns", test.input)
+		err := setup(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error but found none for input %s", i, test.input)
+		}
+
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			}
+
+			if !strings.Contains(err.Error(), test.expectedErrContent) {
+				t.Errorf("Test %d: Expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
+			}
+		}
+	}
+	pkgscion.Reset()
+}
+
+func TestSetupDefaults(t *testing.T) {
+	defer pkgscion.Reset()
+	pkgscion.Reset()
+
+	c := caddy.NewTestController("dns", `scion {
+		ia 19-ffaa:1:1067
+		doq_port 853
+	}`)
+	if err := setup(c); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	d, ok := dnsserver.SCIONDefaults(c)
+	if !ok {
+		t.Fatalf("Expected the instance to have SCION defaults")
+	}
+	if pkgscion.Get() != pkgscion.New() {
+		t.Errorf("Expected the process wide defaults to be left alone until the instance starts")
+	}
+	if d.LocalIA.String() != "19-ffaa:1:1067" {
+		t.Errorf("Expected local IA 19-ffaa:1:1067, got %s", d.LocalIA)
+	}
+	if d.Port() != 853 {
+		t.Errorf("Expected DoQ port 853, got %d", d.Port())
+	}
+	if d.ReverseSuffix != dnsutil.SCIONReverseSuffixDefault {
+		t.Errorf("Expected reverse suffix %s, got %s", dnsutil.SCIONReverseSuffixDefault, d.ReverseSuffix)
+	}
+
+	// The same block in another server block is fine.
+	nextBlock(c, `scion {
+		ia 19-ffaa:1:1067
+		doq_port 853
+	}`)
+	if err := setup(c); err != nil {
+		t.Errorf("Expected no error for an identical block, got %s", err)
+	}
+	// A second, different, block must be rejected, even if it has the built-in defaults.
+	for _, input := range []string{"scion {\n doq_port 8853\n}", "scion"} {
+		nextBlock(c, input)
+		if err := setup(c); err == nil {
+			t.Errorf("Expected error for conflicting scion blocks, got none for %q", input)
+		}
+	}
+}
+
+func TestSetupReverseSuffix(t *testing.T) {
+	defer pkgscion.Reset()
+	pkgscion.Reset()
+
+	c := caddy.NewTestController("dns", `scion {
+		reverse_suffix SCION.test
+	}`)
+	if err := setup(c); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if d, _ := dnsserver.SCIONDefaults(c); d.ReverseSuffix != "scion.test." {
+		t.Errorf("Expected reverse suffix scion.test., got %s", d.ReverseSuffix)
+	}
+	if s := dnsutil.SCIONReverseSuffix(); s != dnsutil.SCIONReverseSuffixDefault {
+		t.Errorf("Expected reverse suffix %s until the instance starts, got %s", dnsutil.SCIONReverseSuffixDefault, s)
+	}
+
+	// The next server instance starts from the defaults again.
+	c = caddy.NewTestController("dns", `scion`)
+	if d, ok := dnsserver.SCIONDefaults(c); ok || d.ReverseSuffix != dnsutil.SCIONReverseSuffixDefault {
+		t.Errorf("Expected reverse suffix %s for a new instance, got %s", dnsutil.SCIONReverseSuffixDefault, d.ReverseSuffix)
+	}
+}
+
+// nextBlock makes c set up the next server block of the same instance, with input as its contents.
+func nextBlock(c *caddy.Controller, input string) {
+	c.Dispenser = caddyfile.NewDispenser("Testfile", strings.NewReader(input))
+	c.ServerBlockIndex++
+}
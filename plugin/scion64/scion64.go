@@ -0,0 +1,223 @@
+// Package scion64 implements a plugin that synthesizes SCION address answers from IP answers, in the
+// way dns64 synthesizes AAAA answers from A answers.
+package scion64
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/nonwriter"
+	"github.com/coredns/coredns/plugin/pkg/response"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// UpstreamInt wraps the Upstream API for dependency injection during testing
+type UpstreamInt interface {
+	Lookup(ctx context.Context, state request.Request, name string, typ uint16) (*dns.Msg, error)
+}
+
+// Mapping maps the hosts in an IP prefix to the SCION AS they are reachable in.
+type Mapping struct {
+	Prefix *net.IPNet
+	IA     pkgscion.IA
+}
+
+// SCION64 synthesizes SCION address answers.
+type SCION64 struct {
+	Next plugin.Handler
+	// Mappings are sorted by prefix length, longest first, so the first match is the most specific.
+	Mappings     []Mapping
+	TranslateAll bool
+	// TXT also synthesizes the answers to TXT questions, with scion= TXT records.
+	TXT      bool
+	Upstream UpstreamInt
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (s *SCION64) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	if !s.requestShouldIntercept(&state) {
+		return s.Next.ServeDNS(ctx, w, r)
+	}
+
+	nw := nonwriter.New(w)
+	origRc, origErr := s.Next.ServeDNS(ctx, nw, r)
+	if nw.Msg == nil {
+		return origRc, origErr
+	}
+
+	if !s.responseShouldSynthesize(state.QType(), nw.Msg) {
+		w.WriteMsg(nw.Msg)
+		return origRc, origErr
+	}
+
+	msg, err := s.DoSCION64(ctx, state, nw.Msg)
+	if err != nil {
+		return dns.RcodeServerFailure, err
+	}
+	if msg == nil {
+		// None of the addresses is in a mapped prefix.
+		w.WriteMsg(nw.Msg)
+		return origRc, origErr
+	}
+
+	RequestsTranslatedCount.WithLabelValues(metrics.WithServer(ctx)).Inc()
+	w.WriteMsg(msg)
+	return msg.Rcode, nil
+}
+
+// Name implements the Handler interface.
+func (s *SCION64) Name() string { return "scion64" }
+
+// requestShouldIntercept returns true for the questions SCION addresses are looked up with, of class
+// INET: SCION records, and TXT records if s.TXT is set.
+func (s *SCION64) requestShouldIntercept(req *request.Request) bool {
+	qtype := req.QType()
+	return (qtype == pkgscion.TypeSCION || qtype == dns.TypeTXT && s.TXT) && req.QClass() == dns.ClassINET
+}
+
+// responseShouldSynthesize returns true if the response to a question of type qtype carries no SCION
+// address and the name exists.
+func (s *SCION64) responseShouldSynthesize(qtype uint16, origResponse *dns.Msg) bool {
+	ty, _ := response.Typify(origResponse, time.Now().UTC())
+	if ty == response.NameError {
+		return false
+	}
+	if s.TranslateAll {
+		return true
+	}
+	for _, rr := range origResponse.Answer {
+		if hasSCIONAddress(qtype, rr) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasSCIONAddress returns true if rr is a SCION address in the answer to a question of type qtype.
+func hasSCIONAddress(qtype uint16, rr dns.RR) bool {
+	if qtype == pkgscion.TypeSCION {
+		_, ok := pkgscion.SCIONOf(rr)
+		return ok
+	}
+	t, ok := rr.(*dns.TXT)
+	if !ok {
+		return false
+	}
+	for _, txt := range t.Txt {
+		if strings.HasPrefix(txt, pkgscion.TXTPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// DoSCION64 looks up the A and AAAA records of the name in state and synthesizes the response from the
+// ones in mapped prefixes. It returns a nil message if there are none, and an error if the lookups fail.
+func (s *SCION64) DoSCION64(ctx context.Context, state request.Request, origResponse *dns.Msg) (*dns.Msg, error) {
+	var resps []*dns.Msg
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		resp, err := s.Upstream.Lookup(ctx, state, state.Name(), qtype)
+		if err != nil {
+			return nil, err
+		}
+		resps = append(resps, resp)
+	}
+	return s.Synthesize(state.QType(), state.Req, origResponse, resps...), nil
+}
+
+// Synthesize merges the response to the question of type qtype with the SCION addresses of the records
+// in the A and AAAA responses. It returns nil if none of them is in a mapped prefix.
+func (s *SCION64) Synthesize(qtype uint16, origReq, origResponse *dns.Msg, resps ...*dns.Msg) *dns.Msg {
+	// The TTL is the minimum of the address record and the SOA record, as in dns64.
+	SOATtl := uint32(600)
+	for _, ns := range origResponse.Ns {
+		if ns.Header().Rrtype == dns.TypeSOA {
+			SOATtl = ns.Header().Ttl
+		}
+	}
+
+	var (
+		synth []dns.RR
+		// addrResp is the response the first synthesized record is from, its authority and additional
+		// sections go with the answer, as in dns64.
+		addrResp *dns.Msg
+	)
+	for _, resp := range resps {
+		for _, rr := range resp.Answer {
+			var ip net.IP
+			switch a := rr.(type) {
+			case *dns.A:
+				ip = a.A
+			case *dns.AAAA:
+				ip = a.AAAA
+			default:
+				continue
+			}
+			ia, ok := s.lookup(ip)
+			if !ok {
+				continue
+			}
+			if addrResp == nil {
+				addrResp = resp
+			}
+			ttl := SOATtl
+			if rr.Header().Ttl < ttl {
+				ttl = rr.Header().Ttl
+			}
+			scion := pkgscion.NewSCION(rr.Header().Name, ttl, ia, ip)
+			if qtype == pkgscion.TypeSCION {
+				synth = append(synth, scion)
+				continue
+			}
+			synth = append(synth, &dns.TXT{
+				Hdr: dns.RR_Header{Name: rr.Header().Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: ttl},
+				Txt: []string{scion.Data.(*pkgscion.SCION).TXT()},
+			})
+		}
+	}
+	if len(synth) == 0 {
+		return nil
+	}
+
+	ret := new(dns.Msg)
+	ret.SetReply(origReq)
+	ret.Truncated = origResponse.Truncated
+	ret.Ns = addrResp.Ns
+	ret.Extra = addrResp.Extra
+	if qtype == dns.TypeTXT {
+		// Other TXT records of the name are kept next to the synthesized ones, with translate_all the
+		// SCION addresses are replaced.
+		for _, rr := range origResponse.Answer {
+			if !hasSCIONAddress(qtype, rr) {
+				ret.Answer = append(ret.Answer, rr)
+			}
+		}
+	} else {
+		// Keep the CNAMEs that lead to the addresses.
+		for _, rr := range resps[0].Answer {
+			if rr.Header().Rrtype == dns.TypeCNAME {
+				ret.Answer = append(ret.Answer, rr)
+			}
+		}
+	}
+	ret.Answer = append(ret.Answer, synth...)
+	return ret
+}
+
+// lookup returns the ISD-AS of the most specific prefix ip is in.
+func (s *SCION64) lookup(ip net.IP) (pkgscion.IA, bool) {
+	for _, m := range s.Mappings {
+		if m.Prefix.Contains(ip) {
+			return m.IA, true
+		}
+	}
+	return pkgscion.IA{}, false
+}
@@ -0,0 +1,205 @@
+package scion64
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func testSCION64(next *dns.Msg, up map[uint16]*dns.Msg, translateAll, txt bool) *SCION64 {
+	_, n4, _ := net.ParseCIDR("192.0.2.0/24")
+	_, n6, _ := net.ParseCIDR("2001:db8::/32")
+	_, n41, _ := net.ParseCIDR("192.0.2.128/25")
+	ia1, _ := pkgscion.ParseIA("19-ffaa:1:1067")
+	ia2, _ := pkgscion.ParseIA("19-ffaa:1:1068")
+	return &SCION64{
+		Next: &fakeHandler{reply: next},
+		Mappings: []Mapping{
+			{Prefix: n41, IA: ia2},
+			{Prefix: n4, IA: ia1},
+			{Prefix: n6, IA: ia1},
+		},
+		TranslateAll: translateAll,
+		TXT:          txt,
+		Upstream:     &fakeUpstream{resps: up},
+	}
+}
+
+func reply(qtype uint16, rcode int, answer ...dns.RR) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", qtype)
+	m.Response = true
+	m.Rcode = rcode
+	m.Answer = answer
+	m.Ns = []dns.RR{test.SOA("example.com. 70 IN SOA foo bar 1 1 1 1 1")}
+	return m
+}
+
+func TestSCION64(t *testing.T) {
+	addrs := map[uint16]*dns.Msg{
+		dns.TypeA: reply(dns.TypeA, dns.RcodeSuccess,
+			test.A("example.com. 60 IN A 192.0.2.42"),
+			test.A("example.com. 5000 IN A 192.0.2.200"),
+			test.A("example.com. 60 IN A 198.51.100.1"),
+		),
+		dns.TypeAAAA: reply(dns.TypeAAAA, dns.RcodeSuccess, test.AAAA("example.com. 60 IN AAAA 2001:db8::1")),
+	}
+	unmapped := map[uint16]*dns.Msg{
+		dns.TypeA:    reply(dns.TypeA, dns.RcodeSuccess, test.A("example.com. 60 IN A 198.51.100.1")),
+		dns.TypeAAAA: reply(dns.TypeAAAA, dns.RcodeSuccess),
+	}
+
+	tests := []struct {
+		name         string
+		qtype        uint16
+		next         *dns.Msg
+		up           map[uint16]*dns.Msg
+		translateAll bool
+		txt          bool
+		expected     []string
+	}{
+		{
+			name:  "SCION records",
+			qtype: pkgscion.TypeSCION,
+			next:  reply(pkgscion.TypeSCION, dns.RcodeSuccess),
+			up:    addrs,
+			expected: []string{
+				"example.com.\t60\tIN\tSCION\t19-ffaa:1:1067,[192.0.2.42]",
+				"example.com.\t70\tIN\tSCION\t19-ffaa:1:1068,[192.0.2.200]",
+				"example.com.\t60\tIN\tSCION\t19-ffaa:1:1067,[2001:db8::1]",
+			},
+		},
+		{
+			name:  "TXT records",
+			qtype: dns.TypeTXT,
+			next:  reply(dns.TypeTXT, dns.RcodeSuccess, test.TXT(`example.com. 60 IN TXT "v=spf1 -all"`)),
+			up:    addrs,
+			txt:   true,
+			expected: []string{
+				"example.com.\t60\tIN\tTXT\t\"v=spf1 -all\"",
+				"example.com.\t60\tIN\tTXT\t\"scion=19-ffaa:1:1067,[192.0.2.42]\"",
+				"example.com.\t70\tIN\tTXT\t\"scion=19-ffaa:1:1068,[192.0.2.200]\"",
+				"example.com.\t60\tIN\tTXT\t\"scion=19-ffaa:1:1067,[2001:db8::1]\"",
+			},
+		},
+		{
+			name:     "SCION address present",
+			qtype:    dns.TypeTXT,
+			next:     reply(dns.TypeTXT, dns.RcodeSuccess, test.TXT(`example.com. 60 IN TXT "scion=19-ffaa:1:1069,[10.0.0.1]"`)),
+			txt:      true,
+			expected: []string{"example.com.\t60\tIN\tTXT\t\"scion=19-ffaa:1:1069,[10.0.0.1]\""},
+		},
+		{
+			name:         "SCION address present, translate all",
+			qtype:        dns.TypeTXT,
+			next:         reply(dns.TypeTXT, dns.RcodeSuccess, test.TXT(`example.com. 60 IN TXT "scion=19-ffaa:1:1069,[10.0.0.1]"`)),
+			up:           addrs,
+			translateAll: true,
+			txt:          true,
+			expected: []string{
+				"example.com.\t60\tIN\tTXT\t\"scion=19-ffaa:1:1067,[192.0.2.42]\"",
+				"example.com.\t70\tIN\tTXT\t\"scion=19-ffaa:1:1068,[192.0.2.200]\"",
+				"example.com.\t60\tIN\tTXT\t\"scion=19-ffaa:1:1067,[2001:db8::1]\"",
+			},
+		},
+		{
+			name:     "TXT records without txt",
+			qtype:    dns.TypeTXT,
+			next:     reply(dns.TypeTXT, dns.RcodeSuccess, test.TXT(`example.com. 60 IN TXT "v=spf1 -all"`)),
+			expected: []string{"example.com.\t60\tIN\tTXT\t\"v=spf1 -all\""},
+		},
+		{
+			name:     "name error",
+			qtype:    pkgscion.TypeSCION,
+			next:     reply(pkgscion.TypeSCION, dns.RcodeNameError),
+			expected: []string{},
+		},
+		{
+			name:     "no mapped address",
+			qtype:    pkgscion.TypeSCION,
+			next:     reply(pkgscion.TypeSCION, dns.RcodeSuccess),
+			up:       unmapped,
+			expected: []string{},
+		},
+		{
+			name:     "other type",
+			qtype:    dns.TypeA,
+			next:     reply(dns.TypeA, dns.RcodeSuccess, test.A("example.com. 60 IN A 192.0.2.1")),
+			expected: []string{"example.com.\t60\tIN\tA\t192.0.2.1"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := testSCION64(tc.next, tc.up, tc.translateAll, tc.txt)
+			s.Upstream.(*fakeUpstream).t = t
+
+			m := new(dns.Msg)
+			m.SetQuestion("example.com.", tc.qtype)
+			rec := dnstest.NewRecorder(&test.ResponseWriter{})
+			if _, err := s.ServeDNS(context.TODO(), rec, m); err != nil {
+				t.Fatalf("Expected no error, got %s", err)
+			}
+			if len(rec.Msg.Answer) != len(tc.expected) {
+				t.Fatalf("Expected %d answers, got %v", len(tc.expected), rec.Msg.Answer)
+			}
+			for i, rr := range rec.Msg.Answer {
+				if rr.String() != tc.expected[i] {
+					t.Errorf("Expected answer %d to be %q, got %q", i, tc.expected[i], rr.String())
+				}
+			}
+		})
+	}
+}
+
+func TestSCION64Authority(t *testing.T) {
+	// The SOA of the NODATA response to the SCION question must not end up in the positive answer.
+	a := reply(dns.TypeA, dns.RcodeSuccess, test.A("example.com. 60 IN A 192.0.2.42"))
+	a.Ns = nil
+	up := map[uint16]*dns.Msg{dns.TypeA: a, dns.TypeAAAA: reply(dns.TypeAAAA, dns.RcodeSuccess)}
+	s := testSCION64(reply(pkgscion.TypeSCION, dns.RcodeSuccess), up, false, false)
+	s.Upstream.(*fakeUpstream).t = t
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", pkgscion.TypeSCION)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := s.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if len(rec.Msg.Answer) != 1 {
+		t.Fatalf("Expected 1 answer, got %v", rec.Msg.Answer)
+	}
+	if len(rec.Msg.Ns) != 0 {
+		t.Errorf("Expected no authority section, got %v", rec.Msg.Ns)
+	}
+}
+
+type fakeHandler struct {
+	reply *dns.Msg
+}
+
+func (fh *fakeHandler) ServeDNS(_ context.Context, w dns.ResponseWriter, _ *dns.Msg) (int, error) {
+	w.WriteMsg(fh.reply)
+	return fh.reply.Rcode, nil
+}
+
+func (fh *fakeHandler) Name() string { return "fake" }
+
+type fakeUpstream struct {
+	t     *testing.T
+	resps map[uint16]*dns.Msg
+}
+
+func (fu *fakeUpstream) Lookup(_ context.Context, _ request.Request, name string, typ uint16) (*dns.Msg, error) {
+	resp, ok := fu.resps[typ]
+	if !ok {
+		fu.t.Fatalf("Unexpected lookup of type %d for %s", typ, name)
+	}
+	return resp, nil
+}
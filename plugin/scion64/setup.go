@@ -0,0 +1,97 @@
+package scion64
+
+import (
+	"net"
+	"sort"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+)
+
+const pluginName = "scion64"
+
+func init() { plugin.Register(pluginName, setup) }
+
+func setup(c *caddy.Controller) error {
+	s, err := scion64Parse(c)
+	if err != nil {
+		return plugin.Error(pluginName, err)
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		s.Next = next
+		return s
+	})
+
+	return nil
+}
+
+func scion64Parse(c *caddy.Controller) (*SCION64, error) {
+	s := &SCION64{Upstream: upstream.New()}
+
+	for c.Next() {
+		args := c.RemainingArgs()
+		if len(args) == 2 {
+			m, err := parseMapping(c, args[0], args[1])
+			if err != nil {
+				return nil, err
+			}
+			s.Mappings = append(s.Mappings, m)
+		} else if len(args) > 0 {
+			return nil, c.ArgErr()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "map":
+				args := c.RemainingArgs()
+				if len(args) != 2 {
+					return nil, c.ArgErr()
+				}
+				m, err := parseMapping(c, args[0], args[1])
+				if err != nil {
+					return nil, err
+				}
+				s.Mappings = append(s.Mappings, m)
+			case "translate_all":
+				s.TranslateAll = true
+			case "txt":
+				s.TXT = true
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	if len(s.Mappings) == 0 {
+		return nil, c.Err("no prefix mapped to an ISD-AS")
+	}
+
+	seen := make(map[string]bool)
+	for _, m := range s.Mappings {
+		if seen[m.Prefix.String()] {
+			return nil, c.Errf("prefix %s mapped more than once", m.Prefix)
+		}
+		seen[m.Prefix.String()] = true
+	}
+	sort.SliceStable(s.Mappings, func(i, j int) bool {
+		ni, _ := s.Mappings[i].Prefix.Mask.Size()
+		nj, _ := s.Mappings[j].Prefix.Mask.Size()
+		return ni > nj
+	})
+	return s, nil
+}
+
+func parseMapping(c *caddy.Controller, prefix, isdas string) (Mapping, error) {
+	_, n, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return Mapping{}, c.Errf("invalid prefix %q: %s", prefix, err)
+	}
+	ia, err := pkgscion.ParseIA(isdas)
+	if err != nil {
+		return Mapping{}, c.Err(err.Error())
+	}
+	return Mapping{Prefix: n, IA: ia}, nil
+}
@@ -0,0 +1,70 @@
+package scion64
+
+import (
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetupSCION64(t *testing.T) {
+	tests := []struct {
+		input            string
+		shouldErr        bool
+		wantPrefixes     []string
+		wantTranslateAll bool
+		wantTXT          bool
+	}{
+		{`scion64 192.0.2.0/24 19-ffaa:1:1067`, false, []string{"192.0.2.0/24"}, false, false},
+		{`scion64 {
+			map 192.0.2.0/24 19-ffaa:1:1067
+			map 192.0.2.128/25 19-ffaa:1:1068
+			map 2001:db8::/32 1-64512
+			translate_all
+			txt
+		}`, false, []string{"2001:db8::/32", "192.0.2.128/25", "192.0.2.0/24"}, true, true},
+		{`scion64 192.0.2.0/24 19-ffaa:1:1067 {
+			map 198.51.100.0/24 19-ffaa:1:1068
+		}`, false, []string{"192.0.2.0/24", "198.51.100.0/24"}, false, false},
+		{`scion64`, true, nil, false, false},
+		{`scion64 192.0.2.0/24`, true, nil, false, false},
+		{`scion64 192.0.2.0 19-ffaa:1:1067`, true, nil, false, false},
+		{`scion64 192.0.2.0/24 ffaa:1:1067`, true, nil, false, false},
+		{`scion64 {
+			map 192.0.2.0/24 19-ffaa:1:1067
+			map 192.0.2.0/24 19-ffaa:1:1068
+		}`, true, nil, false, false},
+		{`scion64 {
+			map 192.0.2.0/24
+		}`, true, nil, false, false},
+		{`scion64 {
+			prefix 64:ff9b::/96
+		}`, true, nil, false, false},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		s, err := scion64Parse(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if len(s.Mappings) != len(test.wantPrefixes) {
+			t.Fatalf("Test %d: expected %d mappings, got %d", i, len(test.wantPrefixes), len(s.Mappings))
+		}
+		for j, m := range s.Mappings {
+			if m.Prefix.String() != test.wantPrefixes[j] {
+				t.Errorf("Test %d: expected mapping %d for %s, got %s", i, j, test.wantPrefixes[j], m.Prefix)
+			}
+		}
+		if s.TranslateAll != test.wantTranslateAll {
+			t.Errorf("Test %d: expected translate_all %t, got %t", i, test.wantTranslateAll, s.TranslateAll)
+		}
+		if s.TXT != test.wantTXT {
+			t.Errorf("Test %d: expected txt %t, got %t", i, test.wantTXT, s.TXT)
+		}
+	}
+}
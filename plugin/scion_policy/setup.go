@@ -0,0 +1,108 @@
+// Package scionpolicy implements the scion_policy plugin, which restricts the paths used by outbound SCION
+// connections.
+package scionpolicy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+)
+
+func init() { plugin.Register("scion_policy", setup) }
+
+func setup(c *caddy.Controller) error {
+	p, err := parse(c)
+	if err != nil {
+		return plugin.Error("scion_policy", err)
+	}
+
+	// The scion plugin runs first in every server block, so a sequence is already set if it was
+	// given there or in an earlier scion_policy block.
+	d, _ := dnsserver.SCIONDefaults(c)
+	if p.Policy == "" {
+		p.Policy = d.Policy
+	}
+	if d.Policy != "" && d.Policy != p.Policy {
+		return plugin.Error("scion_policy", fmt.Errorf("conflicting path policy sequences %q and %q", d.Policy, p.Policy))
+	}
+	if (d.AllowISDs != "" || d.DenyISDs != "") && (d.AllowISDs != p.AllowISDs || d.DenyISDs != p.DenyISDs) {
+		return plugin.Error("scion_policy", fmt.Errorf("conflicting scion_policy blocks, all of them must allow and deny the same ISDs"))
+	}
+
+	d.Policy, d.AllowISDs, d.DenyISDs = p.Policy, p.AllowISDs, p.DenyISDs
+	if err := dnsserver.SetSCIONDefaults(c, d); err != nil {
+		return plugin.Error("scion_policy", err)
+	}
+	return nil
+}
+
+// parse returns the policy in a scion_policy block, only the Policy, AllowISDs and DenyISDs fields are set.
+func parse(c *caddy.Controller) (pkgscion.Defaults, error) {
+	var p pkgscion.Defaults
+
+	for c.Next() {
+		if len(c.RemainingArgs()) > 0 {
+			return p, c.ArgErr()
+		}
+		for c.NextBlock() {
+			switch c.Val() {
+			case "sequence":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return p, c.ArgErr()
+				}
+				p.Policy = strings.Join(args, " ")
+			case "allow":
+				isds, err := parseISDs(c)
+				if err != nil {
+					return p, err
+				}
+				p.AllowISDs = isds
+			case "deny":
+				isds, err := parseISDs(c)
+				if err != nil {
+					return p, err
+				}
+				p.DenyISDs = isds
+			default:
+				return p, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	if p.Policy == "" && p.AllowISDs == "" && p.DenyISDs == "" {
+		return p, c.Err("scion_policy needs a sequence, allow or deny")
+	}
+	if _, err := p.PathPolicy(); err != nil {
+		return p, c.Err(err.Error())
+	}
+	return p, nil
+}
+
+// parseISDs parses the remaining arguments as ISDs and returns them sorted, so blocks listing the same ISDs
+// compare equal.
+func parseISDs(c *caddy.Controller) (string, error) {
+	args := c.RemainingArgs()
+	if len(args) == 0 {
+		return "", c.ArgErr()
+	}
+	isds, err := pkgscion.ParseISDs(strings.Join(args, " "))
+	if err != nil {
+		return "", c.Err(err.Error())
+	}
+	sorted := make([]int, 0, len(isds))
+	for isd := range isds {
+		sorted = append(sorted, int(isd))
+	}
+	sort.Ints(sorted)
+	s := make([]string, len(sorted))
+	for i, isd := range sorted {
+		s[i] = strconv.Itoa(isd)
+	}
+	return strings.Join(s, " "), nil
+}
@@ -0,0 +1,108 @@
+package scionpolicy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/caddy/caddyfile"
+	"github.com/coredns/coredns/core/dnsserver"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input              string
+		shouldErr          bool
+		expectedErrContent string
+		expected           pkgscion.Defaults
+	}{
+		{`scion_policy {
+			sequence 19-ffaa:1:1067 0*
+		}`, false, "", pkgscion.Defaults{Policy: "19-ffaa:1:1067 0*"}},
+		{`scion_policy {
+			allow 19 17
+			deny 18
+		}`, false, "", pkgscion.Defaults{AllowISDs: "17 19", DenyISDs: "18"}},
+		{`scion_policy`, true, "needs a sequence, allow or deny", pkgscion.Defaults{}},
+		{`scion_policy 19`, true, "Wrong argument count", pkgscion.Defaults{}},
+		{`scion_policy {
+			sequence
+		}`, true, "Wrong argument count", pkgscion.Defaults{}},
+		{`scion_policy {
+			deny 0
+		}`, true, "invalid ISD", pkgscion.Defaults{}},
+		{`scion_policy {
+			allow ch
+		}`, true, "invalid ISD", pkgscion.Defaults{}},
+		{`scion_policy {
+			giraffe
+		}`, true, "unknown property", pkgscion.Defaults{}},
+	}
+
+	for i, test := range tests {
+		pkgscion.Reset()
+		c := caddy.NewTestController("dns", test.input)
+		err := setup(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error but found none for input %s", i, test.input)
+		}
+
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			}
+
+			if !strings.Contains(err.Error(), test.expectedErrContent) {
+				t.Errorf("Test %d: Expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
+			}
+			continue
+		}
+
+		d, _ := dnsserver.SCIONDefaults(c)
+		if d.Policy != test.expected.Policy || d.AllowISDs != test.expected.AllowISDs || d.DenyISDs != test.expected.DenyISDs {
+			t.Errorf("Test %d: Expected policy %q, allow %q and deny %q, got %q, %q and %q", i,
+				test.expected.Policy, test.expected.AllowISDs, test.expected.DenyISDs, d.Policy, d.AllowISDs, d.DenyISDs)
+		}
+	}
+	pkgscion.Reset()
+}
+
+func TestSetupConflict(t *testing.T) {
+	defer pkgscion.Reset()
+
+	c := caddy.NewTestController("dns", "scion_policy {\n deny 17\n}")
+	d := pkgscion.New()
+	d.Policy = "0*"
+	if err := dnsserver.SetSCIONDefaults(c, d); err != nil {
+		t.Fatal(err)
+	}
+
+	// A block with only ISD lists keeps the sequence from the scion plugin.
+	if err := setup(c); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if d, _ := dnsserver.SCIONDefaults(c); d.Policy != "0*" || d.DenyISDs != "17" {
+		t.Errorf("Expected sequence 0* and denied ISD 17, got %q and %q", d.Policy, d.DenyISDs)
+	}
+	// The same block again, e.g. in another server block, is fine.
+	nextBlock(c, "scion_policy {\n deny 17\n}")
+	if err := setup(c); err != nil {
+		t.Errorf("Expected no error for an identical block, got %v", err)
+	}
+	nextBlock(c, "scion_policy {\n deny 18\n}")
+	if err := setup(c); err == nil {
+		t.Errorf("Expected an error for conflicting ISD lists")
+	}
+	nextBlock(c, "scion_policy {\n sequence 1-ff00:0:110 0*\n deny 17\n}")
+	if err := setup(c); err == nil {
+		t.Errorf("Expected an error for conflicting sequences")
+	}
+}
+
+// nextBlock makes c set up the next server block of the same instance, with input as its contents.
+func nextBlock(c *caddy.Controller, input string) {
+	c.Dispenser = caddyfile.NewDispenser("Testfile", strings.NewReader(input))
+	c.ServerBlockIndex++
+}
@@ -1,6 +1,10 @@
 package secondary
 
 import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/coredns/caddy"
@@ -9,7 +13,12 @@ import (
 	"github.com/coredns/coredns/plugin/file"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/parse"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	pkgtls "github.com/coredns/coredns/plugin/pkg/tls"
+	"github.com/coredns/coredns/plugin/pkg/transport"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
+
+	"github.com/miekg/dns"
 )
 
 var log = clog.NewWithPlugin("secondary")
@@ -27,6 +36,13 @@ func setup(c *caddy.Controller) error {
 	*/
 	config := dnsserver.GetConfig(c)
 
+	// The transfers and refreshes of the zones stop when the server shuts down, e.g. on reload.
+	ctx, cancel := context.WithCancel(context.Background())
+	c.OnShutdown(func() error {
+		cancel()
+		return nil
+	})
+
 	// Add startup functions to retrieve the zone and keep it up to date.
 	for i := range zones.Names {
 		n := zones.Names[i]
@@ -50,13 +66,17 @@ func setup(c *caddy.Controller) error {
 								break
 							}
 							log.Warningf("All '%s' masters failed to transfer, retrying in %s: %s", n, dur.String(), err)
-							time.Sleep(dur)
+							select {
+							case <-time.After(dur):
+							case <-ctx.Done():
+								return
+							}
 							dur = step * dur
 							if dur > max {
 								dur = max
 							}
 						}
-						z.Update()
+						z.Update(ctx)
 					}()
 				})
 				return nil
@@ -93,6 +113,98 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 					if err != nil {
 						return file.Zones{}, err
 					}
+				case "expired":
+					policy, ttl, err := parseExpired(c)
+					if err != nil {
+						return file.Zones{}, err
+					}
+					for _, origin := range origins {
+						z[origin].ExpiredPolicy = policy
+						z[origin].StaleTTL = ttl
+					}
+					continue
+				case "window":
+					primary, windows, err := parseWindow(c)
+					if err != nil {
+						return file.Zones{}, err
+					}
+					for _, origin := range origins {
+						if z[origin].TransferWindows == nil {
+							z[origin].TransferWindows = make(map[string][]file.TransferWindow)
+						}
+						z[origin].TransferWindows[primary] = append(z[origin].TransferWindows[primary], windows...)
+					}
+					continue
+				case "bandwidth":
+					if !c.NextArg() {
+						return file.Zones{}, c.ArgErr()
+					}
+					rate, err := parseRate(c.Val())
+					if err != nil {
+						return file.Zones{}, c.Errf("invalid bandwidth '%s': %s", c.Val(), err)
+					}
+					if c.NextArg() {
+						return file.Zones{}, c.ArgErr()
+					}
+					for _, origin := range origins {
+						z[origin].TransferRate = rate
+					}
+					continue
+				case "tsig":
+					key, err := parseTsig(c)
+					if err != nil {
+						return file.Zones{}, err
+					}
+					for _, origin := range origins {
+						z[origin].TransferKey = key
+					}
+					continue
+				case "tls":
+					args := c.RemainingArgs()
+					if len(args) != 2 && len(args) != 3 {
+						return file.Zones{}, c.ArgErr()
+					}
+					cfg, err := pkgtls.NewTLSConfigFromArgs(args...)
+					if err != nil {
+						return file.Zones{}, c.Err(err.Error())
+					}
+					for _, origin := range origins {
+						z[origin].TransferTLS = cfg
+					}
+					continue
+				case "tls_trc":
+					args := c.RemainingArgs()
+					if len(args) != 1 && len(args) != 2 {
+						return file.Zones{}, c.ArgErr()
+					}
+					trcs, err := pkgscion.LoadTRCs(args[0])
+					if err != nil {
+						return file.Zones{}, c.Errf("tls_trc: %s", err)
+					}
+					var ia pkgscion.IA
+					if len(args) == 2 {
+						if ia, err = pkgscion.ParseIA(args[1]); err != nil {
+							return file.Zones{}, c.Errf("tls_trc: %s", err)
+						}
+					}
+					for _, origin := range origins {
+						z[origin].TransferTRCs = trcs
+						z[origin].TransferTRCIA = ia
+					}
+					continue
+				case "refresh", "retry", "jitter":
+					// The zones of the block share the policy, each property sets a part of it.
+					policy := file.DefaultRefreshPolicy
+					if len(origins) > 0 {
+						policy = z[origins[0]].RefreshPolicy
+					}
+					if err := parseRefresh(c, &policy); err != nil {
+						return file.Zones{}, err
+					}
+					for _, origin := range origins {
+						z[origin].RefreshPolicy = policy
+					}
+					continue
 				default:
 					return file.Zones{}, c.Errf("unknown property '%s'", c.Val())
 				}
@@ -104,7 +216,187 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 					z[origin].Upstream = upstream.New()
 				}
 			}
+
+			for _, origin := range origins {
+				if z[origin].TransferTRCs != nil && z[origin].TransferTRCIA.IsZero() {
+					// AS certificates carry no name, without an ISD-AS any AS could pose as the primary.
+					for _, primary := range z[origin].TransferFrom {
+						if trans, _ := parse.Transport(primary); trans == transport.TLS {
+							return file.Zones{}, c.Errf("tls_trc needs the ISD-AS of primary '%s', which isn't reached over SCION", primary)
+						}
+					}
+				}
+				for primary := range z[origin].TransferWindows {
+					if !contains(z[origin].TransferFrom, primary) {
+						return file.Zones{}, c.Errf("transfer window for '%s', which is not a primary of %s", primary, origin)
+					}
+				}
+			}
 		}
 	}
 	return file.Zones{Z: z, Names: names}, nil
 }
+
+// parseWindow parses the window property: window ADDRESS WINDOW [WINDOW...]. The address is
+// normalized the same way as in transfer from, so both can be compared.
+func parseWindow(c *caddy.Controller) (string, []file.TransferWindow, error) {
+	args := c.RemainingArgs()
+	if len(args) < 2 {
+		return "", nil, c.ArgErr()
+	}
+	primary, err := parse.TransferAddr(args[0])
+	if err != nil {
+		return "", nil, err
+	}
+	windows := make([]file.TransferWindow, 0, len(args)-1)
+	for _, a := range args[1:] {
+		w, err := file.ParseTransferWindow(a)
+		if err != nil {
+			return "", nil, c.Err(err.Error())
+		}
+		windows = append(windows, w)
+	}
+	return primary, windows, nil
+}
+
+// parseTsig parses the tsig property: tsig NAME SECRET [ALGORITHM]. The algorithm defaults to hmac-sha256.
+func parseTsig(c *caddy.Controller) (*file.TransferKey, error) {
+	args := c.RemainingArgs()
+	if len(args) != 2 && len(args) != 3 {
+		return nil, c.ArgErr()
+	}
+	key := &file.TransferKey{Name: dns.CanonicalName(args[0]), Secret: args[1], Algorithm: dns.HmacSHA256}
+	if _, err := base64.StdEncoding.DecodeString(key.Secret); err != nil {
+		return nil, c.Errf("invalid secret for TSIG key '%s': %s", args[0], err)
+	}
+	if len(args) == 3 {
+		key.Algorithm = dns.CanonicalName(args[2])
+		switch key.Algorithm {
+		case dns.HmacSHA1, dns.HmacSHA224, dns.HmacSHA256, dns.HmacSHA384, dns.HmacSHA512:
+		default:
+			return nil, c.Errf("unsupported TSIG algorithm '%s'", args[2])
+		}
+	}
+	return key, nil
+}
+
+// parseRefresh parses the refresh, retry and jitter properties into policy:
+//
+//	refresh MIN MAX
+//	retry MIN MAX [constant|exponential]
+//	jitter REFRESH [RETRY]
+//
+// A zero duration leaves the bound unset.
+func parseRefresh(c *caddy.Controller, policy *file.RefreshPolicy) error {
+	prop := c.Val()
+	args := c.RemainingArgs()
+	durations := make([]time.Duration, 0, 2)
+	for i, a := range args {
+		if prop == "retry" && i == 2 {
+			switch a {
+			case "constant":
+				policy.Backoff = file.BackoffConstant
+			case "exponential":
+				policy.Backoff = file.BackoffExponential
+			default:
+				return c.Errf("unknown retry backoff '%s'", a)
+			}
+			continue
+		}
+		d, err := time.ParseDuration(a)
+		if err != nil || d < 0 {
+			return c.Errf("invalid %s duration '%s'", prop, a)
+		}
+		durations = append(durations, d)
+	}
+
+	switch prop {
+	case "refresh", "retry":
+		if len(args) < 2 || (prop == "refresh" && len(args) > 2) || len(args) > 3 {
+			return c.ArgErr()
+		}
+		min, max := durations[0], durations[1]
+		if max > 0 && min > max {
+			return c.Errf("%s minimum %s is above the maximum %s", prop, min, max)
+		}
+		if prop == "refresh" {
+			policy.MinRefresh, policy.MaxRefresh = min, max
+		} else {
+			policy.MinRetry, policy.MaxRetry = min, max
+		}
+	case "jitter":
+		if len(args) == 0 || len(args) > 2 {
+			return c.ArgErr()
+		}
+		policy.RefreshJitter = durations[0]
+		if len(durations) == 2 {
+			policy.RetryJitter = durations[1]
+		}
+	}
+	return nil
+}
+
+// parseRate parses a bandwidth in bytes per second, with an optional k, m or g suffix.
+func parseRate(s string) (int64, error) {
+	mult := int64(1)
+	switch strings.ToLower(s[len(s)-1:]) {
+	case "k":
+		mult = 1000
+	case "m":
+		mult = 1000 * 1000
+	case "g":
+		mult = 1000 * 1000 * 1000
+	}
+	if mult != 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 {
+		return 0, strconv.ErrRange
+	}
+	return n * mult, nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parseExpired parses the expired property: expired servfail|refuse|stale [TTL].
+func parseExpired(c *caddy.Controller) (file.ExpiredPolicy, uint32, error) {
+	args := c.RemainingArgs()
+	if len(args) == 0 {
+		return 0, 0, c.ArgErr()
+	}
+	switch args[0] {
+	case "servfail", "refuse":
+		if len(args) != 1 {
+			return 0, 0, c.ArgErr()
+		}
+		if args[0] == "refuse" {
+			return file.ExpiredRefuse, 0, nil
+		}
+		return file.ExpiredServfail, 0, nil
+	case "stale":
+		if len(args) > 2 {
+			return 0, 0, c.ArgErr()
+		}
+		ttl := uint64(file.DefaultStaleTTL)
+		if len(args) == 2 {
+			var err error
+			ttl, err = strconv.ParseUint(args[1], 10, 32)
+			if err != nil {
+				return 0, 0, c.Errf("invalid stale TTL '%s': %s", args[1], err)
+			}
+		}
+		return file.ExpiredServeStale, uint32(ttl), nil
+	}
+	return 0, 0, c.Errf("unknown expired policy '%s'", args[0])
+}
@@ -2,8 +2,12 @@ package secondary
 
 import (
 	"testing"
+	"time"
 
 	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/file"
+
+	"github.com/miekg/dns"
 )
 
 func TestSecondaryParse(t *testing.T) {
@@ -35,6 +39,22 @@ func TestSecondaryParse(t *testing.T) {
 			"127.0.0.1:53",
 			[]string{"example.org."},
 		},
+		{
+			`secondary example.org {
+				transfer from squic://NS1.example.net
+			}`,
+			false,
+			"squic://ns1.example.net",
+			[]string{"example.org."},
+		},
+		{
+			`secondary example.org {
+				transfer from 19-ffaa:1:fe4,[127.0.0.1]
+			}`,
+			false,
+			"squic://19-ffaa:1:fe4,[127.0.0.1]",
+			[]string{"example.org."},
+		},
 	}
 
 	for i, test := range tests {
@@ -61,3 +81,355 @@ func TestSecondaryParse(t *testing.T) {
 		}
 	}
 }
+
+func TestSecondaryParseExpired(t *testing.T) {
+	tests := []struct {
+		input          string
+		shouldErr      bool
+		expectedPolicy file.ExpiredPolicy
+		expectedTTL    uint32
+	}{
+		{`secondary example.org`, false, file.ExpiredServfail, 0},
+		{`secondary example.org {
+			expired servfail
+		}`, false, file.ExpiredServfail, 0},
+		{`secondary example.org {
+			expired refuse
+		}`, false, file.ExpiredRefuse, 0},
+		{`secondary example.org {
+			expired stale
+		}`, false, file.ExpiredServeStale, file.DefaultStaleTTL},
+		{`secondary example.org {
+			expired stale 10
+		}`, false, file.ExpiredServeStale, 10},
+		// fails
+		{`secondary example.org {
+			expired
+		}`, true, 0, 0},
+		{`secondary example.org {
+			expired refuse 10
+		}`, true, 0, 0},
+		{`secondary example.org {
+			expired stale ten
+		}`, true, 0, 0},
+		{`secondary example.org {
+			expired drop
+		}`, true, 0, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		s, err := secondaryParse(c)
+
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		z := s.Z["example.org."]
+		if z.ExpiredPolicy != test.expectedPolicy {
+			t.Errorf("Test %d expected policy %d, got %d", i, test.expectedPolicy, z.ExpiredPolicy)
+		}
+		if z.StaleTTL != test.expectedTTL {
+			t.Errorf("Test %d expected stale TTL %d, got %d", i, test.expectedTTL, z.StaleTTL)
+		}
+	}
+}
+
+func TestSecondaryParseWindowBandwidth(t *testing.T) {
+	tests := []struct {
+		input           string
+		shouldErr       bool
+		expectedWindows int
+		expectedRate    int64
+	}{
+		{`secondary example.org {
+			transfer from 10.0.1.1
+		}`, false, 0, 0},
+		{`secondary example.org {
+			transfer from 10.0.1.1
+			window 10.0.1.1 02:00-04:00 23:00-23:30
+			bandwidth 500k
+		}`, false, 2, 500000},
+		{`secondary example.org {
+			window 10.0.1.1:53 02:00-04:00
+			transfer from 10.0.1.1
+			bandwidth 2M
+		}`, false, 1, 2000000},
+		// fails
+		{`secondary example.org {
+			transfer from 10.0.1.1
+			window 10.0.1.2 02:00-04:00
+		}`, true, 0, 0},
+		{`secondary example.org {
+			transfer from 10.0.1.1
+			window 10.0.1.1
+		}`, true, 0, 0},
+		{`secondary example.org {
+			transfer from 10.0.1.1
+			window 10.0.1.1 02:00
+		}`, true, 0, 0},
+		{`secondary example.org {
+			transfer from 10.0.1.1
+			bandwidth
+		}`, true, 0, 0},
+		{`secondary example.org {
+			transfer from 10.0.1.1
+			bandwidth 0
+		}`, true, 0, 0},
+		{`secondary example.org {
+			transfer from 10.0.1.1
+			bandwidth fast
+		}`, true, 0, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		s, err := secondaryParse(c)
+
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		z := s.Z["example.org."]
+		if x := len(z.TransferWindows["10.0.1.1:53"]); x != test.expectedWindows {
+			t.Errorf("Test %d expected %d transfer windows, got %d", i, test.expectedWindows, x)
+		}
+		if z.TransferRate != test.expectedRate {
+			t.Errorf("Test %d expected bandwidth %d, got %d", i, test.expectedRate, z.TransferRate)
+		}
+	}
+}
+
+func TestSecondaryParseTsig(t *testing.T) {
+	tests := []struct {
+		input             string
+		shouldErr         bool
+		expectedName      string
+		expectedAlgorithm string
+	}{
+		{`secondary example.org {
+			transfer from 10.0.1.1
+		}`, false, "", ""},
+		{`secondary example.org {
+			transfer from 10.0.1.1
+			tsig Example.Key c2VjcmV0
+		}`, false, "example.key.", dns.HmacSHA256},
+		{`secondary example.org {
+			transfer from 10.0.1.1
+			tsig example.key. c2VjcmV0 hmac-sha512
+		}`, false, "example.key.", dns.HmacSHA512},
+		// fails
+		{`secondary example.org {
+			transfer from 10.0.1.1
+			tsig example.key.
+		}`, true, "", ""},
+		{`secondary example.org {
+			transfer from 10.0.1.1
+			tsig example.key. not-base64!
+		}`, true, "", ""},
+		{`secondary example.org {
+			transfer from 10.0.1.1
+			tsig example.key. c2VjcmV0 hmac-md5
+		}`, true, "", ""},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		s, err := secondaryParse(c)
+
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		key := s.Z["example.org."].TransferKey
+		if test.expectedName == "" {
+			if key != nil {
+				t.Errorf("Test %d expected no TSIG key, got %q", i, key.Name)
+			}
+			continue
+		}
+		if key == nil {
+			t.Fatalf("Test %d expected TSIG key %q, got none", i, test.expectedName)
+		}
+		if key.Name != test.expectedName || key.Algorithm != test.expectedAlgorithm {
+			t.Errorf("Test %d expected TSIG key %q with %q, got %q with %q", i, test.expectedName, test.expectedAlgorithm, key.Name, key.Algorithm)
+		}
+	}
+}
+
+func TestSecondaryParseTLS(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		expected  bool
+	}{
+		{`secondary example.org {
+			transfer from 19-ffaa:1:fe4,[127.0.0.1]:8853
+		}`, false, false},
+		{`secondary example.org {
+			transfer from 19-ffaa:1:fe4,[127.0.0.1]:8853
+			tls ../tls/test_cert.pem ../tls/test_key.pem ../tls/test_ca.pem
+		}`, false, true},
+		// fails
+		{`secondary example.org {
+			transfer from 19-ffaa:1:fe4,[127.0.0.1]:8853
+			tls ../tls/test_ca.pem
+		}`, true, false},
+		{`secondary example.org {
+			transfer from 19-ffaa:1:fe4,[127.0.0.1]:8853
+			tls missing_cert.pem missing_key.pem
+		}`, true, false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		s, err := secondaryParse(c)
+
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		cfg := s.Z["example.org."].TransferTLS
+		if (cfg != nil) != test.expected {
+			t.Fatalf("Test %d expected a client TLS config %t, got %v", i, test.expected, cfg)
+		}
+		if cfg != nil && len(cfg.Certificates) != 1 {
+			t.Errorf("Test %d expected a client certificate, got %d", i, len(cfg.Certificates))
+		}
+	}
+}
+
+func TestSecondaryParseTLSTRC(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		expected  bool
+	}{
+		{`secondary example.org {
+			transfer from 19-ffaa:1:fe4,[127.0.0.1]:8853
+		}`, false, false},
+		{`secondary example.org {
+			transfer from 19-ffaa:1:fe4,[127.0.0.1]:8853
+			tls_trc ../pkg/scion/testdata/trc
+		}`, false, true},
+		{`secondary example.org {
+			transfer from tls://127.0.0.1
+			tls_trc ../pkg/scion/testdata/trc 19-ffaa:1:fe4
+		}`, false, true},
+		// fails
+		{`secondary example.org {
+			transfer from 19-ffaa:1:fe4,[127.0.0.1]:8853
+			tls_trc
+		}`, true, false},
+		{`secondary example.org {
+			transfer from tls://127.0.0.1
+			tls_trc ../pkg/scion/testdata/trc
+		}`, true, false},
+		{`secondary example.org {
+			transfer from 19-ffaa:1:fe4,[127.0.0.1]:8853
+			tls_trc ../pkg/scion/testdata/trc 19
+		}`, true, false},
+		{`secondary example.org {
+			transfer from 19-ffaa:1:fe4,[127.0.0.1]:8853
+			tls_trc missing
+		}`, true, false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		s, err := secondaryParse(c)
+
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if err != nil {
+			continue
+		}
+		if trcs := s.Z["example.org."].TransferTRCs; (trcs != nil) != test.expected {
+			t.Errorf("Test %d expected TRCs %t, got %v", i, test.expected, trcs)
+		}
+	}
+}
+
+func TestSecondaryParseRefresh(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		expected  file.RefreshPolicy
+	}{
+		{`secondary example.org {
+			transfer from 10.0.1.1
+		}`, false, file.DefaultRefreshPolicy},
+		{`secondary example.org {
+			transfer from 10.0.1.1
+			refresh 5m 1h
+			retry 30s 0 exponential
+			jitter 10s
+		}`, false, file.RefreshPolicy{
+			MinRefresh: 5 * time.Minute, MaxRefresh: time.Hour,
+			MinRetry: 30 * time.Second, Backoff: file.BackoffExponential,
+			RefreshJitter: 10 * time.Second, RetryJitter: 2 * time.Second,
+		}},
+		{`secondary example.org {
+			transfer from 10.0.1.1
+			jitter 0 0
+		}`, false, file.RefreshPolicy{}},
+		// fails
+		{`secondary example.org {
+			transfer from 10.0.1.1
+			refresh 5m
+		}`, true, file.RefreshPolicy{}},
+		{`secondary example.org {
+			transfer from 10.0.1.1
+			refresh 1h 5m
+		}`, true, file.RefreshPolicy{}},
+		{`secondary example.org {
+			transfer from 10.0.1.1
+			retry 30s 5m linear
+		}`, true, file.RefreshPolicy{}},
+		{`secondary example.org {
+			transfer from 10.0.1.1
+			jitter soon
+		}`, true, file.RefreshPolicy{}},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		s, err := secondaryParse(c)
+
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		if p := s.Z["example.org."].RefreshPolicy; p != test.expected {
+			t.Errorf("Test %d expected refresh policy %+v, got %+v", i, test.expected, p)
+		}
+	}
+}
@@ -60,7 +60,8 @@ func write(w io.Writer, z *file.Zone) error {
 // the record types DNSKEY, RRSIG, CDNSKEY and CDS are *not* included in the returned
 // zone (if encountered).
 func Parse(f io.Reader, origin, fileName string) (*file.Zone, error) {
-	zp := dns.NewZoneParser(f, dns.Fqdn(origin), fileName)
+	sr := file.NewSCIONOriginReader(f, fileName)
+	zp := dns.NewZoneParser(sr, dns.Fqdn(origin), fileName)
 	zp.SetIncludeAllowed(true)
 	z := file.NewZone(origin, fileName)
 	seenSOA := false
@@ -84,6 +85,9 @@ func Parse(f io.Reader, origin, fileName string) (*file.Zone, error) {
 			}
 		}
 	}
+	if err := sr.Err(); err != nil {
+		return nil, err
+	}
 	if !seenSOA {
 		return nil, fmt.Errorf("file %q has no SOA record", fileName)
 	}
@@ -0,0 +1,16 @@
+package steer
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ResponseCount is the number of responses per service and the rule that selected their endpoints.
+var ResponseCount = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "steer",
+	Name:      "responses_total",
+	Help:      "Counter of steered responses per service and the rule that selected the endpoints.",
+}, []string{"server", "service", "rule"})
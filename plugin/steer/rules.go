@@ -0,0 +1,117 @@
+package steer
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+)
+
+// predicate selects clients by their ISD-AS. An AS of zero matches the whole ISD, the zero
+// predicate matches all clients, including those not using SCION.
+type predicate struct {
+	ia  pkgscion.IA
+	any bool
+}
+
+// parsePredicate parses *, an ISD or an ISD-AS.
+func parsePredicate(s string) (predicate, error) {
+	if s == "*" {
+		return predicate{any: true}, nil
+	}
+	if !strings.Contains(s, "-") {
+		isd, err := strconv.ParseUint(s, 10, 16)
+		if err != nil || isd == 0 {
+			return predicate{}, fmt.Errorf("invalid ISD %q", s)
+		}
+		return predicate{ia: pkgscion.IA{ISD: uint16(isd)}}, nil
+	}
+	ia, err := pkgscion.ParseIA(s)
+	if err != nil {
+		return predicate{}, err
+	}
+	if ia.ISD == 0 {
+		return predicate{}, fmt.Errorf("ISD-AS %q has no ISD", s)
+	}
+	return predicate{ia: ia}, nil
+}
+
+func (p predicate) String() string {
+	switch {
+	case p.any:
+		return "*"
+	case p.ia.AS == 0:
+		return strconv.FormatUint(uint64(p.ia.ISD), 10)
+	}
+	return p.ia.String()
+}
+
+// specificity orders predicates, the most specific matching one is used.
+func (p predicate) specificity() int {
+	switch {
+	case p.any:
+		return 0
+	case p.ia.AS == 0:
+		return 1
+	}
+	return 2
+}
+
+func (p predicate) matches(ia pkgscion.IA, scion bool) bool {
+	switch {
+	case p.any:
+		return true
+	case !scion:
+		return false
+	case p.ia.AS == 0:
+		return p.ia.ISD == ia.ISD
+	}
+	return p.ia == ia
+}
+
+// rule designates the endpoints for the clients matching predicate.
+type rule struct {
+	predicate predicate
+	endpoints []string // ISD-AS,[IP]
+}
+
+// service holds the rules of a single service name.
+type service struct {
+	name  string
+	rules []*rule
+}
+
+// match returns the most specific rule for a client in ia, or nil if none applies. Clients not
+// using SCION have the zero ia and only match the * rule.
+func (s *service) match(ia pkgscion.IA) *rule {
+	var best *rule
+	for _, r := range s.rules {
+		if !r.predicate.matches(ia, !ia.IsZero()) {
+			continue
+		}
+		if best == nil || r.predicate.specificity() > best.predicate.specificity() {
+			best = r
+		}
+	}
+	return best
+}
+
+// parseEndpoint parses a SCION host address like 19-ffaa:1:e4b,[10.0.0.1] and returns it in its
+// canonical form.
+func parseEndpoint(s string) (string, error) {
+	ias, host, ok := strings.Cut(s, ",")
+	if !ok {
+		return "", fmt.Errorf("endpoint %q is not of the form ISD-AS,[IP]", s)
+	}
+	ia, err := pkgscion.ParseIA(ias)
+	if err != nil {
+		return "", err
+	}
+	ip := net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"))
+	if ip == nil {
+		return "", fmt.Errorf("invalid IP address in endpoint %q", s)
+	}
+	return ia.String() + ",[" + ip.String() + "]", nil
+}
@@ -0,0 +1,106 @@
+package steer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/miekg/dns"
+)
+
+func init() { plugin.Register("steer", setup) }
+
+// defaultTTL is the TTL of the answers if not configured. It is short, so clients don't keep an
+// endpoint for long after they moved to another AS.
+const defaultTTL = 30
+
+func setup(c *caddy.Controller) error {
+	s, err := parse(c)
+	if err != nil {
+		return plugin.Error("steer", err)
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		s.Next = next
+		return s
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller) (Steer, error) {
+	s := Steer{Services: map[string]*service{}, TTL: defaultTTL}
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return s, plugin.ErrOnce
+		}
+		i++
+
+		s.Zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "ttl":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return s, c.ArgErr()
+				}
+				ttl, err := strconv.ParseUint(args[0], 10, 32)
+				if err != nil {
+					return s, c.Errf("invalid ttl '%s'", args[0])
+				}
+				s.TTL = uint32(ttl)
+			default:
+				name := dns.Fqdn(strings.ToLower(c.Val()))
+				args := c.RemainingArgs()
+				if len(args) < 2 {
+					return s, c.ArgErr()
+				}
+				if plugin.Zones(s.Zones).Matches(name) == "" {
+					return s, c.Errf("service '%s' is not in any of the zones %v", name, s.Zones)
+				}
+				if err := s.addRule(name, args[0], args[1:]); err != nil {
+					return s, c.Err(err.Error())
+				}
+			}
+		}
+	}
+	if len(s.Services) == 0 {
+		return s, c.Err("no services specified")
+	}
+	return s, nil
+}
+
+// addRule adds the rule for the clients matching pred to the service name.
+func (s Steer) addRule(name, pred string, endpoints []string) error {
+	p, err := parsePredicate(pred)
+	if err != nil {
+		return err
+	}
+	svc, ok := s.Services[name]
+	if !ok {
+		svc = &service{name: name}
+		s.Services[name] = svc
+	}
+	for _, r := range svc.rules {
+		if r.predicate == p {
+			return fmt.Errorf("duplicate rule %s for service %s", p, name)
+		}
+	}
+	r := &rule{predicate: p}
+	for _, e := range endpoints {
+		ep, err := parseEndpoint(e)
+		if err != nil {
+			return err
+		}
+		r.endpoints = append(r.endpoints, ep)
+	}
+	svc.rules = append(svc.rules, r)
+	return nil
+}
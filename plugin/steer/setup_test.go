@@ -0,0 +1,81 @@
+package steer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input              string
+		shouldErr          bool
+		expectedServices   int
+		expectedTTL        uint32
+		expectedErrContent string // substring from the expected error. Empty for positive cases.
+	}{
+		{`steer example.org {
+			www.example.org 19 19-ffaa:1:e4b,[10.0.0.1]
+			www.example.org * 17-ffaa:0:1102,[10.0.0.2]
+		}`, false, 1, defaultTTL, ""},
+		{`steer example.org {
+			ttl 300
+			www.example.org 19-ffaa:1:e4b 19-ffaa:1:e4b,[10.0.0.1] 19-ffaa:1:e4b,[fd00::1]
+			api.example.org 17 17-ffaa:0:1102,10.0.0.2
+		}`, false, 2, 300, ""},
+		// fails
+		{`steer example.org`, true, 0, 0, "no services specified"},
+		{`steer example.org {
+			www.example.org 19
+		}`, true, 0, 0, "Wrong argument count"},
+		{`steer example.org {
+			www.example.net 19 19-ffaa:1:e4b,[10.0.0.1]
+		}`, true, 0, 0, "not in any of the zones"},
+		{`steer example.org {
+			www.example.org 19-x 19-ffaa:1:e4b,[10.0.0.1]
+		}`, true, 0, 0, "invalid AS"},
+		{`steer example.org {
+			www.example.org 19 10.0.0.1
+		}`, true, 0, 0, "not of the form ISD-AS,[IP]"},
+		{`steer example.org {
+			www.example.org 19 19-ffaa:1:e4b,[example]
+		}`, true, 0, 0, "invalid IP address"},
+		{`steer example.org {
+			www.example.org 19 19-ffaa:1:e4b,[10.0.0.1]
+			www.example.org 19 19-ffaa:1:e4b,[10.0.0.2]
+		}`, true, 0, 0, "duplicate rule 19"},
+		{`steer example.org {
+			ttl -1
+			www.example.org 19 19-ffaa:1:e4b,[10.0.0.1]
+		}`, true, 0, 0, "invalid ttl"},
+		{`steer example.org {
+			www.example.org 19 19-ffaa:1:e4b,[10.0.0.1]
+		}
+		steer`, true, 0, 0, "this plugin"},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		s, err := parse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error but found none for input %s", i, test.input)
+		}
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			}
+			if !strings.Contains(err.Error(), test.expectedErrContent) {
+				t.Errorf("Test %d: Expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
+			}
+			continue
+		}
+		if len(s.Services) != test.expectedServices {
+			t.Errorf("Test %d: Expected %d services, got %d", i, test.expectedServices, len(s.Services))
+		}
+		if s.TTL != test.expectedTTL {
+			t.Errorf("Test %d: Expected TTL %d, got %d", i, test.expectedTTL, s.TTL)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Package steer implements a plugin that answers each SCION client with the endpoint of a service
+// designated for its ISD or AS.
+package steer
+
+import (
+	"context"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// Steer answers TXT queries for its services with the endpoints for the client's region.
+type Steer struct {
+	Next  plugin.Handler
+	Zones []string
+
+	// Services maps the (fully qualified, lower cased) service names to their rules.
+	Services map[string]*service
+	TTL      uint32
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (s Steer) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	zone := plugin.Zones(s.Zones).Matches(state.Name())
+	if zone == "" || state.QType() != dns.TypeTXT {
+		return plugin.NextOrFailure(s.Name(), s.Next, ctx, w, r)
+	}
+	svc, ok := s.Services[state.Name()]
+	if !ok {
+		return plugin.NextOrFailure(s.Name(), s.Next, ctx, w, r)
+	}
+	rule := svc.match(clientIA(state))
+	if rule == nil {
+		return plugin.NextOrFailure(s.Name(), s.Next, ctx, w, r)
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+	for _, e := range rule.endpoints {
+		m.Answer = append(m.Answer, &dns.TXT{
+			Hdr: dns.RR_Header{Name: state.QName(), Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: s.TTL},
+			Txt: []string{"scion=" + e},
+		})
+	}
+	w.WriteMsg(m)
+
+	ResponseCount.WithLabelValues(metrics.WithServer(ctx), svc.name, rule.predicate.String()).Inc()
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the plugin.Handler interface.
+func (s Steer) Name() string { return "steer" }
+
+// clientIA returns the ISD-AS of SCION clients and the zero IA for all others.
+func clientIA(state request.Request) pkgscion.IA {
+	c, _ := pkgscion.ClientOf(state.W.RemoteAddr())
+	return c.IA
+}
@@ -0,0 +1,76 @@
+package steer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestSteer(t *testing.T) {
+	c := caddy.NewTestController("dns", `steer example.org {
+		www.example.org 19 19-ffaa:1:e4b,[10.0.0.1]
+		www.example.org 19-ffaa:1:fe4 19-ffaa:1:fe4,[10.0.0.2] 19-ffaa:1:fe4,[10.0.0.3]
+		www.example.org * 17-ffaa:0:1102,[10.0.0.4]
+		api.example.org 17 17-ffaa:0:1102,[10.0.0.5]
+	}`)
+	s, err := parse(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Next = test.NextHandler(dns.RcodeNameError, nil)
+
+	client := func(addr string) dns.ResponseWriter {
+		return &test.ResponseWriter{RemoteSCION: addr}
+	}
+
+	tests := []struct {
+		w        dns.ResponseWriter
+		qname    string
+		qtype    uint16
+		expected []string
+		rcode    int
+	}{
+		{client("19-ffaa:1:fe4,[127.0.0.1]:1234"), "www.example.org.", dns.TypeTXT, []string{"scion=19-ffaa:1:fe4,[10.0.0.2]", "scion=19-ffaa:1:fe4,[10.0.0.3]"}, dns.RcodeSuccess},
+		{client("19-ffaa:1:1067,[127.0.0.1]:1234"), "www.example.org.", dns.TypeTXT, []string{"scion=19-ffaa:1:e4b,[10.0.0.1]"}, dns.RcodeSuccess},
+		{client("17-ffaa:1:1,[127.0.0.1]:1234"), "WWW.example.org.", dns.TypeTXT, []string{"scion=17-ffaa:0:1102,[10.0.0.4]"}, dns.RcodeSuccess},
+		// Clients not using SCION only get the default.
+		{&test.ResponseWriter{}, "www.example.org.", dns.TypeTXT, []string{"scion=17-ffaa:0:1102,[10.0.0.4]"}, dns.RcodeSuccess},
+		// No default, so these are passed on.
+		{&test.ResponseWriter{}, "api.example.org.", dns.TypeTXT, nil, dns.RcodeNameError},
+		{client("19-ffaa:1:fe4,[127.0.0.1]:1234"), "api.example.org.", dns.TypeTXT, nil, dns.RcodeNameError},
+		{client("19-ffaa:1:fe4,[127.0.0.1]:1234"), "www.example.org.", dns.TypeA, nil, dns.RcodeNameError},
+		{client("19-ffaa:1:fe4,[127.0.0.1]:1234"), "ftp.example.org.", dns.TypeTXT, nil, dns.RcodeNameError},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		rec := dnstest.NewRecorder(tc.w)
+		rcode, _ := s.ServeDNS(context.Background(), rec, m)
+		if rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, tc.rcode, rcode)
+			continue
+		}
+		if tc.expected == nil {
+			continue
+		}
+		if len(rec.Msg.Answer) != len(tc.expected) {
+			t.Errorf("Test %d: expected %d answers, got %d", i, len(tc.expected), len(rec.Msg.Answer))
+			continue
+		}
+		for j, rr := range rec.Msg.Answer {
+			txt := rr.(*dns.TXT)
+			if txt.Txt[0] != tc.expected[j] {
+				t.Errorf("Test %d: expected %s, got %s", i, tc.expected[j], txt.Txt[0])
+			}
+			if txt.Hdr.Ttl != defaultTTL {
+				t.Errorf("Test %d: expected TTL %d, got %d", i, defaultTTL, txt.Hdr.Ttl)
+			}
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package stub
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/pkg/doqclient"
+	"github.com/coredns/coredns/plugin/pkg/parse"
+	pkgtls "github.com/coredns/coredns/plugin/pkg/tls"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
+)
+
+func init() { plugin.Register("stub", setup) }
+
+// defaultRefresh is the longest time the authoritative servers are used without looking them up again,
+// if not configured.
+const defaultRefresh = time.Hour
+
+func setup(c *caddy.Controller) error {
+	s, err := parseStub(c)
+	if err != nil {
+		return plugin.Error("stub", err)
+	}
+
+	c.OnStartup(func() error {
+		for _, z := range s.zones {
+			go z.refresh()
+		}
+		return nil
+	})
+	c.OnShutdown(func() error {
+		for _, z := range s.zones {
+			z.close()
+		}
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		s.Next = next
+		return s
+	})
+
+	return nil
+}
+
+func parseStub(c *caddy.Controller) (*Stub, error) {
+	s := &Stub{zones: map[string]*zone{}}
+	var (
+		from          []string
+		refresh       = defaultRefresh
+		tlsConfig     = new(tls.Config)
+		tlsServerName string
+	)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		s.Zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "from":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				for _, a := range args {
+					if dnsutil.IsSCIONAddress(a) {
+						from = append(from, a)
+						continue
+					}
+					hosts, err := parse.HostPortOrFile(a)
+					if err != nil {
+						return nil, err
+					}
+					from = append(from, hosts...)
+				}
+			case "refresh":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				d, err := time.ParseDuration(c.Val())
+				if err != nil {
+					return nil, c.Errf("invalid refresh '%s': %s", c.Val(), err)
+				}
+				if d < minRefresh {
+					return nil, c.Errf("refresh '%s' is shorter than %s", c.Val(), minRefresh)
+				}
+				refresh = d
+			case "tls":
+				args := c.RemainingArgs()
+				if len(args) > 3 {
+					return nil, c.ArgErr()
+				}
+				cfg, err := pkgtls.NewTLSConfigFromArgs(args...)
+				if err != nil {
+					return nil, err
+				}
+				tlsConfig = cfg
+			case "tls_servername":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				tlsServerName = c.Val()
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	if len(from) == 0 {
+		return nil, fmt.Errorf("no servers to learn the authoritative servers from, use 'from'")
+	}
+
+	tlsConfig.NextProtos = transport.DoQALPNs
+	tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+
+	// The from servers reached over SCION are all verified against tls_servername.
+	fromTLSConfig := tlsConfig.Clone()
+	fromTLSConfig.ServerName = tlsServerName
+	for _, f := range from {
+		if dnsutil.IsSCIONAddress(f) && tlsServerName == "" {
+			return nil, fmt.Errorf("tls_servername is needed to learn the authoritative servers from %s", f)
+		}
+	}
+
+	d, _ := dnsserver.SCIONDefaults(c)
+	policy, err := d.PathPolicy()
+	if err != nil {
+		return nil, err
+	}
+	fromClient := doqclient.New(transport.SQUIC, fromTLSConfig)
+	fromClient.Policy = policy
+
+	for _, origin := range s.Zones {
+		s.zones[origin] = &zone{
+			origin:     origin,
+			from:       from,
+			maxRefresh: refresh,
+			tlsConfig:  tlsConfig,
+			policy:     policy,
+			ask:        askFunc(fromClient),
+		}
+	}
+	return s, nil
+}
+
+// askFunc returns a function that sends a query to a server, over DoQ with c if it has a SCION address
+// and over TCP otherwise.
+func askFunc(c *doqclient.Client) func(m *dns.Msg, addr string) (*dns.Msg, error) {
+	return func(m *dns.Msg, addr string) (*dns.Msg, error) {
+		if dnsutil.IsSCIONAddress(addr) {
+			ctx, cancel := context.WithTimeout(context.Background(), exchangeTimeout)
+			defer cancel()
+			return c.Exchange(ctx, m, addr)
+		}
+		m.Id = dns.Id()
+		ret, _, err := (&dns.Client{Net: "tcp"}).Exchange(m, addr)
+		return ret, err
+	}
+}
@@ -0,0 +1,83 @@
+package stub
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input              string
+		shouldErr          bool
+		expectedErrContent string
+		expectedFrom       []string
+		expectedRefresh    time.Duration
+	}{
+		{`stub example.org {
+			from 10.0.0.1
+		}`, false, "", []string{"10.0.0.1:53"}, defaultRefresh},
+		{`stub example.org {
+			from 10.0.0.1:5353 19-ffaa:1:e4b,[127.0.0.1]:8853
+			tls_servername ns1.example.org
+			refresh 10m
+		}`, false, "", []string{"10.0.0.1:5353", "19-ffaa:1:e4b,[127.0.0.1]:8853"}, 10 * time.Minute},
+		{`stub example.org`, true, "use 'from'", nil, 0},
+		{`stub example.org {
+			from 19-ffaa:1:e4b,[127.0.0.1]:8853
+		}`, true, "tls_servername is needed", nil, 0},
+		{`stub example.org {
+			from ns1
+		}`, true, "not an IP address or file", nil, 0},
+		{`stub example.org {
+			from 10.0.0.1
+			refresh 1s
+		}`, true, "shorter than", nil, 0},
+		{`stub example.org {
+			from 10.0.0.1
+			refresh often
+		}`, true, "invalid refresh", nil, 0},
+		{`stub example.org {
+			giraffe
+		}`, true, "unknown property", nil, 0},
+		{`stub example.org {
+			from 10.0.0.1
+		}
+		stub example.net {
+			from 10.0.0.1
+		}`, true, "this plugin", nil, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		s, err := parseStub(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error but found none for input %s", i, test.input)
+		}
+
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			}
+
+			if !strings.Contains(err.Error(), test.expectedErrContent) {
+				t.Errorf("Test %d: Expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
+			}
+			continue
+		}
+
+		z := s.zones["example.org."]
+		if z == nil {
+			t.Fatalf("Test %d: Expected zone example.org.", i)
+		}
+		if strings.Join(z.from, " ") != strings.Join(test.expectedFrom, " ") {
+			t.Errorf("Test %d: Expected from %v, got %v", i, test.expectedFrom, z.from)
+		}
+		if z.maxRefresh != test.expectedRefresh {
+			t.Errorf("Test %d: Expected refresh %s, got %s", i, test.expectedRefresh, z.maxRefresh)
+		}
+	}
+}
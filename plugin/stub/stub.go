@@ -0,0 +1,79 @@
+// Package stub implements the stub plugin, which forwards the queries for a zone to its authoritative
+// SCION servers, instead of transferring the zone like secondary does.
+package stub
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+var log = clog.NewWithPlugin("stub")
+
+// exchangeTimeout bounds a query to a single authoritative server.
+const exchangeTimeout = 2 * time.Second
+
+var errNoServers = errors.New("no authoritative SCION servers known")
+
+// Stub forwards queries for its zones to the authoritative SCION servers of the zones.
+type Stub struct {
+	Next  plugin.Handler
+	Zones []string
+
+	zones map[string]*zone
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (s *Stub) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	origin := plugin.Zones(s.Zones).Matches(state.Name())
+	if origin == "" {
+		return plugin.NextOrFailure(s.Name(), s.Next, ctx, w, r)
+	}
+	z := s.zones[origin]
+
+	if r.Opcode == dns.OpcodeNotify {
+		if z.isNotify(state) {
+			m := new(dns.Msg)
+			m.SetReply(r)
+			m.Authoritative = true
+			w.WriteMsg(m)
+
+			log.Infof("Notify from %s for %s: refreshing the authoritative servers", state.IP(), origin)
+			go z.refresh()
+			return dns.RcodeSuccess, nil
+		}
+		log.Infof("Dropping notify from %s for %s", state.IP(), origin)
+		return dns.RcodeSuccess, nil
+	}
+
+	servers := z.current()
+	if len(servers) == 0 {
+		return dns.RcodeServerFailure, plugin.Error(s.Name(), errNoServers)
+	}
+
+	var err error
+	start := rand.Intn(len(servers))
+	for i := range servers {
+		srv := servers[(start+i)%len(servers)]
+		var ret *dns.Msg
+		ret, err = srv.exchange(ctx, r)
+		if err != nil {
+			log.Debugf("Failed to query %s (%s) for %s: %s", srv.name, srv.addr, state.Name(), err)
+			continue
+		}
+		w.WriteMsg(ret)
+		return dns.RcodeSuccess, nil
+	}
+	return dns.RcodeServerFailure, plugin.Error(s.Name(), err)
+}
+
+// Name implements the plugin.Handler interface.
+func (s *Stub) Name() string { return "stub" }
@@ -0,0 +1,303 @@
+package stub
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/pkg/doqclient"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+// minRefresh is the shortest time the authoritative servers of a zone are used before they are looked
+// up again. It is also how long a failed lookup waits before trying again.
+const minRefresh = 30 * time.Second
+
+// zone holds the authoritative SCION servers of a stub zone.
+type zone struct {
+	origin string
+	// from are the servers asked for the NS records and the SCION address hints.
+	from []string
+	// maxRefresh is the longest time the servers are used before they are looked up again.
+	maxRefresh time.Duration
+	// tlsConfig is used for the authoritative servers, the ServerName is set to their NS name.
+	tlsConfig *tls.Config
+	policy    pan.Policy
+	// ask sends m to the from server addr.
+	ask func(m *dns.Msg, addr string) (*dns.Msg, error)
+
+	mu         sync.RWMutex
+	servers    []*server
+	expires    time.Time
+	refreshing uint32
+}
+
+// current returns the authoritative servers. If their TTL expired they are looked up again, in the
+// background unless there are none yet.
+func (z *zone) current() []*server {
+	z.mu.RLock()
+	servers, expires := z.servers, z.expires
+	z.mu.RUnlock()
+	if time.Now().Before(expires) {
+		return servers
+	}
+	if len(servers) > 0 {
+		go z.refresh()
+		return servers
+	}
+	z.refresh()
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	return z.servers
+}
+
+// refresh looks up the authoritative servers. If that fails the current servers are kept.
+func (z *zone) refresh() {
+	if !atomic.CompareAndSwapUint32(&z.refreshing, 0, 1) {
+		return
+	}
+	defer atomic.StoreUint32(&z.refreshing, 0)
+
+	servers, ttl, err := z.lookup()
+
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	if err != nil {
+		log.Warningf("Failed to look up the authoritative servers of %s: %s", z.origin, err)
+		z.expires = time.Now().Add(minRefresh)
+		return
+	}
+	for _, old := range z.servers {
+		if !contains(servers, old) {
+			old.close()
+		}
+	}
+	z.servers = servers
+	z.expires = time.Now().Add(ttl)
+	log.Infof("Using %d authoritative servers for %s for %s", len(servers), z.origin, ttl)
+}
+
+// lookup asks the from servers for the NS records of the zone and the SCION addresses of the name
+// servers. It returns the servers and how long they may be used, the smallest TTL of the records.
+func (z *zone) lookup() ([]*server, time.Duration, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(z.origin, dns.TypeNS)
+	ret, err := z.exchange(m)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ttl := uint32(z.maxRefresh / time.Second)
+	hints := scionHints(ret.Extra)
+	var servers []*server
+	for _, rr := range ret.Answer {
+		ns, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+		ttl = minTTL(ttl, ns.Hdr.Ttl)
+		name := strings.ToLower(ns.Ns)
+		hs, ok := hints[name]
+		if !ok {
+			// No glue, ask for the hints of the name server itself.
+			m := new(dns.Msg)
+			m.SetQuestion(name, dns.TypeTXT)
+			if ret, err := z.exchange(m); err == nil {
+				hs = scionHints(ret.Answer)[name]
+			}
+		}
+		for _, h := range hs {
+			ttl = minTTL(ttl, h.ttl)
+			servers = append(servers, z.server(h.addr, name))
+		}
+	}
+	if len(servers) == 0 {
+		return nil, 0, fmt.Errorf("no SCION addresses found for the name servers of %s", z.origin)
+	}
+
+	d := time.Duration(ttl) * time.Second
+	if d < minRefresh {
+		d = minRefresh
+	}
+	return servers, d, nil
+}
+
+// exchange sends m to the from servers, until one of them answers successfully.
+func (z *zone) exchange(m *dns.Msg) (*dns.Msg, error) {
+	var err error
+	for _, addr := range z.from {
+		var ret *dns.Msg
+		ret, err = z.ask(m, addr)
+		if err != nil {
+			continue
+		}
+		if ret.Rcode != dns.RcodeSuccess {
+			err = fmt.Errorf("%s answered %s for %s", addr, dns.RcodeToString[ret.Rcode], m.Question[0].Name)
+			continue
+		}
+		return ret, nil
+	}
+	return nil, err
+}
+
+// server returns the server for addr, the one already in use if there is one, so its connection is kept.
+func (z *zone) server(addr, name string) *server {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	for _, s := range z.servers {
+		if s.addr == addr && s.name == name {
+			return s
+		}
+	}
+	tlsConfig := z.tlsConfig.Clone()
+	tlsConfig.ServerName = strings.TrimSuffix(name, ".")
+	c := doqclient.New(transport.SQUIC, tlsConfig)
+	c.Policy = z.policy
+	return &server{addr: addr, name: name, client: c}
+}
+
+// close closes the connections to all servers.
+func (z *zone) close() {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	for _, s := range z.servers {
+		s.close()
+	}
+}
+
+// isNotify returns true if the notify in state comes from one of the from servers or one of the
+// authoritative servers.
+func (z *zone) isNotify(state request.Request) bool {
+	remote := dnsutil.HostOf(state.W.RemoteAddr().String())
+	for _, f := range z.from {
+		if dnsutil.HostOf(f) == remote {
+			return true
+		}
+	}
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	for _, s := range z.servers {
+		if dnsutil.HostOf(s.addr) == remote {
+			return true
+		}
+	}
+	return false
+}
+
+type hint struct {
+	addr string
+	ttl  uint32
+}
+
+// scionHints returns the SCION addresses in the TXT records of the form scion=ISD-AS,[IP] in rrs, by owner
+// name. Addresses without a port get the default DoQ port.
+func scionHints(rrs []dns.RR) map[string][]hint {
+	hints := map[string][]hint{}
+	for _, rr := range rrs {
+		txt, ok := rr.(*dns.TXT)
+		if !ok {
+			continue
+		}
+		for _, t := range txt.Txt {
+			if !strings.HasPrefix(t, "scion=") {
+				continue
+			}
+			scaddr, err := pan.ParseUDPAddr(strings.TrimPrefix(t, "scion="))
+			if err != nil {
+				continue
+			}
+			if scaddr.Port == 0 {
+				scaddr = scaddr.WithPort(uint16(pkgscion.Get().Port()))
+			}
+			addr := scaddr.IA.String() + ",[" + scaddr.IP.String() + "]:" + strconv.Itoa(int(scaddr.Port))
+			name := strings.ToLower(txt.Hdr.Name)
+			hints[name] = append(hints[name], hint{addr: addr, ttl: txt.Hdr.Ttl})
+		}
+	}
+	return hints
+}
+
+func contains(servers []*server, s *server) bool {
+	for _, t := range servers {
+		if t == s {
+			return true
+		}
+	}
+	return false
+}
+
+func minTTL(a, b uint32) uint32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// server is an authoritative SCION server of a zone. A connection to it is kept open between queries.
+type server struct {
+	addr   string
+	name   string
+	client *doqclient.Client
+
+	mu   sync.Mutex
+	conn *doqclient.Conn
+}
+
+func (s *server) exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	ctx, cancel := context.WithTimeout(ctx, exchangeTimeout)
+	defer cancel()
+	conn, err := s.hold(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := conn.Exchange(ctx, m)
+	if err != nil {
+		s.release(conn)
+	}
+	return ret, err
+}
+
+// hold returns the connection that is kept open, dialing it if there is none.
+func (s *server) hold(ctx context.Context) (*doqclient.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		return s.conn, nil
+	}
+	conn, err := s.client.Dial(ctx, s.addr)
+	if err != nil {
+		return nil, err
+	}
+	s.conn = conn
+	return conn, nil
+}
+
+// release stops keeping conn open, if it is still the one in use. The next query dials a new connection.
+func (s *server) release(conn *doqclient.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == conn {
+		s.conn = nil
+		conn.Close()
+	}
+}
+
+func (s *server) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
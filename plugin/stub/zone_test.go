@@ -0,0 +1,137 @@
+package stub
+
+import (
+	"crypto/tls"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// fakeFrom answers the queries of a zone for example.org from a map.
+func fakeFrom(answers map[string]*dns.Msg) func(m *dns.Msg, addr string) (*dns.Msg, error) {
+	return func(m *dns.Msg, addr string) (*dns.Msg, error) {
+		if addr != "10.0.0.1:53" {
+			return nil, errors.New("unreachable")
+		}
+		ret := new(dns.Msg)
+		ret.SetReply(m)
+		a, ok := answers[m.Question[0].Name]
+		if !ok {
+			ret.Rcode = dns.RcodeNameError
+			return ret, nil
+		}
+		ret.Answer, ret.Extra = a.Answer, a.Extra
+		return ret, nil
+	}
+}
+
+func newTestZone(answers map[string]*dns.Msg) *zone {
+	return &zone{
+		origin:     "example.org.",
+		from:       []string{"10.0.0.2:53", "10.0.0.1:53"},
+		maxRefresh: time.Hour,
+		tlsConfig:  new(tls.Config),
+		ask:        fakeFrom(answers),
+	}
+}
+
+func TestLookup(t *testing.T) {
+	z := newTestZone(map[string]*dns.Msg{
+		"example.org.": {
+			Answer: []dns.RR{
+				test.NS("example.org. 3600 IN NS ns1.example.org."),
+				test.NS("example.org. 3600 IN NS ns2.example.org."),
+				test.NS("example.org. 3600 IN NS ns3.example.org."),
+			},
+			Extra: []dns.RR{
+				test.TXT(`ns1.example.org. 600 IN TXT "scion=19-ffaa:1:e4b,[10.0.0.10]"`),
+				test.TXT(`ns1.example.org. 600 IN TXT "v=spf1 -all"`),
+			},
+		},
+		"ns2.example.org.": {
+			Answer: []dns.RR{test.TXT(`ns2.example.org. 120 IN TXT "scion=17-ffaa:0:1102,[10.0.0.20]:853"`)},
+		},
+	})
+
+	servers, ttl, err := z.lookup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(servers) != 2 {
+		t.Fatalf("Expected 2 servers, got %d", len(servers))
+	}
+	if servers[0].addr != "19-ffaa:1:e4b,[10.0.0.10]:8853" || servers[0].name != "ns1.example.org." {
+		t.Errorf("Expected ns1 with the default DoQ port, got %s at %s", servers[0].name, servers[0].addr)
+	}
+	if servers[0].client.TLSConfig.ServerName != "ns1.example.org" {
+		t.Errorf("Expected the TLS server name ns1.example.org, got %q", servers[0].client.TLSConfig.ServerName)
+	}
+	if servers[1].addr != "17-ffaa:0:1102,[10.0.0.20]:853" || servers[1].name != "ns2.example.org." {
+		t.Errorf("Expected ns2 from its own TXT record, got %s at %s", servers[1].name, servers[1].addr)
+	}
+	if ttl != 120*time.Second {
+		t.Errorf("Expected the smallest TTL 120s, got %s", ttl)
+	}
+
+	// The servers still in use are kept, with their connections.
+	z.servers = servers
+	again, _, err := z.lookup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again[0] != servers[0] || again[1] != servers[1] {
+		t.Errorf("Expected the servers to be reused")
+	}
+}
+
+func TestLookupFailure(t *testing.T) {
+	z := newTestZone(map[string]*dns.Msg{
+		"example.org.": {Answer: []dns.RR{test.NS("example.org. 10 IN NS ns1.example.org.")}},
+	})
+	if _, _, err := z.lookup(); err == nil {
+		t.Errorf("Expected an error for name servers without SCION addresses")
+	}
+
+	z = newTestZone(nil)
+	if _, _, err := z.lookup(); err == nil {
+		t.Errorf("Expected an error for a zone without NS records")
+	}
+
+	// A failed refresh keeps the servers.
+	old := &server{addr: "19-ffaa:1:e4b,[10.0.0.10]:8853", name: "ns1.example.org."}
+	z.servers = []*server{old}
+	z.refresh()
+	if len(z.servers) != 1 || z.servers[0] != old {
+		t.Errorf("Expected the servers to be kept")
+	}
+	if !z.expires.After(time.Now()) {
+		t.Errorf("Expected the next lookup to be delayed")
+	}
+}
+
+func TestIsNotify(t *testing.T) {
+	z := newTestZone(nil)
+	z.servers = []*server{{addr: "19-ffaa:1:e4b,[10.0.0.10]:8853", name: "ns1.example.org."}}
+
+	r := new(dns.Msg)
+	r.SetNotify("example.org.")
+
+	// test.ResponseWriter uses 10.240.0.1 as its remote address.
+	if z.isNotify(request.Request{W: &test.ResponseWriter{}, Req: r}) {
+		t.Errorf("Expected a notify from an unknown server to be dropped")
+	}
+	z.from = append(z.from, "10.240.0.1:53")
+	if !z.isNotify(request.Request{W: &test.ResponseWriter{}, Req: r}) {
+		t.Errorf("Expected a notify from a from server to be accepted")
+	}
+
+	w := &test.ResponseWriter{RemoteSCION: "19-ffaa:1:e4b,[10.0.0.10]:40000"}
+	if !z.isNotify(request.Request{W: w, Req: r}) {
+		t.Errorf("Expected a notify from an authoritative server to be accepted")
+	}
+}
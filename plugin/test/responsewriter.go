@@ -4,6 +4,7 @@ import (
 	"net"
 
 	"github.com/miekg/dns"
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
 )
 
 // ResponseWriter is useful for writing tests. It uses some fixed values for the client. The
@@ -12,7 +13,10 @@ import (
 type ResponseWriter struct {
 	TCP      bool // if TCP is true we return an TCP connection instead of an UDP one.
 	RemoteIP string
-	Zone     string
+	// RemoteSCION is the SCION address of a client connected over SCION, e.g. 19-ffaa:1:1067,[10.0.0.1]:40000.
+	// If set, it is the remote address instead of RemoteIP.
+	RemoteSCION string
+	Zone        string
 }
 
 // LocalAddr returns the local address, 127.0.0.1:53 (UDP, TCP if t.TCP is true).
@@ -25,8 +29,12 @@ func (t *ResponseWriter) LocalAddr() net.Addr {
 	return &net.UDPAddr{IP: ip, Port: port, Zone: ""}
 }
 
-// RemoteAddr returns the remote address, defaults to 10.240.0.1:40212 (UDP, TCP is t.TCP is true).
+// RemoteAddr returns the remote address, defaults to 10.240.0.1:40212 (UDP, TCP is t.TCP is true). If
+// t.RemoteSCION is set, it returns that SCION address.
 func (t *ResponseWriter) RemoteAddr() net.Addr {
+	if t.RemoteSCION != "" {
+		return pan.MustParseUDPAddr(t.RemoteSCION)
+	}
 	remoteIP := "10.240.0.1"
 	if t.RemoteIP != "" {
 		remoteIP = t.RemoteIP
@@ -57,6 +65,9 @@ func (t *ResponseWriter) TsigTimersOnly(bool) {}
 // Hijack implements dns.ResponseWriter interface.
 func (t *ResponseWriter) Hijack() {}
 
+// SupportsMultiMsg implements dns.ResponseWriter interface.
+func (t *ResponseWriter) SupportsMultiMsg() bool { return false }
+
 // ResponseWriter6 returns fixed client and remote address in IPv6.  The remote
 // address is always fe80::42:ff:feca:4c65 and port 40212. The local address is always ::1 and port 53.
 type ResponseWriter6 struct {
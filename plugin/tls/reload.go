@@ -0,0 +1,125 @@
+package tls
+
+import (
+	ctls "crypto/tls"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultReloadInterval is how often the certificate files are checked for changes if not configured.
+const defaultReloadInterval = time.Minute
+
+// certReloader serves the certificate loaded from certPath and keyPath to new handshakes, and loads it
+// again when the files change or on SIGHUP. Established connections, in particular long lived DoQ sessions,
+// keep running with the certificate they were set up with.
+type certReloader struct {
+	certPath string
+	keyPath  string
+	interval time.Duration
+
+	mu      sync.RWMutex
+	cert    *ctls.Certificate
+	modTime time.Time // latest modification time of the files the certificate was loaded from
+
+	stop chan struct{}
+}
+
+func newCertReloader(certPath, keyPath string, interval time.Duration) (*certReloader, error) {
+	r := &certReloader{certPath: certPath, keyPath: keyPath, interval: interval}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// getCertificate implements tls.Config.GetCertificate.
+func (r *certReloader) getCertificate(*ctls.ClientHelloInfo) (*ctls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// modified returns the latest modification time of the certificate and key files.
+func (r *certReloader) modified() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.certPath, r.keyPath} {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// reload loads the certificate if the files changed since it was last loaded. Reloaded is true if it
+// did. If the files can't be loaded, e.g. because only one of them was replaced so far, the current
+// certificate is kept.
+func (r *certReloader) reload() (reloaded bool, err error) {
+	modTime, err := r.modified()
+	if err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	unchanged := r.cert != nil && modTime.Equal(r.modTime)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := ctls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.mu.Unlock()
+	return true, nil
+}
+
+// start checks the files every interval, and on SIGHUP, until shutdown is called.
+func (r *certReloader) start() error {
+	r.stop = make(chan struct{})
+	go r.run(r.stop)
+	return nil
+}
+
+func (r *certReloader) shutdown() error {
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+	return nil
+}
+
+func (r *certReloader) run(stop <-chan struct{}) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-hup:
+		}
+		reloaded, err := r.reload()
+		if err != nil {
+			log.Errorf("Failed to reload certificate %s, keeping the current one: %s", r.certPath, err)
+			continue
+		}
+		if reloaded {
+			log.Infof("Reloaded certificate %s", r.certPath)
+		}
+	}
+}
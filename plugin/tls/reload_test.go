@@ -0,0 +1,73 @@
+package tls
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	copyFile(t, "test_cert.pem", certPath)
+	copyFile(t, "test_key.pem", keyPath)
+
+	r, err := newCertReloader(certPath, keyPath, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := r.getCertificate(nil)
+	if first == nil {
+		t.Fatal("Expected a certificate")
+	}
+
+	if reloaded, err := r.reload(); err != nil || reloaded {
+		t.Errorf("Expected no reload of unchanged files, got %t, %v", reloaded, err)
+	}
+
+	// A half written key is an error, the current certificate is kept.
+	if err := os.WriteFile(keyPath, []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, keyPath, time.Now().Add(time.Minute))
+	if _, err := r.reload(); err == nil {
+		t.Error("Expected an error for a broken key")
+	}
+	if cert, _ := r.getCertificate(nil); cert != first {
+		t.Error("Expected the current certificate to be kept")
+	}
+
+	copyFile(t, "test_key.pem", keyPath)
+	touch(t, keyPath, time.Now().Add(2*time.Minute))
+	if reloaded, err := r.reload(); err != nil || !reloaded {
+		t.Fatalf("Expected a reload of the changed files, got %t, %v", reloaded, err)
+	}
+	cert, _ := r.getCertificate(nil)
+	if cert == first {
+		t.Error("Expected a newly loaded certificate")
+	}
+	if !bytes.Equal(cert.Certificate[0], first.Certificate[0]) {
+		t.Error("Expected the same certificate data")
+	}
+}
+
+func copyFile(t *testing.T, from, to string) {
+	t.Helper()
+	b, err := os.ReadFile(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(to, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func touch(t *testing.T, path string, mtime time.Time) {
+	t.Helper()
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
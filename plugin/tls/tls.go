@@ -2,22 +2,21 @@ package tls
 
 import (
 	ctls "crypto/tls"
+	"time"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 	"github.com/coredns/coredns/plugin/pkg/tls"
+	"github.com/coredns/coredns/plugin/pkg/transport"
 )
 
-// NextProtoDQ - During connection establishment, DNS/QUIC support is indicated
-// by selecting the ALPN token "dq" in the crypto handshake.
-// Current draft version: https://datatracker.ietf.org/doc/html/draft-ietf-dprive-dnsoquic-02
-const NextProtoDQ = "doq-i02"
+var log = clog.NewWithPlugin("tls")
 
-// nextProtosDQ are ALPNs for a DNS-over-QUIC server
-var nextProtosDoQ = []string{
-	NextProtoDQ, "doq-i00", "dq", "doq",
-}
+// nextProtosDoQ are ALPNs for a DNS-over-QUIC server, the quic plugin's legacy_alpn adds those of the drafts.
+var nextProtosDoQ = []string{transport.DoQALPN}
 
 func init() { plugin.Register("tls", setup) }
 
@@ -42,6 +41,9 @@ func parseTLS(c *caddy.Controller) error {
 			return plugin.Error("tls", c.ArgErr())
 		}
 		clientAuth := ctls.NoClientCert
+		clientAuthSet := false
+		reload := time.Duration(0)
+		var clientTRCs *pkgscion.TRCs
 		for c.NextBlock() {
 			switch c.Val() {
 			case "client_auth":
@@ -49,6 +51,7 @@ func parseTLS(c *caddy.Controller) error {
 				if len(authTypeArgs) != 1 {
 					return c.ArgErr()
 				}
+				clientAuthSet = true
 				switch authTypeArgs[0] {
 				case "nocert":
 					clientAuth = ctls.NoClientCert
@@ -63,6 +66,29 @@ func parseTLS(c *caddy.Controller) error {
 				default:
 					return c.Errf("unknown authentication type '%s'", authTypeArgs[0])
 				}
+			case "reload":
+				reload = defaultReloadInterval
+				reloadArgs := c.RemainingArgs()
+				if len(reloadArgs) > 1 {
+					return c.ArgErr()
+				}
+				if len(reloadArgs) == 1 {
+					d, err := time.ParseDuration(reloadArgs[0])
+					if err != nil || d <= 0 {
+						return c.Errf("invalid reload interval '%s'", reloadArgs[0])
+					}
+					reload = d
+				}
+			case "client_trc":
+				trcArgs := c.RemainingArgs()
+				if len(trcArgs) != 1 {
+					return c.ArgErr()
+				}
+				trcs, err := pkgscion.LoadTRCs(trcArgs[0])
+				if err != nil {
+					return c.Errf("client_trc: %s", err)
+				}
+				clientTRCs = trcs
 			default:
 				return c.Errf("unknown option '%s'", c.Val())
 			}
@@ -74,6 +100,25 @@ func parseTLS(c *caddy.Controller) error {
 		tls.ClientAuth = clientAuth
 		// NewTLSConfigFromArgs only sets RootCAs, so we need to let ClientCAs refer to it.
 		tls.ClientCAs = tls.RootCAs
+		if clientTRCs != nil {
+			if clientAuthSet {
+				return c.Err("client_auth and client_trc can't be combined")
+			}
+			// Client certificates are AS certificates of the SCION control-plane PKI.
+			clientTRCs.ConfigureServer(tls)
+		}
+
+		if reload > 0 {
+			// New handshakes get the certificate from the reloader, the listeners keep running.
+			r, err := newCertReloader(args[0], args[1], reload)
+			if err != nil {
+				return err
+			}
+			tls.Certificates = nil
+			tls.GetCertificate = r.getCertificate
+			c.OnStartup(r.start)
+			c.OnShutdown(r.shutdown)
+		}
 
 		// DNS-over-QUIC config
 		tlsDoQ := tls.Clone()
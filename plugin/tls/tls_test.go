@@ -23,8 +23,17 @@ func TestTLS(t *testing.T) {
 		{"tls test_cert.pem test_key.pem test_ca.pem {\nclient_auth require\n}", false, "", ""},
 		{"tls test_cert.pem test_key.pem test_ca.pem {\nclient_auth verify_if_given\n}", false, "", ""},
 		{"tls test_cert.pem test_key.pem test_ca.pem {\nclient_auth require_and_verify\n}", false, "", ""},
+		{"tls test_cert.pem test_key.pem {\nreload\n}", false, "", ""},
+		{"tls test_cert.pem test_key.pem {\nreload 10s\n}", false, "", ""},
+		{"tls test_cert.pem test_key.pem {\nclient_trc ../pkg/scion/testdata/trc\n}", false, "", ""},
 		// negative
+		{"tls test_cert.pem test_key.pem {\nreload 0s\n}", true, "", "invalid reload interval"},
+		{"tls test_cert.pem test_key.pem {\nreload 10s 20s\n}", true, "", "Wrong argument"},
+		{"tls missing_cert.pem missing_key.pem {\nreload\n}", true, "", "no such file"},
 		{"tls test_cert.pem test_key.pem test_ca.pem {\nunknown\n}", true, "", "unknown option"},
+		{"tls test_cert.pem test_key.pem {\nclient_trc\n}", true, "", "Wrong argument"},
+		{"tls test_cert.pem test_key.pem {\nclient_trc missing\n}", true, "", "client_trc"},
+		{"tls test_cert.pem test_key.pem test_ca.pem {\nclient_trc ../pkg/scion/testdata/trc\nclient_auth require\n}", true, "", "can't be combined"},
 		// client_auth takes exactly one parameter, which must be one of known keywords.
 		{"tls test_cert.pem test_key.pem test_ca.pem {\nclient_auth\n}", true, "", "Wrong argument"},
 		{"tls test_cert.pem test_key.pem test_ca.pem {\nclient_auth none bogus\n}", true, "", "Wrong argument"},
@@ -87,6 +87,14 @@ func (t *trace) Tracer() ot.Tracer {
 	return t.tracer
 }
 
+// Sample implements the trace.Sampler interface, it returns true for every t.every-th query.
+func (t *trace) Sample() bool {
+	if t.every == 0 {
+		return false
+	}
+	return atomic.AddUint64(&t.count, 1)%t.every == 0
+}
+
 // OnStartup sets up the tracer
 func (t *trace) OnStartup() error {
 	var err error
@@ -148,20 +156,22 @@ func (t *trace) Name() string { return "trace" }
 
 // ServeDNS implements the plugin.Handle interface.
 func (t *trace) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
-	trace := false
-	if t.every > 0 {
-		queryNr := atomic.AddUint64(&t.count, 1)
-
-		if queryNr%t.every == 0 {
+	var trace bool
+	var spanCtx ot.SpanContext
+	if qs, ok := ctx.Value(dnsserver.QUICSpanKey{}).(*dnsserver.QUICSpan); ok {
+		// The DoQ server sampled the query already, see Sample.
+		if qs.Span != nil {
 			trace = true
+			spanCtx = qs.Span.Context()
 		}
+	} else {
+		trace = t.Sample()
 	}
 	span := ot.SpanFromContext(ctx)
 	if !trace || span != nil {
 		return plugin.NextOrFailure(t.Name(), t.Next, ctx, w, r)
 	}
 
-	var spanCtx ot.SpanContext
 	if val := ctx.Value(dnsserver.HTTPRequestKey{}); val != nil {
 		if httpReq, ok := val.(*http.Request); ok {
 			spanCtx, _ = t.Tracer().Extract(ot.HTTPHeaders, ot.HTTPHeadersCarrier(httpReq.Header))
@@ -172,3 +172,59 @@ func TestTrace_DOH_TraceHeaderExtraction(t *testing.T) {
 		t.Errorf("Unexpected traceID: rootSpan.TraceID: want %v, got %v", rootCoreDNSTraceID, outsideSpanTraceID)
 	}
 }
+
+func TestTrace_DoQ(t *testing.T) {
+	m := mocktracer.New()
+	tr := &trace{
+		Next: test.HandlerFunc(func(_ context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+			m := new(dns.Msg)
+			m.SetRcode(r, dns.RcodeSuccess)
+			w.WriteMsg(m)
+			return dns.RcodeSuccess, nil
+		}),
+		every:  2,
+		tracer: m,
+	}
+	q := new(dns.Msg).SetQuestion("example.net.", dns.TypeA)
+
+	streamSpan := m.StartSpan("doq.stream")
+	defer streamSpan.Finish()
+
+	// The DoQ server sampled the query, it is traced as child of the stream regardless of every.
+	ctx := context.WithValue(context.TODO(), dnsserver.QUICSpanKey{}, &dnsserver.QUICSpan{Span: streamSpan})
+	tr.ServeDNS(ctx, dnstest.NewRecorder(&test.ResponseWriter{}), q)
+
+	fs := m.FinishedSpans()
+	if len(fs) != 2 {
+		t.Fatalf("Unexpected span count: len(fs): want 2, got %v", len(fs))
+	}
+	if parent := fs[1].ParentID; parent != streamSpan.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Errorf("Unexpected parent: want the stream span, got %v", parent)
+	}
+
+	// The DoQ server didn't sample the query, it isn't traced.
+	m.Reset()
+	ctx = context.WithValue(context.TODO(), dnsserver.QUICSpanKey{}, &dnsserver.QUICSpan{})
+	for i := 0; i < 2; i++ {
+		tr.ServeDNS(ctx, dnstest.NewRecorder(&test.ResponseWriter{}), q)
+	}
+	if fs := m.FinishedSpans(); len(fs) != 0 {
+		t.Errorf("Unexpected span count: len(fs): want 0, got %v", len(fs))
+	}
+}
+
+func TestSample(t *testing.T) {
+	tr := &trace{every: 3}
+	sampled := 0
+	for i := 0; i < 9; i++ {
+		if tr.Sample() {
+			sampled++
+		}
+	}
+	if sampled != 3 {
+		t.Errorf("Expected 3 of 9 queries to be sampled, got %d", sampled)
+	}
+	if (&trace{}).Sample() {
+		t.Errorf("Expected no queries to be sampled without every")
+	}
+}
@@ -0,0 +1,36 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+)
+
+var errNoClientCert = errors.New("no verified client certificate")
+
+// checkClientCert returns an error if the secondary that asked for the zone transfer in ctx didn't present a
+// client certificate that the server verified, or, if clientIAs are given, if it isn't in one of them. Only
+// transfers over DoQ carry client certificates; the server must request them with the client_auth option of
+// the tls plugin.
+func (x *xfr) checkClientCert(ctx context.Context) error {
+	peer, ok := dnsserver.QUICPeerOf(ctx)
+	if !ok || len(peer.TLS.VerifiedChains) == 0 {
+		return errNoClientCert
+	}
+	if len(x.clientIAs) == 0 {
+		return nil
+	}
+	c, ok := pkgscion.ClientOf(peer.RemoteAddr)
+	if !ok {
+		return fmt.Errorf("client %s has no SCION address", peer.RemoteAddr)
+	}
+	for _, p := range x.clientIAs {
+		if p.Matches(c.IA) {
+			return nil
+		}
+	}
+	return fmt.Errorf("client %s is not in an allowed ISD-AS", c)
+}
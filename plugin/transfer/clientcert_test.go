@@ -0,0 +1,67 @@
+package transfer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
+)
+
+func TestCheckClientCert(t *testing.T) {
+	scionAddr, err := pan.ParseUDPAddr("19-ffaa:1:fe4,[127.0.0.1]:4242")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ipAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4242}
+	verified := tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{new(x509.Certificate)}}}
+	pattern, _ := pkgscion.ParseIAPattern("19-ffaa:1:*")
+
+	tests := []struct {
+		peer      *dnsserver.QUICPeer
+		clientIAs []pkgscion.IAPattern
+		shouldErr bool
+	}{
+		{nil, nil, true},
+		{&dnsserver.QUICPeer{RemoteAddr: ipAddr}, nil, true},
+		{&dnsserver.QUICPeer{RemoteAddr: ipAddr, TLS: verified}, nil, false},
+		{&dnsserver.QUICPeer{RemoteAddr: ipAddr, TLS: verified}, []pkgscion.IAPattern{pattern}, true},
+		{&dnsserver.QUICPeer{RemoteAddr: scionAddr, TLS: verified}, []pkgscion.IAPattern{pattern}, false},
+		{&dnsserver.QUICPeer{RemoteAddr: scionAddr, TLS: verified}, []pkgscion.IAPattern{{ISD: 20}}, true},
+	}
+	for i, tc := range tests {
+		ctx := context.TODO()
+		if tc.peer != nil {
+			ctx = context.WithValue(ctx, dnsserver.QUICPeerKey{}, *tc.peer)
+		}
+		x := &xfr{requireClientCert: true, clientIAs: tc.clientIAs}
+		if err := x.checkClientCert(ctx); (err != nil) != tc.shouldErr {
+			t.Errorf("Test %d: expected error %t, got %v", i, tc.shouldErr, err)
+		}
+	}
+}
+
+func TestTransferRequireClientCert(t *testing.T) {
+	transfer := newTestTransfer()
+	transfer.xfrs[0].requireClientCert = true
+
+	m := new(dns.Msg)
+	m.SetAxfr(transfer.xfrs[0].Zones[0])
+
+	// A transfer over TCP carries no client certificate.
+	w := dnstest.NewMultiRecorder(&test.ResponseWriter{TCP: true})
+	if _, err := transfer.ServeDNS(context.TODO(), w, m); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Msgs) != 1 || w.Msgs[0].Rcode != dns.RcodeRefused {
+		t.Errorf("Expected the transfer to be refused without a client certificate")
+	}
+}
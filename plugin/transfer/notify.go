@@ -1,23 +1,42 @@
 package transfer
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
+	"sync"
+	"time"
 
+	"github.com/coredns/coredns/plugin/pkg/doqclient"
+	"github.com/coredns/coredns/plugin/pkg/parse"
 	"github.com/coredns/coredns/plugin/pkg/rcode"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	"github.com/coredns/coredns/plugin/pkg/transport"
 
 	"github.com/miekg/dns"
+	"github.com/miekg/dns/resolvapi"
 )
 
-// Notify will send notifies to all configured to hosts IP addresses. The string zone must be lowercased.
+// A notify that isn't accepted is sent again notifyRetries times, after notifyBackoff at first and twice as
+// long each time after, up to maxNotifyBackoff.
+var (
+	notifyRetries    = 5
+	notifyBackoff    = 2 * time.Second
+	maxNotifyBackoff = time.Minute
+)
+
+// notifyTimeout bounds a single notify, including dialing a DoQ secondary over SCION.
+const notifyTimeout = 10 * time.Second
+
+// Notify will send notifies to all configured to hosts addresses. The string zone must be lowercased.
+// Hosts with a SCION address, or a quic:// or squic:// address, are notified over DoQ, the others over
+// DNS. Notifies that aren't accepted are queued and sent again with exponential backoff.
 func (t *Transfer) Notify(zone string) error {
 	if t == nil { // t might be nil, mostly expected in tests, so intercept and to a noop in that case
 		return nil
 	}
 
-	m := new(dns.Msg)
-	m.SetNotify(zone)
-	c := new(dns.Client)
-
 	x := longestMatch(t.xfrs, zone)
 	if x == nil {
 		// return without error if there is no matching zone
@@ -25,34 +44,146 @@ func (t *Transfer) Notify(zone string) error {
 	}
 
 	var err1 error
-	for _, t := range x.to {
-		if t == "*" {
+	for _, to := range x.to {
+		if to == "*" {
 			continue
 		}
-		if err := sendNotify(c, m, t); err != nil {
+		// This notify supersedes one that is still queued.
+		t.notifies.cancel(zone, to)
+		if err := x.sendNotify(zone, to); err != nil {
 			err1 = err
+			t.notifies.retry(x, zone, to, 0)
 		}
 	}
 	log.Debugf("Sent notifies for zone %q to %v", zone, x.to)
 	return err1 // this only captures the last error
 }
 
-func sendNotify(c *dns.Client, m *dns.Msg, s string) error {
-	var err error
+// sendNotify sends a notify for zone to the secondary to.
+func (x *xfr) sendNotify(zone, to string) error {
+	m := new(dns.Msg)
+	m.SetNotify(zone)
 
-	code := dns.RcodeServerFailure
-	for i := 0; i < 3; i++ {
-		ret, _, err := c.Exchange(m, s)
-		if err != nil {
-			continue
-		}
-		code = ret.Rcode
-		if code == dns.RcodeSuccess {
-			return nil
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	var (
+		ret *dns.Msg
+		err error
+	)
+	switch trans, addr := parse.Transport(to); trans {
+	case transport.QUIC, transport.SQUIC:
+		// DoQ requires a message ID of 0, RFC 9250 section 4.2.1.
+		m.Id = 0
+		c := doqclient.New(trans, x.tlsConfigFor(trans, addr))
+		if trans == transport.SQUIC {
+			if c.Policy, err = pkgscion.Get().PathPolicy(); err != nil {
+				break
+			}
 		}
+		ret, err = c.Exchange(ctx, m, addr)
+	default:
+		ret, _, err = new(dns.Client).ExchangeContext(ctx, m, addr)
 	}
 	if err != nil {
-		return fmt.Errorf("notify for zone %q was not accepted by %q: %q", m.Question[0].Name, s, err)
+		return fmt.Errorf("notify for zone %q was not accepted by %q: %q", zone, to, err)
+	}
+	if ret.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("notify for zone %q was not accepted by %q: rcode was %q", zone, to, rcode.ToString(ret.Rcode))
+	}
+	return nil
+}
+
+// tlsConfigFor returns the TLS config to notify the DoQ secondary addr with. Without tls_servername, the
+// name of a squic secondary is looked up, a quic secondary must have a certificate for its IP address.
+func (x *xfr) tlsConfigFor(trans, addr string) *tls.Config {
+	cfg := new(tls.Config)
+	if x.tlsConfig != nil {
+		cfg = x.tlsConfig.Clone()
+	}
+	cfg.NextProtos = transport.DoQALPNs
+
+	switch {
+	case x.tlsServerName != "":
+		cfg.ServerName = x.tlsServerName
+	case trans == transport.SQUIC:
+		if names, err := resolvapi.LookupUDPAddr(context.TODO(), addr); err == nil && len(names) > 0 {
+			cfg.ServerName = names[0]
+		}
+	default:
+		cfg.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+	return cfg
+}
+
+// notifyQueue holds the notifies that are sent again, at most one per zone and secondary.
+type notifyQueue struct {
+	mu      sync.Mutex
+	pending map[notifyKey]*time.Timer
+	stopped bool
+}
+
+type notifyKey struct{ zone, to string }
+
+// retry sends the notify for zone to the secondary to again after the backoff of the attempt that failed.
+func (q *notifyQueue) retry(x *xfr, zone, to string, attempt int) {
+	if attempt >= notifyRetries {
+		log.Errorf("Giving up notifying %q of zone %q after %d retries", to, zone, attempt)
+		return
+	}
+	backoff := notifyBackoff << attempt
+	if backoff > maxNotifyBackoff {
+		backoff = maxNotifyBackoff
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.stopped {
+		return
+	}
+	if q.pending == nil {
+		q.pending = make(map[notifyKey]*time.Timer)
+	}
+	key := notifyKey{zone, to}
+	var timer *time.Timer
+	timer = time.AfterFunc(backoff, func() {
+		q.mu.Lock()
+		if q.pending[key] != timer {
+			// Cancelled, or superseded by a newer notify.
+			q.mu.Unlock()
+			return
+		}
+		delete(q.pending, key)
+		q.mu.Unlock()
+
+		if err := x.sendNotify(zone, to); err != nil {
+			log.Warningf("Retry %d: %s", attempt+1, err)
+			q.retry(x, zone, to, attempt+1)
+			return
+		}
+		log.Debugf("Sent notify for zone %q to %q after %d retries", zone, to, attempt+1)
+	})
+	q.pending[key] = timer
+}
+
+// cancel drops the queued notify for zone to the secondary to, if there is one.
+func (q *notifyQueue) cancel(zone, to string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	key := notifyKey{zone, to}
+	if timer, ok := q.pending[key]; ok {
+		timer.Stop()
+		delete(q.pending, key)
+	}
+}
+
+// stop drops all queued notifies, no new ones are queued afterwards.
+func (q *notifyQueue) stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for key, timer := range q.pending {
+		timer.Stop()
+		delete(q.pending, key)
 	}
-	return fmt.Errorf("notify for zone %q was not accepted by %q: rcode was %q", m.Question[0].Name, s, rcode.ToString(code))
+	q.stopped = true
 }
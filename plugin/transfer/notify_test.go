@@ -0,0 +1,63 @@
+package transfer
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+
+	"github.com/miekg/dns"
+)
+
+func TestNotifyRetry(t *testing.T) {
+	defer func(backoff time.Duration) { notifyBackoff = backoff }(notifyBackoff)
+	notifyBackoff = 10 * time.Millisecond
+
+	// The secondary only accepts the third notify.
+	var notifies int32
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if atomic.AddInt32(&notifies, 1) < 3 {
+			m.Rcode = dns.RcodeServerFailure
+		}
+		w.WriteMsg(m)
+	})
+	defer s.Close()
+
+	transfer := &Transfer{xfrs: []*xfr{{Zones: []string{"example.org."}, to: []string{s.Addr}}}}
+	defer transfer.notifies.stop()
+
+	if err := transfer.Notify("example.org."); err == nil {
+		t.Fatal("Expected the first notify to fail")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&notifies) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Give a spurious retry the time to show up.
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt32(&notifies); n != 3 {
+		t.Errorf("Expected 3 notifies, got %d", n)
+	}
+}
+
+func TestNotifyCancel(t *testing.T) {
+	x := &xfr{Zones: []string{"example.org."}, to: []string{"127.0.0.1:0"}}
+	q := new(notifyQueue)
+	q.retry(x, "example.org.", "127.0.0.1:0", 0)
+	if len(q.pending) != 1 {
+		t.Fatalf("Expected a queued notify, got %d", len(q.pending))
+	}
+	q.cancel("example.org.", "127.0.0.1:0")
+	if len(q.pending) != 0 {
+		t.Errorf("Expected no queued notify after cancel, got %d", len(q.pending))
+	}
+
+	q.stop()
+	q.retry(x, "example.org.", "127.0.0.1:0", 0)
+	if len(q.pending) != 0 {
+		t.Errorf("Expected no queued notify after stop, got %d", len(q.pending))
+	}
+}
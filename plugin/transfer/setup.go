@@ -5,7 +5,11 @@ import (
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/parse"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
+	pkgtls "github.com/coredns/coredns/plugin/pkg/tls"
 	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
 )
 
 func init() {
@@ -30,6 +34,7 @@ func setup(c *caddy.Controller) error {
 	c.OnStartup(func() error {
 		config := dnsserver.GetConfig(c)
 		t.tsigSecret = config.TsigSecret
+		t.maxMsgSize = config.MaxMsgSize.Transfer
 		// find all plugins that implement Transferer and add them to Transferers
 		plugins := config.Handlers()
 		for _, pl := range plugins {
@@ -42,6 +47,11 @@ func setup(c *caddy.Controller) error {
 		return nil
 	})
 
+	c.OnShutdown(func() error {
+		t.notifies.stop()
+		return nil
+	})
+
 	return nil
 }
 
@@ -62,12 +72,36 @@ func parseTransfer(c *caddy.Controller) (*Transfer, error) {
 						x.to = append(x.to, host)
 						continue
 					}
-					normalized, err := parse.HostPort(host, transport.Port)
+					normalized, err := parseTo(host)
 					if err != nil {
 						return nil, err
 					}
 					x.to = append(x.to, normalized)
 				}
+			case "tls":
+				args := c.RemainingArgs()
+				if len(args) > 3 {
+					return nil, c.ArgErr()
+				}
+				cfg, err := pkgtls.NewTLSConfigFromArgs(args...)
+				if err != nil {
+					return nil, err
+				}
+				x.tlsConfig = cfg
+			case "tls_servername":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				x.tlsServerName = c.Val()
+			case "require_client_cert":
+				x.requireClientCert = true
+				for _, arg := range c.RemainingArgs() {
+					p, err := pkgscion.ParseIAPattern(arg)
+					if err != nil {
+						return nil, c.Err(err.Error())
+					}
+					x.clientIAs = append(x.clientIAs, p)
+				}
 			default:
 				return nil, plugin.Error("transfer", c.Errf("unknown property %q", c.Val()))
 			}
@@ -79,3 +113,17 @@ func parseTransfer(c *caddy.Controller) (*Transfer, error) {
 	}
 	return t, nil
 }
+
+// parseTo normalizes the address of a secondary. Secondaries with a SCION address, or a squic:// or
+// quic:// address, are notified over DoQ and keep their scheme, the others are notified over DNS.
+func parseTo(host string) (string, error) {
+	trans, addr := parse.Transport(host)
+	if _, err := pan.ParseUDPAddr(addr); err == nil || trans == transport.SQUIC || trans == transport.QUIC {
+		hosts, err := parse.HostPortOrFile(host)
+		if err != nil {
+			return "", err
+		}
+		return hosts[0], nil
+	}
+	return parse.HostPort(host, transport.Port)
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/coredns/caddy"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 )
 
 func TestParse(t *testing.T) {
@@ -31,7 +32,43 @@ func TestParse(t *testing.T) {
 				}},
 			},
 		},
+		{`transfer example.org {
+			to squic://19-ffaa:1:fe4,[127.0.0.1] 19-ffaa:1:fe4,[127.0.0.2]:53 quic://1.2.3.4 1.2.3.5
+			tls_servername ns2.example.org
+		 }`,
+			nil,
+			false,
+			&Transfer{
+				xfrs: []*xfr{{
+					Zones: []string{"example.org."},
+					to:    []string{"squic://19-ffaa:1:fe4,127.0.0.1:8853", "squic://19-ffaa:1:fe4,127.0.0.2:53", "quic://1.2.3.4:8853", "1.2.3.5:53"},
+				}},
+			},
+		},
+		{`transfer example.org {
+			to 19-ffaa:1:fe4,[127.0.0.2]:53
+			require_client_cert 19-ffaa:1:fe4 20-*
+		 }`,
+			nil,
+			false,
+			&Transfer{
+				xfrs: []*xfr{{
+					Zones:             []string{"example.org."},
+					to:                []string{"squic://19-ffaa:1:fe4,127.0.0.2:53"},
+					requireClientCert: true,
+					clientIAs:         []pkgscion.IAPattern{{}, {}},
+				}},
+			},
+		},
 		// errors
+		{`transfer example.org {
+			to 1.2.3.4
+			require_client_cert 19-ffaa:1
+		 }`,
+			nil,
+			true,
+			nil,
+		},
 		{`transfer example.net example.org {
 		 }`,
 			nil,
@@ -109,6 +146,9 @@ func TestParse(t *testing.T) {
 					t.Errorf("Test %d expected %v in 'to', got %v", i, tc.exp.xfrs[j].to[k], to)
 				}
 			}
+			if tc.exp.xfrs[j].requireClientCert != x.requireClientCert || len(tc.exp.xfrs[j].clientIAs) != len(x.clientIAs) {
+				t.Errorf("Test %d expected client certs required %t for %d ISD-ASes, got %t for %d", i, tc.exp.xfrs[j].requireClientCert, len(tc.exp.xfrs[j].clientIAs), x.requireClientCert, len(x.clientIAs))
+			}
 		}
 	}
 }
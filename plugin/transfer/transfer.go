@@ -2,11 +2,16 @@ package transfer
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
+	"fmt"
 	"net"
 
+	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/parse"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -19,12 +24,23 @@ type Transfer struct {
 	Transferers []Transferer // List of plugins that implement Transferer
 	xfrs        []*xfr
 	tsigSecret  map[string]string
+	maxMsgSize  int // maximum size of a single transfer message, zero means no limit besides dns.MaxMsgSize
+	notifies    notifyQueue
 	Next        plugin.Handler
 }
 
 type xfr struct {
 	Zones []string
 	to    []string
+
+	// tlsConfig and tlsServerName, if set, verify the secondaries that are notified over DoQ.
+	tlsConfig     *tls.Config
+	tlsServerName string
+
+	// requireClientCert refuses transfers to secondaries without a verified client certificate. With
+	// clientIAs, their SCION address must be in one of them as well.
+	requireClientCert bool
+	clientIAs         []pkgscion.IAPattern
 }
 
 // Transferer may be implemented by plugins to enable zone transfers
@@ -59,7 +75,8 @@ func (t *Transfer) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 		return plugin.NextOrFailure(t.Name(), t.Next, ctx, w, r)
 	}
 
-	if proto := state.Proto(); proto != "tcp" && proto != "squic" {
+	// Over DoQ the messages of the transfer go on the stream of the query, over IP as well as over SCION.
+	if proto := state.Proto(); proto != "tcp" && proto != "squic" && !dnsserver.XFRStream(ctx) {
 		return dns.RcodeRefused, nil
 	}
 
@@ -75,6 +92,15 @@ func (t *Transfer) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 		w.WriteMsg(m)
 		return 0, nil
 	}
+	if x.requireClientCert {
+		if err := x.checkClientCert(ctx); err != nil {
+			log.Warningf("Refused transfer of zone %q to %s: %s", state.QName(), state.IP(), err)
+			m := new(dns.Msg)
+			m.SetRcode(r, dns.RcodeRefused)
+			w.WriteMsg(m)
+			return 0, nil
+		}
+	}
 
 	// Get serial from request if this is an IXFR.
 	var serial uint32
@@ -124,12 +150,34 @@ func (t *Transfer) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 
 	rrs := []dns.RR{}
 	l := 0
+	size := 0
 	var soa *dns.SOA
 	for records := range pchan {
 		if x, ok := records[0].(*dns.SOA); ok && soa == nil {
 			soa = x
 		}
-		rrs = append(rrs, records...)
+		for _, rr := range records {
+			rrSize := 0
+			if t.maxMsgSize > 0 {
+				rrSize = rrLen(rr)
+				if rrSize > t.maxMsgSize-headerLen {
+					return dns.RcodeServerFailure, fmt.Errorf("record %q of %d bytes doesn't fit into the maximum transfer message size of %d", rr.Header().Name, rrSize, t.maxMsgSize)
+				}
+				// Flush before the message would exceed the maximum size.
+				if len(rrs) > 0 && headerLen+size+rrSize > t.maxMsgSize {
+					select {
+					case ch <- &dns.Envelope{RR: rrs}:
+					case err := <-errCh:
+						return dns.RcodeServerFailure, err
+					}
+					l += len(rrs)
+					rrs = []dns.RR{}
+					size = 0
+				}
+			}
+			rrs = append(rrs, rr)
+			size += rrSize
+		}
 		if len(rrs) > 500 {
 			select {
 			case ch <- &dns.Envelope{RR: rrs}:
@@ -138,6 +186,7 @@ func (t *Transfer) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 			}
 			l += len(rrs)
 			rrs = []dns.RR{}
+			size = 0
 		}
 	}
 
@@ -183,14 +232,25 @@ func (t *Transfer) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 	return 0, nil
 }
 
+// headerLen is the size of the header and the question of a transfer message. The question can't be
+// larger than the maximum length of a name plus type and class.
+const headerLen = 12 + 255 + 4
+
+// rrLen returns the uncompressed wire length of rr.
+func rrLen(rr dns.RR) int {
+	m := &dns.Msg{Answer: []dns.RR{rr}}
+	return m.Len() - 12
+}
+
 func (x xfr) allowed(state request.Request) bool {
 	for _, h := range x.to {
 		if h == "*" {
 			return true
 		}
+		_, h = parse.Transport(h)
 		to, _, err := net.SplitHostPort(h)
 		if err != nil {
-			return false
+			continue
 		}
 		// If remote IP matches we accept. TODO(): make this works with ranges
 		if to == state.IP() {
@@ -218,4 +278,4 @@ func longestMatch(xfrs []*xfr, name string) *xfr {
 }
 
 // Name implements the Handler interface.
-func (Transfer) Name() string { return "transfer" }
+func (t *Transfer) Name() string { return "transfer" }
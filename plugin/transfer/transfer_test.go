@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
 	"github.com/coredns/coredns/plugin/test"
@@ -164,6 +165,28 @@ func TestTransferAXFRExampleCom(t *testing.T) {
 	validateAXFRResponse(t, w)
 }
 
+func TestTransferAXFRDoQ(t *testing.T) {
+	transfer := newTestTransfer()
+
+	m := &dns.Msg{}
+	m.SetAxfr(transfer.xfrs[0].Zones[0])
+
+	// A transfer over UDP is refused.
+	w := dnstest.NewMultiRecorder(&test.ResponseWriter{})
+	if rcode, _ := transfer.ServeDNS(context.TODO(), w, m); rcode != dns.RcodeRefused {
+		t.Errorf("Expected REFUSED over UDP, got %s", dns.RcodeToString[rcode])
+	}
+
+	// A quic server over IP has UDP addresses too, its transfers are answered on the stream of the query.
+	ctx := context.WithValue(context.TODO(), dnsserver.XFRStreamKey{}, true)
+	w = dnstest.NewMultiRecorder(&test.ResponseWriter{})
+	if _, err := transfer.ServeDNS(ctx, w, m); err != nil {
+		t.Error(err)
+	}
+
+	validateAXFRResponse(t, w)
+}
+
 func TestTransferIXFRCurrent(t *testing.T) {
 	transfer := newTestTransfer()
 
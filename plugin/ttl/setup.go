@@ -0,0 +1,100 @@
+package ttl
+
+import (
+	"strconv"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+)
+
+func init() { plugin.Register("ttl", setup) }
+
+func setup(c *caddy.Controller) error {
+	t, err := parse(c)
+	if err != nil {
+		return plugin.Error("ttl", err)
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		t.Next = next
+		return t
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller) (TTL, error) {
+	t := TTL{}
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return t, plugin.ErrOnce
+		}
+		i++
+
+		if len(c.RemainingArgs()) > 0 {
+			return t, c.ArgErr()
+		}
+
+		b := 0
+		for c.NextBlock() {
+			switch c.Val() {
+			case "positive":
+				clamp, err := parseClamp(c)
+				if err != nil {
+					return t, err
+				}
+				t.positive = clamp
+			case "negative":
+				clamp, err := parseClamp(c)
+				if err != nil {
+					return t, err
+				}
+				t.negative = clamp
+			case "clients":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return t, c.ArgErr()
+				}
+				switch args[0] {
+				case "all":
+					t.all = true
+				case "scion":
+					t.all = false
+				default:
+					return t, c.Errf("unknown clients '%s', expected 'scion' or 'all'", args[0])
+				}
+			default:
+				return t, c.Errf("unknown property '%s'", c.Val())
+			}
+			b++
+		}
+
+		if b == 0 {
+			return t, c.Err("ttl block with no options specified")
+		}
+	}
+	return t, nil
+}
+
+// parseClamp parses the MIN and MAX arguments of a positive or negative property.
+func parseClamp(c *caddy.Controller) (Clamp, error) {
+	args := c.RemainingArgs()
+	if len(args) != 2 {
+		return Clamp{}, c.ArgErr()
+	}
+	min, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		return Clamp{}, c.Errf("invalid minimum TTL '%s': %s", args[0], err)
+	}
+	max, err := strconv.ParseUint(args[1], 10, 32)
+	if err != nil {
+		return Clamp{}, c.Errf("invalid maximum TTL '%s': %s", args[1], err)
+	}
+	if max > 0 && min > max {
+		return Clamp{}, c.Errf("minimum TTL %d is larger than maximum TTL %d", min, max)
+	}
+	return Clamp{Min: uint32(min), Max: uint32(max)}, nil
+}
@@ -0,0 +1,85 @@
+package ttl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input              string
+		shouldErr          bool
+		expectedPositive   Clamp
+		expectedNegative   Clamp
+		expectedAll        bool
+		expectedErrContent string
+	}{
+		{`ttl {
+			positive 60 3600
+		}`, false, Clamp{60, 3600}, Clamp{}, false, ""},
+		{`ttl {
+			positive 0 300
+			negative 30 60
+			clients all
+		}`, false, Clamp{0, 300}, Clamp{30, 60}, true, ""},
+		{`ttl {
+			negative 300 0
+			clients scion
+		}`, false, Clamp{}, Clamp{300, 0}, false, ""},
+		// fails
+		{`ttl`, true, Clamp{}, Clamp{}, false, "no options specified"},
+		{`ttl 60`, true, Clamp{}, Clamp{}, false, "Wrong argument count"},
+		{`ttl {
+			positive 60
+		}`, true, Clamp{}, Clamp{}, false, "Wrong argument count"},
+		{`ttl {
+			positive 3600 60
+		}`, true, Clamp{}, Clamp{}, false, "is larger than maximum TTL"},
+		{`ttl {
+			negative -1 60
+		}`, true, Clamp{}, Clamp{}, false, "invalid minimum TTL"},
+		{`ttl {
+			clients some
+		}`, true, Clamp{}, Clamp{}, false, "unknown clients"},
+		{`ttl {
+			giraffe 1 2
+		}`, true, Clamp{}, Clamp{}, false, "unknown property"},
+		{`ttl {
+			positive 1 2
+		}
+		ttl {
+			positive 1 2
+		}`, true, Clamp{}, Clamp{}, false, "plugin can only be used once"},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		tt, err := parse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error but found none for input %s", i, test.input)
+		}
+
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			}
+			if !strings.Contains(err.Error(), test.expectedErrContent) {
+				t.Errorf("Test %d: Expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
+			}
+			continue
+		}
+
+		if tt.positive != test.expectedPositive {
+			t.Errorf("Test %d: Expected positive clamp %v, got %v", i, test.expectedPositive, tt.positive)
+		}
+		if tt.negative != test.expectedNegative {
+			t.Errorf("Test %d: Expected negative clamp %v, got %v", i, test.expectedNegative, tt.negative)
+		}
+		if tt.all != test.expectedAll {
+			t.Errorf("Test %d: Expected all %t, got %t", i, test.expectedAll, tt.all)
+		}
+	}
+}
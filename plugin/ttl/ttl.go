@@ -0,0 +1,99 @@
+// Package ttl implements a plugin that clamps the TTLs of the answers sent to (SCION) clients.
+package ttl
+
+import (
+	"context"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/response"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// Clamp holds the lower and upper bound for TTLs, a zero value means no bound.
+type Clamp struct {
+	Min uint32
+	Max uint32
+}
+
+// apply returns ttl bounded by c.
+func (c Clamp) apply(ttl uint32) uint32 {
+	if c.Min > 0 && ttl < c.Min {
+		ttl = c.Min
+	}
+	if c.Max > 0 && ttl > c.Max {
+		ttl = c.Max
+	}
+	return ttl
+}
+
+// TTL is the ttl plugin.
+type TTL struct {
+	Next plugin.Handler
+
+	positive Clamp
+	negative Clamp
+	// all applies the clamps to all clients, not only those connecting over SCION.
+	all bool
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (t TTL) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	if !t.all && state.Proto() != "squic" {
+		return plugin.NextOrFailure(t.Name(), t.Next, ctx, w, r)
+	}
+	return plugin.NextOrFailure(t.Name(), t.Next, ctx, &ResponseWriter{ResponseWriter: w, ttl: t}, r)
+}
+
+// Name implements the plugin.Handler interface.
+func (t TTL) Name() string { return "ttl" }
+
+// ResponseWriter clamps the TTLs of the response before writing it.
+type ResponseWriter struct {
+	dns.ResponseWriter
+	ttl TTL
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
+	ty, _ := response.Typify(res, time.Now().UTC())
+	switch ty {
+	case response.NoError, response.Delegation:
+		res.Answer = clamp(res.Answer, w.ttl.positive)
+		res.Ns = clamp(res.Ns, w.ttl.positive)
+		res.Extra = clamp(res.Extra, w.ttl.positive)
+	case response.NameError, response.NoData:
+		res.Ns = clamp(res.Ns, w.ttl.negative)
+	}
+	return w.ResponseWriter.WriteMsg(res)
+}
+
+// clamp returns rrs with the TTLs bounded by c, the OPT pseudo record is left alone. Both the slice
+// and the records may be shared with a zone or the cache, so they are copied instead of modified in
+// place.
+func clamp(rrs []dns.RR, c Clamp) []dns.RR {
+	var clamped []dns.RR
+	for i, rr := range rrs {
+		if rr.Header().Rrtype == dns.TypeOPT {
+			continue
+		}
+		ttl := c.apply(rr.Header().Ttl)
+		if ttl == rr.Header().Ttl {
+			continue
+		}
+		if clamped == nil {
+			clamped = make([]dns.RR, len(rrs))
+			copy(clamped, rrs)
+		}
+		rr = dns.Copy(rr)
+		rr.Header().Ttl = ttl
+		clamped[i] = rr
+	}
+	if clamped == nil {
+		return rrs
+	}
+	return clamped
+}
@@ -0,0 +1,120 @@
+package ttl
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestTTL(t *testing.T) {
+	tests := []struct {
+		ttl      TTL
+		rcode    int
+		answer   []dns.RR
+		ns       []dns.RR
+		expected []uint32 // TTLs of answer and ns, in order
+	}{
+		{
+			ttl:      TTL{positive: Clamp{Min: 60, Max: 3600}, all: true},
+			answer:   []dns.RR{test.A("example.org. 5 IN A 127.0.0.1"), test.A("example.org. 86400 IN A 127.0.0.2")},
+			expected: []uint32{60, 3600},
+		},
+		{
+			ttl:      TTL{positive: Clamp{Min: 60}, all: true},
+			answer:   []dns.RR{test.A("example.org. 30 IN A 127.0.0.1"), test.A("example.org. 86400 IN A 127.0.0.2")},
+			expected: []uint32{60, 86400},
+		},
+		{
+			ttl:      TTL{positive: Clamp{Min: 60, Max: 3600}, negative: Clamp{Max: 30}, all: true},
+			rcode:    dns.RcodeNameError,
+			ns:       []dns.RR{test.SOA("example.org. 1800 IN SOA ns.example.org. admin.example.org. 1 3600 600 86400 1800")},
+			expected: []uint32{30},
+		},
+		{
+			ttl:      TTL{negative: Clamp{Min: 300}, all: true},
+			ns:       []dns.RR{test.SOA("example.org. 10 IN SOA ns.example.org. admin.example.org. 1 3600 600 86400 10")},
+			expected: []uint32{300},
+		},
+		{
+			// not a SCION client, nothing is clamped
+			ttl:      TTL{positive: Clamp{Min: 60, Max: 3600}},
+			answer:   []dns.RR{test.A("example.org. 5 IN A 127.0.0.1")},
+			expected: []uint32{5},
+		},
+	}
+
+	for i, tc := range tests {
+		tc.ttl.Next = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+			m := new(dns.Msg)
+			m.SetRcode(r, tc.rcode)
+			m.Answer = tc.answer
+			m.Ns = tc.ns
+			w.WriteMsg(m)
+			return tc.rcode, nil
+		})
+
+		req := new(dns.Msg)
+		req.SetQuestion("example.org.", dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := tc.ttl.ServeDNS(context.TODO(), rec, req); err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+
+		got := []uint32{}
+		for _, rr := range append(rec.Msg.Answer, rec.Msg.Ns...) {
+			got = append(got, rr.Header().Ttl)
+		}
+		if len(got) != len(tc.expected) {
+			t.Fatalf("Test %d: expected %d records, got %d", i, len(tc.expected), len(got))
+		}
+		for j := range got {
+			if got[j] != tc.expected[j] {
+				t.Errorf("Test %d: expected TTL %d for record %d, got %d", i, tc.expected[j], j, got[j])
+			}
+		}
+	}
+}
+
+const zone = `$ORIGIN example.org.
+@	3600 IN	SOA	ns.example.org. admin.example.org. 1 3600 600 86400 1800
+	3600 IN	NS	ns.example.org.
+ns	5    IN	A	127.0.0.1
+`
+
+func TestTTLZoneUnchanged(t *testing.T) {
+	z, err := file.Parse(strings.NewReader(zone), "example.org.", "stdin", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fm := file.File{Next: test.ErrorHandler(), Zones: file.Zones{Z: map[string]*file.Zone{"example.org.": z}, Names: []string{"example.org."}}}
+	tt := TTL{Next: fm, positive: Clamp{Min: 60}, all: true}
+
+	// The second query would get a TTL of 60 from the zone as well if the first one changed it.
+	for _, min := range []uint32{60, 120} {
+		tt.positive.Min = min
+		req := new(dns.Msg)
+		req.SetQuestion("ns.example.org.", dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := tt.ServeDNS(context.TODO(), rec, req); err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+		if len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].Header().Ttl != min {
+			t.Fatalf("Expected an answer with TTL %d, got %v", min, rec.Msg.Answer)
+		}
+	}
+
+	for _, e := range z.All() {
+		for _, rr := range e.All() {
+			if rr.Header().Name == "ns.example.org." && rr.Header().Ttl != 5 {
+				t.Errorf("Expected the zone's TTL of 5 to be unchanged, got %d", rr.Header().Ttl)
+			}
+		}
+	}
+}
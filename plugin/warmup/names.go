@@ -0,0 +1,112 @@
+package warmup
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// entry is a recorded question.
+type entry struct {
+	name  string
+	qtype uint16
+}
+
+// names holds the most recently served distinct questions, up to size.
+type names struct {
+	size int
+
+	mu    sync.Mutex
+	ring  []entry
+	next  int
+	index map[entry]struct{}
+}
+
+func newNames(size int) *names {
+	return &names{size: size, index: make(map[entry]struct{}, size)}
+}
+
+// add records a question, if it isn't recorded yet. Once full the oldest question is dropped.
+func (n *names) add(name string, qtype uint16) {
+	e := entry{strings.ToLower(name), qtype}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, ok := n.index[e]; ok {
+		return
+	}
+	if len(n.ring) < n.size {
+		n.ring = append(n.ring, e)
+	} else {
+		delete(n.index, n.ring[n.next])
+		n.ring[n.next] = e
+		n.next = (n.next + 1) % n.size
+	}
+	n.index[e] = struct{}{}
+}
+
+// list returns the recorded questions, oldest first.
+func (n *names) list() []entry {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	l := make([]entry, 0, len(n.ring))
+	l = append(l, n.ring[n.next:]...)
+	return append(l, n.ring[:n.next]...)
+}
+
+// store writes entries to file, one "NAME TYPE" per line. The file is replaced atomically.
+func store(file string, entries []entry) error {
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s %s\n", e.name, dns.Type(e.qtype))
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// load reads at most max entries from file. A file that doesn't exist holds no entries.
+func load(file string, max int) ([]entry, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []entry
+	s := bufio.NewScanner(f)
+	for s.Scan() && len(entries) < max {
+		fields := strings.Fields(s.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		qtype, ok := dns.StringToType[fields[1]]
+		if !ok {
+			continue
+		}
+		if _, ok := dns.IsDomainName(fields[0]); !ok {
+			continue
+		}
+		entries = append(entries, entry{dns.Fqdn(fields[0]), qtype})
+	}
+	return entries, s.Err()
+}
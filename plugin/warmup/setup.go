@@ -0,0 +1,118 @@
+package warmup
+
+import (
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/durations"
+)
+
+func init() { plugin.Register(pluginName, setup) }
+
+const (
+	defaultSize        = 1000
+	defaultInterval    = 5 * time.Minute
+	defaultTimeout     = 30 * time.Second
+	defaultConcurrency = 10
+)
+
+func setup(c *caddy.Controller) error {
+	wu, err := parse(c)
+	if err != nil {
+		return plugin.Error(pluginName, err)
+	}
+
+	c.OnStartup(func() error {
+		go wu.replay()
+		go wu.save()
+		return nil
+	})
+	c.OnShutdown(func() error {
+		close(wu.stop)
+		if err := store(wu.file, wu.names.list()); err != nil {
+			log.Warningf("Failed to save names: %s", err)
+		}
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		wu.Next = next
+		return wu
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller) (*Warmup, error) {
+	wu := &Warmup{
+		interval:    defaultInterval,
+		timeout:     defaultTimeout,
+		concurrency: defaultConcurrency,
+		stop:        make(chan struct{}),
+	}
+	size := defaultSize
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		args := c.RemainingArgs()
+		if len(args) != 1 {
+			return nil, c.ArgErr()
+		}
+		wu.file = args[0]
+		if root := dnsserver.GetConfig(c).Root; !filepath.IsAbs(wu.file) && root != "" {
+			wu.file = filepath.Join(root, wu.file)
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "size", "concurrency":
+				prop := c.Val()
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n <= 0 {
+					return nil, c.Errf("invalid %s '%s'", prop, args[0])
+				}
+				if prop == "size" {
+					size = n
+				} else {
+					wu.concurrency = n
+				}
+			case "interval", "timeout":
+				prop := c.Val()
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				dur, err := durations.NewDurationFromArg(args[0])
+				if err != nil {
+					return nil, c.Err(err.Error())
+				}
+				if dur <= 0 {
+					return nil, c.Errf("%s '%s' must be positive", prop, args[0])
+				}
+				if prop == "interval" {
+					wu.interval = dur
+				} else {
+					wu.timeout = dur
+				}
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+
+	wu.names = newNames(size)
+	return wu, nil
+}
@@ -0,0 +1,85 @@
+package warmup
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input               string
+		shouldErr           bool
+		expectedFile        string
+		expectedSize        int
+		expectedInterval    time.Duration
+		expectedTimeout     time.Duration
+		expectedConcurrency int
+		expectedErrContent  string
+	}{
+		{`warmup /var/lib/coredns/warmup`, false, "/var/lib/coredns/warmup", 1000, 5 * time.Minute, 30 * time.Second, 10, ""},
+		{`warmup /var/lib/coredns/warmup {
+			size 500
+			interval 1m
+			timeout 2m
+			concurrency 50
+		}`, false, "/var/lib/coredns/warmup", 500, time.Minute, 2 * time.Minute, 50, ""},
+		// fails
+		{`warmup`, true, "", 0, 0, 0, 0, "Wrong argument count"},
+		{`warmup a b`, true, "", 0, 0, 0, 0, "Wrong argument count"},
+		{`warmup /tmp/warmup {
+			size 0
+		}`, true, "", 0, 0, 0, 0, "invalid size"},
+		{`warmup /tmp/warmup {
+			concurrency many
+		}`, true, "", 0, 0, 0, 0, "invalid concurrency"},
+		{`warmup /tmp/warmup {
+			interval 0s
+		}`, true, "", 0, 0, 0, 0, "must be positive"},
+		{`warmup /tmp/warmup {
+			timeout
+		}`, true, "", 0, 0, 0, 0, "Wrong argument count"},
+		{`warmup /tmp/warmup {
+			giraffe
+		}`, true, "", 0, 0, 0, 0, "unknown property"},
+		{`warmup /tmp/warmup
+		warmup /tmp/warmup`, true, "", 0, 0, 0, 0, "plugin can only be used once"},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		wu, err := parse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error but found none for input %s", i, test.input)
+		}
+
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			}
+			if !strings.Contains(err.Error(), test.expectedErrContent) {
+				t.Errorf("Test %d: Expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
+			}
+			continue
+		}
+
+		if wu.file != test.expectedFile {
+			t.Errorf("Test %d: Expected file %s, got %s", i, test.expectedFile, wu.file)
+		}
+		if wu.names.size != test.expectedSize {
+			t.Errorf("Test %d: Expected size %d, got %d", i, test.expectedSize, wu.names.size)
+		}
+		if wu.interval != test.expectedInterval {
+			t.Errorf("Test %d: Expected interval %s, got %s", i, test.expectedInterval, wu.interval)
+		}
+		if wu.timeout != test.expectedTimeout {
+			t.Errorf("Test %d: Expected timeout %s, got %s", i, test.expectedTimeout, wu.timeout)
+		}
+		if wu.concurrency != test.expectedConcurrency {
+			t.Errorf("Test %d: Expected concurrency %d, got %d", i, test.expectedConcurrency, wu.concurrency)
+		}
+	}
+}
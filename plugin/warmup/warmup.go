@@ -0,0 +1,124 @@
+// Package warmup implements a plugin that replays recently served names after a restart, to warm the
+// caches of the plugins after it.
+package warmup
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+const pluginName = "warmup"
+
+var log = clog.NewWithPlugin(pluginName)
+
+// Warmup records the names it serves and replays them on startup.
+type Warmup struct {
+	Next plugin.Handler
+
+	file        string
+	interval    time.Duration
+	timeout     time.Duration
+	concurrency int
+
+	names *names
+	ready atomic.Bool
+	stop  chan struct{}
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (wu *Warmup) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	rec := dnstest.NewRecorder(w)
+	rcode, err := plugin.NextOrFailure(pluginName, wu.Next, ctx, rec, r)
+	if rec.Msg != nil && (rec.Rcode == dns.RcodeSuccess || rec.Rcode == dns.RcodeNameError) && state.QClass() == dns.ClassINET {
+		wu.names.add(state.Name(), state.QType())
+	}
+	return rcode, err
+}
+
+// Name implements the plugin.Handler interface.
+func (wu *Warmup) Name() string { return pluginName }
+
+// Ready implements the ready.Readiness interface, it returns true once the names are replayed.
+func (wu *Warmup) Ready() bool { return wu.ready.Load() }
+
+// replay sends queries for the names saved in the file through the plugins after warmup.
+func (wu *Warmup) replay() {
+	defer wu.ready.Store(true)
+
+	entries, err := load(wu.file, wu.names.size)
+	if err != nil {
+		log.Warningf("Not replaying names: %s", err)
+		return
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), wu.timeout)
+	defer cancel()
+	start := time.Now()
+	var (
+		wg       sync.WaitGroup
+		replayed atomic.Int64
+		sem      = make(chan struct{}, wu.concurrency)
+	)
+Replay:
+	for _, e := range entries {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break Replay
+		}
+		wg.Add(1)
+		go func(e entry) {
+			defer func() { <-sem; wg.Done() }()
+			m := new(dns.Msg)
+			m.SetQuestion(e.name, e.qtype)
+			if _, err := wu.Next.ServeDNS(ctx, &replayWriter{}, m); err == nil {
+				replayed.Add(1)
+			}
+		}(e)
+	}
+	wg.Wait()
+	log.Infof("Replayed %d of %d names in %s", replayed.Load(), len(entries), time.Since(start).Round(time.Millisecond))
+}
+
+// save writes the recorded names to the file, every interval until the plugin is stopped.
+func (wu *Warmup) save() {
+	ticker := time.NewTicker(wu.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-wu.stop:
+			return
+		case <-ticker.C:
+			if err := store(wu.file, wu.names.list()); err != nil {
+				log.Warningf("Failed to save names: %s", err)
+			}
+		}
+	}
+}
+
+// replayWriter discards the responses to replayed queries.
+type replayWriter struct{}
+
+func (*replayWriter) LocalAddr() net.Addr         { return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 53} }
+func (*replayWriter) RemoteAddr() net.Addr        { return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 53} }
+func (*replayWriter) WriteMsg(*dns.Msg) error     { return nil }
+func (*replayWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (*replayWriter) Close() error                { return nil }
+func (*replayWriter) TsigStatus() error           { return nil }
+func (*replayWriter) TsigTimersOnly(bool)         {}
+func (*replayWriter) Hijack()                     {}
+func (*replayWriter) SupportsMultiMsg() bool      { return false }
@@ -0,0 +1,92 @@
+package warmup
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestNames(t *testing.T) {
+	n := newNames(2)
+	n.add("a.example.org.", dns.TypeA)
+	n.add("A.example.org.", dns.TypeA)
+	n.add("b.example.org.", dns.TypeAAAA)
+	n.add("c.example.org.", dns.TypeA)
+
+	expected := []entry{{"b.example.org.", dns.TypeAAAA}, {"c.example.org.", dns.TypeA}}
+	l := n.list()
+	if len(l) != len(expected) {
+		t.Fatalf("Expected %d names, got %d", len(expected), len(l))
+	}
+	for i := range l {
+		if l[i] != expected[i] {
+			t.Errorf("Expected name %d to be %v, got %v", i, expected[i], l[i])
+		}
+	}
+}
+
+func TestStoreLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "warmup")
+	if entries, err := load(file, 10); err != nil || len(entries) != 0 {
+		t.Fatalf("Expected no names from a missing file, got %v, %v", entries, err)
+	}
+
+	entries := []entry{{"a.example.org.", dns.TypeA}, {"b.example.org.", dns.TypeMX}, {"c.example.org.", dns.TypeTXT}}
+	if err := store(file, entries); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := load(file, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 2 || loaded[0] != entries[0] || loaded[1] != entries[1] {
+		t.Errorf("Expected %v, got %v", entries[:2], loaded)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "warmup")
+	if err := store(file, []entry{{"a.example.org.", dns.TypeA}, {"b.example.org.", dns.TypeAAAA}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu      sync.Mutex
+		queried []string
+	)
+	wu := &Warmup{file: file, timeout: 5 * time.Second, concurrency: 1, names: newNames(10)}
+	wu.Next = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		mu.Lock()
+		queried = append(queried, r.Question[0].Name)
+		mu.Unlock()
+		m := new(dns.Msg)
+		m.SetReply(r)
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+
+	if wu.Ready() {
+		t.Errorf("Expected warmup not to be ready before the replay")
+	}
+	wu.replay()
+	if !wu.Ready() {
+		t.Errorf("Expected warmup to be ready after the replay")
+	}
+	if len(queried) != 2 {
+		t.Errorf("Expected 2 replayed queries, got %v", queried)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("c.example.org.", dns.TypeA)
+	wu.ServeDNS(context.TODO(), &test.ResponseWriter{}, m)
+	if l := wu.names.list(); len(l) != 1 || l[0].name != "c.example.org." {
+		t.Errorf("Expected c.example.org. to be recorded, got %v", l)
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"context"
 	"net"
 	"strconv"
+	"strings"
 
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -15,7 +17,7 @@ import (
 const name = "whoami"
 
 // Whoami is a plugin that returns your IP address, port and the protocol used for connecting
-// to CoreDNS.
+// to CoreDNS. For SCION clients it returns the SCION address and the path instead.
 type Whoami struct{}
 
 // ServeDNS implements the plugin.Handler interface.
@@ -26,6 +28,12 @@ func (wh Whoami) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 	a.SetReply(r)
 	a.Authoritative = true
 
+	if client, ok := pkgscion.ClientOf(w.RemoteAddr()); ok {
+		a.Extra = scionExtra(state, client)
+		w.WriteMsg(a)
+		return 0, nil
+	}
+
 	ip := state.IP()
 	var rr dns.RR
 
@@ -40,21 +48,70 @@ func (wh Whoami) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 		rr.(*dns.AAAA).AAAA = net.ParseIP(ip)
 	}
 
-	srv := new(dns.SRV)
-	srv.Hdr = dns.RR_Header{Name: "_" + state.Proto() + "." + state.QName(), Rrtype: dns.TypeSRV, Class: state.QClass()}
-	if state.QName() == "." {
-		srv.Hdr.Name = "_" + state.Proto() + state.QName()
-	}
 	port, _ := strconv.ParseUint(state.Port(), 10, 16)
-	srv.Port = uint16(port)
-	srv.Target = "."
-
-	a.Extra = []dns.RR{rr, srv}
+	a.Extra = []dns.RR{rr, srvRecord(state, "_"+state.Proto(), uint16(port))}
 
 	w.WriteMsg(a)
 
 	return 0, nil
 }
 
+// scionExtra returns the records describing a SCION client: its address as a TXT record, the path its
+// query came in on, if known, as a TXT record and the port and transport as a SRV record.
+func scionExtra(state request.Request, client pkgscion.Client) []dns.RR {
+	txt := new(dns.TXT)
+	txt.Hdr = dns.RR_Header{Name: state.QName(), Rrtype: dns.TypeTXT, Class: state.QClass()}
+	txt.Txt = []string{client.String()}
+	extra := []dns.RR{txt}
+
+	if client.Path != "" {
+		path := new(dns.TXT)
+		path.Hdr = dns.RR_Header{Name: ownerName(state, "_path"), Rrtype: dns.TypeTXT, Class: state.QClass()}
+		if client.Route != "" {
+			path.Txt = splitRoute(client.Route)
+		}
+		path.Txt = append(path.Txt, client.Path)
+		extra = append(extra, path)
+	}
+
+	return append(extra, srvRecord(state, "_"+state.Proto(), client.Port))
+}
+
+// maxTXT is the maximum length of a character string in a TXT record.
+const maxTXT = 255
+
+// splitRoute splits route into strings of at most maxTXT bytes, breaking it between hops where it can.
+func splitRoute(route string) []string {
+	var parts []string
+	for len(route) > maxTXT {
+		i := strings.LastIndexByte(route[:maxTXT+1], ' ')
+		if i <= 0 {
+			parts = append(parts, route[:maxTXT])
+			route = route[maxTXT:]
+			continue
+		}
+		parts = append(parts, route[:i])
+		route = route[i+1:]
+	}
+	return append(parts, route)
+}
+
+// srvRecord returns the SRV record for port below the query name.
+func srvRecord(state request.Request, label string, port uint16) *dns.SRV {
+	srv := new(dns.SRV)
+	srv.Hdr = dns.RR_Header{Name: ownerName(state, label), Rrtype: dns.TypeSRV, Class: state.QClass()}
+	srv.Port = port
+	srv.Target = "."
+	return srv
+}
+
+// ownerName returns label prepended to the query name.
+func ownerName(state request.Request, label string) string {
+	if state.QName() == "." {
+		return label + state.QName()
+	}
+	return label + "." + state.QName()
+}
+
 // Name implements the Handler interface.
 func (wh Whoami) Name() string { return name }
@@ -2,12 +2,18 @@ package whoami
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	pkgscion "github.com/coredns/coredns/plugin/pkg/scion"
 	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
+	"github.com/netsec-ethz/scion-apps/pkg/pan"
 )
 
 func TestWhoami(t *testing.T) {
@@ -79,3 +85,97 @@ func TestWhoami(t *testing.T) {
 		}
 	}
 }
+
+func TestWhoamiSCION(t *testing.T) {
+	w := &test.ResponseWriter{RemoteSCION: "19-ffaa:1:1067,[10.0.0.1]:40000"}
+	remote := w.RemoteAddr().(pan.UDPAddr)
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(w)
+	if _, err := (Whoami{}).ServeDNS(context.TODO(), rec, req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(rec.Msg.Extra) != 2 {
+		t.Fatalf("Expected 2 records in the additional section, got %d", len(rec.Msg.Extra))
+	}
+	txt, ok := rec.Msg.Extra[0].(*dns.TXT)
+	if !ok {
+		t.Fatalf("Expected a TXT record, got %s", rec.Msg.Extra[0])
+	}
+	if expected := "19-ffaa:1:1067,[10.0.0.1]:40000"; len(txt.Txt) != 1 || txt.Txt[0] != expected {
+		t.Errorf("Expected TXT %q, got %v", expected, txt.Txt)
+	}
+	srv, ok := rec.Msg.Extra[1].(*dns.SRV)
+	if !ok {
+		t.Fatalf("Expected a SRV record, got %s", rec.Msg.Extra[1])
+	}
+	if srv.Port != 40000 || srv.Hdr.Name != "_udp.example.org." {
+		t.Errorf("Expected _udp.example.org. SRV with port 40000, got %s", srv)
+	}
+
+	// Once the squic listener's selector recorded the path of a packet from the client, it's in the
+	// reply. pan's reply paths only have a fingerprint, the route needs the daemon's metadata.
+	s, err := pkgscion.Defaults{}.NewReplySelector()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Record(remote, &pan.Path{Destination: remote.IA, Fingerprint: "1 4 5 12"})
+
+	rec = dnstest.NewRecorder(w)
+	if _, err := (Whoami{}).ServeDNS(context.TODO(), rec, req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(rec.Msg.Extra) != 3 {
+		t.Fatalf("Expected 3 records in the additional section, got %d", len(rec.Msg.Extra))
+	}
+	path, ok := rec.Msg.Extra[1].(*dns.TXT)
+	if !ok || path.Hdr.Name != "_path.example.org." {
+		t.Fatalf("Expected a _path.example.org. TXT record, got %s", rec.Msg.Extra[1])
+	}
+	if expected := fmt.Sprintf("%x", "1 4 5 12"); len(path.Txt) != 1 || path.Txt[0] != expected {
+		t.Errorf("Expected TXT %q, got %v", expected, path.Txt)
+	}
+}
+
+func TestWhoamiSCIONLongRoute(t *testing.T) {
+	ia, err := pkgscion.ParseIA("19-ffaa:1:1067")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hops := []string{"19-ffaa:1:1067"}
+	for i := 0; i < 20; i++ {
+		hops = append(hops, "12>34", "19-ffaa:0:13"+strconv.Itoa(10+i))
+	}
+	route := strings.Join(hops, " ")
+	client := pkgscion.Client{IA: ia, Host: "10.0.0.1", Port: 40000, Path: "3f2c9a", Hops: 21, Route: route}
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	a := new(dns.Msg)
+	a.SetReply(req)
+	a.Extra = scionExtra(request.Request{W: &test.ResponseWriter{}, Req: req}, client)
+	if _, err := a.Pack(); err != nil {
+		t.Fatalf("Expected the reply to pack, got %v", err)
+	}
+
+	path, ok := a.Extra[1].(*dns.TXT)
+	if !ok {
+		t.Fatalf("Expected a TXT record, got %s", a.Extra[1])
+	}
+	if len(path.Txt) < 3 {
+		t.Fatalf("Expected the route to be split, got %v", path.Txt)
+	}
+	for _, s := range path.Txt {
+		if len(s) > 255 {
+			t.Errorf("Expected strings of at most 255 bytes, got %d", len(s))
+		}
+	}
+	if last := path.Txt[len(path.Txt)-1]; last != "3f2c9a" {
+		t.Errorf("Expected the fingerprint last, got %q", last)
+	}
+	if joined := strings.Join(path.Txt[:len(path.Txt)-1], " "); joined != route {
+		t.Errorf("Expected the route %q, got %q", route, joined)
+	}
+}